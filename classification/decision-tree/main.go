@@ -1,34 +1,156 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
+	"os"
+	"os/signal"
 
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
 	"github.com/sjwhitworth/golearn/base"
 	"github.com/sjwhitworth/golearn/evaluation"
 	"github.com/sjwhitworth/golearn/trees"
 )
 
+// files locates the datasets and the runs of the example.
+var files = workspace.Register(flag.CommandLine, "decision-tree", "../dataset")
+
 func main() {
+	if err := experiment.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	// Stop the cross-validations on an interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := runExample(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runExample runs the mode selected by the flags, the cross-validation of
+// the tree by default, returning the first error met.
+func runExample(ctx context.Context) error {
+	// Download the iris dataset when it is not present yet.
+	irisPath := files.Data("iris.csv")
+	if err := dataset.FetchIris(irisPath); err != nil {
+		return err
+	}
 	// Load the iris dataset into golearn "instances".
-	irisData, err := base.ParseCSVToInstances("../dataset/iris.csv", true)
+	irisData, err := base.ParseCSVToInstances(irisPath, true)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Seed the random number generator for reproducibility.
 	rand.Seed(44111342)
-	// Initialize the ID3 decision tree with a train-prune split parameter of 0.6.
-	decisionTree := trees.NewID3DecisionTree(0.6)
-	// Perform 5-fold cross-validation to train and evaluate the model.
-	cv, err := evaluation.GenerateCrossFoldValidationConfusionMatrices(irisData, decisionTree, 5)
+	// Record the structure of the tree fitted on every fold, when
+	// requested.
+	var report *structureReport
+	var fitted func(root *trees.DecisionTreeNode, fold split.Fold) error
+	if *structure {
+		if report, err = newStructureReport(irisData); err != nil {
+			return err
+		}
+		fitted = report.add
+	}
+	// Perform repeated stratified 5-fold cross-validation to train and
+	// evaluate ID3 decision trees with the train-prune split parameter.
+	cv, err := crossValidate(ctx, irisData, fitted)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Calculate the mean, variance, and standard deviation of the accuracy.
 	mean, variance := evaluation.GetCrossValidatedMetric(cv, evaluation.GetAccuracy)
 	stdev := math.Sqrt(variance)
 	// Print the cross-validation accuracy metrics.
 	fmt.Printf("\nAccuracy\n%.2f (+/- %.2f)\n\n", mean, stdev*2)
+
+	// Report the per-class scores and the confusion matrix over all folds.
+	run, err := files.NewRun()
+	if err != nil {
+		return err
+	}
+	summary, err := classReport(cv, run)
+	if err != nil {
+		return err
+	}
+	if report != nil {
+		scores, err := report.write(run)
+		if err != nil {
+			return err
+		}
+		for name, v := range scores {
+			summary[name] = v
+		}
+	}
+	// Evaluate the tuning of the pruning split without the optimism of
+	// choosing and scoring it on the same folds, when requested.
+	if *nested {
+		nestedScores, err := nestedCV(ctx, irisData, run)
+		if err != nil {
+			return err
+		}
+		for name, v := range nestedScores {
+			summary[name] = v
+		}
+	}
+	// Save the cross-validation metrics, along with the scores of the
+	// summed matrix, for external dashboards.
+	summary["accuracy_mean"], summary["accuracy_stdev"] = mean, stdev
+	if err := run.WriteMetrics(summary); err != nil {
+		return err
+	}
+	if err := experiment.Write(run.Path(experiment.File), flag.CommandLine); err != nil {
+		return err
+	}
+	if err := run.WriteTable("folds", metrics.FoldColumns, metrics.FoldRows(cv)); err != nil {
+		return err
+	}
+	// Fit the tree on every row and save it.
+	if err := saveModel(irisData, run); err != nil {
+		return err
+	}
+	fmt.Println("Artifacts saved to", run.Dir)
+	return nil
+}
+
+// classReport prints the precision, recall, F1 score and specificity of
+// every class from the summed fold confusion matrices along with their
+// averages and the matrix itself, saves them to the class_metrics and
+// confusion_matrix tables and saves the matrix as a heat map in the run
+// directory. It returns the averaged scores.
+func classReport(cv []evaluation.ConfusionMatrix, run *artifacts.Run) (map[string]float64, error) {
+	cm := metrics.Sum(cv)
+	scores := metrics.PerClass(cm)
+	if err := metrics.WriteReport(os.Stdout, scores); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	summary := metrics.Summarize(cm)
+	if err := metrics.WriteSummary(os.Stdout, summary); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := run.WriteTable("class_metrics", metrics.ClassColumns, metrics.ClassRows(scores)); err != nil {
+		return nil, err
+	}
+	classes := metrics.Classes(cm)
+	counts := metrics.Counts(cm, classes)
+	if err := metrics.WriteConfusion(os.Stdout, classes, counts); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := run.WriteTable("confusion_matrix", metrics.ConfusionColumns(classes), metrics.ConfusionRows(classes, counts)); err != nil {
+		return nil, err
+	}
+	return summary, plots.ConfusionMatrix(run.PlotPath("confusion_matrix.png"), classes, counts)
 }
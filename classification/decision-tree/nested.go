@@ -0,0 +1,83 @@
+package main
+
+// Nested cross-validation
+//
+// Choosing the pruning split of the tree by the accuracy of the folds it
+// is evaluated on makes that accuracy optimistic. Nested cross-validation
+// chooses it on inner folds of the training rows of every outer fold, and
+// scores the choice on the held-out outer fold.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/search"
+	"github.com/sjwhitworth/golearn/base"
+	"github.com/sjwhitworth/golearn/evaluation"
+	"github.com/sjwhitworth/golearn/trees"
+)
+
+// numInnerFolds is the number of inner folds of the nested
+// cross-validation.
+const numInnerFolds = 3
+
+// nested evaluates the tuning of the pruning split by nested
+// cross-validation.
+var nested = flag.Bool("nested", false, "evaluate the tuning of the pruning split by nested cross-validation")
+
+// tuningSpace holds the candidate train-prune splits of the tree.
+var tuningSpace = search.New(search.Choice("prune_split", 0.5, 0.6, 0.7, 0.8, 0.9))
+
+// nestedCV runs the nested cross-validation of the tree, prints the
+// pruning split chosen on every outer fold along with its inner and outer
+// accuracies, and saves them to the nested_folds table. It returns the
+// mean and standard deviation of the outer accuracies and the mean of the
+// inner ones.
+func nestedCV(ctx context.Context, data base.FixedDataGrid, run *artifacts.Run) (map[string]float64, error) {
+	d, err := dataset.FromInstances(data)
+	if err != nil {
+		return nil, err
+	}
+	strata := make([]int, len(d.Labels))
+	for i, label := range d.Labels {
+		strata[i] = int(label)
+	}
+	folds, err := search.NestedCV(ctx, tuningSpace.Grid(0), strata, *numFolds, numInnerFolds, foldSeed, func(p search.Params, train, test []int) (float64, error) {
+		trainData := base.NewInstancesViewFromVisible(data, train, data.AllAttributes())
+		testData := base.NewInstancesViewFromVisible(data, test, data.AllAttributes())
+		decisionTree := trees.NewID3DecisionTree(p.Float("prune_split"))
+		if err := decisionTree.Fit(trainData); err != nil {
+			return 0, err
+		}
+		predictions, err := decisionTree.Predict(testData)
+		if err != nil {
+			return 0, err
+		}
+		cm, err := evaluation.GetConfusionMatrix(testData, predictions)
+		if err != nil {
+			return 0, err
+		}
+		return evaluation.GetAccuracy(cm), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Nested cross-validation")
+	if err := search.WriteNested(os.Stdout, folds); err != nil {
+		return nil, err
+	}
+	mean, stdev, innerMean := search.NestedScores(folds)
+	fmt.Printf("Accuracy %.2f (+/- %.2f), inner accuracy of the chosen parameters %.2f\n\n", mean, stdev*2, innerMean)
+	if err := run.WriteTable("nested_folds", search.NestedColumns, search.NestedRows(folds)); err != nil {
+		return nil, err
+	}
+	return map[string]float64{
+		"nested_accuracy_mean":  mean,
+		"nested_accuracy_stdev": stdev,
+		"nested_inner_accuracy": innerMean,
+	}, nil
+}
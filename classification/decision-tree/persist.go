@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/sjwhitworth/golearn/base"
+	"github.com/sjwhitworth/golearn/trees"
+)
+
+const (
+	// modelFile names the saved tree in the run directory.
+	modelFile = "decision_tree.json"
+	// modelKind is the kind of the saved tree.
+	modelKind = "golearn-id3"
+)
+
+// pruneSplit is the share of the training rows the tree is grown on, the
+// others being used to prune it.
+var pruneSplit = flag.Float64("prune-split", 0.6, "share of the training rows the tree is grown on, the others pruning it")
+
+// id3Model saves an ID3 tree as its Save method does, but without the
+// debugging output that golearn prints, and closing the file so that it
+// is complete.
+type id3Model struct {
+	*trees.ID3DecisionTree
+}
+
+func (m id3Model) Save(path string) error {
+	writer, err := base.CreateSerializedClassifierStub(path, m.GetMetadata())
+	if err != nil {
+		return err
+	}
+	if err := m.SaveWithPrefix(writer, ""); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// saveModel fits the tree on every row, saves it to the model directory
+// of the run, and returns an error unless the saved tree predicts as the
+// fitted one.
+func saveModel(data base.FixedDataGrid, run *artifacts.Run) error {
+	decisionTree := trees.NewID3DecisionTree(*pruneSplit)
+	if err := decisionTree.Fit(data); err != nil {
+		return err
+	}
+	path := run.ModelPath(modelFile)
+	if err := model.SaveGolearn(path, modelKind, id3Model{decisionTree}); err != nil {
+		return err
+	}
+	saved := trees.NewID3DecisionTree(*pruneSplit)
+	if _, err := model.LoadGolearn(path, modelKind, saved); err != nil {
+		return err
+	}
+	want, err := decisionTree.Predict(data)
+	if err != nil {
+		return err
+	}
+	got, err := saved.Predict(data)
+	if err != nil {
+		return err
+	}
+	_, numRows := data.Size()
+	for i := 0; i < numRows; i++ {
+		if base.GetClass(got, i) != base.GetClass(want, i) {
+			return fmt.Errorf("the saved tree predicts %s for row %d, the fitted one %s", base.GetClass(got, i), i, base.GetClass(want, i))
+		}
+	}
+	fmt.Println("Model saved to", path)
+	return nil
+}
@@ -0,0 +1,331 @@
+package main
+
+// Tree structure across folds
+//
+// The accuracy of every fold says how well the trees predict, not whether
+// they agree on how. The structure report records the depth, size and
+// top-level splits of the ID3 tree fitted on every fold, and of a CART
+// tree of package tree fitted on the same rows, and counts how often the
+// same top-level splits come back. Top-level splits that change from fold
+// to fold show a structure that follows the sample more than the data.
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+	"github.com/sjwhitworth/golearn/base"
+	"github.com/sjwhitworth/golearn/trees"
+)
+
+// structure reports the structure of the trees fitted on every fold.
+var structure = flag.Bool("structure", false, "report the depth, size and top-level splits of the trees fitted on every fold")
+
+// leafName stands for a leaf among the top-level splits.
+const leafName = "leaf"
+
+// treeShape describes the structure of the tree fitted on a fold.
+type treeShape struct {
+	model         string
+	repeat, fold  int
+	depth, nodes  int
+	leaves        int
+	rootFeature   string
+	rootThreshold float64
+	// children names the split features of the children of the root,
+	// left first.
+	children string
+}
+
+// topSplit names the top-level splits of the tree: the feature of the
+// root followed by those of its children.
+func (s treeShape) topSplit() string {
+	if s.children == "" {
+		return s.rootFeature
+	}
+	return s.rootFeature + " > " + s.children
+}
+
+// id3Shape returns the structure of an ID3 tree. The children of every
+// node are taken in the order of their branch values, which puts the
+// rows at most the threshold of a numeric split first.
+func id3Shape(root *trees.DecisionTreeNode) treeShape {
+	var s treeShape
+	var walk func(n *trees.DecisionTreeNode, depth int)
+	walk = func(n *trees.DecisionTreeNode, depth int) {
+		s.nodes++
+		s.depth = max(s.depth, depth)
+		if len(n.Children) == 0 {
+			s.leaves++
+			return
+		}
+		for _, child := range id3Children(n) {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	s.rootFeature, s.rootThreshold = id3Feature(root), math.NaN()
+	if len(root.Children) > 0 {
+		s.rootThreshold = root.SplitRule.SplitVal
+		var children []string
+		for _, child := range id3Children(root) {
+			children = append(children, id3Feature(child))
+		}
+		s.children = strings.Join(children, ", ")
+	}
+	return s
+}
+
+// id3Children returns the children of the node in the order of their
+// branch values.
+func id3Children(n *trees.DecisionTreeNode) []*trees.DecisionTreeNode {
+	keys := make([]string, 0, len(n.Children))
+	for key := range n.Children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]*trees.DecisionTreeNode, len(keys))
+	for i, key := range keys {
+		children[i] = n.Children[key]
+	}
+	return children
+}
+
+// id3Feature returns the split feature of the node, or leafName.
+func id3Feature(n *trees.DecisionTreeNode) string {
+	if len(n.Children) == 0 {
+		return leafName
+	}
+	return n.SplitRule.SplitAttr.GetName()
+}
+
+// cartShape returns the structure of a CART tree with the named
+// features.
+func cartShape(t *tree.Tree, names []string) treeShape {
+	s := treeShape{depth: t.Depth(), nodes: len(t.Nodes), rootThreshold: math.NaN()}
+	feature := func(i int) string {
+		if t.Nodes[i].Leaf() {
+			return leafName
+		}
+		return names[t.Nodes[i].Feature]
+	}
+	for i := range t.Nodes {
+		if t.Nodes[i].Leaf() {
+			s.leaves++
+		}
+	}
+	root := t.Nodes[0]
+	s.rootFeature = feature(0)
+	if !root.Leaf() {
+		s.rootThreshold = root.Threshold
+		s.children = feature(root.Left) + ", " + feature(root.Right)
+	}
+	return s
+}
+
+// structureReport gathers the structure of the trees of every fold.
+type structureReport struct {
+	data   *dataset.Dataset
+	shapes []treeShape
+}
+
+func newStructureReport(data base.FixedDataGrid) (*structureReport, error) {
+	d, err := dataset.FromInstances(data)
+	if err != nil {
+		return nil, err
+	}
+	return &structureReport{data: d}, nil
+}
+
+// add records the structure of the ID3 tree fitted on the training rows
+// of the fold, and fits a CART tree on the same rows to record its
+// structure as well.
+func (r *structureReport) add(root *trees.DecisionTreeNode, fold split.Fold) error {
+	s := id3Shape(root)
+	s.model, s.repeat, s.fold = "id3", fold.Repeat, fold.Index
+	r.shapes = append(r.shapes, s)
+	_, numFeatures := r.data.Features.Dims()
+	x := mat64.NewDense(len(fold.Train), numFeatures, nil)
+	y := make([]float64, len(fold.Train))
+	for i, row := range fold.Train {
+		x.SetRow(i, r.data.Features.RawRowView(row))
+		y[i] = r.data.Labels[row]
+	}
+	cart := tree.New(tree.Classification, tree.Params{})
+	if err := cart.Fit(x, y, nil, nil); err != nil {
+		return err
+	}
+	s = cartShape(cart, r.data.Names)
+	s.model, s.repeat, s.fold = "cart", fold.Repeat, fold.Index
+	r.shapes = append(r.shapes, s)
+	return nil
+}
+
+// splitCount counts the folds of a model sharing the same top-level
+// splits, with the range of their root thresholds.
+type splitCount struct {
+	model, split  string
+	count         int
+	share         float64
+	meanThreshold float64
+	minThreshold  float64
+	maxThreshold  float64
+}
+
+// shapeStats summarizes the depth and size of the trees of a model.
+type shapeStats struct {
+	model                string
+	depth, nodes, leaves []float64
+	folds                int
+}
+
+// summarize returns the statistics of the trees of every model, in the
+// order the models were added, and the counts of their top-level splits,
+// most frequent first.
+func (r *structureReport) summarize() ([]*shapeStats, []splitCount) {
+	var stats []*shapeStats
+	byModel := make(map[string]*shapeStats)
+	counts := make(map[[2]string]*splitCount)
+	for _, s := range r.shapes {
+		st := byModel[s.model]
+		if st == nil {
+			st = &shapeStats{model: s.model}
+			byModel[s.model] = st
+			stats = append(stats, st)
+		}
+		st.folds++
+		st.depth = append(st.depth, float64(s.depth))
+		st.nodes = append(st.nodes, float64(s.nodes))
+		st.leaves = append(st.leaves, float64(s.leaves))
+		key := [2]string{s.model, s.topSplit()}
+		c := counts[key]
+		if c == nil {
+			c = &splitCount{model: s.model, split: s.topSplit(), minThreshold: math.Inf(1), maxThreshold: math.Inf(-1)}
+			counts[key] = c
+		}
+		c.count++
+		if !math.IsNaN(s.rootThreshold) {
+			c.meanThreshold += s.rootThreshold
+			c.minThreshold = math.Min(c.minThreshold, s.rootThreshold)
+			c.maxThreshold = math.Max(c.maxThreshold, s.rootThreshold)
+		}
+	}
+	var splits []splitCount
+	for _, c := range counts {
+		c.share = float64(c.count) / float64(byModel[c.model].folds)
+		c.meanThreshold /= float64(c.count)
+		if math.IsInf(c.minThreshold, 1) {
+			c.meanThreshold, c.minThreshold, c.maxThreshold = math.NaN(), math.NaN(), math.NaN()
+		}
+		splits = append(splits, *c)
+	}
+	order := make(map[string]int)
+	for i, st := range stats {
+		order[st.model] = i
+	}
+	sort.Slice(splits, func(i, j int) bool {
+		a, b := splits[i], splits[j]
+		if a.model != b.model {
+			return order[a.model] < order[b.model]
+		}
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return a.split < b.split
+	})
+	return stats, splits
+}
+
+// meanRange formats the mean of the values with their range.
+func meanRange(values []float64) string {
+	mean, lo, hi := 0.0, math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		mean += v / float64(len(values))
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	return fmt.Sprintf("%.1f (%g-%g)", mean, lo, hi)
+}
+
+// optional returns the value, or nil when it is NaN, as for the
+// thresholds of trees that are a single leaf.
+func optional(v float64) any {
+	if math.IsNaN(v) {
+		return nil
+	}
+	return v
+}
+
+// mean returns the mean of the values.
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// write prints the depth and size of the trees of every model and their
+// most frequent top-level splits, and saves the structure of every fold
+// to the tree_structure table and the split counts to the top_splits
+// table. It returns the mean depth and size of the trees of every model
+// and the share of the folds taking its most frequent top-level splits.
+func (r *structureReport) write(run *artifacts.Run) (map[string]float64, error) {
+	stats, splits := r.summarize()
+	fmt.Println("Tree structure across folds")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "model\tfolds\tdepth\tnodes\tleaves")
+	for _, st := range stats {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", st.model, st.folds, meanRange(st.depth), meanRange(st.nodes), meanRange(st.leaves))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "model\ttop-level splits\tfolds\tshare\troot threshold")
+	for _, c := range splits {
+		threshold := "-"
+		if !math.IsNaN(c.meanThreshold) {
+			threshold = fmt.Sprintf("%.2f (%.2f-%.2f)", c.meanThreshold, c.minThreshold, c.maxThreshold)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f\t%s\n", c.model, c.split, c.count, c.share, threshold)
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	// Save the structure of every fold and the split counts.
+	rows := make([][]any, len(r.shapes))
+	for i, s := range r.shapes {
+		rows[i] = []any{s.model, s.repeat + 1, s.fold + 1, s.depth, s.nodes, s.leaves, s.rootFeature, optional(s.rootThreshold), s.children}
+	}
+	columns := []string{"model", "repeat", "fold", "depth", "nodes", "leaves", "root_feature", "root_threshold", "children"}
+	if err := run.WriteTable("tree_structure", columns, rows); err != nil {
+		return nil, err
+	}
+	rows = make([][]any, len(splits))
+	for i, c := range splits {
+		rows[i] = []any{c.model, c.split, c.count, c.share, optional(c.meanThreshold), optional(c.minThreshold), optional(c.maxThreshold)}
+	}
+	columns = []string{"model", "split", "folds", "share", "threshold_mean", "threshold_min", "threshold_max"}
+	if err := run.WriteTable("top_splits", columns, rows); err != nil {
+		return nil, err
+	}
+	metrics := make(map[string]float64)
+	for _, st := range stats {
+		metrics[st.model+"_depth_mean"] = mean(st.depth)
+		metrics[st.model+"_nodes_mean"] = mean(st.nodes)
+	}
+	// The splits are sorted by count within every model.
+	for _, c := range splits {
+		if _, ok := metrics[c.model+"_top_split_share"]; !ok {
+			metrics[c.model+"_top_split_share"] = c.share
+		}
+	}
+	return metrics, nil
+}
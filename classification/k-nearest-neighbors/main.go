@@ -1,30 +1,49 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"math"
+	"os"
 
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
 	"github.com/sjwhitworth/golearn/base"
 	"github.com/sjwhitworth/golearn/evaluation"
-	"github.com/sjwhitworth/golearn/knn"
 )
 
+// files locates the datasets and the runs of the example.
+var files = workspace.Register(flag.CommandLine, "k-nearest-neighbors", "../dataset")
+
 func main() {
+	if err := experiment.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if err := runExample(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runExample cross-validates the classifier and saves it, returning the
+// first error met.
+func runExample() error {
 	// Read in the iris data set into golearn "instances".
-	irisData, err := base.ParseCSVToInstances("../dataset/iris.csv", true)
+	irisData, err := base.ParseCSVToInstances(files.Data("iris.csv"), true)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Initialize a new KNN classifier. We will use a simple
 	// Euclidean distance measure and k=2.
-	knn := knn.NewKnnClassifier("euclidean", "linear", 2)
+	knn := newClassifier()
 
 	// Use cross-fold validation to successively train and evaluate the model
 	// on 5 folds of the data set.
 	cv, err := evaluation.GenerateCrossFoldValidationConfusionMatrices(irisData, knn, 5)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Get the mean, variance and standard deviation of the accuracy for the
@@ -33,4 +52,50 @@ func main() {
 	stdev := math.Sqrt(variance)
 	// Output the cross metrics to standard out.
 	fmt.Printf("\nAccuracy\n%.2f (+/- %.2f)\n\n", mean, stdev*2)
+
+	// Save the cross-validation metrics for external dashboards.
+	run, err := files.NewRun()
+	if err != nil {
+		return err
+	}
+	// Add the scores of the matrix summed over the folds.
+	cm := metrics.Sum(cv)
+	summary := metrics.Summarize(cm)
+	if err := metrics.WriteSummary(os.Stdout, summary); err != nil {
+		return err
+	}
+	fmt.Println()
+	summary["accuracy_mean"], summary["accuracy_stdev"] = mean, stdev
+	if err := run.WriteMetrics(summary); err != nil {
+		return err
+	}
+	if err := run.WriteTable("folds", metrics.FoldColumns, metrics.FoldRows(cv)); err != nil {
+		return err
+	}
+	scores := metrics.PerClass(cm)
+	if err := run.WriteTable("class_metrics", metrics.ClassColumns, metrics.ClassRows(scores)); err != nil {
+		return err
+	}
+	// Print the confusion matrix and save it as a table and a heat map.
+	classes := metrics.Classes(cm)
+	counts := metrics.Counts(cm, classes)
+	if err := metrics.WriteConfusion(os.Stdout, classes, counts); err != nil {
+		return err
+	}
+	fmt.Println()
+	if err := run.WriteTable("confusion_matrix", metrics.ConfusionColumns(classes), metrics.ConfusionRows(classes, counts)); err != nil {
+		return err
+	}
+	if err := plots.ConfusionMatrix(run.PlotPath("confusion_matrix.png"), classes, counts); err != nil {
+		return err
+	}
+	// Fit the classifier on every row and save it.
+	if err := saveModel(irisData, run); err != nil {
+		return err
+	}
+	if err := experiment.Write(run.Path(experiment.File), flag.CommandLine); err != nil {
+		return err
+	}
+	fmt.Println("Artifacts saved to", run.Dir)
+	return nil
 }
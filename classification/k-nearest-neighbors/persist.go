@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/sjwhitworth/golearn/base"
+	"github.com/sjwhitworth/golearn/knn"
+)
+
+const (
+	// modelFile names the saved classifier in the run directory.
+	modelFile = "knn.json"
+	// modelKind is the kind of the saved classifier.
+	modelKind = "golearn-knn"
+)
+
+// newClassifier returns the classifier of the example: a simple Euclidean
+// distance measure and k=2.
+func newClassifier() *knn.KNNClassifier {
+	return knn.NewKnnClassifier("euclidean", "linear", 2)
+}
+
+// knnModel saves a KNN classifier as its Save method does, without the
+// debugging output that golearn prints.
+type knnModel struct {
+	*knn.KNNClassifier
+}
+
+func (m knnModel) Save(path string) error {
+	writer, err := base.CreateSerializedClassifierStub(path, m.GetMetadata())
+	if err != nil {
+		return err
+	}
+	return m.SaveWithPrefix(writer, "")
+}
+
+// saveModel fits the classifier on every row, saves it to the model
+// directory of the run, and returns an error unless the saved classifier
+// holds the same training data as the fitted one.
+func saveModel(data base.FixedDataGrid, run *artifacts.Run) error {
+	cls := newClassifier()
+	if err := cls.Fit(data); err != nil {
+		return err
+	}
+	path := run.ModelPath(modelFile)
+	if err := model.SaveGolearn(path, modelKind, knnModel{cls}); err != nil {
+		return err
+	}
+	saved := newClassifier()
+	if _, err := model.LoadGolearn(path, modelKind, saved); err != nil {
+		return err
+	}
+	// A KNN classifier is its training data. Compare it rather than the
+	// predictions, as golearn breaks ties between classes at random.
+	if !sameInstances(saved.TrainingData, cls.TrainingData) {
+		return errors.New("the saved classifier holds other training data than the fitted one")
+	}
+	fmt.Println("Model saved to", path)
+	return nil
+}
+
+// sameInstances reports whether the instances have the same attributes and
+// values. The attributes are matched by name, as golearn may reorder them
+// when loading a classifier, and its InstancesAreEqual compares its first
+// argument with itself.
+func sameInstances(a, b base.FixedDataGrid) bool {
+	colsA, rowsA := a.Size()
+	colsB, rowsB := b.Size()
+	if colsA != colsB || rowsA != rowsB {
+		return false
+	}
+	for _, attrA := range a.AllAttributes() {
+		attrB := base.GetAttributeByName(b, attrA.GetName())
+		if attrB == nil || !attrA.Equals(attrB) {
+			return false
+		}
+		specA, err := a.GetAttribute(attrA)
+		if err != nil {
+			return false
+		}
+		specB, err := b.GetAttribute(attrB)
+		if err != nil {
+			return false
+		}
+		for i := 0; i < rowsA; i++ {
+			if attrA.GetStringFromSysVal(a.Get(specA, i)) != attrB.GetStringFromSysVal(b.Get(specB, i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
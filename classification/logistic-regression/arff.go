@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/gonum/matrix/mat64"
+)
+
+// exportARFF writes the training and test sets to ARFF files in the run
+// directory, so the golearn examples and Weka can read them directly.
+func exportARFF(run *artifacts.Run) error {
+	for _, set := range []string{"training", "test"} {
+		features, labels, err := readLoanData(files.Data(set + ".csv"))
+		if err != nil {
+			return err
+		}
+		// Drop the intercept column, which golearn models do not need.
+		numRows, numCols := features.Dims()
+		d := &dataset.Dataset{
+			Names:     featureColumns(),
+			ClassName: labelColumn,
+			Features:  mat64.DenseCopyOf(features.View(0, 0, numRows, numCols-1)),
+			Labels:    labels,
+		}
+		if err := dataset.WriteARFF(d, run.Path(set+".arff"), "loan"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
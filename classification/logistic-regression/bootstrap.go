@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/bootstrap"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// numReplicas is the number of bootstrap replicas trained for the
+// uncertainty estimate of the loan scores.
+const numReplicas = 25
+
+// bootstrapSeed seeds the resampling of the training rows.
+const bootstrapSeed = 44111342
+
+// logisticReplica adapts logistic.Fit to model.Estimator for the
+// bootstrap replicas: it fits on the rows of readLoanData, which end with
+// the intercept column, and draws its initial weights and row orders from
+// the random stream of its replica.
+type logisticReplica struct {
+	opts    logistic.Options
+	r       *rand.Rand
+	weights []float64
+}
+
+// newLogisticReplica returns a bootstrap.NewFunc of logistic regressions
+// trained with the options.
+func newLogisticReplica(opts logistic.Options) bootstrap.NewFunc {
+	return func(r *rand.Rand) model.Estimator {
+		return &logisticReplica{opts: opts, r: r}
+	}
+}
+
+func (m *logisticReplica) Fit(ctx context.Context, x *mat64.Dense, y []float64) error {
+	weights, _, err := logistic.Fit(ctx, x, y, m.opts, m.r)
+	if err != nil {
+		return err
+	}
+	m.weights = weights
+	return nil
+}
+
+// Probability returns the probability of class 1 of a feature row.
+func (m *logisticReplica) Probability(featureRow []float64) (float64, error) {
+	if len(featureRow) != len(m.weights) {
+		return 0, fmt.Errorf("row has %d features, the model %d", len(featureRow), len(m.weights))
+	}
+	return logistic.Probability(m.weights, featureRow), nil
+}
+
+func (m *logisticReplica) Predict(featureRow []float64) (float64, error) {
+	p, err := m.Probability(featureRow)
+	if err != nil || p < 0.5 {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func bootstrapScores(ctx context.Context, run *artifacts.Run) (float64, error) {
+	// Load the training and test data.
+	features, labels, err := readLoanData(files.Data("training.csv"))
+	if err != nil {
+		return 0, err
+	}
+	testFeatures, _, err := readLoanData(files.Data("test.csv"))
+	if err != nil {
+		return 0, err
+	}
+	// Train the bootstrap replicas of the logistic regression model.
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return 0, err
+	}
+	ensemble, err := bootstrap.Fit(ctx, newLogisticReplica(opts), features, labels, numReplicas, bootstrapSeed, *workers)
+	if err != nil {
+		return 0, err
+	}
+	// Create the output file.
+	f, err := os.Create(run.Path("bootstrap_scores.csv"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	// Create a CSV writer.
+	w := csv.NewWriter(f)
+	if err := w.Write(append(featureColumns(), "mean", "variance")); err != nil {
+		return 0, err
+	}
+	// Score every test row with the ensemble and write out the mean
+	// probability along with its variance.
+	numRows, _ := testFeatures.Dims()
+	var sumVariance float64
+	for i := 0; i < numRows; i++ {
+		featureRow := mat64.Row(nil, i, testFeatures)
+		mean, variance, err := ensemble.Predict(featureRow)
+		if err != nil {
+			return 0, err
+		}
+		sumVariance += variance
+		// Write the features without the intercept, followed by the scores.
+		var record []string
+		for _, x := range featureRow[:len(featureRow)-1] {
+			record = append(record, strconv.FormatFloat(x, 'f', 4, 64))
+		}
+		record = append(record,
+			strconv.FormatFloat(mean, 'f', 4, 64),
+			strconv.FormatFloat(variance, 'f', 6, 64),
+		)
+		if err := w.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	// Write any buffered data to the underlying writer.
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, err
+	}
+	// Output the average prediction variance to stdout.
+	meanVariance := sumVariance / float64(numRows)
+	fmt.Printf("Bootstrap replicas = %d\nMean prediction variance = %0.6f\n", numReplicas, meanVariance)
+	// Save the out-of-bag predictions of the training rows.
+	preds, counts, err := ensemble.OOB(features)
+	if err != nil {
+		return 0, err
+	}
+	numOOB, err := writeOOB(run.Path("oob_scores.csv"), preds, counts, labels)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("Out-of-bag predictions = %d of %d training rows\n\n", numOOB, len(labels))
+	return meanVariance, nil
+}
+
+// writeOOB writes the out-of-bag prediction, the number of replicas it
+// averages and the observed class of every training row to path, and
+// returns the number of rows that have an out-of-bag prediction.
+func writeOOB(path string, preds []float64, counts []int, labels []float64) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"row", labelColumn, "oob_probability", "oob_replicas"}); err != nil {
+		return 0, err
+	}
+	var numOOB int
+	for i, p := range preds {
+		if !math.IsNaN(p) {
+			numOOB++
+		}
+		record := []string{
+			strconv.Itoa(i),
+			strconv.FormatFloat(labels[i], 'f', -1, 64),
+			strconv.FormatFloat(p, 'f', 4, 64),
+			strconv.Itoa(counts[i]),
+		}
+		if err := w.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, err
+	}
+	return numOOB, nil
+}
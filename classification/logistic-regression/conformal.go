@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/conformal"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Split conformal prediction sets
+// A part of the training data is held out to calibrate how far the model's
+// probability for the true class can drop. Every class whose probability stays
+// within that bound goes into the prediction set, which then contains the true
+// class with probability of at least 1 - alpha.
+
+// conformalAlpha is the allowed miscoverage rate of the prediction sets.
+const conformalAlpha = 0.1
+
+// calibrationFraction is the share of the training rows held out for calibration.
+const calibrationFraction = 0.2
+
+// subsetRows returns the given rows of the features and labels.
+func subsetRows(features *mat64.Dense, labels []float64, rows []int) (*mat64.Dense, []float64) {
+	_, numCols := features.Dims()
+	data := make([]float64, 0, len(rows)*numCols)
+	subLabels := make([]float64, len(rows))
+	for i, idx := range rows {
+		data = append(data, mat64.Row(nil, idx, features)...)
+		subLabels[i] = labels[idx]
+	}
+	return mat64.NewDense(len(rows), numCols, data), subLabels
+}
+
+func conformalSets(ctx context.Context) (coverage, meanSetSize float64, err error) {
+	// Load the training and test data.
+	features, labels, err := readLoanData(files.Data("training.csv"))
+	if err != nil {
+		return 0, 0, err
+	}
+	testFeatures, testLabels, err := readLoanData(files.Data("test.csv"))
+	if err != nil {
+		return 0, 0, err
+	}
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return 0, 0, err
+	}
+	// Hold out a random part of the training rows for calibration.
+	r := rand.New(rand.NewSource(bootstrapSeed))
+	perm := r.Perm(len(labels))
+	numCalibration := int(float64(len(labels)) * calibrationFraction)
+	calFeatures, calLabels := subsetRows(features, labels, perm[:numCalibration])
+	fitFeatures, fitLabels := subsetRows(features, labels, perm[numCalibration:])
+	// Fit the model on the remaining rows and calibrate it.
+	m := newLogisticReplica(opts)(r)
+	if err := m.Fit(ctx, fitFeatures, fitLabels); err != nil {
+		return 0, 0, err
+	}
+	c, err := conformal.CalibrateClassifier(conformal.Binary(m.(model.Prober).Probability), calFeatures, calLabels, conformalAlpha)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Measure the coverage and the size of the prediction sets on the test set.
+	var covered, singletons, totalSize int
+	for i, label := range testLabels {
+		set, err := c.Set(mat64.Row(nil, i, testFeatures))
+		if err != nil {
+			return 0, 0, err
+		}
+		totalSize += len(set)
+		if len(set) == 1 {
+			singletons++
+		}
+		for _, class := range set {
+			if class == label {
+				covered++
+				break
+			}
+		}
+	}
+	numTest := float64(len(testLabels))
+	coverage = float64(covered) / numTest
+	meanSetSize = float64(totalSize) / numTest
+	// Output the coverage and set sizes to standard out.
+	fmt.Printf("Conformal prediction sets (alpha = %0.2f)\n", conformalAlpha)
+	fmt.Printf("Test coverage = %0.2f\nMean set size = %0.2f\nSingleton sets = %0.2f\n\n",
+		coverage, meanSetSize, float64(singletons)/numTest)
+	return coverage, meanSetSize, nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/bootstrap"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Deterministic parallelism
+// The bootstrap replicas and the stability seeds are trained in parallel.
+// Every task draws from its own random stream, derived from a seed and the
+// index of the task, and the results are combined in task order, so the
+// outputs are bit-identical whatever the number of workers. The
+// determinism check runs both paths twice, on a single worker and on
+// several, and compares the results bit by bit.
+
+var (
+	// workers is the number of goroutines training in parallel.
+	workers = flag.Int("workers", 0, "number of parallel workers for the bootstrap and stability runs (0 uses every CPU)")
+	// checkDeterminism runs the parallel paths twice instead of the
+	// example and fails when the results differ.
+	checkDeterminism = flag.Bool("check-determinism", false, "run the parallel paths on one worker and on -workers workers and fail unless the results are bit-identical")
+)
+
+// sameBits reports whether both slices hold bit-identical values.
+func sameBits(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Float64bits(a[i]) != math.Float64bits(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parallelOutputs runs the bootstrap ensemble and the stability seeds on
+// the given number of workers and returns their outputs: the ensemble
+// scores of the test rows and the accuracy and AUC of every seed.
+func parallelOutputs(ctx context.Context, numWorkers int) (scores, seedMetrics []float64, err error) {
+	features, labels, err := readLoanData(files.Data("training.csv"))
+	if err != nil {
+		return nil, nil, err
+	}
+	testFeatures, testLabels, err := readLoanData(files.Data("test.csv"))
+	if err != nil {
+		return nil, nil, err
+	}
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	ensemble, err := bootstrap.Fit(ctx, newLogisticReplica(opts), features, labels, numReplicas, bootstrapSeed, numWorkers)
+	if err != nil {
+		return nil, nil, err
+	}
+	numRows, _ := testFeatures.Dims()
+	for i := 0; i < numRows; i++ {
+		mean, variance, err := ensemble.Predict(mat64.Row(nil, i, testFeatures))
+		if err != nil {
+			return nil, nil, err
+		}
+		scores = append(scores, mean, variance)
+	}
+	results := parallel.Map(numSeeds, numWorkers, func(task int) seedResult {
+		return evaluateSeed(ctx, uint64(task+1), opts, features, labels, testFeatures, testLabels)
+	})
+	for _, result := range results {
+		if result.err != nil {
+			return nil, nil, result.err
+		}
+		seedMetrics = append(seedMetrics, result.accuracy, result.auc)
+	}
+	return scores, seedMetrics, nil
+}
+
+// verifyDeterminism compares the outputs of the parallel paths on a
+// single worker and on several, and returns an error when they differ.
+func verifyDeterminism(ctx context.Context) error {
+	numWorkers := parallel.Workers(*workers)
+	if numWorkers < 2 {
+		numWorkers = 2
+	}
+	serialScores, serialSeeds, err := parallelOutputs(ctx, 1)
+	if err != nil {
+		return err
+	}
+	parallelScores, parallelSeeds, err := parallelOutputs(ctx, numWorkers)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Bootstrap scores on 1 and %d workers identical: %t\n", numWorkers, sameBits(serialScores, parallelScores))
+	fmt.Printf("Stability metrics on 1 and %d workers identical: %t\n", numWorkers, sameBits(serialSeeds, parallelSeeds))
+	if !sameBits(serialScores, parallelScores) || !sameBits(serialSeeds, parallelSeeds) {
+		return errors.New("parallel results depend on the number of workers")
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Federated averaging
+// Before deploying the loan model to lenders who cannot pool their rows,
+// we simulate federated training: the training rows are dealt to a number
+// of virtual clients, every round each client trains the model on its own
+// rows from the current weights, and the weights are averaged (FedAvg).
+// Centralized training on all the rows for the same number of epochs
+// gives the baseline, and the test metrics of both after every round
+// show whether the federated model converges to it.
+
+var (
+	// federatedClients is the number of clients the training rows are
+	// dealt to.
+	federatedClients = flag.Int("federated-clients", 0, "simulate federated averaging across this many clients (0 disables)")
+	// federatedRounds is the number of rounds of federated averaging.
+	federatedRounds = flag.Int("federated-rounds", 20, "number of federated averaging rounds")
+	// federatedEpochs is the number of local epochs of every round.
+	federatedEpochs = flag.Int("federated-epochs", 5, "epochs every client trains for in a round")
+	// federatedSplit selects how the rows are dealt to the clients.
+	federatedSplit = flag.String("federated-split", "iid", "how the rows are dealt to the clients: iid (at random) or skewed (sorted by label, so most clients see a single class)")
+)
+
+// federatedSeed seeds the dealing of the rows and the training.
+const federatedSeed = 44111342
+
+// roundScores holds the test metrics of the federated and the
+// centralized models after a round.
+type roundScores struct {
+	round                  int
+	federated, centralized testScores
+}
+
+// testScores holds the test metrics of a model.
+type testScores struct {
+	logLoss, accuracy, auc float64
+}
+
+// scoreTest returns the test metrics of the weights.
+func scoreTest(weights []float64, features *mat64.Dense, labels []float64) (testScores, error) {
+	var s testScores
+	s.logLoss, s.accuracy = logistic.Evaluate(weights, features, labels, *decisionThreshold)
+	probabilities := make([]float64, len(labels))
+	for i := range labels {
+		probabilities[i] = logistic.Probability(weights, mat64.Row(nil, i, features))
+	}
+	var err error
+	s.auc, err = metrics.AUC(labels, probabilities)
+	return s, err
+}
+
+// dealClients deals the training rows to n clients of nearly equal size,
+// at random or, skewed, sorted by label.
+func dealClients(features *mat64.Dense, labels []float64, n int, mode string, r *rand.Rand) ([]logistic.Client, error) {
+	if n > len(labels) {
+		return nil, fmt.Errorf("%d clients for %d training rows", n, len(labels))
+	}
+	order := r.Perm(len(labels))
+	switch mode {
+	case "iid":
+	case "skewed":
+		sort.SliceStable(order, func(a, b int) bool { return labels[order[a]] < labels[order[b]] })
+	default:
+		return nil, fmt.Errorf("unknown federated split %q, expected iid or skewed", mode)
+	}
+	clients := make([]logistic.Client, n)
+	for k := range clients {
+		rows := order[k*len(order)/n : (k+1)*len(order)/n]
+		x, y := subsetRows(features, labels, rows)
+		clients[k] = logistic.Client{X: x, Y: y}
+	}
+	return clients, nil
+}
+
+// federated compares federated averaging across -federated-clients
+// clients with centralized training, round by round, on the test set. It
+// writes the table and the plot of the test log loss of every round to
+// the run directory and returns the final metrics.
+func federated(ctx context.Context, run *artifacts.Run) (map[string]float64, error) {
+	// Load the training and test data.
+	features, labels, err := readLoanData(files.Data("training.csv"))
+	if err != nil {
+		return nil, err
+	}
+	testFeatures, testLabels, err := readLoanData(files.Data("test.csv"))
+	if err != nil {
+		return nil, err
+	}
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return nil, err
+	}
+	if *federatedRounds < 1 || *federatedEpochs < 1 {
+		return nil, fmt.Errorf("invalid federated training of %d rounds of %d epochs", *federatedRounds, *federatedEpochs)
+	}
+	clients, err := dealClients(features, labels, *federatedClients, *federatedSplit, rand.New(rand.NewSource(federatedSeed)))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]roundScores, *federatedRounds)
+	// Both run every epoch, so that they train for as long.
+	opts.Tolerance = 0
+	// Train centrally for as many epochs, scoring the test set at the end
+	// of every round.
+	central := opts
+	central.Steps = *federatedRounds * *federatedEpochs
+	central.OnEpoch = func(epoch int, weights []float64, _ map[string]float64) error {
+		if round := epoch / *federatedEpochs; epoch%*federatedEpochs == 0 {
+			var err error
+			results[round-1].centralized, err = scoreTest(weights, testFeatures, testLabels)
+			return err
+		}
+		return nil
+	}
+	if _, _, err := logistic.Fit(ctx, features, labels, central, rand.New(rand.NewSource(federatedSeed))); err != nil {
+		return nil, err
+	}
+	// Train the clients and average them every round.
+	local := opts
+	local.Steps = *federatedEpochs
+	_, err = logistic.FitFederated(ctx, clients, *federatedRounds, local, federatedSeed, *workers, func(round int, weights []float64) error {
+		results[round-1].round = round
+		var err error
+		results[round-1].federated, err = scoreTest(weights, testFeatures, testLabels)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Write the metrics of every round to the run directory.
+	columns := []string{"round", "federated_log_loss", "federated_accuracy", "federated_auc", "centralized_log_loss", "centralized_accuracy", "centralized_auc"}
+	rows := make([][]any, len(results))
+	rounds := make([]float64, len(results))
+	losses := [][]float64{make([]float64, len(results)), make([]float64, len(results))}
+	for i, s := range results {
+		rows[i] = []any{s.round, s.federated.logLoss, s.federated.accuracy, s.federated.auc, s.centralized.logLoss, s.centralized.accuracy, s.centralized.auc}
+		rounds[i] = float64(s.round)
+		losses[0][i], losses[1][i] = s.federated.logLoss, s.centralized.logLoss
+	}
+	if err := run.WriteTable("federated", columns, rows); err != nil {
+		return nil, err
+	}
+	if err := plots.Lines(run.PlotPath("federated.png"), "Federated averaging", "Round", "Test log loss", rounds, []string{"federated", "centralized"}, losses); err != nil {
+		return nil, err
+	}
+	// Output the convergence of both to stdout.
+	fmt.Printf("Federated averaging over %d clients (%s), %d rounds of %d epochs\n", len(clients), *federatedSplit, *federatedRounds, *federatedEpochs)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "round\tfederated loss\tAUC\tcentralized loss\tAUC")
+	for _, s := range results {
+		fmt.Fprintf(tw, "%d\t%0.4f\t%0.3f\t%0.4f\t%0.3f\n", s.round, s.federated.logLoss, s.federated.auc, s.centralized.logLoss, s.centralized.auc)
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+	last := results[len(results)-1]
+	fmt.Printf("Final test AUC gap (centralized - federated) = %0.3f\n\n", last.centralized.auc-last.federated.auc)
+	return map[string]float64{
+		"federated_log_loss":   last.federated.logLoss,
+		"federated_accuracy":   last.federated.accuracy,
+		"federated_auc":        last.federated.auc,
+		"centralized_log_loss": last.centralized.logLoss,
+		"centralized_auc":      last.centralized.auc,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/golden"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"golang.org/x/exp/rand"
+)
+
+// Golden predictions
+// The trainer is seeded from the clock, so two runs never produce the same
+// weights. To check that a refactoring keeps its behavior, the golden mode
+// trains with a fixed seed on a small fixture of clean loan rows and
+// compares the predicted probabilities with the ones recorded before the
+// change:
+//
+//	go run . -golden record   # after an intended behavior change
+//	go run . -golden verify   # exits with an error when predictions drift
+
+const (
+	// goldenFixture holds the clean loan rows the golden model is trained
+	// and scored on.
+	goldenFixture = "testdata/loan_fixture.csv"
+	// goldenPredictions holds the recorded predictions of the golden model.
+	goldenPredictions = "testdata/golden_predictions.csv"
+	// goldenSeed seeds the golden model.
+	goldenSeed = 20240611
+)
+
+var (
+	// goldenMode records or verifies the golden predictions instead of
+	// running the loan example.
+	goldenMode = flag.String("golden", "", "record or verify the predictions of a fixed-seed model on the fixture data instead of running the example")
+	// goldenTolerance is the largest difference from a recorded prediction
+	// that verify accepts.
+	goldenTolerance = flag.Float64("golden-tol", 1e-9, "largest absolute difference from a golden prediction accepted by -golden verify")
+)
+
+// goldenCheck trains the model on the fixture with the golden seed, and
+// records its predictions or verifies them against the recorded ones.
+func goldenCheck(ctx context.Context) error {
+	features, labels, err := readLoanData(files.Fixture(goldenFixture))
+	if err != nil {
+		return err
+	}
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return err
+	}
+	r := rand.New(rand.NewSource(goldenSeed))
+	weights, _, err := logistic.Fit(ctx, features, labels, opts, r)
+	if err != nil {
+		return err
+	}
+	predictions := logistic.PredictProba(weights, features)
+	path := files.Fixture(goldenPredictions)
+	switch *goldenMode {
+	case "record":
+		if err := golden.Write(path, predictions); err != nil {
+			return err
+		}
+		fmt.Printf("Recorded %d golden predictions to %s\n", len(predictions), path)
+	case "verify":
+		if err := golden.Verify(path, predictions, *goldenTolerance); err != nil {
+			return err
+		}
+		fmt.Printf("%d predictions match %s\n", len(predictions), path)
+	default:
+		return fmt.Errorf("unknown golden mode %q, want record or verify", *goldenMode)
+	}
+	return nil
+}
@@ -1,23 +1,37 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
-	"time"
 
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/sketch"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tensorboard"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tracking"
+	"github.com/bachhm.dev/go-machine-learning/pkg/transform"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
 	"github.com/go-gota/gota/dataframe"
 	"github.com/gonum/matrix/mat64"
 	"golang.org/x/exp/rand"
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
 )
 
 // 1. Remove non-numerical characters from the interest rate and FICO score
@@ -39,91 +53,451 @@ import (
 // running the same example with non-normalized data, there are convergence
 // issues
 
-const (
-	scoreMax = 830.0
-	scoreMin = 640.0
-)
+// rateThreshold is the highest interest rate, in percent, of the good
+// credit class (1.0).
+var rateThreshold = flag.Float64("rate-threshold", 12.0, "highest interest rate in percent labeled as good credit")
 
-func main() {
-	dataProfiling()
-	savePlotPng()
-	splitData()
-	train()
-	test()
+// scoreMin and scoreMax are the FICO scores mapped to 0 and 1 by the
+// normalization. Bounds that are not set are taken from the training rows.
+var scoreMin, scoreMax optionalFloat
+
+// imputeStrategy fills the missing FICO scores.
+var imputeStrategy = flag.String("impute", "median", "how the missing FICO scores are filled from the training rows: mean, median, mode or constant=<value>")
+
+func init() {
+	flag.Var(&scoreMin, "score-min", "FICO score normalized to 0 (default: the lowest score of the training rows)")
+	flag.Var(&scoreMax, "score-max", "FICO score normalized to 1 (default: the highest score of the training rows)")
+}
+
+// optionalFloat is a float flag that records whether it was set.
+type optionalFloat struct {
+	value float64
+	set   bool
+}
+
+func (o *optionalFloat) String() string {
+	if o == nil || !o.set {
+		return ""
+	}
+	return strconv.FormatFloat(o.value, 'g', -1, 64)
 }
 
-func dataProfiling() {
-	// Open the loan dataset file.
-	f, err := os.Open("../dataset/loan_data.csv")
+func (o *optionalFloat) Set(s string) error {
+	v, err := strconv.ParseFloat(s, 64)
 	if err != nil {
+		return err
+	}
+	o.value, o.set = v, true
+	return nil
+}
+
+// or returns the flag value when set and def otherwise.
+func (o *optionalFloat) or(def float64) float64 {
+	if o.set {
+		return o.value
+	}
+	return def
+}
+
+// testFraction is the share of the rows held out for testing.
+const testFraction = 0.2
+
+// splitSeed seeds the shuffling of the stratified split.
+const splitSeed = 44111342
+
+// rawLoanData is the raw loan data, or the sample of it used by the run.
+var rawLoanData string
+
+// splitMode selects how splitData divides the rows.
+var splitMode = flag.String("split", "sequential", "how to split the loan data: sequential or stratified")
+
+// labelColumn is the header of the class column of the clean loan data.
+const labelColumn = "int.rate"
+
+// featureNames lists the clean loan data columns used as features.
+var featureNames = flag.String("features", "fico", "comma-separated header names of the feature columns")
+
+// lambda is the strength of the L2 weight decay applied while training.
+var lambda = flag.Float64("lambda", 0, "L2 regularization strength of the logistic regression weights")
+
+// tensorboardDir is the subdirectory of the run holding the TensorBoard
+// event files.
+const tensorboardDir = "tensorboard"
+
+// tensorboardLogs writes the training curves as TensorBoard event files.
+var tensorboardLogs = flag.Bool("tensorboard", false, "write TensorBoard event files of the training curves to the run directory")
+
+// files locates the datasets and the runs of the example.
+var files = workspace.Register(flag.CommandLine, "logistic-regression", "../dataset")
+
+// restoreBest enables per-epoch validation tracking in train().
+var restoreBest = flag.Bool("restore-best", false, "track a validation split every epoch and restore the best weights")
+
+func main() {
+	if err := experiment.Parse(flag.CommandLine, os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
-	defer f.Close()
-	// Create a new CSV reader reading from the opened file.
-	reader := csv.NewReader(f)
-	reader.FieldsPerRecord = 2
-	// Read in all of the CSV records
-	rawCSVData, err := reader.ReadAll()
-	if err != nil {
+	// Stop training on an interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := runExample(ctx); err != nil {
 		log.Fatal(err)
 	}
-	// Create the output file.
-	f, err = os.Create("../dataset/clean_loan_data.csv")
+}
+
+// runExample runs the mode selected by the flags, the whole pipeline by
+// default, returning the first error met.
+func runExample(ctx context.Context) error {
+	// Record or verify the golden predictions instead, when requested.
+	if *goldenMode != "" {
+		return goldenCheck(ctx)
+	}
+	// Compare the model with scikit-learn instead, when requested.
+	if *parityMode {
+		return checkParity(ctx)
+	}
+	// Check that the parallel paths are deterministic instead, when
+	// requested.
+	if *checkDeterminism {
+		return verifyDeterminism(ctx)
+	}
+	// Create the artifact directory of this run.
+	run, err := files.NewRun()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer f.Close()
-	// Create a CSV writer.
-	w := csv.NewWriter(f)
-	// Sequentially move the rows writing out the parsed values.
-	for idx, record := range rawCSVData {
-		// Skip the header row.
-		if idx == 0 {
-			// Write the header to the output file.
-			if err := w.Write(record); err != nil {
-				log.Fatal(err)
-			}
-			continue
+	// Stream the metrics to the webhook sink, when one is configured.
+	sink := tracking.SinkFromEnv(filepath.Base(run.Dir))
+	// Classify a multiclass dataset one-vs-rest instead, when requested.
+	if *multiclassPath != "" {
+		metrics, err := multiclass(ctx, run)
+		if err != nil {
+			return err
+		}
+		if err := run.WriteMetrics(metrics); err != nil {
+			return err
 		}
-		// Initialize a slice to hold our parsed values.
-		outRecord := make([]string, 2)
-		// Parse and standardize the FICO score.
-		score, err := strconv.ParseFloat(strings.Split(record[0], "-")[0], 64)
+		if err := finishSink(sink, metrics); err != nil {
+			return err
+		}
+		fmt.Printf("Artifacts saved to %s\n", run.Dir)
+		return nil
+	}
+	// Record the memory used by every stage of the run.
+	tracker, err := newMemoryTracker()
+	if err != nil {
+		return err
+	}
+	tracker.Begin("load")
+	if rawLoanData, err = sampleData(run, files.Data("loan_data.csv")); err != nil {
+		return err
+	}
+	imputer, scaler, testIdx, err := dataProfiling()
+	if err != nil {
+		return err
+	}
+	minScore, maxScore := scaler.Min[0], scaler.Max[0]
+	if err := savePlotPng(run); err != nil {
+		return err
+	}
+	tracker.Begin("preprocess")
+	if err := splitData(testIdx); err != nil {
+		return err
+	}
+	if err := exportARFF(run); err != nil {
+		return err
+	}
+	if err := rankFeatures(run); err != nil {
+		return err
+	}
+	tracker.Begin("train")
+	weights, err := trainOrLoad(ctx, run, sink, imputer.Values[0], minScore, maxScore)
+	if err != nil {
+		return err
+	}
+	tracker.Begin("evaluate")
+	metrics, err := test(run, weights)
+	if err != nil {
+		return err
+	}
+	if metrics["best_threshold"], metrics["best_threshold_f1"], err = thresholdSweep(run, weights); err != nil {
+		return err
+	}
+	if metrics["bootstrap_mean_variance"], err = bootstrapScores(ctx, run); err != nil {
+		return err
+	}
+	if metrics["conformal_coverage"], metrics["conformal_mean_set_size"], err = conformalSets(ctx); err != nil {
+		return err
+	}
+	if err := scorecard(ctx, run); err != nil {
+		return err
+	}
+	stabilityMetrics, err := stability(ctx, run)
+	if err != nil {
+		return err
+	}
+	for name, value := range stabilityMetrics {
+		metrics[name] = value
+	}
+	// Simulate federated training, when requested.
+	if *federatedClients > 0 {
+		federatedMetrics, err := federated(ctx, run)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		for name, value := range federatedMetrics {
+			metrics[name] = value
 		}
-		outRecord[0] = strconv.FormatFloat((score-scoreMin)/(scoreMax-scoreMin), 'f', 4, 64)
-		// Parse the Interest rate class.
-		rate, err := strconv.ParseFloat(strings.TrimSuffix(record[1], "%"), 64)
+	}
+	memoryMetrics, err := writeMemory(run, tracker)
+	if err != nil {
+		return err
+	}
+	for name, value := range memoryMetrics {
+		metrics[name] = value
+	}
+	// Record the metrics and the configuration of the run.
+	if err := run.WriteMetrics(metrics); err != nil {
+		return err
+	}
+	config := map[string]any{
+		"impute":               *imputeStrategy,
+		"score_fill":           imputer.Values[0],
+		"score_min":            minScore,
+		"score_max":            maxScore,
+		"rate_threshold":       *rateThreshold,
+		"resample":             *resampleMethod,
+		"resample_ratio":       *resampleRatio,
+		"smote_neighbors":      *smoteNeighbors,
+		"class_weight":         *classWeight,
+		"threshold":            *decisionThreshold,
+		"test_fraction":        testFraction,
+		"split":                *splitMode,
+		"num_steps":            *numSteps,
+		"seed":                 *trainSeed,
+		"learning_rate":        *learningRate,
+		"lambda":               *lambda,
+		"batch_mode":           *batchMode,
+		"batch_size":           *batchSize,
+		"shuffle":              *shuffleEpochs,
+		"tol":                  *tolerance,
+		"patience":             *patience,
+		"tensorboard":          *tensorboardLogs,
+		"optimizer":            *optimizerName,
+		"lr_schedule":          *lrSchedule,
+		"lr_decay":             *lrDecay,
+		"num_replicas":         numReplicas,
+		"workers":              parallel.Workers(*workers),
+		"bootstrap_seed":       bootstrapSeed,
+		"conformal_alpha":      conformalAlpha,
+		"calibration_fraction": calibrationFraction,
+		"num_score_bins":       numScoreBins,
+		"base_score":           baseScore,
+		"base_odds":            baseOdds,
+		"pdo":                  pdo,
+		"num_seeds":            numSeeds,
+		"restore_best":         *restoreBest,
+		"validation_fraction":  validationFraction,
+		"memory_budget":        *memoryBudget,
+		"model":                *modelPath,
+		"sample":               *sampleSize,
+		"sample_by":            *sampleBy,
+		"sample_weight":        *sampleWeight,
+		"dp_epsilon":           *dpEpsilon,
+		"dp_delta":             *dpDelta,
+		"dp_clip":              *dpClip,
+		"federated_clients":    *federatedClients,
+		"federated_rounds":     *federatedRounds,
+		"federated_epochs":     *federatedEpochs,
+		"federated_split":      *federatedSplit,
+		"rank_criterion":       *rankCriterion,
+		"rank_bins":            *rankBins,
+	}
+	if err := run.WriteConfig(config); err != nil {
+		return err
+	}
+	if err := experiment.Write(run.Path(experiment.File), flag.CommandLine); err != nil {
+		return err
+	}
+	if err := finishSink(sink, metrics); err != nil {
+		return err
+	}
+	fmt.Printf("Artifacts saved to %s\n", run.Dir)
+	// Log the run to the MLflow tracking server, when one is configured.
+	if mlflow, ok := tracking.FromEnv(); ok {
+		runID, err := mlflow.LogRun("logistic-regression", filepath.Base(run.Dir), config, metrics, run.Dir)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		if rate <= 12.0 {
-			outRecord[1] = "1.0"
-			// Write the record to the output file.
-			if err := w.Write(outRecord); err != nil {
-				log.Fatal(err)
-			}
-			continue
+		fmt.Printf("Logged MLflow run %s\n", runID)
+	}
+	return nil
+}
+
+// parseLoanRecord parses a record of the raw loan data: the FICO score,
+// keeping the minimum of a range, and the interest rate in percent.
+// Missing values, such as blanks and N/A, are NaN.
+func parseLoanRecord(record []string) (score, rate float64, err error) {
+	if len(record) != 2 {
+		return 0, 0, fmt.Errorf("%d fields, expected the FICO score and the interest rate", len(record))
+	}
+	if score, err = transform.ParseFloat(strings.Split(record[0], "-")[0]); err != nil {
+		return 0, 0, err
+	}
+	if rate, err = transform.ParseFloat(strings.TrimSuffix(record[1], "%")); err != nil {
+		return 0, 0, err
+	}
+	return score, rate, nil
+}
+
+// dataProfiling writes the clean loan data: the FICO scores normalized
+// to [0, 1] and the interest rates turned into classes. It chooses the
+// rows held out as the test set and fits the imputation of the missing
+// scores and their min-max scaler on the other rows only, so that the
+// test rows are prepared as new loans would be, and returns the imputation
+// and the scaler with the test rows. Rows without an interest rate have
+// no class and are left out. The raw data is streamed twice, once to
+// profile it, keeping only the score and class of every row, and once to
+// write the clean rows.
+func dataProfiling() (*transform.Impute, *transform.MinMax, []int, error) {
+	// Profile the raw scores as a categorical column with sketches, whose
+	// memory does not grow with the number of distinct values.
+	distinct, err := sketch.NewHyperLogLog(0)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	frequent := sketch.NewHeavyHitters(5, 1e-3, 1e-3)
+	var scores []float64
+	var classes []int
+	// unlabeled holds the rows without an interest rate.
+	unlabeled := make(map[int]bool)
+	err = dataset.EachRecord(rawLoanData, nil, func(row int, record []string) error {
+		score, rate, err := parseLoanRecord(record)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %v", rawLoanData, row, err)
 		}
-		outRecord[1] = "0.0"
-		// Write the record to the output file.
-		if err := w.Write(outRecord); err != nil {
-			log.Fatal(err)
+		if math.IsNaN(rate) {
+			unlabeled[row] = true
+			return nil
+		}
+		distinct.Add(record[0])
+		frequent.Add(record[0])
+		scores = append(scores, score)
+		classes = append(classes, rateClass(rate))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if frequent.Total() == 0 {
+		return nil, nil, nil, fmt.Errorf("%s has no rows below the header", rawLoanData)
+	}
+	// Print the number of distinct scores and the most frequent ones.
+	fmt.Printf("FICO scores: %d rows, about %.0f distinct\n", frequent.Total(), distinct.Estimate())
+	for _, item := range frequent.Top() {
+		fmt.Printf("%-20s count = %d\n", item.Key, item.Count)
+	}
+	fmt.Println()
+	testIdx, err := holdOut(classes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Fill the missing scores and fit the scaler on the training rows,
+	// then take the bounds set by the flags instead.
+	test := make([]bool, len(scores))
+	for _, i := range testIdx {
+		test[i] = true
+	}
+	var training []float64
+	for i, score := range scores {
+		if !test[i] {
+			training = append(training, score)
 		}
 	}
-	// Write any buffered data to the underlying writer (standard output).
+	strategy, constant, err := transform.ParseStrategy(*imputeStrategy)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	imputer := &transform.Impute{Strategies: []transform.Strategy{strategy}, Constants: []float64{constant}}
+	if err := imputer.Fit(mat64.NewDense(len(training), 1, training)); err != nil {
+		return nil, nil, nil, err
+	}
+	filled, err := imputer.Transform(mat64.NewDense(len(training), 1, training))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	scaler := &transform.MinMax{}
+	if err := scaler.Fit(filled); err != nil {
+		return nil, nil, nil, err
+	}
+	scaler.Min[0], scaler.Max[0] = scoreMin.or(scaler.Min[0]), scoreMax.or(scaler.Max[0])
+	if scaler.Max[0] <= scaler.Min[0] {
+		return nil, nil, nil, fmt.Errorf("invalid FICO bounds: min %v is not below max %v", scaler.Min[0], scaler.Max[0])
+	}
+	filled, err = imputer.Transform(mat64.NewDense(len(scores), 1, scores))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	normalized, err := scaler.Transform(filled)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var numMissing int
+	for _, score := range scores {
+		if math.IsNaN(score) {
+			numMissing++
+		}
+	}
+	if numMissing > 0 || len(unlabeled) > 0 {
+		fmt.Printf("Filled %d missing FICO scores with %v, the %s of the training rows, and left out %d rows without an interest rate\n\n", numMissing, imputer.Values[0], strategy, len(unlabeled))
+	}
+	// Create the output file.
+	f, err := os.Create(files.Data("clean_loan_data.csv"))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+	// Create a CSV writer.
+	w := csv.NewWriter(f)
+	// Sequentially move the rows writing out the normalized scores and
+	// the classes, after the header.
+	outRecord := make([]string, 2)
+	i := 0
+	err = dataset.EachRecord(rawLoanData, w.Write, func(row int, record []string) error {
+		if unlabeled[row] {
+			return nil
+		}
+		outRecord[0] = strconv.FormatFloat(normalized.At(i, 0), 'f', 4, 64)
+		outRecord[1] = strconv.FormatFloat(float64(classes[i]), 'f', 1, 64)
+		i++
+		// Write the record to the output file.
+		return w.Write(outRecord)
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Write any buffered data to the underlying file.
 	w.Flush()
 	if err := w.Error(); err != nil {
-		log.Fatal(err)
+		return nil, nil, nil, err
 	}
+	return imputer, scaler, testIdx, f.Close()
 }
 
-func savePlotPng() {
+// rateClass returns the class of an interest rate: 1 for the rates of
+// good credit, up to -rate-threshold, and 0 for the others.
+func rateClass(rate float64) int {
+	if rate <= *rateThreshold {
+		return 1
+	}
+	return 0
+}
+
+func savePlotPng(run *artifacts.Run) error {
 	// Open the CSV file.
-	loanDataFile, err := os.Open("../dataset/clean_loan_data.csv")
+	loanDataFile, err := os.Open(files.Data("clean_loan_data.csv"))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer loanDataFile.Close()
 	// Create a dataframe from the CSV file.
@@ -132,244 +506,346 @@ func savePlotPng() {
 	// for all of the columns in one shot.
 	loanSummary := loanDF.Describe()
 	// Output the summary statistics to stdout.
-	fmt.Println(loanSummary) // Create a histogram for each of the columns in the dataset.
+	fmt.Println(loanSummary)
+	// Create a histogram for each of the columns in the dataset.
 	for _, colName := range loanDF.Names() {
-		// Create a plotter.Values value and fill it with the
-		// values from the respective column of the dataframe.
-		plotVals := make(plotter.Values, loanDF.Nrow())
-		for i, floatVal := range loanDF.Col(colName).Float() {
-			plotVals[i] = floatVal
-		}
-		// Make a plot and set its title.
-		p := plot.New()
-		p.Title.Text = fmt.Sprintf("Histogram of a %s", colName)
-		// Create a histogram of our values.
-		h, err := plotter.NewHist(plotVals, 16)
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Normalize the histogram.
-		h.Normalize(1)
-		// Add the histogram to the plot.
-		p.Add(h)
-		// Save the plot to a PNG file.
-		if err := p.Save(4*vg.Inch, 4*vg.Inch, colName+"_hist.png"); err != nil {
-			log.Fatal(err)
+		title := fmt.Sprintf("Histogram of a %s", colName)
+		if err := plots.Histogram(run.PlotPath(colName+"_hist.png"), title, loanDF.Col(colName).Float(), 16); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func splitData() {
-	// Open the clean loan dataset file.
-	f, err := os.Open("../dataset/clean_loan_data.csv")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer f.Close()
-	// Create a dataframe from the CSV file.
-	// The types of the columns will be inferred.
-	loanDF := dataframe.ReadCSV(f)
-	// Calculate the number of elements in each set.
-	trainingNum := (4 * loanDF.Nrow()) / 5
-	testNum := loanDF.Nrow() / 5
-	if trainingNum+testNum < loanDF.Nrow() {
-		trainingNum++
-	}
-	// Create the subset indices.
-	trainingIdx := make([]int, trainingNum)
-	testIdx := make([]int, testNum)
-	// Enumerate the training indices.
-	for i := 0; i < trainingNum; i++ {
-		trainingIdx[i] = i
-	}
-	// Enumerate the test indices.
-	for i := 0; i < testNum; i++ {
-		testIdx[i] = trainingNum + i
-	}
-	// Create the subset dataframes.
-	trainingDF := loanDF.Subset(trainingIdx)
-	testDF := loanDF.Subset(testIdx)
-	// Create a map that will be used in writing the data
-	// to files.
-	setMap := map[int]dataframe.DataFrame{
-		0: trainingDF,
-		1: testDF,
-	}
-	// Create the respective files.
-	for idx, setName := range []string{"../dataset/training.csv", "test.csv"} {
-		// Save the filtered dataset file.
-		f, err := os.Create(setName)
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Create a buffered writer.
-		w := bufio.NewWriter(f)
-		// Write the dataframe out as a CSV.
-		if err := setMap[idx].WriteCSV(w); err != nil {
-			log.Fatal(err)
-		}
+// holdOut returns the rows held out as the test set, given the class of
+// every row: the last rows, or a stratified sample keeping the class
+// proportions of both sets, as -split selects.
+func holdOut(classes []int) ([]int, error) {
+	// Hold out 20% of the rows as the test set.
+	switch *splitMode {
+	case "sequential":
+		// Take the last rows as the test set.
+		_, testIdx := split.TrainTest(len(classes), split.Config{TestFraction: testFraction})
+		return testIdx, nil
+	case "stratified":
+		// Keep the class proportions equal in both sets.
+		_, testIdx := split.StratifiedTrainTest(classes, split.Config{
+			TestFraction: testFraction,
+			Shuffle:      true,
+			Seed:         splitSeed,
+		})
+		return testIdx, nil
 	}
+	return nil, fmt.Errorf("unknown split mode %q, expected sequential or stratified", *splitMode)
 }
 
-func train() {
-	// Open the training dataset file.
-	f, err := os.Open("../dataset/training.csv")
+// splitData splits the clean loan data into the training and test files,
+// holding out the given rows as the test set.
+func splitData(testIdx []int) error {
+	return split.SplitCSV(files.Data("clean_loan_data.csv"), files.Data("training.csv"), files.Data("test.csv"), testIdx)
+}
+
+func train(ctx context.Context, run *artifacts.Run, sink tracking.Sink) (weights []float64, err error) {
+	// Load the training features and labels.
+	features, labels, err := readLoanData(files.Data("training.csv"))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer f.Close()
-	// Create a new CSV reader reading from the opened file.
-	reader := csv.NewReader(f)
-	reader.FieldsPerRecord = 2
-	// Read in all of the CSV records
-	rawCSVData, err := reader.ReadAll()
+	// Train the logistic regression model.
+	r := rand.New(rand.NewSource(*trainSeed))
+	opts, err := flagTrainOptions()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	// featureData and labels will hold all the float values that
-	// will eventually be used in our training.
-	featureData := make([]float64, 2*len(rawCSVData))
-	labels := make([]float64, len(rawCSVData))
-	// featureIndex will track the current index of the features
-	// matrix values.
-	var featureIndex int
-	// Sequentially move the rows into the slices of floats.
-	for idx, record := range rawCSVData {
-		// Skip the header row.
-		if idx == 0 {
-			continue
+	// Stream the metrics of every epoch to the sink.
+	hooks := []epochHook{sinkHook(sink)}
+	// Stream the training curves to TensorBoard, when requested.
+	if *tensorboardLogs {
+		tw, err := tensorboard.NewWriter(run.Path(tensorboardDir))
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if cerr := tw.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}()
+		hooks = append(hooks, tensorboardHook(tw))
+	}
+	opts.OnEpoch = chainHooks(hooks...)
+	var summary logistic.Summary
+	if *restoreBest {
+		// Hold out part of the training rows, track them after every
+		// epoch and keep the best weights seen.
+		perm := r.Perm(len(labels))
+		numValidation := int(float64(len(labels)) * validationFraction)
+		valFeatures, valLabels := subsetRows(features, labels, perm[:numValidation])
+		fitFeatures, fitLabels := subsetRows(features, labels, perm[numValidation:])
+		// Rebalance the rows fitted on only, after holding out the
+		// validation rows.
+		if fitFeatures, fitLabels, err = resampleTraining(fitFeatures, fitLabels, r); err != nil {
+			return nil, err
 		}
-		// Add the FICO score feature.
-		featureVal, err := strconv.ParseFloat(record[0], 64)
+		if err := weighClasses(&opts, fitLabels); err != nil {
+			return nil, err
+		}
+		var history []logistic.Epoch
+		weights, history, summary, err = logistic.FitBest(ctx, fitFeatures, fitLabels, valFeatures, valLabels, opts, r)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		featureData[featureIndex] = featureVal
-		// Add an intercept.
-		featureData[featureIndex+1] = 1.0
-		// Increment our feature row.
-		featureIndex += 2
-		// Add the class label.
-		labelVal, err := strconv.ParseFloat(record[1], 64)
+		best := logistic.BestEpoch(history)
+		fmt.Printf("\nBest validation epoch = %d of %d (log loss = %0.4f, accuracy = %0.2f)\n",
+			best.Epoch, len(history), best.LogLoss, best.Accuracy)
+	} else {
+		if features, labels, err = resampleTraining(features, labels, r); err != nil {
+			return nil, err
+		}
+		if err := weighClasses(&opts, labels); err != nil {
+			return nil, err
+		}
+		weights, summary, err = logistic.Fit(ctx, features, labels, opts, r)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		labels[idx] = labelVal
 	}
-	// Form a matrix from the features.
-	features := mat64.NewDense(len(rawCSVData), 2, featureData)
-	// Train the logistic regression model.
-	weights := logisticRegression(features, labels, 100, 0.3) // Output the Logistic Regression model formula to stdout.
-	formula := "p = 1 / ( 1 + exp(- m1 * FICO.score - m2) )"
-	fmt.Printf("\n%s\n\nm1 = %0.2f\nm2 = %0.2f\n\n", formula, weights[0], weights[1])
+	printFitSummary(summary, opts)
+	if err := saveLossCurve(run, summary); err != nil {
+		return nil, err
+	}
+	// Output the Logistic Regression model formula to stdout.
+	columns := featureColumns()
+	formula := "p = 1 / ( 1 + exp("
+	for j, name := range columns {
+		formula += fmt.Sprintf(" - m%d * %s", j+1, name)
+	}
+	formula += fmt.Sprintf(" - m%d ) )", len(columns)+1)
+	fmt.Printf("\n%s\n\n", formula)
+	for j, w := range weights {
+		fmt.Printf("m%d = %0.2f\n", j+1, w)
+	}
+	fmt.Println()
+	return weights, nil
+}
+
+// printFitSummary outputs the number of epochs run and the final
+// training loss to stdout.
+func printFitSummary(summary logistic.Summary, opts logistic.Options) {
+	status := "ran every epoch"
+	if summary.Converged {
+		status = "stopped early"
+	}
+	fmt.Printf("\nEpochs = %d of %d (%s), training log loss = %0.4f\n",
+		summary.Iterations, opts.Steps, status, summary.Loss)
+	if opts.Privacy != nil {
+		fmt.Printf("Differential privacy: ε = %0.3f for δ = %g\n", summary.Epsilon, opts.Privacy.Delta)
+	}
+}
+
+// epochHook is called after every training epoch with the epoch number,
+// the current weights and the metrics of the epoch.
+type epochHook = logistic.EpochHook
+
+// chainHooks returns an epoch hook calling every hook in turn, stopping
+// at the first error.
+func chainHooks(hooks ...epochHook) epochHook {
+	return func(epoch int, weights []float64, scalars map[string]float64) error {
+		for _, hook := range hooks {
+			if err := hook(epoch, weights, scalars); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 }
 
-// logistic implements the logistic function, which
-// is used in logistic regression.
-func logistic(x float64) float64 {
-	return 1 / (1 + math.Exp(-x))
+// sinkHook returns an epoch hook sending the metrics of every epoch to
+// the sink.
+func sinkHook(sink tracking.Sink) epochHook {
+	return func(epoch int, _ []float64, scalars map[string]float64) error {
+		return sink.LogStep(epoch, scalars)
+	}
 }
 
-// logisticRegression fits a logistic regression model
-// for the given data.
-func logisticRegression(features *mat64.Dense, labels []float64, numSteps int, learningRate float64) []float64 {
-	// Initialize random weights.
-	_, numWeights := features.Dims()
-	weights := make([]float64, numWeights)
-	s := rand.NewSource(uint64(time.Now().UnixNano()))
-	r := rand.New(s)
-	for idx, _ := range weights {
-		weights[idx] = r.Float64()
-	}
-	// Iteratively optimize the weights.
-	for i := 0; i < numSteps; i++ {
-		// Initialize a variable to accumulate error for this iteration.
-		var sumError float64
-		// Make predictions for each label and accumulate error.
-		for idx, label := range labels {
-			// Get the features corresponding to this label.
-			featureRow := mat64.Row(nil, idx, features)
-			// Calculate the error for this iteration's weights.
-			pred := logistic(featureRow[0] * weights[0] * featureRow[1] * weights[1])
-			predError := label - pred
-			sumError += math.Pow(predError, 2)
-			// Update the feature weights.
-			for j := 0; j < len(featureRow); j++ {
-				weights[j] += learningRate * predError * pred * (1 - pred) * featureRow[j]
+// finishSink sends the final metrics of the run to the sink and closes it.
+func finishSink(sink tracking.Sink, metrics map[string]float64) error {
+	if err := sink.LogFinal(metrics); err != nil {
+		return err
+	}
+	return sink.Close()
+}
+
+// tensorboardHook returns an epoch hook writing the metrics of every
+// epoch as scalars and the weights as a histogram.
+func tensorboardHook(tw *tensorboard.Writer) epochHook {
+	return func(epoch int, weights []float64, scalars map[string]float64) error {
+		tags := make([]string, 0, len(scalars))
+		for tag := range scalars {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			if err := tw.Scalar(tag, epoch, scalars[tag]); err != nil {
+				return err
 			}
 		}
+		if err := tw.Histogram("weights", epoch, weights); err != nil {
+			return err
+		}
+		return tw.Flush()
 	}
-	return weights
+}
 
+// saveLossCurve plots the training log loss of every epoch to
+// loss_curve.png in the run directory.
+func saveLossCurve(run *artifacts.Run, summary logistic.Summary) error {
+	epochs := make([]float64, len(summary.History))
+	for i := range epochs {
+		epochs[i] = float64(i + 1)
+	}
+	return plots.Line(run.PlotPath("loss_curve.png"), "Training loss", "Epoch", "Log loss", epochs, summary.History)
+}
+
+// featureColumns returns the header names of the feature columns
+// selected with the -features flag, which readLoanData requires to be
+// non-empty.
+func featureColumns() []string {
+	var columns []string
+	for _, name := range strings.Split(*featureNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// readLoanData reads a clean loan CSV file into a feature matrix, holding
+// the columns selected by featureColumns followed by an intercept column,
+// and the class labels. The records are streamed, so only the parsed
+// values are held in memory, never the text of the file.
+func readLoanData(path string) (*mat64.Dense, []float64, error) {
+	columns := featureColumns()
+	if len(columns) == 0 {
+		return nil, nil, errors.New("no feature columns selected")
+	}
+	featureIdx := make([]int, len(columns))
+	var labelIdx int
+	// Look up the position of every selected column in the header row.
+	lookup := func(names []string) error {
+		for j, name := range columns {
+			if featureIdx[j] = slices.Index(names, name); featureIdx[j] < 0 {
+				return fmt.Errorf("%s has no column %q", path, name)
+			}
+		}
+		if labelIdx = slices.Index(names, labelColumn); labelIdx < 0 {
+			return fmt.Errorf("%s has no column %q", path, labelColumn)
+		}
+		return nil
+	}
+	// featureData and labels will hold all the float values that
+	// will eventually be used in our training.
+	var featureData, labels []float64
+	// Sequentially move the rows into the slices of floats.
+	err := dataset.EachRecord(path, lookup, func(row int, record []string) error {
+		// Add the selected features.
+		for _, i := range featureIdx {
+			featureVal, err := strconv.ParseFloat(record[i], 64)
+			if err != nil {
+				return fmt.Errorf("%s: row %d: %v", path, row, err)
+			}
+			featureData = append(featureData, featureVal)
+		}
+		// Add an intercept.
+		featureData = append(featureData, 1.0)
+		// Add the class label.
+		labelVal, err := strconv.ParseFloat(record[labelIdx], 64)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %v", path, row, err)
+		}
+		labels = append(labels, labelVal)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(labels) == 0 {
+		return nil, nil, fmt.Errorf("%s has no rows below the header", path)
+	}
+	// Form a matrix from the features.
+	return mat64.NewDense(len(labels), len(columns)+1, featureData), labels, nil
 }
 
 // predict makes a prediction based on our
 // trained logistic regression model.
-func predict(score float64) float64 {
+func predict(weights, featureRow []float64) float64 {
 	// Calculate the predicted probability.
-	p := 1 / (1 + math.Exp(-13.65*score+4.89))
+	p := logistic.Probability(weights, featureRow)
 	// Output the corresponding class.
-	if p >= 0.5 {
+	if p >= *decisionThreshold {
 		return 1.0
 	}
 	return 0.0
 }
 
-func test() {
-	// Open the test examples.
-	f, err := os.Open("../dataset/test.csv")
+func test(run *artifacts.Run, weights []float64) (map[string]float64, error) {
+	// Load the test examples.
+	features, observed, err := readLoanData(files.Data("test.csv"))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer f.Close()
-	// Create a new CSV reader reading from the opened file.
-	reader := csv.NewReader(f) // observed and predicted will hold the parsed observed and predicted values
-	// form the labeled data file.
-	var observed []float64
-	var predicted []float64
-	// line will track row numbers for logging.
-	line := 1
-	// Read in the records looking for unexpected types in the columns.
-	for {
-		// Read in a row. Check if we are at the end of the file.
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		// Skip the header.
-		if line == 1 {
-			line++
-			continue
-		}
-		// Read in the observed value.
-		observedVal, err := strconv.ParseFloat(record[1], 64)
-		if err != nil {
-			log.Printf("Parsing line %d failed, unexpected type\n", line)
-			continue
-		}
-		// Make the corresponding prediction.
-		score, err := strconv.ParseFloat(record[0], 64)
-		if err != nil {
-			log.Printf("Parsing line %d failed, unexpected type\n", line)
-			continue
-		}
-		predictedVal := predict(score)
-		// Append the record to our slice, if it has the expected type.
-		observed = append(observed, observedVal)
-		predicted = append(predicted, predictedVal)
-		line++
-	}
-	// This variable will hold our count of true positive and
-	// true negative values.
-	var truePosNeg int
-	// Accumulate the true positive/negative count.
-	for idx, oVal := range observed {
-		if oVal == predicted[idx] {
-			truePosNeg++
-		}
+	// predicted and probabilities will hold the predicted classes and
+	// probabilities of the test examples.
+	probabilities := logistic.PredictProba(weights, features)
+	predicted := make([]float64, len(observed))
+	for idx := range observed {
+		predicted[idx] = predict(weights, mat64.Row(nil, idx, features))
 	}
+	// Count the true and false positives and negatives.
+	counts := metrics.BinaryCounts(observed, predicted)
 	// Calculate the accuracy (subset accuracy).
-	accuracy := float64(truePosNeg) / float64(len(observed))
+	accuracy := counts.Accuracy()
 	// Output the Accuracy value to standard out.
 	fmt.Printf("\nAccuracy = %0.2f\n\n", accuracy)
+	// Output the scores of the positive class, and the balanced accuracy
+	// which is not inflated by the majority class.
+	fmt.Printf("Precision = %0.2f\nRecall = %0.2f\nF1 = %0.2f\nSpecificity = %0.2f\nBalanced accuracy = %0.2f\n\n",
+		counts.Precision(), counts.Recall(), counts.F1(), counts.Specificity(), counts.BalancedAccuracy())
+	// Print the confusion matrix and save it as a table and a heat map.
+	if err := metrics.WriteConfusion(os.Stdout, metrics.BinaryClasses, counts.Counts()); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := run.WriteTable("confusion_matrix", metrics.ConfusionColumns(metrics.BinaryClasses), metrics.ConfusionRows(metrics.BinaryClasses, counts.Counts())); err != nil {
+		return nil, err
+	}
+	if err := plots.ConfusionMatrix(run.PlotPath("confusion_matrix.png"), metrics.BinaryClasses, counts.Counts()); err != nil {
+		return nil, err
+	}
+	// Output the credit scoring metrics, which rank the predicted
+	// probabilities rather than the thresholded classes.
+	ks, err := metrics.KS(observed, probabilities)
+	if err != nil {
+		return nil, err
+	}
+	gini, err := metrics.Gini(observed, probabilities)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("KS = %0.2f\nGini = %0.2f\n", ks, gini)
+	// Output the area under the ROC curve and save the curve.
+	auc, err := metrics.AUC(observed, probabilities)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("AUC = %0.2f\n\n", auc)
+	curve, err := metrics.ROC(observed, probabilities)
+	if err != nil {
+		return nil, err
+	}
+	if err := plots.ROC(run.PlotPath("roc.png"), curve.FPR, curve.TPR, auc); err != nil {
+		return nil, err
+	}
+	scores := counts.Map()
+	scores["ks"], scores["gini"], scores["auc"] = ks, gini, auc
+	return scores, nil
 }
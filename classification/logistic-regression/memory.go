@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/memory"
+)
+
+// Memory usage
+// Every stage of the run (load, preprocess, train, evaluate) records the
+// bytes it allocated and the peak memory of the process into the memory
+// table of the run. With a budget, the process stops with an error naming
+// the stage once its memory exceeds the budget, before the system runs
+// out of memory and kills it.
+
+// memoryBudget is the most memory the run may use, such as 512MiB or 2GB.
+var memoryBudget = flag.String("memory-budget", "", "abort the run once its memory exceeds this size, such as 512MiB or 2GB (default: no budget)")
+
+// newMemoryTracker returns the tracker of the stages of the run, enforcing
+// the memory budget.
+func newMemoryTracker() (*memory.Tracker, error) {
+	budget, err := memory.ParseSize(*memoryBudget)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewTracker(budget, func(err error) {
+		log.Fatalf("%v; raise -memory-budget or reduce the data", err)
+	}), nil
+}
+
+// writeMemory ends the tracking, writes the memory table of the run and
+// returns the peak memory figures as metrics.
+func writeMemory(run *artifacts.Run, tracker *memory.Tracker) (map[string]float64, error) {
+	tracker.Close()
+	stages := tracker.Stages()
+	if err := run.WriteTable("memory", memory.Columns, memory.Rows(stages)); err != nil {
+		return nil, err
+	}
+	// Summarize the stages.
+	var peak, peakRSS, allocated uint64
+	for _, s := range stages {
+		fmt.Printf("Memory %-10s allocated = %10s peak = %10s\n", s.Name, memory.FormatSize(s.AllocBytes), memory.FormatSize(s.PeakBytes))
+		peak = max(peak, s.PeakBytes)
+		peakRSS = max(peakRSS, s.PeakRSSBytes)
+		allocated += s.AllocBytes
+	}
+	fmt.Printf("Peak RSS = %s\n\n", memory.FormatSize(peakRSS))
+	return map[string]float64{
+		"alloc_bytes":    float64(allocated),
+		"peak_bytes":     float64(peak),
+		"peak_rss_bytes": float64(peakRSS),
+	}, nil
+}
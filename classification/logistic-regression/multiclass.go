@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/transform"
+	"github.com/gonum/matrix/mat64"
+	"github.com/sjwhitworth/golearn/evaluation"
+	"golang.org/x/exp/rand"
+)
+
+// One-vs-rest classification
+// A multiclass problem is split into one binary problem per class: the
+// rows of the class against all other rows. Every binary model is a
+// logistic regression trained as for the loan data, and a row is given
+// the class whose model returns the highest probability.
+
+// multiclassPath, when set, runs the one-vs-rest model on that CSV file
+// instead of the loan example.
+var multiclassPath = flag.String("multiclass", "", "CSV file with numeric features and a class in the last column, such as ../dataset/iris.csv, to classify one-vs-rest instead of the loan data")
+
+// oneVsRest holds one binary logistic regression per class.
+type oneVsRest struct {
+	classes []string
+	weights [][]float64
+}
+
+// fitOneVsRest trains a binary model for every class, where labels holds
+// the index of the class of every row.
+func fitOneVsRest(ctx context.Context, features *mat64.Dense, labels []int, classes []string, opts logistic.Options, r *rand.Rand) (*oneVsRest, error) {
+	m := &oneVsRest{classes: classes}
+	binary := make([]float64, len(labels))
+	for c := range classes {
+		// Label the rows of the class 1 and every other row 0.
+		for i, label := range labels {
+			binary[i] = 0
+			if label == c {
+				binary[i] = 1
+			}
+		}
+		weights, _, err := logistic.Fit(ctx, features, binary, opts, r)
+		if err != nil {
+			return nil, err
+		}
+		m.weights = append(m.weights, weights)
+	}
+	return m, nil
+}
+
+// predict returns the index of the class with the highest probability
+// for the feature row.
+func (m *oneVsRest) predict(featureRow []float64) int {
+	best, bestP := 0, -1.0
+	for c, weights := range m.weights {
+		if p := logistic.Probability(weights, featureRow); p > bestP {
+			best, bestP = c, p
+		}
+	}
+	return best
+}
+
+// readMulticlassData reads a CSV file with a header row, numeric feature
+// columns and a class in the last column into a feature matrix with an
+// intercept column, the class index of every row and the class names in
+// order of appearance.
+func readMulticlassData(path string) (*mat64.Dense, []int, []string, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil, nil, nil, fmt.Errorf("%s has no data rows", path)
+	}
+	header := records[0]
+	numFeatures := len(header) - 1
+	featureData := make([]float64, 0, (numFeatures+1)*(len(records)-1))
+	labels := make([]int, 0, len(records)-1)
+	classIndex := make(map[string]int)
+	var classes []string
+	for _, record := range records[1:] {
+		// Add the features followed by an intercept.
+		for j := 0; j < numFeatures; j++ {
+			val, err := strconv.ParseFloat(record[j], 64)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			featureData = append(featureData, val)
+		}
+		featureData = append(featureData, 1.0)
+		// Add the class index, registering new classes.
+		class := record[numFeatures]
+		c, ok := classIndex[class]
+		if !ok {
+			c = len(classes)
+			classIndex[class] = c
+			classes = append(classes, class)
+		}
+		labels = append(labels, c)
+	}
+	return mat64.NewDense(len(labels), numFeatures+1, featureData), labels, classes, header[:numFeatures], nil
+}
+
+// standardize rescales every feature column but the trailing intercept
+// of both matrices to zero mean and unit variance, using the mean and
+// standard deviation of the training rows.
+func standardize(train, test *mat64.Dense) error {
+	numRows, numCols := train.Dims()
+	scaler := &transform.Standard{}
+	if err := scaler.Fit(train.View(0, 0, numRows, numCols-1)); err != nil {
+		return err
+	}
+	for _, m := range []*mat64.Dense{train, test} {
+		rows, _ := m.Dims()
+		features := m.View(0, 0, rows, numCols-1).(*mat64.Dense)
+		scaled, err := scaler.Transform(features)
+		if err != nil {
+			return err
+		}
+		features.Copy(scaled)
+	}
+	return nil
+}
+
+// multiclass trains the one-vs-rest model on a stratified split of the
+// multiclass data, prints the test accuracy and per-class scores and
+// saves them in the run directory.
+func multiclass(ctx context.Context, run *artifacts.Run) (map[string]float64, error) {
+	features, labels, classes, names, err := readMulticlassData(*multiclassPath)
+	if err != nil {
+		return nil, err
+	}
+	// Hold out a test set with the same class proportions.
+	trainRows, testRows := split.StratifiedTrainTest(labels, split.Config{
+		TestFraction: testFraction,
+		Shuffle:      true,
+		Seed:         splitSeed,
+	})
+	floatLabels := make([]float64, len(labels))
+	for i, label := range labels {
+		floatLabels[i] = float64(label)
+	}
+	trainFeatures, trainLabels := subsetRows(features, floatLabels, trainRows)
+	testFeatures, testLabels := subsetRows(features, floatLabels, testRows)
+	// Put the features on a common scale, so that no class model starts
+	// out saturated.
+	if err := standardize(trainFeatures, testFeatures); err != nil {
+		return nil, err
+	}
+	trainClasses := make([]int, len(trainLabels))
+	for i, label := range trainLabels {
+		trainClasses[i] = int(label)
+	}
+	// Train one binary model per class.
+	r := rand.New(rand.NewSource(uint64(splitSeed)))
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return nil, err
+	}
+	m, err := fitOneVsRest(ctx, trainFeatures, trainClasses, classes, opts, r)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("\nOne-vs-rest logistic regression on %s (%d classes, features %v)\n", *multiclassPath, len(classes), names)
+	// Build the confusion matrix of the test set.
+	cm := make(evaluation.ConfusionMatrix)
+	for _, class := range classes {
+		cm[class] = make(map[string]int)
+	}
+	for i, label := range testLabels {
+		predicted := m.predict(mat64.Row(nil, i, testFeatures))
+		cm[classes[int(label)]][classes[predicted]]++
+	}
+	summary := metrics.Summarize(cm)
+	fmt.Printf("\nAccuracy = %0.2f\n\n", summary["accuracy"])
+	scores := metrics.PerClass(cm)
+	if err := metrics.WriteReport(os.Stdout, scores); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := metrics.WriteSummary(os.Stdout, summary); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := run.WriteTable("class_metrics", metrics.ClassColumns, metrics.ClassRows(scores)); err != nil {
+		return nil, err
+	}
+	// Print the confusion matrix and save it as a table and a heat map.
+	counts := metrics.Counts(cm, classes)
+	if err := metrics.WriteConfusion(os.Stdout, classes, counts); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := run.WriteTable("confusion_matrix", metrics.ConfusionColumns(classes), metrics.ConfusionRows(classes, counts)); err != nil {
+		return nil, err
+	}
+	if err := plots.ConfusionMatrix(run.PlotPath("confusion_matrix.png"), classes, counts); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
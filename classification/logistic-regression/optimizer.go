@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+)
+
+// Optimization modes
+// The trainer updates the weights once per batch of rows. Pure stochastic
+// gradient descent uses batches of a single row, mini-batch gradient
+// descent uses batches of -batch-size rows and full batch gradient descent
+// makes a single update per epoch from the gradient over every row. The
+// rows can be visited in a new random order every epoch. Every update is
+// applied by an update rule from the optim package (plain steps, momentum
+// or Adam), with a learning rate that can decay over the epochs.
+
+const (
+	// stepDecayFactor and stepDecayEvery set the step learning rate
+	// schedule, which scales the rate by the factor every few epochs.
+	stepDecayFactor = 0.5
+	stepDecayEvery  = 25
+)
+
+// numSteps is the number of epochs the trainer runs.
+var numSteps = flag.Int("steps", 100, "number of training epochs")
+
+// trainSeed seeds the initial weights and the row orders of the trainer,
+// so that repeated runs train the same model.
+var trainSeed = flag.Uint64("seed", 44111342, "seed of the initial weights and the row orders of the trainer")
+
+// learningRate is the gradient descent step size of the first epoch.
+var learningRate = flag.Float64("learning-rate", 0.3, "gradient descent step size of the first epoch")
+
+// batchMode selects how many rows contribute to every weight update.
+var batchMode = flag.String("batch-mode", "sgd", "rows per weight update: sgd (one), minibatch (-batch-size) or batch (all)")
+
+// batchSize is the number of rows per update in minibatch mode.
+var batchSize = flag.Int("batch-size", 32, "rows per weight update in minibatch mode")
+
+// optimizerName selects the optimizer applying every weight update.
+var optimizerName = flag.String("optimizer", "sgd", "weight update rule: sgd, momentum or adam")
+
+// lrSchedule selects how the learning rate changes over the epochs.
+var lrSchedule = flag.String("lr-schedule", "constant", "learning rate schedule: constant, inverse or step")
+
+// lrDecay is the decay rate of the inverse learning rate schedule.
+var lrDecay = flag.Float64("lr-decay", 0.01, "decay rate of the inverse learning rate schedule")
+
+// tolerance stops training once an epoch changes the training loss by less.
+var tolerance = flag.Float64("tol", 0, "stop training once an epoch changes the training log loss by less than this (0 disables)")
+
+// patience stops training once the validation loss has not improved for
+// that many epochs.
+var patience = flag.Int("patience", 0, "with -restore-best, stop after this many epochs without a validation improvement (0 disables)")
+
+// shuffleEpochs visits the training rows in a new order every epoch.
+var shuffleEpochs = flag.Bool("shuffle", false, "shuffle the training rows before every epoch")
+
+// dpEpsilon, dpDelta and dpClip train with differential privacy: the
+// gradient of every row is clipped to the norm -dp-clip and the noise
+// added to every update is set to spend at most -dp-epsilon over all the
+// epochs, for the given δ.
+var (
+	dpEpsilon = flag.Float64("dp-epsilon", 0, "train with differential privacy within this ε budget (0 disables)")
+	dpDelta   = flag.Float64("dp-delta", 1e-5, "δ of differentially private training")
+	dpClip    = flag.Float64("dp-clip", 1, "largest gradient norm of a row in differentially private training")
+)
+
+// flagTrainOptions returns the trainer settings selected on the command
+// line.
+func flagTrainOptions() (logistic.Options, error) {
+	opts := logistic.Options{
+		Steps:     *numSteps,
+		Lambda:    *lambda,
+		Shuffle:   *shuffleEpochs,
+		Tolerance: *tolerance,
+		Patience:  *patience,
+		Threshold: *decisionThreshold,
+	}
+	if _, err := optim.New(*optimizerName); err != nil {
+		return logistic.Options{}, err
+	}
+	opts.NewOptimizer = func() optim.Optimizer {
+		opt, _ := optim.New(*optimizerName)
+		return opt
+	}
+	switch *lrSchedule {
+	case "constant":
+		opts.Schedule = optim.Constant(*learningRate)
+	case "inverse":
+		opts.Schedule = optim.InverseTime(*learningRate, *lrDecay)
+	case "step":
+		opts.Schedule = optim.StepDecay(*learningRate, stepDecayFactor, stepDecayEvery)
+	default:
+		return logistic.Options{}, fmt.Errorf("unknown learning rate schedule %q, expected constant, inverse or step", *lrSchedule)
+	}
+	switch *batchMode {
+	case "sgd":
+		opts.BatchSize = 1
+	case "minibatch":
+		if *batchSize < 1 {
+			return logistic.Options{}, fmt.Errorf("invalid batch size %d", *batchSize)
+		}
+		opts.BatchSize = *batchSize
+	case "batch":
+		opts.BatchSize = 0
+	default:
+		return logistic.Options{}, fmt.Errorf("unknown batch mode %q, expected sgd, minibatch or batch", *batchMode)
+	}
+	if *dpEpsilon > 0 {
+		opts.Privacy = &logistic.Privacy{Clip: *dpClip, Epsilon: *dpEpsilon, Delta: *dpDelta}
+	}
+	return opts, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parity"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Parity with scikit-learn
+// The model is trained with the golden seed on the rows of the reference
+// dataset and its predicted classes are compared with the reference
+// ones of pkg/parity/reference.py, those of scikit-learn's unpenalized
+// LogisticRegression or, as its source records, of the exact maximum
+// likelihood solution. Both minimize the log loss, but the example
+// stops after its -steps epochs of gradient descent, short of the
+// minimum, so the coefficients are reported and only the classes are
+// expected to agree.
+
+// parityFixture holds the reference outputs the model is compared with.
+const parityFixture = "testdata/parity_logistic.json"
+
+// parityMode compares the model with the reference instead of running
+// the example.
+var parityMode = flag.Bool("parity", false, "compare the model with the reference outputs in "+parityFixture+" instead of running the example")
+
+// checkParity trains the model on the reference dataset, prints how it
+// compares with the reference and exits with an error when it does not
+// match.
+func checkParity(ctx context.Context) error {
+	ref, err := parity.Load(files.Fixture(parityFixture))
+	if err != nil {
+		return err
+	}
+	features, labels, err := readLoanData(files.Fixture(ref.Data))
+	if err != nil {
+		return err
+	}
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return err
+	}
+	r := rand.New(rand.NewSource(goldenSeed))
+	weights, _, err := logistic.Fit(ctx, features, labels, opts, r)
+	if err != nil {
+		return err
+	}
+	// Name the weights as the reference coefficients, the intercept last.
+	got := map[string]float64{"intercept": weights[len(weights)-1]}
+	for j, name := range featureColumns() {
+		got[name] = weights[j]
+	}
+	numRows, _ := features.Dims()
+	predictions := make([]float64, numRows)
+	for i := range predictions {
+		predictions[i] = predict(weights, mat64.Row(nil, i, features))
+	}
+	results := []parity.Result{ref.CompareClasses(predictions)}
+	return parity.WriteReport(os.Stdout, ref, got, results)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tracking"
+)
+
+// modelFile names the saved model in the run directory.
+const modelFile = "logistic_regression.json"
+
+// modelPath is a model saved by a previous run, used instead of training.
+var modelPath = flag.String("model", "", "evaluate the model saved at this path, such as runs/<run>/models/"+modelFile+", instead of training one")
+
+// loanModel returns the model of the weights. It fills the missing FICO
+// scores with fill and maps the raw scores to [0, 1] with the bounds of
+// the clean loan data, so that the saved model predicts from raw loan
+// data.
+func loanModel(weights []float64, fill, minScore, maxScore float64) *model.Logistic {
+	m := &model.Logistic{Features: featureColumns(), Weights: weights, Threshold: *decisionThreshold}
+	for _, name := range m.Features {
+		shift, scale := 0.0, 1.0
+		if name == "fico" {
+			shift, scale = minScore, maxScore-minScore
+			m.Fill = map[string]float64{name: fill}
+		}
+		m.Shift = append(m.Shift, shift)
+		m.Scale = append(m.Scale, scale)
+	}
+	return m
+}
+
+// trainOrLoad trains the model and saves it to the model directory of
+// the run, or loads the model saved at -model. A loaded model must have
+// been trained on the same features, normalized with the same bounds, as
+// this run prepares the test data with them. Its decision threshold is
+// used unless -threshold is given.
+func trainOrLoad(ctx context.Context, run *artifacts.Run, sink tracking.Sink, fill, minScore, maxScore float64) ([]float64, error) {
+	want := loanModel(nil, fill, minScore, maxScore)
+	if *modelPath == "" {
+		weights, err := train(ctx, run, sink)
+		if err != nil {
+			return nil, err
+		}
+		want.Weights = weights
+		if err := model.Save(run.ModelPath(modelFile), model.KindLogistic, want); err != nil {
+			return nil, err
+		}
+		return want.Weights, nil
+	}
+	var m model.Logistic
+	h, err := model.Load(*modelPath, model.KindLogistic, &m)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(m.Features, want.Features) || !slices.Equal(m.Shift, want.Shift) || !slices.Equal(m.Scale, want.Scale) {
+		return nil, fmt.Errorf("%s was trained on features %v shifted by %v and scaled by %v, this run prepares %v shifted by %v and scaled by %v",
+			*modelPath, m.Features, m.Shift, m.Scale, want.Features, want.Shift, want.Scale)
+	}
+	if !maps.Equal(m.Fill, want.Fill) {
+		return nil, fmt.Errorf("%s fills the missing values with %v, this run with %v", *modelPath, m.Fill, want.Fill)
+	}
+	thresholdSet := false
+	flag.Visit(func(f *flag.Flag) { thresholdSet = thresholdSet || f.Name == "threshold" })
+	if !thresholdSet {
+		*decisionThreshold = m.Threshold
+	}
+	fmt.Printf("\nLoaded the model of %s from %s\n\n", h.Created.Format(time.RFC3339), *modelPath)
+	return m.Weights, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/selection"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Feature ranking
+// Before training on a column, we can check how much it tells of the
+// class. The mutual information of a feature and the class catches any
+// relation between them, and the chi-squared test of independence says
+// how unlikely the relation is to be chance. Both count the training rows
+// in bins of the feature, so they work the same for scores and for coded
+// categories. We rank every column of the training rows, so that the
+// informative ones can be passed to -features.
+
+var (
+	// rankCriterion is the statistic the columns are ranked by.
+	rankCriterion = flag.String("rank-criterion", "mi", "statistic ranking the training columns before training: mi, chi2, or none to skip the ranking")
+	// rankBins is the number of quantile bins of the numeric columns.
+	rankBins = flag.Int("rank-bins", selection.DefaultBins, "number of quantile bins of the columns ranked by mutual information or chi-squared")
+)
+
+// rankFeatures ranks every column of the training rows but the label by
+// the relevance criterion, prints the ranking and saves it to the
+// feature_ranking table and a bar chart of the criterion.
+func rankFeatures(run *artifacts.Run) error {
+	if *rankCriterion == "none" {
+		return nil
+	}
+	criterion, err := selection.ParseCriterion(*rankCriterion)
+	if err != nil {
+		return err
+	}
+	// Read every column of the training rows.
+	path := files.Data("training.csv")
+	var names []string
+	labelIdx := -1
+	header := func(columns []string) error {
+		if labelIdx = slices.Index(columns, labelColumn); labelIdx < 0 {
+			return fmt.Errorf("%s has no column %q", path, labelColumn)
+		}
+		names = slices.Delete(slices.Clone(columns), labelIdx, labelIdx+1)
+		return nil
+	}
+	var values, labels []float64
+	err = dataset.EachRecord(path, header, func(row int, record []string) error {
+		for j, field := range record {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return fmt.Errorf("%s: row %d: %v", path, row, err)
+			}
+			if j == labelIdx {
+				labels = append(labels, v)
+			} else {
+				values = append(values, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(labels) == 0 {
+		return fmt.Errorf("%s has no rows below the header", path)
+	}
+	x := mat64.NewDense(len(labels), len(names), values)
+	ranking, err := selection.RankClasses(x, labels, criterion, *rankBins)
+	if err != nil {
+		return err
+	}
+	// Output the ranking to stdout, save it and chart the criterion.
+	fmt.Printf("Training columns ranked by %s with %s\n", criterion, labelColumn)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "rank\tfeature\tmi\tchi2\tdf\tp_value\tbins")
+	columns := []string{"rank", "feature", "mi", "chi2", "df", "p_value", "bins"}
+	rows := make([][]any, len(ranking))
+	ranked := make([]string, len(ranking))
+	scores := make([]float64, len(ranking))
+	for i, r := range ranking {
+		rows[i] = []any{i + 1, names[r.Index], r.MutualInformation, r.ChiSquared, r.DegreesOfFreedom, r.PValue, r.Bins}
+		fmt.Fprintf(tw, "%d\t%s\t%.4f\t%.1f\t%d\t%.3g\t%d\n", i+1, names[r.Index], r.MutualInformation, r.ChiSquared, r.DegreesOfFreedom, r.PValue, r.Bins)
+		ranked[i] = names[r.Index]
+		scores[i] = r.MutualInformation
+		if criterion == selection.ChiSquared {
+			scores[i] = r.ChiSquared
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+	if err := run.WriteTable("feature_ranking", columns, rows); err != nil {
+		return err
+	}
+	yLabel := "Mutual information (nats)"
+	if criterion == selection.ChiSquared {
+		yLabel = "Chi-squared statistic"
+	}
+	return plots.Bars(run.PlotPath("feature_ranking.png"), "Feature ranking", yLabel, ranked, scores)
+}
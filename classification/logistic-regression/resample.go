@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/resample"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Class imbalance
+// How many loans are labeled good depends on -rate-threshold, and the
+// further it moves from the median rate the more one class dominates: the
+// model can then score a high accuracy by favoring the majority class,
+// which the balanced accuracy of the test set reveals. The training rows,
+// and only they, can be rebalanced before fitting, by dropping rows of the
+// majority class, repeating rows of the minority class, or synthesizing
+// minority rows by SMOTE. The test rows keep the classes as the model
+// meets them. Instead of changing the rows, -class-weight weighs the
+// errors of every class in the gradient updates.
+
+var (
+	// resampleMethod rebalances the classes of the training rows.
+	resampleMethod = flag.String("resample", "none", "rebalance the classes of the training rows before fitting: none, under (random undersampling), over (random oversampling) or smote")
+	// resampleRatio is the size of the minority class relative to the
+	// majority after resampling.
+	resampleRatio = flag.Float64("resample-ratio", 1, "size of the minority class relative to the majority class after resampling, from 0 to 1")
+	// smoteNeighbors is the number of neighbors SMOTE interpolates
+	// towards.
+	smoteNeighbors = flag.Int("smote-neighbors", 5, "number of nearest neighbors of the same class SMOTE interpolates towards")
+	// classWeight weighs the gradient of every training row by its
+	// class.
+	classWeight = flag.String("class-weight", "none", "weigh the training errors by class: none, balanced (inverse to the class frequencies of the training rows) or the weights of bad and good loans as w0,w1")
+)
+
+// resampleTraining rebalances the classes of the training rows by
+// -resample and prints the class counts before and after.
+func resampleTraining(features *mat64.Dense, labels []float64, r *rand.Rand) (*mat64.Dense, []float64, error) {
+	method, err := resample.ParseMethod(*resampleMethod)
+	if err != nil || method == resample.None {
+		return features, labels, err
+	}
+	resampledFeatures, resampledLabels, err := resample.Resample(features, labels, resample.Config{
+		Method:    method,
+		Ratio:     *resampleRatio,
+		Neighbors: *smoteNeighbors,
+	}, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	before, after := countClasses(labels), countClasses(resampledLabels)
+	fmt.Printf("Resampled the training rows (%s): %d bad and %d good before, %d bad and %d good after\n",
+		method, before[0], before[1], after[0], after[1])
+	return resampledFeatures, resampledLabels, nil
+}
+
+// weighClasses sets the class weights of -class-weight in opts, from the
+// labels of the rows fitted on, and prints them.
+func weighClasses(opts *logistic.Options, labels []float64) error {
+	var weights []float64
+	switch *classWeight {
+	case "none":
+		return nil
+	case "balanced":
+		var err error
+		if weights, err = logistic.BalancedWeights(labels); err != nil {
+			return err
+		}
+	default:
+		fields := strings.Split(*classWeight, ",")
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid class weights %q, expected none, balanced or w0,w1", *classWeight)
+		}
+		for _, field := range fields {
+			weight, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return fmt.Errorf("invalid class weights %q: %v", *classWeight, err)
+			}
+			weights = append(weights, weight)
+		}
+	}
+	opts.ClassWeights = weights
+	fmt.Printf("Class weights: %0.4f bad, %0.4f good\n", weights[0], weights[1])
+	return nil
+}
+
+// countClasses returns the number of labels of class 0 and 1.
+func countClasses(labels []float64) [2]int {
+	var counts [2]int
+	for _, label := range labels {
+		counts[int(label)]++
+	}
+	return counts
+}
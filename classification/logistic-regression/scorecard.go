@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Scorecard
+// Credit models are traditionally delivered as a points based scorecard. We bin the
+// raw FICO score, replace every bin with its weight of evidence (WOE), fit the
+// logistic regression on the WOE values, and scale the log odds so that the base
+// odds map to the base score and every doubling of the odds adds pdo points.
+
+const (
+	// numScoreBins is the number of quantile bins the FICO score is split into.
+	numScoreBins = 8
+	// baseScore is the score assigned to the base odds.
+	baseScore = 600.0
+	// baseOdds are the good:bad odds that score baseScore points.
+	baseOdds = 50.0
+	// pdo is the number of points that doubles the odds.
+	pdo = 20.0
+)
+
+// woeBin is a single bin of a feature with its weight of evidence.
+type woeBin struct {
+	lower, upper float64
+	good, bad    int
+	woe          float64
+	points       float64
+}
+
+// binEdges returns the upper bounds of numBins quantile bins of the values.
+// The last bin has no upper bound.
+func binEdges(values []float64, numBins int) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	var edges []float64
+	for k := 1; k < numBins; k++ {
+		edge := sorted[k*len(sorted)/numBins]
+		// Skip edges that repeat because of tied values.
+		if len(edges) > 0 && edges[len(edges)-1] == edge {
+			continue
+		}
+		edges = append(edges, edge)
+	}
+	return append(edges, math.Inf(1))
+}
+
+// binIndex returns the index of the bin holding value.
+func binIndex(edges []float64, value float64) int {
+	return sort.Search(len(edges), func(i int) bool { return value < edges[i] })
+}
+
+// woeBins counts the good (1.0) and bad (0.0) labels per bin and computes the
+// weight of evidence, ln(share of goods / share of bads), of every bin. Half a
+// count is added to each cell so that empty cells do not produce infinities.
+func woeBins(values, labels []float64, edges []float64) []woeBin {
+	bins := make([]woeBin, len(edges))
+	lower := math.Inf(-1)
+	for i, edge := range edges {
+		bins[i].lower, bins[i].upper = lower, edge
+		lower = edge
+	}
+	var totalGood, totalBad float64
+	for i, value := range values {
+		bin := &bins[binIndex(edges, value)]
+		if labels[i] == 1.0 {
+			bin.good++
+			totalGood++
+			continue
+		}
+		bin.bad++
+		totalBad++
+	}
+	for i := range bins {
+		goodShare := (float64(bins[i].good) + 0.5) / totalGood
+		badShare := (float64(bins[i].bad) + 0.5) / totalBad
+		bins[i].woe = math.Log(goodShare / badShare)
+	}
+	return bins
+}
+
+// readRawScores reads the unstandardized FICO scores and interest rate
+// classes from the raw loan dataset, leaving out the rows with a missing
+// value, which the bins of the scorecard have no place for.
+func readRawScores(path string) ([]float64, []float64, error) {
+	var scores, labels []float64
+	err := dataset.EachRecord(path, nil, func(row int, record []string) error {
+		// Parse the FICO score and the interest rate class.
+		score, rate, err := parseLoanRecord(record)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %v", path, row, err)
+		}
+		if math.IsNaN(score) || math.IsNaN(rate) {
+			return nil
+		}
+		label := 0.0
+		if rate <= *rateThreshold {
+			label = 1.0
+		}
+		scores = append(scores, score)
+		labels = append(labels, label)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return scores, labels, nil
+}
+
+func scorecard(ctx context.Context, run *artifacts.Run) error {
+	// Load the raw FICO scores and classes.
+	scores, labels, err := readRawScores(rawLoanData)
+	if err != nil {
+		return err
+	}
+	// Bin the scores and compute the weight of evidence of every bin.
+	edges := binEdges(scores, numScoreBins)
+	bins := woeBins(scores, labels, edges)
+	// Replace each score by the WOE of its bin and fit the logistic
+	// regression on the WOE feature plus an intercept.
+	featureData := make([]float64, 0, 2*len(scores))
+	for _, score := range scores {
+		featureData = append(featureData, bins[binIndex(edges, score)].woe, 1.0)
+	}
+	features := mat64.NewDense(len(scores), 2, featureData)
+	r := rand.New(rand.NewSource(*trainSeed))
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return err
+	}
+	weights, _, err := logistic.Fit(ctx, features, labels, opts, r)
+	if err != nil {
+		return err
+	}
+	// Scale the log odds into points.
+	factor := pdo / math.Ln2
+	offset := baseScore - factor*math.Log(baseOdds)
+	basePoints := offset + factor*weights[1]
+	for i := range bins {
+		bins[i].points = factor * weights[0] * bins[i].woe
+	}
+	// Create the scorecard file.
+	f, err := os.Create(run.Path("scorecard.csv"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Create a CSV writer.
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"feature", "lower", "upper", "good", "bad", "woe", "points"}); err != nil {
+		return err
+	}
+	if err := w.Write([]string{"base", "", "", "", "", "", strconv.FormatFloat(basePoints, 'f', 0, 64)}); err != nil {
+		return err
+	}
+	for _, bin := range bins {
+		record := []string{
+			"fico",
+			strconv.FormatFloat(bin.lower, 'f', -1, 64),
+			strconv.FormatFloat(bin.upper, 'f', -1, 64),
+			strconv.Itoa(bin.good),
+			strconv.Itoa(bin.bad),
+			strconv.FormatFloat(bin.woe, 'f', 4, 64),
+			strconv.FormatFloat(bin.points, 'f', 0, 64),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	// Write any buffered data to the underlying writer.
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	// Output the scorecard to stdout.
+	fmt.Printf("Scorecard (base score %0.0f at odds %0.0f:1, %0.0f points to double the odds)\n", baseScore, baseOdds, pdo)
+	fmt.Printf("base points = %0.0f\n", basePoints)
+	for _, bin := range bins {
+		fmt.Printf("fico [%v, %v) woe = %0.2f points = %0.0f\n", bin.lower, bin.upper, bin.woe, bin.points)
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Multi-seed stability
+// The weights are initialized randomly and updated row by row, so both the random
+// initialization and the order of the training rows change the fitted model. To
+// see how much, we repeat the full train/evaluate cycle for a number of seeds and
+// report the distribution of the test metrics.
+
+// numSeeds is the number of seeds the train/evaluate cycle is repeated for.
+const numSeeds = 10
+
+// seedResult holds the test metrics of a single seed.
+type seedResult struct {
+	seed     uint64
+	accuracy float64
+	auc      float64
+	err      error
+}
+
+// evaluateSeed shuffles the training rows and fits the model with the given
+// seed and trainer settings, then computes the accuracy and AUC on the test
+// set.
+func evaluateSeed(ctx context.Context, seed uint64, opts logistic.Options, features *mat64.Dense, labels []float64, testFeatures *mat64.Dense, testLabels []float64) seedResult {
+	r := rand.New(rand.NewSource(seed))
+	// Shuffle the training rows.
+	shuffled, shuffledLabels := subsetRows(features, labels, r.Perm(len(labels)))
+	// Train the model.
+	weights, _, err := logistic.Fit(ctx, shuffled, shuffledLabels, opts, r)
+	if err != nil {
+		return seedResult{seed: seed, err: err}
+	}
+	// Score the test set.
+	var correct int
+	probabilities := make([]float64, len(testLabels))
+	for i, label := range testLabels {
+		probabilities[i] = logistic.Probability(weights, mat64.Row(nil, i, testFeatures))
+		predicted := 0.0
+		if probabilities[i] >= *decisionThreshold {
+			predicted = 1.0
+		}
+		if predicted == label {
+			correct++
+		}
+	}
+	auc, err := metrics.AUC(testLabels, probabilities)
+	return seedResult{
+		seed:     seed,
+		accuracy: float64(correct) / float64(len(testLabels)),
+		auc:      auc,
+		err:      err,
+	}
+}
+
+// meanStd returns the mean and the sample standard deviation of the values.
+func meanStd(values []float64) (mean, std float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(values)-1))
+}
+
+// minMax returns the smallest and the largest of the values.
+func minMax(values []float64) (min, max float64) {
+	min, max = math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	return min, max
+}
+
+func stability(ctx context.Context, run *artifacts.Run) (map[string]float64, error) {
+	// Load the training and test data.
+	features, labels, err := readLoanData(files.Data("training.csv"))
+	if err != nil {
+		return nil, err
+	}
+	testFeatures, testLabels, err := readLoanData(files.Data("test.csv"))
+	if err != nil {
+		return nil, err
+	}
+	opts, err := flagTrainOptions()
+	if err != nil {
+		return nil, err
+	}
+	// Repeat the train/evaluate cycle for every seed.
+	// Every seed is evaluated independently, so they run in parallel.
+	results := parallel.Map(numSeeds, *workers, func(task int) seedResult {
+		return evaluateSeed(ctx, uint64(task+1), opts, features, labels, testFeatures, testLabels)
+	})
+	var accuracies, aucs []float64
+	for _, result := range results {
+		if result.err != nil {
+			return nil, result.err
+		}
+		accuracies = append(accuracies, result.accuracy)
+		aucs = append(aucs, result.auc)
+	}
+	// Write the metrics of every seed to the run directory.
+	rows := make([][]any, len(results))
+	for i, result := range results {
+		rows[i] = []any{result.seed, result.accuracy, result.auc}
+	}
+	if err := run.WriteTable("stability", []string{"seed", "accuracy", "auc"}, rows); err != nil {
+		return nil, err
+	}
+	// Output the distribution of the metrics to stdout.
+	accMean, accStd := meanStd(accuracies)
+	accMin, accMax := minMax(accuracies)
+	aucMean, aucStd := meanStd(aucs)
+	aucMin, aucMax := minMax(aucs)
+	fmt.Printf("Stability over %d seeds\n", numSeeds)
+	fmt.Printf("Accuracy = %0.2f (+/- %0.2f) min = %0.2f max = %0.2f\n", accMean, accStd*2, accMin, accMax)
+	fmt.Printf("AUC = %0.2f (+/- %0.2f) min = %0.2f max = %0.2f\n\n", aucMean, aucStd*2, aucMin, aucMax)
+	return map[string]float64{
+		"stability_accuracy_mean": accMean,
+		"stability_accuracy_std":  accStd,
+		"stability_auc_mean":      aucMean,
+		"stability_auc_std":       aucStd,
+	}, nil
+}
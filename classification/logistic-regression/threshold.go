@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+)
+
+// Decision threshold
+// predict() turns the probability into a class by comparing it with a
+// threshold. 0.5 maximizes the accuracy of a well calibrated model, but
+// a lower threshold catches more of the positive class at the cost of
+// precision. To choose the threshold we sweep it from 0 to 1 over the
+// test set and report the scores of every value.
+
+// decisionThreshold is the probability from which predict() returns 1.
+var decisionThreshold = flag.Float64("threshold", 0.5, "probability from which a loan is classified as 1")
+
+// numThresholds is the number of evenly spaced thresholds of the sweep,
+// including 0 and 1.
+const numThresholds = 101
+
+// thresholdScores holds the test scores at a single threshold.
+type thresholdScores struct {
+	threshold float64
+	metrics.Binary
+}
+
+// scoreThreshold classifies the probabilities at the threshold and
+// counts the classes against the observed labels.
+func scoreThreshold(observed, probabilities []float64, threshold float64) thresholdScores {
+	predicted := make([]float64, len(probabilities))
+	for i, p := range probabilities {
+		if p >= threshold {
+			predicted[i] = 1.0
+		}
+	}
+	return thresholdScores{threshold: threshold, Binary: metrics.BinaryCounts(observed, predicted)}
+}
+
+// sweepThresholds scores numThresholds evenly spaced thresholds from 0
+// to 1.
+func sweepThresholds(observed, probabilities []float64) []thresholdScores {
+	sweep := make([]thresholdScores, numThresholds)
+	for i := range sweep {
+		sweep[i] = scoreThreshold(observed, probabilities, float64(i)/float64(numThresholds-1))
+	}
+	return sweep
+}
+
+// thresholdSweep sweeps the decision threshold over the test set, saves
+// the scores of every threshold as a table and a plot in the run
+// directory and returns the threshold with the highest F1 score and that
+// score.
+func thresholdSweep(run *artifacts.Run, weights []float64) (bestThreshold, bestF1 float64, err error) {
+	// Score the test set at every threshold.
+	features, observed, err := readLoanData(files.Data("test.csv"))
+	if err != nil {
+		return 0, 0, err
+	}
+	probabilities := logistic.PredictProba(weights, features)
+	sweep := sweepThresholds(observed, probabilities)
+	// Write the sweep to the run directory.
+	rows := make([][]any, len(sweep))
+	thresholds := make([]float64, len(sweep))
+	series := make([][]float64, 4)
+	for i, s := range sweep {
+		scores := []float64{s.Accuracy(), s.Precision(), s.Recall(), s.F1()}
+		rows[i] = []any{s.threshold, scores[0], scores[1], scores[2], scores[3], s.TruePositives, s.FalsePositives}
+		thresholds[i] = s.threshold
+		for j, v := range scores {
+			series[j] = append(series[j], v)
+		}
+		if scores[3] > bestF1 {
+			bestThreshold, bestF1 = s.threshold, scores[3]
+		}
+	}
+	columns := []string{"threshold", "accuracy", "precision", "recall", "f1", "true_positives", "false_positives"}
+	if err := run.WriteTable("threshold_sweep", columns, rows); err != nil {
+		return 0, 0, err
+	}
+	if err := plots.Lines(run.PlotPath("threshold_sweep.png"), "Decision threshold sweep", "Threshold", "Score",
+		thresholds, []string{"accuracy", "precision", "recall", "f1"}, series); err != nil {
+		return 0, 0, err
+	}
+	// Output the best threshold next to the one in use.
+	current := scoreThreshold(observed, probabilities, *decisionThreshold)
+	fmt.Printf("Threshold = %0.2f: precision = %0.2f, recall = %0.2f, F1 = %0.2f\n",
+		current.threshold, current.Precision(), current.Recall(), current.F1())
+	fmt.Printf("Best F1 threshold = %0.2f (F1 = %0.2f)\n\n", bestThreshold, bestF1)
+	return bestThreshold, bestF1, nil
+}
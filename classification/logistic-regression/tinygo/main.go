@@ -0,0 +1,68 @@
+//go:build tinygo
+
+// Command tinygo scores loans on a microcontroller with a logistic
+// regression embedded in the program. It predicts with package inference
+// only, whose compact decoder and prediction use no reflection, so it
+// builds with TinyGo, for a board or for the host:
+//
+//	tinygo flash -target pico .
+//	tinygo run .
+//
+// loan.gmlc is the int8 compact file of a logistic regression of int.rate
+// on fico, trained on classification/dataset/training.csv:
+//
+//	gomlearn train -model logistic -data classification/dataset/training.csv -target int.rate -out loan.json
+//	gomlearn quantize -model loan.json -precision int8 -out loan.gmlc
+//
+// The scores are printed to the serial port of the board.
+package main
+
+import (
+	_ "embed"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/inference"
+)
+
+//go:embed loan.gmlc
+var modelFile []byte
+
+// ficoScores are FICO scores scaled to [0, 1], as in the dataset.
+var ficoScores = []float64{0.1, 0.3, 0.5, 0.7, 0.9}
+
+func main() {
+	m, err := inference.DecodeCompact(modelFile)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	scorer, ok := m.(*inference.Logistic)
+	if !ok {
+		println("loan.gmlc holds no logistic regression")
+		return
+	}
+	// A single row is reused, so that scoring allocates nothing.
+	row := make([]float64, 1)
+	// line buffers a line of output, formatted with strconv, which is
+	// much smaller than fmt.
+	line := make([]byte, 0, 64)
+	for _, fico := range ficoScores {
+		row[0] = fico
+		p, err := scorer.Probability(row)
+		if err != nil {
+			println(err.Error())
+			return
+		}
+		class := 0
+		if p >= scorer.Threshold {
+			class = 1
+		}
+		line = append(line[:0], "fico "...)
+		line = strconv.AppendFloat(line, fico, 'f', 2, 64)
+		line = append(line, ": probability "...)
+		line = strconv.AppendFloat(line, p, 'f', 4, 64)
+		line = append(line, ", class "...)
+		line = strconv.AppendInt(line, int64(class), 10)
+		println(string(line))
+	}
+}
@@ -0,0 +1,5 @@
+package main
+
+// validationFraction is the share of the training rows held out for
+// per-epoch validation.
+const validationFraction = 0.2
@@ -0,0 +1,76 @@
+//go:build js && wasm
+
+// Command wasm scores loans in a browser with a logistic regression saved
+// by the loan example. It predicts with package inference only, so it
+// compiles to a small WebAssembly module:
+//
+//	GOOS=js GOARCH=wasm go build -o scorer.wasm .
+//	cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" .  # misc/wasm before Go 1.24
+//
+// index.html loads the module and a model file, then calls the loadModel
+// and scoreLoan functions it registers.
+package main
+
+import (
+	"math"
+	"syscall/js"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/inference"
+)
+
+// scorer is the loaded model.
+var scorer *inference.Logistic
+
+func main() {
+	js.Global().Set("loadModel", js.FuncOf(loadModel))
+	js.Global().Set("scoreLoan", js.FuncOf(scoreLoan))
+	// Keep the functions alive for the page.
+	select {}
+}
+
+// loadModel decodes the text of a model file, its only argument. It
+// returns null, or the error message.
+func loadModel(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return "loadModel takes the text of a model file"
+	}
+	m, err := inference.Decode([]byte(args[0].String()))
+	if err != nil {
+		return err.Error()
+	}
+	lm, ok := m.(*inference.Logistic)
+	if !ok {
+		return "the model file holds no logistic regression"
+	}
+	scorer = lm
+	return nil
+}
+
+// scoreLoan scores a loan given as an object of its features by name,
+// such as {fico: 720}, and returns the probability of class 1, an
+// interest rate at most the threshold of training, and the class. Missing
+// features are filled as in training.
+func scoreLoan(this js.Value, args []js.Value) any {
+	if scorer == nil {
+		return map[string]any{"error": "no model is loaded"}
+	}
+	if len(args) != 1 || args[0].Type() != js.TypeObject {
+		return map[string]any{"error": "scoreLoan takes an object of the features"}
+	}
+	row := make([]float64, len(scorer.Features))
+	for j, name := range scorer.Features {
+		row[j] = math.NaN()
+		if v := args[0].Get(name); v.Type() == js.TypeNumber {
+			row[j] = v.Float()
+		}
+	}
+	p, err := scorer.Probability(row)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	class := 0
+	if p >= scorer.Threshold {
+		class = 1
+	}
+	return map[string]any{"probability": p, "class": class}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sjwhitworth/golearn/base"
+	"github.com/sjwhitworth/golearn/filters"
+)
+
+// thresholdFilter binarizes float attributes against a configurable
+// threshold per attribute. golearn's BinaryConvertFilter, which handles
+// every other attribute type here, always sets float features that are
+// above zero.
+type thresholdFilter struct {
+	*filters.BinaryConvertFilter
+	// thresholds holds the threshold of every float attribute by name.
+	// Attributes without an entry use 0, like BinaryConvertFilter.
+	thresholds map[string]float64
+}
+
+// newThresholdFilter creates a filter with the given threshold overrides.
+func newThresholdFilter(thresholds map[string]float64) *thresholdFilter {
+	return &thresholdFilter{
+		BinaryConvertFilter: filters.NewBinaryConvertFilter(),
+		thresholds:          thresholds,
+	}
+}
+
+// threshold returns the threshold used for the named float attribute.
+func (t *thresholdFilter) threshold(name string) float64 {
+	return t.thresholds[name]
+}
+
+// Transform sets a float feature when its value is above the threshold of
+// its attribute and delegates other attribute types to BinaryConvertFilter.
+func (t *thresholdFilter) Transform(a base.Attribute, n base.Attribute, attrBytes []byte) []byte {
+	if _, ok := a.(*base.FloatAttribute); !ok {
+		return t.BinaryConvertFilter.Transform(a, n, attrBytes)
+	}
+	if base.UnpackBytesToFloat(attrBytes) > t.threshold(a.GetName()) {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// parseThresholds parses threshold overrides given as "attribute=value"
+// pairs separated by commas. An empty string returns an empty map.
+func parseThresholds(s string) (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	if s == "" {
+		return thresholds, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid threshold %q, expected attribute=value", pair)
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		thresholds[strings.TrimSpace(name)] = v
+	}
+	return thresholds, nil
+}
+
+// featureStats summarizes a float feature within one class.
+type featureStats struct {
+	count      int
+	set        int
+	sum        float64
+	minV, maxV float64
+}
+
+// discretizationReport prints, for every float attribute, the threshold
+// used and the class-conditional distribution of the raw values and of
+// the binarized feature, so the preprocessing can be audited.
+func discretizationReport(data base.FixedDataGrid, t *thresholdFilter) error {
+	for _, a := range base.NonClassAttributes(data) {
+		if _, ok := a.(*base.FloatAttribute); !ok {
+			continue
+		}
+		spec, err := data.GetAttribute(a)
+		if err != nil {
+			return err
+		}
+		threshold := t.threshold(a.GetName())
+		// Accumulate the statistics of every class.
+		stats := make(map[string]*featureStats)
+		_, numRows := data.Size()
+		for i := 0; i < numRows; i++ {
+			class := base.GetClass(data, i)
+			s, ok := stats[class]
+			if !ok {
+				s = &featureStats{minV: base.UnpackBytesToFloat(data.Get(spec, i))}
+				s.maxV = s.minV
+				stats[class] = s
+			}
+			v := base.UnpackBytesToFloat(data.Get(spec, i))
+			s.count++
+			s.sum += v
+			if v > threshold {
+				s.set++
+			}
+			if v < s.minV {
+				s.minV = v
+			}
+			if v > s.maxV {
+				s.maxV = v
+			}
+		}
+		classes := make([]string, 0, len(stats))
+		for class := range stats {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		// Output the report of the attribute to stdout.
+		fmt.Printf("\n%s: set when > %g\n", a.GetName(), threshold)
+		fmt.Printf("%-12s %8s %8s %8s %8s %8s\n", "class", "rows", "P(set)", "min", "mean", "max")
+		for _, class := range classes {
+			s := stats[class]
+			fmt.Printf("%-12s %8d %8.2f %8.4f %8.4f %8.4f\n", class, s.count,
+				float64(s.set)/float64(s.count), s.minV, s.sum/float64(s.count), s.maxV)
+		}
+	}
+	fmt.Println()
+	return nil
+}
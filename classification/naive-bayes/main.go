@@ -1,13 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"os"
 
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/naivebayes"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
 	"github.com/sjwhitworth/golearn/base"
 	"github.com/sjwhitworth/golearn/evaluation"
-	"github.com/sjwhitworth/golearn/filters"
-	"github.com/sjwhitworth/golearn/naive"
+	"golang.org/x/exp/rand"
 )
 
 // main is the entry point of the program. It performs the following tasks:
@@ -17,14 +24,48 @@ import (
 // 4. Uses cross-fold validation to train and evaluate the model on 5 folds of the dataset.
 // 5. Calculates the mean, variance, and standard deviation of the accuracy from the cross-validation results.
 // 6. Prints the cross-validation accuracy metrics.
+var (
+	alpha      = flag.Float64("alpha", 1.0, "Lidstone smoothing of the feature counts, positive (1 is Laplace smoothing)")
+	priors     = flag.String("priors", "", "class priors as class=probability pairs, e.g. 1=0.3,0=0.7 (default: training frequencies)")
+	thresholds = flag.String("thresholds", "", "binarization thresholds as attribute=value pairs, e.g. fico=0.5 (default: 0)")
+	dpEpsilon  = flag.Float64("dp-epsilon", 0, "fit on counts made ε-differentially private with Laplace noise (0 disables)")
+	dpSeed     = flag.Uint64("dp-seed", 1, "seed of the Laplace noise of -dp-epsilon")
+)
+
+// files locates the datasets and the runs of the example.
+var files = workspace.Register(flag.CommandLine, "naive-bayes", "../dataset")
+
 func main() {
-	train()
+	if err := experiment.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if err := runExample(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runExample trains and evaluates the classifier, returning the first
+// error met.
+func runExample() error {
+	// Create the artifact directory of this run.
+	run, err := files.NewRun()
+	if err != nil {
+		return err
+	}
+	if err := train(run); err != nil {
+		return err
+	}
+	if err := experiment.Write(run.Path(experiment.File), flag.CommandLine); err != nil {
+		return err
+	}
+	fmt.Println("Artifacts saved to", run.Dir)
+	return nil
 }
 
 // convertToBinary utilizes built in golearn functionality to
-// convert our labels to a binary label format.
-func convertToBinary(src base.FixedDataGrid) base.FixedDataGrid {
-	b := filters.NewBinaryConvertFilter()
+// convert our labels to a binary label format. Float features are
+// set when they are above the threshold configured in b.
+func convertToBinary(src base.FixedDataGrid, b *thresholdFilter) base.FixedDataGrid {
 	attrs := base.NonClassAttributes(src)
 	for _, a := range attrs {
 		b.AddAttribute(a)
@@ -34,33 +75,79 @@ func convertToBinary(src base.FixedDataGrid) base.FixedDataGrid {
 	return ret
 }
 
-func train() {
+func train(run *artifacts.Run) error {
 	// Load the loan training dataset into golearn "instances".
-	trainingData, err := base.ParseCSVToInstances("../dataset/training.csv", true)
+	trainingData, err := base.ParseCSVToInstances(files.Data("training.csv"), true)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// Create a new Naive Bayes classifier with the configured smoothing
+	// and class priors.
+	classPriors, err := naivebayes.ParsePriors(*priors)
+	if err != nil {
+		return err
+	}
+	nb, err := naivebayes.NewBernoulli(*alpha, classPriors)
+	if err != nil {
+		return err
+	}
+	if *dpEpsilon > 0 {
+		nb.SetPrivacy(*dpEpsilon, rand.New(rand.NewSource(*dpSeed)))
+		fmt.Printf("Fitting on counts with ε = %g differential privacy\n", *dpEpsilon)
+	}
+	// Binarize the features with the configured thresholds and report
+	// how the training data is discretized.
+	featureThresholds, err := parseThresholds(*thresholds)
+	if err != nil {
+		return err
+	}
+	if err := discretizationReport(trainingData, newThresholdFilter(featureThresholds)); err != nil {
+		return err
 	}
-	// Create a new Naive Bayes classifier.
-	nb := naive.NewBernoulliNBClassifier()
 	// Train the Naive Bayes classifier.
-	nb.Fit(convertToBinary(trainingData))
+	if err := nb.Fit(convertToBinary(trainingData, newThresholdFilter(featureThresholds))); err != nil {
+		return err
+	}
 	// Load the loan test dataset into golearn "instances".
 	// Use the training data as a template to ensure the test data format matches.
-	testData, err := base.ParseCSVToTemplatedInstances("../dataset/test.csv", true, trainingData)
+	testData, err := base.ParseCSVToTemplatedInstances(files.Data("test.csv"), true, trainingData)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Make predictions on the test data.
-	predictions, err := nb.Predict(convertToBinary(testData))
+	predictions, err := nb.Predict(convertToBinary(testData, newThresholdFilter(featureThresholds)))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	// Generate a confusion matrix.
 	cm, err := evaluation.GetConfusionMatrix(testData, predictions)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	// Calculate and print the accuracy and the class-balanced scores.
+	summary := metrics.Summarize(cm)
+	fmt.Printf("\nAccuracy: %0.2f\n\n", summary["accuracy"])
+	if err := metrics.WriteSummary(os.Stdout, summary); err != nil {
+		return err
+	}
+	fmt.Println()
+	// Save the test metrics for external dashboards.
+	if err := run.WriteMetrics(summary); err != nil {
+		return err
+	}
+	scores := metrics.PerClass(cm)
+	if err := run.WriteTable("class_metrics", metrics.ClassColumns, metrics.ClassRows(scores)); err != nil {
+		return err
+	}
+	// Print the confusion matrix and save it as a table and a heat map.
+	classes := metrics.Classes(cm)
+	counts := metrics.Counts(cm, classes)
+	if err := metrics.WriteConfusion(os.Stdout, classes, counts); err != nil {
+		return err
+	}
+	fmt.Println()
+	if err := run.WriteTable("confusion_matrix", metrics.ConfusionColumns(classes), metrics.ConfusionRows(classes, counts)); err != nil {
+		return err
 	}
-	// Calculate and print the accuracy.
-	accuracy := evaluation.GetAccuracy(cm)
-	fmt.Printf("\nAccuracy: %0.2f\n\n", accuracy)
+	return plots.ConfusionMatrix(run.PlotPath("confusion_matrix.png"), classes, counts)
 }
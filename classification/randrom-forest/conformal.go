@@ -0,0 +1,81 @@
+package main
+
+// Split conformal prediction sets
+//
+// A forest is fitted on part of the rows, and one minus the probability it
+// gives the true class of held out calibration rows bounds how far that
+// probability can drop. Every class whose probability stays within the
+// bound goes into the prediction set of a new row, which then holds its
+// true class with probability at least 1 - alpha. Iris has three classes,
+// so a set holds one, two or all three species.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/conformal"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+)
+
+const (
+	// conformalAlpha is the allowed miscoverage rate of the prediction
+	// sets.
+	conformalAlpha = 0.1
+	// calibrationFraction and testFraction are the shares of the rows held
+	// out to calibrate the prediction sets and to measure their coverage.
+	calibrationFraction = 0.2
+	testFraction        = 0.2
+)
+
+// conformalMode reports the conformal prediction sets of the forest.
+var conformalMode = flag.Bool("conformal", false, "report the coverage and size of split conformal prediction sets of the forest on held out rows")
+
+// conformalSets fits a forest on part of the rows, calibrates its
+// prediction sets on a held out part and prints their coverage and sizes
+// on the remaining rows. It returns the coverage and the mean set size.
+func conformalSets(ctx context.Context, d *dataset.Dataset) (coverage, meanSetSize float64, err error) {
+	perm := rand.New(rand.NewSource(seed)).Perm(len(d.Labels))
+	numCalibration := int(float64(len(perm)) * calibrationFraction)
+	numTest := int(float64(len(perm)) * testFraction)
+	calFeatures, calLabels := rows(d, perm[:numCalibration])
+	testFeatures, testLabels := rows(d, perm[numCalibration:numCalibration+numTest])
+	fitFeatures, fitLabels := rows(d, perm[numCalibration+numTest:])
+	f := newForest()
+	if err := f.Fit(ctx, fitFeatures, fitLabels, nil); err != nil {
+		return 0, 0, err
+	}
+	// The forest always predicts a probability for every class.
+	predictProba := func(row []float64) ([]float64, error) { return f.PredictProba(row), nil }
+	c, err := conformal.CalibrateClassifier(predictProba, calFeatures, calLabels, conformalAlpha)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Measure the coverage and the size of the prediction sets.
+	var covered, totalSize int
+	sizes := make([]int, len(d.ClassValues)+1)
+	for i, label := range testLabels {
+		set, err := c.Set(testFeatures.RawRowView(i))
+		if err != nil {
+			return 0, 0, err
+		}
+		totalSize += len(set)
+		sizes[len(set)]++
+		for _, class := range set {
+			if class == label {
+				covered++
+				break
+			}
+		}
+	}
+	coverage = float64(covered) / float64(numTest)
+	meanSetSize = float64(totalSize) / float64(numTest)
+	fmt.Printf("Conformal prediction sets (alpha = %0.2f, %d calibration rows, %d test rows)\n", conformalAlpha, numCalibration, numTest)
+	fmt.Printf("Test coverage = %0.2f\nMean set size = %0.2f\n", coverage, meanSetSize)
+	for size, n := range sizes {
+		fmt.Printf("Sets of %d classes = %d\n", size, n)
+	}
+	fmt.Println()
+	return coverage, meanSetSize, nil
+}
@@ -1,44 +1,366 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"math"
+	"math/rand"
+	"os"
+	"os/signal"
 
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/forest"
+	"github.com/bachhm.dev/go-machine-learning/pkg/memory"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
+	"github.com/gonum/matrix/mat64"
 	"github.com/sjwhitworth/golearn/base"
-	"github.com/sjwhitworth/golearn/ensemble"
 	"github.com/sjwhitworth/golearn/evaluation"
-	"golang.org/x/exp/rand"
 )
 
-// main is the entry point of the program. It performs the following tasks:
-// 1. Loads the iris dataset into golearn "instances" from a CSV file.
-// 2. Seeds the random number generator to ensure reproducibility.
-// 3. Creates a random forest classifier with 10 trees and 2 features per tree.
-// 4. Uses cross-fold validation to train and evaluate the model on 5 folds of the dataset.
-// 5. Calculates the mean, variance, and standard deviation of the accuracy from the cross-validation results.
-// 6. Prints the cross-validation accuracy metrics.
+const (
+	// seed seeds the folds and the forests.
+	seed = 44111342
+	// modelFile names the saved forest in the run directory.
+	modelFile = "random_forest.json"
+)
+
+var (
+	// numTrees is the number of trees of the forest.
+	numTrees = flag.Int("trees", 10, "number of trees of the forest")
+	// maxFeatures is the number of features drawn as split candidates at
+	// every node. Typically, it is set to the square root of the total
+	// number of features.
+	maxFeatures = flag.Int("max-features", 2, "number of features drawn as split candidates at every node (0 uses the square root of the number of features)")
+	// maxDepth limits the depth of the trees.
+	maxDepth = flag.Int("max-depth", 0, "largest depth of the trees (0 for no limit)")
+	// maxBins bins the features into histograms to find the splits
+	// faster on large datasets.
+	maxBins = flag.Int("max-bins", 0, "number of histogram bins of every feature used to find splits (0 tries every threshold)")
+	// numFolds is the number of cross-validation folds.
+	numFolds = flag.Int("folds", 5, "number of cross-validation folds")
+	// repeats is the number of cross-validation passes, each dealing the
+	// rows into different folds. Repeating the passes steadies the
+	// reported accuracy on a dataset as small as iris.
+	repeats = flag.Int("repeats", 10, "number of repeated cross-validation passes")
+	// workers is the number of cross-validation folds evaluated, and of
+	// trees grown, in parallel.
+	workers = flag.Int("workers", 0, "number of cross-validation folds evaluated, and of trees grown, in parallel (0 uses every CPU)")
+	// memoryBudget caps the memory the folds fitted in parallel are
+	// estimated to use together.
+	memoryBudget = flag.String("memory-budget", "", "largest memory the folds fitted in parallel are estimated to use together, such as 512MiB, fitting fewer at once as the forests grow (default no limit)")
+)
+
+// files locates the datasets and the runs of the example.
+var files = workspace.Register(flag.CommandLine, "random-forest", "../dataset")
+
 func main() {
-	// Load the iris dataset into golearn "instances".
-	irisData, err := base.ParseCSVToInstances("../dataset/iris.csv", true)
-	if err != nil {
+	if err := experiment.Parse(flag.CommandLine, os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
+	// Stop fitting the forests on an interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := runExample(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	rand.Seed(44111342)
-
-	// Create a random forest with 10 trees and 2 features per tree.
-	// Typically, the number of features per tree is set to the square root of the total number of features.
-	rf := ensemble.NewRandomForest(10, 2)
-	// Use cross-fold validation to successively train and evaluate the model
-	// on 5 folds of the data set.
-	cv, err := evaluation.GenerateCrossFoldValidationConfusionMatrices(irisData, rf, 5)
+// runExample performs the following tasks, returning the first error met:
+// 1. Downloads the iris dataset if needed and loads it into golearn "instances" from a CSV file.
+// 2. Creates a random forest of CART trees with 10 trees and 2 features per split.
+// 3. Uses repeated stratified cross-fold validation to train and evaluate the model on 5 folds of the dataset, 10 times over.
+// 4. Calculates the mean, variance, and standard deviation of the accuracy from the cross-validation results.
+// 5. Prints the cross-validation accuracy metrics.
+// 6. Prints the per-class precision, recall and F1 score and saves the confusion matrix plot.
+// 7. Fits the forest on the whole dataset, reports its out-of-bag accuracy and
+// feature importances, and saves it to the run directory.
+// 8. With -nested, evaluates the tuning of the forest by nested cross-validation.
+// 9. With -conformal, reports split conformal prediction sets of the species.
+func runExample(ctx context.Context) error {
+	// Download the iris dataset when it is not present yet.
+	irisPath := files.Data("iris.csv")
+	if err := dataset.FetchIris(irisPath); err != nil {
+		return err
+	}
+	// Load the iris dataset into golearn "instances", and convert them
+	// to a feature matrix with class indices.
+	irisData, err := base.ParseCSVToInstances(irisPath, true)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	iris, err := dataset.FromInstances(irisData)
+	if err != nil {
+		return err
+	}
+	// Use repeated cross-fold validation to successively train and evaluate
+	// the model on 5 folds of the data set, dealt anew on every pass.
+	cv, registered, err := crossValidate(ctx, iris)
+	if err != nil {
+		return err
 	}
 	// Calculate the mean, variance, and standard deviation of the accuracy.
 	mean, variance := evaluation.GetCrossValidatedMetric(cv, evaluation.GetAccuracy)
 	stdev := math.Sqrt(variance)
 	// Print the cross-validation accuracy metrics.
 	fmt.Printf("\nAccuracy\n%.2f (+/- %.2f)\n\n", mean, stdev*2)
+	// Print the registered metrics, custom ones included.
+	fmt.Println("Registered metrics, mean over the folds")
+	if err := metrics.WriteScores(os.Stdout, registered); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	// Report the per-class scores and the confusion matrix over all folds.
+	run, err := files.NewRun()
+	if err != nil {
+		return err
+	}
+	summary, err := classReport(cv, run)
+	if err != nil {
+		return err
+	}
+	// Fit the forest on every row, and save it along with its
+	// out-of-bag accuracy and feature importances.
+	if summary["oob_accuracy"], err = fitAll(ctx, iris, run); err != nil {
+		return err
+	}
+	// Evaluate the tuning of the forest without the optimism of choosing
+	// and scoring the parameters on the same folds, when requested.
+	if *nested {
+		nestedScores, err := nestedCV(ctx, iris, run)
+		if err != nil {
+			return err
+		}
+		for name, v := range nestedScores {
+			summary[name] = v
+		}
+	}
+	// Report split conformal prediction sets of the species, when
+	// requested.
+	if *conformalMode {
+		if summary["conformal_coverage"], summary["conformal_mean_set_size"], err = conformalSets(ctx, iris); err != nil {
+			return err
+		}
+	}
+	// Save the cross-validation metrics, along with the scores of the
+	// summed matrix, for external dashboards.
+	summary["accuracy_mean"], summary["accuracy_stdev"] = mean, stdev
+	for name, score := range registered {
+		if !math.IsNaN(score) {
+			summary["cv_"+name] = score
+		}
+	}
+	if err := run.WriteMetrics(summary); err != nil {
+		return err
+	}
+	if err := run.WriteTable("folds", metrics.FoldColumns, metrics.FoldRows(cv)); err != nil {
+		return err
+	}
+	config := map[string]any{
+		"num_trees":    *numTrees,
+		"max_features": *maxFeatures,
+		"max_depth":    *maxDepth,
+		"max_bins":     *maxBins,
+		"num_folds":    *numFolds,
+		"num_repeats":  *repeats,
+		"objective":    *objective,
+		"conformal":    *conformalMode,
+		"seed":         seed,
+	}
+	if err := run.WriteConfig(config); err != nil {
+		return err
+	}
+	if err := experiment.Write(run.Path(experiment.File), flag.CommandLine); err != nil {
+		return err
+	}
+	fmt.Println("Artifacts saved to", run.Dir)
+	return nil
+}
+
+// newForest returns an unfitted forest configured by the flags.
+func newForest() *forest.Forest {
+	f := forest.New(tree.Classification, *numTrees, tree.Params{MaxFeatures: *maxFeatures, MaxDepth: *maxDepth, MaxBins: *maxBins}, seed)
+	f.Workers = *workers
+	return f
+}
+
+// rows returns the given rows of the features and labels.
+func rows(d *dataset.Dataset, idx []int) (*mat64.Dense, []float64) {
+	_, numFeatures := d.Features.Dims()
+	x := mat64.NewDense(len(idx), numFeatures, nil)
+	y := make([]float64, len(idx))
+	for k, i := range idx {
+		x.SetRow(k, d.Features.RawRowView(i))
+		y[k] = d.Labels[i]
+	}
+	return x, y
+}
+
+// foldResult holds the predictions of the held-out rows of a fold.
+type foldResult struct {
+	cm                  evaluation.ConfusionMatrix
+	observed, predicted []float64
+}
+
+// crossValidate fits a forest on all folds but one and predicts the
+// remaining fold, for every fold of every pass, returning the confusion
+// matrix of every fold and the mean over the folds of every registered
+// metric. The folds are stratified by class, and -workers of them are
+// fitted at a time.
+func crossValidate(ctx context.Context, d *dataset.Dataset) ([]evaluation.ConfusionMatrix, map[string]float64, error) {
+	strata := make([]int, len(d.Labels))
+	for i, label := range d.Labels {
+		strata[i] = int(label)
+	}
+	folds, err := split.RepeatedStratifiedKFold(strata, *numFolds, *repeats, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The forests draw from their own seeded streams, so the folds need
+	// no other random numbers. Grow the trees of every forest in turn, as
+	// the folds already keep the workers busy.
+	budget, err := memory.ParseSize(*memoryBudget)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, numFeatures := d.Features.Dims()
+	cost := func(fold split.Fold) parallel.Cost {
+		return parallel.Cost{CPUs: 1, Memory: estimateMemory(len(fold.Train), numFeatures, len(d.ClassValues))}
+	}
+	results, err := split.CrossValidateBudget(ctx, folds, parallel.Budget{CPUs: *workers, Memory: budget}, cost, uint64(seed), func(k int, fold split.Fold, _ *rand.Rand) (foldResult, error) {
+		x, y := rows(d, fold.Train)
+		f := newForest()
+		f.Workers = 1
+		if err := f.Fit(ctx, x, y, nil); err != nil {
+			return foldResult{}, err
+		}
+		// Count the predicted classes of the held-out rows.
+		res := foldResult{
+			cm:        make(evaluation.ConfusionMatrix),
+			observed:  make([]float64, len(fold.Test)),
+			predicted: make([]float64, len(fold.Test)),
+		}
+		for j, i := range fold.Test {
+			res.observed[j], res.predicted[j] = d.Labels[i], f.Predict(d.Features.RawRowView(i))
+			actual := d.ClassValues[int(res.observed[j])]
+			class := d.ClassValues[int(res.predicted[j])]
+			if res.cm[actual] == nil {
+				res.cm[actual] = make(map[string]int)
+			}
+			res.cm[actual][class]++
+		}
+		return res, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	cv := make([]evaluation.ConfusionMatrix, len(results))
+	registered := make(map[string]float64)
+	for k, res := range results {
+		cv[k] = res.cm
+		// Iris has three classes, so the metrics needing the probability
+		// of class 1 score NaN and are left out of the reports.
+		for name, score := range metrics.ScoreAll(true, res.observed, res.predicted, nil) {
+			registered[name] += score / float64(len(results))
+		}
+	}
+	return cv, registered, nil
+}
+
+// estimateMemory returns a rough upper estimate of the memory fitting a
+// forest on numRows rows allocates: the copy of the rows, the copy every
+// tree sorts while it grows, and the nodes of the trees, which hold a
+// value per class. A tree has fewer than two nodes per row, and at most
+// 2^(d+1) - 1 nodes of depth d. The estimate only needs to grow with the
+// cost of the fold, so that the budget fits fewer large folds at once.
+func estimateMemory(numRows, numFeatures, numClasses int) uint64 {
+	const floatBytes, nodeBytes = 8, 64
+	rows := uint64(numRows) * uint64(numFeatures+1) * floatBytes
+	nodes := 2 * uint64(numRows)
+	if d := *maxDepth; d > 0 && d < 40 {
+		nodes = min(nodes, uint64(1)<<(d+1))
+	}
+	tree := nodes * (nodeBytes + uint64(max(numClasses, 1))*floatBytes)
+	return 2*rows + uint64(*numTrees)*tree
+}
+
+// fitAll fits the forest on every row, prints its out-of-bag accuracy and
+// feature importances, saves the importances to the feature_importances
+// table and the forest to the model directory of the run, and checks
+// that the saved forest predicts as the fitted one. It returns the
+// out-of-bag accuracy.
+func fitAll(ctx context.Context, d *dataset.Dataset, run *artifacts.Run) (float64, error) {
+	f := newForest()
+	if err := f.Fit(ctx, d.Features, d.Labels, nil); err != nil {
+		return 0, err
+	}
+	fmt.Printf("Out-of-bag accuracy = %0.2f (%d rows)\n\n", f.OOBScore, f.OOBRows)
+	// Print and save the feature importances.
+	importances := make([][]any, len(d.Names))
+	for j, name := range d.Names {
+		fmt.Printf("%-20s importance = %0.3f\n", name, f.Importances[j])
+		importances[j] = []any{name, f.Importances[j]}
+	}
+	fmt.Println()
+	if err := run.WriteTable("feature_importances", []string{"feature", "importance"}, importances); err != nil {
+		return 0, err
+	}
+	// Save the forest and load it back.
+	path := run.ModelPath(modelFile)
+	if err := f.Save(path); err != nil {
+		return 0, err
+	}
+	saved, err := forest.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	numRows, _ := d.Features.Dims()
+	for i := 0; i < numRows; i++ {
+		row := d.Features.RawRowView(i)
+		if saved.Predict(row) != f.Predict(row) {
+			return 0, fmt.Errorf("the saved forest %s predicts row %d differently", path, i)
+		}
+	}
+	return f.OOBScore, nil
+}
+
+// classReport prints the precision, recall, F1 score and specificity of
+// every class from the summed fold confusion matrices along with their
+// averages and the matrix itself, saves them to the class_metrics and
+// confusion_matrix tables and saves the matrix as a heat map in the run
+// directory. It returns the averaged scores.
+func classReport(cv []evaluation.ConfusionMatrix, run *artifacts.Run) (map[string]float64, error) {
+	cm := metrics.Sum(cv)
+	scores := metrics.PerClass(cm)
+	if err := metrics.WriteReport(os.Stdout, scores); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	summary := metrics.Summarize(cm)
+	if err := metrics.WriteSummary(os.Stdout, summary); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := run.WriteTable("class_metrics", metrics.ClassColumns, metrics.ClassRows(scores)); err != nil {
+		return nil, err
+	}
+	classes := metrics.Classes(cm)
+	counts := metrics.Counts(cm, classes)
+	if err := metrics.WriteConfusion(os.Stdout, classes, counts); err != nil {
+		return nil, err
+	}
+	fmt.Println()
+	if err := run.WriteTable("confusion_matrix", metrics.ConfusionColumns(classes), metrics.ConfusionRows(classes, counts)); err != nil {
+		return nil, err
+	}
+	return summary, plots.ConfusionMatrix(run.PlotPath("confusion_matrix.png"), classes, counts)
 }
@@ -0,0 +1,101 @@
+package main
+
+// Nested cross-validation
+//
+// Choosing the number of trees and of split candidates by the accuracy of
+// the folds they are evaluated on makes that accuracy optimistic: the
+// winner is partly the candidate that got lucky on those folds. Nested
+// cross-validation chooses them on inner folds of the training rows of
+// every outer fold, and scores the choice on the held-out outer fold.
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/forest"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/search"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+)
+
+// numInnerFolds is the number of inner folds of the nested
+// cross-validation.
+const numInnerFolds = 3
+
+var (
+	// nested evaluates the tuning of the forest by nested cross-validation.
+	nested = flag.Bool("nested", false, "evaluate the tuning of the number of trees and of split candidates by nested cross-validation")
+	// objective names the registered metric the tuning maximizes, or
+	// minimizes for losses.
+	objective = flag.String("objective", "accuracy", "registered classifier metric the nested cross-validation tunes the forest by")
+)
+
+// tuningSpace holds the candidate hyperparameters of the forest.
+var tuningSpace = search.New(
+	search.Choice("trees", 5, 10, 25, 50),
+	search.Choice("max_features", 1, 2, 4),
+)
+
+// nestedCV runs the nested cross-validation of the forest, prints the
+// parameters chosen on every outer fold along with their inner and outer
+// scores by the objective, and saves them to the nested_folds table. It
+// returns the mean and standard deviation of the outer scores and the
+// mean of the inner ones.
+func nestedCV(ctx context.Context, d *dataset.Dataset, run *artifacts.Run) (map[string]float64, error) {
+	m, err := metrics.Lookup(*objective)
+	if err != nil {
+		return nil, err
+	}
+	if !m.Classifier {
+		return nil, fmt.Errorf("-objective: %q is not a classifier metric", m.Name)
+	}
+	strata := make([]int, len(d.Labels))
+	for i, label := range d.Labels {
+		strata[i] = int(label)
+	}
+	folds, err := search.NestedCV(ctx, tuningSpace.Grid(0), strata, *numFolds, numInnerFolds, seed, func(p search.Params, train, test []int) (float64, error) {
+		x, y := rows(d, train)
+		params := tree.Params{MaxFeatures: p.Int("max_features"), MaxDepth: *maxDepth, MaxBins: *maxBins}
+		f := forest.New(tree.Classification, p.Int("trees"), params, seed)
+		f.Workers = *workers
+		if err := f.Fit(ctx, x, y, nil); err != nil {
+			return 0, err
+		}
+		observed, predicted := make([]float64, len(test)), make([]float64, len(test))
+		for j, i := range test {
+			observed[j], predicted[j] = d.Labels[i], f.Predict(d.Features.RawRowView(i))
+		}
+		score := m.Objective(observed, predicted, nil)
+		if math.IsNaN(score) {
+			return 0, fmt.Errorf("-objective: %q cannot score the three iris classes", m.Name)
+		}
+		return score, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Nested cross-validation")
+	if err := search.WriteNested(os.Stdout, folds); err != nil {
+		return nil, err
+	}
+	// The scores of the folds are negated for losses, which the search
+	// maximizes; turn the means back into losses.
+	mean, stdev, innerMean := search.NestedScores(folds)
+	if !m.HigherIsBetter {
+		mean, innerMean = -mean, -innerMean
+	}
+	fmt.Printf("Outer %s %.2f (+/- %.2f), inner %s of the chosen parameters %.2f\n\n", m.Name, mean, stdev*2, m.Name, innerMean)
+	if err := run.WriteTable("nested_folds", search.NestedColumns, search.NestedRows(folds)); err != nil {
+		return nil, err
+	}
+	return map[string]float64{
+		"nested_" + m.Name + "_mean":  mean,
+		"nested_" + m.Name + "_stdev": stdev,
+		"nested_inner_" + m.Name:      innerMean,
+	}, nil
+}
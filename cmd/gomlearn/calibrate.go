@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+)
+
+// operatingPoint is the calibration and the decision threshold of a
+// logistic regression, chosen on out-of-fold probabilities.
+type operatingPoint struct {
+	calibration *model.Platt
+	// threshold is the calibrated probability from which rows are
+	// classified as 1, and tpr and fpr are its out-of-fold true and false
+	// positive rates.
+	threshold, tpr, fpr float64
+	// auc is the out-of-fold AUC and rows the number of rows it is
+	// computed on.
+	auc  float64
+	rows int
+}
+
+// calibrateLogistic cross-validates a logistic regression on the rows of
+// the table to predict the probability of every row from a model that
+// was not fitted on it. It fits a Platt scaling of these out-of-fold
+// probabilities and chooses the threshold with the largest Youden index
+// on the ROC curve of the calibrated ones, so that a model saved with
+// both makes on every row the decision the cross-validation measured.
+func calibrateLogistic(ctx context.Context, t *table, target string, names []string, hp hyperparams, numFolds int) (*operatingPoint, error) {
+	labels, err := t.floats(target)
+	if err != nil {
+		return nil, err
+	}
+	strata := make([]int, len(labels))
+	for i, label := range labels {
+		strata[i] = int(label)
+	}
+	folds, err := split.RepeatedStratifiedKFold(strata, numFolds, 1, int64(hp.seed))
+	if err != nil {
+		return nil, err
+	}
+	// Gradient descent starts from zero weights, so the folds draw no
+	// random numbers.
+	probabilities, err := split.CrossValidate(ctx, folds, 0, hp.seed, func(k int, fold split.Fold, r *rand.Rand) ([]float64, error) {
+		fitted, err := fitModel(ctx, model.KindLogistic, tree.Classification, t.subset(fold.Train), target, names, nil, hp)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(fitted).probabilities(t.subset(fold.Test))
+	})
+	if err != nil {
+		return nil, err
+	}
+	oof := make([]float64, len(labels))
+	for k, fold := range folds {
+		for i, row := range fold.Test {
+			oof[row] = probabilities[k][i]
+		}
+	}
+	calibration, err := logistic.FitPlatt(oof, labels)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range oof {
+		oof[i] = calibration.Apply(p)
+	}
+	curve, err := metrics.ROC(labels, oof)
+	if err != nil {
+		return nil, err
+	}
+	auc, err := metrics.AUC(labels, oof)
+	if err != nil {
+		return nil, err
+	}
+	op := &operatingPoint{calibration: calibration, threshold: hp.threshold, auc: auc, rows: len(oof)}
+	if threshold, _ := curve.YoudenThreshold(); !math.IsInf(threshold, 1) {
+		op.threshold = threshold
+	}
+	binary := metrics.BinaryCounts(labels, thresholded(oof, op.threshold))
+	op.tpr, op.fpr = binary.Recall(), 1-binary.Specificity()
+	return op, nil
+}
+
+// thresholded returns 1 for the probabilities from the threshold and 0
+// for the others, as model.Logistic.Predict does.
+func thresholded(probabilities []float64, threshold float64) []float64 {
+	predicted := make([]float64, len(probabilities))
+	for i, p := range probabilities {
+		if p >= threshold {
+			predicted[i] = 1
+		}
+	}
+	return predicted
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/segment"
+)
+
+// writeCard writes a Markdown model card to path: what the model is, its
+// scores on the rows of the table and its scores per segment, with the
+// underperforming segments listed first.
+func writeCard(path, modelPath string, s *saved, t *table, target string, reports []*segment.Report) error {
+	observed, err := s.observed(t, target)
+	if err != nil {
+		return err
+	}
+	predicted, err := s.predictAll(t)
+	if err != nil {
+		return err
+	}
+	var scores map[string]float64
+	if s.classifier {
+		scores = metrics.Summarize(s.confusion(observed, predicted))
+	} else {
+		r, err := metrics.RegressionScores(observed, predicted, len(s.features))
+		if err != nil {
+			return err
+		}
+		scores = r.Map()
+	}
+	// Add the registered metrics, custom ones included, leaving out
+	// those the model gives no probabilities for.
+	registered, err := s.scores(t, target)
+	if err != nil {
+		return err
+	}
+	for name, score := range registered {
+		if !math.IsNaN(score) {
+			scores[name] = score
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Model card: %s\n\n", filepath.Base(modelPath))
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Model | %s |\n", s.kind)
+	fmt.Fprintf(&b, "| Target | %s |\n", target)
+	fmt.Fprintf(&b, "| Features | %s |\n", strings.Join(s.features, ", "))
+	if s.classes != nil {
+		fmt.Fprintf(&b, "| Classes | %s |\n", strings.Join(s.classes, ", "))
+	}
+	fmt.Fprintf(&b, "| Evaluated on | %s (%d rows) |\n", t.path, len(t.rows))
+	fmt.Fprintf(&b, "| Evaluated at | %s |\n\n", time.Now().Format(time.RFC3339))
+	b.WriteString("## Evaluation\n\n| metric | value |\n|---|---:|\n")
+	names := make([]string, 0, len(scores))
+	for name := range scores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "| %s | %.4f |\n", name, scores[name])
+	}
+	if len(reports) > 0 {
+		b.WriteString("\n## Segments\n\n")
+		var under []string
+		for _, r := range reports {
+			for _, res := range r.Underperforming() {
+				under = append(under, fmt.Sprintf("- %s: %s = %.4f over %d rows (%+.4f)", res.Segment, r.Metric, res.Score, res.Rows, res.Gap))
+			}
+		}
+		if len(under) == 0 {
+			b.WriteString("No segment underperforms.\n")
+		} else {
+			b.WriteString("Underperforming segments:\n\n" + strings.Join(under, "\n") + "\n")
+		}
+		for _, r := range reports {
+			b.WriteString("\n")
+			if err := r.WriteMarkdown(&b); err != nil {
+				return err
+			}
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
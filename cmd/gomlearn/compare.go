@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/compare"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/pii"
+	"github.com/bachhm.dev/go-machine-learning/pkg/segment"
+)
+
+// compareModels scores a champion and a challenger model on the same
+// labeled CSV file and reports how they differ: the delta of every
+// metric with its bootstrap interval, McNemar's test for classifiers,
+// the deltas per segment and the rows the models disagree on.
+func compareModels(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("compare-models", flag.ExitOnError)
+	championPath := fs.String("champion", "", "model file of the model in use")
+	challengerPath := fs.String("challenger", "", "model file of the model meant to replace it")
+	dataPath := fs.String("data", "", "CSV file of the labeled test rows both models are scored on")
+	target := fs.String("target", "", "column holding the labels (default the target the champion was trained on)")
+	var specs specList
+	fs.Var(&specs, "segment", "compare the models on the segments of a column, such as purpose, or the bands of a numeric column, such as fico:650,700 (repeatable)")
+	replicas := fs.Int("replicas", 1000, "bootstrap resamples of the rows for the intervals of the deltas")
+	level := fs.Float64("level", 0.95, "coverage of the intervals of the deltas")
+	seed := fs.Int64("seed", 1, "seed of the bootstrap resamples")
+	tolerance := fs.Float64("disagree-tol", 0.1, "for regressions, the difference of the predictions, in standard deviations of the target, from which the models disagree on a row")
+	keys := addKeyFlags(fs, false, true)
+	piiFlags := addPIIFlags(fs)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *championPath == "" || *challengerPath == "" || *dataPath == "" {
+		return errors.New("compare-models: -champion, -challenger and -data are required")
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	champion, err := loadModel(*championPath, p)
+	if err != nil {
+		return err
+	}
+	challenger, err := loadModel(*challengerPath, p)
+	if err != nil {
+		return err
+	}
+	if champion.classifier != challenger.classifier {
+		return errors.New("compare-models: one model is a classifier and the other a regression")
+	}
+	if *target == "" {
+		*target = champion.target
+	}
+	if *target == "" {
+		return fmt.Errorf("compare-models: %s does not name its target, set -target", *championPath)
+	}
+	policy, err := piiFlags.policy()
+	if err != nil {
+		return err
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	if err := policy.Check(t.header); err != nil {
+		return err
+	}
+	observed, champ, chall, classes, err := comparable(champion, challenger, t, *target)
+	if err != nil {
+		return err
+	}
+	classifier := champion.classifier
+	fmt.Printf("Champion %s (%s) against challenger %s (%s) on %d rows of %s\n\n",
+		*championPath, champion.kind, *challengerPath, challenger.kind, len(observed), *dataPath)
+	// Compare the metrics with their bootstrap intervals.
+	metricsOf := []segment.Metric{segment.MAE, segment.RMSE}
+	if classifier {
+		metricsOf = []segment.Metric{segment.Accuracy, segment.BalancedAccuracy}
+	}
+	cfg := compare.Bootstrap{Replicas: *replicas, Level: *level, Seed: *seed}
+	var deltas []compare.Delta
+	for _, m := range metricsOf {
+		d, err := compare.BootstrapDelta(observed, champ, chall, m, cfg)
+		if err != nil {
+			return err
+		}
+		deltas = append(deltas, d)
+	}
+	if err := writeDeltas(os.Stdout, deltas, *level); err != nil {
+		return err
+	}
+	if classifier {
+		test, err := compare.McNemar(observed, champ, chall)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nMcNemar's test: %d rows only the champion predicts correctly, %d only the challenger, p = %.4f\n",
+			test.ChampionOnly, test.ChallengerOnly, test.P)
+	}
+	// Compare the models on every segment.
+	for _, text := range specs {
+		spec, err := segment.Parse(text)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		if err := writeSegmentDeltas(os.Stdout, t, policy, spec, observed, champ, chall, metricsOf[0]); err != nil {
+			return err
+		}
+	}
+	// Describe the rows the models disagree on.
+	fmt.Println()
+	if classifier {
+		d, err := compare.Classes(observed, champ, chall)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Disagreement: %d of %d rows (%.2f%%)\n", d.Disagree, d.Rows, 100*d.Rate())
+		fmt.Printf("Of those, the champion is right on %d, the challenger on %d and neither on %d\n", d.ChampionRight, d.ChallengerRight, d.BothWrong)
+		return writeSwitches(os.Stdout, classes, champ, chall)
+	}
+	_, std := meanStd(observed)
+	d, err := compare.Values(champ, chall, *tolerance*std)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Mean absolute difference of the predictions = %.4f\n", d.MeanAbsDiff)
+	fmt.Printf("Disagreement beyond %.4f: %d of %d rows (%.2f%%)\n", *tolerance*std, d.Disagree, d.Rows, 100*d.Rate())
+	return nil
+}
+
+// comparable returns the observed values and the predictions of both
+// models for the rows of the table. The classes of classifiers are
+// indexed anew by name, so that models listing their classes in
+// different orders compare, and their names are returned. Numeric names
+// match by value, as a logistic regression names the class "1" that a
+// tree read from the same column names "1.0".
+func comparable(champion, challenger *saved, t *table, target string) (observed, champ, chall []float64, classes []string, err error) {
+	if observed, err = champion.observed(t, target); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if champ, err = champion.predictAll(t); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("champion: %v", err)
+	}
+	if chall, err = challenger.predictAll(t); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("challenger: %v", err)
+	}
+	if !champion.classifier {
+		return observed, champ, chall, nil, nil
+	}
+	index := make(map[string]float64)
+	reindex := func(s *saved, values []float64) {
+		for i, v := range values {
+			name := s.format(v)
+			key := name
+			if f, err := strconv.ParseFloat(name, 64); err == nil {
+				key = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+			if _, ok := index[key]; !ok {
+				index[key] = float64(len(classes))
+				classes = append(classes, name)
+			}
+			values[i] = index[key]
+		}
+	}
+	reindex(champion, observed)
+	reindex(champion, champ)
+	reindex(challenger, chall)
+	return observed, champ, chall, classes, nil
+}
+
+// writeDeltas prints the metric of both models and their delta as a
+// table, with a verdict where the interval excludes 0.
+func writeDeltas(w io.Writer, deltas []compare.Delta, level float64) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "metric\tchampion\tchallenger\tdelta\t%g%% interval\tp\t\n", 100*level)
+	for _, d := range deltas {
+		verdict := ""
+		switch {
+		case d.Better():
+			verdict = "challenger better"
+		case d.Worse():
+			verdict = "challenger worse"
+		}
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%+.4f\t[%+.4f, %+.4f]\t%.4f\t%s\n",
+			d.Metric, d.Champion, d.Challenger, d.Delta, d.Low, d.High, d.P, verdict)
+	}
+	return tw.Flush()
+}
+
+// writeSegmentDeltas prints the metric of both models on every segment of
+// the spec, naming the segments of PII columns by their masked values.
+func writeSegmentDeltas(w io.Writer, t *table, policy *pii.Policy, spec segment.Spec, observed, champ, chall []float64, m segment.Metric) error {
+	segments, err := assignSegments(t, spec, policy)
+	if err != nil {
+		return err
+	}
+	a, err := segment.Evaluate(spec, segments, observed, champ, m, segment.Config{})
+	if err != nil {
+		return err
+	}
+	b, err := segment.Evaluate(spec, segments, observed, chall, m, segment.Config{})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Segments of %s (%s)\n", spec.Column, m.Name)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "segment\trows\tchampion\tchallenger\tdelta")
+	for i, res := range a.Segments {
+		fmt.Fprintf(tw, "%s\t%d\t%.4f\t%.4f\t%+.4f\n", res.Segment, res.Rows, res.Score, b.Segments[i].Score, b.Segments[i].Score-res.Score)
+	}
+	return tw.Flush()
+}
+
+// writeSwitches prints how often every predicted class of the champion
+// turns into another class of the challenger, the most frequent first.
+func writeSwitches(w io.Writer, classes []string, champ, chall []float64) error {
+	type pair struct{ from, to float64 }
+	counts := make(map[pair]int)
+	for i := range champ {
+		if champ[i] != chall[i] {
+			counts[pair{champ[i], chall[i]}]++
+		}
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	pairs := make([]pair, 0, len(counts))
+	for p := range counts {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if counts[pairs[a]] != counts[pairs[b]] {
+			return counts[pairs[a]] > counts[pairs[b]]
+		}
+		return pairs[a].from < pairs[b].from || pairs[a].from == pairs[b].from && pairs[a].to < pairs[b].to
+	})
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "champion\tchallenger\trows")
+	for _, p := range pairs {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", classes[int(p.from)], classes[int(p.to)], counts[p])
+	}
+	return tw.Flush()
+}
+
+// meanStd returns the mean and the standard deviation of the values.
+func meanStd(values []float64) (mean, std float64) {
+	for _, v := range values {
+		mean += v / float64(len(values))
+	}
+	for _, v := range values {
+		std += (v - mean) * (v - mean) / float64(len(values))
+	}
+	return mean, math.Sqrt(std)
+}
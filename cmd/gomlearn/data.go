@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+)
+
+// table is a CSV file with a header row, held as strings.
+type table struct {
+	path   string
+	header []string
+	rows   [][]string
+}
+
+// readTable reads the CSV file at path.
+func readTable(path string) (*table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%s: no rows below the header", path)
+	}
+	return &table{path: path, header: records[0], rows: records[1:]}, nil
+}
+
+// subset returns the table of the given rows, which it shares. The row
+// numbers of its errors count the rows of the subset.
+func (t *table) subset(rows []int) *table {
+	sub := &table{path: t.path, header: t.header, rows: make([][]string, len(rows))}
+	for i, row := range rows {
+		sub.rows[i] = t.rows[row]
+	}
+	return sub
+}
+
+// column returns the index of the named column.
+func (t *table) column(name string) (int, error) {
+	j := slices.Index(t.header, name)
+	if j < 0 {
+		return 0, fmt.Errorf("%s: no column %q", t.path, name)
+	}
+	return j, nil
+}
+
+// values returns the values of the named column as text.
+func (t *table) values(name string) ([]string, error) {
+	j, err := t.column(name)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(t.rows))
+	for i, row := range t.rows {
+		values[i] = row[j]
+	}
+	return values, nil
+}
+
+// floats returns the values of the named column.
+func (t *table) floats(name string) ([]float64, error) {
+	j, err := t.column(name)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(t.rows))
+	for i, row := range t.rows {
+		values[i], err = strconv.ParseFloat(row[j], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: row %d: column %q: %v", t.path, i+1, name, err)
+		}
+	}
+	return values, nil
+}
+
+// matrix returns the named columns as a feature matrix.
+func (t *table) matrix(names []string) (*mat64.Dense, error) {
+	x := mat64.NewDense(len(t.rows), len(names), nil)
+	for j, name := range names {
+		values, err := t.floats(name)
+		if err != nil {
+			return nil, err
+		}
+		x.SetCol(j, values)
+	}
+	return x, nil
+}
+
+// targets returns the targets of trees and forests of the task: the class
+// indices of the named column, as labels returns them, for
+// classification, and its values, without classes, for regression.
+func (t *table) targets(task tree.Task, name string, classes []string) ([]float64, []string, error) {
+	if task == tree.Classification {
+		return t.labels(name, classes)
+	}
+	y, err := t.floats(name)
+	return y, nil, err
+}
+
+// labels returns the class index of every row of the named column. When
+// classes is nil, the classes are the distinct values of the column in
+// sorted order; otherwise rows of other classes are an error.
+func (t *table) labels(name string, classes []string) ([]float64, []string, error) {
+	j, err := t.column(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if classes == nil {
+		for _, row := range t.rows {
+			if !slices.Contains(classes, row[j]) {
+				classes = append(classes, row[j])
+			}
+		}
+		slices.Sort(classes)
+	}
+	labels := make([]float64, len(t.rows))
+	for i, row := range t.rows {
+		k := slices.Index(classes, row[j])
+		if k < 0 {
+			return nil, nil, fmt.Errorf("%s: row %d: class %q was not seen in training", t.path, i+1, row[j])
+		}
+		labels[i] = float64(k)
+	}
+	return labels, classes, nil
+}
+
+// transformed returns the table of the named features of x, followed by
+// the target column of t, so that models fit on preprocessed rows as on
+// any table.
+func (t *table) transformed(names []string, x *mat64.Dense, target string) (*table, error) {
+	y, err := t.values(target)
+	if err != nil {
+		return nil, err
+	}
+	out := &table{path: t.path, header: append(slices.Clone(names), target), rows: make([][]string, len(t.rows))}
+	for i := range out.rows {
+		row := make([]string, 0, len(out.header))
+		for _, v := range x.RawRowView(i) {
+			row = append(row, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		out.rows[i] = append(row, y[i])
+	}
+	return out, nil
+}
+
+// featureNames returns the comma separated columns of list, or every
+// column but the target when list is empty.
+func (t *table) featureNames(list, target string) ([]string, error) {
+	if list == "" {
+		var names []string
+		for _, name := range t.header {
+			if name != target {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+	names := strings.Split(list, ",")
+	for _, name := range names {
+		if _, err := t.column(name); err != nil {
+			return nil, err
+		}
+		if name == target {
+			return nil, fmt.Errorf("the target %q is also a feature", target)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/surrogate"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+)
+
+// distill grows a shallow decision tree on the predictions of a saved
+// model, and prints its fidelity to the model and its rules.
+func distill(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("distill", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model file saved by gomlearn train or by the examples")
+	dataPath := fs.String("data", "", "CSV file of the rows the model predicts, labeled or not")
+	maxDepth := fs.Int("max-depth", 3, "largest depth of the surrogate tree")
+	minLeaf := fs.Int("min-samples-leaf", 5, "fewest rows of every leaf of the surrogate tree")
+	holdout := fs.Float64("holdout", 0.25, "share of the rows held out from growing the tree to measure its fidelity")
+	seed := fs.Int64("seed", 1, "seed of the choice of the held out rows")
+	out := fs.String("out", "", "path the surrogate tree is saved to, as a tree model (default: not saved)")
+	keys := addKeyFlags(fs, true, true)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" || *dataPath == "" {
+		return errors.New("distill: -model and -data are required")
+	}
+	if *holdout < 0 || *holdout >= 1 {
+		return fmt.Errorf("distill: -holdout %g, want a share in [0, 1)", *holdout)
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	s, err := loadModel(*modelPath, p)
+	if err != nil {
+		return err
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	x, err := s.matrix(t)
+	if err != nil {
+		return err
+	}
+	predictions, err := s.predictAll(t)
+	if err != nil {
+		return err
+	}
+	// Grow the tree on part of the rows and keep the others to check
+	// that it mimics the model beyond the rows it has seen.
+	grow, held := split.TrainTest(len(predictions), split.Config{TestFraction: *holdout, Shuffle: true, Seed: *seed})
+	if len(grow) == 0 {
+		return fmt.Errorf("distill: no rows left to grow the tree on")
+	}
+	task := tree.Regression
+	if s.classifier {
+		task = tree.Classification
+	}
+	st := tree.New(task, tree.Params{MaxDepth: *maxDepth, MinSamplesLeaf: *minLeaf})
+	if task == tree.Classification {
+		st.NumClasses = max(len(s.classes), 2)
+	}
+	growX, growPredictions := subset(x, predictions, grow)
+	fidelity, err := surrogate.Distill(st, growX, growPredictions, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Surrogate tree of the %s model: depth %d, %d leaves\n", s.kind, st.Depth(), st.Leaves())
+	fmt.Printf("Fidelity on the %d rows it was grown on: %.4f\n", len(grow), fidelity)
+	if len(held) > 0 {
+		heldX, heldPredictions := subset(x, predictions, held)
+		heldFidelity, err := surrogate.Fidelity(st, heldX, heldPredictions)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Fidelity on the %d held out rows: %.4f\n", len(held), heldFidelity)
+	}
+	fmt.Println()
+	if err := st.WriteRules(os.Stdout, s.features, s.classes); err != nil {
+		return err
+	}
+	if *out == "" {
+		return nil
+	}
+	var m any = &model.Tree{Target: s.target, Features: s.features, Classes: s.classes, Tree: st}
+	kind := model.KindTree
+	if s.pipeline != nil {
+		// The tree splits the features of the pipeline, so it keeps its
+		// preprocessing.
+		pl := *s.pipeline
+		if err := pl.SetModel(kind, m); err != nil {
+			return err
+		}
+		m, kind = &pl, model.KindPipeline
+	}
+	if err := model.SaveProtected(*out, kind, m, p); err != nil {
+		return err
+	}
+	fmt.Printf("\nSaved the surrogate tree to %s\n", *out)
+	return nil
+}
+
+// subset returns the given rows of x and their values.
+func subset(x *mat64.Dense, values []float64, rows []int) (*mat64.Dense, []float64) {
+	_, numCols := x.Dims()
+	sx := mat64.NewDense(len(rows), numCols, nil)
+	sv := make([]float64, len(rows))
+	for i, row := range rows {
+		sx.SetRow(i, x.RawRowView(row))
+		sv[i] = values[row]
+	}
+	return sx, sv
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/pii"
+	"github.com/bachhm.dev/go-machine-learning/pkg/segment"
+	"github.com/sjwhitworth/golearn/evaluation"
+)
+
+// evaluate scores a saved model on a labeled CSV file, overall and on
+// every segment of the rows requested by -segment. Besides the built-in
+// reports, it prints every metric of the metrics registry for the model's
+// task, custom ones included.
+func evaluate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model file saved by gomlearn train or by the examples")
+	dataPath := fs.String("data", "", "CSV file of the labeled rows to score the model on")
+	target := fs.String("target", "", "column holding the labels (default the target the model was trained on)")
+	var specs specList
+	fs.Var(&specs, "segment", "score the segments of a column, such as purpose, or the bands of a numeric column, such as fico:650,700 (repeatable)")
+	metricName := fs.String("metric", "", "registered metric the segments are scored by (default accuracy for classifiers and rmse for regressions)")
+	tolerance := fs.Float64("tolerance", 0.05, "largest shortfall of a segment from the overall score not flagged as underperforming")
+	minRows := fs.Int("min-rows", 20, "fewest rows of a segment flagged as underperforming")
+	cardPath := fs.String("card", "", "path of a Markdown model card written with the scores and the segments (default: none)")
+	keys := addKeyFlags(fs, false, true)
+	piiFlags := addPIIFlags(fs)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" || *dataPath == "" {
+		return errors.New("evaluate: -model and -data are required")
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	s, err := loadModel(*modelPath, p)
+	if err != nil {
+		return err
+	}
+	if *target == "" {
+		*target = s.target
+	}
+	if *target == "" {
+		return fmt.Errorf("evaluate: %s does not name its target, set -target", *modelPath)
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	if err := report(os.Stdout, s, t, *target); err != nil {
+		return err
+	}
+	scores, err := s.scores(t, *target)
+	if err != nil {
+		return err
+	}
+	fmt.Println("\nRegistered metrics")
+	if err := metrics.WriteScores(os.Stdout, scores); err != nil {
+		return err
+	}
+	m, err := segmentMetric(s, *metricName)
+	if err != nil {
+		return err
+	}
+	policy, err := piiFlags.policy()
+	if err != nil {
+		return err
+	}
+	if err := policy.Check(t.header); err != nil {
+		return err
+	}
+	reports, err := segmentReports(s, t, policy, *target, specs, m, segment.Config{Tolerance: *tolerance, MinRows: *minRows})
+	if err != nil {
+		return err
+	}
+	for _, r := range reports {
+		fmt.Println()
+		if err := r.Write(os.Stdout); err != nil {
+			return err
+		}
+	}
+	if *cardPath == "" {
+		return nil
+	}
+	if err := writeCard(*cardPath, *modelPath, s, t, *target, reports); err != nil {
+		return err
+	}
+	fmt.Printf("\nWrote the model card to %s\n", *cardPath)
+	return nil
+}
+
+// specList collects the values of a repeated flag.
+type specList []string
+
+func (l *specList) String() string { return fmt.Sprint(*l) }
+
+func (l *specList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// segmentMetric returns the registered metric of the name for scoring the
+// segments, by default the accuracy for classifiers and the RMSE for
+// regressions.
+func segmentMetric(s *saved, name string) (segment.Metric, error) {
+	if name == "" {
+		if s.classifier {
+			return segment.Accuracy, nil
+		}
+		return segment.RMSE, nil
+	}
+	m, err := metrics.Lookup(name)
+	if err != nil {
+		return segment.Metric{}, err
+	}
+	if m.Classifier != s.classifier {
+		return segment.Metric{}, fmt.Errorf("metric %q does not score %s models", name, s.kind)
+	}
+	return segment.FromMetric(m), nil
+}
+
+// segmentReports scores the model on the segments of every spec by the
+// metric, naming the segments of PII columns by their masked values.
+func segmentReports(s *saved, t *table, policy *pii.Policy, target string, specs []string, m segment.Metric, cfg segment.Config) ([]*segment.Report, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	observed, err := s.observed(t, target)
+	if err != nil {
+		return nil, err
+	}
+	predicted, err := s.predictAll(t)
+	if err != nil {
+		return nil, err
+	}
+	var reports []*segment.Report
+	for _, text := range specs {
+		spec, err := segment.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		segments, err := assignSegments(t, spec, policy)
+		if err != nil {
+			return nil, err
+		}
+		r, err := segment.Evaluate(spec, segments, observed, predicted, m, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(r.Overall) {
+			// Segments are scored on the predictions alone, which metrics
+			// such as the AUC cannot score.
+			return nil, fmt.Errorf("metric %q cannot score segments, as it needs the predicted probabilities", m.Name)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// assignSegments returns the segment of every row of the table. The
+// segments of the values of a PII column are named by the masked values,
+// which group the rows as the values do when hashed; the bands of a
+// numeric column are named by their edges alone.
+func assignSegments(t *table, spec segment.Spec, policy *pii.Policy) ([]string, error) {
+	values, err := t.values(spec.Column)
+	if err != nil {
+		return nil, err
+	}
+	if len(spec.Edges) == 0 {
+		values = policy.Values(spec.Column, values)
+	}
+	segments, err := spec.Assign(values)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", t.path, err)
+	}
+	return segments, nil
+}
+
+// report prints the scores of the model on the rows of the table: the
+// summary, per-class scores and confusion matrix of classifiers, and the
+// errors of regressions.
+func report(w io.Writer, s *saved, t *table, target string) error {
+	observed, err := s.observed(t, target)
+	if err != nil {
+		return err
+	}
+	predicted, err := s.predictAll(t)
+	if err != nil {
+		return err
+	}
+	if !s.classifier {
+		scores, err := metrics.RegressionScores(observed, predicted, len(s.features))
+		if err != nil {
+			return err
+		}
+		metrics.WriteRegression(w, scores)
+		return nil
+	}
+	cm := s.confusion(observed, predicted)
+	if err := metrics.WriteSummary(w, metrics.Summarize(cm)); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	if err := metrics.WriteReport(w, metrics.PerClass(cm)); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	classes := metrics.Classes(cm)
+	return metrics.WriteConfusion(w, classes, metrics.Counts(cm, classes))
+}
+
+// confusion tallies the observed against the predicted classes.
+func (s *saved) confusion(observed, predicted []float64) evaluation.ConfusionMatrix {
+	cm := make(evaluation.ConfusionMatrix)
+	for i := range observed {
+		ref := s.format(observed[i])
+		if cm[ref] == nil {
+			cm[ref] = make(map[string]int)
+		}
+		cm[ref][s.format(predicted[i])]++
+	}
+	return cm
+}
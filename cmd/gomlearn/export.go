@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+)
+
+// export writes a saved linear or logistic regression with fixed-point
+// integer coefficients, for encrypted inference engines. Checked on the
+// rows of -data, it reports how far the integer scores stray from the
+// model and records the largest score, which sizes the plaintext modulus
+// of the encryption scheme.
+func export(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	modelPath := fs.String("model", "", "linear or logistic model file saved by gomlearn train or by the examples")
+	format := fs.String("format", "fixed-point", "export format: fixed-point")
+	coefficientBits := fs.Int("coefficient-bits", 16, "fractional bits of the fixed-point coefficients")
+	featureBits := fs.Int("feature-bits", 8, "fractional bits of the fixed-point feature values")
+	dataPath := fs.String("data", "", "CSV file of rows to check the export on (default: not checked)")
+	out := fs.String("out", "model.fixed.json", "path the export is written to")
+	keys := addKeyFlags(fs, false, true)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" {
+		return errors.New("export: -model is required")
+	}
+	if *format != "fixed-point" {
+		return fmt.Errorf("export: unknown format %q, expected fixed-point", *format)
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	s, err := loadModel(*modelPath, p)
+	if err != nil {
+		return err
+	}
+	if s.pipeline != nil {
+		return fmt.Errorf("export: %s is a pipeline, whose preprocessing has no fixed-point form", *modelPath)
+	}
+	fp, err := model.Quantize(s.model, *coefficientBits, *featureBits)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Fixed-point %s model: %d coefficient and %d feature fractional bits, scores scaled by 2^%d\n\n",
+		fp.Kind, fp.CoefficientBits, fp.FeatureBits, fp.CoefficientBits+fp.FeatureBits)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	// Show every integer with the real value it stands for.
+	scoreBits := fp.CoefficientBits + fp.FeatureBits
+	fmt.Fprintln(tw, "term\tinteger\tvalue")
+	for j, c := range fp.Coefficients {
+		fmt.Fprintf(tw, "%s\t%d\t%.6g\n", fp.Features[j], c, math.Ldexp(float64(c), -fp.CoefficientBits))
+	}
+	fmt.Fprintf(tw, "intercept\t%d\t%.6g\n", fp.Intercept, math.Ldexp(float64(fp.Intercept), -scoreBits))
+	if fp.Kind == model.KindLogistic {
+		fmt.Fprintf(tw, "threshold\t%d\t%.6g (probability %.4g)\n", fp.ScoreThreshold, math.Ldexp(float64(fp.ScoreThreshold), -scoreBits), fp.Decode(fp.ScoreThreshold))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if *dataPath != "" {
+		if err := checkExport(s, fp, *dataPath); err != nil {
+			return err
+		}
+	}
+	if err := model.WriteFixedPoint(*out, fp); err != nil {
+		return err
+	}
+	fmt.Printf("\nSaved the fixed-point model to %s\n", *out)
+	return nil
+}
+
+// checkExport compares the predictions of the fixed-point model on the
+// rows of the file with those of the model, and sets its score bound.
+func checkExport(s *saved, fp *model.FixedPoint, path string) error {
+	t, err := readTable(path)
+	if err != nil {
+		return err
+	}
+	x, err := s.matrix(t)
+	if err != nil {
+		return err
+	}
+	lm, _ := s.model.(*model.Logistic)
+	var maxError float64
+	var disagree int
+	for i := range t.rows {
+		row := x.RawRowView(i)
+		encoded, err := fp.Encode(row)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %v", path, i+1, err)
+		}
+		score, err := fp.Score(encoded)
+		if err != nil {
+			return err
+		}
+		if abs := max(score, -score); abs > fp.ScoreBound {
+			fp.ScoreBound = abs
+		}
+		// Compare the probabilities of logistic regressions, and the
+		// predictions of linear ones.
+		want, err := s.model.Predict(row)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %v", path, i+1, err)
+		}
+		if lm != nil {
+			if (score >= fp.ScoreThreshold) != (want == 1) {
+				disagree++
+			}
+			if want, err = lm.Probability(row); err != nil {
+				return err
+			}
+		}
+		maxError = math.Max(maxError, math.Abs(fp.Decode(score)-want))
+	}
+	fmt.Printf("\nChecked on the %d rows of %s\n", len(t.rows), path)
+	if lm != nil {
+		fmt.Printf("Largest probability error: %.3g, classes changed: %d\n", maxError, disagree)
+	} else {
+		fmt.Printf("Largest prediction error: %.3g\n", maxError)
+	}
+	// A modulus of 2*bound+1 represents every score from -bound to bound.
+	fmt.Printf("Largest absolute score: %d, which needs a plaintext modulus of at least %d bits\n",
+		fp.ScoreBound, bits.Len64(uint64(2*fp.ScoreBound+1)))
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+)
+
+// keyFlags holds the flags naming the key files that model files are
+// encrypted, signed and verified with.
+type keyFlags struct {
+	key, signingKey, verifyKey *string
+}
+
+// addKeyFlags adds the key flags to fs: -key always, -signing-key to the
+// commands that save models and -verify-key to those that load them.
+func addKeyFlags(fs *flag.FlagSet, saves, loads bool) *keyFlags {
+	var none string
+	k := &keyFlags{signingKey: &none, verifyKey: &none}
+	k.key = fs.String("key", "", "file of the AES-256 key model files are encrypted with (default: not encrypted)")
+	if saves {
+		k.signingKey = fs.String("signing-key", "", "file of the Ed25519 key saved models are signed with (default: not signed)")
+	}
+	if loads {
+		k.verifyKey = fs.String("verify-key", "", "file of the Ed25519 public key that loaded models must be signed by (default: not verified)")
+	}
+	return k
+}
+
+// protection reads the key files.
+func (k *keyFlags) protection() (model.Protection, error) {
+	return model.ReadProtection(*k.key, *k.signingKey, *k.verifyKey)
+}
+
+// keygen writes a new encryption key and a new signing key pair.
+func keygen(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := fs.String("out", "", "prefix of the key files: <out>.key, <out>.sign and <out>.verify")
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("keygen: -out is required")
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		suffix string
+		key    []byte
+	}{{".key", key}, {".sign", private.Seed()}, {".verify", public}} {
+		if err := model.WriteKey(*out+f.suffix, f.key); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Wrote the encryption key to %[1]s.key, the signing key to %[1]s.sign and the verification key to %[1]s.verify\n", *out)
+	fmt.Println("Keep the .key and .sign files secret; the .verify file can be shared with the programs that load the models.")
+	return nil
+}
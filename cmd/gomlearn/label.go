@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/labeling"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+)
+
+// label queues the rows a saved classifier is least sure of for people to
+// label, or merges the labeled rows of a queue into training rows.
+func label(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("label: expected export or merge")
+	}
+	switch args[0] {
+	case "export":
+		return exportQueue(args[1:])
+	case "merge":
+		return mergeQueue(args[1:])
+	}
+	return fmt.Errorf("label: unknown subcommand %q, expected export or merge", args[0])
+}
+
+// exportQueue writes the rows of a CSV file whose probability of class 1
+// is closest to the threshold of a saved binary classifier to a queue
+// file, in CSV or JSON lines.
+func exportQueue(args []string) error {
+	fs := flag.NewFlagSet("label export", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model file of a binary classifier saved by gomlearn train or by the examples")
+	dataPath := fs.String("data", "", "CSV file of the unlabeled rows")
+	threshold := fs.Float64("threshold", -1, "probability the uncertainty is measured from (default the threshold of logistic regressions, 0.5 for other models)")
+	margin := fs.Float64("margin", 0.1, "queue the rows of probability within this of the threshold")
+	limit := fs.Int("limit", 0, "queue at most this many rows, the closest to the threshold first (0 queues them all)")
+	idColumn := fs.String("id", "", "column identifying the rows (default the row number)")
+	out := fs.String("out", "queue.csv", "queue file, in JSON lines if its extension is .jsonl or .ndjson and in CSV otherwise")
+	keys := addKeyFlags(fs, false, true)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" || *dataPath == "" {
+		return errors.New("label export: -model and -data are required")
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	s, err := loadModel(*modelPath, p)
+	if err != nil {
+		return err
+	}
+	if !s.binary() {
+		return fmt.Errorf("label export: a %s model predicts no probability of class 1", s.kind)
+	}
+	if *threshold < 0 {
+		*threshold = 0.5
+		if lm, ok := s.model.(*model.Logistic); ok {
+			*threshold = lm.Threshold
+		}
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	proba, err := s.probabilities(t)
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.path, err)
+	}
+	indices := labeling.Uncertain(proba, *threshold, *margin, *limit)
+	q, err := labeling.New(t.header, t.rows, indices, proba, *idColumn)
+	if err != nil {
+		return err
+	}
+	if err := q.Write(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Queued %d of %d rows within %g of the threshold %g to %s\n", len(q.Items), len(t.rows), *margin, *threshold, *out)
+	return nil
+}
+
+// mergeQueue writes training rows with the labeled rows of a queue added,
+// ready to retrain a model on, such as with gomlearn train -init.
+func mergeQueue(args []string) error {
+	fs := flag.NewFlagSet("label merge", flag.ExitOnError)
+	queuePath := fs.String("queue", "", "queue file written by label export, with the labels filled in")
+	dataPath := fs.String("data", "", "CSV file of the training rows")
+	target := fs.String("target", "", "column the labels go to")
+	idColumn := fs.String("id", "", "column identifying the rows, so that a queued row already in the training rows is relabeled rather than added again (default every labeled row is added)")
+	out := fs.String("out", "", "CSV file of the merged training rows")
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *queuePath == "" || *dataPath == "" || *target == "" || *out == "" {
+		return errors.New("label merge: -queue, -data, -target and -out are required")
+	}
+	q, err := labeling.Read(*queuePath)
+	if err != nil {
+		return err
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	labeled := q.Labeled()
+	rows, added, relabeled, err := labeling.Merge(t.header, t.rows, labeled, *target, *idColumn)
+	if err != nil {
+		return fmt.Errorf("%s: %v", *queuePath, err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write(t.header)
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Merged %d of %d queued rows into %s: %d added, %d relabeled, %d rows in all\n", len(labeled), len(q.Items), *out, added, relabeled, len(rows))
+	return nil
+}
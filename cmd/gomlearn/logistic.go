@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+	"github.com/bachhm.dev/go-machine-learning/pkg/transform"
+	"github.com/gonum/matrix/mat64"
+)
+
+// fitLogistic fits a logistic regression of the 0 and 1 labels with
+// package logistic, by full batch gradient descent with the Adam optimizer
+// from zero weights. The features are standardized first, so that one
+// learning rate suits every feature; the model keeps their means and
+// deviations to predict from raw values. Fitting stops with the error of
+// ctx once ctx is cancelled.
+func fitLogistic(ctx context.Context, x *mat64.Dense, y []float64, steps int, learningRate float64) (*model.Logistic, error) {
+	f, err := newLogisticFit(x, y)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.run(ctx, steps, learningRate); err != nil {
+		return nil, err
+	}
+	return f.model, nil
+}
+
+// logisticFit is a logistic regression being fitted by fitLogistic, which
+// can run further steps, as a warm start: running n steps and then m more
+// gives the model of n + m steps.
+type logisticFit struct {
+	model *model.Logistic
+	// z holds the standardized features and y the labels.
+	z *mat64.Dense
+	y []float64
+	// classifier trains the weights of the model, warm started so that
+	// every run continues the previous one.
+	classifier *logistic.Classifier
+}
+
+// newLogisticFit returns the fit of a logistic regression of the labels
+// on the rows of x, before its first step.
+func newLogisticFit(x *mat64.Dense, y []float64) (*logisticFit, error) {
+	_, numFeatures := x.Dims()
+	// Standardize every feature.
+	var scaler transform.Standard
+	if err := scaler.Fit(x); err != nil {
+		return nil, err
+	}
+	return startLogisticFit(x, y, &model.Logistic{
+		Shift:     scaler.Mean,
+		Scale:     scaler.Std,
+		Weights:   make([]float64, numFeatures+1),
+		Threshold: 0.5,
+	})
+}
+
+// resumeLogisticFit returns the fit of a logistic regression of the labels
+// on the rows of x starting from the weights of a saved one, such as to
+// retrain it on rows labeled since. The features are standardized by the
+// means and deviations of the saved model, which its weights apply to.
+func resumeLogisticFit(x *mat64.Dense, y []float64, init *model.Logistic) (*logisticFit, error) {
+	_, numFeatures := x.Dims()
+	if len(init.Shift) != numFeatures || len(init.Scale) != numFeatures || len(init.Weights) != numFeatures+1 {
+		return nil, fmt.Errorf("the logistic regression to start from has %d features, not %d", len(init.Weights)-1, numFeatures)
+	}
+	return startLogisticFit(x, y, &model.Logistic{
+		Shift:     slices.Clone(init.Shift),
+		Scale:     slices.Clone(init.Scale),
+		Weights:   slices.Clone(init.Weights),
+		Threshold: init.Threshold,
+	})
+}
+
+// startLogisticFit returns the fit of the labels on the rows of x from the
+// weights of m, standardizing the rows by its shift and scale.
+func startLogisticFit(x *mat64.Dense, y []float64, m *model.Logistic) (*logisticFit, error) {
+	scaler := transform.Standard{Mean: m.Shift, Std: m.Scale}
+	z, err := scaler.Transform(x)
+	if err != nil {
+		return nil, err
+	}
+	classifier := logistic.New(logistic.Options{
+		NewOptimizer: func() optim.Optimizer { return optim.NewAdam(0.9, 0.999, 1e-8) },
+	})
+	classifier.WarmStart = true
+	classifier.Weights = slices.Clone(m.Weights)
+	return &logisticFit{model: m, z: z, y: y, classifier: classifier}, nil
+}
+
+// run takes further steps until steps have been run in all, each against
+// the gradient of the mean log loss over the rows.
+func (f *logisticFit) run(ctx context.Context, steps int, learningRate float64) error {
+	f.classifier.Steps = steps
+	f.classifier.Schedule = optim.Constant(learningRate)
+	// The weights start from those of the model and draw nothing from
+	// the random source.
+	err := f.classifier.Fit(ctx, f.z, f.y, nil)
+	copy(f.model.Weights, f.classifier.Weights)
+	return err
+}
@@ -0,0 +1,104 @@
+// Command gomlearn trains, evaluates and applies the models of this
+// repository on any CSV file, taking the dataset paths and the model type
+// as flags rather than the fixed paths of the examples:
+//
+//	gomlearn profile -data iris.csv
+//	gomlearn train -model forest -data iris.csv -target species -out iris.json
+//	gomlearn tune -model forest -data iris.csv -target species -grid trees=50,100 -out iris.json
+//	gomlearn evaluate -model iris.json -data iris_test.csv
+//	gomlearn predict -model iris.json -data new_flowers.csv -out predictions.csv
+//	cat new_flowers.csv | gomlearn score -model iris.json > predictions.csv
+//	gomlearn score -model loan.json -rules policy.yaml -in applications.csv -out decisions.csv
+//	gomlearn select -data loans.csv -target not.fully.paid -min-correlation 0.05
+//	gomlearn rfe -model linear -data advertising.csv -target Sales -plot rfe.png
+//	gomlearn distill -model iris.json -data iris.csv -max-depth 2
+//	gomlearn shift -reference training.csv -current served.csv
+//	gomlearn compare-models -champion old.json -challenger new.json -data test.csv
+//	gomlearn label export -model loan.json -data unlabeled.csv -margin 0.1 -out queue.csv
+//	gomlearn label merge -queue queue.csv -data training.csv -target not.fully.paid -out merged.csv
+//	gomlearn train -model logistic -init loan.json -data merged.csv -target not.fully.paid -steps 200 -out loan.json
+//	gomlearn keygen -out prod
+//	gomlearn runs list -metrics accuracy
+//	gomlearn runs compare forest-baseline forest-deeper
+//	gomlearn export -model loan.json -data loan_test.csv -out loan.fixed.json
+//	gomlearn quantize -model loan.json -precision int8 -data loan_test.csv -out loan.gmlc
+//
+// Every feature column must be numeric. Models are saved with package
+// model, so the models saved by the examples can be evaluated and applied
+// as well. The -key, -signing-key and -verify-key flags encrypt, sign and
+// verify model files with the keys written by keygen.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+)
+
+// command is a subcommand of gomlearn.
+type command struct {
+	name    string
+	summary string
+	// run parses the arguments following the name of the command and
+	// runs it until done or until ctx is cancelled.
+	run func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"train", "fit a model on a CSV file and save it", train},
+	{"tune", "cross-validate a grid of hyperparameters and save the best model refitted", tune},
+	{"evaluate", "score a saved model on a labeled CSV file", evaluate},
+	{"predict", "append the predictions of a saved model to a CSV file", predict},
+	{"score", "stream CSV rows from standard input to standard output with the predictions appended", score},
+	{"profile", "summarize every column of a CSV file", profile},
+	{"select", "rank the features of a CSV file by their correlation with the target", selectFeatures},
+	{"rfe", "eliminate the weakest features of a linear or logistic regression one at a time, cross-validating every subset", eliminate},
+	{"distill", "summarize a saved model by a shallow tree grown on its predictions", distill},
+	{"shift", "test whether new rows are distributed as the reference rows", detectShift},
+	{"keygen", "write a new key to encrypt model files and a key pair to sign them", keygen},
+	{"compare-models", "compare a champion and a challenger model on the same labeled rows", compareModels},
+	{"label", "queue the rows a classifier is least sure of for labeling, or merge the labeled rows into training rows: label export, label merge", label},
+	{"runs", "list the runs of the examples, or compare some of them: runs list, runs compare <id> <id>", runs},
+	{"export", "write a linear or logistic model with fixed-point integer coefficients for encrypted inference", export},
+	{"quantize", "write a linear or logistic model with int8 or float16 weights in a compact binary file", quantize},
+}
+
+// usage prints the commands to standard error.
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: gomlearn <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun gomlearn <command> -h for the flags of a command.")
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gomlearn: ")
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	// Stop the command on an interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(ctx, os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+	fmt.Fprintf(os.Stderr, "gomlearn: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+)
+
+// predictor is a model that predicts from a row of feature values.
+type predictor interface {
+	Predict(row []float64) (float64, error)
+}
+
+// saved is a model read back from a model file.
+type saved struct {
+	kind     string
+	target   string
+	features []string
+	// classifier is set for the models predicting classes. Their
+	// predictions index into classes, or are 0 and 1 when classes is
+	// nil, as for logistic regressions.
+	classifier bool
+	classes    []string
+	model      predictor
+	// pipeline, when set, turns the columns of raw rows into the
+	// features, named by features, of the model.
+	pipeline *model.Pipeline
+}
+
+// loadModel reads the model file at path, of any kind gomlearn supports,
+// decrypting and verifying it with p. Compact model files, which are
+// neither encrypted nor signed, are read as well.
+func loadModel(path string, p model.Protection) (*saved, error) {
+	compact, err := model.IsCompact(path)
+	if err != nil {
+		return nil, err
+	}
+	if compact {
+		if p.Key != nil || p.VerifyKey != nil {
+			return nil, fmt.Errorf("%s is a compact model file, which cannot be encrypted or signed", path)
+		}
+		m, err := model.LoadCompact(path)
+		if err != nil {
+			return nil, err
+		}
+		return wrap(m), nil
+	}
+	h, err := model.ReadHeaderProtected(path, p)
+	if err != nil {
+		return nil, err
+	}
+	if h.Kind == model.KindPipeline {
+		var pl model.Pipeline
+		if _, err := model.LoadProtected(path, h.Kind, &pl, p); err != nil {
+			return nil, err
+		}
+		m, err := pl.Unwrap()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		s := wrap(m)
+		s.pipeline = &pl
+		return s, nil
+	}
+	var m any
+	switch h.Kind {
+	case model.KindLogistic:
+		m = &model.Logistic{}
+	case model.KindLinear:
+		m = &model.Linear{}
+	case model.KindTree:
+		m = &model.Tree{}
+	case model.KindForest:
+		m = &model.Forest{}
+	default:
+		if _, ok := model.Lookup(h.Kind); !ok {
+			return nil, fmt.Errorf("%s holds a %s model, which gomlearn does not support and no plugin registers", path, h.Kind)
+		}
+		if m, err = model.NewCustom(h.Kind); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := model.LoadProtected(path, h.Kind, m, p); err != nil {
+		return nil, err
+	}
+	return wrap(m), nil
+}
+
+// encoder returns the function writing the features of a record of a file
+// with the header into dst. The feature columns are found once.
+func (s *saved) encoder(header []string) (func(fields []string, dst []float64) error, error) {
+	if s.pipeline != nil {
+		return s.pipeline.Encoder(header)
+	}
+	columns := make([]int, len(s.features))
+	for k, feature := range s.features {
+		if columns[k] = slices.Index(header, feature); columns[k] < 0 {
+			return nil, fmt.Errorf("no column %q", feature)
+		}
+	}
+	return func(fields []string, dst []float64) error {
+		for k, j := range columns {
+			var err error
+			if dst[k], err = strconv.ParseFloat(fields[j], 64); err != nil {
+				return fmt.Errorf("column %q: %v", s.features[k], err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// matrix returns the feature matrix of the rows of the table: the
+// feature columns, or the output of the preprocessing of pipelines.
+func (s *saved) matrix(t *table) (*mat64.Dense, error) {
+	if s.pipeline == nil {
+		return t.matrix(s.features)
+	}
+	x, err := s.pipeline.Transform(t.header, t.rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", t.path, err)
+	}
+	return x, nil
+}
+
+// wrap returns a fitted model of any kind gomlearn supports as a saved
+// model.
+func wrap(m any) *saved {
+	switch m := m.(type) {
+	case *model.Logistic:
+		return &saved{kind: model.KindLogistic, target: m.Target, features: m.Features, classifier: true, model: m}
+	case *model.Linear:
+		return &saved{kind: model.KindLinear, target: m.Target, features: m.Features, model: m}
+	case *model.Tree:
+		return &saved{kind: model.KindTree, target: m.Target, features: m.Features, classifier: m.Tree.Task == tree.Classification, classes: m.Classes, model: m}
+	case *model.Forest:
+		return &saved{kind: model.KindForest, target: m.Target, features: m.Features, classifier: m.Forest.Task == tree.Classification, classes: m.Classes, model: m}
+	case *model.Custom:
+		r, _ := model.Lookup(m.Kind)
+		return &saved{kind: m.Kind, target: m.Target, features: m.Features, classifier: r.Classifier, model: m}
+	}
+	panic(fmt.Sprintf("gomlearn: unsupported model %T", m))
+}
+
+// observed returns the target of every row of the table, as class
+// indices for classifiers.
+func (s *saved) observed(t *table, target string) ([]float64, error) {
+	if s.classes != nil {
+		labels, _, err := t.labels(target, s.classes)
+		return labels, err
+	}
+	values, err := t.floats(target)
+	if err != nil || !s.classifier {
+		return values, err
+	}
+	for i, v := range values {
+		if v != 0 && v != 1 {
+			return nil, fmt.Errorf("%s: row %d: column %q: the %s model expects labels 0 and 1, not %g", t.path, i+1, target, s.kind, v)
+		}
+	}
+	return values, nil
+}
+
+// format returns the text of a prediction: the name of the class for
+// classifiers.
+func (s *saved) format(prediction float64) string {
+	if s.classes != nil {
+		return s.classes[int(prediction)]
+	}
+	return strconv.FormatFloat(prediction, 'g', -1, 64)
+}
+
+// predictAll returns the prediction of every row of the table.
+func (s *saved) predictAll(t *table) ([]float64, error) {
+	x, err := s.matrix(t)
+	if err != nil {
+		return nil, err
+	}
+	predictions := make([]float64, len(t.rows))
+	for i := range predictions {
+		predictions[i], err = s.model.Predict(x.RawRowView(i))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+1, err)
+		}
+	}
+	return predictions, nil
+}
+
+// binary reports whether the model is a classifier of two classes, which
+// predicts the probability of class 1.
+func (s *saved) binary() bool {
+	return s.classifier && (s.classes == nil || len(s.classes) == 2)
+}
+
+// probability returns the predicted probability of class 1 of the row,
+// for binary classifiers.
+func (s *saved) probability(row []float64) (float64, error) {
+	switch m := s.model.(type) {
+	case *model.Logistic:
+		return m.Probability(row)
+	case *model.Tree:
+		return m.Tree.PredictProba(row)[1], nil
+	case *model.Forest:
+		return m.Forest.PredictProba(row)[1], nil
+	case *model.Custom:
+		return m.Probability(row)
+	}
+	return 0, fmt.Errorf("a %s model predicts no probabilities", s.kind)
+}
+
+// probabilities returns the predicted probability of class 1 of every row
+// of the table for binary classifiers, and nil for other models.
+func (s *saved) probabilities(t *table) ([]float64, error) {
+	if !s.binary() {
+		return nil, nil
+	}
+	x, err := s.matrix(t)
+	if err != nil {
+		return nil, err
+	}
+	proba := make([]float64, len(t.rows))
+	for i := range proba {
+		if proba[i], err = s.probability(x.RawRowView(i)); err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+1, err)
+		}
+	}
+	return proba, nil
+}
+
+// outcomes returns the observed values, the predictions and, for binary
+// classifiers, the probabilities of class 1 of every row of the table, as
+// the metrics take them.
+func (s *saved) outcomes(t *table, target string) (observed, predicted, proba []float64, err error) {
+	if observed, err = s.observed(t, target); err != nil {
+		return nil, nil, nil, err
+	}
+	if predicted, err = s.predictAll(t); err != nil {
+		return nil, nil, nil, err
+	}
+	if proba, err = s.probabilities(t); err != nil {
+		return nil, nil, nil, err
+	}
+	return observed, predicted, proba, nil
+}
+
+// scores returns the score of every registered metric of the model's task
+// on the rows of the table.
+func (s *saved) scores(t *table, target string) (map[string]float64, error) {
+	observed, predicted, proba, err := s.outcomes(t, target)
+	if err != nil {
+		return nil, err
+	}
+	return metrics.ScoreAll(s.classifier, observed, predicted, proba), nil
+}
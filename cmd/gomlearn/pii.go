@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/pii"
+)
+
+// piiFlags holds the flags marking the PII columns.
+type piiFlags struct {
+	columns, key *string
+}
+
+// addPIIFlags adds -pii and -pii-key to fs.
+func addPIIFlags(fs *flag.FlagSet) *piiFlags {
+	return &piiFlags{
+		columns: fs.String("pii", "", "comma separated columns holding personal data, each optionally followed by :hash (default) or :redact, whose values are never written out"),
+		key:     fs.String("pii-key", "", "file of a 32 byte key the PII values are hashed with (default: plain SHA-256)"),
+	}
+}
+
+// policy returns the PII policy of the flags.
+func (f *piiFlags) policy() (*pii.Policy, error) {
+	var key []byte
+	if *f.key != "" {
+		var err error
+		if key, err = model.ReadKey(*f.key, 32); err != nil {
+			return nil, err
+		}
+	}
+	return pii.Parse(*f.columns, key)
+}
@@ -0,0 +1,13 @@
+package main
+
+// Plugins add estimators and preprocessing steps to gomlearn by
+// registering them from an init function, with model.Register and
+// transform.Register, the way custom metrics are registered with
+// metrics.Register. Import the package of a plugin here for its side
+// effects and rebuild gomlearn:
+//
+//	import _ "example.com/gomlearn-plugins/quantile"
+//
+// Registered kinds are then trained with -model and loaded from model
+// files like the built-in ones, both on the command line and in
+// experiment files.
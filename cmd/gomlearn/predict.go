@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+)
+
+// predict writes the rows of a CSV file with the predictions of a saved
+// model appended, and the probability of class 1 for logistic
+// regressions. With -rules, the business rules decide on every row after
+// the model, and with -reason-codes, every row gets the reason codes of
+// its prediction.
+func predict(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("predict", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model file saved by gomlearn train or by the examples")
+	dataPath := fs.String("data", "", "CSV file of the rows to predict")
+	out := fs.String("out", "-", "CSV file the predictions are written to (- for standard output)")
+	rulesPath := fs.String("rules", "", rulesUsage)
+	reasonCodesPath := fs.String("reason-codes", "", reasonCodesUsage)
+	baselinePath := fs.String("baseline", "", baselineUsage)
+	keys := addKeyFlags(fs, false, true)
+	piiFlags := addPIIFlags(fs)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" || *dataPath == "" {
+		return errors.New("predict: -model and -data are required")
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	s, err := loadModel(*modelPath, p)
+	if err != nil {
+		return err
+	}
+	policy, err := piiFlags.policy()
+	if err != nil {
+		return err
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	if err := policy.Check(t.header); err != nil {
+		return err
+	}
+	masker := policy.Masker(t.header)
+	decisionRules, err := loadRules(*rulesPath, s)
+	if err != nil {
+		return err
+	}
+	var decisions *decisionLog
+	if decisionRules != nil {
+		if decisions, err = newDecisionLog(decisionRules, t.header); err != nil {
+			return fmt.Errorf("%s: %v", t.path, err)
+		}
+	}
+	explain, err := loadExplainer(*reasonCodesPath, *baselinePath, s)
+	if err != nil {
+		return err
+	}
+	predictions, err := s.predictAll(t)
+	if err != nil {
+		return err
+	}
+	lm, _ := s.model.(*model.Logistic)
+	x, err := s.matrix(t)
+	if err != nil {
+		return err
+	}
+	// Append the prediction to every row.
+	header := append(t.header[:len(t.header):len(t.header)], "prediction")
+	if lm != nil {
+		header = append(header, "probability")
+	}
+	if decisions != nil {
+		header = append(header, decisionColumn, reasonCodesColumn)
+	}
+	if explain != nil {
+		header = append(header, adverseCodesColumn, adverseReasonsColumn)
+	}
+	records := [][]string{header}
+	for i, row := range t.rows {
+		// Mask a copy of the row, as the features were read from it.
+		record := append(masker.Record(slices.Clone(row)), s.format(predictions[i]))
+		if lm != nil {
+			p, err := lm.Probability(x.RawRowView(i))
+			if err != nil {
+				return err
+			}
+			record = append(record, strconv.FormatFloat(p, 'f', 6, 64))
+		}
+		if decisions != nil {
+			score := predictions[i]
+			if s.binary() {
+				if score, err = s.probability(x.RawRowView(i)); err != nil {
+					return fmt.Errorf("%s: row %d: %v", t.path, i+1, err)
+				}
+			}
+			decision, codes, err := decisions.decide(row, score)
+			if err != nil {
+				return fmt.Errorf("%s: row %d: %v", t.path, i+1, err)
+			}
+			record = append(record, decision, codes)
+		}
+		if explain != nil {
+			codes, texts, err := explain.explain(x.RawRowView(i))
+			if err != nil {
+				return fmt.Errorf("%s: row %d: %v", t.path, i+1, err)
+			}
+			record = append(record, codes, texts)
+		}
+		records = append(records, record)
+	}
+	if *out == "-" {
+		if err := csv.NewWriter(os.Stdout).WriteAll(records); err != nil {
+			return err
+		}
+		if decisions != nil {
+			return decisions.write(os.Stderr)
+		}
+		return nil
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := csv.NewWriter(f).WriteAll(records); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if decisions != nil {
+		return decisions.write(os.Stdout)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/sketch"
+)
+
+// hllPrecision sets the HyperLogLog registers counting the distinct
+// values of a column: 2^12 registers, for a standard error of about 1.6%.
+const hllPrecision = 12
+
+// profile prints a summary of every column of a CSV file. Numeric
+// columns get their range and quartiles, and every column its number of
+// distinct values and most frequent value. The rows are streamed through
+// sketches, so the memory used does not grow with the number of rows, and
+// the quantiles and distinct counts are estimates. The values of the PII
+// columns are hashed or redacted, and their range and quartiles left out.
+func profile(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("profile", flag.ExitOnError)
+	dataPath := fs.String("data", "", "CSV file to profile")
+	piiFlags := addPIIFlags(fs)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *dataPath == "" {
+		return errors.New("profile: -data is required")
+	}
+	policy, err := piiFlags.policy()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(*dataPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %v", *dataPath, err)
+	}
+	if err := policy.Check(header); err != nil {
+		return err
+	}
+	// Summarize every column as the rows stream by.
+	columns := make([]*columnProfile, len(header))
+	for j := range columns {
+		if columns[j], err = newColumnProfile(); err != nil {
+			return err
+		}
+	}
+	numRows := 0
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", *dataPath, err)
+		}
+		for j, value := range row {
+			columns[j].add(value)
+		}
+		numRows++
+	}
+	fmt.Printf("%s: %d rows, %d columns\n\n", *dataPath, numRows, len(header))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "column\tmissing\tdistinct\tmin\t25%\t50%\t75%\tmax\ttop")
+	for j, name := range header {
+		c := columns[j]
+		fmt.Fprintf(tw, "%s\t%d\t%.0f\t", name, c.missing, c.distinct.Estimate())
+		// The range and quartiles of PII columns would show their values.
+		if c.numeric && c.digest.Count() > 0 && !policy.Has(name) {
+			d := c.digest
+			fmt.Fprintf(tw, "%g\t%.4g\t%.4g\t%.4g\t%g\t", d.Min(), d.Quantile(0.25), d.Quantile(0.5), d.Quantile(0.75), d.Max())
+		} else {
+			fmt.Fprint(tw, "\t\t\t\t\t")
+		}
+		if items := c.top.Top(); len(items) > 0 {
+			fmt.Fprintf(tw, "%s (%d)", policy.Value(name, items[0].Key), items[0].Count)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+// columnProfile summarizes the values of a column.
+type columnProfile struct {
+	missing int
+	// numeric is set while every value parses as a number.
+	numeric  bool
+	distinct *sketch.HyperLogLog
+	top      *sketch.HeavyHitters
+	digest   *sketch.TDigest
+}
+
+func newColumnProfile() (*columnProfile, error) {
+	distinct, err := sketch.NewHyperLogLog(hllPrecision)
+	if err != nil {
+		return nil, err
+	}
+	return &columnProfile{
+		numeric:  true,
+		distinct: distinct,
+		top:      sketch.NewHeavyHitters(1, 0.001, 0.01),
+		digest:   sketch.NewTDigest(sketch.DefaultCompression),
+	}, nil
+}
+
+// add adds a value of the column. Empty values are missing.
+func (c *columnProfile) add(value string) {
+	if value == "" {
+		c.missing++
+		return
+	}
+	c.distinct.Add(value)
+	c.top.Add(value)
+	if c.numeric {
+		v, err := strconv.ParseFloat(value, 64)
+		c.numeric = err == nil
+		c.digest.Add(v)
+	}
+}
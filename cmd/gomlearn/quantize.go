@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+)
+
+// quantize writes a saved linear or logistic regression in the compact
+// binary format with its weights quantized to int8 or float16. On the
+// labeled rows of -data, it reports how much every registered metric
+// drops and how many predictions change.
+func quantize(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("quantize", flag.ExitOnError)
+	modelPath := fs.String("model", "", "linear or logistic model file saved by gomlearn train or by the examples")
+	precisionName := fs.String("precision", "int8", "precision of the weights: int8 or float16")
+	dataPath := fs.String("data", "", "CSV file of labeled rows to measure the drop of the metrics on (default: not measured)")
+	target := fs.String("target", "", "column holding the labels (default the target the model was trained on)")
+	out := fs.String("out", "model.gmlc", "path the compact model is written to")
+	keys := addKeyFlags(fs, false, true)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" {
+		return errors.New("quantize: -model is required")
+	}
+	precision, err := model.ParsePrecision(*precisionName)
+	if err != nil {
+		return err
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	s, err := loadModel(*modelPath, p)
+	if err != nil {
+		return err
+	}
+	if s.pipeline != nil {
+		return fmt.Errorf("quantize: %s is a pipeline, whose preprocessing has no compact form", *modelPath)
+	}
+	// Quantize the model and read it back, as a device would.
+	var buf bytes.Buffer
+	if err := model.WriteCompact(&buf, s.model, precision); err != nil {
+		return err
+	}
+	m, err := model.ReadCompact(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	q := wrap(m)
+	info, err := os.Stat(*modelPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Quantized the %s model to %s: %d bytes, from %d\n", s.kind, precision, buf.Len(), info.Size())
+	if *dataPath != "" {
+		if *target == "" {
+			*target = s.target
+		}
+		if *target == "" {
+			return fmt.Errorf("quantize: %s does not name its target, set -target", *modelPath)
+		}
+		t, err := readTable(*dataPath)
+		if err != nil {
+			return err
+		}
+		if err := quantizationReport(s, q, t, *target); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("\nSaved the compact model to %s\n", *out)
+	return nil
+}
+
+// quantizationReport prints the registered metrics of the model and of
+// its quantized copy on the labeled rows, with the drop of every metric,
+// positive when the quantized model scores worse.
+func quantizationReport(s, q *saved, t *table, target string) error {
+	before, err := s.scores(t, target)
+	if err != nil {
+		return err
+	}
+	after, err := q.scores(t, target)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(before))
+	for name, score := range before {
+		if !math.IsNaN(score) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	fmt.Printf("\nScores on the %d rows of %s\n", len(t.rows), t.path)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric\toriginal\tquantized\tdrop")
+	for _, name := range names {
+		metric, err := metrics.Lookup(name)
+		if err != nil {
+			return err
+		}
+		drop := before[name] - after[name]
+		if !metric.HigherIsBetter {
+			drop = -drop
+		}
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%+.4f\n", name, before[name], after[name], drop)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	original, err := s.predictAll(t)
+	if err != nil {
+		return err
+	}
+	quantized, err := q.predictAll(t)
+	if err != nil {
+		return err
+	}
+	// Count the changed classes of classifiers, and the largest change
+	// of the predictions of regressions.
+	var changed int
+	var largest float64
+	for i, v := range original {
+		if quantized[i] != v {
+			changed++
+		}
+		largest = math.Max(largest, math.Abs(quantized[i]-v))
+	}
+	if s.classifier {
+		fmt.Printf("Predictions changed: %d of %d\n", changed, len(original))
+	} else {
+		fmt.Printf("Largest change of a prediction: %.4g\n", largest)
+	}
+	return nil
+}
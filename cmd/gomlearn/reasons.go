@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/reasons"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Columns appended to the rows explained by the reason codes of
+// -reason-codes.
+const (
+	adverseCodesColumn   = "adverse_codes"
+	adverseReasonsColumn = "adverse_reasons"
+)
+
+// Usages of the flags of the reason codes.
+const (
+	reasonCodesUsage = "YAML file mapping features to reason codes, appending the adverse_codes and adverse_reasons columns: the codes and reasons of the features that moved the score most in the adverse direction (default: no reason codes)"
+	baselineUsage    = "CSV file whose mean row is the baseline of the feature contributions of -reason-codes (default: the training means stored in standardized logistic regressions)"
+)
+
+// explainer gives the reason codes of the predictions of a logistic or
+// linear regression, from the contributions of its features.
+type explainer struct {
+	contributions func(row []float64) ([]float64, error)
+	mapper        *reasons.Mapper
+}
+
+// loadExplainer reads the reason codes at path for the model, or returns
+// nil when path is empty. The contributions are measured from the mean
+// row of the CSV file at baselinePath, when set.
+func loadExplainer(path, baselinePath string, s *saved) (*explainer, error) {
+	if path == "" {
+		if baselinePath != "" {
+			return nil, errors.New("-baseline applies to -reason-codes only")
+		}
+		return nil, nil
+	}
+	c, err := reasons.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := c.Bind(s.features)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	var baseline []float64
+	if baselinePath != "" {
+		t, err := readTable(baselinePath)
+		if err != nil {
+			return nil, err
+		}
+		x, err := s.matrix(t)
+		if err != nil {
+			return nil, err
+		}
+		baseline = make([]float64, len(s.features))
+		for j := range baseline {
+			baseline[j] = mean(mat64.Col(nil, j, x))
+		}
+	}
+	e := &explainer{mapper: mapper}
+	switch m := s.model.(type) {
+	case *model.Logistic:
+		e.contributions = func(row []float64) ([]float64, error) { return m.Contributions(row, baseline) }
+	case *model.Linear:
+		e.contributions = func(row []float64) ([]float64, error) { return m.Contributions(row, baseline) }
+	default:
+		return nil, fmt.Errorf("reason codes apply to logistic and linear regressions, not to a %s model", s.kind)
+	}
+	// Fail before the first row when the model needs a baseline.
+	if _, err := e.contributions(make([]float64, len(s.features))); err != nil {
+		return nil, fmt.Errorf("%v: set -baseline", err)
+	}
+	return e, nil
+}
+
+// explain returns the reason codes of the row of features and their
+// reasons, each separated by semicolons.
+func (e *explainer) explain(row []float64) (codes, texts string, err error) {
+	contributions, err := e.contributions(row)
+	if err != nil {
+		return "", "", err
+	}
+	rs, err := e.mapper.Reasons(contributions)
+	if err != nil {
+		return "", "", err
+	}
+	c, t := make([]string, len(rs)), make([]string, len(rs))
+	for k, r := range rs {
+		c[k], t[k] = r.Code.Code, r.Reason
+	}
+	return strings.Join(c, ";"), strings.Join(t, "; "), nil
+}
+
+// mean returns the mean of the values.
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/selection"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/gonum/matrix/mat64"
+)
+
+// eliminate runs a recursive feature elimination of a linear or logistic
+// regression on a CSV file: it cross-validates the model on every
+// feature, drops the feature of the smallest standardized coefficient and
+// starts over, then prints the score of every number of features with
+// the -features list of the best subset.
+func eliminate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("rfe", flag.ExitOnError)
+	kind := fs.String("model", "", "type of the model: linear or logistic")
+	dataPath := fs.String("data", "", "CSV file of the training rows")
+	target := fs.String("target", "", "column to predict, 0 or 1 labels for logistic regressions")
+	features := fs.String("features", "", "comma separated feature columns to eliminate from (default every column but the target)")
+	numFolds := fs.Int("folds", 5, "number of cross-validation folds, stratified by class for logistic regressions")
+	seed := fs.Uint64("seed", 1, "seed of the folds")
+	metricName := fs.String("metric", "", "registered metric scoring the subsets (default accuracy for logistic and rmse for linear regressions)")
+	minFeatures := fs.Int("min-features", 1, "smallest number of features scored")
+	step := fs.Int("step", 1, "number of features dropped at once")
+	steps := fs.Int("steps", 1000, "number of gradient descent steps of logistic regressions")
+	learningRate := fs.Float64("learning-rate", 0.05, "learning rate of logistic regressions")
+	workers := fs.Int("workers", 0, "number of folds fitted in parallel (0 uses every CPU)")
+	out := fs.String("out", "", "CSV file the score of every subset is written to (default: not written)")
+	plotPath := fs.String("plot", "", "PNG file of a plot of the score against the number of features (default: not plotted)")
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *kind == "" || *dataPath == "" || *target == "" {
+		return errors.New("rfe: -model, -data and -target are required")
+	}
+	if *kind != model.KindLinear && *kind != model.KindLogistic {
+		return fmt.Errorf("rfe: cannot eliminate the features of %q models, expected linear or logistic", *kind)
+	}
+	classifier := *kind == model.KindLogistic
+	m, err := tuneMetric(classifier, *metricName)
+	if err != nil {
+		return err
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	names, err := t.featureNames(*features, *target)
+	if err != nil {
+		return err
+	}
+	x, err := t.matrix(names)
+	if err != nil {
+		return err
+	}
+	y, err := t.floats(*target)
+	if err != nil {
+		return err
+	}
+	// Deal the rows into folds, keeping the class proportions of
+	// logistic regressions.
+	var folds []split.Fold
+	if classifier {
+		strata := make([]int, len(y))
+		for i, label := range y {
+			strata[i] = int(label)
+		}
+		folds, err = split.RepeatedStratifiedKFold(strata, *numFolds, 1, int64(*seed))
+	} else {
+		folds, err = split.RepeatedKFold(len(y), *numFolds, 1, int64(*seed))
+	}
+	if err != nil {
+		return err
+	}
+	fit := func(ctx context.Context, x *mat64.Dense, y []float64) (selection.Weighted, error) {
+		// The subsets are fitted on the columns of x, which Eliminate
+		// numbers.
+		_, numCols := x.Dims()
+		columns := make([]string, numCols)
+		for j := range columns {
+			columns[j] = fmt.Sprint("x", j+1)
+		}
+		if classifier {
+			lm, err := fitLogistic(ctx, x, y, *steps, *learningRate)
+			if err != nil {
+				return nil, err
+			}
+			lm.Features, lm.Threshold = columns, 0.5
+			return weighedLogistic{lm}, nil
+		}
+		lm, err := fitLinear(x, y, *target, columns)
+		if err != nil {
+			return nil, err
+		}
+		return weighedLinear{lm, columnStds(x)}, nil
+	}
+	fmt.Printf("Eliminating %d features of a %s regression of %s on %d folds of %d rows by %s\n\n", len(names), *kind, *target, len(folds), len(y), m.Name)
+	e, err := selection.Eliminate(ctx, x, y, folds, fit, selection.EliminationConfig{
+		Metric:      m,
+		MinFeatures: *minFeatures,
+		Step:        *step,
+		Workers:     *workers,
+	})
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "features\t%s\tse\tdropped next\tbest\n", m.Name)
+	rows := make([][]any, len(e.Subsets))
+	counts := make([]float64, len(e.Subsets))
+	scores := make([]float64, len(e.Subsets))
+	for s, subset := range e.Subsets {
+		kept, dropped := columnNames(names, subset.Features), columnNames(names, subset.Dropped)
+		best := ""
+		if s == e.Best {
+			best = "*"
+		}
+		rows[s] = []any{len(subset.Features), subset.Score, subset.SE, strings.Join(kept, ";"), strings.Join(dropped, ";")}
+		fmt.Fprintf(tw, "%d\t%.4f\t%.4f\t%s\t%s\n", len(subset.Features), subset.Score, subset.SE, strings.Join(dropped, ","), best)
+		// Plot from the fewest features up.
+		counts[len(e.Subsets)-1-s], scores[len(e.Subsets)-1-s] = float64(len(subset.Features)), subset.Score
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	best := e.Subsets[e.Best]
+	fmt.Printf("\nBest subset: %d of %d features, %s %.4f: -features %s\n", len(best.Features), len(names), m.Name, best.Score, strings.Join(columnNames(names, best.Features), ","))
+	fmt.Printf("Features from the last eliminated: %s\n", strings.Join(columnNames(names, e.Ranking()), ","))
+	if *out != "" {
+		columns := []string{"features", m.Name, "se", "kept", "dropped"}
+		if err := artifacts.WriteCSV(*out, columns, rows); err != nil {
+			return err
+		}
+		fmt.Printf("Saved the subsets to %s\n", *out)
+	}
+	if *plotPath != "" {
+		title := fmt.Sprintf("Recursive feature elimination, %d folds", len(folds))
+		if err := plots.Line(*plotPath, title, "Number of features", "Cross-validated "+m.Name, counts, scores); err != nil {
+			return err
+		}
+		fmt.Printf("Saved the plot to %s\n", *plotPath)
+	}
+	return nil
+}
+
+// weighedLogistic weighs the features of a logistic regression fitted by
+// fitLogistic by their weights, which apply to standardized features.
+type weighedLogistic struct {
+	*model.Logistic
+}
+
+func (m weighedLogistic) Importance() []float64 {
+	return m.Weights[:len(m.Features)]
+}
+
+// weighedLinear weighs the features of a linear regression by their
+// coefficients times their standard deviations: the change of the
+// prediction per standard deviation of every feature.
+type weighedLinear struct {
+	*model.Linear
+	std []float64
+}
+
+func (m weighedLinear) Importance() []float64 {
+	weights := make([]float64, len(m.Coefficients))
+	for j, c := range m.Coefficients {
+		weights[j] = c * m.std[j]
+	}
+	return weights
+}
+
+// columnStds returns the standard deviation of every column of x.
+func columnStds(x *mat64.Dense) []float64 {
+	numRows, numCols := x.Dims()
+	stds := make([]float64, numCols)
+	for j := range stds {
+		col := mat64.Col(nil, j, x)
+		m := mean(col)
+		var ss float64
+		for _, v := range col {
+			ss += (v - m) * (v - m)
+		}
+		stds[j] = math.Sqrt(ss / float64(numRows))
+	}
+	return stds
+}
+
+// columnNames returns the names of the columns.
+func columnNames(names []string, columns []int) []string {
+	picked := make([]string, len(columns))
+	for k, j := range columns {
+		picked[k] = names[j]
+	}
+	return picked
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/rules"
+)
+
+// Columns appended to the rows decided by the rules of -rules.
+const (
+	decisionColumn    = "decision"
+	reasonCodesColumn = "reason_codes"
+)
+
+// rulesUsage is the usage of the -rules flag.
+const rulesUsage = "YAML file of business rules (score bands, cutoffs on raw columns and override lists) deciding on every row after the model, appending the decision and reason_codes columns (default: no rules)"
+
+// loadRules reads the rules at path for the model, or returns nil when
+// path is empty. Score bands apply to the probability of class 1 of
+// binary classifiers and to the predictions of regressions.
+func loadRules(path string, s *saved) (*rules.Rules, error) {
+	if path == "" {
+		return nil, nil
+	}
+	r, err := rules.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if r.HasBands() && s.classifier && !s.binary() {
+		return nil, fmt.Errorf("%s: score bands need a binary classifier or a regression, not a classifier of %d classes", path, len(s.classes))
+	}
+	return r, nil
+}
+
+// decisionLog decides on rows by the rules and counts the decisions and
+// the reason codes, for the summary of a run.
+type decisionLog struct {
+	rules     *rules.Rules
+	engine    *rules.Engine
+	rows      int
+	decisions map[string]int
+	codes     map[string]int
+}
+
+// newDecisionLog returns the log of the rules deciding on the records of
+// a file with the header.
+func newDecisionLog(r *rules.Rules, header []string) (*decisionLog, error) {
+	e, err := r.Bind(header)
+	if err != nil {
+		return nil, err
+	}
+	return &decisionLog{rules: r, engine: e, decisions: make(map[string]int), codes: make(map[string]int)}, nil
+}
+
+// decide returns the decision on the record and its reason codes,
+// separated by semicolons.
+func (l *decisionLog) decide(fields []string, score float64) (decision, codes string, err error) {
+	out, err := l.engine.Decide(fields, score)
+	if err != nil {
+		return "", "", err
+	}
+	l.rows++
+	l.decisions[out.Decision]++
+	for _, code := range out.Codes {
+		l.codes[code]++
+	}
+	return out.Decision, strings.Join(out.Codes, ";"), nil
+}
+
+// write writes the number of rows of every decision and reason code.
+func (l *decisionLog) write(w io.Writer) error {
+	fmt.Fprintf(w, "Decisions on %d rows\n", l.rows)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "decision\trows")
+	for _, d := range sortedKeys(l.decisions) {
+		fmt.Fprintf(tw, "%s\t%d\n", d, l.decisions[d])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "reason code\trows\treason")
+	for _, code := range sortedKeys(l.codes) {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", code, l.codes[code], l.rules.Reason(code))
+	}
+	return tw.Flush()
+}
+
+// sortedKeys returns the keys of the counts in sorted order.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
+)
+
+// runs lists the runs the examples wrote to a runs directory, or
+// compares some of them side by side.
+func runs(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("runs: expected list or compare")
+	}
+	switch args[0] {
+	case "list":
+		return listRuns(args[1:])
+	case "compare":
+		return compareRuns(args[1:])
+	}
+	return fmt.Errorf("runs: unknown subcommand %q, expected list or compare", args[0])
+}
+
+// runsDirFlag defines the -runs-dir flag on fs, with the default of the
+// examples.
+func runsDirFlag(fs *flag.FlagSet) *string {
+	dir := "runs"
+	if v := os.Getenv(workspace.RunsDirEnv); v != "" {
+		dir = v
+	}
+	return fs.String("runs-dir", dir, "directory the examples wrote their runs to (default $"+workspace.RunsDirEnv+" when set)")
+}
+
+// listRuns prints one line per run, oldest first, with the metrics named
+// by -metrics.
+func listRuns(args []string) error {
+	fs := flag.NewFlagSet("runs list", flag.ExitOnError)
+	runsDir := runsDirFlag(fs)
+	program := fs.String("program", "", "only list the runs of this program, such as random-forest")
+	metricNames := fs.String("metrics", "", "comma separated metrics shown for every run")
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	records, err := artifacts.ListRuns(*runsDir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if *metricNames != "" {
+		names = strings.Split(*metricNames, ",")
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := append([]string{"id", "program", "started", "commit", "metrics", "artifacts"}, names...)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, r := range records {
+		if *program != "" && r.Manifest.Program != *program {
+			continue
+		}
+		m := r.Manifest
+		fields := []string{m.ID, orDash(m.Program), m.Started.Local().Format(time.DateTime), orDash(shortCommit(m.Commit)), strconv.Itoa(len(r.Metrics)), strconv.Itoa(len(r.Artifacts))}
+		for _, name := range names {
+			v, ok := r.Metrics[name]
+			fields = append(fields, "-")
+			if ok {
+				fields[len(fields)-1] = strconv.FormatFloat(v, 'g', 6, 64)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(fields, "\t"))
+	}
+	return tw.Flush()
+}
+
+// compareRuns prints the manifests, parameters and metrics of the runs
+// named by the arguments in a column per run.
+func compareRuns(args []string) error {
+	fs := flag.NewFlagSet("runs compare", flag.ExitOnError)
+	runsDir := runsDirFlag(fs)
+	all := fs.Bool("all", false, "also show the parameters and datasets the runs agree on")
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return errors.New("runs compare: expected the IDs or directories of at least two runs")
+	}
+	records := make([]*artifacts.Record, fs.NArg())
+	for i, id := range fs.Args() {
+		dir := id
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			dir = filepath.Join(*runsDir, id)
+		}
+		r, err := artifacts.ReadRun(dir)
+		if err != nil {
+			return err
+		}
+		records[i] = r
+	}
+	return writeComparison(os.Stdout, records, *all)
+}
+
+// writeComparison writes a row per field of the runs and a column per
+// run: the program, start and commit, the hash of every dataset, the
+// parameters and the metrics. The datasets and parameters the runs agree
+// on are left out unless all is set.
+func writeComparison(w io.Writer, records []*artifacts.Record, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(name string, values []string) {
+		fmt.Fprintln(tw, name+"\t"+strings.Join(values, "\t"))
+	}
+	column := func(f func(r *artifacts.Record) string) []string {
+		values := make([]string, len(records))
+		for i, r := range records {
+			values[i] = f(r)
+		}
+		return values
+	}
+	row("run", column(func(r *artifacts.Record) string { return r.Manifest.ID }))
+	row("program", column(func(r *artifacts.Record) string { return orDash(r.Manifest.Program) }))
+	row("started", column(func(r *artifacts.Record) string { return r.Manifest.Started.Local().Format(time.DateTime) }))
+	row("commit", column(func(r *artifacts.Record) string { return orDash(shortCommit(r.Manifest.Commit)) }))
+	// section writes the values of the keys of every run, skipping the
+	// keys whose values agree when skipSame is set.
+	section := func(title string, keys []string, skipSame bool, value func(r *artifacts.Record, key string) (any, bool)) {
+		var lines [][]string
+		for _, key := range keys {
+			values := make([]string, len(records))
+			same := true
+			first, _ := value(records[0], key)
+			for i, r := range records {
+				v, ok := value(r, key)
+				values[i] = "-"
+				if ok {
+					values[i] = formatValue(v)
+				}
+				same = same && reflect.DeepEqual(v, first)
+			}
+			if !same || !skipSame {
+				lines = append(lines, append([]string{key}, values...))
+			}
+		}
+		if len(lines) == 0 {
+			return
+		}
+		// Keep a cell per run on every line, so that tabwriter aligns
+		// the columns of every section together.
+		row("", make([]string, len(records)))
+		row(title, make([]string, len(records)))
+		for _, line := range lines {
+			row("  "+line[0], line[1:])
+		}
+	}
+	var datasets, params, metricNames []string
+	for _, r := range records {
+		datasets = appendKeys(datasets, r.Manifest.Datasets)
+		params = appendKeys(params, r.Params)
+		metricNames = appendKeys(metricNames, r.Metrics)
+	}
+	section("datasets", datasets, !all, func(r *artifacts.Record, key string) (any, bool) {
+		v, ok := r.Manifest.Datasets[key]
+		return shortCommit(v), ok
+	})
+	section("params", params, !all, func(r *artifacts.Record, key string) (any, bool) {
+		v, ok := r.Params[key]
+		return v, ok
+	})
+	// Always show the metrics, which the comparison is for.
+	section("metrics", metricNames, false, func(r *artifacts.Record, key string) (any, bool) {
+		v, ok := r.Metrics[key]
+		return v, ok
+	})
+	return tw.Flush()
+}
+
+// appendKeys adds the keys of m missing from keys, keeping them sorted.
+func appendKeys[V any](keys []string, m map[string]V) []string {
+	for key := range m {
+		i := sort.SearchStrings(keys, key)
+		if i == len(keys) || keys[i] != key {
+			keys = append(keys[:i], append([]string{key}, keys[i:]...)...)
+		}
+	}
+	return keys
+}
+
+// formatValue formats a parameter or metric, floats in six significant
+// digits.
+func formatValue(v any) string {
+	if x, ok := v.(float64); ok {
+		return strconv.FormatFloat(x, 'g', 6, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// shortCommit returns the first 12 characters of a revision or hash,
+// keeping the -dirty suffix.
+func shortCommit(s string) string {
+	revision, dirty := strings.CutSuffix(s, "-dirty")
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if dirty {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// orDash returns s, or "-" when it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
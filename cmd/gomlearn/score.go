@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/pii"
+	"github.com/bachhm.dev/go-machine-learning/pkg/rules"
+)
+
+// scoreCheckEvery is the number of rows scored between checks for an
+// interrupt.
+const scoreCheckEvery = 4096
+
+// score streams the rows of a CSV file through a saved model, writing
+// every row with its prediction appended, and the probability of class 1
+// for binary classifiers. Unlike predict, it holds a single row in memory
+// at a time, so it scores files of any size and fits shell pipelines:
+//
+//	zcat loans.csv.gz | gomlearn score -model loans.json | gzip > scored.csv.gz
+//
+// The preprocessing fitted at train time, such as the standardization of
+// the features of logistic regressions, is part of the saved model and
+// applies to the raw values read. With -rules, the business rules decide
+// on every row after the model, and the number of rows of every decision
+// and reason code is printed to standard error. With -reason-codes, every
+// row gets the reason codes of its prediction.
+func score(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("score", flag.ExitOnError)
+	modelPath := fs.String("model", "", "model file saved by gomlearn train or by the examples")
+	in := fs.String("in", "-", "CSV file of the rows to score (- for standard input)")
+	out := fs.String("out", "-", "CSV file the scored rows are written to (- for standard output)")
+	predictionColumn := fs.String("prediction-column", "prediction", "name of the appended prediction column")
+	probabilityColumn := fs.String("probability-column", "probability", "name of the appended probability column of binary classifiers (empty to leave it out)")
+	rulesPath := fs.String("rules", "", rulesUsage)
+	reasonCodesPath := fs.String("reason-codes", "", reasonCodesUsage)
+	baselinePath := fs.String("baseline", "", baselineUsage)
+	keys := addKeyFlags(fs, false, true)
+	piiFlags := addPIIFlags(fs)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" {
+		return errors.New("score: -model is required")
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	s, err := loadModel(*modelPath, p)
+	if err != nil {
+		return err
+	}
+	policy, err := piiFlags.policy()
+	if err != nil {
+		return err
+	}
+	decisionRules, err := loadRules(*rulesPath, s)
+	if err != nil {
+		return err
+	}
+	explain, err := loadExplainer(*reasonCodesPath, *baselinePath, s)
+	if err != nil {
+		return err
+	}
+	r, w := os.Stdin, os.Stdout
+	if *in != "-" {
+		if r, err = os.Open(*in); err != nil {
+			return err
+		}
+		defer r.Close()
+	}
+	if *out != "-" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+	}
+	bw := bufio.NewWriterSize(w, 1<<16)
+	err = scoreRows(ctx, s, policy, decisionRules, explain, bufio.NewReaderSize(r, 1<<16), bw, *in, *predictionColumn, *probabilityColumn)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if w != os.Stdout {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// scoreRows reads the CSV rows of r and writes them to w with the
+// predictions of the model appended and the PII columns masked by the
+// policy. When decisionRules is not nil, the decision and reason codes of
+// the rules are appended as well, and when explain is not nil, the reason
+// codes of the prediction. name names r in errors.
+func scoreRows(ctx context.Context, s *saved, policy *pii.Policy, decisionRules *rules.Rules, explain *explainer, r io.Reader, w io.Writer, name, predictionColumn, probabilityColumn string) error {
+	if name == "-" {
+		name = "standard input"
+	}
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	cw := csv.NewWriter(w)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%s: no header row", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if err := policy.Check(header); err != nil {
+		return err
+	}
+	masker := policy.Masker(header)
+	encode, err := s.encoder(header)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	withProbability := probabilityColumn != "" && s.binary()
+	record := append(slices.Clone(header), predictionColumn)
+	if withProbability {
+		record = append(record, probabilityColumn)
+	}
+	var decisions *decisionLog
+	if decisionRules != nil {
+		if decisions, err = newDecisionLog(decisionRules, header); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		record = append(record, decisionColumn, reasonCodesColumn)
+	}
+	if explain != nil {
+		record = append(record, adverseCodesColumn, adverseReasonsColumn)
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	row := make([]float64, len(s.features))
+	for i := 1; ; i++ {
+		if i%scoreCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if err := encode(fields, row); err != nil {
+			return fmt.Errorf("%s: row %d: %v", name, i, err)
+		}
+		prediction, err := s.model.Predict(row)
+		if err != nil {
+			return fmt.Errorf("%s: row %d: %v", name, i, err)
+		}
+		// Decide on the raw fields, before they are masked.
+		var decision, codes string
+		if decisions != nil {
+			score := prediction
+			if s.binary() {
+				if score, err = s.probability(row); err != nil {
+					return fmt.Errorf("%s: row %d: %v", name, i, err)
+				}
+			}
+			if decision, codes, err = decisions.decide(fields, score); err != nil {
+				return fmt.Errorf("%s: row %d: %v", name, i, err)
+			}
+		}
+		record = masker.Record(append(record[:0], fields...))
+		record = append(record, s.format(prediction))
+		if withProbability {
+			p, err := s.probability(row)
+			if err != nil {
+				return fmt.Errorf("%s: row %d: %v", name, i, err)
+			}
+			record = append(record, strconv.FormatFloat(p, 'f', 6, 64))
+		}
+		if decisions != nil {
+			record = append(record, decision, codes)
+		}
+		if explain != nil {
+			codes, texts, err := explain.explain(row)
+			if err != nil {
+				return fmt.Errorf("%s: row %d: %v", name, i, err)
+			}
+			record = append(record, codes, texts)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	if decisions != nil {
+		// Standard output may hold the rows, so log to standard error.
+		return decisions.write(os.Stderr)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/selection"
+	"github.com/gonum/matrix/mat64"
+)
+
+// selectFeatures ranks the numeric features of a CSV file by their
+// correlation with the target, drops the near-constant and weakly
+// correlated ones, and prints the ranking with the -features list of the
+// kept ones. With -method mi or chi2, it ranks them by their relevance to
+// the classes of the target instead.
+func selectFeatures(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("select", flag.ExitOnError)
+	dataPath := fs.String("data", "", "CSV file of labeled rows")
+	target := fs.String("target", "", "column the features are ranked against: numeric for correlations, such as a 0 or 1 label, any for mi and chi2")
+	features := fs.String("features", "", "comma separated feature columns (default every column but the target)")
+	methodName := fs.String("method", "pearson", "statistic the features are ranked by: the pearson or spearman correlation, or, against classes, mi (mutual information) or chi2")
+	minVariance := fs.Float64("min-variance", 0, "drop the features whose variance does not exceed this (0 drops constant features)")
+	minCorrelation := fs.Float64("min-correlation", 0, "drop the features whose absolute correlation with the target is below this")
+	bins := fs.Int("bins", selection.DefaultBins, "number of quantile bins of the features ranked by mi or chi2")
+	top := fs.Int("top", 0, "keep the features ranked first by mi or chi2 (0 keeps them all)")
+	out := fs.String("out", "", "CSV file the ranking is written to (default: not written)")
+	plotPath := fs.String("plot", "", "PNG file of a bar chart of the mi or chi2 ranking (default: not plotted)")
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *dataPath == "" || *target == "" {
+		return errors.New("select: -data and -target are required")
+	}
+	criterion, err := selection.ParseCriterion(*methodName)
+	classes := err == nil
+	var method selection.Method
+	if !classes {
+		if method, err = selection.ParseMethod(*methodName); err != nil {
+			return fmt.Errorf("select: unknown method %q, expected pearson, spearman, mi or chi2", *methodName)
+		}
+	}
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	names, err := t.featureNames(*features, *target)
+	if err != nil {
+		return err
+	}
+	x, err := t.matrix(names)
+	if err != nil {
+		return err
+	}
+	if classes {
+		// Any column holds classes, such as text labels.
+		y, _, err := t.labels(*target, nil)
+		if err != nil {
+			return err
+		}
+		return rankClasses(x, y, names, *target, criterion, *bins, *top, *out, *plotPath)
+	}
+	y, err := t.floats(*target)
+	if err != nil {
+		return err
+	}
+	report, err := selection.Rank(x, y, selection.Config{
+		Method:         method,
+		MinVariance:    *minVariance,
+		MinCorrelation: *minCorrelation,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d features ranked by their %s correlation with %s\n\n", len(names), method, *target)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "rank\tfeature\tpearson\tspearman\tvariance\tdropped")
+	rows := make([][]any, len(report.Features))
+	for i, f := range report.Features {
+		rows[i] = []any{i + 1, names[f.Index], f.Pearson, f.Spearman, f.Variance, f.Dropped}
+		fmt.Fprintf(tw, "%d\t%s\t%.4f\t%.4f\t%.4g\t%s\n", i+1, names[f.Index], f.Pearson, f.Spearman, f.Variance, f.Dropped)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	var kept []string
+	for _, j := range report.Selected() {
+		kept = append(kept, names[j])
+	}
+	fmt.Printf("\nKept %d of %d features: -features %s\n", len(kept), len(names), strings.Join(kept, ","))
+	if *out != "" {
+		columns := []string{"rank", "feature", "pearson", "spearman", "variance", "dropped"}
+		if err := artifacts.WriteCSV(*out, columns, rows); err != nil {
+			return err
+		}
+		fmt.Printf("Saved the ranking to %s\n", *out)
+	}
+	return nil
+}
+
+// rankClasses ranks the features of x by the criterion of their relevance
+// to the classes y of the target, prints the ranking with the -features
+// list of the top ones, and writes it to out and a bar chart of it to
+// plotPath, when set.
+func rankClasses(x mat64.Matrix, y []float64, names []string, target string, criterion selection.Criterion, bins, top int, out, plotPath string) error {
+	ranking, err := selection.RankClasses(x, y, criterion, bins)
+	if err != nil {
+		return err
+	}
+	if top <= 0 || top > len(ranking) {
+		top = len(ranking)
+	}
+	fmt.Printf("%d features ranked by %s with the classes of %s\n\n", len(names), criterion, target)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "rank\tfeature\tmi\tchi2\tdf\tp_value\tbins")
+	rows := make([][]any, len(ranking))
+	ranked := make([]string, len(ranking))
+	scores := make([]float64, len(ranking))
+	for i, r := range ranking {
+		rows[i] = []any{i + 1, names[r.Index], r.MutualInformation, r.ChiSquared, r.DegreesOfFreedom, r.PValue, r.Bins}
+		fmt.Fprintf(tw, "%d\t%s\t%.4f\t%.1f\t%d\t%.3g\t%d\n", i+1, names[r.Index], r.MutualInformation, r.ChiSquared, r.DegreesOfFreedom, r.PValue, r.Bins)
+		ranked[i] = names[r.Index]
+		scores[i] = r.MutualInformation
+		if criterion == selection.ChiSquared {
+			scores[i] = r.ChiSquared
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\nKept %d of %d features: -features %s\n", top, len(names), strings.Join(ranked[:top], ","))
+	if out != "" {
+		columns := []string{"rank", "feature", "mi", "chi2", "df", "p_value", "bins"}
+		if err := artifacts.WriteCSV(out, columns, rows); err != nil {
+			return err
+		}
+		fmt.Printf("Saved the ranking to %s\n", out)
+	}
+	if plotPath != "" {
+		yLabel := "Mutual information (nats)"
+		if criterion == selection.ChiSquared {
+			yLabel = "Chi-squared statistic"
+		}
+		if err := plots.Bars(plotPath, "Feature ranking", yLabel, ranked, scores); err != nil {
+			return err
+		}
+		fmt.Printf("Saved the chart to %s\n", plotPath)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/shift"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+)
+
+// detectShift trains a forest to tell the rows of a reference CSV file
+// from those of a current one, and prints its cross-validated AUC and the
+// features that tell them apart most.
+func detectShift(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("shift", flag.ExitOnError)
+	referencePath := fs.String("reference", "", "CSV file of the reference rows, such as the training data")
+	currentPath := fs.String("current", "", "CSV file of the current rows, such as the rows served since training")
+	features := fs.String("features", "", "comma-separated numeric feature columns (default: every column of both files)")
+	numTrees := fs.Int("trees", 100, "number of trees of every forest")
+	folds := fs.Int("folds", 5, "number of cross-validation folds")
+	maxDepth := fs.Int("max-depth", 0, "largest depth of the trees (0 for no limit)")
+	threshold := fs.Float64("threshold", 0.6, "AUC from which the samples are reported as shifted")
+	top := fs.Int("top", 10, "number of features listed, the most discriminative first (0 lists every feature)")
+	seed := fs.Int64("seed", 1, "seed of the folds and the forests")
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *referencePath == "" || *currentPath == "" {
+		return errors.New("shift: -reference and -current are required")
+	}
+	reference, err := readTable(*referencePath)
+	if err != nil {
+		return err
+	}
+	current, err := readTable(*currentPath)
+	if err != nil {
+		return err
+	}
+	names := strings.Split(*features, ",")
+	if *features == "" {
+		names = nil
+		for _, name := range reference.header {
+			if slices.Contains(current.header, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	refX, err := reference.matrix(names)
+	if err != nil {
+		return err
+	}
+	curX, err := current.matrix(names)
+	if err != nil {
+		return err
+	}
+	res, err := shift.Test(ctx, refX, curX, shift.Config{
+		NumTrees: *numTrees,
+		Folds:    *folds,
+		Params:   tree.Params{MaxDepth: *maxDepth},
+		Seed:     uint64(*seed),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Classifier two-sample test: %d reference rows, %d current rows, %d features\n", res.Reference, res.Current, len(names))
+	verdict := "no shift detected"
+	if res.Shifted(*threshold) {
+		verdict = "shift detected"
+	}
+	fmt.Printf("AUC = %.4f (%s at threshold %g; 0.5 when the samples cannot be told apart)\n\n", res.AUC, verdict, *threshold)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "feature\timportance")
+	for i, f := range res.Features {
+		if *top > 0 && i >= *top {
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%.4f\n", names[f.Index], f.Importance)
+	}
+	return tw.Flush()
+}
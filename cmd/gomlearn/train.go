@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/forest"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/transform"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+	"github.com/sajari/regression"
+)
+
+// train fits a model on a CSV file, saves it and scores it on the
+// training rows.
+func train(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	kind := fs.String("model", "", "type of the model: linear, logistic, tree, forest or a kind registered by a plugin")
+	dataPath := fs.String("data", "", "CSV file of the training rows")
+	target := fs.String("target", "", "column to predict")
+	features := fs.String("features", "", "comma separated feature columns (default every column but the target)")
+	categorical := fs.String("categorical", "", "comma separated text columns among the features, encoded by -encoding; the model is saved as a pipeline")
+	encoding := fs.String("encoding", model.EncodingOneHot, "encoding of the -categorical columns: onehot or ordinal")
+	preprocess := fs.String("preprocess", "", "comma separated steps transforming the features in order, such as impute=median,standard; the model is saved as a pipeline")
+	columnSpec := fs.String("columns", "", "comma separated step:column entries routing columns to the standard, minmax, impute, onehot, passthrough, drop or a registered step, * naming the other columns; the model is saved as a pipeline (replaces -features and -categorical)")
+	out := fs.String("out", "model.json", "path the model is saved to")
+	taskName := fs.String("task", "classification", "task of tree and forest models: classification or regression")
+	maxDepth := fs.Int("max-depth", 0, "largest depth of the trees (0 for no limit)")
+	minLeaf := fs.Int("min-samples-leaf", 1, "fewest rows of every leaf of the trees")
+	maxFeatures := fs.Int("max-features", 0, "number of features drawn as split candidates at every node (0 uses every feature for trees, and the square root or a third of them for forests)")
+	maxBins := fs.Int("max-bins", 0, "number of histogram bins of every feature used to find splits (0 tries every threshold)")
+	numTrees := fs.Int("trees", 100, "number of trees of forests")
+	seed := fs.Uint64("seed", 1, "seed of the random choices of trees and forests")
+	steps := fs.Int("steps", 1000, "gradient descent steps of logistic regressions")
+	learningRate := fs.Float64("learning-rate", 0.05, "learning rate of logistic regressions")
+	threshold := fs.Float64("threshold", 0.5, "probability from which logistic regressions predict class 1")
+	calibrate := fs.Bool("calibrate", false, "calibrate the probabilities of logistic regressions and choose their threshold by cross-validation")
+	numFolds := fs.Int("folds", 5, "number of cross-validation folds of -calibrate")
+	initPath := fs.String("init", "", "logistic model file whose weights the gradient descent starts from, to retrain it on new rows such as merged by gomlearn label merge (default: zero weights)")
+	keys := addKeyFlags(fs, true, false)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *kind == "" || *dataPath == "" || *target == "" {
+		return errors.New("train: -model, -data and -target are required")
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	if *calibrate {
+		if *kind != model.KindLogistic {
+			return errors.New("train: -calibrate applies to logistic models only")
+		}
+		thresholdSet := false
+		fs.Visit(func(f *flag.Flag) { thresholdSet = thresholdSet || f.Name == "threshold" })
+		if thresholdSet {
+			return errors.New("train: -calibrate chooses the threshold, -threshold cannot be set with it")
+		}
+	}
+	if *columnSpec != "" && (*features != "" || *categorical != "") {
+		return errors.New("train: -columns routes the feature columns, -features and -categorical cannot be set with it")
+	}
+	var init *model.Logistic
+	if *initPath != "" {
+		if *kind != model.KindLogistic || *columnSpec != "" || *categorical != "" || *preprocess != "" {
+			return errors.New("train: -init applies to logistic models without preprocessing only")
+		}
+		s, err := loadModel(*initPath, p)
+		if err != nil {
+			return err
+		}
+		lm, ok := s.model.(*model.Logistic)
+		if !ok || s.pipeline != nil {
+			return fmt.Errorf("train: %s is a %s model, not a logistic regression", *initPath, s.kind)
+		}
+		init = lm
+		// Keep the features of the model by default.
+		if *features == "" {
+			*features = strings.Join(lm.Features, ",")
+		}
+	}
+	raw, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	names, err := raw.featureNames(*features, *target)
+	if err != nil {
+		return err
+	}
+	if init != nil && !slices.Equal(names, init.Features) {
+		return fmt.Errorf("train: %s has the features %s, not %s", *initPath, strings.Join(init.Features, ","), strings.Join(names, ","))
+	}
+	// Models of a pipeline fit on the output of its preprocessing.
+	t := raw
+	var pl *model.Pipeline
+	if *columnSpec != "" || *categorical != "" || *preprocess != "" {
+		if pl, err = newPipeline(*target, names, *columnSpec, *categorical, *encoding, *preprocess); err != nil {
+			return err
+		}
+		x, err := pl.FitTransform(raw.header, raw.rows)
+		if err != nil {
+			return fmt.Errorf("%s: %v", raw.path, err)
+		}
+		if t, err = raw.transformed(pl.Features, x, *target); err != nil {
+			return err
+		}
+		names = pl.Features
+	}
+	var task tree.Task
+	if err := task.UnmarshalText([]byte(*taskName)); err != nil {
+		return err
+	}
+	hp := hyperparams{
+		params:       tree.Params{MaxDepth: *maxDepth, MinSamplesLeaf: *minLeaf, MaxFeatures: *maxFeatures, MaxBins: *maxBins},
+		trees:        *numTrees,
+		seed:         *seed,
+		steps:        *steps,
+		learningRate: *learningRate,
+		threshold:    *threshold,
+		init:         init,
+	}
+	m, err := fitModel(ctx, *kind, task, t, *target, names, nil, hp)
+	if err != nil {
+		return err
+	}
+	switch m := m.(type) {
+	case *model.Logistic:
+		if !*calibrate {
+			break
+		}
+		op, err := calibrateLogistic(ctx, t, *target, names, hp, *numFolds)
+		if err != nil {
+			return err
+		}
+		m.Calibration, m.Threshold = op.calibration, op.threshold
+		fmt.Printf("Calibrated on %d out-of-fold probabilities: a = %.4f, b = %.4f, AUC = %.4f\n", op.rows, op.calibration.A, op.calibration.B, op.auc)
+		fmt.Printf("Threshold %.4f, the largest TPR - FPR: TPR = %.4f, FPR = %.4f\n", op.threshold, op.tpr, op.fpr)
+	case *model.Tree:
+		fmt.Printf("Grew a tree of depth %d with %d nodes\n", m.Tree.Depth(), len(m.Tree.Nodes))
+	case *model.Forest:
+		fmt.Printf("Grew %d trees, out-of-bag score %.4f on %d rows\n", len(m.Forest.Trees), m.Forest.OOBScore, m.Forest.OOBRows)
+	}
+	saveKind := *kind
+	if pl != nil {
+		if err := pl.SetModel(*kind, m); err != nil {
+			return err
+		}
+		m, saveKind = pl, model.KindPipeline
+		fmt.Printf("Preprocessed the columns into %d features\n", len(pl.Features))
+	}
+	if err := model.SaveProtected(*out, saveKind, m, p); err != nil {
+		return err
+	}
+	fmt.Printf("Saved the %s model of %s to %s\n\n", *kind, *target, *out)
+	// Score the saved model on the training rows, which also checks that
+	// it loads back.
+	s, err := loadModel(*out, p)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Training scores")
+	return report(os.Stdout, s, raw, *target)
+}
+
+// newPipeline returns the pipeline reading the feature columns, the
+// categorical ones among them encoded by encoding, or routing the columns
+// by the description of a column transformer, and transforming them by
+// the comma separated steps, written name or impute=<strategy>.
+func newPipeline(target string, features []string, columns, categorical, encoding, steps string) (*model.Pipeline, error) {
+	pl := &model.Pipeline{Target: target, Encoding: encoding}
+	if columns != "" {
+		var err error
+		if pl.Columns, err = transform.ParseColumns(columns); err != nil {
+			return nil, err
+		}
+		pl.Encoding = ""
+		features = nil
+	}
+	var textColumns []string
+	if categorical != "" {
+		textColumns = strings.Split(categorical, ",")
+	}
+	for _, name := range textColumns {
+		if !slices.Contains(features, name) {
+			return nil, fmt.Errorf("train: categorical column %q is not a feature", name)
+		}
+	}
+	for _, name := range features {
+		if slices.Contains(textColumns, name) {
+			pl.Categorical = append(pl.Categorical, name)
+		} else {
+			pl.Numeric = append(pl.Numeric, name)
+		}
+	}
+	if steps == "" {
+		return pl, nil
+	}
+	for _, entry := range strings.Split(steps, ",") {
+		name, arg, hasArg := strings.Cut(entry, "=")
+		step, err := model.NewStep(name)
+		if err != nil {
+			return nil, err
+		}
+		if hasArg {
+			imp, ok := step.Transformer.(*transform.Impute)
+			if !ok {
+				return nil, fmt.Errorf("train: step %s takes no argument", name)
+			}
+			strategy, constant, err := transform.ParseStrategy(arg)
+			if err != nil {
+				return nil, err
+			}
+			imp.Strategies, imp.Constants = []transform.Strategy{strategy}, []float64{constant}
+		}
+		pl.Steps = append(pl.Steps, step)
+	}
+	return pl, nil
+}
+
+// hyperparams holds the settings of the models fitted by gomlearn.
+type hyperparams struct {
+	// params limits the growth of trees and of the trees of forests.
+	params tree.Params
+	// trees is the number of trees of forests.
+	trees int
+	// seed seeds the random choices of trees and forests.
+	seed uint64
+	// workers is the number of trees of forests grown in parallel (see
+	// parallel.Workers).
+	workers int
+	// steps and learningRate drive the gradient descent of logistic
+	// regressions, which predict class 1 from threshold.
+	steps        int
+	learningRate float64
+	threshold    float64
+	// init, when set, is the logistic regression the gradient descent
+	// starts from instead of zero weights.
+	init *model.Logistic
+}
+
+// fitModel fits a model of the kind on the rows of the table and returns
+// it as saved by package model. Classifying trees and forests index the
+// given classes, or the distinct values of the target in sorted order
+// when classes is nil.
+func fitModel(ctx context.Context, kind string, task tree.Task, t *table, target string, names, classes []string, hp hyperparams) (any, error) {
+	x, err := t.matrix(names)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case model.KindLinear:
+		y, err := t.floats(target)
+		if err != nil {
+			return nil, err
+		}
+		return fitLinear(x, y, target, names)
+	case model.KindLogistic:
+		y, err := t.floats(target)
+		if err != nil {
+			return nil, err
+		}
+		if hp.init == nil {
+			lm, err := fitLogistic(ctx, x, y, hp.steps, hp.learningRate)
+			if err != nil {
+				return nil, err
+			}
+			lm.Target, lm.Features, lm.Threshold = target, names, hp.threshold
+			return lm, nil
+		}
+		f, err := resumeLogisticFit(x, y, hp.init)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.run(ctx, hp.steps, hp.learningRate); err != nil {
+			return nil, err
+		}
+		lm := f.model
+		lm.Target, lm.Features, lm.Threshold = target, names, hp.threshold
+		return lm, nil
+	case model.KindTree, model.KindForest:
+		var y []float64
+		y, classes, err = t.targets(task, target, classes)
+		if err != nil {
+			return nil, err
+		}
+		if kind == model.KindTree {
+			tm := tree.New(task, hp.params)
+			if err := tm.Fit(x, y, nil, rand.New(rand.NewSource(int64(hp.seed)))); err != nil {
+				return nil, err
+			}
+			return &model.Tree{Target: target, Features: names, Classes: classes, Tree: tm}, nil
+		}
+		f := forest.New(task, hp.trees, hp.params, hp.seed)
+		f.Workers = hp.workers
+		if err := f.Fit(ctx, x, y, nil); err != nil {
+			return nil, err
+		}
+		return &model.Forest{Target: target, Features: names, Classes: classes, Forest: f}, nil
+	}
+	if _, ok := model.Lookup(kind); ok {
+		return fitCustom(ctx, kind, t, target, names, x)
+	}
+	kinds := strings.Join(append([]string{model.KindLinear, model.KindLogistic, model.KindTree, model.KindForest}, model.Registered()...), ", ")
+	return nil, fmt.Errorf("unknown model %q, expected one of %s", kind, kinds)
+}
+
+// fitLinear fits a linear regression of y on the features of x by least
+// squares.
+func fitLinear(x *mat64.Dense, y []float64, target string, names []string) (*model.Linear, error) {
+	var r regression.Regression
+	r.SetObserved(target)
+	for j, name := range names {
+		r.SetVar(j, name)
+	}
+	for i, label := range y {
+		r.Train(regression.DataPoint(label, x.RawRowView(i)))
+	}
+	if err := r.Run(); err != nil {
+		return nil, err
+	}
+	return model.FromRegression(&r, len(names)), nil
+}
+
+// fitCustom fits an estimator of a kind registered by a plugin on the
+// feature matrix x of the table.
+func fitCustom(ctx context.Context, kind string, t *table, target string, names []string, x *mat64.Dense) (*model.Custom, error) {
+	m, err := model.NewCustom(kind)
+	if err != nil {
+		return nil, err
+	}
+	r, _ := model.Lookup(kind)
+	y, err := t.floats(target)
+	if err != nil {
+		return nil, err
+	}
+	if r.Classifier {
+		for i, label := range y {
+			if label != 0 && label != 1 {
+				return nil, fmt.Errorf("%s: row %d: column %q: the %s model expects labels 0 and 1, not %g", t.path, i+1, target, kind, label)
+			}
+		}
+	}
+	if err := m.Estimator.Fit(ctx, x, y); err != nil {
+		return nil, fmt.Errorf("%s: %v", kind, err)
+	}
+	m.Target, m.Features = target, names
+	return m, nil
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/forest"
+	"github.com/bachhm.dev/go-machine-learning/pkg/memory"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/bachhm.dev/go-machine-learning/pkg/search"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+)
+
+// tunable lists the hyperparameters of every kind of model that -grid
+// can vary.
+var tunable = map[string][]string{
+	model.KindLogistic: {"learning_rate", "steps", "threshold"},
+	model.KindTree:     {"max_depth", "min_samples_leaf", "max_features", "max_bins"},
+	model.KindForest:   {"trees", "max_depth", "min_samples_leaf", "max_features", "max_bins"},
+}
+
+// warmStartable names the hyperparameter that -warm-start continues the
+// fits along, for the kinds of model that support it.
+var warmStartable = map[string]string{
+	model.KindLogistic: "steps",
+	model.KindForest:   "trees",
+}
+
+// tune cross-validates a model on the points of a space of
+// hyperparameters, every point of a grid, points drawn at random or
+// points proposed by Bayesian optimization, writes the points ranked by
+// their mean score to a CSV file and saves the best one refitted on every
+// row.
+func tune(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	kind := fs.String("model", "", "type of the model: logistic, tree or forest")
+	dataPath := fs.String("data", "", "CSV file of the training rows")
+	target := fs.String("target", "", "column to predict")
+	features := fs.String("features", "", "comma separated feature columns (default every column but the target)")
+	taskName := fs.String("task", "classification", "task of tree and forest models: classification or regression")
+	var grids gridList
+	fs.Var(&grids, "grid", "values of a hyperparameter, such as learning_rate=0.01,0.1, or their range, such as learning_rate=loguniform:0.001,1, threshold=uniform:0.3,0.7 or max_depth=uniform:2,10, integers for integer bounds (repeatable, default a space per model)")
+	method := fs.String("search", "grid", "how the candidates are chosen: grid, every point of the grid; random, -trials points drawn at random; or bayes, -trials points each proposed from the scores of the points before it")
+	trials := fs.Int("trials", 20, "number of candidates of the random and bayes searches")
+	points := fs.Int("points", 5, "number of values of the ranges of -grid in the grid search")
+	numFolds := fs.Int("folds", 5, "number of cross-validation folds, stratified by class for classifiers")
+	seed := fs.Uint64("seed", 1, "seed of the folds and of the random choices of trees and forests")
+	metricName := fs.String("metric", "", "registered metric the candidates are ranked by (default accuracy for classifiers and rmse for regressions)")
+	workers := fs.Int("workers", 0, "number of candidates and folds fitted in parallel (0 uses every CPU)")
+	warmStart := fs.Bool("warm-start", false, "fit the candidates that differ only in steps (logistic) or trees (forest) once per fold, continuing the fit of every value from the previous one instead of starting over")
+	memoryBudget := fs.String("memory-budget", "", "largest memory the candidates fitted in parallel are estimated to use together, such as 4GiB, fitting fewer at once as their cost grows (default no limit)")
+	resultsPath := fs.String("results", "tune_results.csv", "path of the CSV file of the ranked candidates")
+	out := fs.String("out", "model.json", "path the best candidate, refitted on every row, is saved to")
+	keys := addKeyFlags(fs, true, false)
+	if err := experiment.Parse(fs, args); err != nil {
+		return err
+	}
+	if *kind == "" || *dataPath == "" || *target == "" {
+		return errors.New("tune: -model, -data and -target are required")
+	}
+	budgetBytes, err := memory.ParseSize(*memoryBudget)
+	if err != nil {
+		return err
+	}
+	p, err := keys.protection()
+	if err != nil {
+		return err
+	}
+	names, ok := tunable[*kind]
+	if !ok {
+		return fmt.Errorf("tune: cannot tune %q models, expected logistic, tree or forest", *kind)
+	}
+	path, ok := warmStartable[*kind]
+	if *warmStart && !ok {
+		return fmt.Errorf("tune: -warm-start applies to logistic and forest models, not to %s models", *kind)
+	}
+	switch *method {
+	case "grid":
+	case "random", "bayes":
+		if *warmStart {
+			return fmt.Errorf("tune: -warm-start applies to the grid search, not to the %s search", *method)
+		}
+		if *trials < 1 {
+			return fmt.Errorf("tune: -trials must be at least 1, got %d", *trials)
+		}
+	default:
+		return fmt.Errorf("tune: unknown search %q, expected grid, random or bayes", *method)
+	}
+	var task tree.Task
+	if err := task.UnmarshalText([]byte(*taskName)); err != nil {
+		return err
+	}
+	classifier := *kind == model.KindLogistic || task == tree.Classification
+	t, err := readTable(*dataPath)
+	if err != nil {
+		return err
+	}
+	featureNames, err := t.featureNames(*features, *target)
+	if err != nil {
+		return err
+	}
+	// Build the space.
+	dims := grids.dims
+	if len(dims) == 0 {
+		dims = defaultSpace(*kind, *method, len(featureNames))
+	}
+	for _, name := range grids.names {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("tune: %s models have no hyperparameter %q, expected one of %s", *kind, name, strings.Join(names, ", "))
+		}
+	}
+	space := search.New(dims...)
+	// The Bayesian search proposes its candidates as it goes.
+	var candidates []search.Params
+	switch *method {
+	case "grid":
+		candidates = space.Grid(*points)
+	case "random":
+		candidates = space.Samples(*trials, *seed)
+	}
+	m, err := tuneMetric(classifier, *metricName)
+	if err != nil {
+		return err
+	}
+	// Deal the rows into folds, keeping the class proportions of
+	// classifiers.
+	var folds []split.Fold
+	var classes []string
+	if classifier {
+		var labels []float64
+		labels, classes, err = t.labels(*target, nil)
+		if err != nil {
+			return err
+		}
+		strata := make([]int, len(labels))
+		for i, label := range labels {
+			strata[i] = int(label)
+		}
+		folds, err = split.RepeatedStratifiedKFold(strata, *numFolds, 1, int64(*seed))
+		if *kind == model.KindLogistic {
+			classes = nil
+		}
+	} else {
+		folds, err = split.RepeatedKFold(len(t.rows), *numFolds, 1, int64(*seed))
+	}
+	if err != nil {
+		return err
+	}
+	base := hyperparams{params: tree.Params{MinSamplesLeaf: 1}, trees: 100, seed: *seed, steps: 1000, learningRate: 0.05, threshold: 0.5}
+	// The candidates and folds run in parallel already, so every forest
+	// grows its trees one at a time.
+	inner := base
+	inner.workers = 1
+	if *method == "bayes" {
+		fmt.Printf("Tuning %d %s candidates by Bayesian optimization on %d folds of %d rows by %s\n", *trials, *kind, len(folds), len(t.rows), m.Name)
+	} else {
+		fmt.Printf("Tuning %d %s candidates on %d folds of %d rows by %s\n", len(candidates), *kind, len(folds), len(t.rows), m.Name)
+	}
+	budget := parallel.Budget{CPUs: *workers, Memory: budgetBytes}
+	cost := func(p search.Params) parallel.Cost {
+		return parallel.Cost{CPUs: 1, Memory: estimateMemory(*kind, inner.with(p), len(t.rows), len(featureNames), len(classes))}
+	}
+	if budgetBytes > 0 && candidates != nil {
+		var largest uint64
+		for _, c := range candidates {
+			largest = max(largest, cost(c).Memory)
+		}
+		fmt.Printf("Memory budget %s, the largest candidate is estimated at %s per fold\n", memory.FormatSize(budgetBytes), memory.FormatSize(largest))
+	}
+	// score scores a fitted candidate on the test rows.
+	score := func(fitted any, test *table) (float64, error) {
+		observed, predicted, proba, err := wrap(fitted).outcomes(test, *target)
+		if err != nil {
+			return 0, err
+		}
+		s := m.Objective(observed, predicted, proba)
+		if math.IsNaN(s) && proba == nil {
+			return 0, fmt.Errorf("metric %q cannot score the %s model, as it needs the predicted probabilities", m.Name, *kind)
+		}
+		return s, nil
+	}
+	scorer := func(p search.Params, train, test []int) (float64, error) {
+		fitted, err := fitModel(ctx, *kind, task, t.subset(train), *target, featureNames, classes, inner.with(p))
+		if err != nil {
+			return 0, err
+		}
+		return score(fitted, t.subset(test))
+	}
+	var results []search.Result
+	switch {
+	case *warmStart:
+		fmt.Printf("Warm starting the fits along %s\n", path)
+		fmt.Println()
+		results, err = search.GridSearchPath(ctx, candidates, folds, path, budget, cost, func(p search.Params, values []float64, train, test []int) ([]float64, error) {
+			testRows := t.subset(test)
+			scores := make([]float64, 0, len(values))
+			err := fitPath(ctx, *kind, task, t.subset(train), *target, featureNames, classes, inner.with(p), values, func(fitted any) error {
+				s, err := score(fitted, testRows)
+				scores = append(scores, s)
+				return err
+			})
+			return scores, err
+		})
+	case *method == "bayes":
+		fmt.Println()
+		results, err = search.BayesSearch(ctx, space, *trials, *seed, folds, budget, cost, scorer)
+	default:
+		fmt.Println()
+		results, err = search.GridSearchBudget(ctx, candidates, folds, budget, cost, scorer)
+	}
+	if err != nil {
+		return err
+	}
+	// Report the scores as the metric gives them, not as maximized.
+	if !m.HigherIsBetter {
+		for i := range results {
+			results[i].Mean = -results[i].Mean
+			for k := range results[i].Scores {
+				results[i].Scores[k] = -results[i].Scores[k]
+			}
+		}
+	}
+	if err := search.WriteResults(os.Stdout, results, 10); err != nil {
+		return err
+	}
+	columns, rows := search.ResultTable(results, m.Name)
+	if err := artifacts.WriteCSV(*resultsPath, columns, rows); err != nil {
+		return err
+	}
+	fmt.Printf("\nWrote the %d ranked candidates to %s\n", len(results), *resultsPath)
+	// Refit the best candidate on every row.
+	best := results[0]
+	fitted, err := fitModel(ctx, *kind, task, t, *target, featureNames, classes, base.with(best.Params))
+	if err != nil {
+		return err
+	}
+	if err := model.SaveProtected(*out, *kind, fitted, p); err != nil {
+		return err
+	}
+	fmt.Printf("Refitted the best candidate, %s with a mean %s of %.4f, on every row and saved it to %s\n", best.Params.Format(), m.Name, best.Mean, *out)
+	return nil
+}
+
+// Rough sizes, in bytes, of what the fits of the candidates allocate.
+const (
+	// floatBytes is the size of a value of a feature matrix.
+	floatBytes = 8
+	// nodeBytes is the size of a tree node without its values.
+	nodeBytes = 64
+)
+
+// estimateMemory returns a rough upper estimate of the memory fitting a
+// model of the kind on a fold of numRows rows allocates: the copies of
+// the rows, and the nodes of trees, which hold a value per class, or one
+// for regressions. It does not need to be exact, only to grow with the
+// cost of the candidates so that the budget fits fewer of the large ones
+// at once.
+func estimateMemory(kind string, hp hyperparams, numRows, numFeatures, numClasses int) uint64 {
+	// The fold is copied out of the table, then into a feature matrix.
+	rows := uint64(numRows) * uint64(numFeatures+1) * floatBytes
+	data := 2 * rows
+	if kind == model.KindLogistic {
+		return data
+	}
+	// A tree has fewer than two nodes per row, and at most 2^(d+1) - 1
+	// nodes of depth d.
+	nodes := 2 * uint64(numRows)
+	if d := hp.params.MaxDepth; d > 0 && d < 40 {
+		nodes = min(nodes, uint64(1)<<(d+1))
+	}
+	tree := nodes * (nodeBytes + uint64(max(numClasses, 1))*floatBytes)
+	// Growing a tree sorts a copy of the rows.
+	growing := rows
+	if kind == model.KindForest {
+		// The trees are grown one at a time, and kept.
+		return data + growing + uint64(hp.trees)*tree
+	}
+	return data + growing + tree
+}
+
+// tuneMetric returns the registered metric of the name, by default the
+// accuracy for classifiers and the RMSE for regressions.
+func tuneMetric(classifier bool, name string) (metrics.Metric, error) {
+	if name == "" {
+		name = "rmse"
+		if classifier {
+			name = "accuracy"
+		}
+	}
+	m, err := metrics.Lookup(name)
+	if err != nil {
+		return metrics.Metric{}, err
+	}
+	if m.Classifier != classifier {
+		return metrics.Metric{}, fmt.Errorf("metric %q does not score the models of this task", name)
+	}
+	return m, nil
+}
+
+// defaultSpace returns the space tuned when -grid is not set: the
+// learning rate and the number of steps of logistic regressions, the
+// depth and the leaf size of trees, and the number of trees and of split
+// candidates of forests. The random and bayes searches draw the learning
+// rate from a range rather than from the values of the grid.
+func defaultSpace(kind, method string, numFeatures int) []search.Dimension {
+	switch kind {
+	case model.KindLogistic:
+		learningRate := search.Choice("learning_rate", 0.01, 0.05, 0.1)
+		if method != "grid" {
+			learningRate = search.LogUniform("learning_rate", 0.005, 0.5)
+		}
+		return []search.Dimension{
+			learningRate,
+			search.Choice("steps", 500, 1000, 2000),
+		}
+	case model.KindTree:
+		return []search.Dimension{
+			search.Choice("max_depth", 2, 4, 8, 0),
+			search.Choice("min_samples_leaf", 1, 5, 10),
+		}
+	}
+	// Try a single feature, the usual square root and third, and every
+	// feature.
+	var maxFeatures []any
+	for _, n := range []int{1, int(math.Sqrt(float64(numFeatures))), numFeatures / 3, numFeatures} {
+		if n >= 1 && !slices.Contains(maxFeatures, any(n)) {
+			maxFeatures = append(maxFeatures, n)
+		}
+	}
+	slices.SortFunc(maxFeatures, func(a, b any) int { return a.(int) - b.(int) })
+	return []search.Dimension{
+		search.Choice("trees", 50, 100, 200),
+		search.Choice("max_features", maxFeatures...),
+	}
+}
+
+// with returns the hyperparameters with those of a point of the grid.
+func (hp hyperparams) with(p search.Params) hyperparams {
+	for name := range p {
+		switch name {
+		case "learning_rate":
+			hp.learningRate = p.Float(name)
+		case "steps":
+			hp.steps = p.Int(name)
+		case "threshold":
+			hp.threshold = p.Float(name)
+		case "trees":
+			hp.trees = p.Int(name)
+		case "max_depth":
+			hp.params.MaxDepth = p.Int(name)
+		case "min_samples_leaf":
+			hp.params.MinSamplesLeaf = p.Int(name)
+		case "max_features":
+			hp.params.MaxFeatures = p.Int(name)
+		case "max_bins":
+			hp.params.MaxBins = p.Int(name)
+		}
+	}
+	return hp
+}
+
+// fitPath fits a model of the kind on the rows of the table at every one
+// of the increasing values of its warm start hyperparameter (see
+// warmStartable), continuing from the model of the previous value, and
+// calls each with the model of every value before fitting the next.
+func fitPath(ctx context.Context, kind string, task tree.Task, t *table, target string, names, classes []string, hp hyperparams, values []float64, each func(fitted any) error) error {
+	x, err := t.matrix(names)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case model.KindLogistic:
+		y, err := t.floats(target)
+		if err != nil {
+			return err
+		}
+		f, err := newLogisticFit(x, y)
+		if err != nil {
+			return err
+		}
+		for _, steps := range values {
+			if err := f.run(ctx, int(steps), hp.learningRate); err != nil {
+				return err
+			}
+			lm := f.model
+			lm.Target, lm.Features, lm.Threshold = target, names, hp.threshold
+			if err := each(lm); err != nil {
+				return err
+			}
+		}
+		return nil
+	case model.KindForest:
+		y, classes, err := t.targets(task, target, classes)
+		if err != nil {
+			return err
+		}
+		f := forest.New(task, 0, hp.params, hp.seed)
+		f.Workers, f.WarmStart, f.Names = hp.workers, true, names
+		for _, trees := range values {
+			f.NumTrees = int(trees)
+			if err := f.Fit(ctx, x, y, nil); err != nil {
+				return err
+			}
+			if err := each(&model.Forest{Target: target, Features: names, Classes: classes, Forest: f}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot warm start %s models", kind)
+}
+
+// gridList collects the dimensions of the repeated -grid flag, each
+// written name=value,value, or name=uniform:min,max or
+// name=loguniform:min,max for a range.
+type gridList struct {
+	names []string
+	dims  []search.Dimension
+}
+
+func (l *gridList) String() string { return strings.Join(l.names, ",") }
+
+func (l *gridList) Set(s string) error {
+	name, list, ok := strings.Cut(s, "=")
+	if !ok || name == "" || list == "" {
+		return fmt.Errorf("expected name=value,value, not %q", s)
+	}
+	if slices.Contains(l.names, name) {
+		return fmt.Errorf("%s is set twice", name)
+	}
+	if dist, bounds, ok := strings.Cut(list, ":"); ok {
+		return l.setRange(name, dist, bounds)
+	}
+	var values []any
+	for _, field := range strings.Split(list, ",") {
+		if n, err := strconv.Atoi(field); err == nil {
+			values = append(values, n)
+			continue
+		}
+		x, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", name, field)
+		}
+		values = append(values, x)
+	}
+	l.names = append(l.names, name)
+	l.dims = append(l.dims, search.Choice(name, values...))
+	return nil
+}
+
+// setRange adds the dimension of a range of the distribution.
+func (l *gridList) setRange(name, dist, bounds string) error {
+	lo, hi, ok := strings.Cut(bounds, ",")
+	if !ok {
+		return fmt.Errorf("%s: expected %s:min,max, not %q", name, dist, dist+":"+bounds)
+	}
+	min, err := strconv.ParseFloat(lo, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", name, lo)
+	}
+	max, err := strconv.ParseFloat(hi, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", name, hi)
+	}
+	var d search.Dimension
+	switch dist {
+	case "uniform":
+		if min > max {
+			return fmt.Errorf("%s: min %v is above max %v", name, min, max)
+		}
+		// Integer bounds draw integers.
+		a, errLo := strconv.Atoi(lo)
+		b, errHi := strconv.Atoi(hi)
+		if errLo == nil && errHi == nil {
+			d = search.IntUniform(name, a, b)
+		} else {
+			d = search.Uniform(name, min, max)
+		}
+	case "loguniform":
+		if min <= 0 || min > max {
+			return fmt.Errorf("%s: loguniform needs 0 < min <= max, got %v,%v", name, min, max)
+		}
+		d = search.LogUniform(name, min, max)
+	default:
+		return fmt.Errorf("%s: unknown range %q, expected uniform or loguniform", name, dist)
+	}
+	l.names = append(l.names, name)
+	l.dims = append(l.dims, d)
+	return nil
+}
@@ -0,0 +1,183 @@
+// Package artifacts manages the output directory of a training run, so
+// that plots, models, metrics and the configuration of every invocation
+// are kept side by side instead of overwriting the previous run.
+package artifacts
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// PlotsDir is the subdirectory holding the plots of a run.
+	PlotsDir = "plots"
+	// ModelsDir is the subdirectory holding the models of a run.
+	ModelsDir = "models"
+	// MetricsFile is the name of the metrics file of a run.
+	MetricsFile = "metrics.json"
+	// MetricsCSVFile is the name of the metrics file of a run in CSV
+	// form, with one "metric,value" row per metric.
+	MetricsCSVFile = "metrics.csv"
+	// ConfigFile is the name of the configuration file of a run.
+	ConfigFile = "config.yaml"
+)
+
+// Run is the artifact directory of a single training invocation.
+type Run struct {
+	// Dir is the root directory of the run.
+	Dir string
+}
+
+// NewRun creates a run directory named after the program and the current
+// time below root, along with its plots and models subdirectories. A
+// numeric suffix is added when a run with the same timestamp exists.
+func NewRun(root, name string) (*Run, error) {
+	return NewNamedRun(root, name+"-"+time.Now().Format("20060102-150405"))
+}
+
+// NewNamedRun creates a run directory of the given name below root, along
+// with its plots and models subdirectories. A numeric suffix is added
+// when a run of that name exists.
+func NewNamedRun(root, name string) (*Run, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	base := filepath.Join(root, name)
+	dir := base
+	for i := 1; ; i++ {
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		dir = fmt.Sprintf("%s-%d", base, i)
+	}
+	for _, sub := range []string{PlotsDir, ModelsDir} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &Run{Dir: dir}, nil
+}
+
+// Path returns the path of a file in the root of the run directory.
+func (r *Run) Path(name string) string {
+	return filepath.Join(r.Dir, name)
+}
+
+// PlotPath returns the path of a plot file of the run.
+func (r *Run) PlotPath(name string) string {
+	return filepath.Join(r.Dir, PlotsDir, name)
+}
+
+// ModelPath returns the path of a model file of the run.
+func (r *Run) ModelPath(name string) string {
+	return filepath.Join(r.Dir, ModelsDir, name)
+}
+
+// WriteMetrics writes the metrics of the run to metrics.json and, sorted
+// by name, to metrics.csv.
+func (r *Run) WriteMetrics(metrics map[string]float64) error {
+	data, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.Path(MetricsFile), append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([][]any, len(names))
+	for i, name := range names {
+		rows[i] = []any{name, metrics[name]}
+	}
+	return WriteCSV(r.Path(MetricsCSVFile), []string{"metric", "value"}, rows)
+}
+
+// WriteTable writes a table of results, such as per-fold or per-class
+// metrics, to name.csv and to name.json. The JSON file holds one object
+// per row keyed by the column names, keeping numbers as numbers.
+func (r *Run) WriteTable(name string, columns []string, rows [][]any) error {
+	records := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf("artifacts: row %d of %s has %d values for %d columns", i, name, len(row), len(columns))
+		}
+		records[i] = make(map[string]any, len(columns))
+		for j, column := range columns {
+			records[i][column] = row[j]
+		}
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(r.Path(name+".json"), append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return WriteCSV(r.Path(name+".csv"), columns, rows)
+}
+
+// WriteCSV writes the header and the rows to a CSV file at path,
+// formatting floats in the shortest exact form.
+func WriteCSV(path string, header []string, rows [][]any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for j, v := range row {
+			if x, ok := v.(float64); ok {
+				record[j] = strconv.FormatFloat(x, 'g', -1, 64)
+			} else {
+				record[j] = fmt.Sprint(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WriteConfig writes the configuration of the run to config.yaml as one
+// "key: value" line per setting, sorted by key. String values are quoted.
+func (r *Run) WriteConfig(config map[string]any) error {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var data []byte
+	for _, key := range keys {
+		value := fmt.Sprint(config[key])
+		if s, ok := config[key].(string); ok {
+			value = strconv.Quote(s)
+		}
+		data = append(data, key+": "+value+"\n"...)
+	}
+	return os.WriteFile(r.Path(ConfigFile), data, 0o644)
+}
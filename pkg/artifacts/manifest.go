@@ -0,0 +1,212 @@
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the file identifying a run.
+const ManifestFile = "run.json"
+
+// Manifest identifies a run: which program ran, when, from which commit
+// of the source and on which data.
+type Manifest struct {
+	// ID is the name of the run directory, unique in its runs directory.
+	ID      string    `json:"id"`
+	Program string    `json:"program"`
+	Started time.Time `json:"started"`
+	// Commit is the revision of the source the program was built from,
+	// with a "-dirty" suffix when it had uncommitted changes, or empty
+	// when unknown.
+	Commit string `json:"commit,omitempty"`
+	// Datasets maps the name of every dataset file to the SHA-256 hash of
+	// its contents.
+	Datasets map[string]string `json:"datasets,omitempty"`
+}
+
+// WriteManifest writes the manifest of the run, started now by the
+// program, with the hashes of the dataset files, keyed by their base
+// names.
+func (r *Run) WriteManifest(program string, datasets []string) error {
+	m := Manifest{ID: filepath.Base(r.Dir), Program: program, Started: time.Now().UTC().Truncate(time.Second), Commit: SourceCommit()}
+	for _, path := range datasets {
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		if m.Datasets == nil {
+			m.Datasets = make(map[string]string)
+		}
+		m.Datasets[filepath.Base(path)] = hash
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path(ManifestFile), append(data, '\n'), 0o644)
+}
+
+// hashFile returns the SHA-256 hash of the contents of the file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SourceCommit returns the revision of the source of the program: the
+// one stamped by go build, or else, as go run does not stamp it, the one
+// git reports for the working directory. It returns "" when neither is
+// known.
+func SourceCommit() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision, modified string
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if revision != "" {
+			if modified == "true" {
+				revision += "-dirty"
+			}
+			return revision
+		}
+	}
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	revision := strings.TrimSpace(string(out))
+	if status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output(); err == nil && len(status) > 0 {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// Record is a run read back from its directory.
+type Record struct {
+	Dir      string
+	Manifest Manifest
+	// Params holds the resolved flags of the run, or its configuration
+	// for the runs that did not write them.
+	Params  map[string]any
+	Metrics map[string]float64
+	// Artifacts lists the plots, models and other files of the run,
+	// relative to Dir.
+	Artifacts []string
+}
+
+// ReadRun reads the run of the directory. Runs written before manifests
+// were are identified by the name of their directory and its time of
+// change.
+func ReadRun(dir string) (*Record, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Record{Dir: dir, Manifest: Manifest{ID: filepath.Base(dir), Started: info.ModTime().UTC().Truncate(time.Second)}}
+	if err := readJSON(filepath.Join(dir, ManifestFile), &r.Manifest); err != nil {
+		return nil, err
+	}
+	if err := readJSON(filepath.Join(dir, MetricsFile), &r.Metrics); err != nil {
+		return nil, err
+	}
+	// Prefer the resolved flags, which hold every setting, to the
+	// configuration the program chose to write.
+	for _, name := range []string{experiment.File, ConfigFile} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &r.Params); err != nil {
+			return nil, fmt.Errorf("artifacts: %s: %v", filepath.Join(dir, name), err)
+		}
+		break
+	}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		switch rel {
+		case ManifestFile, MetricsFile, MetricsCSVFile, experiment.File, ConfigFile:
+		default:
+			r.Artifacts = append(r.Artifacts, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// readJSON decodes the JSON file at path into v, leaving v unchanged when
+// there is no file.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("artifacts: %s: %v", path, err)
+	}
+	return nil
+}
+
+// ListRuns reads every run directory below root, oldest first.
+func ListRuns(root string) ([]*Record, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var runs []*Record
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		r, err := ReadRun(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	sort.SliceStable(runs, func(a, b int) bool {
+		return runs[a].Manifest.Started.Before(runs[b].Manifest.Started)
+	})
+	return runs, nil
+}
@@ -0,0 +1,230 @@
+// Package boost loads gradient boosted tree ensembles trained by XGBoost
+// (JSON model files) and LightGBM (text model files) and predicts with
+// them in pure Go, so models trained with those libraries can be used
+// without their C++ runtimes. Only numerical splits are supported.
+//
+// The package does not train ensembles. Histogram split finding, which
+// boosting libraries use on large datasets, is in the CART trees of
+// package tree (Params.MaxBins), on which the random forest builds.
+package boost
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrCategorical is returned for models with categorical splits.
+var ErrCategorical = errors.New("boost: categorical splits are not supported")
+
+// Link maps the summed tree outputs, the margins, to predictions.
+type Link int
+
+const (
+	// Identity leaves the margin as is, as for squared error regression.
+	Identity Link = iota
+	// Sigmoid maps the margin to the probability of the positive class.
+	Sigmoid
+	// Softmax maps the margins of every class to class probabilities.
+	Softmax
+	// Exp maps the margin to a positive value, as for Poisson, gamma and
+	// Tweedie regression.
+	Exp
+)
+
+// Missing selects the rows sent to the default child of a split.
+type Missing int
+
+const (
+	// MissingNaN sends NaN values to the default child.
+	MissingNaN Missing = iota
+	// MissingZero sends NaN and zero values to the default child.
+	MissingZero
+	// MissingNone treats NaN values as zero and has no default child.
+	MissingNone
+)
+
+// Node is a node of a tree. Leaves have a Feature of -1.
+type Node struct {
+	// Feature and Threshold define the split. Children are indices into
+	// the nodes of the tree.
+	Feature     int
+	Threshold   float64
+	Left, Right int
+	// DefaultLeft sends the missing values, as defined by Missing, to the
+	// left child.
+	DefaultLeft bool
+	Missing     Missing
+	// Value is the output of a leaf.
+	Value float64
+}
+
+// Tree is a tree of the ensemble, with its root first.
+type Tree struct {
+	Nodes []Node
+	// Class is the class whose margin the tree adds to, for multiclass
+	// models.
+	Class int
+}
+
+// Ensemble is a gradient boosted tree ensemble.
+type Ensemble struct {
+	// Source names the library the model was read from.
+	Source string
+	// Objective is the training objective as named by the library.
+	Objective string
+	Link      Link
+	// Scale multiplies the margins before the link, such as the sigmoid
+	// parameter of LightGBM.
+	Scale float64
+	// NumClasses is the number of classes of multiclass models, and 1
+	// otherwise.
+	NumClasses int
+	// NumFeatures is the number of features of the rows.
+	NumFeatures int
+	// FeatureNames names the features, when the model file does.
+	FeatureNames []string
+	// BaseMargin is added to the margin of every class.
+	BaseMargin float64
+	// TreeScale multiplies the summed outputs of the trees, such as to
+	// average them in random forest models.
+	TreeScale float64
+	Trees     []Tree
+	// strictLess compares the features with the thresholds as XGBoost
+	// does: in single precision, going left when below the threshold.
+	// Otherwise rows go left when at most the threshold, as in LightGBM.
+	strictLess bool
+}
+
+// left reports whether the value goes to the left child of the node.
+func (e *Ensemble) left(n *Node, v float64) bool {
+	switch {
+	case math.IsNaN(v) && n.Missing != MissingNone:
+		return n.DefaultLeft
+	case math.IsNaN(v):
+		v = 0
+	case v == 0 && n.Missing == MissingZero:
+		return n.DefaultLeft
+	}
+	if e.strictLess {
+		return float32(v) < float32(n.Threshold)
+	}
+	return v <= n.Threshold
+}
+
+// leaf returns the output of the leaf of the tree reached by the row.
+func (e *Ensemble) leaf(t *Tree, row []float64) float64 {
+	n := &t.Nodes[0]
+	for n.Feature >= 0 {
+		if e.left(n, row[n.Feature]) {
+			n = &t.Nodes[n.Left]
+		} else {
+			n = &t.Nodes[n.Right]
+		}
+	}
+	return n.Value
+}
+
+// Margins returns the summed outputs of the trees of every class.
+func (e *Ensemble) Margins(row []float64) ([]float64, error) {
+	if len(row) < e.NumFeatures {
+		return nil, fmt.Errorf("boost: row has %d features, the model %d", len(row), e.NumFeatures)
+	}
+	margins := make([]float64, e.NumClasses)
+	for i := range e.Trees {
+		t := &e.Trees[i]
+		margins[t.Class] += e.leaf(t, row)
+	}
+	for k := range margins {
+		margins[k] = e.BaseMargin + e.TreeScale*margins[k]
+	}
+	return margins, nil
+}
+
+// PredictProba returns the predictions of the row: the class
+// probabilities of multiclass models, the probability of the positive
+// class of binary models and the predicted value of regressions.
+func (e *Ensemble) PredictProba(row []float64) ([]float64, error) {
+	margins, err := e.Margins(row)
+	if err != nil {
+		return nil, err
+	}
+	for k := range margins {
+		margins[k] *= e.Scale
+	}
+	switch e.Link {
+	case Sigmoid:
+		for k, m := range margins {
+			margins[k] = 1 / (1 + math.Exp(-m))
+		}
+	case Exp:
+		for k, m := range margins {
+			margins[k] = math.Exp(m)
+		}
+	case Softmax:
+		top := margins[0]
+		for _, m := range margins {
+			top = math.Max(top, m)
+		}
+		var sum float64
+		for k, m := range margins {
+			margins[k] = math.Exp(m - top)
+			sum += margins[k]
+		}
+		for k := range margins {
+			margins[k] /= sum
+		}
+	}
+	return margins, nil
+}
+
+// Predict returns the predicted class index of classifiers, 1 when the
+// positive class has a probability of at least 0.5 for binary models, or
+// the predicted value of regressions.
+func (e *Ensemble) Predict(row []float64) (float64, error) {
+	p, err := e.PredictProba(row)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case e.Link == Softmax:
+		best := 0
+		for k := range p {
+			if p[k] > p[best] {
+				best = k
+			}
+		}
+		return float64(best), nil
+	case e.Link == Sigmoid:
+		if p[0] >= 0.5 {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return p[0], nil
+}
+
+// validate checks that there are trees and that the children and
+// features of every node are in range.
+func (e *Ensemble) validate() error {
+	if len(e.Trees) == 0 {
+		return errors.New("boost: no trees")
+	}
+	for i, t := range e.Trees {
+		if len(t.Nodes) == 0 {
+			return fmt.Errorf("boost: tree %d has no nodes", i)
+		}
+		if t.Class < 0 || t.Class >= e.NumClasses {
+			return fmt.Errorf("boost: tree %d adds to class %d of %d", i, t.Class, e.NumClasses)
+		}
+		for j, n := range t.Nodes {
+			if n.Feature < 0 {
+				continue
+			}
+			if n.Feature >= e.NumFeatures || n.Left <= j || n.Right <= j || n.Left >= len(t.Nodes) || n.Right >= len(t.Nodes) {
+				return fmt.Errorf("boost: tree %d: node %d is malformed", i, j)
+			}
+		}
+	}
+	return nil
+}
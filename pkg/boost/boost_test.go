@@ -0,0 +1,173 @@
+package boost_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/boost"
+)
+
+// The model files were written by hand in the formats XGBoost 2.0 and
+// LightGBM 4 save, with round leaf values so that the expected
+// predictions can be worked out on paper.
+const (
+	xgboostModel  = "testdata/xgboost_binary.json"
+	lightgbmModel = "testdata/lightgbm_multiclass.txt"
+)
+
+// checkProba fails the test unless the ensemble predicts the
+// probabilities want for every row, and the classes of wantClass.
+func checkProba(t *testing.T, e *boost.Ensemble, rows, want [][]float64, wantClass []float64) {
+	t.Helper()
+	for i, row := range rows {
+		got, err := e.PredictProba(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want[i]) {
+			t.Fatalf("row %v: %d probabilities, want %d", row, len(got), len(want[i]))
+		}
+		for k := range got {
+			if math.Abs(got[k]-want[i][k]) > 1e-12 {
+				t.Errorf("row %v: probability %d = %v, want %v", row, k, got[k], want[i][k])
+			}
+		}
+		class, err := e.Predict(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if class != wantClass[i] {
+			t.Errorf("row %v: class %g, want %g", row, class, wantClass[i])
+		}
+	}
+}
+
+func TestReadXGBoost(t *testing.T) {
+	e, err := boost.ReadXGBoost(xgboostModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.NumFeatures != 2 || len(e.Trees) != 2 || e.Link != boost.Sigmoid || e.BaseMargin != 0 {
+		t.Fatalf("read %d features, %d trees, link %d and base margin %g", e.NumFeatures, len(e.Trees), e.Link, e.BaseMargin)
+	}
+	rows := [][]float64{
+		{720, 10},
+		{640, 30},
+		// A missing FICO takes the default child, left, in both trees.
+		{math.NaN(), 15},
+		// XGBoost goes left only below the threshold.
+		{700, 20},
+	}
+	// The margins are 0.85, -0.7, -0.3 and 0.3.
+	want := [][]float64{{0.7005671424739729}, {0.3318122278318339}, {0.425557483188341}, {0.574442516811659}}
+	checkProba(t, e, rows, want, []float64{1, 0, 0, 1})
+}
+
+func TestReadLightGBM(t *testing.T) {
+	e, err := boost.ReadLightGBM(lightgbmModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.NumFeatures != 2 || e.NumClasses != 3 || len(e.Trees) != 3 || e.Link != boost.Softmax {
+		t.Fatalf("read %d features, %d classes, %d trees and link %d", e.NumFeatures, e.NumClasses, len(e.Trees), e.Link)
+	}
+	rows := [][]float64{
+		{1.4, 0.2},
+		{4.5, 1.5},
+		{5.5, 2.1},
+		// A missing petal width takes the default child, left, of the
+		// split of tree 1 on NaN, and counts as 0 in tree 2, whose split
+		// has no missing type.
+		{5.0, math.NaN()},
+	}
+	// The margins are (1, -0.5, -0.3), (-0.5, 0.8, -0.3), (-0.5, -0.4, 0.9)
+	// and again (-0.5, 0.8, -0.3).
+	want := [][]float64{
+		{0.6686002795432605, 0.14918488744977265, 0.18221483300696684},
+		{0.16975912836830703, 0.6228966040197765, 0.20734426761191654},
+		{0.1623278888042037, 0.17940006189902344, 0.6582720492967729},
+		{0.16975912836830703, 0.6228966040197765, 0.20734426761191654},
+	}
+	checkProba(t, e, rows, want, []float64{0, 1, 2, 1})
+	if _, err := e.PredictProba([]float64{1.4}); err == nil {
+		t.Error("predicted a row of one feature")
+	}
+}
+
+// malformed writes the model file at path with old replaced by new to a
+// temporary file, and returns its path.
+func malformed(t *testing.T, path, old, new string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), old) {
+		t.Fatalf("%s does not hold %q", path, old)
+	}
+	out := filepath.Join(t.TempDir(), filepath.Base(path))
+	if err := os.WriteFile(out, []byte(strings.Replace(string(data), old, new, 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestReadXGBoostMalformed(t *testing.T) {
+	for _, c := range []struct {
+		name, old, new string
+		want           error
+	}{
+		{"no trees", "\"tree_info\": [0, 0],\n        \"trees\": [", "\"tree_info\": [],\n        \"trees\": [], \"unused\": [", nil},
+		{"tree_info short", `"tree_info": [0, 0]`, `"tree_info": [0]`, nil},
+		{"split index out of range", `"split_indices": [0, 0, 0]`, `"split_indices": [5, 0, 0]`, nil},
+		{"negative split index", `"split_indices": [0, 0, 0]`, `"split_indices": [-1, 0, 0]`, nil},
+		{"child out of range", `"left_children": [1, -1, -1]`, `"left_children": [7, -1, -1]`, nil},
+		{"child before parent", `"left_children": [1, 3, -1, -1, -1]`, `"left_children": [1, 0, -1, -1, -1]`, nil},
+		{"short arrays", `"split_conditions": [700.0, -0.4, 0.6]`, `"split_conditions": [700.0]`, nil},
+		{"class out of range", `"tree_info": [0, 0]`, `"tree_info": [0, 1]`, nil},
+		{"categorical split", `"split_type": [0, 0, 0]`, `"split_type": [1, 0, 0]`, boost.ErrCategorical},
+		{"unsupported objective", `"binary:logistic"`, `"rank:pairwise"`, nil},
+		{"bad num_feature", `"num_feature": "2", "num_target"`, `"num_feature": "two", "num_target"`, nil},
+		{"not JSON", `"learner": {`, `"learner": [`, nil},
+	} {
+		path := malformed(t, xgboostModel, c.old, c.new)
+		_, err := boost.ReadXGBoost(path)
+		if err == nil {
+			t.Errorf("%s: read without an error", c.name)
+		} else if c.want != nil && !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestReadLightGBMMalformed(t *testing.T) {
+	for _, c := range []struct {
+		name, old, new string
+		want           error
+	}{
+		{"no trees", "Tree=0", "end of trees\nTree=0", nil},
+		{"no header", "tree\nversion=v4", "Tree=9\nversion=v4", nil},
+		{"split feature out of range", "split_feature=0 1", "split_feature=0 2", nil},
+		{"negative split feature", "split_feature=0 1", "split_feature=0 -1", nil},
+		{"leaf child out of range", "left_child=-1 -2", "left_child=-1 -9", nil},
+		{"internal child before parent", "right_child=1 -3", "right_child=0 -3", nil},
+		{"too few leaf values", "leaf_value=1 -0.5", "leaf_value=1", nil},
+		{"bad threshold", "threshold=2.4500000000000006\n", "threshold=low\n", nil},
+		{"no leaves", "num_leaves=2", "num_leaves=0", nil},
+		{"class out of range", "num_class=3\n", "num_class=2\n", nil},
+		{"categorical split", "decision_type=2 10", "decision_type=1 10", boost.ErrCategorical},
+		{"unsupported objective", "objective=multiclass", "objective=lambdarank", nil},
+	} {
+		path := malformed(t, lightgbmModel, c.old, c.new)
+		_, err := boost.ReadLightGBM(path)
+		if err == nil {
+			t.Errorf("%s: read without an error", c.name)
+		} else if c.want != nil && !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+}
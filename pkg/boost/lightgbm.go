@@ -0,0 +1,244 @@
+package boost
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Bits of the decision types of LightGBM splits.
+const (
+	lgbCategorical = 1 << 0
+	lgbDefaultLeft = 1 << 1
+	// The missing type is held in bits 2 and 3: 0 for none, 1 for zero
+	// and 2 for NaN.
+	lgbMissingShift = 2
+)
+
+// ReadLightGBM reads a model saved by LightGBM as text, with
+// Booster.save_model("model.txt"). Numerical splits and the regression,
+// binary, multiclass, Poisson, gamma and Tweedie objectives are
+// supported.
+func ReadLightGBM(path string) (*Ensemble, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Read the header and the trees as blocks of key=value lines.
+	var header map[string]string
+	var trees []map[string]string
+	block := make(map[string]string)
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		if _, ok := block["Tree"]; ok {
+			trees = append(trees, block)
+		} else if header == nil {
+			header = block
+		}
+		block = make(map[string]string)
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1<<20), 1<<30)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "end of trees" {
+			break
+		}
+		if line == "" || line == "tree" {
+			flush()
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		if key == "Tree" {
+			flush()
+		}
+		block[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	if header == nil {
+		return nil, fmt.Errorf("boost: %s: no model header", path)
+	}
+	e, err := lgbEnsemble(header)
+	if err != nil {
+		return nil, fmt.Errorf("boost: %s: %v", path, err)
+	}
+	// Trees are grouped by iteration, one per class.
+	perIteration := max(atoi(header["num_tree_per_iteration"]), 1)
+	e.Trees = make([]Tree, len(trees))
+	for i, block := range trees {
+		if block["is_linear"] == "1" {
+			return nil, fmt.Errorf("boost: %s: tree %d: linear trees are not supported", path, i)
+		}
+		nodes, err := lgbNodes(block)
+		if err != nil {
+			return nil, fmt.Errorf("boost: %s: tree %d: %w", path, i, err)
+		}
+		e.Trees[i] = Tree{Nodes: nodes, Class: i % perIteration}
+	}
+	// Random forest models average the iterations rather than sum them.
+	if _, ok := header["average_output"]; ok && len(trees) > 0 {
+		e.TreeScale = float64(perIteration) / float64(len(trees))
+	}
+	if err := e.validate(); err != nil {
+		return nil, fmt.Errorf("%v in %s", err, path)
+	}
+	return e, nil
+}
+
+// lgbEnsemble returns the ensemble described by the model header.
+func lgbEnsemble(header map[string]string) (*Ensemble, error) {
+	e := &Ensemble{Source: "lightgbm", Scale: 1, TreeScale: 1}
+	e.NumClasses = max(atoi(header["num_class"]), 1)
+	e.NumFeatures = atoi(header["max_feature_idx"]) + 1
+	if names := header["feature_names"]; names != "" {
+		e.FeatureNames = strings.Fields(names)
+	}
+	// The objective is a name followed by parameters such as sigmoid:1.
+	fields := strings.Fields(header["objective"])
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no objective")
+	}
+	e.Objective = fields[0]
+	switch e.Objective {
+	case "regression", "regression_l1", "huber", "fair", "quantile", "mape":
+		e.Link = Identity
+	case "binary", "cross_entropy":
+		e.Link = Sigmoid
+	case "multiclass", "softmax":
+		e.Link = Softmax
+	case "poisson", "gamma", "tweedie":
+		e.Link = Exp
+	default:
+		return nil, fmt.Errorf("unsupported objective %q", e.Objective)
+	}
+	for _, param := range fields[1:] {
+		if value, ok := strings.CutPrefix(param, "sigmoid:"); ok {
+			s, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("objective %q: %v", header["objective"], err)
+			}
+			e.Scale = s
+		}
+	}
+	return e, nil
+}
+
+// lgbNodes converts a tree block into nodes: the internal nodes first, in
+// the order of the file, then the leaves. Children are stored as internal
+// node indices, or as the bitwise complement of leaf indices.
+func lgbNodes(block map[string]string) ([]Node, error) {
+	numLeaves := atoi(block["num_leaves"])
+	if numLeaves < 1 {
+		return nil, fmt.Errorf("num_leaves = %q", block["num_leaves"])
+	}
+	leafValues, err := floats(block["leaf_value"], numLeaves)
+	if err != nil {
+		return nil, fmt.Errorf("leaf_value: %v", err)
+	}
+	numInternal := numLeaves - 1
+	nodes := make([]Node, numInternal+numLeaves)
+	for k, v := range leafValues {
+		nodes[numInternal+k] = Node{Feature: -1, Value: v}
+	}
+	if numInternal == 0 {
+		return nodes[numInternal:], nil
+	}
+	features, err := ints(block["split_feature"], numInternal)
+	if err != nil {
+		return nil, fmt.Errorf("split_feature: %v", err)
+	}
+	thresholds, err := floats(block["threshold"], numInternal)
+	if err != nil {
+		return nil, fmt.Errorf("threshold: %v", err)
+	}
+	decisions, err := ints(block["decision_type"], numInternal)
+	if err != nil {
+		return nil, fmt.Errorf("decision_type: %v", err)
+	}
+	left, err := ints(block["left_child"], numInternal)
+	if err != nil {
+		return nil, fmt.Errorf("left_child: %v", err)
+	}
+	right, err := ints(block["right_child"], numInternal)
+	if err != nil {
+		return nil, fmt.Errorf("right_child: %v", err)
+	}
+	child := func(c int) int {
+		if c < 0 {
+			return numInternal + ^c
+		}
+		return c
+	}
+	for j := 0; j < numInternal; j++ {
+		if decisions[j]&lgbCategorical != 0 {
+			return nil, ErrCategorical
+		}
+		if features[j] < 0 {
+			return nil, fmt.Errorf("node %d splits on feature %d", j, features[j])
+		}
+		missing := MissingNone
+		switch (decisions[j] >> lgbMissingShift) & 3 {
+		case 1:
+			missing = MissingZero
+		case 2:
+			missing = MissingNaN
+		}
+		nodes[j] = Node{
+			Feature:     features[j],
+			Threshold:   thresholds[j],
+			Left:        child(left[j]),
+			Right:       child(right[j]),
+			DefaultLeft: decisions[j]&lgbDefaultLeft != 0,
+			Missing:     missing,
+		}
+	}
+	return nodes, nil
+}
+
+// atoi parses an integer, returning 0 when s is not one.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+// floats parses n space separated numbers.
+func floats(s string, n int) ([]float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) != n {
+		return nil, fmt.Errorf("%d values, want %d", len(fields), n)
+	}
+	values := make([]float64, n)
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// ints parses n space separated integers.
+func ints(s string, n int) ([]int, error) {
+	fields := strings.Fields(s)
+	if len(fields) != n {
+		return nil, fmt.Errorf("%d values, want %d", len(fields), n)
+	}
+	values := make([]int, n)
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
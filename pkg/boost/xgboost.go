@@ -0,0 +1,158 @@
+package boost
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// xgbModel is the part of an XGBoost JSON model file read by ReadXGBoost.
+type xgbModel struct {
+	Learner struct {
+		FeatureNames    []string `json:"feature_names"`
+		GradientBooster struct {
+			Name  string `json:"name"`
+			Model struct {
+				TreeInfo []int     `json:"tree_info"`
+				Trees    []xgbTree `json:"trees"`
+			} `json:"model"`
+		} `json:"gradient_booster"`
+		LearnerModelParam struct {
+			BaseScore  string `json:"base_score"`
+			NumClass   string `json:"num_class"`
+			NumFeature string `json:"num_feature"`
+		} `json:"learner_model_param"`
+		Objective struct {
+			Name string `json:"name"`
+		} `json:"objective"`
+	} `json:"learner"`
+}
+
+// xgbTree is a tree of an XGBoost JSON model, as parallel arrays indexed
+// by node.
+type xgbTree struct {
+	LeftChildren    []int     `json:"left_children"`
+	RightChildren   []int     `json:"right_children"`
+	SplitIndices    []int     `json:"split_indices"`
+	SplitConditions []float64 `json:"split_conditions"`
+	DefaultLeft     []flag    `json:"default_left"`
+	SplitType       []int     `json:"split_type"`
+}
+
+// flag is a boolean stored either as a JSON boolean or as 0 or 1, as
+// the versions of XGBoost differ.
+type flag bool
+
+func (f *flag) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*f = true
+	case "false", "0":
+		*f = false
+	default:
+		return fmt.Errorf("invalid flag %s", data)
+	}
+	return nil
+}
+
+// ReadXGBoost reads a model saved by XGBoost in JSON, with
+// Booster.save_model("model.json"). The gbtree booster with numerical splits
+// and the regression, logistic, Poisson, gamma, Tweedie and multiclass
+// objectives are supported.
+func ReadXGBoost(path string) (*Ensemble, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m xgbModel
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("boost: %s: %v", path, err)
+	}
+	l := m.Learner
+	if name := l.GradientBooster.Name; name != "gbtree" {
+		return nil, fmt.Errorf("boost: %s: unsupported booster %q", path, name)
+	}
+	e := &Ensemble{Source: "xgboost", Objective: l.Objective.Name, Scale: 1, TreeScale: 1, FeatureNames: l.FeatureNames, strictLess: true}
+	// Read the model parameters, stored as strings.
+	e.NumFeatures, err = strconv.Atoi(l.LearnerModelParam.NumFeature)
+	if err != nil {
+		return nil, fmt.Errorf("boost: %s: num_feature: %v", path, err)
+	}
+	numClass, err := strconv.Atoi(l.LearnerModelParam.NumClass)
+	if err != nil {
+		return nil, fmt.Errorf("boost: %s: num_class: %v", path, err)
+	}
+	e.NumClasses = max(numClass, 1)
+	// Newer versions store the base score as a one-element list.
+	baseScore, err := strconv.ParseFloat(strings.Trim(l.LearnerModelParam.BaseScore, "[]"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("boost: %s: base_score: %v", path, err)
+	}
+	// The base score is stored in the space of the predictions; map it
+	// back to a margin.
+	switch e.Objective {
+	case "reg:squarederror", "reg:linear", "reg:pseudohubererror", "reg:absoluteerror", "binary:logitraw":
+		e.Link, e.BaseMargin = Identity, baseScore
+	case "binary:logistic", "reg:logistic":
+		e.Link, e.BaseMargin = Sigmoid, math.Log(baseScore/(1-baseScore))
+	case "count:poisson", "reg:gamma", "reg:tweedie":
+		e.Link, e.BaseMargin = Exp, math.Log(baseScore)
+	case "multi:softprob", "multi:softmax":
+		e.Link, e.BaseMargin = Softmax, baseScore
+	default:
+		return nil, fmt.Errorf("boost: %s: unsupported objective %q", path, e.Objective)
+	}
+	// Convert the trees.
+	trees := l.GradientBooster.Model.Trees
+	info := l.GradientBooster.Model.TreeInfo
+	if len(info) != len(trees) {
+		return nil, fmt.Errorf("boost: %s: tree_info has %d entries for %d trees", path, len(info), len(trees))
+	}
+	e.Trees = make([]Tree, len(trees))
+	for i, xt := range trees {
+		e.Trees[i].Class = info[i]
+		nodes, err := xt.nodes()
+		if err != nil {
+			return nil, fmt.Errorf("boost: %s: tree %d: %w", path, i, err)
+		}
+		e.Trees[i].Nodes = nodes
+	}
+	if err := e.validate(); err != nil {
+		return nil, fmt.Errorf("%v in %s", err, path)
+	}
+	return e, nil
+}
+
+// nodes converts the arrays of the tree into nodes. The split
+// conditions of leaves hold their outputs.
+func (xt *xgbTree) nodes() ([]Node, error) {
+	n := len(xt.LeftChildren)
+	if len(xt.RightChildren) != n || len(xt.SplitIndices) != n || len(xt.SplitConditions) != n || len(xt.DefaultLeft) != n {
+		return nil, fmt.Errorf("node arrays differ in length")
+	}
+	nodes := make([]Node, n)
+	for j := range nodes {
+		if xt.LeftChildren[j] < 0 {
+			nodes[j] = Node{Feature: -1, Value: xt.SplitConditions[j]}
+			continue
+		}
+		if j < len(xt.SplitType) && xt.SplitType[j] != 0 {
+			return nil, ErrCategorical
+		}
+		if xt.SplitIndices[j] < 0 {
+			return nil, fmt.Errorf("node %d splits on feature %d", j, xt.SplitIndices[j])
+		}
+		nodes[j] = Node{
+			Feature:     xt.SplitIndices[j],
+			Threshold:   xt.SplitConditions[j],
+			Left:        xt.LeftChildren[j],
+			Right:       xt.RightChildren[j],
+			DefaultLeft: bool(xt.DefaultLeft[j]),
+			Missing:     MissingNaN,
+		}
+	}
+	return nodes, nil
+}
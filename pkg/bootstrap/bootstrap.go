@@ -0,0 +1,157 @@
+// Package bootstrap fits ensembles of a model on bootstrap resamples of
+// the training rows. The spread of the predictions of the replicas
+// estimates the uncertainty of the model, and the replicas that left a
+// row out of their resample give it an out-of-bag prediction, without a
+// separate holdout. Any model.Estimator can be resampled; the replicas are
+// fitted in parallel, each from its own random stream, so the ensemble
+// does not depend on the number of workers.
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// NewFunc returns an unfitted estimator of a replica, which draws any
+// random numbers it needs from r, the random stream of the replica.
+type NewFunc func(r *rand.Rand) model.Estimator
+
+// Ensemble holds estimators fitted on bootstrap resamples of the same
+// rows. The replicas of classifiers implementing model.Prober predict
+// the probability of class 1, and the others their Predict value.
+type Ensemble struct {
+	Replicas []model.Estimator
+	// InBag records, for every replica, which training rows were drawn
+	// into its resample.
+	InBag [][]bool
+}
+
+// replica is a fitted replica of the ensemble along with its resample.
+type replica struct {
+	estimator model.Estimator
+	inBag     []bool
+	err       error
+}
+
+// Fit fits numReplicas estimators returned by newEstimator, each on a
+// resample of the rows of x and their targets y drawn with replacement.
+// The replicas are fitted on the given number of workers (see
+// parallel.Workers), each drawing its resample and then its fit from its
+// own random stream derived from seed. It returns the first error of the
+// replicas, and stops with the error of ctx once ctx is cancelled.
+func Fit(ctx context.Context, newEstimator NewFunc, x *mat64.Dense, y []float64, numReplicas int, seed uint64, workers int) (*Ensemble, error) {
+	numRows, numCols := x.Dims()
+	if numRows != len(y) {
+		return nil, fmt.Errorf("bootstrap: %d rows and %d targets", numRows, len(y))
+	}
+	if numRows == 0 {
+		return nil, errors.New("bootstrap: no rows")
+	}
+	if numReplicas < 2 {
+		return nil, fmt.Errorf("bootstrap: %d replicas, need at least 2 for a variance", numReplicas)
+	}
+	replicas := parallel.Map(numReplicas, workers, func(b int) replica {
+		if err := ctx.Err(); err != nil {
+			return replica{err: err}
+		}
+		r := rand.New(rand.NewSource(parallel.Seed(seed, b)))
+		// Draw the resampled rows.
+		sampleData := make([]float64, 0, numRows*numCols)
+		sampleY := make([]float64, numRows)
+		inBag := make([]bool, numRows)
+		for i := range sampleY {
+			idx := r.Intn(numRows)
+			inBag[idx] = true
+			sampleData = append(sampleData, x.RawRowView(idx)...)
+			sampleY[i] = y[idx]
+		}
+		// Fit the replica on the resample.
+		est := newEstimator(r)
+		err := est.Fit(ctx, mat64.NewDense(numRows, numCols, sampleData), sampleY)
+		return replica{estimator: est, inBag: inBag, err: err}
+	})
+	e := &Ensemble{}
+	for _, rep := range replicas {
+		if rep.err != nil {
+			return nil, rep.err
+		}
+		e.Replicas = append(e.Replicas, rep.estimator)
+		e.InBag = append(e.InBag, rep.inBag)
+	}
+	return e, nil
+}
+
+// score returns the probability of class 1 of the row if the estimator
+// predicts one, and its prediction otherwise.
+func score(est model.Estimator, row []float64) (float64, error) {
+	if p, ok := est.(model.Prober); ok {
+		return p.Probability(row)
+	}
+	return est.Predict(row)
+}
+
+// Predict returns the mean and the unbiased variance of the predictions
+// of the replicas for the row.
+func (e *Ensemble) Predict(row []float64) (mean, variance float64, err error) {
+	preds := make([]float64, len(e.Replicas))
+	for b, est := range e.Replicas {
+		if preds[b], err = score(est, row); err != nil {
+			return 0, 0, err
+		}
+		mean += preds[b]
+	}
+	mean /= float64(len(preds))
+	for _, p := range preds {
+		variance += (p - mean) * (p - mean)
+	}
+	variance /= float64(len(preds) - 1)
+	return mean, variance, nil
+}
+
+// Mean returns the mean prediction of the replicas for the row.
+func (e *Ensemble) Mean(row []float64) (float64, error) {
+	mean, _, err := e.Predict(row)
+	return mean, err
+}
+
+// OOB returns the out-of-bag prediction of every training row of x, the
+// mean over the replicas whose resample left the row out, along with the
+// number of those replicas. Rows drawn by every replica get a NaN
+// prediction.
+func (e *Ensemble) OOB(x *mat64.Dense) (preds []float64, counts []int, err error) {
+	numRows, _ := x.Dims()
+	for b := range e.InBag {
+		if len(e.InBag[b]) != numRows {
+			return nil, nil, fmt.Errorf("bootstrap: %d rows, the ensemble was fitted on %d", numRows, len(e.InBag[b]))
+		}
+	}
+	preds = make([]float64, numRows)
+	counts = make([]int, numRows)
+	for i := 0; i < numRows; i++ {
+		row := x.RawRowView(i)
+		for b, est := range e.Replicas {
+			if e.InBag[b][i] {
+				continue
+			}
+			p, err := score(est, row)
+			if err != nil {
+				return nil, nil, err
+			}
+			preds[i] += p
+			counts[i]++
+		}
+		if counts[i] == 0 {
+			preds[i] = math.NaN()
+			continue
+		}
+		preds[i] /= float64(counts[i])
+	}
+	return preds, counts, nil
+}
@@ -0,0 +1,228 @@
+// Package compare compares two models, a champion in use and a
+// challenger meant to replace it, on the predictions they make for the
+// same rows. Because both score the same rows, the tests are paired: the
+// difference of every metric gets a bootstrap confidence interval from
+// resampling the rows, and the accuracies of classifiers are compared by
+// McNemar's test on the rows exactly one of the models gets right.
+package compare
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/segment"
+)
+
+// ErrLengths is returned when the observed values and the predictions of
+// the models differ in number or are empty.
+var ErrLengths = errors.New("compare: observed values and predictions must be non-empty and of the same length")
+
+func check(observed, champion, challenger []float64) error {
+	if len(observed) == 0 || len(champion) != len(observed) || len(challenger) != len(observed) {
+		return ErrLengths
+	}
+	return nil
+}
+
+// Bootstrap configures the resampling of the rows.
+type Bootstrap struct {
+	// Replicas is the number of resamples (0 uses 1000).
+	Replicas int
+	// Level is the coverage of the confidence interval (0 uses 0.95).
+	Level float64
+	// Seed seeds the resamples.
+	Seed int64
+}
+
+// Delta is the difference of a metric between the models.
+type Delta struct {
+	Metric string
+	// HigherIsBetter is copied from the metric.
+	HigherIsBetter bool
+	// Champion and Challenger are the metric of the models on every row.
+	Champion, Challenger float64
+	// Delta is Challenger - Champion.
+	Delta float64
+	// Low and High bound the confidence interval of Delta.
+	Low, High float64
+	// P is the two-sided bootstrap p-value of Delta being 0: twice the
+	// share of the resamples whose delta falls on the other side of 0.
+	P float64
+}
+
+// Better reports whether the challenger scores better than the champion,
+// with the interval of the delta excluding 0.
+func (d Delta) Better() bool {
+	if d.HigherIsBetter {
+		return d.Low > 0
+	}
+	return d.High < 0
+}
+
+// Worse reports whether the challenger scores worse than the champion,
+// with the interval of the delta excluding 0.
+func (d Delta) Worse() bool {
+	if d.HigherIsBetter {
+		return d.High < 0
+	}
+	return d.Low > 0
+}
+
+// BootstrapDelta returns the delta of the metric between the predictions
+// of the models, with its interval from resampling the rows.
+func BootstrapDelta(observed, champion, challenger []float64, m segment.Metric, cfg Bootstrap) (Delta, error) {
+	if err := check(observed, champion, challenger); err != nil {
+		return Delta{}, err
+	}
+	d := Delta{
+		Metric:         m.Name,
+		HigherIsBetter: m.HigherIsBetter,
+		Champion:       m.Score(observed, champion),
+		Challenger:     m.Score(observed, challenger),
+	}
+	d.Delta = d.Challenger - d.Champion
+	replicas, level := cfg.Replicas, cfg.Level
+	if replicas <= 0 {
+		replicas = 1000
+	}
+	if level <= 0 {
+		level = 0.95
+	}
+	r := rand.New(rand.NewSource(cfg.Seed))
+	n := len(observed)
+	obs, a, b := make([]float64, n), make([]float64, n), make([]float64, n)
+	deltas := make([]float64, replicas)
+	var opposite int
+	for k := range deltas {
+		for i := range obs {
+			j := r.Intn(n)
+			obs[i], a[i], b[i] = observed[j], champion[j], challenger[j]
+		}
+		deltas[k] = m.Score(obs, b) - m.Score(obs, a)
+		if deltas[k]*d.Delta <= 0 {
+			opposite++
+		}
+	}
+	sort.Float64s(deltas)
+	d.Low = quantile(deltas, (1-level)/2)
+	d.High = quantile(deltas, (1+level)/2)
+	d.P = math.Min(1, 2*float64(opposite)/float64(replicas))
+	return d, nil
+}
+
+// quantile returns the q-quantile of the sorted values, interpolating
+// between the closest ranks.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (pos-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// McNemarTest is the outcome of McNemar's test.
+type McNemarTest struct {
+	// ChampionOnly and ChallengerOnly count the rows only that model
+	// predicts correctly. The rows both or neither predict correctly say
+	// nothing about which is better.
+	ChampionOnly, ChallengerOnly int
+	// P is the exact two-sided p-value of the models being equally
+	// accurate, from the binomial distribution of the rows only one
+	// model predicts correctly.
+	P float64
+}
+
+// McNemar tests whether two classifiers are equally accurate on the
+// rows, given the observed and the predicted classes.
+func McNemar(observed, champion, challenger []float64) (McNemarTest, error) {
+	if err := check(observed, champion, challenger); err != nil {
+		return McNemarTest{}, err
+	}
+	var t McNemarTest
+	for i, o := range observed {
+		switch a, b := champion[i] == o, challenger[i] == o; {
+		case a && !b:
+			t.ChampionOnly++
+		case b && !a:
+			t.ChallengerOnly++
+		}
+	}
+	n := t.ChampionOnly + t.ChallengerOnly
+	k := min(t.ChampionOnly, t.ChallengerOnly)
+	// Sum the binomial probabilities of k or fewer successes out of n
+	// fair trials, in log space to stay finite for large n.
+	var tail float64
+	for i := 0; i <= k; i++ {
+		tail += math.Exp(logChoose(n, i) - float64(n)*math.Ln2)
+	}
+	t.P = math.Min(1, 2*tail)
+	return t, nil
+}
+
+// logChoose returns the log of n choose k.
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// Disagreement describes the rows the models predict differently.
+type Disagreement struct {
+	// Rows is the number of rows, and Disagree the number the models
+	// predict differently.
+	Rows, Disagree int
+	// ChampionRight, ChallengerRight and BothWrong split the rows of
+	// classifiers predicted differently by which model, if any, predicts
+	// the observed class.
+	ChampionRight, ChallengerRight, BothWrong int
+	// MeanAbsDiff is the mean absolute difference of the predictions of
+	// regressions.
+	MeanAbsDiff float64
+}
+
+// Rate returns the share of the rows the models predict differently.
+func (d Disagreement) Rate() float64 {
+	return float64(d.Disagree) / float64(d.Rows)
+}
+
+// Classes returns the disagreement of two classifiers.
+func Classes(observed, champion, challenger []float64) (Disagreement, error) {
+	if err := check(observed, champion, challenger); err != nil {
+		return Disagreement{}, err
+	}
+	d := Disagreement{Rows: len(observed)}
+	for i, o := range observed {
+		if champion[i] == challenger[i] {
+			continue
+		}
+		d.Disagree++
+		switch o {
+		case champion[i]:
+			d.ChampionRight++
+		case challenger[i]:
+			d.ChallengerRight++
+		default:
+			d.BothWrong++
+		}
+	}
+	return d, nil
+}
+
+// Values returns the disagreement of two regressions, counting the rows
+// whose predictions differ by more than tolerance.
+func Values(champion, challenger []float64, tolerance float64) (Disagreement, error) {
+	if len(champion) == 0 || len(challenger) != len(champion) {
+		return Disagreement{}, ErrLengths
+	}
+	d := Disagreement{Rows: len(champion)}
+	for i, a := range champion {
+		diff := math.Abs(challenger[i] - a)
+		d.MeanAbsDiff += diff / float64(len(champion))
+		if diff > tolerance {
+			d.Disagree++
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,154 @@
+// Package conformal wraps any model in split conformal prediction. The
+// model is fitted on part of the training rows, and its nonconformity
+// scores on the held out calibration rows size a prediction interval, or
+// pick the classes of a prediction set, that holds the observed target of
+// a new row with probability at least 1 - alpha, whatever the model, as
+// long as the rows are exchangeable. The calibration rows must not be
+// those the model was fitted on, or the guarantee does not hold. The model
+// is only called through a PredictFunc or a ProbaFunc, so it can be of any
+// package.
+package conformal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// PredictFunc returns the prediction of a model for a row of features: a
+// value for regressions, and the probability of class 1 for binary
+// classifiers, which Binary turns into a ProbaFunc.
+type PredictFunc func(row []float64) (float64, error)
+
+// Quantile returns the ceil((n+1)(1-alpha))-th smallest of the n
+// nonconformity scores, the threshold that gives split conformal
+// prediction its coverage guarantee, or +Inf when there are too few
+// scores for it.
+func Quantile(scores []float64, alpha float64) float64 {
+	sorted := append([]float64(nil), scores...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(float64(len(sorted)+1) * (1 - alpha)))
+	if rank > len(sorted) {
+		return math.Inf(1)
+	}
+	return sorted[rank-1]
+}
+
+// check returns an error unless alpha is a miscoverage rate and there is
+// a target for every calibration row.
+func check(numRows, numTargets int, alpha float64) error {
+	if !(alpha > 0 && alpha < 1) {
+		return fmt.Errorf("conformal: alpha %g is not between 0 and 1", alpha)
+	}
+	if numRows != numTargets {
+		return fmt.Errorf("conformal: %d calibration rows and %d targets", numRows, numTargets)
+	}
+	if numRows == 0 {
+		return errors.New("conformal: no calibration rows")
+	}
+	return nil
+}
+
+// Regressor wraps a regression with the half width of its prediction
+// intervals.
+type Regressor struct {
+	Predict PredictFunc
+	// Width is the half width of the intervals, +Inf when there were too
+	// few calibration rows for alpha.
+	Width float64
+}
+
+// CalibrateRegressor returns the regressor of predict whose interval half
+// width is the quantile of the absolute residuals of the calibration rows
+// of x and their targets y.
+func CalibrateRegressor(predict PredictFunc, x mat64.Matrix, y []float64, alpha float64) (*Regressor, error) {
+	numRows, _ := x.Dims()
+	if err := check(numRows, len(y), alpha); err != nil {
+		return nil, err
+	}
+	scores := make([]float64, numRows)
+	for i := range scores {
+		yPredicted, err := predict(mat64.Row(nil, i, x))
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = math.Abs(y[i] - yPredicted)
+	}
+	return &Regressor{Predict: predict, Width: Quantile(scores, alpha)}, nil
+}
+
+// Interval returns the lower and upper bound of the prediction interval
+// of the row.
+func (c *Regressor) Interval(row []float64) (lower, upper float64, err error) {
+	yPredicted, err := c.Predict(row)
+	if err != nil {
+		return 0, 0, err
+	}
+	return yPredicted - c.Width, yPredicted + c.Width, nil
+}
+
+// ProbaFunc returns the probability of every class of a classifier for a
+// row of features, indexed by class.
+type ProbaFunc func(row []float64) ([]float64, error)
+
+// Binary returns the ProbaFunc of a binary classifier whose probability
+// returns the probability of class 1.
+func Binary(probability PredictFunc) ProbaFunc {
+	return func(row []float64) ([]float64, error) {
+		p, err := probability(row)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{1 - p, p}, nil
+	}
+}
+
+// Classifier wraps a classifier with the largest nonconformity score, one
+// minus the probability of a class, of the classes of its prediction
+// sets.
+type Classifier struct {
+	PredictProba ProbaFunc
+	Threshold    float64
+}
+
+// CalibrateClassifier returns the classifier of predictProba calibrated on
+// the held out rows of x and their labels, the indices of their classes,
+// each scored by one minus the probability of its label.
+func CalibrateClassifier(predictProba ProbaFunc, x mat64.Matrix, labels []float64, alpha float64) (*Classifier, error) {
+	numRows, _ := x.Dims()
+	if err := check(numRows, len(labels), alpha); err != nil {
+		return nil, err
+	}
+	scores := make([]float64, numRows)
+	for i, label := range labels {
+		probs, err := predictProba(mat64.Row(nil, i, x))
+		if err != nil {
+			return nil, err
+		}
+		class := int(label)
+		if float64(class) != label || class < 0 || class >= len(probs) {
+			return nil, fmt.Errorf("conformal: row %d: label %g is not a class index below %d", i+1, label, len(probs))
+		}
+		scores[i] = 1 - probs[class]
+	}
+	return &Classifier{PredictProba: predictProba, Threshold: Quantile(scores, alpha)}, nil
+}
+
+// Set returns the prediction set of the row: the index of every class, in
+// increasing order, whose score is within the threshold.
+func (c *Classifier) Set(row []float64) ([]float64, error) {
+	probs, err := c.PredictProba(row)
+	if err != nil {
+		return nil, err
+	}
+	var set []float64
+	for class, p := range probs {
+		if 1-p <= c.Threshold {
+			set = append(set, float64(class))
+		}
+	}
+	return set, nil
+}
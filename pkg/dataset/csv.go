@@ -0,0 +1,52 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+)
+
+// EachRecord streams the CSV file at path, with a header row. It calls
+// header, unless nil, with the header row, then record with the number
+// of every record below the header, starting at 1, and its fields. Only
+// one record is held in memory at a time, so files larger than the memory
+// can be read: record must copy the fields it keeps, as they are
+// overwritten by the next record. Every record must have as many fields
+// as the header.
+//
+// EachRecord returns the first error of reading the file or of the
+// callbacks.
+func EachRecord(path string, header func(names []string) error, record func(row int, fields []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	names, err := reader.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%s is empty", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if header != nil {
+		if err := header(names); err != nil {
+			return err
+		}
+	}
+	reader.ReuseRecord = true
+	for row := 1; ; row++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if err := record(row, fields); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package dataset holds a numeric feature matrix with its class labels and
+// converts it to and from golearn instances and ARFF files, so data
+// prepared for the gradient descent trainers can be fed to the golearn
+// examples without writing and re-parsing CSV files. It also streams CSV
+// files record by record, for files too large to read at once.
+package dataset
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/gonum/matrix/mat64"
+	"github.com/sjwhitworth/golearn/base"
+)
+
+// floatPrecision is the number of decimals kept when float attributes are
+// written out as text, such as in ARFF files.
+const floatPrecision = 6
+
+// Dataset is a numeric feature matrix with one class label per row.
+type Dataset struct {
+	// Names holds the name of every feature column.
+	Names []string
+	// ClassName is the name of the class column.
+	ClassName string
+	// Features holds one row per observation.
+	Features *mat64.Dense
+	// Labels holds the class of every row.
+	Labels []float64
+	// ClassValues, when set, names the classes: label i stands for
+	// ClassValues[i]. When empty, the labels are the class values.
+	ClassValues []string
+}
+
+// classString returns the class value of a label.
+func (d *Dataset) classString(label float64) string {
+	if len(d.ClassValues) > 0 {
+		return d.ClassValues[int(label)]
+	}
+	return strconv.FormatFloat(label, 'f', -1, 64)
+}
+
+// ToInstances converts the dataset into golearn instances with a float
+// attribute per feature and a categorical class attribute.
+func ToInstances(d *Dataset) (*base.DenseInstances, error) {
+	numRows, numCols := d.Features.Dims()
+	if len(d.Names) != numCols {
+		return nil, fmt.Errorf("dataset has %d feature names for %d columns", len(d.Names), numCols)
+	}
+	if len(d.Labels) != numRows {
+		return nil, fmt.Errorf("dataset has %d labels for %d rows", len(d.Labels), numRows)
+	}
+	inst := base.NewDenseInstances()
+	// Add the feature and class attributes.
+	specs := make([]base.AttributeSpec, numCols)
+	for j, name := range d.Names {
+		attr := base.NewFloatAttribute(name)
+		attr.Precision = floatPrecision
+		specs[j] = inst.AddAttribute(attr)
+	}
+	classAttr := base.NewCategoricalAttribute()
+	classAttr.SetName(d.ClassName)
+	// Declare the class values up front so that they keep their order.
+	for _, value := range d.ClassValues {
+		classAttr.GetSysValFromString(value)
+	}
+	classSpec := inst.AddAttribute(classAttr)
+	if err := inst.AddClassAttribute(classAttr); err != nil {
+		return nil, err
+	}
+	// Copy the rows.
+	if err := inst.Extend(numRows); err != nil {
+		return nil, err
+	}
+	for i := 0; i < numRows; i++ {
+		for j, spec := range specs {
+			inst.Set(spec, i, base.PackFloatToBytes(d.Features.At(i, j)))
+		}
+		inst.Set(classSpec, i, classAttr.GetSysValFromString(d.classString(d.Labels[i])))
+	}
+	return inst, nil
+}
+
+// FromInstances converts golearn instances with float features and a
+// single class attribute into a dataset. Numeric class values become the
+// labels; otherwise the labels index into ClassValues. The features are
+// sorted by name: golearn lists the attributes of parsed CSV files in an
+// order that changes from run to run, and models drawing features at
+// random, such as random forests, would not be reproducible otherwise.
+func FromInstances(grid base.FixedDataGrid) (*Dataset, error) {
+	classAttrs := grid.AllClassAttributes()
+	if len(classAttrs) != 1 {
+		return nil, fmt.Errorf("expected one class attribute, found %d", len(classAttrs))
+	}
+	attrs := base.NonClassAttributes(grid)
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].GetName() < attrs[j].GetName() })
+	d := &Dataset{ClassName: classAttrs[0].GetName()}
+	for _, attr := range attrs {
+		if _, ok := attr.(*base.FloatAttribute); !ok {
+			return nil, fmt.Errorf("attribute %s is not a float attribute", attr.GetName())
+		}
+		d.Names = append(d.Names, attr.GetName())
+	}
+	classSpec, err := grid.GetAttribute(classAttrs[0])
+	if err != nil {
+		return nil, err
+	}
+	// Read the class values first, to find out whether they are numeric.
+	_, numRows := grid.Size()
+	classStrings := make([]string, numRows)
+	numeric := true
+	for i := range classStrings {
+		classStrings[i] = classAttrs[0].GetStringFromSysVal(grid.Get(classSpec, i))
+		if _, err := strconv.ParseFloat(classStrings[i], 64); err != nil {
+			numeric = false
+		}
+	}
+	// Convert the class values to labels.
+	d.Labels = make([]float64, numRows)
+	index := make(map[string]int)
+	for i, s := range classStrings {
+		if numeric {
+			d.Labels[i], _ = strconv.ParseFloat(s, 64)
+			continue
+		}
+		idx, ok := index[s]
+		if !ok {
+			idx = len(d.ClassValues)
+			index[s] = idx
+			d.ClassValues = append(d.ClassValues, s)
+		}
+		d.Labels[i] = float64(idx)
+	}
+	// Copy the features.
+	data := make([]float64, 0, numRows*len(attrs))
+	err = grid.MapOverRows(base.ResolveAttributes(grid, attrs), func(row [][]byte, i int) (bool, error) {
+		for _, v := range row {
+			data = append(data, base.UnpackBytesToFloat(v))
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.Features = mat64.NewDense(numRows, len(attrs), data)
+	return d, nil
+}
+
+// WriteARFF writes the dataset to a dense ARFF file at path.
+func WriteARFF(d *Dataset, path, relation string) error {
+	inst, err := ToInstances(d)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Write the features followed by the class attribute.
+	attrs := append(base.NonClassAttributes(inst), inst.AllClassAttributes()...)
+	if err := base.SerializeInstancesToWriterDenseARFFWithAttributes(f, inst, attrs, relation); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadARFF reads a dense ARFF file into a dataset. The last attribute of
+// the file is the class.
+func ReadARFF(path string) (*Dataset, error) {
+	inst, err := base.ParseDenseARFFToInstances(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromInstances(inst)
+}
@@ -0,0 +1,55 @@
+package dataset
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"slices"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Fingerprint identifies the training rows of a model, so that a warm
+// start can tell whether it continues on the rows of the earlier fit.
+type Fingerprint struct {
+	// Rows and Features are the dimensions of the feature matrix.
+	Rows, Features int
+	// Names holds the name of every feature, when known.
+	Names []string
+	// Hash is the FNV-1a hash of the features, the labels and the
+	// weights, if any.
+	Hash uint64
+}
+
+// NewFingerprint returns the fingerprint of the rows of x with their
+// labels y, weighted by weights when not nil, and the feature names.
+func NewFingerprint(x mat64.Matrix, y, weights []float64, names []string) Fingerprint {
+	rows, features := x.Dims()
+	h := fnv.New64a()
+	var buf [8]byte
+	write := func(v float64) {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		h.Write(buf[:])
+	}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < features; j++ {
+			write(x.At(i, j))
+		}
+	}
+	for _, v := range y {
+		write(v)
+	}
+	// Mark the weights, so that unit weights differ from none.
+	if weights != nil {
+		h.Write([]byte{1})
+		for _, v := range weights {
+			write(v)
+		}
+	}
+	return Fingerprint{Rows: rows, Features: features, Names: slices.Clone(names), Hash: h.Sum64()}
+}
+
+// Equal reports whether the fingerprints are of the same rows.
+func (f Fingerprint) Equal(g Fingerprint) bool {
+	return f.Rows == g.Rows && f.Features == g.Features && f.Hash == g.Hash && slices.Equal(f.Names, g.Names)
+}
@@ -0,0 +1,76 @@
+package dataset
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// IrisURL is the UCI repository location of the iris data set.
+const IrisURL = "https://archive.ics.uci.edu/ml/machine-learning-databases/iris/iris.data"
+
+// client downloads the data sets. Its timeout bounds a whole download,
+// from connecting to reading the last byte, so that an unresponsive
+// server fails the download instead of hanging it.
+var client = &http.Client{Timeout: time.Minute}
+
+// irisHeader names the columns of the iris CSV file, which the UCI copy
+// lacks.
+const irisHeader = "sepal_length,sepal_width,petal_length,petal_width,species"
+
+// FetchIris downloads the iris data set from IrisURL into a CSV file with
+// a header row at path. Nothing is downloaded when path already exists.
+// The download fails after a minute without completing.
+func FetchIris(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Download the raw data.
+	resp, err := client.Get(IrisURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("dataset: downloading %s: %s", IrisURL, resp.Status)
+	}
+
+	// Write the header followed by every non-empty line, to a temporary
+	// file first so that a failed download leaves nothing behind.
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, irisHeader)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fmt.Fprintln(w, line)
+	}
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
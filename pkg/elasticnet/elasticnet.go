@@ -0,0 +1,374 @@
+// Package elasticnet fits lasso and elastic-net linear regressions along a
+// path of regularization strengths. The penalty of a fit is lambda times
+// L1Ratio·‖b‖₁ + (1 − L1Ratio)·‖b‖²/2, added to half the mean squared
+// error, as in glmnet. The path starts at the smallest lambda that sets
+// every coefficient to 0 and decreases geometrically. Each lambda is fitted
+// by coordinate descent starting from the coefficients of the previous
+// one, so the whole path costs little more than a single fit, and the
+// coefficients trace how features enter the model as the penalty relaxes.
+package elasticnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Config describes the path.
+type Config struct {
+	// L1Ratio mixes the penalties: 1 is the lasso, 0 ridge regression.
+	L1Ratio float64
+	// Lambdas, when set, are the regularization strengths of the path,
+	// from the largest. Otherwise NumLambdas strengths are spaced
+	// geometrically from the smallest one that zeroes every coefficient
+	// down to MinRatio times it.
+	Lambdas []float64
+	// NumLambdas is the number of strengths of the path, 100 by default.
+	NumLambdas int
+	// MinRatio is the smallest strength relative to the largest, 1e-3 by
+	// default.
+	MinRatio float64
+	// Tolerance stops the coordinate descent of a strength once no
+	// standardized coefficient moves by more than it in a pass over the
+	// features, 1e-7 by default.
+	Tolerance float64
+	// MaxPasses bounds the passes over the features of every strength,
+	// 1000 by default.
+	MaxPasses int
+}
+
+// withDefaults returns the configuration with its zero values replaced
+// by the defaults.
+func (c Config) withDefaults() Config {
+	if c.NumLambdas == 0 {
+		c.NumLambdas = 100
+	}
+	if c.MinRatio == 0 {
+		c.MinRatio = 1e-3
+	}
+	if c.Tolerance == 0 {
+		c.Tolerance = 1e-7
+	}
+	if c.MaxPasses == 0 {
+		c.MaxPasses = 1000
+	}
+	return c
+}
+
+// check returns an error when the configuration is invalid.
+func (c Config) check() error {
+	switch {
+	case c.L1Ratio < 0 || c.L1Ratio > 1:
+		return fmt.Errorf("elasticnet: L1 ratio %v is not in [0, 1]", c.L1Ratio)
+	case len(c.Lambdas) == 0 && c.NumLambdas < 1:
+		return fmt.Errorf("elasticnet: %d lambdas, want at least 1", c.NumLambdas)
+	case len(c.Lambdas) == 0 && (c.MinRatio <= 0 || c.MinRatio >= 1):
+		return fmt.Errorf("elasticnet: min ratio %v is not in (0, 1)", c.MinRatio)
+	case c.Tolerance <= 0:
+		return fmt.Errorf("elasticnet: tolerance %v is not positive", c.Tolerance)
+	case c.MaxPasses < 1:
+		return fmt.Errorf("elasticnet: %d passes, want at least 1", c.MaxPasses)
+	}
+	for i, lambda := range c.Lambdas {
+		if lambda < 0 || (i > 0 && lambda > c.Lambdas[i-1]) {
+			return errors.New("elasticnet: lambdas must be non-negative and decreasing")
+		}
+	}
+	return nil
+}
+
+// Path holds the fits of a regularization path.
+type Path struct {
+	// L1Ratio is the mix of the penalties.
+	L1Ratio float64
+	// Lambdas holds the regularization strengths, from the largest.
+	Lambdas []float64
+	// Mean and Scale standardize every feature as (x - Mean) / Scale.
+	// The scale of a constant feature is 0, and its coefficient is
+	// always 0.
+	Mean, Scale []float64
+	// YMean is the mean of the target, the intercept of the
+	// standardized fits.
+	YMean float64
+	// Coefficients holds the standardized coefficients of every
+	// strength, in the order of Lambdas. Being on the same scale, they
+	// can be compared across features.
+	Coefficients [][]float64
+	// Passes holds the number of passes of the coordinate descent of
+	// every strength.
+	Passes []int
+}
+
+// Fit fits the path on the rows of x and the targets y.
+func Fit(ctx context.Context, x mat64.Matrix, y []float64, cfg Config) (*Path, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	n, numFeatures := x.Dims()
+	if n != len(y) {
+		return nil, fmt.Errorf("elasticnet: %d rows and %d targets", n, len(y))
+	}
+	if n < 2 || numFeatures == 0 {
+		return nil, errors.New("elasticnet: want at least 2 rows and 1 feature")
+	}
+	p := &Path{L1Ratio: cfg.L1Ratio, Mean: make([]float64, numFeatures), Scale: make([]float64, numFeatures)}
+	// Standardize the columns, so the penalty treats the features alike,
+	// and center the target.
+	columns := make([][]float64, numFeatures)
+	for j := range columns {
+		columns[j] = make([]float64, n)
+		mat64.Col(columns[j], j, x)
+		p.Mean[j], p.Scale[j] = meanScale(columns[j])
+		for i := range columns[j] {
+			if p.Scale[j] == 0 {
+				columns[j][i] = 0
+			} else {
+				columns[j][i] = (columns[j][i] - p.Mean[j]) / p.Scale[j]
+			}
+		}
+	}
+	p.YMean, _ = meanScale(y)
+	residuals := make([]float64, n)
+	for i, v := range y {
+		residuals[i] = v - p.YMean
+	}
+	p.Lambdas = cfg.Lambdas
+	if p.Lambdas == nil {
+		p.Lambdas = grid(maxLambda(columns, residuals, cfg.L1Ratio), cfg.NumLambdas, cfg.MinRatio)
+	}
+	// Fit every strength starting from the coefficients of the previous
+	// one, keeping the residuals up to date as the coefficients move.
+	b := make([]float64, numFeatures)
+	for _, lambda := range p.Lambdas {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		passes := descend(columns, residuals, b, lambda, cfg)
+		p.Coefficients = append(p.Coefficients, append([]float64(nil), b...))
+		p.Passes = append(p.Passes, passes)
+	}
+	return p, nil
+}
+
+// descend runs coordinate descent on the coefficients b for the strength
+// lambda, and returns the number of passes over the features.
+func descend(columns [][]float64, residuals, b []float64, lambda float64, cfg Config) int {
+	n := float64(len(residuals))
+	l1 := lambda * cfg.L1Ratio
+	l2 := lambda * (1 - cfg.L1Ratio)
+	for pass := 1; ; pass++ {
+		var maxMove float64
+		for j, column := range columns {
+			// Columns are standardized, so their mean square is 1,
+			// or 0 for constant ones, which keep a 0 coefficient.
+			var rho float64
+			for i, v := range column {
+				rho += v * residuals[i]
+			}
+			if rho == 0 && b[j] == 0 {
+				continue
+			}
+			rho = rho/n + b[j]
+			next := softThreshold(rho, l1) / (1 + l2)
+			if move := next - b[j]; move != 0 {
+				for i, v := range column {
+					residuals[i] -= move * v
+				}
+				maxMove = math.Max(maxMove, math.Abs(move))
+				b[j] = next
+			}
+		}
+		if maxMove < cfg.Tolerance || pass == cfg.MaxPasses {
+			return pass
+		}
+	}
+}
+
+// softThreshold shrinks z towards 0 by gamma.
+func softThreshold(z, gamma float64) float64 {
+	switch {
+	case z > gamma:
+		return z - gamma
+	case z < -gamma:
+		return z + gamma
+	}
+	return 0
+}
+
+// maxLambda returns the smallest strength at which every coefficient is
+// 0. Ridge regression never zeroes them, so its path starts where a
+// 0.001 L1 ratio would, as glmnet does.
+func maxLambda(columns [][]float64, residuals []float64, l1Ratio float64) float64 {
+	var max float64
+	for _, column := range columns {
+		var dot float64
+		for i, v := range column {
+			dot += v * residuals[i]
+		}
+		max = math.Max(max, math.Abs(dot)/float64(len(residuals)))
+	}
+	return max / math.Max(l1Ratio, 1e-3)
+}
+
+// grid returns num strengths spaced geometrically from max down to
+// minRatio times it.
+func grid(max float64, num int, minRatio float64) []float64 {
+	if max == 0 {
+		max = 1
+	}
+	lambdas := make([]float64, num)
+	for i := range lambdas {
+		if num == 1 {
+			lambdas[i] = max
+			break
+		}
+		lambdas[i] = max * math.Pow(minRatio, float64(i)/float64(num-1))
+	}
+	return lambdas
+}
+
+// meanScale returns the mean of the values and their standard deviation,
+// taken over n rather than n - 1.
+func meanScale(values []float64) (mean, scale float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	for _, v := range values {
+		scale += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(scale / float64(len(values)))
+}
+
+// Raw returns the intercept and the coefficients of the i-th strength on
+// the scale of the raw features.
+func (p *Path) Raw(i int) (intercept float64, coefficients []float64) {
+	intercept = p.YMean
+	coefficients = make([]float64, len(p.Mean))
+	for j, b := range p.Coefficients[i] {
+		if p.Scale[j] == 0 {
+			continue
+		}
+		coefficients[j] = b / p.Scale[j]
+		intercept -= coefficients[j] * p.Mean[j]
+	}
+	return intercept, coefficients
+}
+
+// Model returns the linear regression of the i-th strength.
+func (p *Path) Model(i int, target string, features []string) *model.Linear {
+	intercept, coefficients := p.Raw(i)
+	return &model.Linear{Target: target, Features: features, Intercept: intercept, Coefficients: coefficients}
+}
+
+// NonZero returns the number of non-zero coefficients of the i-th
+// strength.
+func (p *Path) NonZero(i int) int {
+	var count int
+	for _, b := range p.Coefficients[i] {
+		if b != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Predict returns the prediction of the i-th strength for the row of raw
+// feature values.
+func (p *Path) Predict(i int, row []float64) float64 {
+	intercept, coefficients := p.Raw(i)
+	y := intercept
+	for j, v := range row {
+		y += coefficients[j] * v
+	}
+	return y
+}
+
+// Validation holds the cross-validated errors of a path.
+type Validation struct {
+	// MSE and SE are the mean over the folds of the mean squared error
+	// of every strength, and its standard error.
+	MSE, SE []float64
+	// Best is the strength with the smallest mean error, and OneSE the
+	// largest strength whose mean error is within one standard error of
+	// it, a sparser model that predicts nearly as well.
+	Best, OneSE int
+}
+
+// CrossValidate fits the path of the lambdas of path on the training rows
+// of every fold, on workers goroutines (see split.CrossValidate), and
+// scores every strength by its mean squared error on the test rows.
+func CrossValidate(ctx context.Context, x mat64.Matrix, y []float64, path *Path, folds []split.Fold, workers int, cfg Config) (*Validation, error) {
+	if len(folds) < 2 {
+		return nil, fmt.Errorf("elasticnet: %d folds, want at least 2", len(folds))
+	}
+	cfg.L1Ratio = path.L1Ratio
+	cfg.Lambdas = path.Lambdas
+	_, numFeatures := x.Dims()
+	errs, err := split.CrossValidate(ctx, folds, workers, 0, func(k int, fold split.Fold, _ *rand.Rand) ([]float64, error) {
+		train := rows(x, fold.Train)
+		trainY := make([]float64, len(fold.Train))
+		for i, idx := range fold.Train {
+			trainY[i] = y[idx]
+		}
+		p, err := Fit(ctx, train, trainY, cfg)
+		if err != nil {
+			return nil, err
+		}
+		mse := make([]float64, len(p.Lambdas))
+		row := make([]float64, numFeatures)
+		for s := range p.Lambdas {
+			for _, idx := range fold.Test {
+				mat64.Row(row, idx, x)
+				d := y[idx] - p.Predict(s, row)
+				mse[s] += d * d
+			}
+			mse[s] /= float64(len(fold.Test))
+		}
+		return mse, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	v := &Validation{MSE: make([]float64, len(path.Lambdas)), SE: make([]float64, len(path.Lambdas))}
+	k := float64(len(errs))
+	for s := range path.Lambdas {
+		for _, mse := range errs {
+			v.MSE[s] += mse[s] / k
+		}
+		var ss float64
+		for _, mse := range errs {
+			ss += (mse[s] - v.MSE[s]) * (mse[s] - v.MSE[s])
+		}
+		v.SE[s] = math.Sqrt(ss / (k - 1) / k)
+		if v.MSE[s] < v.MSE[v.Best] {
+			v.Best = s
+		}
+	}
+	v.OneSE = v.Best
+	for s := v.Best; s >= 0; s-- {
+		if v.MSE[s] <= v.MSE[v.Best]+v.SE[v.Best] {
+			v.OneSE = s
+		}
+	}
+	return v, nil
+}
+
+// rows returns the rows of x at the indices.
+func rows(x mat64.Matrix, indices []int) *mat64.Dense {
+	_, numFeatures := x.Dims()
+	out := mat64.NewDense(len(indices), numFeatures, nil)
+	row := make([]float64, numFeatures)
+	for i, idx := range indices {
+		mat64.Row(row, idx, x)
+		out.SetRow(i, row)
+	}
+	return out
+}
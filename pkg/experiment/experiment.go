@@ -0,0 +1,137 @@
+// Package experiment describes an experiment by a YAML file holding the
+// values of the command line flags of a program, keyed by flag name:
+//
+//	trees: 50
+//	max-features: 2
+//	repeats: 10
+//
+// Parse loads the file named by the -config flag and sets every flag it
+// names, unless the flag is also given on the command line, which takes
+// precedence. Write saves the resolved value of every flag in the same
+// format, so that the file written next to the outputs of a run
+// reproduces it. JSON files are read as well, JSON being a subset of
+// YAML.
+package experiment
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FlagName is the name of the flag naming the experiment file.
+	FlagName = "config"
+	// File is the name of the resolved experiment file written to the
+	// run directory.
+	File = "experiment.yaml"
+)
+
+// Load reads the experiment file at path into the values of the flags it
+// names. Lists are joined with commas, as the flags taking several values
+// expect.
+func Load(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("experiment: %s: %v", path, err)
+	}
+	values := make(map[string]string, len(raw))
+	for name, v := range raw {
+		switch v := v.(type) {
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[name] = strings.Join(items, ",")
+		case map[string]any:
+			return nil, fmt.Errorf("experiment: %s: %s holds a mapping, not a flag value", path, name)
+		case nil:
+			values[name] = ""
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// Apply sets the flags of fs to the values, except for the flags in set.
+// Values of flags that fs does not define are an error, so that typos do
+// not go unnoticed.
+func Apply(fs *flag.FlagSet, values map[string]string, set map[string]bool) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fs.Lookup(name) == nil || name == FlagName {
+			return fmt.Errorf("experiment: unknown flag %q", name)
+		}
+		if set[name] {
+			continue
+		}
+		if err := fs.Set(name, values[name]); err != nil {
+			return fmt.Errorf("experiment: %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Parse defines the -config flag on fs, parses the arguments and applies
+// the experiment file named by -config, if any. Flags given in the
+// arguments override the file.
+func Parse(fs *flag.FlagSet, args []string) error {
+	path := fs.String(FlagName, "", "YAML file of flag values describing the experiment; flags on the command line take precedence")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return nil
+	}
+	values, err := Load(*path)
+	if err != nil {
+		return err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return Apply(fs, values, set)
+}
+
+// Resolved returns the value of every flag of fs but -config. Flags whose
+// values implement flag.Getter, as those of the flag package do, keep
+// their types; the others are given as text, and left out when empty,
+// as optional flags are when unset.
+func Resolved(fs *flag.FlagSet) map[string]any {
+	values := make(map[string]any)
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == FlagName {
+			return
+		}
+		if g, ok := f.Value.(flag.Getter); ok {
+			values[f.Name] = g.Get()
+		} else if s := f.Value.String(); s != "" {
+			values[f.Name] = s
+		}
+	})
+	return values
+}
+
+// Write saves the resolved flags of fs to path as an experiment file.
+func Write(path string, fs *flag.FlagSet) error {
+	data, err := yaml.Marshal(Resolved(fs))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
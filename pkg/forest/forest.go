@@ -0,0 +1,282 @@
+// Package forest grows random forests of the CART trees of package tree,
+// for classification and regression. Every tree is fitted on a bootstrap
+// sample of the weighted rows and draws its split candidates from a
+// random subset of the features. The rows left out of the sample of a
+// tree give the out-of-bag score, an estimate of the test score without a
+// held-out set. Trees are grown in parallel, each from its own random
+// stream, so a forest depends on its seed only and not on the number of
+// workers. With WarmStart, a fitted forest grows further trees without
+// regrowing its own. Growing stops early when its context is cancelled.
+// Forests save to and load from JSON.
+package forest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+)
+
+// ErrNotFitted is returned when a forest is saved before Fit.
+var ErrNotFitted = errors.New("forest: forest is not fitted")
+
+// ErrWarmStart is returned when a warm start would keep trees grown on
+// other rows than those given to Fit.
+var ErrWarmStart = errors.New("forest: warm start on other rows than the earlier fit")
+
+// Forest is a random forest.
+type Forest struct {
+	Task tree.Task `json:"task"`
+	// NumTrees is the number of trees grown by Fit.
+	NumTrees int `json:"num_trees"`
+	// Params limits the growth of every tree. When MaxFeatures is 0, it
+	// is the square root of the number of features for classification
+	// and a third of them for regression.
+	Params tree.Params `json:"params"`
+	// Seed seeds the random streams of the trees.
+	Seed uint64 `json:"seed"`
+	// Workers is the number of trees grown in parallel (see
+	// parallel.Workers). It does not change the fitted forest.
+	Workers int `json:"-"`
+	// WarmStart makes Fit keep the trees of an earlier Fit on the same
+	// rows, up to NumTrees, and grow only the trees beyond them, so that a
+	// forest grows in steps, as when tuning the number of trees. Every
+	// tree draws from its own stream, so the forest is the one Fit would
+	// grow at once. Fit refuses to warm start on other rows, or on the
+	// trees of a loaded forest.
+	WarmStart bool `json:"-"`
+	// Names, when set, names the features, which the fingerprint checked
+	// by a warm start includes.
+	Names []string `json:"-"`
+	// NumClasses is the number of classes of a classification forest.
+	NumClasses int `json:"num_classes,omitempty"`
+	// Trees holds the fitted trees.
+	Trees []*tree.Tree `json:"trees"`
+	// Importances holds the mean of the feature importances of the trees.
+	Importances []float64 `json:"importances"`
+	// OOBScore is the accuracy, for classification, or the R², for
+	// regression, of the out-of-bag predictions.
+	OOBScore float64 `json:"oob_score"`
+	// OOBRows is the number of rows the out-of-bag score is computed on:
+	// the rows left out of the sample of at least one tree. The score is
+	// 0 when there are none.
+	OOBRows int `json:"oob_rows"`
+	// fingerprint identifies the rows of the last Fit.
+	fingerprint dataset.Fingerprint
+}
+
+// New returns an unfitted forest of numTrees trees.
+func New(task tree.Task, numTrees int, params tree.Params, seed uint64) *Forest {
+	return &Forest{Task: task, NumTrees: numTrees, Params: params, Seed: seed}
+}
+
+// maxFeatures returns the number of split candidates of the trees.
+func (f *Forest) maxFeatures(numFeatures int) int {
+	if f.Params.MaxFeatures > 0 {
+		return f.Params.MaxFeatures
+	}
+	if f.Task == tree.Classification {
+		return max(int(math.Sqrt(float64(numFeatures))), 1)
+	}
+	return max(numFeatures/3, 1)
+}
+
+// grown is a tree along with the bootstrap counts of the rows.
+type grown struct {
+	tree   *tree.Tree
+	counts []int
+	err    error
+}
+
+// Fit grows the trees on the rows of x and their labels y, weighted by
+// weights when not nil, and computes the out-of-bag score. The trees not
+// yet started when ctx is cancelled are skipped, and Fit returns the
+// error of ctx.
+func (f *Forest) Fit(ctx context.Context, x mat64.Matrix, y, weights []float64) error {
+	numRows, numFeatures := x.Dims()
+	if numRows == 0 || len(y) != numRows || (weights != nil && len(weights) != numRows) {
+		return tree.ErrLengths
+	}
+	if f.NumTrees < 1 {
+		return fmt.Errorf("forest: %d trees", f.NumTrees)
+	}
+	// Set the number of classes up front, so that every tree predicts
+	// probabilities over all classes whatever its sample.
+	if f.Task == tree.Classification {
+		f.NumClasses = 0
+		for _, label := range y {
+			f.NumClasses = max(f.NumClasses, int(label)+1)
+		}
+	}
+	params := f.Params
+	params.MaxFeatures = f.maxFeatures(numFeatures)
+	// Keep the trees of a warm start, along with their samples, which
+	// their streams draw again.
+	fingerprint := dataset.NewFingerprint(x, y, weights, f.Names)
+	var kept []grown
+	if f.WarmStart && len(f.Trees) > 0 && !f.fingerprint.Equal(fingerprint) {
+		return ErrWarmStart
+	}
+	if f.WarmStart {
+		for task := 0; task < min(len(f.Trees), f.NumTrees); task++ {
+			_, counts := f.bootstrap(task, numRows)
+			kept = append(kept, grown{tree: f.Trees[task], counts: counts})
+		}
+	}
+	trees := parallel.Map(f.NumTrees-len(kept), f.Workers, func(task int) grown {
+		if err := ctx.Err(); err != nil {
+			return grown{err: err}
+		}
+		r, counts := f.bootstrap(len(kept)+task, numRows)
+		sample := make([]float64, numRows)
+		for i, c := range counts {
+			sample[i] = float64(c)
+			if weights != nil {
+				sample[i] *= weights[i]
+			}
+		}
+		t := tree.New(f.Task, params)
+		t.NumClasses = f.NumClasses
+		return grown{tree: t, counts: counts, err: t.Fit(x, y, sample, r)}
+	})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	trees = append(kept, trees...)
+	f.Trees = make([]*tree.Tree, f.NumTrees)
+	f.Importances = make([]float64, numFeatures)
+	for i, g := range trees {
+		if g.err != nil {
+			return fmt.Errorf("forest: tree %d: %w", i, g.err)
+		}
+		f.Trees[i] = g.tree
+		for j, v := range g.tree.Importances {
+			f.Importances[j] += v / float64(f.NumTrees)
+		}
+	}
+	f.OOBScore, f.OOBRows = f.oobScore(x, y, trees)
+	f.fingerprint = fingerprint
+	return nil
+}
+
+// bootstrap returns the random stream of tree task after drawing its
+// bootstrap sample of the numRows rows, as a count per row, which
+// multiplies the weight of the row.
+func (f *Forest) bootstrap(task, numRows int) (*rand.Rand, []int) {
+	r := rand.New(rand.NewSource(int64(parallel.Seed(f.Seed, task))))
+	counts := make([]int, numRows)
+	for i := 0; i < numRows; i++ {
+		counts[r.Intn(numRows)]++
+	}
+	return r, counts
+}
+
+// oobScore scores the rows on the trees whose sample left them out.
+func (f *Forest) oobScore(x mat64.Matrix, y []float64, trees []grown) (float64, int) {
+	var observed, predicted []float64
+	numRows, _ := x.Dims()
+	for i := 0; i < numRows; i++ {
+		row := mat64.Row(nil, i, x)
+		var value []float64
+		var numTrees int
+		for _, g := range trees {
+			if g.counts[i] > 0 {
+				continue
+			}
+			value = addTo(value, g.tree.PredictProba(row))
+			numTrees++
+		}
+		if numTrees == 0 {
+			continue
+		}
+		observed = append(observed, y[i])
+		predicted = append(predicted, tree.Decide(f.Task, scale(value, numTrees)))
+	}
+	if len(observed) == 0 {
+		return 0, 0
+	}
+	if f.Task == tree.Regression {
+		scores, err := metrics.RegressionScores(observed, predicted, 0)
+		if err != nil {
+			return 0, 0
+		}
+		return scores.R2, len(observed)
+	}
+	var correct int
+	for i, o := range observed {
+		if predicted[i] == o {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(observed)), len(observed)
+}
+
+// addTo adds value to sum element-wise, allocating sum when nil.
+func addTo(sum, value []float64) []float64 {
+	if sum == nil {
+		sum = make([]float64, len(value))
+	}
+	for k, v := range value {
+		sum[k] += v
+	}
+	return sum
+}
+
+// scale divides the values by n in place.
+func scale(values []float64, n int) []float64 {
+	for k := range values {
+		values[k] /= float64(n)
+	}
+	return values
+}
+
+// PredictProba returns the mean of the class probabilities predicted by
+// the trees for classification, or the one-value mean prediction for
+// regression.
+func (f *Forest) PredictProba(row []float64) []float64 {
+	var value []float64
+	for _, t := range f.Trees {
+		value = addTo(value, t.PredictProba(row))
+	}
+	return scale(value, len(f.Trees))
+}
+
+// Predict returns the most probable class index of the row, or the
+// predicted value for regression.
+func (f *Forest) Predict(row []float64) float64 {
+	return tree.Decide(f.Task, f.PredictProba(row))
+}
+
+// Save writes the fitted forest to path as JSON.
+func (f *Forest) Save(path string) error {
+	if len(f.Trees) == 0 {
+		return ErrNotFitted
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Load reads a forest saved by Save.
+func Load(path string) (*Forest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Forest
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("forest: %s: %v", path, err)
+	}
+	return &f, nil
+}
@@ -0,0 +1,148 @@
+// Package golden records the predictions of a model on a fixed fixture
+// dataset and compares later predictions against them, so that a
+// refactoring of a trainer that silently changes its behavior is caught.
+// Golden files are CSV files with one "row,prediction" line per row,
+// written with full float precision.
+package golden
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxReported is the number of deviating rows listed in a Mismatch error.
+const maxReported = 5
+
+// Write records the predictions in the golden file at path, creating its
+// directory when needed.
+func Write(path string, predictions []float64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"row", "prediction"}); err != nil {
+		f.Close()
+		return err
+	}
+	for i, p := range predictions {
+		if err := w.Write([]string{strconv.Itoa(i), strconv.FormatFloat(p, 'g', -1, 64)}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns the predictions recorded in the golden file at path.
+func Read(path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("golden: %s has no header", path)
+	}
+	predictions := make([]float64, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if len(record) != 2 {
+			return nil, fmt.Errorf("golden: %s line %d: want 2 fields, got %d", path, i+2, len(record))
+		}
+		p, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("golden: %s line %d: %v", path, i+2, err)
+		}
+		predictions = append(predictions, p)
+	}
+	return predictions, nil
+}
+
+// Deviation is a row whose prediction differs from the golden one.
+type Deviation struct {
+	// Row is the index of the row.
+	Row int
+	// Want is the recorded prediction and Got the new one.
+	Want, Got float64
+}
+
+// Mismatch is returned by Verify when predictions deviate from the golden
+// file beyond the tolerance.
+type Mismatch struct {
+	// Path is the golden file.
+	Path string
+	// Tolerance is the largest absolute difference accepted.
+	Tolerance float64
+	// Deviations lists the rows beyond the tolerance.
+	Deviations []Deviation
+	// MaxDiff is the largest absolute difference found.
+	MaxDiff float64
+}
+
+func (m *Mismatch) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "golden: %d predictions deviate from %s by more than %g (max %g)",
+		len(m.Deviations), m.Path, m.Tolerance, m.MaxDiff)
+	for i, d := range m.Deviations {
+		if i == maxReported {
+			fmt.Fprintf(&b, "; ...")
+			break
+		}
+		fmt.Fprintf(&b, "; row %d: want %g, got %g", d.Row, d.Want, d.Got)
+	}
+	return b.String()
+}
+
+// ErrLength is returned by Verify when the number of predictions differs
+// from the golden file.
+var ErrLength = errors.New("golden: number of predictions differs")
+
+// Verify compares the predictions with the golden file at path. It
+// returns a *Mismatch error when any prediction differs from the recorded
+// one by more than tolerance; NaN predictions only match NaN.
+func Verify(path string, predictions []float64, tolerance float64) error {
+	want, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if len(want) != len(predictions) {
+		return fmt.Errorf("%w: %s has %d, got %d", ErrLength, path, len(want), len(predictions))
+	}
+	m := &Mismatch{Path: path, Tolerance: tolerance}
+	for i, got := range predictions {
+		if math.IsNaN(want[i]) && math.IsNaN(got) {
+			continue
+		}
+		diff := math.Abs(got - want[i])
+		if diff <= tolerance {
+			continue
+		}
+		if math.IsNaN(diff) {
+			diff = math.Inf(1)
+		}
+		m.Deviations = append(m.Deviations, Deviation{Row: i, Want: want[i], Got: got})
+		m.MaxDiff = math.Max(m.MaxDiff, diff)
+	}
+	if len(m.Deviations) > 0 {
+		return m
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package golden_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/golden"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// The fixture and golden predictions of the logistic regression example,
+// recorded with go run . -golden record.
+const (
+	loanFixture = "../../classification/logistic-regression/testdata/loan_fixture.csv"
+	loanGolden  = "../../classification/logistic-regression/testdata/golden_predictions.csv"
+	// loanSeed is the golden seed of the example.
+	loanSeed = 20240611
+)
+
+// readFixture returns the FICO column of the loan fixture with the
+// intercept column, and the rate class labels.
+func readFixture(t *testing.T) (*mat64.Dense, []float64) {
+	t.Helper()
+	var data, labels []float64
+	err := dataset.EachRecord(loanFixture, nil, func(row int, record []string) error {
+		fico, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			return err
+		}
+		label, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return err
+		}
+		data = append(data, fico, 1)
+		labels = append(labels, label)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mat64.NewDense(len(labels), 2, data), labels
+}
+
+// TestLoanFixture trains the logistic regression of the example with its
+// golden seed and default settings, and compares its predictions with
+// the recorded ones, as go run . -golden verify does.
+func TestLoanFixture(t *testing.T) {
+	x, y := readFixture(t)
+	opts := logistic.Options{
+		Steps:        100,
+		Schedule:     optim.Constant(0.3),
+		NewOptimizer: func() optim.Optimizer { return optim.SGD{} },
+		BatchSize:    1,
+		Threshold:    0.5,
+	}
+	weights, _, err := logistic.Fit(context.Background(), x, y, opts, rand.New(rand.NewSource(loanSeed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := golden.Verify(loanGolden, logistic.PredictProba(weights, x), 1e-9); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "golden.csv")
+	want := []float64{0.1, 1.0 / 3, -2e-300, math.NaN(), math.Inf(1)}
+	if err := golden.Write(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := golden.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d predictions, wrote %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] && !(math.IsNaN(got[i]) && math.IsNaN(want[i])) {
+			t.Errorf("prediction %d = %v, wrote %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.csv")
+	if err := golden.Write(path, []float64{0.25, 0.5, math.NaN()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := golden.Verify(path, []float64{0.25 + 1e-12, 0.5, math.NaN()}, 1e-9); err != nil {
+		t.Errorf("within the tolerance: %v", err)
+	}
+	err := golden.Verify(path, []float64{0.25, 0.6, 0.1}, 1e-9)
+	var m *golden.Mismatch
+	if !errors.As(err, &m) {
+		t.Fatalf("got %v, want a *Mismatch", err)
+	}
+	if len(m.Deviations) != 2 || m.Deviations[0].Row != 1 || m.Deviations[1].Row != 2 {
+		t.Errorf("deviations %+v, want rows 1 and 2", m.Deviations)
+	}
+	if !math.IsInf(m.MaxDiff, 1) {
+		t.Errorf("max difference %g, want +Inf for a NaN golden prediction", m.MaxDiff)
+	}
+	if err := golden.Verify(path, []float64{0.25, 0.5}, 1e-9); !errors.Is(err, golden.ErrLength) {
+		t.Errorf("got %v, want ErrLength", err)
+	}
+}
@@ -0,0 +1,196 @@
+package inference
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// CompactMagic starts every compact model file, written by package
+// model.WriteCompact.
+const CompactMagic = "GMLC"
+
+// CompactVersion is the latest version of the compact files
+// DecodeCompact reads.
+const CompactVersion = 1
+
+// Precisions of the weights of compact files.
+const (
+	compactFloat16 = 1
+	compactInt8    = 2
+)
+
+// Kinds of the models of compact files.
+const (
+	compactLogistic = 1
+	compactLinear   = 2
+)
+
+// Flags of the optional sections of compact files.
+const (
+	compactStandardized = 1 << iota
+	compactCalibrated
+)
+
+// ErrCompact is returned when decoding data that is not a compact model.
+var ErrCompact = errors.New("model: not a compact model")
+
+// errTruncated is returned when a compact model ends early.
+var errTruncated = errors.New("model: truncated compact model")
+
+// DecodeCompact returns the model of a compact model file: a *Logistic or
+// a *Linear. Unlike Decode, it uses no reflection, and it allocates the
+// names and weights of the model only, each bounded by the length of the
+// data, so that it suits TinyGo and microcontrollers.
+func DecodeCompact(data []byte) (Predictor, error) {
+	if len(data) < len(CompactMagic)+4 || string(data[:len(CompactMagic)]) != CompactMagic {
+		return nil, ErrCompact
+	}
+	head := data[len(CompactMagic):]
+	version, kind, precision, flags := head[0], head[1], head[2], head[3]
+	if version < 1 || version > CompactVersion {
+		return nil, errors.New("model: compact version " + strconv.Itoa(int(version)) + ", this program reads up to " + strconv.Itoa(CompactVersion))
+	}
+	if precision != compactFloat16 && precision != compactInt8 {
+		return nil, errors.New("model: compact model of unknown precision " + strconv.Itoa(int(precision)))
+	}
+	d := &compactDecoder{data: head[4:]}
+	target := d.string()
+	features := make([]string, d.length())
+	for j := range features {
+		features[j] = d.string()
+	}
+	var shift, scale []float64
+	if flags&compactStandardized != 0 {
+		shift = d.float32s(len(features))
+		scale = d.float32s(len(features))
+	}
+	weights := make([]float64, len(features), len(features)+1)
+	switch precision {
+	case compactFloat16:
+		for j := range weights {
+			weights[j] = Float16Value(d.uint16())
+		}
+	case compactInt8:
+		s := d.float32()
+		for j := range weights {
+			weights[j] = float64(int8(d.byte())) * s
+		}
+	}
+	intercept := d.float32()
+	var m Predictor
+	switch kind {
+	case compactLogistic:
+		lm := &Logistic{Target: target, Features: features, Shift: shift, Scale: scale, Weights: append(weights, intercept)}
+		lm.Threshold = d.float32()
+		if flags&compactCalibrated != 0 {
+			lm.Calibration = &Platt{A: d.float32(), B: d.float32()}
+		}
+		m = lm
+	case compactLinear:
+		m = &Linear{Target: target, Features: features, Intercept: intercept, Coefficients: weights}
+	default:
+		return nil, errors.New("model: compact model of unknown kind " + strconv.Itoa(int(kind)))
+	}
+	if d.truncated {
+		return nil, errTruncated
+	}
+	return m, nil
+}
+
+// Float16Value returns the value of the IEEE 754 half precision bits h.
+func Float16Value(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant != 0 {
+			return math.NaN()
+		}
+		return math.Inf(int(sign))
+	}
+	return sign * math.Ldexp(1+mant/1024, exp-15)
+}
+
+// compactDecoder decodes the little endian fields of a compact file from
+// its bytes. Once the data runs out, it is truncated and every field reads
+// as zero.
+type compactDecoder struct {
+	data      []byte
+	truncated bool
+}
+
+// next returns the next n bytes, or nil when fewer are left.
+func (d *compactDecoder) next(n int) []byte {
+	if d.truncated || n > len(d.data) {
+		d.truncated = true
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *compactDecoder) byte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *compactDecoder) uint16() uint16 {
+	if b := d.next(2); b != nil {
+		return uint16(b[0]) | uint16(b[1])<<8
+	}
+	return 0
+}
+
+func (d *compactDecoder) float32() float64 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return float64(math.Float32frombits(uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24))
+}
+
+func (d *compactDecoder) float32s(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = d.float32()
+	}
+	return values
+}
+
+// length returns a uvarint length. A length beyond the bytes left
+// truncates the data, so that a corrupt file cannot allocate much.
+func (d *compactDecoder) length() int {
+	var v uint64
+	for shift := uint(0); ; shift += 7 {
+		if shift > 63 {
+			d.truncated = true
+			return 0
+		}
+		b := d.byte()
+		if d.truncated {
+			return 0
+		}
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			break
+		}
+	}
+	if v > uint64(len(d.data)) {
+		d.truncated = true
+		return 0
+	}
+	return int(v)
+}
+
+func (d *compactDecoder) string() string {
+	return string(d.next(d.length()))
+}
@@ -0,0 +1,53 @@
+//go:build !tinygo
+
+package inference
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// Format identifies the model files of package model.
+	Format = "go-machine-learning/model"
+	// Version is the latest version of the model files Decode reads.
+	Version = 1
+)
+
+// ErrFormat is returned when decoding data that is not a model file.
+var ErrFormat = errors.New("model: not a model file")
+
+// file is the content of a model file, without the creation time.
+type file struct {
+	Format  string          `json:"format"`
+	Version int             `json:"version"`
+	Kind    string          `json:"kind"`
+	Model   json.RawMessage `json:"model"`
+}
+
+// Decode returns the model of the content of a model file: a *Logistic or
+// a *Linear. Encrypted or signed files must be opened by package model
+// first.
+func Decode(data []byte) (Predictor, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil || f.Format != Format {
+		return nil, ErrFormat
+	}
+	if f.Version < 1 || f.Version > Version {
+		return nil, fmt.Errorf("model: version %d, this program reads up to %d", f.Version, Version)
+	}
+	var m Predictor
+	switch f.Kind {
+	case KindLogistic:
+		m = &Logistic{}
+	case KindLinear:
+		m = &Linear{}
+	default:
+		return nil, fmt.Errorf("model: a %s model, inference reads logistic and linear ones", f.Kind)
+	}
+	if err := json.Unmarshal(f.Model, m); err != nil {
+		return nil, fmt.Errorf("model: %v", err)
+	}
+	return m, nil
+}
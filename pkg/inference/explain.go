@@ -0,0 +1,60 @@
+package inference
+
+import "errors"
+
+// Contributions returns how much every feature of the row of raw values
+// moves the log odds of the logistic regression away from those of the
+// baseline row: the weight of the feature times the distance of its value
+// from the baseline. The contributions sum to the difference of the log
+// odds of the row and of the baseline, before calibration. A nil baseline
+// stands for the training means of a standardized model, its Shift.
+func (m *Logistic) Contributions(row, baseline []float64) ([]float64, error) {
+	if err := check(m.Features, row); err != nil {
+		return nil, err
+	}
+	if len(m.Weights) != len(m.Features)+1 {
+		return nil, sizeError(len(m.Weights), "weights", len(m.Features))
+	}
+	if baseline == nil {
+		if m.Shift == nil {
+			return nil, errors.New("model: the logistic regression is not standardized, so its contributions need a baseline row")
+		}
+		baseline = m.Shift
+	}
+	if err := check(m.Features, baseline); err != nil {
+		return nil, err
+	}
+	contributions := make([]float64, len(row))
+	for j, x := range row {
+		w := m.Weights[j]
+		if m.Shift != nil {
+			w /= m.Scale[j]
+		}
+		contributions[j] = w * (x - baseline[j])
+	}
+	return contributions, nil
+}
+
+// Contributions returns how much every feature of the row moves the
+// prediction of the linear regression away from that of the baseline row:
+// the coefficient of the feature times the distance of its value from the
+// baseline. The contributions sum to the difference of the predictions.
+func (m *Linear) Contributions(row, baseline []float64) ([]float64, error) {
+	if err := check(m.Features, row); err != nil {
+		return nil, err
+	}
+	if baseline == nil {
+		return nil, errors.New("model: the contributions of a linear regression need a baseline row")
+	}
+	if err := check(m.Features, baseline); err != nil {
+		return nil, err
+	}
+	if len(m.Coefficients) != len(m.Features) {
+		return nil, sizeError(len(m.Coefficients), "coefficients", len(m.Features))
+	}
+	contributions := make([]float64, len(row))
+	for j, x := range row {
+		contributions[j] = m.Coefficients[j] * (x - baseline[j])
+	}
+	return contributions, nil
+}
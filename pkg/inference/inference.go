@@ -0,0 +1,149 @@
+// Package inference predicts with saved linear and logistic regressions.
+// It holds the prediction code of package model, which uses it, without
+// the dependencies of training: it imports the standard library only, so
+// that programs scoring rows, such as a browser demo compiled to
+// WebAssembly, stay small. Decode reads the model files written by
+// package model.Save, unencrypted and unsigned.
+//
+// Prediction uses no reflection and allocates nothing, so the package
+// also builds with TinyGo for microcontrollers. Decode, which needs
+// encoding/json, is left out of TinyGo builds; DecodeCompact reads the
+// compact files of model.WriteCompact instead.
+package inference
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// Kinds of the models of this package.
+const (
+	KindLogistic = "logistic"
+	KindLinear   = "linear"
+)
+
+// Predictor is a model that predicts from a row of feature values.
+type Predictor interface {
+	Predict(row []float64) (float64, error)
+}
+
+// Logistic is a logistic regression: the probability of class 1 is the
+// logistic function of the weighted sum of the features and an intercept.
+type Logistic struct {
+	// Target names the predicted variable, when known.
+	Target string `json:"target,omitempty"`
+	// Features names the features, in the order of the rows.
+	Features []string `json:"features"`
+	// Fill, when set, holds the values that replace the missing values,
+	// NaN, of the named features, before they are standardized.
+	Fill map[string]float64 `json:"fill,omitempty"`
+	// Shift and Scale, when set, standardize every feature as
+	// (x - Shift) / Scale before weighting it.
+	Shift []float64 `json:"shift,omitempty"`
+	Scale []float64 `json:"scale,omitempty"`
+	// Weights holds the weight of every feature followed by the
+	// intercept.
+	Weights []float64 `json:"weights"`
+	// Threshold is the probability from which rows are classified as 1.
+	Threshold float64 `json:"threshold"`
+	// Calibration, when set, maps the probabilities of the weights to
+	// calibrated ones, which Threshold then applies to.
+	Calibration *Platt `json:"calibration,omitempty"`
+}
+
+// Platt is a Platt scaling of probabilities: the calibrated probability
+// is the logistic function of A times the log odds of the probability
+// plus B.
+type Platt struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// Apply returns the calibrated probability of p.
+func (c *Platt) Apply(p float64) float64 {
+	return 1 / (1 + math.Exp(-(c.A*LogOdds(p) + c.B)))
+}
+
+// LogOdds returns the log odds of the probability p, clipped away from 0
+// and 1 to keep them finite.
+func LogOdds(p float64) float64 {
+	const eps = 1e-15
+	p = math.Min(math.Max(p, eps), 1-eps)
+	return math.Log(p / (1 - p))
+}
+
+// check returns an error when the row does not match the features.
+func check(features []string, row []float64) error {
+	if len(row) != len(features) {
+		return errors.New("model: row has " + strconv.Itoa(len(row)) + " features, the model " + strconv.Itoa(len(features)))
+	}
+	return nil
+}
+
+// sizeError returns the error of a model holding n of what, such as
+// weights, for its number of features.
+func sizeError(n int, what string, features int) error {
+	return errors.New("model: " + strconv.Itoa(n) + " " + what + " for " + strconv.Itoa(features) + " features")
+}
+
+// Probability returns the probability of class 1 of the row of raw
+// feature values, calibrated when the model has a calibration.
+func (m *Logistic) Probability(row []float64) (float64, error) {
+	if err := check(m.Features, row); err != nil {
+		return 0, err
+	}
+	if len(m.Weights) != len(m.Features)+1 {
+		return 0, sizeError(len(m.Weights), "weights", len(m.Features))
+	}
+	z := m.Weights[len(m.Features)]
+	for j, x := range row {
+		if fill, ok := m.Fill[m.Features[j]]; ok && math.IsNaN(x) {
+			x = fill
+		}
+		if m.Shift != nil {
+			x = (x - m.Shift[j]) / m.Scale[j]
+		}
+		z += m.Weights[j] * x
+	}
+	p := 1 / (1 + math.Exp(-z))
+	if m.Calibration != nil {
+		p = m.Calibration.Apply(p)
+	}
+	return p, nil
+}
+
+// Predict returns the class, 0 or 1, of the row of raw feature values.
+func (m *Logistic) Predict(row []float64) (float64, error) {
+	p, err := m.Probability(row)
+	if err != nil || p < m.Threshold {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// Linear is a linear regression: the prediction is the intercept plus the
+// weighted sum of the features.
+type Linear struct {
+	// Target names the predicted variable.
+	Target string `json:"target"`
+	// Features names the features, in the order of the rows.
+	Features     []string  `json:"features"`
+	Intercept    float64   `json:"intercept"`
+	Coefficients []float64 `json:"coefficients"`
+}
+
+// Predict returns the prediction of the row.
+func (m *Linear) Predict(row []float64) (float64, error) {
+	if err := check(m.Features, row); err != nil {
+		return 0, err
+	}
+	if len(m.Coefficients) != len(m.Features) {
+		return 0, sizeError(len(m.Coefficients), "coefficients", len(m.Features))
+	}
+	y := m.Intercept
+	for j, x := range row {
+		y += m.Coefficients[j] * x
+	}
+	return y, nil
+}
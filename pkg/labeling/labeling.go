@@ -0,0 +1,256 @@
+// Package labeling queues the rows a classifier is least sure of for
+// people to label, and merges the labeled rows back into the training
+// rows. A queue is a CSV or a JSON lines file: every item holds the raw
+// columns of a row, its predicted probability and an empty label that
+// the labeler fills in. Labeling the rows of probability near the
+// decision threshold first, as uncertainty sampling does, improves a
+// model with fewer labels than labeling rows at random.
+package labeling
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// Columns of the queue files before the columns of the rows.
+const (
+	IDColumn          = "id"
+	ProbabilityColumn = "probability"
+	LabelColumn       = "label"
+)
+
+// Item is a row of a queue.
+type Item struct {
+	// ID identifies the row: the value of its ID column, or its number in
+	// the scored file, from 1.
+	ID string `json:"id"`
+	// Probability is the predicted probability of class 1 of the row.
+	Probability float64 `json:"probability"`
+	// Label is the class given by the labeler, empty until labeled.
+	Label string `json:"label"`
+	// Fields holds the raw value of every column of the row, by name.
+	Fields map[string]string `json:"fields"`
+}
+
+// Queue holds the items to label.
+type Queue struct {
+	// Columns names the columns of the rows, in order.
+	Columns []string
+	Items   []Item
+}
+
+// Uncertain returns the indices of the probabilities within margin of
+// the threshold, the closest first, keeping at most limit of them, or
+// all of them if limit is 0.
+func Uncertain(probabilities []float64, threshold, margin float64, limit int) []int {
+	var indices []int
+	for i, p := range probabilities {
+		if math.Abs(p-threshold) <= margin {
+			indices = append(indices, i)
+		}
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		return math.Abs(probabilities[indices[a]]-threshold) < math.Abs(probabilities[indices[b]]-threshold)
+	})
+	if limit > 0 && len(indices) > limit {
+		indices = indices[:limit]
+	}
+	return indices
+}
+
+// New returns the queue of the rows of a file with the header at the
+// indices, with their probabilities. The rows are identified by their
+// idColumn, or by their number from 1 if idColumn is empty.
+func New(header []string, rows [][]string, indices []int, probabilities []float64, idColumn string) (*Queue, error) {
+	idIdx := -1
+	if idColumn != "" {
+		if idIdx = slices.Index(header, idColumn); idIdx < 0 {
+			return nil, fmt.Errorf("labeling: no column %q", idColumn)
+		}
+	}
+	q := &Queue{Columns: slices.Clone(header)}
+	for _, i := range indices {
+		item := Item{ID: strconv.Itoa(i + 1), Probability: probabilities[i], Fields: make(map[string]string, len(header))}
+		if idIdx >= 0 {
+			item.ID = rows[i][idIdx]
+		}
+		for j, name := range header {
+			item.Fields[name] = rows[i][j]
+		}
+		q.Items = append(q.Items, item)
+	}
+	return q, nil
+}
+
+// Labeled returns the items with a label.
+func (q *Queue) Labeled() []Item {
+	var items []Item
+	for _, item := range q.Items {
+		if item.Label != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isJSONLines reports whether the queue file at path is in JSON lines,
+// by its extension, .jsonl or .ndjson, and in CSV otherwise.
+func isJSONLines(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".jsonl" || ext == ".ndjson"
+}
+
+// Write writes the queue to the file at path, in JSON lines, one item per
+// line, if its extension is .jsonl or .ndjson, and in CSV otherwise, with
+// the id, probability and label columns before those of the rows.
+func (q *Queue) Write(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if isJSONLines(path) {
+		enc := json.NewEncoder(w)
+		for _, item := range q.Items {
+			if err := enc.Encode(item); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	} else {
+		cw := csv.NewWriter(w)
+		cw.Write(append([]string{IDColumn, ProbabilityColumn, LabelColumn}, q.Columns...))
+		for _, item := range q.Items {
+			record := []string{item.ID, strconv.FormatFloat(item.Probability, 'f', 6, 64), item.Label}
+			for _, name := range q.Columns {
+				record = append(record, item.Fields[name])
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read reads the queue file at path written by Write, possibly labeled
+// since, in JSON lines or CSV by its extension. The columns of a JSON
+// lines queue are those of its first item, sorted.
+func Read(path string) (*Queue, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	q := &Queue{}
+	if isJSONLines(path) {
+		dec := json.NewDecoder(bufio.NewReader(f))
+		for line := 1; dec.More(); line++ {
+			var item Item
+			if err := dec.Decode(&item); err != nil {
+				return nil, fmt.Errorf("%s: item %d: %v", path, line, err)
+			}
+			if q.Columns == nil {
+				for name := range item.Fields {
+					q.Columns = append(q.Columns, name)
+				}
+				sort.Strings(q.Columns)
+			}
+			q.Items = append(q.Items, item)
+		}
+		return q, nil
+	}
+	records, err := csv.NewReader(bufio.NewReader(f)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	header := records[0]
+	if len(header) < 3 || header[0] != IDColumn || header[1] != ProbabilityColumn || header[2] != LabelColumn {
+		return nil, fmt.Errorf("%s: a queue starts with the %s, %s and %s columns", path, IDColumn, ProbabilityColumn, LabelColumn)
+	}
+	q.Columns = header[3:]
+	for i, record := range records[1:] {
+		p, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: row %d: %v", path, i+1, err)
+		}
+		item := Item{ID: record[0], Probability: p, Label: record[2], Fields: make(map[string]string, len(q.Columns))}
+		for j, name := range q.Columns {
+			item.Fields[name] = record[3+j]
+		}
+		q.Items = append(q.Items, item)
+	}
+	return q, nil
+}
+
+// Merge returns the training rows of a file with the header with the
+// labeled items added, the label going to the target column, and the
+// numbers of rows added and relabeled. When idColumn is set, an item with
+// the ID of a training row relabels that row instead of adding one, so
+// merging the same queue twice adds its rows once. Items must have every
+// column of the header but the target.
+func Merge(header []string, rows [][]string, items []Item, target, idColumn string) ([][]string, int, int, error) {
+	targetIdx := slices.Index(header, target)
+	if targetIdx < 0 {
+		return nil, 0, 0, fmt.Errorf("labeling: no target column %q", target)
+	}
+	byID := make(map[string]int)
+	if idColumn != "" {
+		idIdx := slices.Index(header, idColumn)
+		if idIdx < 0 {
+			return nil, 0, 0, fmt.Errorf("labeling: no column %q", idColumn)
+		}
+		for i, row := range rows {
+			byID[row[idIdx]] = i
+		}
+	}
+	merged := slices.Clone(rows)
+	var added, relabeled int
+	for _, item := range items {
+		if item.Label == "" {
+			return nil, 0, 0, fmt.Errorf("labeling: item %s has no label", item.ID)
+		}
+		if i, ok := byID[item.ID]; ok {
+			merged[i] = slices.Clone(merged[i])
+			merged[i][targetIdx] = item.Label
+			relabeled++
+			continue
+		}
+		row := make([]string, len(header))
+		for j, name := range header {
+			if j == targetIdx {
+				row[j] = item.Label
+				continue
+			}
+			v, ok := item.Fields[name]
+			if !ok {
+				return nil, 0, 0, fmt.Errorf("labeling: item %s has no column %q", item.ID, name)
+			}
+			row[j] = v
+		}
+		if idColumn != "" {
+			byID[item.ID] = len(merged)
+		}
+		merged = append(merged, row)
+		added++
+	}
+	return merged, added, relabeled, nil
+}
@@ -0,0 +1,92 @@
+package logistic
+
+import (
+	"errors"
+	"math"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+)
+
+// FitPlatt fits a Platt scaling of the probabilities to the labels, 0 or
+// 1, by Newton's method on the log loss. The probabilities should come
+// from rows the model was not fitted on, such as out-of-fold predictions,
+// as the model is overconfident on its own training rows. Following
+// Platt, the labels are smoothed towards 1/2 by the class counts, so that
+// separable rows do not send the scaling to infinity.
+func FitPlatt(proba, labels []float64) (*model.Platt, error) {
+	if len(proba) != len(labels) {
+		return nil, errors.New("logistic: as many probabilities as labels are needed")
+	}
+	var numPos, numNeg float64
+	for _, label := range labels {
+		switch label {
+		case 1:
+			numPos++
+		case 0:
+			numNeg++
+		default:
+			return nil, errors.New("logistic: labels must be 0 or 1")
+		}
+	}
+	if numPos == 0 || numNeg == 0 {
+		return nil, errors.New("logistic: calibration needs rows of both classes")
+	}
+	hiTarget, loTarget := (numPos+1)/(numPos+2), 1/(numNeg+2)
+	f := make([]float64, len(proba))
+	t := make([]float64, len(proba))
+	for i, p := range proba {
+		f[i] = model.LogOdds(p)
+		t[i] = loTarget
+		if labels[i] == 1 {
+			t[i] = hiTarget
+		}
+	}
+	// loss returns the log loss of the scaling against the smoothed
+	// labels.
+	loss := func(a, b float64) float64 {
+		var sum float64
+		for i := range f {
+			z := a*f[i] + b
+			// log(1 + e^z) - t z, computed without overflow.
+			sum += math.Max(z, 0) + math.Log1p(math.Exp(-math.Abs(z))) - t[i]*z
+		}
+		return sum
+	}
+	// Start from the identity scaling, shifted to the prior odds.
+	a, b := 1.0, math.Log((numPos+1)/(numNeg+1))
+	current := loss(a, b)
+	const minStep, tolerance = 1e-10, 1e-9
+	for iter := 0; iter < 100; iter++ {
+		// Gradient and Hessian of the loss.
+		var ga, gb, haa, hab, hbb float64
+		for i := range f {
+			p := sigmoid(a*f[i] + b)
+			d, w := p-t[i], math.Max(p*(1-p), 1e-12)
+			ga += d * f[i]
+			gb += d
+			haa += w * f[i] * f[i]
+			hab += w * f[i]
+			hbb += w
+		}
+		if math.Abs(ga) < tolerance && math.Abs(gb) < tolerance {
+			break
+		}
+		// Solve the Newton system, regularizing a singular Hessian.
+		haa, hbb = haa+1e-12, hbb+1e-12
+		det := haa*hbb - hab*hab
+		da, db := -(hbb*ga-hab*gb)/det, -(haa*gb-hab*ga)/det
+		// Halve the step until the loss decreases enough.
+		step := 1.0
+		for ; step >= minStep; step /= 2 {
+			next := loss(a+step*da, b+step*db)
+			if next < current+1e-4*step*(ga*da+gb*db) {
+				a, b, current = a+step*da, b+step*db, next
+				break
+			}
+		}
+		if step < minStep {
+			break
+		}
+	}
+	return &model.Platt{A: a, B: b}, nil
+}
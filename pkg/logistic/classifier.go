@@ -0,0 +1,140 @@
+package logistic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Classifier is a logistic regression of raw feature rows: it adds the
+// intercept column itself, so its rows hold the features alone.
+type Classifier struct {
+	Options
+	// WarmStart makes Fit continue from the weights and the optimizer
+	// state of an earlier Fit on the same rows, and run only the epochs
+	// beyond those it ran, up to Steps, so that a regression trains in
+	// steps, as when tuning the number of epochs. Without shuffling or
+	// privacy noise, which draw from the random source, the weights are
+	// those Fit would train at once. Weights set before the first Fit,
+	// such as those of a saved model, are the initial weights. Fit
+	// refuses to continue an earlier Fit on other rows.
+	WarmStart bool
+	// Names, when set, names the features, which the fingerprint checked
+	// by a warm start includes.
+	Names []string
+	// Weights holds the weight of every feature followed by the
+	// intercept, once fitted.
+	Weights []float64
+	// Summary describes the last fit. With WarmStart, its Iterations
+	// counts the epochs of the earlier fits too, and its History only
+	// those of the last one.
+	Summary Summary
+	// opt is the optimizer of the earlier fits and epochs the number of
+	// epochs they ran, which WarmStart continues on the rows identified
+	// by fingerprint.
+	opt         optim.Optimizer
+	epochs      int
+	fingerprint dataset.Fingerprint
+}
+
+// ErrWarmStart is returned when a warm start would continue a fit on other
+// rows than those given to Fit.
+var ErrWarmStart = errors.New("logistic: warm start on other rows than the earlier fit")
+
+// New returns an unfitted classifier with the trainer settings.
+func New(opts Options) *Classifier {
+	return &Classifier{Options: opts}
+}
+
+// Fit fits the classifier on the features x and the labels, 0 or 1, with
+// the initial weights and row orders drawn from r. It stops with the
+// error of ctx when ctx is cancelled; a warm started classifier then
+// keeps the epochs it ran, and the next Fit resumes from them.
+func (c *Classifier) Fit(ctx context.Context, x mat64.Matrix, y []float64, r *rand.Rand) error {
+	xi := AddIntercept(x)
+	if err := check(xi, y, c.Options); err != nil {
+		return err
+	}
+	if !c.WarmStart {
+		weights, summary, err := Fit(ctx, xi, y, c.Options, r)
+		if err != nil {
+			return err
+		}
+		c.Weights, c.Summary, c.opt, c.epochs = weights, summary, nil, 0
+		return nil
+	}
+	fingerprint := dataset.NewFingerprint(x, y, nil, c.Names)
+	if c.epochs > 0 && !c.fingerprint.Equal(fingerprint) {
+		return ErrWarmStart
+	}
+	_, numWeights := xi.Dims()
+	switch {
+	case c.Weights == nil:
+		c.Weights, c.opt, c.epochs = initWeights(numWeights, r), nil, 0
+	case len(c.Weights) != numWeights:
+		return fmt.Errorf("logistic: warm start from %d features, the rows have %d", len(c.Weights)-1, numWeights-1)
+	}
+	if c.opt == nil {
+		c.opt = c.optimizer()
+	}
+	// The noise set from an ε budget depends on the number of epochs, so
+	// the earlier ones were noised for fewer.
+	if c.epochs > 0 && c.Privacy != nil && c.Privacy.NoiseMultiplier == 0 {
+		return errors.New("logistic: privacy: warm starts need a noise multiplier rather than an ε budget")
+	}
+	summary, err := fitFrom(ctx, xi, y, c.Weights, c.opt, c.epochs, c.Options, r)
+	c.Summary, c.epochs, c.fingerprint = summary, summary.Iterations, fingerprint
+	return err
+}
+
+// weights returns the weights of the classifier once it is fitted to rows
+// of the length of row.
+func (c *Classifier) weights(row []float64) ([]float64, error) {
+	if c.Weights == nil {
+		return nil, errors.New("logistic: classifier is not fitted")
+	}
+	if len(row)+1 != len(c.Weights) {
+		return nil, fmt.Errorf("logistic: row has %d features, the classifier %d", len(row), len(c.Weights)-1)
+	}
+	return c.Weights, nil
+}
+
+// PredictProba returns the probability of class 1 of the row.
+func (c *Classifier) PredictProba(row []float64) (float64, error) {
+	weights, err := c.weights(row)
+	if err != nil {
+		return 0, err
+	}
+	return Probability(weights, append(row[:len(row):len(row)], 1)), nil
+}
+
+// Predict returns the class, 0 or 1, of the row.
+func (c *Classifier) Predict(row []float64) (float64, error) {
+	p, err := c.PredictProba(row)
+	if err != nil || p < c.threshold() {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// Model returns the saveable model of the fitted classifier, whose
+// features are named by names.
+func (c *Classifier) Model(names []string) (*model.Logistic, error) {
+	if c.Weights == nil {
+		return nil, errors.New("logistic: classifier is not fitted")
+	}
+	if len(names)+1 != len(c.Weights) {
+		return nil, fmt.Errorf("logistic: %d names for %d features", len(names), len(c.Weights)-1)
+	}
+	return &model.Logistic{
+		Features:  names,
+		Weights:   append([]float64(nil), c.Weights...),
+		Threshold: c.threshold(),
+	}, nil
+}
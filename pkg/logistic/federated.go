@@ -0,0 +1,92 @@
+package logistic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Client holds the training rows of a client of a federated fit, which
+// never leave it.
+type Client struct {
+	X *mat64.Dense
+	Y []float64
+}
+
+// RoundHook is called after every round of FitFederated with the round
+// number counted from 1 and the averaged weights. An error stops
+// training, and the fit returns it.
+type RoundHook func(round int, weights []float64) error
+
+// FitFederated fits a logistic regression by federated averaging
+// (FedAvg, McMahan et al., "Communication-efficient learning of deep
+// networks from decentralized data", 2017). Every round, each client
+// runs opts.Steps epochs of Fit on its own rows, starting from the
+// current weights, and the weights become the average of the clients'
+// weights, weighted by their number of rows. Only weights are exchanged.
+//
+// The initial weights are drawn from the seed, and every client of every
+// round draws its row orders from its own stream, so the clients are
+// trained in parallel on workers (see parallel.Workers) without changing
+// the result. opts.OnEpoch is not called, and private training is not
+// supported, as its budget would have to be composed over the rounds.
+func FitFederated(ctx context.Context, clients []Client, rounds int, opts Options, seed uint64, workers int, onRound RoundHook) ([]float64, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("logistic: no clients")
+	}
+	if rounds < 1 {
+		return nil, fmt.Errorf("logistic: %d rounds, want at least 1", rounds)
+	}
+	if opts.Privacy != nil {
+		return nil, errors.New("logistic: federated fits do not support privacy")
+	}
+	_, numWeights := clients[0].X.Dims()
+	var total int
+	for k, c := range clients {
+		if err := check(c.X, c.Y, opts); err != nil {
+			return nil, fmt.Errorf("client %d: %w", k+1, err)
+		}
+		if _, cols := c.X.Dims(); cols != numWeights {
+			return nil, fmt.Errorf("logistic: client %d: %d columns, want %d", k+1, cols, numWeights)
+		}
+		total += len(c.Y)
+	}
+	opts.OnEpoch = nil
+	weights := initWeights(numWeights, rand.New(rand.NewSource(seed)))
+	type update struct {
+		weights []float64
+		err     error
+	}
+	for round := 0; round < rounds; round++ {
+		// Train every client from the current weights.
+		updates := parallel.Map(len(clients), workers, func(k int) update {
+			local := append([]float64(nil), weights...)
+			r := rand.New(rand.NewSource(parallel.Seed(seed, round*len(clients)+k)))
+			_, err := fitFrom(ctx, clients[k].X, clients[k].Y, local, opts.optimizer(), 0, opts, r)
+			return update{weights: local, err: err}
+		})
+		// Average the weights of the clients in client order.
+		for j := range weights {
+			weights[j] = 0
+		}
+		for k, u := range updates {
+			if u.err != nil {
+				return nil, u.err
+			}
+			share := float64(len(clients[k].Y)) / float64(total)
+			for j, w := range u.weights {
+				weights[j] += share * w
+			}
+		}
+		if onRound != nil {
+			if err := onRound(round+1, weights); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return weights, nil
+}
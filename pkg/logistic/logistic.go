@@ -0,0 +1,488 @@
+// Package logistic trains binary logistic regressions by gradient
+// descent. The weights hold one entry per feature column; the last column
+// of the features is taken to be a column of ones, whose weight is the
+// intercept and is left out of the L2 penalty. AddIntercept appends that
+// column to a matrix of raw features.
+//
+// Fit runs the epochs on the training rows alone, while FitBest also
+// evaluates a validation set after every epoch and returns the best
+// weights seen. FitFederated simulates federated training across clients
+// holding their own rows. Classifier wraps both behind Fit and Predict
+// methods, and with WarmStart continues its earlier fit.
+// FitPlatt calibrates the probabilities of a fitted regression.
+// Training checks its context before every epoch, and stops with the
+// error of the context once it is cancelled.
+package logistic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// EpochHook is called after every training epoch with the epoch number
+// counted from 1, the current weights and the metrics of the epoch, keyed
+// as loss/train, accuracy/train and, with a validation set,
+// loss/validation and accuracy/validation. An error stops training, and
+// the fit returns it.
+type EpochHook func(epoch int, weights []float64, scalars map[string]float64) error
+
+// Options holds the settings of the gradient descent trainer.
+type Options struct {
+	// Steps is the number of epochs to run.
+	Steps int
+	// Schedule returns the learning rate of every epoch.
+	Schedule optim.Schedule
+	// NewOptimizer returns the update rule of a new fit. Nil takes plain
+	// gradient descent steps.
+	NewOptimizer func() optim.Optimizer
+	// Lambda is the L2 penalty on the feature weights (0 disables it).
+	Lambda float64
+	// BatchSize is the number of rows per update. 1 gives stochastic
+	// gradient descent, while 0 uses every row in a single batch.
+	BatchSize int
+	// Shuffle draws a new row order for every epoch.
+	Shuffle bool
+	// Tolerance stops training once an epoch changes the training log
+	// loss by less (0 disables it).
+	Tolerance float64
+	// Patience stops FitBest once the validation log loss has not
+	// improved for that many epochs (0 disables it).
+	Patience int
+	// Threshold is the probability from which the accuracies reported
+	// to OnEpoch count a row as 1 (0 uses 0.5).
+	Threshold float64
+	// OnEpoch, when set, is called after every epoch.
+	OnEpoch EpochHook
+	// Privacy, when set, trains with differential privacy (DP-SGD).
+	Privacy *Privacy
+	// ClassWeights, when set, holds the weights of the classes 0 and 1:
+	// the gradient of every row is scaled by the weight of its class, so
+	// that the errors of a rare class count more, as an alternative to
+	// resampling the rows. BalancedWeights returns weights inverse to the
+	// class frequencies. The reported log losses stay unweighted.
+	ClassWeights []float64
+}
+
+// Summary describes how a training run ended.
+type Summary struct {
+	// Iterations is the number of epochs actually run.
+	Iterations int
+	// Loss is the training log loss of the returned weights.
+	Loss float64
+	// Converged reports whether training stopped early.
+	Converged bool
+	// History holds the training log loss after every epoch.
+	History []float64
+	// Epsilon is the privacy budget spent by training with
+	// Options.Privacy, for its δ, and 0 without it.
+	Epsilon float64
+}
+
+// Epoch holds the validation metrics after a training epoch of FitBest.
+type Epoch struct {
+	Epoch    int
+	LogLoss  float64
+	Accuracy float64
+}
+
+// check returns an error when the options or the data cannot be trained
+// on.
+func check(x *mat64.Dense, y []float64, opts Options) error {
+	if opts.Steps < 1 {
+		return fmt.Errorf("logistic: %d steps, want at least 1", opts.Steps)
+	}
+	if opts.Schedule == nil {
+		return errors.New("logistic: no learning rate schedule")
+	}
+	if opts.BatchSize < 0 {
+		return fmt.Errorf("logistic: invalid batch size %d", opts.BatchSize)
+	}
+	if opts.Privacy != nil {
+		if err := opts.Privacy.check(opts); err != nil {
+			return err
+		}
+	}
+	if opts.ClassWeights != nil {
+		if len(opts.ClassWeights) != 2 {
+			return fmt.Errorf("logistic: %d class weights, want 2", len(opts.ClassWeights))
+		}
+		for c, weight := range opts.ClassWeights {
+			if !(weight > 0) || math.IsInf(weight, 1) {
+				return fmt.Errorf("logistic: class %d: weight %g, want > 0", c, weight)
+			}
+		}
+	}
+	return checkData(x, y)
+}
+
+// checkData returns an error when the labels do not match the rows of x
+// or are not 0 and 1.
+func checkData(x *mat64.Dense, y []float64) error {
+	numRows, _ := x.Dims()
+	if numRows == 0 {
+		return errors.New("logistic: no rows")
+	}
+	if len(y) != numRows {
+		return fmt.Errorf("logistic: %d labels for %d rows", len(y), numRows)
+	}
+	for i, label := range y {
+		if label != 0 && label != 1 {
+			return fmt.Errorf("logistic: row %d: label %g, want 0 or 1", i+1, label)
+		}
+	}
+	return nil
+}
+
+// BalancedWeights returns the class weights of the labels y, 0 or 1, that
+// give both classes the same total weight: n / (2 n_c) for the n_c rows of
+// class c among n, so that every row weighs 1 on average.
+func BalancedWeights(y []float64) ([]float64, error) {
+	var counts [2]int
+	for i, label := range y {
+		if label != 0 && label != 1 {
+			return nil, fmt.Errorf("logistic: row %d: label %g, want 0 or 1", i+1, label)
+		}
+		counts[int(label)]++
+	}
+	weights := make([]float64, 2)
+	for c, count := range counts {
+		if count == 0 {
+			return nil, fmt.Errorf("logistic: no rows of class %d to balance", c)
+		}
+		weights[c] = float64(len(y)) / float64(2*count)
+	}
+	return weights, nil
+}
+
+// threshold returns the decision threshold of the options.
+func (o Options) threshold() float64 {
+	if o.Threshold == 0 {
+		return 0.5
+	}
+	return o.Threshold
+}
+
+// optimizer returns the update rule of a new fit.
+func (o Options) optimizer() optim.Optimizer {
+	if o.NewOptimizer == nil {
+		return optim.SGD{}
+	}
+	return o.NewOptimizer()
+}
+
+// Fit fits a logistic regression of the labels, 0 or 1, on the features
+// with the trainer settings in opts. The initial weights, and the row
+// order of every epoch when shuffling, are drawn from r. Training runs
+// for opts.Steps epochs, or stops early once an epoch changes the
+// training log loss by less than opts.Tolerance. It returns the error
+// of ctx when ctx is cancelled before training ends.
+func Fit(ctx context.Context, x *mat64.Dense, y []float64, opts Options, r *rand.Rand) ([]float64, Summary, error) {
+	if err := check(x, y, opts); err != nil {
+		return nil, Summary{}, err
+	}
+	// Initialize random weights.
+	_, numWeights := x.Dims()
+	weights := initWeights(numWeights, r)
+	summary, err := fitFrom(ctx, x, y, weights, opts.optimizer(), 0, opts, r)
+	if err != nil {
+		return nil, summary, err
+	}
+	return weights, summary, nil
+}
+
+// fitFrom runs the epochs of Fit on checked data from the epoch first,
+// counted from 0, to opts.Steps, updating the given weights in place with
+// opt. Warm starts continue an earlier run by passing its weights, its
+// optimizer and the number of epochs it ran.
+func fitFrom(ctx context.Context, x *mat64.Dense, y []float64, weights []float64, opt optim.Optimizer, first int, opts Options, r *rand.Rand) (Summary, error) {
+	summary := Summary{Iterations: first}
+	noise, err := opts.Privacy.noise(len(y), opts)
+	if err != nil {
+		return summary, err
+	}
+	// Iteratively optimize the weights.
+	prevLoss := math.Inf(1)
+	for i := first; i < opts.Steps; i++ {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		gradientEpoch(x, y, weights, opts, opt, i, rowOrder(len(y), opts.Shuffle, r), noise, r)
+		summary.Epsilon = opts.Privacy.spent(len(y), opts, noise, i+1)
+		summary.Iterations = i + 1
+		// Track the training loss and stop once it has settled.
+		var accuracy float64
+		summary.Loss, accuracy = Evaluate(weights, x, y, opts.threshold())
+		summary.History = append(summary.History, summary.Loss)
+		if opts.OnEpoch != nil {
+			if err := opts.OnEpoch(i+1, weights, map[string]float64{"loss/train": summary.Loss, "accuracy/train": accuracy}); err != nil {
+				return summary, err
+			}
+		}
+		if opts.Tolerance > 0 && math.Abs(prevLoss-summary.Loss) < opts.Tolerance {
+			summary.Converged = true
+			break
+		}
+		prevLoss = summary.Loss
+	}
+	if summary.History == nil {
+		summary.Loss = LogLoss(weights, x, y)
+	}
+	return summary, nil
+}
+
+// FitBest fits a logistic regression like Fit, evaluating the validation
+// rows valX and labels valY after every epoch. It returns the weights of
+// the epoch with the lowest validation log loss, so a late divergence
+// does not replace a good model, along with the metrics of every epoch
+// and a summary of the run. Besides the training loss tolerance, training
+// stops once the validation loss has not improved for opts.Patience
+// epochs. It returns the error of ctx when ctx is cancelled before
+// training ends.
+func FitBest(ctx context.Context, x *mat64.Dense, y []float64, valX *mat64.Dense, valY []float64, opts Options, r *rand.Rand) ([]float64, []Epoch, Summary, error) {
+	if err := check(x, y, opts); err != nil {
+		return nil, nil, Summary{}, err
+	}
+	if err := checkData(valX, valY); err != nil {
+		return nil, nil, Summary{}, fmt.Errorf("validation set: %w", err)
+	}
+	_, numWeights := x.Dims()
+	if _, valCols := valX.Dims(); valCols != numWeights {
+		return nil, nil, Summary{}, fmt.Errorf("logistic: %d validation columns for %d training columns", valCols, numWeights)
+	}
+	noise, err := opts.Privacy.noise(len(y), opts)
+	if err != nil {
+		return nil, nil, Summary{}, err
+	}
+	// Initialize random weights.
+	weights := initWeights(numWeights, r)
+	best := append([]float64(nil), weights...)
+	bestLoss := math.Inf(1)
+	history := make([]Epoch, 0, opts.Steps)
+	// Iteratively optimize the weights, keeping a copy of the best ones.
+	opt := opts.optimizer()
+	threshold := opts.threshold()
+	var summary Summary
+	prevLoss := math.Inf(1)
+	sinceBest := 0
+	for i := 0; i < opts.Steps; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, history, summary, err
+		}
+		gradientEpoch(x, y, weights, opts, opt, i, rowOrder(len(y), opts.Shuffle, r), noise, r)
+		summary.Epsilon = opts.Privacy.spent(len(y), opts, noise, i+1)
+		summary.Iterations = i + 1
+		loss, accuracy := Evaluate(weights, valX, valY, threshold)
+		history = append(history, Epoch{Epoch: i + 1, LogLoss: loss, Accuracy: accuracy})
+		if loss < bestLoss {
+			bestLoss = loss
+			copy(best, weights)
+			sinceBest = 0
+		} else {
+			sinceBest++
+		}
+		// Track the training loss, then stop once the validation loss
+		// stops improving or the training loss has settled.
+		trainLoss, trainAccuracy := Evaluate(weights, x, y, threshold)
+		summary.History = append(summary.History, trainLoss)
+		if opts.OnEpoch != nil {
+			err := opts.OnEpoch(i+1, weights, map[string]float64{
+				"loss/train":          trainLoss,
+				"accuracy/train":      trainAccuracy,
+				"loss/validation":     loss,
+				"accuracy/validation": accuracy,
+			})
+			if err != nil {
+				return nil, history, summary, err
+			}
+		}
+		if opts.Patience > 0 && sinceBest >= opts.Patience {
+			summary.Converged = true
+			break
+		}
+		if opts.Tolerance > 0 && math.Abs(prevLoss-trainLoss) < opts.Tolerance {
+			summary.Converged = true
+			break
+		}
+		prevLoss = trainLoss
+	}
+	summary.Loss = LogLoss(best, x, y)
+	return best, history, summary, nil
+}
+
+// BestEpoch returns the metrics of the epoch with the lowest validation
+// log loss.
+func BestEpoch(history []Epoch) Epoch {
+	best := history[0]
+	for _, m := range history[1:] {
+		if m.LogLoss < best.LogLoss {
+			best = m
+		}
+	}
+	return best
+}
+
+// initWeights draws numWeights initial weights from r.
+func initWeights(numWeights int, r *rand.Rand) []float64 {
+	weights := make([]float64, numWeights)
+	for idx := range weights {
+		weights[idx] = r.Float64()
+	}
+	return weights
+}
+
+// rowOrder returns the order in which an epoch visits the n training
+// rows, shuffled with r when requested.
+func rowOrder(n int, shuffle bool, r *rand.Rand) []int {
+	if shuffle {
+		return r.Perm(n)
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// gradientEpoch makes a single pass over the training rows in the given
+// order, updating the weights in place with opt after every batch of
+// opts.BatchSize rows, from the mean gradient of the batch at the
+// learning rate scheduled for the epoch. The gradient is that of the
+// mean log loss of the batch, the mean of (p - y) x over its rows for the
+// predicted probability p of class 1. The gradient of every row is
+// scaled by the weight of its class in opts.ClassWeights, when set. The
+// gradient includes the L2 penalty on the feature weights, leaving the
+// intercept (the last weight) unpenalized. With opts.Privacy, the
+// gradient of every row is clipped to the norm opts.Privacy.Clip and
+// Gaussian noise of standard deviation noise times the clipping norm,
+// drawn from r, is added to the sum of every batch.
+func gradientEpoch(x *mat64.Dense, y []float64, weights []float64, opts Options, opt optim.Optimizer, epoch int, order []int, noise float64, r *rand.Rand) {
+	size := opts.BatchSize
+	if size <= 0 || size > len(order) {
+		size = len(order)
+	}
+	rate := opts.Schedule(epoch)
+	// grad accumulates the gradient of the current batch.
+	grad := make([]float64, len(weights))
+	for start := 0; start < len(order); start += size {
+		end := start + size
+		if end > len(order) {
+			end = len(order)
+		}
+		for j := range grad {
+			grad[j] = 0
+		}
+		// Accumulate the gradient of every row in the batch.
+		for _, idx := range order[start:end] {
+			// Get the features corresponding to this label.
+			featureRow := x.RawRowView(idx)
+			// The gradient of the log loss of the row is (p - y) x, for
+			// the predicted probability p of class 1.
+			pred := Probability(weights, featureRow)
+			scale := pred - y[idx]
+			if opts.ClassWeights != nil {
+				scale *= opts.ClassWeights[int(y[idx])]
+			}
+			if opts.Privacy != nil {
+				// Clip the norm of the gradient of the row.
+				if norm := math.Abs(scale) * math.Sqrt(dot(featureRow, featureRow)); norm > opts.Privacy.Clip {
+					scale *= opts.Privacy.Clip / norm
+				}
+			}
+			for j, v := range featureRow {
+				grad[j] += scale * v
+			}
+		}
+		if opts.Privacy != nil {
+			for j := range grad {
+				grad[j] += noise * opts.Privacy.Clip * r.NormFloat64()
+			}
+		}
+		for j := range grad {
+			grad[j] /= float64(end - start)
+		}
+		// Add the gradient of the weight decay.
+		for j := 0; j < len(weights)-1; j++ {
+			grad[j] += opts.Lambda * weights[j]
+		}
+		// Update the feature weights.
+		opt.Step(weights, grad, rate)
+	}
+}
+
+// sigmoid implements the logistic function.
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// dot returns the dot product of a and b.
+func dot(a, b []float64) float64 {
+	var s float64
+	for j, v := range a {
+		s += v * b[j]
+	}
+	return s
+}
+
+// Probability returns the probability of class 1 of a feature row under
+// the weights.
+func Probability(weights, row []float64) float64 {
+	return sigmoid(dot(weights, row))
+}
+
+// PredictProba returns the probability of class 1 of every row of x.
+func PredictProba(weights []float64, x *mat64.Dense) []float64 {
+	numRows, _ := x.Dims()
+	probabilities := make([]float64, numRows)
+	for i := range probabilities {
+		probabilities[i] = Probability(weights, x.RawRowView(i))
+	}
+	return probabilities
+}
+
+// LogLoss returns the mean cross-entropy of the predicted probabilities
+// of the rows of x. Probabilities are clipped away from 0 and 1 to keep
+// the loss finite.
+func LogLoss(weights []float64, x *mat64.Dense, y []float64) float64 {
+	loss, _ := Evaluate(weights, x, y, 0.5)
+	return loss
+}
+
+// Evaluate returns the log loss of the weights on the rows of x, and the
+// accuracy of classifying as 1 the rows whose probability reaches the
+// threshold.
+func Evaluate(weights []float64, x *mat64.Dense, y []float64, threshold float64) (loss, accuracy float64) {
+	const eps = 1e-15
+	var correct int
+	for i, label := range y {
+		p := Probability(weights, x.RawRowView(i))
+		predicted := 0.0
+		if p >= threshold {
+			predicted = 1.0
+		}
+		if predicted == label {
+			correct++
+		}
+		p = math.Min(math.Max(p, eps), 1-eps)
+		loss -= label*math.Log(p) + (1-label)*math.Log(1-p)
+	}
+	return loss / float64(len(y)), float64(correct) / float64(len(y))
+}
+
+// AddIntercept returns x followed by a column of ones.
+func AddIntercept(x mat64.Matrix) *mat64.Dense {
+	numRows, numCols := x.Dims()
+	out := mat64.NewDense(numRows, numCols+1, nil)
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			out.Set(i, j, x.At(i, j))
+		}
+		out.Set(i, numCols, 1)
+	}
+	return out
+}
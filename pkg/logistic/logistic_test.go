@@ -0,0 +1,164 @@
+package logistic
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// referenceData returns rows of two features and the intercept column,
+// with labels drawn from a logistic model of the features.
+func referenceData(numRows int) (*mat64.Dense, []float64) {
+	r := rand.New(rand.NewSource(7))
+	x := mat64.NewDense(numRows, 3, nil)
+	y := make([]float64, numRows)
+	for i := range y {
+		x1, x2 := r.NormFloat64(), 2*r.Float64()-1
+		x.SetRow(i, []float64{x1, x2, 1})
+		if r.Float64() < sigmoid(1.5*x1-2*x2+0.5) {
+			y[i] = 1
+		}
+	}
+	return x, y
+}
+
+// newtonFit returns the maximum likelihood weights of a logistic
+// regression with the L2 penalty lambda on the feature weights, by
+// Newton's method, a reference independent of gradient descent.
+func newtonFit(t *testing.T, x *mat64.Dense, y []float64, lambda float64) []float64 {
+	t.Helper()
+	numRows, numWeights := x.Dims()
+	w := make([]float64, numWeights)
+	for iter := 0; iter < 50; iter++ {
+		grad := mat64.NewVector(numWeights, nil)
+		hess := mat64.NewSymDense(numWeights, nil)
+		for i := 0; i < numRows; i++ {
+			row := x.RawRowView(i)
+			p := Probability(w, row)
+			for j := range row {
+				grad.SetVec(j, grad.At(j, 0)+(p-y[i])*row[j]/float64(numRows))
+				for k := j; k < numWeights; k++ {
+					hess.SetSym(j, k, hess.At(j, k)+p*(1-p)*row[j]*row[k]/float64(numRows))
+				}
+			}
+		}
+		for j := 0; j < numWeights-1; j++ {
+			grad.SetVec(j, grad.At(j, 0)+lambda*w[j])
+			hess.SetSym(j, j, hess.At(j, j)+lambda)
+		}
+		var step mat64.Vector
+		if err := step.SolveVec(hess, grad); err != nil {
+			t.Fatal(err)
+		}
+		for j := range w {
+			w[j] -= step.At(j, 0)
+		}
+		if mat64.Norm(&step, 2) < 1e-12 {
+			break
+		}
+	}
+	return w
+}
+
+func TestFitMatchesMaximumLikelihood(t *testing.T) {
+	x, y := referenceData(400)
+	for _, lambda := range []float64{0, 0.05} {
+		want := newtonFit(t, x, y, lambda)
+		opts := Options{Steps: 3000, Schedule: optim.Constant(1), Lambda: lambda}
+		got, _, err := Fit(context.Background(), x, y, opts, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := range want {
+			if math.Abs(got[j]-want[j]) > 1e-4 {
+				t.Errorf("lambda %g: weight %d = %.6f, maximum likelihood %.6f", lambda, j, got[j], want[j])
+			}
+		}
+	}
+}
+
+func TestFitMinimizesLogLoss(t *testing.T) {
+	x, y := referenceData(400)
+	want := newtonFit(t, x, y, 0)
+	opts := Options{Steps: 3000, Schedule: optim.Constant(1)}
+	got, summary, err := Fit(context.Background(), x, y, opts, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loss, best := LogLoss(got, x, y), LogLoss(want, x, y); loss > best+1e-8 {
+		t.Errorf("log loss %.8f, above the minimum %.8f", loss, best)
+	}
+	if summary.Iterations != opts.Steps {
+		t.Errorf("ran %d epochs, want %d", summary.Iterations, opts.Steps)
+	}
+}
+
+func TestClassWeightsMatchWeightedMaximumLikelihood(t *testing.T) {
+	x, y := referenceData(400)
+	weights := []float64{1, 3}
+	// Repeating every row of class 1 three times weighs it by 3.
+	numRows, numCols := x.Dims()
+	var data, labels []float64
+	for i := 0; i < numRows; i++ {
+		copies := int(weights[int(y[i])])
+		for c := 0; c < copies; c++ {
+			data = append(data, x.RawRowView(i)...)
+			labels = append(labels, y[i])
+		}
+	}
+	want := newtonFit(t, mat64.NewDense(len(labels), numCols, data), labels, 0)
+	// The class weights scale the mean gradient of the original rows,
+	// by a factor that does not move its zero.
+	opts := Options{Steps: 4000, Schedule: optim.Constant(1), ClassWeights: weights}
+	got, _, err := Fit(context.Background(), x, y, opts, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := range want {
+		if math.Abs(got[j]-want[j]) > 1e-4 {
+			t.Errorf("weight %d = %.6f, weighted maximum likelihood %.6f", j, got[j], want[j])
+		}
+	}
+}
+
+func TestWarmStartContinuesFit(t *testing.T) {
+	x, y := referenceData(400)
+	features := x.Slice(0, 400, 0, 2)
+	opts := Options{Steps: 300, Schedule: optim.Constant(0.1), NewOptimizer: func() optim.Optimizer { return optim.NewAdam(0.9, 0.999, 1e-8) }}
+	once := New(opts)
+	if err := once.Fit(context.Background(), features, y, rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err)
+	}
+	// Fit 100 epochs, then warm start to 300.
+	steps := New(opts)
+	steps.WarmStart = true
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{100, 300} {
+		steps.Steps = n
+		if err := steps.Fit(context.Background(), features, y, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if steps.Summary.Iterations != 300 || len(steps.Summary.History) != 200 {
+		t.Errorf("ran %d epochs, %d in the last fit, want 300 and 200", steps.Summary.Iterations, len(steps.Summary.History))
+	}
+	for j, w := range once.Weights {
+		if steps.Weights[j] != w {
+			t.Errorf("weight %d = %v in steps, %v at once", j, steps.Weights[j], w)
+		}
+	}
+	// Continuing on other labels is refused.
+	flipped := make([]float64, len(y))
+	for i, label := range y {
+		flipped[i] = 1 - label
+	}
+	steps.Steps = 400
+	if err := steps.Fit(context.Background(), features, flipped, r); !errors.Is(err, ErrWarmStart) {
+		t.Errorf("got %v warm starting on other labels, want ErrWarmStart", err)
+	}
+}
@@ -0,0 +1,91 @@
+package logistic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/privacy"
+)
+
+// Privacy holds the settings of differentially private training by
+// DP-SGD: every batch clips the gradient of each row to the norm Clip and
+// adds Gaussian noise of standard deviation NoiseMultiplier times Clip to
+// their sum before averaging. The weights returned are then (ε, Delta)
+// differentially private with respect to the training rows, with the ε
+// of Summary.Epsilon.
+//
+// Without shuffling the batches of an epoch are disjoint, so every epoch
+// counts as a single update on all the rows. With shuffling, the ε is
+// accounted as if every batch sampled each row independently with
+// probability BatchSize/n, the usual approximation of DP-SGD. The losses
+// and accuracies passed to OnEpoch and kept in the summary, and the
+// validation rows of FitBest, are not covered by the guarantee.
+type Privacy struct {
+	// Clip is the largest norm of the gradient of a row.
+	Clip float64
+	// NoiseMultiplier is the standard deviation of the noise relative to
+	// Clip. Zero sets it from the Epsilon budget.
+	NoiseMultiplier float64
+	// Epsilon is the budget the noise multiplier is set to spend over
+	// all the epochs, when NoiseMultiplier is 0.
+	Epsilon float64
+	// Delta is the probability with which the ε bound may fail, usually
+	// well below one over the number of rows.
+	Delta float64
+}
+
+// check returns an error when the privacy settings cannot be trained with.
+func (p *Privacy) check(opts Options) error {
+	if p.Clip <= 0 {
+		return fmt.Errorf("logistic: privacy: clipping norm %g, want > 0", p.Clip)
+	}
+	if p.Delta <= 0 || p.Delta >= 1 {
+		return fmt.Errorf("logistic: privacy: δ %g, want in (0, 1)", p.Delta)
+	}
+	if p.NoiseMultiplier < 0 || p.NoiseMultiplier == 0 && p.Epsilon <= 0 {
+		return errors.New("logistic: privacy: needs a positive noise multiplier or ε budget")
+	}
+	// The tolerance stops on the training loss, which would leak.
+	if opts.Tolerance > 0 {
+		return errors.New("logistic: privacy: cannot stop on the training loss tolerance")
+	}
+	return nil
+}
+
+// sampling returns the probability that an update uses a row and the
+// number of updates per epoch, as accounted, for n training rows.
+func (p *Privacy) sampling(n int, opts Options) (float64, int) {
+	size := opts.BatchSize
+	if !opts.Shuffle || size <= 0 || size >= n {
+		return 1, 1
+	}
+	return float64(size) / float64(n), (n + size - 1) / size
+}
+
+// noise returns the noise multiplier of training on n rows: the one set,
+// or the smallest that keeps all the epochs within the ε budget. It
+// returns 0 for the nil Privacy.
+func (p *Privacy) noise(n int, opts Options) (float64, error) {
+	if p == nil {
+		return 0, nil
+	}
+	if p.NoiseMultiplier > 0 {
+		return p.NoiseMultiplier, nil
+	}
+	q, updates := p.sampling(n, opts)
+	sigma, err := privacy.NoiseMultiplier(q, updates*opts.Steps, p.Epsilon, p.Delta)
+	if err != nil {
+		return 0, fmt.Errorf("logistic: %w", err)
+	}
+	return sigma, nil
+}
+
+// spent returns the ε spent by the given number of epochs on n rows with
+// the noise multiplier, and 0 for the nil Privacy.
+func (p *Privacy) spent(n int, opts Options, noise float64, epochs int) float64 {
+	if p == nil {
+		return 0
+	}
+	q, updates := p.sampling(n, opts)
+	return privacy.Epsilon(q, noise, updates*epochs, p.Delta)
+}
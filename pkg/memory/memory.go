@@ -0,0 +1,250 @@
+// Package memory records the allocations and the memory use of the stages
+// of a run, such as load, preprocess, train and evaluate, and enforces a
+// memory budget: the process stops with a clear error when its memory
+// grows beyond the budget, rather than being killed by the system.
+package memory
+
+import (
+	"fmt"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sampleInterval is how often the memory in use is sampled.
+const sampleInterval = 20 * time.Millisecond
+
+// Runtime metrics read by the tracker.
+const (
+	allocBytesMetric   = "/gc/heap/allocs:bytes"
+	allocObjectsMetric = "/gc/heap/allocs:objects"
+	totalMemoryMetric  = "/memory/classes/total:bytes"
+	releasedHeapMetric = "/memory/classes/heap/released:bytes"
+)
+
+// Stage holds the memory figures of one stage of a run.
+type Stage struct {
+	// Name names the stage.
+	Name string
+	// Duration is the wall time of the stage.
+	Duration time.Duration
+	// AllocBytes and AllocObjects count the heap allocations made
+	// during the stage.
+	AllocBytes, AllocObjects uint64
+	// PeakBytes is the most memory the Go runtime held from the system
+	// during the stage, as sampled.
+	PeakBytes uint64
+	// PeakRSSBytes is the peak resident set size of the process at the
+	// end of the stage, or 0 where the system does not report it.
+	PeakRSSBytes uint64
+}
+
+// BudgetError reports memory use beyond the budget.
+type BudgetError struct {
+	// Stage is the stage running when the budget was exceeded.
+	Stage string
+	// Budget is the budget and Used the memory in use, in bytes.
+	Budget, Used uint64
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("memory: %s of memory in use during stage %q exceeds the budget of %s",
+		FormatSize(e.Used), e.Stage, FormatSize(e.Budget))
+}
+
+// Tracker records the stages of a run and watches the memory budget.
+type Tracker struct {
+	budget   uint64
+	onExceed func(error)
+	stop     chan struct{}
+	done     chan struct{}
+
+	mu      sync.Mutex
+	stages  []Stage
+	current *Stage
+	start   [2]uint64
+	began   time.Time
+}
+
+// NewTracker returns a tracker that enforces the budget, in bytes, when
+// it is not 0. When the memory in use exceeds the budget, onExceed is
+// called once with a *BudgetError; it is expected to stop the process.
+// The budget is also set as the soft memory limit of the runtime, so the
+// garbage collector works harder before the budget is reached.
+func NewTracker(budget uint64, onExceed func(error)) *Tracker {
+	t := &Tracker{budget: budget, onExceed: onExceed, stop: make(chan struct{}), done: make(chan struct{})}
+	if budget > 0 {
+		debug.SetMemoryLimit(int64(budget))
+	}
+	go t.watch()
+	return t
+}
+
+// read returns the current values of the named runtime metrics.
+func read(names ...string) []uint64 {
+	samples := make([]metrics.Sample, len(names))
+	for i, name := range names {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	values := make([]uint64, len(names))
+	for i, s := range samples {
+		if s.Value.Kind() == metrics.KindUint64 {
+			values[i] = s.Value.Uint64()
+		}
+	}
+	return values
+}
+
+// inUse returns the memory the runtime holds from the system.
+func inUse() uint64 {
+	v := read(totalMemoryMetric, releasedHeapMetric)
+	return v[0] - v[1]
+}
+
+// watch samples the memory in use until Close, updating the peak of the
+// current stage and checking the budget.
+func (t *Tracker) watch() {
+	defer close(t.done)
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+	exceeded := false
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+		used := inUse()
+		t.mu.Lock()
+		stage := ""
+		if t.current != nil {
+			stage = t.current.Name
+			if used > t.current.PeakBytes {
+				t.current.PeakBytes = used
+			}
+		}
+		t.mu.Unlock()
+		if t.budget > 0 && used > t.budget && !exceeded {
+			exceeded = true
+			t.onExceed(&BudgetError{Stage: stage, Budget: t.budget, Used: used})
+		}
+	}
+}
+
+// Begin starts a stage and returns the function ending it. Stages do not
+// nest: beginning a stage ends the current one.
+func (t *Tracker) Begin(name string) (end func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.endLocked()
+	v := read(allocBytesMetric, allocObjectsMetric)
+	t.current = &Stage{Name: name, PeakBytes: inUse()}
+	t.start = [2]uint64{v[0], v[1]}
+	t.began = time.Now()
+	current := t.current
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.current == current {
+			t.endLocked()
+		}
+	}
+}
+
+// endLocked ends the current stage, if any.
+func (t *Tracker) endLocked() {
+	if t.current == nil {
+		return
+	}
+	v := read(allocBytesMetric, allocObjectsMetric)
+	s := *t.current
+	s.Duration = time.Since(t.began)
+	s.AllocBytes, s.AllocObjects = v[0]-t.start[0], v[1]-t.start[1]
+	if used := inUse(); used > s.PeakBytes {
+		s.PeakBytes = used
+	}
+	s.PeakRSSBytes = peakRSS()
+	t.stages = append(t.stages, s)
+	t.current = nil
+}
+
+// Stages returns the stages ended so far, in order.
+func (t *Tracker) Stages() []Stage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Stage(nil), t.stages...)
+}
+
+// Close ends the current stage and stops watching the budget.
+func (t *Tracker) Close() {
+	t.mu.Lock()
+	t.endLocked()
+	t.mu.Unlock()
+	close(t.stop)
+	<-t.done
+}
+
+// Columns names the columns of the rows returned by Rows.
+var Columns = []string{"stage", "seconds", "alloc_bytes", "alloc_objects", "peak_bytes", "peak_rss_bytes"}
+
+// Rows returns the stages as table rows, one per stage.
+func Rows(stages []Stage) [][]any {
+	rows := make([][]any, len(stages))
+	for i, s := range stages {
+		rows[i] = []any{s.Name, s.Duration.Seconds(), s.AllocBytes, s.AllocObjects, s.PeakBytes, s.PeakRSSBytes}
+	}
+	return rows
+}
+
+// sizeUnits maps the suffixes accepted by ParseSize to their multiplier.
+var sizeUnits = []struct {
+	suffix string
+	scale  uint64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as "512MiB", "2GB" or "1048576" into
+// bytes. An empty string is 0.
+func ParseSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	scale := uint64(1)
+	number := s
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			scale = u.scale
+			number = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("memory: invalid size %q", s)
+	}
+	return uint64(value * float64(scale)), nil
+}
+
+// FormatSize formats a number of bytes with a binary unit, such as
+// "1.5 GiB".
+func FormatSize(bytes uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(bytes)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
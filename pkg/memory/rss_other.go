@@ -0,0 +1,9 @@
+//go:build !unix
+
+package memory
+
+// peakRSS returns 0, as the peak resident set size is only read on unix
+// systems.
+func peakRSS() uint64 {
+	return 0
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package memory
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// peakRSS returns the peak resident set size of the process in bytes.
+func peakRSS() uint64 {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0
+	}
+	// Darwin reports bytes, the other systems kilobytes.
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return uint64(usage.Maxrss)
+	}
+	return uint64(usage.Maxrss) * 1024
+}
@@ -0,0 +1,98 @@
+package metrics
+
+// Binary holds the counts of a binary confusion matrix, where the
+// positive class is labeled 1.0.
+type Binary struct {
+	TruePositives, FalsePositives int
+	TrueNegatives, FalseNegatives int
+}
+
+// BinaryCounts counts the predicted classes against the observed ones.
+// Both hold 1.0 for the positive class and any other value for the
+// negative class.
+func BinaryCounts(observed, predicted []float64) Binary {
+	var b Binary
+	for i, o := range observed {
+		switch {
+		case o == 1.0 && predicted[i] == 1.0:
+			b.TruePositives++
+		case o == 1.0:
+			b.FalseNegatives++
+		case predicted[i] == 1.0:
+			b.FalsePositives++
+		default:
+			b.TrueNegatives++
+		}
+	}
+	return b
+}
+
+// ratio returns num / den, or 0 when den is 0.
+func ratio(num, den int) float64 {
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
+
+// Accuracy returns the share of correct predictions.
+func (b Binary) Accuracy() float64 {
+	return ratio(b.TruePositives+b.TrueNegatives, b.TruePositives+b.FalsePositives+b.TrueNegatives+b.FalseNegatives)
+}
+
+// Precision returns the share of positive predictions that are correct.
+func (b Binary) Precision() float64 {
+	return ratio(b.TruePositives, b.TruePositives+b.FalsePositives)
+}
+
+// Recall returns the share of positive rows predicted as positive, also
+// known as sensitivity or true positive rate.
+func (b Binary) Recall() float64 {
+	return ratio(b.TruePositives, b.TruePositives+b.FalseNegatives)
+}
+
+// Specificity returns the share of negative rows predicted as negative,
+// also known as true negative rate.
+func (b Binary) Specificity() float64 {
+	return ratio(b.TrueNegatives, b.TrueNegatives+b.FalsePositives)
+}
+
+// F1 returns the harmonic mean of the precision and the recall.
+func (b Binary) F1() float64 {
+	precision, recall := b.Precision(), b.Recall()
+	if precision+recall == 0 {
+		return 0
+	}
+	return 2 * precision * recall / (precision + recall)
+}
+
+// BalancedAccuracy returns the mean of the recall and the specificity,
+// which unlike the accuracy is not inflated by the majority class.
+func (b Binary) BalancedAccuracy() float64 {
+	return (b.Recall() + b.Specificity()) / 2
+}
+
+// Map returns the scores keyed by the metric names used in the run
+// metrics files.
+func (b Binary) Map() map[string]float64 {
+	return map[string]float64{
+		"accuracy":          b.Accuracy(),
+		"precision":         b.Precision(),
+		"recall":            b.Recall(),
+		"f1":                b.F1(),
+		"specificity":       b.Specificity(),
+		"balanced_accuracy": b.BalancedAccuracy(),
+	}
+}
+
+// Counts returns the confusion matrix as a dense table over the classes
+// 0 and 1, in the layout of the package level Counts.
+func (b Binary) Counts() [][]float64 {
+	return [][]float64{
+		{float64(b.TrueNegatives), float64(b.FalsePositives)},
+		{float64(b.FalseNegatives), float64(b.TruePositives)},
+	}
+}
+
+// BinaryClasses names the classes of Binary.Counts.
+var BinaryClasses = []string{"0", "1"}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteConfusion prints the confusion matrix counts as a table with the
+// actual classes as rows and the predicted classes as columns, along with
+// the total of every row and column. counts[i][j] is the number of rows
+// of classes[i] predicted as classes[j], as returned by Counts.
+func WriteConfusion(w io.Writer, classes []string, counts [][]float64) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	// Write the header of the predicted classes.
+	fmt.Fprint(tw, "actual \\ predicted\t")
+	for _, class := range classes {
+		fmt.Fprintf(tw, "%s\t", class)
+	}
+	fmt.Fprint(tw, "total\t\n")
+	// Write one row per actual class, summing the columns on the way.
+	columnTotals := make([]float64, len(classes))
+	var total float64
+	for i, class := range classes {
+		fmt.Fprintf(tw, "%s\t", class)
+		var rowTotal float64
+		for j, count := range counts[i] {
+			fmt.Fprintf(tw, "%.0f\t", count)
+			rowTotal += count
+			columnTotals[j] += count
+		}
+		fmt.Fprintf(tw, "%.0f\t\n", rowTotal)
+		total += rowTotal
+	}
+	fmt.Fprint(tw, "total\t")
+	for _, count := range columnTotals {
+		fmt.Fprintf(tw, "%.0f\t", count)
+	}
+	fmt.Fprintf(tw, "%.0f\t\n", total)
+	return tw.Flush()
+}
+
+// ConfusionColumns returns the names of the columns of the rows returned
+// by ConfusionRows: the actual class followed by the predicted classes.
+func ConfusionColumns(classes []string) []string {
+	return append([]string{"actual"}, classes...)
+}
+
+// ConfusionRows returns the confusion matrix counts as table rows, one
+// per actual class.
+func ConfusionRows(classes []string, counts [][]float64) [][]any {
+	rows := make([][]any, len(classes))
+	for i, class := range classes {
+		rows[i] = []any{class}
+		for _, count := range counts[i] {
+			rows[i] = append(rows[i], int(count))
+		}
+	}
+	return rows
+}
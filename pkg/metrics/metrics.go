@@ -0,0 +1,180 @@
+// Package metrics derives classification scores from confusion matrices:
+// precision, recall, F1, specificity and balanced accuracy, per class for
+// the golearn confusion matrices and for binary predictions. It also
+// scores regressions: MAE, RMSE, R², adjusted R² and MAPE.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/sjwhitworth/golearn/evaluation"
+)
+
+// ClassMetrics holds the scores of a single class.
+type ClassMetrics struct {
+	// Class is the name of the class.
+	Class string
+	// Precision, Recall, F1 and Specificity are the one-vs-rest scores of
+	// the class.
+	Precision, Recall, F1, Specificity float64
+	// Support is the number of rows whose reference class is Class.
+	Support int
+}
+
+// Sum adds up the confusion matrices of several folds into one.
+func Sum(cms []evaluation.ConfusionMatrix) evaluation.ConfusionMatrix {
+	total := make(evaluation.ConfusionMatrix)
+	for _, cm := range cms {
+		for ref, row := range cm {
+			if total[ref] == nil {
+				total[ref] = make(map[string]int)
+			}
+			for pred, count := range row {
+				total[ref][pred] += count
+			}
+		}
+	}
+	return total
+}
+
+// Classes returns the sorted names of every class that appears in the
+// confusion matrix, either as a reference or as a prediction.
+func Classes(cm evaluation.ConfusionMatrix) []string {
+	seen := make(map[string]bool)
+	for ref, row := range cm {
+		seen[ref] = true
+		for pred := range row {
+			seen[pred] = true
+		}
+	}
+	classes := make([]string, 0, len(seen))
+	for class := range seen {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// Counts returns the confusion matrix as a dense table over classes:
+// counts[i][j] is the number of rows of class i predicted as class j.
+func Counts(cm evaluation.ConfusionMatrix, classes []string) [][]float64 {
+	counts := make([][]float64, len(classes))
+	for i, ref := range classes {
+		counts[i] = make([]float64, len(classes))
+		for j, pred := range classes {
+			counts[i][j] = float64(cm[ref][pred])
+		}
+	}
+	return counts
+}
+
+// PerClass returns the precision, recall, F1 score and specificity of
+// every class of the confusion matrix, in the order of Classes.
+func PerClass(cm evaluation.ConfusionMatrix) []ClassMetrics {
+	classes := Classes(cm)
+	counts := Counts(cm, classes)
+	var total float64
+	for _, row := range counts {
+		for _, count := range row {
+			total += count
+		}
+	}
+	out := make([]ClassMetrics, len(classes))
+	for i, class := range classes {
+		var predicted, actual float64
+		for j := range classes {
+			predicted += counts[j][i]
+			actual += counts[i][j]
+		}
+		// Count the class against all other classes.
+		b := Binary{
+			TruePositives:  int(counts[i][i]),
+			FalsePositives: int(predicted - counts[i][i]),
+			FalseNegatives: int(actual - counts[i][i]),
+			TrueNegatives:  int(total - predicted - actual + counts[i][i]),
+		}
+		out[i] = ClassMetrics{
+			Class:       class,
+			Precision:   b.Precision(),
+			Recall:      b.Recall(),
+			F1:          b.F1(),
+			Specificity: b.Specificity(),
+			Support:     int(actual),
+		}
+	}
+	return out
+}
+
+// Summarize returns the scores of the whole confusion matrix: the
+// accuracy, the balanced accuracy (the mean recall of the classes) and
+// the macro averages of the per-class precision, recall, F1 score and
+// specificity.
+func Summarize(cm evaluation.ConfusionMatrix) map[string]float64 {
+	scores := PerClass(cm)
+	var precision, recall, f1, specificity float64
+	for _, m := range scores {
+		precision += m.Precision
+		recall += m.Recall
+		f1 += m.F1
+		specificity += m.Specificity
+	}
+	n := float64(len(scores))
+	if n == 0 {
+		n = 1
+	}
+	return map[string]float64{
+		"accuracy":          evaluation.GetAccuracy(cm),
+		"balanced_accuracy": recall / n,
+		"macro_precision":   precision / n,
+		"macro_recall":      recall / n,
+		"macro_f1":          f1 / n,
+		"macro_specificity": specificity / n,
+	}
+}
+
+// WriteSummary prints the scores returned by Summarize.
+func WriteSummary(w io.Writer, summary map[string]float64) error {
+	_, err := fmt.Fprintf(w, "Balanced accuracy = %0.2f\nMacro precision = %0.2f\nMacro recall = %0.2f\nMacro F1 = %0.2f\nMacro specificity = %0.2f\n",
+		summary["balanced_accuracy"], summary["macro_precision"], summary["macro_recall"], summary["macro_f1"], summary["macro_specificity"])
+	return err
+}
+
+// WriteReport prints the per-class scores as a table.
+func WriteReport(w io.Writer, scores []ClassMetrics) error {
+	if _, err := fmt.Fprintf(w, "%-20s %9s %9s %9s %11s %9s\n", "class", "precision", "recall", "f1", "specificity", "support"); err != nil {
+		return err
+	}
+	for _, m := range scores {
+		if _, err := fmt.Fprintf(w, "%-20s %9.2f %9.2f %9.2f %11.2f %9d\n", m.Class, m.Precision, m.Recall, m.F1, m.Specificity, m.Support); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClassColumns names the columns of the rows returned by ClassRows.
+var ClassColumns = []string{"class", "precision", "recall", "f1", "specificity", "support"}
+
+// ClassRows returns the per-class scores as table rows, one per class.
+func ClassRows(scores []ClassMetrics) [][]any {
+	rows := make([][]any, len(scores))
+	for i, m := range scores {
+		rows[i] = []any{m.Class, m.Precision, m.Recall, m.F1, m.Specificity, m.Support}
+	}
+	return rows
+}
+
+// FoldColumns names the columns of the rows returned by FoldRows.
+var FoldColumns = []string{"fold", "accuracy"}
+
+// FoldRows returns the accuracy of every cross-validation fold as table
+// rows, numbering the folds from 1.
+func FoldRows(cv []evaluation.ConfusionMatrix) [][]any {
+	rows := make([][]any, len(cv))
+	for i, cm := range cv {
+		rows[i] = []any{i + 1, evaluation.GetAccuracy(cm)}
+	}
+	return rows
+}
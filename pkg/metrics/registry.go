@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// Func scores the predictions of a model against the observed values.
+// proba holds the predicted probability of class 1 of binary classifiers
+// and is nil for other models; functions that need it return NaN without
+// it.
+type Func func(observed, predicted, proba []float64) float64
+
+// Metric is a named scoring function. Registered metrics appear in the
+// cross-validation and evaluation reports and can be chosen as tuning
+// objectives, next to the built-in ones.
+type Metric struct {
+	Name string
+	// Classifier is set for the metrics of classifiers, and unset for
+	// those of regressions.
+	Classifier bool
+	// HigherIsBetter is set for scores such as the accuracy, and unset
+	// for losses and errors such as the RMSE.
+	HigherIsBetter bool
+	Func           Func
+}
+
+// Objective returns the score oriented so that higher is better, as the
+// tuners maximize: the score, or its opposite for losses.
+func (m Metric) Objective(observed, predicted, proba []float64) float64 {
+	s := m.Func(observed, predicted, proba)
+	if m.HigherIsBetter {
+		return s
+	}
+	return -s
+}
+
+var registry = struct {
+	sync.RWMutex
+	metrics map[string]Metric
+}{metrics: make(map[string]Metric)}
+
+// Register adds a metric to the registry, typically from an init
+// function. Names are unique.
+func Register(m Metric) error {
+	if m.Name == "" || m.Func == nil {
+		return errors.New("metrics: a registered metric needs a name and a function")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.metrics[m.Name]; ok {
+		return fmt.Errorf("metrics: %q is already registered", m.Name)
+	}
+	registry.metrics[m.Name] = m
+	return nil
+}
+
+// MustRegister is like Register but panics on an error.
+func MustRegister(m Metric) {
+	if err := Register(m); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the registered metric of the name.
+func Lookup(name string) (Metric, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	m, ok := registry.metrics[name]
+	if !ok {
+		return Metric{}, fmt.Errorf("metrics: no metric %q is registered", name)
+	}
+	return m, nil
+}
+
+// Registered returns the registered metrics of classifiers or of
+// regressions, sorted by name.
+func Registered(classifier bool) []Metric {
+	registry.RLock()
+	defer registry.RUnlock()
+	var ms []Metric
+	for _, m := range registry.metrics {
+		if m.Classifier == classifier {
+			ms = append(ms, m)
+		}
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Name < ms[j].Name })
+	return ms
+}
+
+// ScoreAll returns the score of every registered metric of classifiers or
+// of regressions, keyed by name.
+func ScoreAll(classifier bool, observed, predicted, proba []float64) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, m := range Registered(classifier) {
+		scores[m.Name] = m.Func(observed, predicted, proba)
+	}
+	return scores
+}
+
+// WriteScores prints the scores of ScoreAll as a table sorted by name.
+// Scores that are NaN, such as those needing probabilities the model does
+// not give, are left out.
+func WriteScores(w io.Writer, scores map[string]float64) error {
+	names := make([]string, 0, len(scores))
+	for name, s := range scores {
+		if !math.IsNaN(s) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric\tscore")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%.4f\n", name, scores[name])
+	}
+	return tw.Flush()
+}
+
+// The built-in metrics.
+func init() {
+	MustRegister(Metric{Name: "accuracy", Classifier: true, HigherIsBetter: true, Func: func(observed, predicted, _ []float64) float64 {
+		if len(observed) == 0 || len(predicted) != len(observed) {
+			return math.NaN()
+		}
+		var correct int
+		for i, o := range observed {
+			if predicted[i] == o {
+				correct++
+			}
+		}
+		return float64(correct) / float64(len(observed))
+	}})
+	MustRegister(Metric{Name: "balanced_accuracy", Classifier: true, HigherIsBetter: true, Func: func(observed, predicted, _ []float64) float64 {
+		if len(observed) == 0 || len(predicted) != len(observed) {
+			return math.NaN()
+		}
+		// Average the recall of the observed classes, which a model
+		// predicting the majority class cannot inflate.
+		rows := make(map[float64]int)
+		correct := make(map[float64]int)
+		for i, o := range observed {
+			rows[o]++
+			if predicted[i] == o {
+				correct[o]++
+			}
+		}
+		classes := make([]float64, 0, len(rows))
+		for class := range rows {
+			classes = append(classes, class)
+		}
+		sort.Float64s(classes)
+		var recall float64
+		for _, class := range classes {
+			recall += float64(correct[class]) / float64(rows[class])
+		}
+		return recall / float64(len(rows))
+	}})
+	for _, m := range []struct {
+		name  string
+		score func(observed, scores []float64) (float64, error)
+	}{
+		{"auc", AUC},
+		{"ks", KS},
+		{"gini", Gini},
+	} {
+		score := m.score
+		MustRegister(Metric{Name: m.name, Classifier: true, HigherIsBetter: true, Func: func(observed, _, proba []float64) float64 {
+			s, err := score(observed, proba)
+			if err != nil {
+				return math.NaN()
+			}
+			return s
+		}})
+	}
+	MustRegister(Metric{Name: "log_loss", Classifier: true, Func: func(observed, _, proba []float64) float64 {
+		if len(proba) == 0 || len(proba) != len(observed) {
+			return math.NaN()
+		}
+		var loss float64
+		for i, o := range observed {
+			// Clip the probabilities so that a confident mistake costs a
+			// large but finite loss.
+			p := math.Min(math.Max(proba[i], 1e-15), 1-1e-15)
+			loss -= o*math.Log(p) + (1-o)*math.Log(1-p)
+		}
+		return loss / float64(len(observed))
+	}})
+	for _, m := range []struct {
+		name           string
+		higherIsBetter bool
+		score          func(Regression) float64
+	}{
+		{"mae", false, func(r Regression) float64 { return r.MAE }},
+		{"rmse", false, func(r Regression) float64 { return r.RMSE }},
+		{"r2", true, func(r Regression) float64 { return r.R2 }},
+	} {
+		score := m.score
+		MustRegister(Metric{Name: m.name, HigherIsBetter: m.higherIsBetter, Func: func(observed, predicted, _ []float64) float64 {
+			r, err := RegressionScores(observed, predicted, 0)
+			if err != nil {
+				return math.NaN()
+			}
+			return score(r)
+		}})
+	}
+}
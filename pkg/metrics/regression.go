@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrLengths is returned when the observed and predicted values differ in
+// number or are empty.
+var ErrLengths = errors.New("metrics: observed and predicted values must be non-empty and of the same length")
+
+// Regression holds the scores of the predictions of a regression.
+type Regression struct {
+	// MAE is the mean absolute error.
+	MAE float64
+	// RMSE is the root mean squared error, in the units of the target.
+	RMSE float64
+	// R2 is the coefficient of determination: the share of the variance
+	// of the observed values explained by the predictions.
+	R2 float64
+	// AdjustedR2 is R2 penalized for the number of features, so that
+	// models with more features can be compared. It is NaN when there
+	// are not more rows than features plus one.
+	AdjustedR2 float64
+	// MAPE is the mean absolute percentage error, in percent, over the
+	// rows whose observed value is not 0. It is NaN when every observed
+	// value is 0.
+	MAPE float64
+}
+
+// RegressionScores scores the predicted values against the observed ones
+// of a model with numFeatures features, not counting the intercept.
+func RegressionScores(observed, predicted []float64, numFeatures int) (Regression, error) {
+	n := len(observed)
+	if n == 0 || len(predicted) != n {
+		return Regression{}, ErrLengths
+	}
+	// Accumulate the errors and the mean of the observed values.
+	var absErr, sqErr, pctErr, mean float64
+	var numPct int
+	for i, o := range observed {
+		e := o - predicted[i]
+		absErr += math.Abs(e)
+		sqErr += e * e
+		if o != 0 {
+			pctErr += math.Abs(e / o)
+			numPct++
+		}
+		mean += o
+	}
+	mean /= float64(n)
+	// The total sum of squares around the mean.
+	var sqTotal float64
+	for _, o := range observed {
+		sqTotal += (o - mean) * (o - mean)
+	}
+	r := Regression{
+		MAE:        absErr / float64(n),
+		RMSE:       math.Sqrt(sqErr / float64(n)),
+		R2:         1 - sqErr/sqTotal,
+		AdjustedR2: math.NaN(),
+		MAPE:       math.NaN(),
+	}
+	if dof := n - numFeatures - 1; dof > 0 {
+		r.AdjustedR2 = 1 - (1-r.R2)*float64(n-1)/float64(dof)
+	}
+	if numPct > 0 {
+		r.MAPE = 100 * pctErr / float64(numPct)
+	}
+	return r, nil
+}
+
+// Map returns the scores keyed by the metric names used in the run
+// metrics files.
+func (r Regression) Map() map[string]float64 {
+	return map[string]float64{
+		"mae":         r.MAE,
+		"rmse":        r.RMSE,
+		"r2":          r.R2,
+		"adjusted_r2": r.AdjustedR2,
+		"mape":        r.MAPE,
+	}
+}
+
+// WriteRegression prints the scores, one per line.
+func WriteRegression(w io.Writer, r Regression) {
+	fmt.Fprintf(w, "MAE = %0.2f\n", r.MAE)
+	fmt.Fprintf(w, "RMSE = %0.2f\n", r.RMSE)
+	fmt.Fprintf(w, "R^2 = %0.4f\n", r.R2)
+	fmt.Fprintf(w, "Adjusted R^2 = %0.4f\n", r.AdjustedR2)
+	fmt.Fprintf(w, "MAPE = %0.2f%%\n", r.MAPE)
+}
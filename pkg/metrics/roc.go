@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrNaNScore is returned for scores that hold a NaN, which cannot be
+// ranked.
+var ErrNaNScore = errors.New("metrics: scores must not be NaN")
+
+// ROCCurve is a receiver operating characteristic curve: the false and
+// true positive rates obtained by predicting the positive class from
+// every threshold, from the highest score down.
+type ROCCurve struct {
+	// FPR and TPR are the false and true positive rates of every point,
+	// starting at (0, 0) and ending at (1, 1).
+	FPR, TPR []float64
+	// Thresholds holds the score from which rows are predicted positive
+	// at every point. The first point, which predicts no positive, has
+	// a threshold of +Inf.
+	Thresholds []float64
+}
+
+// ROC returns the ROC curve of the scores, where observed holds 1.0 for
+// the positive class and any other value for the negative class. Tied
+// scores are added together, as a single diagonal step. It returns
+// ErrLengths unless there is a score for every observed value, and
+// ErrNaNScore when a score is NaN.
+func ROC(observed, scores []float64) (ROCCurve, error) {
+	if len(observed) == 0 || len(scores) != len(observed) {
+		return ROCCurve{}, ErrLengths
+	}
+	for _, s := range scores {
+		if math.IsNaN(s) {
+			return ROCCurve{}, ErrNaNScore
+		}
+	}
+	order := descending(scores)
+	var numPos, numNeg float64
+	for _, o := range observed {
+		if o == 1.0 {
+			numPos++
+			continue
+		}
+		numNeg++
+	}
+	curve := ROCCurve{FPR: []float64{0}, TPR: []float64{0}, Thresholds: []float64{inf}}
+	var tp, fp float64
+	for i := 0; i < len(order); {
+		// Consume the rows tied at this score together.
+		j := i
+		for ; j < len(order) && scores[order[j]] == scores[order[i]]; j++ {
+			if observed[order[j]] == 1.0 {
+				tp++
+				continue
+			}
+			fp++
+		}
+		curve.FPR = append(curve.FPR, ratioOf(fp, numNeg))
+		curve.TPR = append(curve.TPR, ratioOf(tp, numPos))
+		curve.Thresholds = append(curve.Thresholds, scores[order[i]])
+		i = j
+	}
+	return curve, nil
+}
+
+// AUC returns the area under the ROC curve of the scores: the
+// probability that a random positive row is scored above a random
+// negative one, with ties counting as one half. It returns the errors of
+// ROC.
+func AUC(observed, scores []float64) (float64, error) {
+	curve, err := ROC(observed, scores)
+	if err != nil {
+		return 0, err
+	}
+	var area float64
+	for i := 1; i < len(curve.FPR); i++ {
+		// Add the trapezoid under every step, which counts ties as one half.
+		area += (curve.FPR[i] - curve.FPR[i-1]) * (curve.TPR[i] + curve.TPR[i-1]) / 2
+	}
+	return area, nil
+}
+
+// KS returns the Kolmogorov-Smirnov statistic of the scores, the largest
+// distance between the score distributions of the positive and negative
+// rows, which is the largest distance of the ROC curve from its diagonal.
+// It returns the errors of ROC.
+func KS(observed, scores []float64) (float64, error) {
+	curve, err := ROC(observed, scores)
+	if err != nil {
+		return 0, err
+	}
+	var ks float64
+	for i := range curve.FPR {
+		ks = math.Max(ks, math.Abs(curve.TPR[i]-curve.FPR[i]))
+	}
+	return ks, nil
+}
+
+// Gini returns the Gini coefficient of the scores, also known as the
+// accuracy ratio, which equals 2*AUC - 1. It returns the errors of ROC.
+func Gini(observed, scores []float64) (float64, error) {
+	auc, err := AUC(observed, scores)
+	if err != nil {
+		return 0, err
+	}
+	return 2*auc - 1, nil
+}
+
+// YoudenThreshold returns the threshold of the point of the curve with
+// the largest Youden index, TPR - FPR, which is the point farthest above
+// the diagonal of a random classifier, and that index. Among tied points
+// the first, with the highest threshold, is returned.
+func (c ROCCurve) YoudenThreshold() (threshold, index float64) {
+	threshold, index = inf, 0
+	for i := 1; i < len(c.FPR); i++ {
+		if j := c.TPR[i] - c.FPR[i]; j > index {
+			threshold, index = c.Thresholds[i], j
+		}
+	}
+	return threshold, index
+}
+
+// inf is the threshold of the first point of a ROC curve.
+var inf = math.Inf(1)
+
+// ratioOf returns num / den, or 0 when den is 0.
+func ratioOf(num, den float64) float64 {
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
+
+// descending returns the indices of the scores from highest to lowest.
+func descending(scores []float64) []int {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+	return order
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAUC(t *testing.T) {
+	observed := []float64{1, 0, 1, 0}
+	// One positive above both negatives and one tied with a negative.
+	auc, err := AUC(observed, []float64{0.9, 0.4, 0.4, 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auc != 0.875 {
+		t.Errorf("AUC = %g, want 0.875", auc)
+	}
+}
+
+func TestROCRejectsNaNScores(t *testing.T) {
+	// A NaN equals no score, not even itself, so it could not be consumed
+	// as a tie.
+	_, err := ROC([]float64{1, 0, 1}, []float64{0.8, math.NaN(), 0.3})
+	if !errors.Is(err, ErrNaNScore) {
+		t.Errorf("got %v, want ErrNaNScore", err)
+	}
+	if _, err := AUC([]float64{1, 0}, []float64{0.5}); !errors.Is(err, ErrLengths) {
+		t.Errorf("got %v, want ErrLengths", err)
+	}
+}
+
+func TestKSAndGini(t *testing.T) {
+	observed := []float64{1, 0, 1, 0}
+	scores := []float64{0.9, 0.4, 0.4, 0.1}
+	// Above 0.4, half the positives and none of the negatives are
+	// predicted positive, and the tie at 0.4 adds one of each.
+	ks, err := KS(observed, scores)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks != 0.5 {
+		t.Errorf("KS = %g, want 0.5", ks)
+	}
+	gini, err := Gini(observed, scores)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gini != 0.75 {
+		t.Errorf("Gini = %g, want 0.75", gini)
+	}
+	// Perfectly separated scores give both their largest value.
+	separated := []float64{0.8, 0.2, 0.7, 0.3}
+	if ks, _ := KS(observed, separated); ks != 1 {
+		t.Errorf("separated KS = %g, want 1", ks)
+	}
+	if gini, _ := Gini(observed, separated); gini != 1 {
+		t.Errorf("separated Gini = %g, want 1", gini)
+	}
+	if _, err := KS(observed, []float64{0.8, math.NaN(), 0.7, 0.3}); !errors.Is(err, ErrNaNScore) {
+		t.Errorf("got %v, want ErrNaNScore", err)
+	}
+}
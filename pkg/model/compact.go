@@ -0,0 +1,272 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/inference"
+)
+
+// CompactMagic starts every compact model file.
+const CompactMagic = inference.CompactMagic
+
+// compactVersion is the version of the compact files written by
+// WriteCompact.
+const compactVersion = inference.CompactVersion
+
+// Precision is the number format of the weights of a compact model file.
+type Precision uint8
+
+const (
+	// Float16 stores every weight as an IEEE 754 half precision float,
+	// with about three significant digits.
+	Float16 Precision = iota + 1
+	// Int8 stores every weight as a signed byte times a scale shared by
+	// the weights of the model, the largest weight mapping to 127.
+	Int8
+)
+
+// String returns the name of the precision.
+func (p Precision) String() string {
+	switch p {
+	case Float16:
+		return "float16"
+	case Int8:
+		return "int8"
+	}
+	return fmt.Sprintf("Precision(%d)", uint8(p))
+}
+
+// ParsePrecision returns the precision of the name, float16 or int8.
+func ParsePrecision(name string) (Precision, error) {
+	switch name {
+	case "float16":
+		return Float16, nil
+	case "int8":
+		return Int8, nil
+	}
+	return 0, fmt.Errorf("model: unknown precision %q, expected float16 or int8", name)
+}
+
+// Kinds of the models in compact files, as inference.DecodeCompact reads
+// them.
+const (
+	compactLogistic = 1
+	compactLinear   = 2
+)
+
+// Flags of the optional sections of compact files.
+const (
+	compactStandardized = 1 << iota
+	compactCalibrated
+)
+
+// WriteCompact writes a *Logistic or *Linear model to w in the compact
+// binary format, with its weights quantized to the precision. The
+// standardization, intercept, threshold and calibration are kept as
+// 32-bit floats; the feature weights of a standardized model share a
+// scale, which suits int8 well. The format is little endian:
+//
+//	"GMLC" version kind precision flags
+//	target and feature names, each as a uvarint length and its bytes
+//	shift and scale of every feature (standardized models)
+//	weights: an int8 scale and a byte each, or two bytes each for float16
+//	intercept, then threshold and calibration (logistic regressions)
+//
+// A compact file of a few features takes some tens of bytes, so it can
+// be embedded in a program with go:embed and read with ReadCompact.
+func WriteCompact(w io.Writer, m any, p Precision) error {
+	if p != Float16 && p != Int8 {
+		return fmt.Errorf("model: invalid precision %v", p)
+	}
+	var kind, flags byte
+	var target string
+	var features []string
+	var shift, scale, weights []float64
+	var intercept, threshold float64
+	var calibration *Platt
+	switch m := m.(type) {
+	case *Logistic:
+		if len(m.Weights) != len(m.Features)+1 {
+			return fmt.Errorf("model: %d weights for %d features", len(m.Weights), len(m.Features))
+		}
+		kind, target, features = compactLogistic, m.Target, m.Features
+		shift, scale = m.Shift, m.Scale
+		weights, intercept = m.Weights[:len(m.Features)], m.Weights[len(m.Features)]
+		threshold, calibration = m.Threshold, m.Calibration
+	case *Linear:
+		if len(m.Coefficients) != len(m.Features) {
+			return fmt.Errorf("model: %d coefficients for %d features", len(m.Coefficients), len(m.Features))
+		}
+		kind, target, features = compactLinear, m.Target, m.Features
+		weights, intercept = m.Coefficients, m.Intercept
+	default:
+		return fmt.Errorf("model: cannot write a %T compactly, only logistic and linear regressions", m)
+	}
+	if shift != nil {
+		flags |= compactStandardized
+	}
+	if calibration != nil {
+		flags |= compactCalibrated
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(CompactMagic)
+	bw.Write([]byte{compactVersion, kind, byte(p), flags})
+	writeString(bw, target)
+	writeUvarint(bw, uint64(len(features)))
+	for _, name := range features {
+		writeString(bw, name)
+	}
+	if shift != nil {
+		writeFloat32s(bw, shift...)
+		writeFloat32s(bw, scale...)
+	}
+	switch p {
+	case Float16:
+		for _, w := range weights {
+			binary.Write(bw, binary.LittleEndian, float16Bits(w))
+		}
+	case Int8:
+		s := int8Scale(weights)
+		writeFloat32s(bw, s)
+		for _, w := range weights {
+			bw.WriteByte(byte(int8(math.Round(w / s))))
+		}
+	}
+	writeFloat32s(bw, intercept)
+	if kind == compactLogistic {
+		writeFloat32s(bw, threshold)
+		if calibration != nil {
+			writeFloat32s(bw, calibration.A, calibration.B)
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadCompact reads a model written by WriteCompact, returning a
+// *Logistic or *Linear with the weights the file stores. It decodes with
+// inference.DecodeCompact.
+func ReadCompact(r io.Reader) (any, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := inference.DecodeCompact(data)
+	if errors.Is(err, inference.ErrCompact) {
+		return nil, fmt.Errorf("%w: not a compact model", ErrFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SaveCompact writes the model to the file at path in the compact format.
+func SaveCompact(path string, m any, p Precision) error {
+	var buf bytes.Buffer
+	if err := WriteCompact(&buf, m, p); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// LoadCompact reads the compact model file at path.
+func LoadCompact(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ReadCompact(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// IsCompact reports whether the file at path starts as a compact model.
+func IsCompact(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(CompactMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil
+	}
+	return string(magic) == CompactMagic, nil
+}
+
+// int8Scale returns the scale mapping the largest absolute weight to
+// 127, rounded to a float32 as it is stored.
+func int8Scale(weights []float64) float64 {
+	var largest float64
+	for _, w := range weights {
+		largest = math.Max(largest, math.Abs(w))
+	}
+	if largest == 0 {
+		return 1
+	}
+	return float64(float32(largest / 127))
+}
+
+// float16Bits returns the IEEE 754 half precision bits of f, rounded to
+// the nearest even. Values too large for half precision become
+// infinities.
+func float16Bits(f float64) uint16 {
+	b := math.Float64bits(f)
+	sign := uint16(b>>48) & 0x8000
+	exp := int(b>>52) & 0x7ff
+	mant := b & (1<<52 - 1)
+	if exp == 0x7ff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+	e := exp - 1023 + 15
+	if e >= 31 {
+		return sign | 0x7c00
+	}
+	var half, rem, halfway uint64
+	if e <= 0 {
+		// Subnormal: shift the mantissa with its leading one.
+		if e < -10 {
+			return sign
+		}
+		mant |= 1 << 52
+		shift := uint(43 - e)
+		half, rem, halfway = mant>>shift, mant&(1<<shift-1), 1<<(shift-1)
+	} else {
+		half, rem, halfway = uint64(e)<<10|mant>>42, mant&(1<<42-1), 1<<41
+	}
+	// A carry out of the mantissa correctly bumps the exponent.
+	if rem > halfway || rem == halfway && half&1 == 1 {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// writeString writes the length of s as a uvarint and its bytes.
+func writeString(w *bufio.Writer, s string) {
+	writeUvarint(w, uint64(len(s)))
+	w.WriteString(s)
+}
+
+// writeUvarint writes x as a uvarint.
+func writeUvarint(w *bufio.Writer, x uint64) {
+	w.Write(binary.AppendUvarint(nil, x))
+}
+
+// writeFloat32s writes the values as little endian 32-bit floats.
+func writeFloat32s(w *bufio.Writer, values ...float64) {
+	for _, v := range values {
+		binary.Write(w, binary.LittleEndian, math.Float32bits(float32(v)))
+	}
+}
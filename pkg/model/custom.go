@@ -0,0 +1,136 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Estimator is a model of a kind registered by another package, which
+// programs such as gomlearn fit, save and load like the built-in kinds.
+// It is saved as JSON, so its fitted state must encode to JSON and
+// decode back into a new estimator of its kind.
+type Estimator interface {
+	// Fit fits the estimator on the rows of x and their targets y,
+	// labels 0 and 1 for classifiers.
+	Fit(ctx context.Context, x *mat64.Dense, y []float64) error
+	// Predict returns the prediction of a row of features.
+	Predict(row []float64) (float64, error)
+}
+
+// Prober is implemented by estimators of classifiers that predict the
+// probability of class 1.
+type Prober interface {
+	Probability(row []float64) (float64, error)
+}
+
+// Registration describes a registered kind of estimator.
+type Registration struct {
+	// Kind names the kind in model files and on command lines.
+	Kind string
+	// Classifier is set for estimators predicting the labels 0 and 1,
+	// and unset for regressions.
+	Classifier bool
+	// New returns a new estimator, to fit or to decode a model file into.
+	New func() Estimator
+}
+
+var registry = struct {
+	sync.RWMutex
+	kinds map[string]Registration
+}{kinds: make(map[string]Registration)}
+
+// Register adds a kind of estimator to the registry, typically from an
+// init function. Kinds are unique and cannot be those of this package.
+func Register(r Registration) error {
+	if r.Kind == "" || r.New == nil {
+		return errors.New("model: a registered estimator needs a kind and a constructor")
+	}
+	switch r.Kind {
+	case KindLogistic, KindLinear, KindTree, KindForest, KindPipeline:
+		return fmt.Errorf("model: %q is a built-in kind", r.Kind)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.kinds[r.Kind]; ok {
+		return fmt.Errorf("model: %q is already registered", r.Kind)
+	}
+	registry.kinds[r.Kind] = r
+	return nil
+}
+
+// MustRegister is like Register but panics on an error.
+func MustRegister(r Registration) {
+	if err := Register(r); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the registration of the kind, and whether it is
+// registered.
+func Lookup(kind string) (Registration, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	r, ok := registry.kinds[kind]
+	return r, ok
+}
+
+// Registered returns the registered kinds, sorted.
+func Registered() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	kinds := make([]string, 0, len(registry.kinds))
+	for kind := range registry.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Custom is a fitted estimator of a registered kind, as saved in model
+// files of that kind.
+type Custom struct {
+	// Kind is the registered kind of the estimator. It is the kind of the
+	// model file, and not part of the model.
+	Kind     string   `json:"-"`
+	Target   string   `json:"target"`
+	Features []string `json:"features"`
+	// Estimator holds the fitted state of the estimator.
+	Estimator Estimator `json:"estimator"`
+}
+
+// NewCustom returns a model of the registered kind, holding a new
+// estimator to fit or to decode a model file into.
+func NewCustom(kind string) (*Custom, error) {
+	r, ok := Lookup(kind)
+	if !ok {
+		return nil, fmt.Errorf("model: no estimator %q is registered", kind)
+	}
+	return &Custom{Kind: kind, Estimator: r.New()}, nil
+}
+
+// Predict returns the prediction of the estimator for a row of raw
+// values.
+func (m *Custom) Predict(row []float64) (float64, error) {
+	if err := check(m.Features, row); err != nil {
+		return 0, err
+	}
+	return m.Estimator.Predict(row)
+}
+
+// Probability returns the probability of class 1 of the row, for
+// estimators implementing Prober.
+func (m *Custom) Probability(row []float64) (float64, error) {
+	p, ok := m.Estimator.(Prober)
+	if !ok {
+		return 0, fmt.Errorf("model: the %s estimator predicts no probabilities", m.Kind)
+	}
+	if err := check(m.Features, row); err != nil {
+		return 0, err
+	}
+	return p.Probability(row)
+}
@@ -0,0 +1,210 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+const (
+	// FixedPointFormat identifies the fixed-point exports of this package.
+	FixedPointFormat = "go-machine-learning/fixed-point"
+	// FixedPointVersion is the version of the exports written by
+	// WriteFixedPoint.
+	FixedPointVersion = 1
+	// maxFixedPointBits bounds the fractional bits of the coefficients
+	// and of the features, so that the products of the score fit in 64
+	// bits for features of up to 2^31 or so.
+	maxFixedPointBits = 30
+)
+
+// FixedPoint is a linear or logistic regression with integer
+// coefficients, for inference engines that compute on encrypted integers,
+// such as the BFV and CKKS homomorphic encryption schemes, where only
+// additions and multiplications are cheap.
+//
+// Rows are encoded as integers, every raw feature value x as
+// round(x * 2^FeatureBits), and the score of an encoded row is
+//
+//	Intercept + sum_j Coefficients[j] * row[j]
+//
+// all in integers, so it can be computed on encrypted rows. The score is
+// the linear predictor, the log odds for logistic regressions, scaled by
+// 2^(CoefficientBits+FeatureBits). A logistic regression classifies a row
+// as 1 when its score is at least ScoreThreshold, which compares the
+// encrypted score without the logistic function. Any standardization of
+// the features is folded into the coefficients.
+type FixedPoint struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// Kind is the kind of the quantized model, KindLogistic or
+	// KindLinear.
+	Kind string `json:"kind"`
+	// Target names the predicted variable, when known.
+	Target string `json:"target,omitempty"`
+	// Features names the features, in the order of the rows.
+	Features []string `json:"features"`
+	// CoefficientBits and FeatureBits are the fractional bits of the
+	// coefficients and of the encoded feature values.
+	CoefficientBits int `json:"coefficient_bits"`
+	FeatureBits     int `json:"feature_bits"`
+	// Coefficients and Intercept give the integer score. The intercept
+	// is scaled by 2^(CoefficientBits+FeatureBits), like the score.
+	Coefficients []int64 `json:"coefficients"`
+	Intercept    int64   `json:"intercept"`
+	// ScoreThreshold is the smallest score classified as 1 by logistic
+	// regressions, and 0 for linear ones.
+	ScoreThreshold int64 `json:"score_threshold"`
+	// Calibration, when set, maps the probability of the decoded score
+	// to a calibrated one, as in Logistic.
+	Calibration *Platt `json:"calibration,omitempty"`
+	// ScoreBound, when set, is the largest absolute score of the rows
+	// the export was checked on, to size the plaintext modulus of the
+	// encryption scheme, which must exceed twice the largest score.
+	ScoreBound int64 `json:"score_bound,omitempty"`
+}
+
+// Quantize returns the fixed-point form of a *Logistic or *Linear model
+// with the given fractional bits of the coefficients and of the features.
+func Quantize(m any, coefficientBits, featureBits int) (*FixedPoint, error) {
+	for _, bits := range []int{coefficientBits, featureBits} {
+		if bits < 0 || bits > maxFixedPointBits {
+			return nil, fmt.Errorf("model: %d fractional bits, want 0 to %d", bits, maxFixedPointBits)
+		}
+	}
+	fp := &FixedPoint{
+		Format:          FixedPointFormat,
+		Version:         FixedPointVersion,
+		CoefficientBits: coefficientBits,
+		FeatureBits:     featureBits,
+	}
+	// Fold the model into real coefficients and intercept on the raw
+	// features.
+	var coefficients []float64
+	var intercept, threshold float64
+	switch m := m.(type) {
+	case *Logistic:
+		if len(m.Weights) != len(m.Features)+1 {
+			return nil, fmt.Errorf("model: %d weights for %d features", len(m.Weights), len(m.Features))
+		}
+		fp.Kind, fp.Target, fp.Features = KindLogistic, m.Target, m.Features
+		intercept = m.Weights[len(m.Features)]
+		for j, w := range m.Weights[:len(m.Features)] {
+			if m.Shift != nil {
+				w /= m.Scale[j]
+				intercept -= w * m.Shift[j]
+			}
+			coefficients = append(coefficients, w)
+		}
+		var err error
+		if threshold, err = logisticThreshold(m); err != nil {
+			return nil, err
+		}
+		fp.Calibration = m.Calibration
+	case *Linear:
+		if len(m.Coefficients) != len(m.Features) {
+			return nil, fmt.Errorf("model: %d coefficients for %d features", len(m.Coefficients), len(m.Features))
+		}
+		fp.Kind, fp.Target, fp.Features = KindLinear, m.Target, m.Features
+		coefficients, intercept = m.Coefficients, m.Intercept
+	default:
+		return nil, fmt.Errorf("model: cannot quantize a %T, only logistic and linear regressions", m)
+	}
+	for _, c := range coefficients {
+		fp.Coefficients = append(fp.Coefficients, int64(math.Round(math.Ldexp(c, coefficientBits))))
+	}
+	fp.Intercept = int64(math.Round(intercept * fp.scale()))
+	if fp.Kind == KindLogistic {
+		fp.ScoreThreshold = int64(math.Ceil(threshold * fp.scale()))
+	}
+	return fp, nil
+}
+
+// logisticThreshold returns the log odds from which the logistic
+// regression classifies rows as 1, before calibration.
+func logisticThreshold(m *Logistic) (float64, error) {
+	z := LogOdds(m.Threshold)
+	if c := m.Calibration; c != nil {
+		// The calibrated probability grows with the log odds only for a
+		// positive slope.
+		if c.A <= 0 {
+			return 0, fmt.Errorf("model: cannot quantize the threshold of a calibration of slope %g", c.A)
+		}
+		z = (z - c.B) / c.A
+	}
+	return z, nil
+}
+
+// scale returns the scale of the scores, 2^(CoefficientBits+FeatureBits).
+func (fp *FixedPoint) scale() float64 {
+	return math.Ldexp(1, fp.CoefficientBits+fp.FeatureBits)
+}
+
+// Encode returns the integer encoding of the row of raw feature values.
+func (fp *FixedPoint) Encode(row []float64) ([]int64, error) {
+	if err := check(fp.Features, row); err != nil {
+		return nil, err
+	}
+	encoded := make([]int64, len(row))
+	for j, x := range row {
+		v := math.Round(math.Ldexp(x, fp.FeatureBits))
+		if math.Abs(v) >= 1<<62 || math.IsNaN(v) {
+			return nil, fmt.Errorf("model: feature %s: %g does not fit in %d fractional bits", fp.Features[j], x, fp.FeatureBits)
+		}
+		encoded[j] = int64(v)
+	}
+	return encoded, nil
+}
+
+// Score returns the integer score of an encoded row, as an encrypted
+// inference engine computes it.
+func (fp *FixedPoint) Score(encoded []int64) (int64, error) {
+	if len(encoded) != len(fp.Coefficients) {
+		return 0, fmt.Errorf("model: row has %d features, the model %d", len(encoded), len(fp.Coefficients))
+	}
+	score := fp.Intercept
+	for j, x := range encoded {
+		score += fp.Coefficients[j] * x
+	}
+	return score, nil
+}
+
+// Decode returns the prediction of a score: the value of linear
+// regressions, and the probability of class 1 of logistic regressions.
+func (fp *FixedPoint) Decode(score int64) float64 {
+	z := float64(score) / fp.scale()
+	if fp.Kind != KindLogistic {
+		return z
+	}
+	p := 1 / (1 + math.Exp(-z))
+	if fp.Calibration != nil {
+		p = fp.Calibration.Apply(p)
+	}
+	return p
+}
+
+// WriteFixedPoint writes the fixed-point model as indented JSON.
+func WriteFixedPoint(path string, fp *FixedPoint) error {
+	data, err := json.MarshalIndent(fp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadFixedPoint reads a fixed-point model written by WriteFixedPoint.
+func ReadFixedPoint(path string) (*FixedPoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fp FixedPoint
+	if err := json.Unmarshal(data, &fp); err != nil || fp.Format != FixedPointFormat {
+		return nil, fmt.Errorf("%w: %s is not a fixed-point export", ErrFormat, path)
+	}
+	if fp.Version < 1 || fp.Version > FixedPointVersion {
+		return nil, fmt.Errorf("model: %s has version %d, this program reads up to %d", path, fp.Version, FixedPointVersion)
+	}
+	return &fp, nil
+}
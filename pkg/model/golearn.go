@@ -0,0 +1,58 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Golearn is a golearn model with its own serialization, such as
+// knn.KNNClassifier or trees.ID3DecisionTree.
+type Golearn interface {
+	Save(path string) error
+	Load(path string) error
+}
+
+// golearnFile is the model of a golearn model file: the bytes written by
+// the Save method of the model.
+type golearnFile struct {
+	Data []byte `json:"data"`
+}
+
+// SaveGolearn writes the golearn model to path with a header of the given
+// kind, embedding the serialization of golearn.
+func SaveGolearn(path, kind string, m Golearn) error {
+	dir, err := os.MkdirTemp("", "golearn-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "model")
+	if err := m.Save(tmp); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(tmp)
+	if err != nil {
+		return err
+	}
+	return Save(path, kind, golearnFile{Data: data})
+}
+
+// LoadGolearn loads a model saved by SaveGolearn into m, after checking
+// that it holds a model of the given kind.
+func LoadGolearn(path, kind string, m Golearn) (Header, error) {
+	var f golearnFile
+	h, err := Load(path, kind, &f)
+	if err != nil {
+		return Header{}, err
+	}
+	dir, err := os.MkdirTemp("", "golearn-*")
+	if err != nil {
+		return Header{}, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "model")
+	if err := os.WriteFile(tmp, f.Data, 0o600); err != nil {
+		return Header{}, err
+	}
+	return h, m.Load(tmp)
+}
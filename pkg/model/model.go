@@ -0,0 +1,137 @@
+// Package model saves trained models to disk and loads them back, so that
+// programs can predict without retraining. Every file is JSON and starts
+// with a header naming the file format, its version and the kind of
+// model, which Load checks before decoding the model. Logistic and linear
+// regressions are stored as plain weights, readable by any language, CART
+// trees and random forests as their nodes, and golearn models are
+// embedded in their own serialization. Register adds kinds of Estimator
+// from other packages, saved as Custom. A Pipeline stores a model along
+// with the preprocessing that turns raw rows into its features.
+// The Contributions methods of logistic and linear regressions explain a
+// prediction by how much every feature moves it from a baseline row.
+//
+// The Protected variants of the functions encrypt model files with
+// AES-GCM, sign them with Ed25519, or both, so that sensitive models can
+// be stored and shipped without being read or changed on the way.
+// Loading decrypts the file and, when a verification key is given,
+// refuses files that are not signed by its private key.
+//
+// Quantize converts logistic and linear regressions to a fixed-point
+// integer form for encrypted inference engines, written with
+// WriteFixedPoint as a separate JSON format. WriteCompact writes them in
+// a compact binary format with int8 or float16 weights, for devices with
+// little memory.
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// Format identifies the model files of this package.
+	Format = "go-machine-learning/model"
+	// Version is the version of the files written by Save. Load reads
+	// files of this version and earlier.
+	Version = 1
+)
+
+// ErrFormat is returned when loading a file that is not a model file.
+var ErrFormat = errors.New("model: not a model file")
+
+// Header describes a model file.
+type Header struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// Kind names the type of the model, such as "logistic".
+	Kind    string    `json:"kind"`
+	Created time.Time `json:"created"`
+}
+
+// file is the content of a model file.
+type file struct {
+	Header
+	Model json.RawMessage `json:"model"`
+}
+
+// Save writes the model, encoded as JSON, to path with a header of the
+// given kind.
+func Save(path, kind string, m any) error {
+	return SaveProtected(path, kind, m, Protection{})
+}
+
+// SaveProtected is like Save, but encrypts and signs the file as p asks.
+func SaveProtected(path, kind string, m any, p Protection) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(file{
+		Header: Header{Format: Format, Version: Version, Kind: kind, Created: time.Now().UTC()},
+		Model:  data,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if data, err = p.seal(data); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// read reads and checks the model file at path, opening it with p.
+func read(path string, p Protection) (*file, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = p.open(path, data); err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil || f.Format != Format {
+		return nil, fmt.Errorf("%w: %s", ErrFormat, path)
+	}
+	if f.Version < 1 || f.Version > Version {
+		return nil, fmt.Errorf("model: %s has version %d, this program reads up to %d", path, f.Version, Version)
+	}
+	return &f, nil
+}
+
+// ReadHeader returns the header of the model file at path.
+func ReadHeader(path string) (Header, error) {
+	return ReadHeaderProtected(path, Protection{})
+}
+
+// ReadHeaderProtected is like ReadHeader, but opens the file with p.
+func ReadHeaderProtected(path string, p Protection) (Header, error) {
+	f, err := read(path, p)
+	if err != nil {
+		return Header{}, err
+	}
+	return f.Header, nil
+}
+
+// Load decodes the model of the file at path into m, after checking that
+// it holds a model of the given kind. It returns the header of the file.
+func Load(path, kind string, m any) (Header, error) {
+	return LoadProtected(path, kind, m, Protection{})
+}
+
+// LoadProtected is like Load, but opens the file with p.
+func LoadProtected(path, kind string, m any, p Protection) (Header, error) {
+	f, err := read(path, p)
+	if err != nil {
+		return Header{}, err
+	}
+	if f.Kind != kind {
+		return Header{}, fmt.Errorf("model: %s holds a %s model, not %s", path, f.Kind, kind)
+	}
+	if err := json.Unmarshal(f.Model, m); err != nil {
+		return Header{}, fmt.Errorf("model: %s: %v", path, err)
+	}
+	return f.Header, nil
+}
@@ -0,0 +1,450 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/transform"
+	"github.com/go-gota/gota/dataframe"
+	"github.com/gonum/matrix/mat64"
+)
+
+// KindPipeline is the kind of the models of type Pipeline.
+const KindPipeline = "pipeline"
+
+// Encodings of the categorical columns of a Pipeline.
+const (
+	// EncodingOneHot encodes the categories with transform.OneHot.
+	EncodingOneHot = "onehot"
+	// EncodingOrdinal encodes the categories with transform.Ordinal.
+	EncodingOrdinal = "ordinal"
+)
+
+// Step is a preprocessing step of a Pipeline: a transformer of package
+// transform, along with the name New returns it by, which decodes it.
+type Step struct {
+	Name        string
+	Transformer transform.Transformer
+}
+
+// NewStep returns the step of a new transformer of the name, such as
+// standard, minmax, impute or a registered step.
+func NewStep(name string) (Step, error) {
+	t, err := transform.New(name)
+	if err != nil {
+		return Step{}, err
+	}
+	return Step{Name: name, Transformer: t}, nil
+}
+
+// savedStep is a Step as saved in model files.
+type savedStep struct {
+	Name  string          `json:"step"`
+	State json.RawMessage `json:"state"`
+}
+
+// MarshalJSON saves the name of the step and the state of its
+// transformer.
+func (s Step) MarshalJSON() ([]byte, error) {
+	state, err := json.Marshal(s.Transformer)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(savedStep{Name: s.Name, State: state})
+}
+
+// UnmarshalJSON decodes the state into a new transformer of the name.
+func (s *Step) UnmarshalJSON(data []byte) error {
+	var saved savedStep
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	t, err := transform.New(saved.Name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(saved.State, t); err != nil {
+		return fmt.Errorf("model: step %s: %v", saved.Name, err)
+	}
+	s.Name, s.Transformer = saved.Name, t
+	return nil
+}
+
+// Pipeline chains the preprocessing of raw rows with a model, and is
+// saved as a single model file of kind pipeline, so that the rows a
+// program predicts go through the very transformations of training. The
+// numeric columns are read with transform.ParseFloat, which leaves the
+// missing values NaN for an impute step, and the categorical columns are
+// encoded after them. A column transformer, when set, makes the features
+// instead, routing every column to its own step. The steps then transform
+// the features in order.
+//
+// Fit fits the preprocessing and an estimator of a registered kind on a
+// data frame. Programs fitting other kinds fit them on the features of
+// FitTransform and attach them with SetModel.
+type Pipeline struct {
+	// Target names the predicted column.
+	Target string `json:"target"`
+	// Numeric and Categorical name the numeric and the text columns the
+	// features are made from.
+	Numeric     []string `json:"numeric,omitempty"`
+	Categorical []string `json:"categorical,omitempty"`
+	// Encoding encodes the categorical columns, EncodingOneHot if empty.
+	Encoding string `json:"encoding,omitempty"`
+	// OneHot or Ordinal is the fitted encoder of the categorical columns.
+	OneHot  *transform.OneHot  `json:"onehot,omitempty"`
+	Ordinal *transform.Ordinal `json:"ordinal,omitempty"`
+	// Columns, when set, makes the features from the raw columns in place
+	// of Numeric and Categorical.
+	Columns *transform.ColumnTransformer `json:"columns,omitempty"`
+	// Steps transform the features in order. Every step keeps the number
+	// of features.
+	Steps []Step `json:"steps,omitempty"`
+	// Features names the features the model is fitted on: the numeric
+	// columns, then the encoded ones, or the features of Columns.
+	Features []string `json:"features"`
+	// Kind is the kind of the model, and Model the model as saved in the
+	// files of its kind.
+	Kind  string          `json:"kind"`
+	Model json.RawMessage `json:"model"`
+
+	// model is the model of Model, once fitted or decoded.
+	model any
+}
+
+// encoder returns the encoder of the categorical columns, nil without
+// any.
+func (p *Pipeline) encoder() (transform.Categorical, error) {
+	if len(p.Categorical) == 0 {
+		return nil, nil
+	}
+	switch p.Encoding {
+	case EncodingOneHot, "":
+		if p.OneHot == nil {
+			p.OneHot = &transform.OneHot{}
+		}
+		return p.OneHot, nil
+	case EncodingOrdinal:
+		if p.Ordinal == nil {
+			p.Ordinal = &transform.Ordinal{}
+		}
+		return p.Ordinal, nil
+	}
+	return nil, fmt.Errorf("model: unknown encoding %q, expected onehot or ordinal", p.Encoding)
+}
+
+// FitTransform fits the encoder and the steps on the rows of a file with
+// the header, and returns the features of the rows.
+func (p *Pipeline) FitTransform(header []string, rows [][]string) (*mat64.Dense, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("model: no rows")
+	}
+	if p.Columns != nil {
+		if err := p.Columns.Fit(header, rows, p.Target); err != nil {
+			return nil, err
+		}
+		p.Features = slices.Clone(p.Columns.Names)
+		return p.fitSteps(header, rows)
+	}
+	if len(p.Numeric)+len(p.Categorical) == 0 {
+		return nil, errors.New("model: the pipeline reads no column")
+	}
+	enc, err := p.encoder()
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		indices, err := columnIndices(header, p.Categorical)
+		if err != nil {
+			return nil, err
+		}
+		values := make([][]string, len(rows))
+		for i, row := range rows {
+			values[i] = pick(row, indices)
+		}
+		if err := enc.Fit(values); err != nil {
+			return nil, err
+		}
+	}
+	p.Features = slices.Clone(p.Numeric)
+	if enc != nil {
+		p.Features = append(p.Features, enc.Names(p.Categorical)...)
+	}
+	return p.fitSteps(header, rows)
+}
+
+// fitSteps fits the steps in order on the encoded rows, and returns their
+// output.
+func (p *Pipeline) fitSteps(header []string, rows [][]string) (*mat64.Dense, error) {
+	x, err := p.encode(header, rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range p.Steps {
+		if err := s.Transformer.Fit(x); err != nil {
+			return nil, fmt.Errorf("model: step %s: %v", s.Name, err)
+		}
+		if x, err = s.Transformer.Transform(x); err != nil {
+			return nil, fmt.Errorf("model: step %s: %v", s.Name, err)
+		}
+	}
+	return x, nil
+}
+
+// Transform returns the features of the rows of a file with the header.
+func (p *Pipeline) Transform(header []string, rows [][]string) (*mat64.Dense, error) {
+	if p.Features == nil {
+		return nil, transform.ErrNotFitted
+	}
+	x, err := p.encode(header, rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range p.Steps {
+		if x, err = s.Transformer.Transform(x); err != nil {
+			return nil, fmt.Errorf("model: step %s: %v", s.Name, err)
+		}
+	}
+	return x, nil
+}
+
+// Encoder returns the function writing the features of a record of a file
+// with the header into dst, which holds a value for every feature, for
+// programs reading rows one at a time. The columns are found once.
+func (p *Pipeline) Encoder(header []string) (func(fields []string, dst []float64) error, error) {
+	if p.Features == nil {
+		return nil, transform.ErrNotFitted
+	}
+	columns := p.inputs()
+	indices, err := columnIndices(header, columns)
+	if err != nil {
+		return nil, err
+	}
+	return func(fields []string, dst []float64) error {
+		if len(dst) != len(p.Features) {
+			return transform.ErrColumns
+		}
+		x, err := p.Transform(columns, [][]string{pick(fields, indices)})
+		if err != nil {
+			return err
+		}
+		copy(dst, x.RawRowView(0))
+		return nil
+	}, nil
+}
+
+// inputs returns the raw columns the pipeline reads.
+func (p *Pipeline) inputs() []string {
+	if p.Columns != nil {
+		return p.Columns.Inputs()
+	}
+	return append(slices.Clone(p.Numeric), p.Categorical...)
+}
+
+// encode returns the numeric and the encoded categorical columns of the
+// rows, or the output of the column transformer, before the steps.
+func (p *Pipeline) encode(header []string, rows [][]string) (*mat64.Dense, error) {
+	if p.Columns != nil {
+		return p.Columns.Transform(header, rows)
+	}
+	numeric, err := columnIndices(header, p.Numeric)
+	if err != nil {
+		return nil, err
+	}
+	categorical, err := columnIndices(header, p.Categorical)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := p.encoder()
+	if err != nil {
+		return nil, err
+	}
+	x := mat64.NewDense(len(rows), len(p.Features), nil)
+	for i, row := range rows {
+		dst := x.RawRowView(i)
+		for k, j := range numeric {
+			if dst[k], err = transform.ParseFloat(row[j]); err != nil {
+				return nil, fmt.Errorf("model: row %d: column %q: %v", i+1, p.Numeric[k], err)
+			}
+		}
+		if enc == nil {
+			continue
+		}
+		if err := enc.Encode(pick(row, categorical), dst[len(numeric):]); err != nil {
+			return nil, fmt.Errorf("model: row %d: %v", i+1, err)
+		}
+	}
+	return x, nil
+}
+
+// columnIndices returns the index of every named column in the header.
+func columnIndices(header, columns []string) ([]int, error) {
+	indices := make([]int, len(columns))
+	for k, name := range columns {
+		if indices[k] = slices.Index(header, name); indices[k] < 0 {
+			return nil, fmt.Errorf("model: no column %q", name)
+		}
+	}
+	return indices, nil
+}
+
+// pick returns the fields of the row at the indices.
+func pick(row []string, indices []int) []string {
+	fields := make([]string, len(indices))
+	for k, j := range indices {
+		fields[k] = row[j]
+	}
+	return fields
+}
+
+// Fit fits the preprocessing and a new estimator of the registered kind
+// Kind on the rows of the data frame, whose Target column holds numbers,
+// labels 0 and 1 for classifiers.
+func (p *Pipeline) Fit(ctx context.Context, df dataframe.DataFrame) error {
+	if df.Err != nil {
+		return df.Err
+	}
+	r, ok := Lookup(p.Kind)
+	if !ok {
+		return fmt.Errorf("model: Fit fits the estimators of registered kinds, not %q; fit other kinds on the features of FitTransform and attach them with SetModel", p.Kind)
+	}
+	records := df.Records()
+	if len(records) < 2 {
+		return errors.New("model: no rows")
+	}
+	header, rows := records[0], records[1:]
+	target := slices.Index(header, p.Target)
+	if target < 0 {
+		return fmt.Errorf("model: no column %q", p.Target)
+	}
+	y := make([]float64, len(rows))
+	for i, row := range rows {
+		v, err := transform.ParseFloat(row[target])
+		if err != nil || math.IsNaN(v) {
+			return fmt.Errorf("model: row %d: column %q: %q is not a number", i+1, p.Target, row[target])
+		}
+		if r.Classifier && v != 0 && v != 1 {
+			return fmt.Errorf("model: row %d: column %q: the %s model expects labels 0 and 1, not %g", i+1, p.Target, p.Kind, v)
+		}
+		y[i] = v
+	}
+	x, err := p.FitTransform(header, rows)
+	if err != nil {
+		return err
+	}
+	m, err := NewCustom(p.Kind)
+	if err != nil {
+		return err
+	}
+	if err := m.Estimator.Fit(ctx, x, y); err != nil {
+		return fmt.Errorf("model: %s: %v", p.Kind, err)
+	}
+	m.Target, m.Features = p.Target, p.Features
+	return p.SetModel(p.Kind, m)
+}
+
+// SetModel attaches a model of the kind, fitted on the features of the
+// pipeline, such as a *Logistic or a *Custom.
+func (p *Pipeline) SetModel(kind string, m any) error {
+	if kind == KindPipeline {
+		return errors.New("model: a pipeline cannot hold a pipeline")
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	p.Kind, p.Model, p.model = kind, data, m
+	return nil
+}
+
+// Unwrap returns the model of the pipeline, decoding it from Model when
+// the pipeline was loaded from a file: a *Logistic, *Linear, *Tree,
+// *Forest, or *Custom for registered kinds.
+func (p *Pipeline) Unwrap() (any, error) {
+	if p.model != nil {
+		return p.model, nil
+	}
+	var m any
+	switch p.Kind {
+	case KindLogistic:
+		m = &Logistic{}
+	case KindLinear:
+		m = &Linear{}
+	case KindTree:
+		m = &Tree{}
+	case KindForest:
+		m = &Forest{}
+	default:
+		c, err := NewCustom(p.Kind)
+		if err != nil {
+			return nil, err
+		}
+		m = c
+	}
+	if err := json.Unmarshal(p.Model, m); err != nil {
+		return nil, fmt.Errorf("model: pipeline %s model: %v", p.Kind, err)
+	}
+	p.model = m
+	return m, nil
+}
+
+// features returns the features of a raw row, given as the values of its
+// columns by name.
+func (p *Pipeline) features(row map[string]string) ([]float64, error) {
+	columns := p.inputs()
+	fields := make([]string, len(columns))
+	for k, name := range columns {
+		v, ok := row[name]
+		if !ok {
+			return nil, fmt.Errorf("model: no column %q", name)
+		}
+		fields[k] = v
+	}
+	x, err := p.Transform(columns, [][]string{fields})
+	if err != nil {
+		return nil, err
+	}
+	return x.RawRowView(0), nil
+}
+
+// Predict returns the prediction of the model for a raw row, given as the
+// values of its columns by name.
+func (p *Pipeline) Predict(row map[string]string) (float64, error) {
+	features, err := p.features(row)
+	if err != nil {
+		return 0, err
+	}
+	m, err := p.Unwrap()
+	if err != nil {
+		return 0, err
+	}
+	predictor, ok := m.(interface {
+		Predict(row []float64) (float64, error)
+	})
+	if !ok {
+		return 0, fmt.Errorf("model: the %s model of the pipeline does not predict", p.Kind)
+	}
+	return predictor.Predict(features)
+}
+
+// Probability returns the probability of class 1 of a raw row, for
+// logistic regressions and estimators implementing Prober.
+func (p *Pipeline) Probability(row map[string]string) (float64, error) {
+	features, err := p.features(row)
+	if err != nil {
+		return 0, err
+	}
+	m, err := p.Unwrap()
+	if err != nil {
+		return 0, err
+	}
+	prober, ok := m.(Prober)
+	if !ok {
+		return 0, fmt.Errorf("model: the %s model of the pipeline predicts no probabilities", p.Kind)
+	}
+	return prober.Probability(features)
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/inference"
+	"github.com/sajari/regression"
+)
+
+// Kinds of the models of this package.
+const (
+	KindLogistic = inference.KindLogistic
+	KindLinear   = inference.KindLinear
+)
+
+// Logistic is a logistic regression: the probability of class 1 is the
+// logistic function of the weighted sum of the features and an intercept.
+// It predicts with package inference.
+type Logistic = inference.Logistic
+
+// Platt is a Platt scaling of probabilities: the calibrated probability
+// is the logistic function of A times the log odds of the probability
+// plus B.
+type Platt = inference.Platt
+
+// LogOdds returns the log odds of the probability p, clipped away from 0
+// and 1 to keep them finite.
+func LogOdds(p float64) float64 {
+	return inference.LogOdds(p)
+}
+
+// check returns an error when the row does not match the features.
+func check(features []string, row []float64) error {
+	if len(row) != len(features) {
+		return fmt.Errorf("model: row has %d features, the model %d", len(row), len(features))
+	}
+	return nil
+}
+
+// Linear is a linear regression: the prediction is the intercept plus the
+// weighted sum of the features. It predicts with package inference.
+type Linear = inference.Linear
+
+// FromRegression returns the coefficients of a trained sajari regression
+// of numFeatures variables.
+func FromRegression(r *regression.Regression, numFeatures int) *Linear {
+	m := &Linear{Target: r.GetObserved(), Intercept: r.Coeff(0)}
+	for j := 0; j < numFeatures; j++ {
+		m.Features = append(m.Features, r.GetVar(j))
+		m.Coefficients = append(m.Coefficients, r.Coeff(j+1))
+	}
+	return m
+}
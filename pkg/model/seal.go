@@ -0,0 +1,218 @@
+package model
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SealedFormat identifies the sealed model files: a model file encrypted,
+// signed, or both, wrapped in an envelope.
+const SealedFormat = "go-machine-learning/sealed-model"
+
+// encryptionAESGCM names the encryption of sealed files.
+const encryptionAESGCM = "aes-256-gcm"
+
+// ErrSignature is returned when loading a model file whose signature
+// does not verify, or that carries no signature when one is required.
+var ErrSignature = errors.New("model: invalid signature")
+
+// Protection encrypts and signs model files on save, and decrypts and
+// verifies them on load. The zero Protection reads and writes plain
+// model files, and reads sealed files that are signed but not encrypted
+// without checking their signature.
+type Protection struct {
+	// Key, when set, is the AES-256 key the files are encrypted with by
+	// AES-GCM, which also detects any change to the encrypted bytes.
+	// Loading then fails for files that are not encrypted, so that a
+	// plain file cannot stand in for an encrypted one.
+	Key []byte
+	// SigningKey, when set, signs the saved files with Ed25519.
+	SigningKey ed25519.PrivateKey
+	// VerifyKey, when set, makes loading fail with ErrSignature unless
+	// the file is signed by the private key of VerifyKey.
+	VerifyKey ed25519.PublicKey
+}
+
+// sealed is the envelope of a sealed model file. The signature covers
+// the encryption, the nonce and the payload, which is the encrypted
+// model file when Encryption is set and the model file itself otherwise.
+type sealed struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Encryption string `json:"encryption,omitempty"`
+	Nonce      []byte `json:"nonce,omitempty"`
+	Payload    []byte `json:"payload"`
+	Signature  []byte `json:"signature,omitempty"`
+}
+
+// message returns the bytes the signature of the envelope covers.
+func (s *sealed) message() []byte {
+	msg := []byte(SealedFormat + "\x00" + s.Encryption + "\x00")
+	msg = append(msg, s.Nonce...)
+	return append(msg, s.Payload...)
+}
+
+// gcm returns the AES-GCM cipher of the key.
+func (p Protection) gcm() (cipher.AEAD, error) {
+	if len(p.Key) != 32 {
+		return nil, fmt.Errorf("model: the encryption key has %d bytes, AES-256 needs 32", len(p.Key))
+	}
+	block, err := aes.NewCipher(p.Key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal wraps the model file data in an envelope, encrypted and signed as
+// the protection asks, or returns it unchanged for the zero Protection.
+func (p Protection) seal(data []byte) ([]byte, error) {
+	if p.Key == nil && p.SigningKey == nil {
+		return data, nil
+	}
+	s := &sealed{Format: SealedFormat, Version: Version, Payload: data}
+	if p.Key != nil {
+		aead, err := p.gcm()
+		if err != nil {
+			return nil, err
+		}
+		s.Encryption = encryptionAESGCM
+		s.Nonce = make([]byte, aead.NonceSize())
+		if _, err := rand.Read(s.Nonce); err != nil {
+			return nil, err
+		}
+		s.Payload = aead.Seal(nil, s.Nonce, data, []byte(SealedFormat))
+	}
+	if p.SigningKey != nil {
+		if len(p.SigningKey) != ed25519.PrivateKeySize {
+			return nil, errors.New("model: invalid Ed25519 signing key")
+		}
+		s.Signature = ed25519.Sign(p.SigningKey, s.message())
+	}
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// open returns the model file held by data: the data itself for a plain
+// model file, or the payload of a sealed one, after checking its
+// signature and decrypting it.
+func (p Protection) open(path string, data []byte) ([]byte, error) {
+	var s sealed
+	if err := json.Unmarshal(data, &s); err != nil || s.Format != SealedFormat {
+		// A plain model file, which read checks.
+		if p.VerifyKey != nil {
+			return nil, fmt.Errorf("%w: %s is not signed", ErrSignature, path)
+		}
+		if p.Key != nil {
+			return nil, fmt.Errorf("model: %s is not encrypted, though a key is given", path)
+		}
+		return data, nil
+	}
+	if s.Version < 1 || s.Version > Version {
+		return nil, fmt.Errorf("model: %s has version %d, this program reads up to %d", path, s.Version, Version)
+	}
+	// Verify the signature before decrypting, so that tampered files are
+	// never decoded.
+	if p.VerifyKey != nil {
+		if len(p.VerifyKey) != ed25519.PublicKeySize {
+			return nil, errors.New("model: invalid Ed25519 verification key")
+		}
+		if s.Signature == nil {
+			return nil, fmt.Errorf("%w: %s is not signed", ErrSignature, path)
+		}
+		if !ed25519.Verify(p.VerifyKey, s.message(), s.Signature) {
+			return nil, fmt.Errorf("%w: %s was changed or signed by another key", ErrSignature, path)
+		}
+	}
+	switch s.Encryption {
+	case "":
+		if p.Key != nil {
+			return nil, fmt.Errorf("model: %s is not encrypted, though a key is given", path)
+		}
+		return s.Payload, nil
+	case encryptionAESGCM:
+		if p.Key == nil {
+			return nil, fmt.Errorf("model: %s is encrypted, its key is needed to load it", path)
+		}
+		aead, err := p.gcm()
+		if err != nil {
+			return nil, err
+		}
+		if len(s.Nonce) != aead.NonceSize() {
+			return nil, fmt.Errorf("model: %s has a nonce of %d bytes, expected %d", path, len(s.Nonce), aead.NonceSize())
+		}
+		data, err := aead.Open(nil, s.Nonce, s.Payload, []byte(SealedFormat))
+		if err != nil {
+			return nil, fmt.Errorf("model: %s cannot be decrypted, the key is wrong or the file was changed", path)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("model: %s is encrypted with %s, which this program does not support", path, s.Encryption)
+}
+
+// ReadKey reads a key of size bytes from the file at path, written as
+// hexadecimal text as WriteKey does.
+func ReadKey(path string, size int) ([]byte, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(text)))
+	if err != nil {
+		return nil, fmt.Errorf("model: %s does not hold a hexadecimal key: %v", path, err)
+	}
+	if len(key) != size {
+		return nil, fmt.Errorf("model: %s holds a key of %d bytes, expected %d", path, len(key), size)
+	}
+	return key, nil
+}
+
+// WriteKey writes the key to a new file at path as hexadecimal text,
+// readable by its owner only.
+func WriteKey(path string, key []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, hex.EncodeToString(key)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadProtection reads the keys of a Protection from the files at the
+// given paths, leaving out the keys whose path is empty: the 32 byte
+// AES-256 key, the 32 byte seed of the Ed25519 signing key and the 32
+// byte Ed25519 public key.
+func ReadProtection(keyPath, signingKeyPath, verifyKeyPath string) (Protection, error) {
+	var p Protection
+	var err error
+	if keyPath != "" {
+		if p.Key, err = ReadKey(keyPath, 32); err != nil {
+			return Protection{}, err
+		}
+	}
+	if signingKeyPath != "" {
+		seed, err := ReadKey(signingKeyPath, ed25519.SeedSize)
+		if err != nil {
+			return Protection{}, err
+		}
+		p.SigningKey = ed25519.NewKeyFromSeed(seed)
+	}
+	if verifyKeyPath != "" {
+		key, err := ReadKey(verifyKeyPath, ed25519.PublicKeySize)
+		if err != nil {
+			return Protection{}, err
+		}
+		p.VerifyKey = key
+	}
+	return p, nil
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"github.com/bachhm.dev/go-machine-learning/pkg/forest"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+)
+
+// Kinds of the tree models of this package.
+const (
+	KindTree   = "tree"
+	KindForest = "forest"
+)
+
+// Tree is a CART decision tree of package tree, with the names of its
+// features and classes.
+type Tree struct {
+	// Target names the predicted variable.
+	Target string `json:"target"`
+	// Features names the features, in the order of the rows.
+	Features []string `json:"features"`
+	// Classes names the classes of a classification tree: class index i
+	// stands for Classes[i].
+	Classes []string   `json:"classes,omitempty"`
+	Tree    *tree.Tree `json:"tree"`
+}
+
+// Predict returns the class index, or the predicted value for
+// regression, of the row.
+func (m *Tree) Predict(row []float64) (float64, error) {
+	if err := check(m.Features, row); err != nil {
+		return 0, err
+	}
+	return m.Tree.Predict(row), nil
+}
+
+// Forest is a random forest of package forest, with the names of its
+// features and classes.
+type Forest struct {
+	// Target names the predicted variable.
+	Target string `json:"target"`
+	// Features names the features, in the order of the rows.
+	Features []string `json:"features"`
+	// Classes names the classes of a classification forest: class index
+	// i stands for Classes[i].
+	Classes []string       `json:"classes,omitempty"`
+	Forest  *forest.Forest `json:"forest"`
+}
+
+// Predict returns the class index, or the predicted value for
+// regression, of the row.
+func (m *Forest) Predict(row []float64) (float64, error) {
+	if err := check(m.Features, row); err != nil {
+		return 0, err
+	}
+	return m.Forest.Predict(row), nil
+}
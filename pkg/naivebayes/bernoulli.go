@@ -0,0 +1,244 @@
+// Package naivebayes holds naive Bayes classifiers of golearn grids.
+package naivebayes
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/privacy"
+	"github.com/sjwhitworth/golearn/base"
+	"golang.org/x/exp/rand"
+)
+
+// Bernoulli is a Bernoulli naive Bayes classifier with configurable
+// smoothing and class priors. golearn's BernoulliNBClassifier always uses
+// add-one smoothing and the class frequencies of the training sample.
+type Bernoulli struct {
+	// alpha is the Lidstone smoothing added to every feature count. An
+	// alpha of 1 is Laplace smoothing.
+	alpha float64
+	// priors, when set, replaces the training class frequencies. The
+	// probabilities are keyed by class label.
+	priors map[string]float64
+	// epsilon, when positive, is the privacy budget of the noisy counts,
+	// drawn from r.
+	epsilon float64
+	r       *rand.Rand
+
+	attrs     []base.Attribute
+	logPrior  map[string]float64
+	condProb  map[string][]float64
+	classList []string
+}
+
+// NewBernoulli creates a classifier with the given smoothing and priors.
+// A nil priors map estimates the priors from the training data. The
+// smoothing must be positive: without it, a feature never set in a class
+// of the training rows gets a probability of 0, and a log probability of
+// -Inf for every row that sets it.
+func NewBernoulli(alpha float64, priors map[string]float64) (*Bernoulli, error) {
+	if err := checkAlpha(alpha); err != nil {
+		return nil, err
+	}
+	return &Bernoulli{alpha: alpha, priors: priors}, nil
+}
+
+// checkAlpha returns an error unless the smoothing alpha is positive and
+// finite.
+func checkAlpha(alpha float64) error {
+	if !(alpha > 0) || math.IsInf(alpha, 1) {
+		return fmt.Errorf("naivebayes: smoothing alpha must be positive, not %g", alpha)
+	}
+	return nil
+}
+
+// SetPrivacy makes Fit ε-differentially private with respect to the
+// training rows: it adds Laplace noise drawn from r to the class counts
+// and the feature counts before estimating the probabilities. A row
+// changes its class count and at most every feature count by one, so the
+// noise has a scale of (1 + features)/ε. The class labels themselves are
+// taken to be public. An epsilon of 0 turns the noise off.
+func (nb *Bernoulli) SetPrivacy(epsilon float64, r *rand.Rand) {
+	nb.epsilon, nb.r = epsilon, r
+}
+
+// Fit estimates the class priors and the smoothed probability of every
+// binary feature being set in each class. It returns an error for a
+// classifier of zero smoothing, such as the zero Bernoulli.
+func (nb *Bernoulli) Fit(X base.FixedDataGrid) error {
+	if err := checkAlpha(nb.alpha); err != nil {
+		return err
+	}
+	classAttrs := X.AllClassAttributes()
+	if len(classAttrs) != 1 {
+		return errors.New("naivebayes: only one class attribute can be used")
+	}
+	nb.attrs = base.AttributeDifference(X.AllAttributes(), classAttrs)
+	for _, a := range nb.attrs {
+		if _, ok := a.(*base.BinaryAttribute); !ok {
+			return fmt.Errorf("naivebayes: %v: should be a BinaryAttribute", a)
+		}
+	}
+	// Count the rows of every class and how often each feature is set.
+	classCounts := make(map[string]int)
+	featureCounts := make(map[string][]int)
+	var numRows int
+	err := X.MapOverRows(base.ResolveAttributes(X, nb.attrs), func(row [][]byte, i int) (bool, error) {
+		class := base.GetClass(X, i)
+		classCounts[class]++
+		if _, ok := featureCounts[class]; !ok {
+			featureCounts[class] = make([]int, len(nb.attrs))
+		}
+		for f, v := range row {
+			if v[0] > 0 {
+				featureCounts[class][f]++
+			}
+		}
+		numRows++
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	nb.classList = nb.classList[:0]
+	for class := range classCounts {
+		nb.classList = append(nb.classList, class)
+	}
+	sort.Strings(nb.classList)
+	counts, featureSums, total := nb.noisyCounts(classCounts, featureCounts, numRows)
+	// Estimate the priors and the smoothed conditional probabilities.
+	nb.logPrior = make(map[string]float64)
+	nb.condProb = make(map[string][]float64)
+	for _, class := range nb.classList {
+		count := counts[class]
+		prior := count / total
+		if nb.priors != nil {
+			p, ok := lookupPrior(nb.priors, class)
+			if !ok {
+				return fmt.Errorf("naivebayes: no prior given for class %q", class)
+			}
+			prior = p
+		}
+		nb.logPrior[class] = math.Log(prior)
+		nb.condProb[class] = make([]float64, len(nb.attrs))
+		for f, n := range featureSums[class] {
+			nb.condProb[class][f] = (n + nb.alpha) / (count + 2*nb.alpha)
+		}
+	}
+	return nil
+}
+
+// noisyCounts returns the class counts, the feature counts and the total
+// number of rows the probabilities are estimated from: the counts as
+// given, or with Laplace noise added in the sorted order of the classes
+// when privacy is set. Noisy class counts are kept at 1 or more, and
+// noisy feature counts between 0 and the count of their class.
+func (nb *Bernoulli) noisyCounts(classCounts map[string]int, featureCounts map[string][]int, numRows int) (map[string]float64, map[string][]float64, float64) {
+	counts := make(map[string]float64, len(classCounts))
+	featureSums := make(map[string][]float64, len(featureCounts))
+	total := 0.0
+	scale := float64(1+len(nb.attrs)) / nb.epsilon
+	for _, class := range nb.classList {
+		count := float64(classCounts[class])
+		if nb.epsilon > 0 {
+			count = math.Max(1, count+privacy.Laplace(nb.r, scale))
+		}
+		counts[class] = count
+		total += count
+		sums := make([]float64, len(nb.attrs))
+		for f, n := range featureCounts[class] {
+			sums[f] = float64(n)
+			if nb.epsilon > 0 {
+				sums[f] = math.Min(count, math.Max(0, sums[f]+privacy.Laplace(nb.r, scale)))
+			}
+		}
+		featureSums[class] = sums
+	}
+	if nb.epsilon <= 0 {
+		total = float64(numRows)
+	}
+	return counts, featureSums, total
+}
+
+// predictOne returns the class with the highest posterior for a row.
+func (nb *Bernoulli) predictOne(row [][]byte) string {
+	bestScore := math.Inf(-1)
+	var bestClass string
+	for _, class := range nb.classList {
+		score := nb.logPrior[class]
+		for f, v := range row {
+			if v[0] > 0 {
+				score += math.Log(nb.condProb[class][f])
+				continue
+			}
+			score += math.Log(1 - nb.condProb[class][f])
+		}
+		if score > bestScore {
+			bestScore = score
+			bestClass = class
+		}
+	}
+	return bestClass
+}
+
+// Predict classifies every row of the grid.
+func (nb *Bernoulli) Predict(what base.FixedDataGrid) (base.FixedDataGrid, error) {
+	ret := base.GeneratePredictionVector(what)
+	err := what.MapOverRows(base.ResolveAttributes(what, nb.attrs), func(row [][]byte, i int) (bool, error) {
+		base.SetClass(ret, i, nb.predictOne(row))
+		return true, nil
+	})
+	return ret, err
+}
+
+// lookupPrior returns the prior of a class. Numeric class labels, which
+// golearn formats as for example "1.000000", also match a prior given
+// for the same number, such as "1".
+func lookupPrior(priors map[string]float64, class string) (float64, bool) {
+	if p, ok := priors[class]; ok {
+		return p, true
+	}
+	value, err := strconv.ParseFloat(class, 64)
+	if err != nil {
+		return 0, false
+	}
+	for key, p := range priors {
+		if k, err := strconv.ParseFloat(key, 64); err == nil && k == value {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// ParsePriors parses class priors given as "class=probability" pairs
+// separated by commas. An empty string returns nil.
+func ParsePriors(s string) (map[string]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	priors := make(map[string]float64)
+	var total float64
+	for _, pair := range strings.Split(s, ",") {
+		class, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("naivebayes: invalid prior %q, expected class=probability", pair)
+		}
+		p, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+		if p <= 0 {
+			return nil, fmt.Errorf("naivebayes: prior of class %q must be positive", class)
+		}
+		priors[strings.TrimSpace(class)] = p
+		total += p
+	}
+	if math.Abs(total-1) > 1e-6 {
+		return nil, fmt.Errorf("naivebayes: priors sum to %v, expected 1", total)
+	}
+	return priors, nil
+}
@@ -0,0 +1,121 @@
+// Package optim holds the update rules and learning rate schedules used by
+// the gradient descent trainers. An Optimizer turns the gradient of the
+// loss into a step on the weights, keeping whatever state it needs between
+// steps, and a Schedule sets the learning rate of every epoch.
+package optim
+
+import (
+	"fmt"
+	"math"
+)
+
+// Optimizer updates weights from the gradient of the loss.
+type Optimizer interface {
+	// Step moves the weights in place against the gradient with the
+	// given learning rate.
+	Step(weights, grad []float64, learningRate float64)
+}
+
+// New returns a fresh optimizer by name: "sgd", "momentum" or "adam",
+// with the default settings of each.
+func New(name string) (Optimizer, error) {
+	switch name {
+	case "sgd":
+		return SGD{}, nil
+	case "momentum":
+		return NewMomentum(0.9), nil
+	case "adam":
+		return NewAdam(0.9, 0.999, 1e-8), nil
+	}
+	return nil, fmt.Errorf("optim: unknown optimizer %q, expected sgd, momentum or adam", name)
+}
+
+// SGD takes plain gradient descent steps.
+type SGD struct{}
+
+// Step implements Optimizer.
+func (SGD) Step(weights, grad []float64, learningRate float64) {
+	for j := range weights {
+		weights[j] -= learningRate * grad[j]
+	}
+}
+
+// Momentum accumulates an exponentially decaying sum of past gradients
+// and steps along it, which smooths the path through narrow valleys.
+type Momentum struct {
+	// Beta is the share of the previous velocity kept at every step.
+	Beta     float64
+	velocity []float64
+}
+
+// NewMomentum returns a momentum optimizer with the given decay.
+func NewMomentum(beta float64) *Momentum {
+	return &Momentum{Beta: beta}
+}
+
+// Step implements Optimizer.
+func (m *Momentum) Step(weights, grad []float64, learningRate float64) {
+	if m.velocity == nil {
+		m.velocity = make([]float64, len(weights))
+	}
+	for j := range weights {
+		m.velocity[j] = m.Beta*m.velocity[j] + grad[j]
+		weights[j] -= learningRate * m.velocity[j]
+	}
+}
+
+// Adam scales every step by running estimates of the mean and the
+// variance of each gradient component, so poorly scaled features get
+// comparable step sizes.
+type Adam struct {
+	// Beta1 and Beta2 are the decay rates of the mean and variance
+	// estimates.
+	Beta1, Beta2 float64
+	// Epsilon avoids dividing by zero.
+	Epsilon float64
+	m, v    []float64
+	t       int
+}
+
+// NewAdam returns an Adam optimizer with the given settings.
+func NewAdam(beta1, beta2, epsilon float64) *Adam {
+	return &Adam{Beta1: beta1, Beta2: beta2, Epsilon: epsilon}
+}
+
+// Step implements Optimizer.
+func (a *Adam) Step(weights, grad []float64, learningRate float64) {
+	if a.m == nil {
+		a.m = make([]float64, len(weights))
+		a.v = make([]float64, len(weights))
+	}
+	a.t++
+	// Correct the bias of the zero-initialized estimates.
+	c1 := 1 - math.Pow(a.Beta1, float64(a.t))
+	c2 := 1 - math.Pow(a.Beta2, float64(a.t))
+	for j := range weights {
+		a.m[j] = a.Beta1*a.m[j] + (1-a.Beta1)*grad[j]
+		a.v[j] = a.Beta2*a.v[j] + (1-a.Beta2)*grad[j]*grad[j]
+		weights[j] -= learningRate * (a.m[j] / c1) / (math.Sqrt(a.v[j]/c2) + a.Epsilon)
+	}
+}
+
+// Schedule returns the learning rate of an epoch, counted from 0.
+type Schedule func(epoch int) float64
+
+// Constant keeps the learning rate fixed.
+func Constant(learningRate float64) Schedule {
+	return func(int) float64 { return learningRate }
+}
+
+// InverseTime decays the learning rate as learningRate / (1 + decay*epoch).
+func InverseTime(learningRate, decay float64) Schedule {
+	return func(epoch int) float64 { return learningRate / (1 + decay*float64(epoch)) }
+}
+
+// StepDecay multiplies the learning rate by factor every given number of
+// epochs.
+func StepDecay(learningRate, factor float64, every int) Schedule {
+	return func(epoch int) float64 {
+		return learningRate * math.Pow(factor, float64(epoch/every))
+	}
+}
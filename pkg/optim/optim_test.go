@@ -0,0 +1,138 @@
+package optim_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// loanPath is the raw loan data set, whose FICO scores are in the
+// hundreds: a poorly scaled feature next to the intercept column.
+const loanPath = "../../classification/dataset/loan_data.csv"
+
+// numLoans is the number of loans read, enough to tell the optimizers
+// apart in a short test.
+const numLoans = 1000
+
+// errEnough stops reading the loans once numLoans are read.
+var errEnough = errors.New("read enough loans")
+
+// readLoans returns the FICO scores of the first loans in hundreds and the
+// intercept column, with the label 1 for interest rates up to 12%, as the
+// logistic regression example labels good credit.
+func readLoans(t *testing.T) (*mat64.Dense, []float64) {
+	t.Helper()
+	var data, labels []float64
+	err := dataset.EachRecord(loanPath, nil, func(row int, record []string) error {
+		fico, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			return err
+		}
+		rate, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return err
+		}
+		data = append(data, fico/100, 1)
+		label := 0.0
+		if rate <= 12 {
+			label = 1
+		}
+		labels = append(labels, label)
+		if len(labels) == numLoans {
+			return errEnough
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnough) {
+		t.Fatal(err)
+	}
+	return mat64.NewDense(len(labels), 2, data), labels
+}
+
+// errReached stops a fit once its loss reaches the target.
+var errReached = errors.New("reached the target loss")
+
+// epochsTo returns the number of full batch epochs the optimizer takes to
+// bring the training log loss of the rows down to the target, or 0 if it
+// does not within maxEpochs.
+func epochsTo(t *testing.T, x *mat64.Dense, y []float64, target float64, name string, learningRate float64, maxEpochs int) int {
+	t.Helper()
+	var epochs int
+	opts := logistic.Options{
+		Steps:    maxEpochs,
+		Schedule: optim.Constant(learningRate),
+		NewOptimizer: func() optim.Optimizer {
+			opt, err := optim.New(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return opt
+		},
+		OnEpoch: func(epoch int, _ []float64, scalars map[string]float64) error {
+			if scalars["loss/train"] <= target {
+				epochs = epoch
+				return errReached
+			}
+			return nil
+		},
+	}
+	_, _, err := logistic.Fit(context.Background(), x, y, opts, rand.New(rand.NewSource(1)))
+	if err != nil && !errors.Is(err, errReached) {
+		t.Fatal(err)
+	}
+	return epochs
+}
+
+// minimumLoss returns the smallest log loss of a logistic regression of
+// the rows, by Newton's method on the two weights.
+func minimumLoss(x *mat64.Dense, y []float64) float64 {
+	w := []float64{0, 0}
+	for iter := 0; iter < 100; iter++ {
+		var g0, g1, h00, h01, h11 float64
+		for i, label := range y {
+			row := x.RawRowView(i)
+			p := logistic.Probability(w, row)
+			g0 += (p - label) * row[0]
+			g1 += (p - label) * row[1]
+			h00 += p * (1 - p) * row[0] * row[0]
+			h01 += p * (1 - p) * row[0] * row[1]
+			h11 += p * (1 - p) * row[1] * row[1]
+		}
+		det := h00*h11 - h01*h01
+		w[0] -= (h11*g0 - h01*g1) / det
+		w[1] -= (h00*g1 - h01*g0) / det
+	}
+	return logistic.LogLoss(w, x, y)
+}
+
+func TestMomentumAndAdamConvergeFasterThanSGD(t *testing.T) {
+	x, y := readLoans(t)
+	target := minimumLoss(x, y) + 0.01
+	const maxEpochs = 3000
+	// Every optimizer runs at the best of the learning rates, and counts
+	// maxEpochs + 1 epochs when it never reaches the target.
+	best := make(map[string]int)
+	for _, name := range []string{"sgd", "momentum", "adam"} {
+		best[name] = maxEpochs + 1
+		for _, rate := range []float64{0.1, 0.3, 1} {
+			if n := epochsTo(t, x, y, target, name, rate, maxEpochs); n > 0 && n < best[name] {
+				best[name] = n
+			}
+		}
+	}
+	t.Logf("epochs to the minimum: sgd %d, momentum %d, adam %d", best["sgd"], best["momentum"], best["adam"])
+	for _, name := range []string{"momentum", "adam"} {
+		if best[name] > maxEpochs {
+			t.Errorf("%s does not reach the minimum within %d epochs", name, maxEpochs)
+		} else if best[name] >= best["sgd"] {
+			t.Errorf("%s takes %d epochs to the minimum, sgd %d", name, best[name], best["sgd"])
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package parallel
+
+import "sync"
+
+// Cost is the estimated resource use of a task while it runs.
+type Cost struct {
+	// CPUs is the number of CPUs the task keeps busy (0 counts as 1).
+	CPUs int
+	// Memory is the memory the task allocates, in bytes.
+	Memory uint64
+}
+
+// Budget caps the summed cost of the tasks running at once.
+type Budget struct {
+	// CPUs is the largest number of CPUs in use (see Workers).
+	CPUs int
+	// Memory is the largest memory in use, in bytes (0 for no limit).
+	Memory uint64
+}
+
+// scheduler admits tasks while their summed cost fits the budget.
+type scheduler struct {
+	mu      sync.Mutex
+	fits    *sync.Cond
+	budget  Budget
+	running int
+	used    Cost
+}
+
+// admit blocks until the task of cost c fits the budget next to the
+// running tasks, then counts it as running. A task larger than the
+// budget runs once no other task does.
+func (s *scheduler) admit(c Cost) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.running > 0 && (s.used.CPUs+c.CPUs > s.budget.CPUs ||
+		s.budget.Memory > 0 && s.used.Memory+c.Memory > s.budget.Memory) {
+		s.fits.Wait()
+	}
+	s.running++
+	s.used.CPUs += c.CPUs
+	s.used.Memory += c.Memory
+}
+
+// release counts the task of cost c as done.
+func (s *scheduler) release(c Cost) {
+	s.mu.Lock()
+	s.running--
+	s.used.CPUs -= c.CPUs
+	s.used.Memory -= c.Memory
+	s.mu.Unlock()
+	s.fits.Broadcast()
+}
+
+// MapBudget calls f for every task from 0 to n-1 like Map, but runs at
+// once only as many tasks as the budget holds, by their estimated costs,
+// so that large tasks do not exhaust the memory of the machine together.
+// Tasks start in task order, so a large task waiting for room is not
+// overtaken by smaller ones; a task larger than the budget runs alone. A
+// nil cost counts every task as one CPU and no memory, which is Map on
+// b.CPUs workers.
+func MapBudget[T any](n int, b Budget, cost func(task int) Cost, f func(task int) T) []T {
+	results := make([]T, n)
+	b.CPUs = Workers(b.CPUs)
+	s := &scheduler{budget: b}
+	s.fits = sync.NewCond(&s.mu)
+	var wg sync.WaitGroup
+	for task := 0; task < n; task++ {
+		var c Cost
+		if cost != nil {
+			c = cost(task)
+		}
+		c.CPUs = max(c.CPUs, 1)
+		s.admit(c)
+		wg.Add(1)
+		go func(task int) {
+			defer wg.Done()
+			defer s.release(c)
+			results[task] = f(task)
+		}(task)
+	}
+	wg.Wait()
+	return results
+}
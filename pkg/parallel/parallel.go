@@ -0,0 +1,58 @@
+// Package parallel runs independent tasks on a pool of workers such that
+// the results do not depend on the number of workers or on scheduling.
+// Every task draws its random numbers from its own stream, seeded from a
+// base seed and the index of the task rather than from a shared
+// generator, and its result is stored at its index, so results are
+// reduced in task order. MapBudget also caps the summed CPU and memory
+// cost of the tasks running at once, from an estimate of every task.
+package parallel
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Workers returns n, or the number of usable CPUs when n is not positive.
+func Workers(n int) int {
+	if n <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return n
+}
+
+// Seed returns the seed of the random stream of a task. It is a function
+// of the base seed and the task index only, mixed with the SplitMix64
+// finalizer so that the streams of neighboring tasks are unrelated.
+func Seed(seed uint64, task int) uint64 {
+	z := seed + uint64(task+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Map calls f for every task from 0 to n-1 on the given number of
+// workers (see Workers) and returns the results in task order.
+func Map[T any](n, workers int, f func(task int) T) []T {
+	results := make([]T, n)
+	workers = Workers(workers)
+	if workers > n {
+		workers = n
+	}
+	tasks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				results[task] = f(task)
+			}
+		}()
+	}
+	for task := 0; task < n; task++ {
+		tasks <- task
+	}
+	close(tasks)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,152 @@
+// Package parity compares the models of the examples with reference
+// outputs stored as JSON fixtures, guarding the numerical correctness of
+// the Go ports. The fixtures are generated with scikit-learn by
+// reference.py in this directory, and the tests of this package compare
+// the models with them. The Source of a fixture records what produced
+// it: the committed ones hold the exact solutions of the regressions,
+// which scikit-learn matches to within its solver tolerance.
+package parity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// Reference is a fixture of reference outputs for one model.
+type Reference struct {
+	// Name describes the model and the dataset.
+	Name string `json:"name"`
+	// Source names what produced the reference, such as the version of
+	// scikit-learn.
+	Source string `json:"source"`
+	// Data is the path of the dataset, relative to the example directory.
+	Data string `json:"data"`
+	// Coefficients holds the fitted coefficients by name.
+	Coefficients map[string]float64 `json:"coefficients,omitempty"`
+	// Predictions holds the prediction of every row of the dataset: a
+	// value for regressions and a class index for classifiers.
+	Predictions []float64 `json:"predictions"`
+	// Probabilities holds the predicted probability of the positive
+	// class of every row, for binary classifiers.
+	Probabilities []float64 `json:"probabilities,omitempty"`
+	// Tolerance is the largest absolute difference accepted for the
+	// coefficients and predictions. When it is 0 they are not compared
+	// value by value.
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// MinAgreement is the smallest share of rows whose predicted class
+	// must equal the reference one, for classifiers whose coefficients
+	// are not expected to match.
+	MinAgreement float64 `json:"min_agreement,omitempty"`
+}
+
+// Load reads the fixture at path.
+func Load(path string) (*Reference, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ref Reference
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, fmt.Errorf("parity: %s: %v", path, err)
+	}
+	return &ref, nil
+}
+
+// Result is the outcome of one comparison.
+type Result struct {
+	// Quantity names what was compared.
+	Quantity string
+	// Measure is the largest difference, or the agreement for class
+	// comparisons.
+	Measure float64
+	// Limit is the tolerance, or the minimum agreement.
+	Limit float64
+	// AtLeast reports that Measure must reach Limit rather than stay
+	// below it.
+	AtLeast bool
+	// Pass reports whether Measure is within Limit.
+	Pass bool
+}
+
+// CompareCoefficients compares the coefficients by name with the
+// reference. A coefficient missing from got counts as an infinite
+// difference.
+func (ref *Reference) CompareCoefficients(got map[string]float64) Result {
+	var maxDiff float64
+	for name, want := range ref.Coefficients {
+		g, ok := got[name]
+		if !ok {
+			maxDiff = math.Inf(1)
+			continue
+		}
+		maxDiff = math.Max(maxDiff, math.Abs(g-want))
+	}
+	return Result{Quantity: "coefficients", Measure: maxDiff, Limit: ref.Tolerance, Pass: maxDiff <= ref.Tolerance}
+}
+
+// CompareValues compares the predicted values with the reference
+// predictions.
+func (ref *Reference) CompareValues(got []float64) Result {
+	maxDiff := math.Inf(1)
+	if len(got) == len(ref.Predictions) {
+		maxDiff = 0
+		for i, want := range ref.Predictions {
+			maxDiff = math.Max(maxDiff, math.Abs(got[i]-want))
+		}
+	}
+	return Result{Quantity: "predictions", Measure: maxDiff, Limit: ref.Tolerance, Pass: maxDiff <= ref.Tolerance}
+}
+
+// CompareClasses compares the predicted classes with the reference ones.
+func (ref *Reference) CompareClasses(got []float64) Result {
+	var agreement float64
+	if len(got) == len(ref.Predictions) && len(got) > 0 {
+		var same int
+		for i, want := range ref.Predictions {
+			if got[i] == want {
+				same++
+			}
+		}
+		agreement = float64(same) / float64(len(got))
+	}
+	return Result{Quantity: "class agreement", Measure: agreement, Limit: ref.MinAgreement, AtLeast: true, Pass: agreement >= ref.MinAgreement}
+}
+
+// WriteReport prints the results of the comparisons with the reference
+// and the coefficients of both sides, and returns an error when any
+// comparison failed.
+func WriteReport(w io.Writer, ref *Reference, got map[string]float64, results []Result) error {
+	fmt.Fprintf(w, "Parity of %s with %s\n\n", ref.Name, ref.Source)
+	names := make([]string, 0, len(ref.Coefficients))
+	for name := range ref.Coefficients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%-20s reference = %12.6f go = %12.6f\n", name, ref.Coefficients[name], got[name])
+	}
+	if len(names) > 0 {
+		fmt.Fprintln(w)
+	}
+	var failed int
+	for _, r := range results {
+		status := "ok"
+		if !r.Pass {
+			status = "FAIL"
+			failed++
+		}
+		op := "<="
+		if r.AtLeast {
+			op = ">="
+		}
+		fmt.Fprintf(w, "%-4s %-16s %g (want %s %g)\n", status, r.Quantity, r.Measure, op, r.Limit)
+	}
+	if failed > 0 {
+		return fmt.Errorf("parity: %d of %d comparisons with %s failed", failed, len(results), ref.Source)
+	}
+	return nil
+}
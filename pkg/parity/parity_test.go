@@ -0,0 +1,130 @@
+package parity_test
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/dataset"
+	"github.com/bachhm.dev/go-machine-learning/pkg/logistic"
+	"github.com/bachhm.dev/go-machine-learning/pkg/optim"
+	"github.com/bachhm.dev/go-machine-learning/pkg/parity"
+	"github.com/gonum/matrix/mat64"
+	"github.com/sajari/regression"
+	"golang.org/x/exp/rand"
+)
+
+// load reads the fixture of the example in dir and returns it with the
+// path of its dataset.
+func load(t *testing.T, dir, fixture string) (*parity.Reference, string) {
+	t.Helper()
+	ref, err := parity.Load(filepath.Join(dir, fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s: %s", ref.Name, ref.Source)
+	return ref, filepath.Join(dir, ref.Data)
+}
+
+// readColumns returns the columns of the CSV file at path as floats, by
+// name.
+func readColumns(t *testing.T, path string, names ...string) [][]float64 {
+	t.Helper()
+	idx := make([]int, len(names))
+	columns := make([][]float64, len(names))
+	header := func(header []string) error {
+		for k, name := range names {
+			idx[k] = -1
+			for j, h := range header {
+				if h == name {
+					idx[k] = j
+				}
+			}
+			if idx[k] < 0 {
+				t.Fatalf("%s has no column %q", path, name)
+			}
+		}
+		return nil
+	}
+	err := dataset.EachRecord(path, header, func(row int, record []string) error {
+		for k, j := range idx {
+			v, err := strconv.ParseFloat(record[j], 64)
+			if err != nil {
+				return err
+			}
+			columns[k] = append(columns[k], v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return columns
+}
+
+// check fails the test for every result that did not pass.
+func check(t *testing.T, results ...parity.Result) {
+	t.Helper()
+	for _, r := range results {
+		if !r.Pass {
+			op := "<="
+			if r.AtLeast {
+				op = ">="
+			}
+			t.Errorf("%s %g, want %s %g", r.Quantity, r.Measure, op, r.Limit)
+		}
+	}
+}
+
+// TestLinear fits the regression of Sales on TV of the linear regression
+// example and compares its coefficients and predictions with the
+// reference.
+func TestLinear(t *testing.T) {
+	ref, data := load(t, "../../regression/linear-regression", "testdata/parity_linear.json")
+	columns := readColumns(t, data, "TV", "Sales")
+	var r regression.Regression
+	r.SetObserved("Sales")
+	r.SetVar(0, "TV")
+	for i, tv := range columns[0] {
+		r.Train(regression.DataPoint(columns[1][i], []float64{tv}))
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	predictions := make([]float64, len(columns[0]))
+	for i, tv := range columns[0] {
+		var err error
+		if predictions[i], err = r.Predict([]float64{tv}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := map[string]float64{"intercept": r.Coeff(0), "TV": r.Coeff(1)}
+	check(t, ref.CompareCoefficients(got), ref.CompareValues(predictions))
+}
+
+// TestLogistic fits the logistic regression of the rate class on FICO of
+// the logistic regression example until it converges, and compares its
+// coefficients and classes with the reference.
+func TestLogistic(t *testing.T) {
+	ref, data := load(t, "../../classification/logistic-regression", "testdata/parity_logistic.json")
+	columns := readColumns(t, data, "fico", "int.rate")
+	x := logistic.AddIntercept(mat64.NewDense(len(columns[0]), 1, columns[0]))
+	opts := logistic.Options{
+		Steps:        20000,
+		Schedule:     optim.Constant(0.05),
+		NewOptimizer: func() optim.Optimizer { return optim.NewAdam(0.9, 0.999, 1e-8) },
+	}
+	weights, _, err := logistic.Fit(context.Background(), x, columns[1], opts, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	predictions := make([]float64, len(columns[1]))
+	for i := range predictions {
+		if logistic.Probability(weights, x.RawRowView(i)) >= 0.5 {
+			predictions[i] = 1
+		}
+	}
+	got := map[string]float64{"fico": weights[0], "intercept": weights[1]}
+	check(t, ref.CompareCoefficients(got), ref.CompareClasses(predictions))
+}
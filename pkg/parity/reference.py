@@ -0,0 +1,89 @@
+#!/usr/bin/env python3
+"""Generate the parity reference fixtures checked by `go run . -parity`.
+
+Run from the repository root, with scikit-learn installed:
+
+    python3 pkg/parity/reference.py
+
+The references are the outputs of LinearRegression and
+LogisticRegression(penalty=None), and the "source" field of every fixture
+records the version of scikit-learn that produced them. The committed
+fixtures predate this requirement: their "source" field records that they
+hold the exact least squares and maximum likelihood solutions instead,
+which scikit-learn matches to within its solver tolerance. Running the
+script replaces them with the outputs of scikit-learn.
+"""
+
+import csv
+import json
+import math
+import os
+import sys
+
+try:
+    import sklearn
+    from sklearn.linear_model import LinearRegression, LogisticRegression
+except ImportError:
+    sys.exit("reference.py needs scikit-learn: pip install scikit-learn")
+
+ROOT = os.path.dirname(os.path.dirname(os.path.dirname(os.path.abspath(__file__))))
+
+
+def read_csv(path):
+    with open(os.path.join(ROOT, path)) as f:
+        rows = list(csv.reader(f))
+    return rows[0], rows[1:]
+
+
+def source():
+    return "scikit-learn " + sklearn.__version__
+
+
+def write(path, fixture):
+    with open(os.path.join(ROOT, path), "w") as f:
+        json.dump(fixture, f, indent=2)
+        f.write("\n")
+    print("wrote", path)
+
+
+def linear():
+    header, rows = read_csv("regression/dataset/Advertising.csv")
+    xs = [[float(r[header.index("TV")])] for r in rows]
+    ys = [float(r[header.index("Sales")]) for r in rows]
+    m = LinearRegression().fit(xs, ys)
+    coef = [float(m.intercept_)] + [float(c) for c in m.coef_]
+    write("regression/linear-regression/testdata/parity_linear.json", {
+        "name": "linear regression of Sales on TV",
+        "source": source(),
+        "data": "../dataset/Advertising.csv",
+        "coefficients": {"intercept": coef[0], "TV": coef[1]},
+        "predictions": [coef[0] + coef[1] * x[0] for x in xs],
+        "tolerance": 1e-6,
+    })
+
+
+def logistic():
+    header, rows = read_csv("classification/logistic-regression/testdata/loan_fixture.csv")
+    xs = [[float(r[header.index("fico")])] for r in rows]
+    ys = [float(r[header.index("int.rate")]) for r in rows]
+    m = LogisticRegression(penalty=None, tol=1e-10, max_iter=10000).fit(xs, ys)
+    coef = [float(m.intercept_[0])] + [float(c) for c in m.coef_[0]]
+    probs = [1 / (1 + math.exp(-(coef[0] + coef[1] * x[0]))) for x in xs]
+    write("classification/logistic-regression/testdata/parity_logistic.json", {
+        "name": "logistic regression of the rate class on FICO",
+        "source": source(),
+        "data": "testdata/loan_fixture.csv",
+        "coefficients": {"intercept": coef[0], "fico": coef[1]},
+        "predictions": [1.0 if p >= 0.5 else 0.0 for p in probs],
+        "probabilities": probs,
+        # The Go trainer minimizes the same log loss, so its coefficients
+        # agree once it has converged. The example stops after its -steps
+        # epochs, short of the minimum, and compares the classes only.
+        "tolerance": 1e-4,
+        "min_agreement": 0.85,
+    })
+
+
+if __name__ == "__main__":
+    linear()
+    logistic()
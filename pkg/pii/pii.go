@@ -0,0 +1,175 @@
+// Package pii marks the columns of a dataset that hold personally
+// identifiable information, such as names, emails or national ids, so
+// that the values of these columns are hashed or redacted wherever they
+// leave the program: profiling reports, prediction files and other
+// artifacts. The values themselves stay untouched in memory, so the
+// columns can still be used as features or segments.
+//
+// A policy is parsed from a comma separated list of columns, each
+// optionally followed by its action:
+//
+//	email,ssn:redact,zip:hash
+//
+// Hashing, the default, replaces a value by a short digest, so that equal
+// values still match across rows and files but cannot be read back.
+// Redacting replaces every value by the same placeholder. With a key,
+// digests are keyed HMACs, which unlike plain hashes cannot be reversed
+// by hashing every likely value, such as every phone number.
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Action is the way the values of a PII column are written out.
+type Action int
+
+const (
+	// Hash replaces every value by its digest.
+	Hash Action = iota
+	// Redact replaces every value by Redacted.
+	Redact
+)
+
+// Redacted replaces the redacted values.
+const Redacted = "[redacted]"
+
+// digestLength is the number of hexadecimal digits of the digests.
+const digestLength = 16
+
+// Policy holds the PII columns and their actions. The nil Policy marks no
+// column, so its methods return the values unchanged.
+type Policy struct {
+	actions map[string]Action
+	key     []byte
+}
+
+// Parse parses the list of PII columns. Digests are keyed by key, unless
+// it is nil. An empty list gives the nil Policy.
+func Parse(list string, key []byte) (*Policy, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	p := &Policy{actions: make(map[string]Action), key: key}
+	for _, field := range strings.Split(list, ",") {
+		column, action, _ := strings.Cut(strings.TrimSpace(field), ":")
+		if column == "" {
+			return nil, fmt.Errorf("pii: %q names an empty column", list)
+		}
+		if _, ok := p.actions[column]; ok {
+			return nil, fmt.Errorf("pii: column %q is listed twice", column)
+		}
+		switch action {
+		case "", "hash":
+			p.actions[column] = Hash
+		case "redact":
+			p.actions[column] = Redact
+		default:
+			return nil, fmt.Errorf("pii: column %q: unknown action %q, expected hash or redact", column, action)
+		}
+	}
+	return p, nil
+}
+
+// Columns returns the PII columns in sorted order.
+func (p *Policy) Columns() []string {
+	if p == nil {
+		return nil
+	}
+	columns := make([]string, 0, len(p.actions))
+	for column := range p.actions {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// Has reports whether the column holds PII.
+func (p *Policy) Has(column string) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.actions[column]
+	return ok
+}
+
+// Check returns an error when a PII column is missing from the header,
+// which usually is a misspelled name that would leave the real column
+// unprotected.
+func (p *Policy) Check(header []string) error {
+	for _, column := range p.Columns() {
+		if !slices.Contains(header, column) {
+			return fmt.Errorf("pii: no column %q", column)
+		}
+	}
+	return nil
+}
+
+// Value returns the value of the column as written out: hashed or
+// redacted for PII columns, and unchanged otherwise. Empty values, which
+// are missing, stay empty.
+func (p *Policy) Value(column, value string) string {
+	if !p.Has(column) || value == "" {
+		return value
+	}
+	if p.actions[column] == Redact {
+		return Redacted
+	}
+	var h hash.Hash
+	if p.key != nil {
+		h = hmac.New(sha256.New, p.key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))[:digestLength]
+}
+
+// Values returns the values of the column as written out, in a new slice
+// for PII columns and as given otherwise.
+func (p *Policy) Values(column string, values []string) []string {
+	if !p.Has(column) {
+		return values
+	}
+	out := make([]string, len(values))
+	for i, value := range values {
+		out[i] = p.Value(column, value)
+	}
+	return out
+}
+
+// Masker writes out the records of a CSV file with the values of its PII
+// columns hashed or redacted.
+type Masker struct {
+	policy  *Policy
+	columns []int
+	names   []string
+}
+
+// Masker returns the masker of the records of a file with the header.
+func (p *Policy) Masker(header []string) *Masker {
+	m := &Masker{policy: p}
+	for j, name := range header {
+		if p.Has(name) {
+			m.columns = append(m.columns, j)
+			m.names = append(m.names, name)
+		}
+	}
+	return m
+}
+
+// Record returns the record as written out. It masks the fields in place,
+// so callers pass a copy of the fields they keep using.
+func (m *Masker) Record(fields []string) []string {
+	for k, j := range m.columns {
+		fields[j] = m.policy.Value(m.names[k], fields[j])
+	}
+	return fields
+}
@@ -0,0 +1,301 @@
+// Package plots renders the charts used by the examples with a shared
+// theme, so every PNG has the same size, fonts, colors and grid.
+package plots
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// Theme describes the look shared by every plot of the package.
+type Theme struct {
+	// Width and Height are the dimensions of the saved image.
+	Width, Height vg.Length
+	// TitleSize and LabelSize are the font sizes of the title and of
+	// the axis labels.
+	TitleSize, LabelSize vg.Length
+	// Color is used for the main series of a plot.
+	Color color.Color
+	// AccentColor is used for fitted lines and reference lines.
+	AccentColor color.Color
+	// GlyphRadius is the radius of scatter points.
+	GlyphRadius vg.Length
+	// LineWidth is the width of line series.
+	LineWidth vg.Length
+	// Grid adds grid lines behind the series.
+	Grid bool
+}
+
+// DefaultTheme is the theme used by the package level functions.
+var DefaultTheme = Theme{
+	Width:       4 * vg.Inch,
+	Height:      4 * vg.Inch,
+	TitleSize:   vg.Points(12),
+	LabelSize:   vg.Points(10),
+	Color:       color.RGBA{R: 31, G: 119, B: 180, A: 255},
+	AccentColor: color.RGBA{R: 214, G: 39, B: 40, A: 255},
+	GlyphRadius: vg.Points(3),
+	LineWidth:   vg.Points(1),
+	Grid:        true,
+}
+
+// newPlot creates a plot with the title and axis labels styled by the theme.
+func (t Theme) newPlot(title, xLabel, yLabel string) *plot.Plot {
+	p := plot.New()
+	p.Title.Text = title
+	p.Title.TextStyle.Font.Size = t.TitleSize
+	p.X.Label.Text = xLabel
+	p.X.Label.TextStyle.Font.Size = t.LabelSize
+	p.Y.Label.Text = yLabel
+	p.Y.Label.TextStyle.Font.Size = t.LabelSize
+	if t.Grid {
+		p.Add(plotter.NewGrid())
+	}
+	return p
+}
+
+// save writes the plot to path. The image format follows the file extension.
+func (t Theme) save(p *plot.Plot, path string) error {
+	return p.Save(t.Width, t.Height, path)
+}
+
+// scatter creates a scatter series of the points in the theme's color.
+func (t Theme) scatter(pts plotter.XYs) (*plotter.Scatter, error) {
+	s, err := plotter.NewScatter(pts)
+	if err != nil {
+		return nil, err
+	}
+	s.GlyphStyle.Radius = t.GlyphRadius
+	s.GlyphStyle.Color = t.Color
+	return s, nil
+}
+
+// line creates a line series of the points in the given color.
+func (t Theme) line(pts plotter.XYs, c color.Color) (*plotter.Line, error) {
+	l, err := plotter.NewLine(pts)
+	if err != nil {
+		return nil, err
+	}
+	l.LineStyle.Width = t.LineWidth
+	l.LineStyle.Color = c
+	return l, nil
+}
+
+// dashed marks a line series as a dashed reference line.
+func dashed(l *plotter.Line) {
+	l.LineStyle.Dashes = []vg.Length{vg.Points(5), vg.Points(5)}
+}
+
+// xys pairs up the x and y values.
+func xys(xs, ys []float64) plotter.XYs {
+	pts := make(plotter.XYs, len(xs))
+	for i := range xs {
+		pts[i].X = xs[i]
+		pts[i].Y = ys[i]
+	}
+	return pts
+}
+
+// Histogram saves a normalized histogram of the values with the given
+// number of bins.
+func Histogram(path, title string, values []float64, bins int) error {
+	t := DefaultTheme
+	p := t.newPlot(title, "", "")
+	h, err := plotter.NewHist(plotter.Values(values), bins)
+	if err != nil {
+		return err
+	}
+	h.FillColor = t.Color
+	// Normalize the histogram.
+	h.Normalize(1)
+	p.Add(h)
+	return t.save(p, path)
+}
+
+// Scatter saves a scatter plot of ys against xs.
+func Scatter(path, xLabel, yLabel string, xs, ys []float64) error {
+	t := DefaultTheme
+	p := t.newPlot("", xLabel, yLabel)
+	s, err := t.scatter(xys(xs, ys))
+	if err != nil {
+		return err
+	}
+	p.Add(s)
+	return t.save(p, path)
+}
+
+// Line saves a line plot of ys against xs.
+func Line(path, title, xLabel, yLabel string, xs, ys []float64) error {
+	t := DefaultTheme
+	p := t.newPlot(title, xLabel, yLabel)
+	l, err := t.line(xys(xs, ys), t.Color)
+	if err != nil {
+		return err
+	}
+	p.Add(l)
+	return t.save(p, path)
+}
+
+// Lines saves a line plot of several series against the same xs, with a
+// legend naming every series. ys[i] holds the values of the series
+// names[i].
+func Lines(path, title, xLabel, yLabel string, xs []float64, names []string, ys [][]float64) error {
+	t := DefaultTheme
+	p := t.newPlot(title, xLabel, yLabel)
+	for i, name := range names {
+		l, err := t.line(xys(xs, ys[i]), plotutil.Color(i))
+		if err != nil {
+			return err
+		}
+		p.Add(l)
+		p.Legend.Add(name, l)
+	}
+	p.Legend.Top = true
+	return t.save(p, path)
+}
+
+// Fit saves a scatter plot of the observed ys against xs with the
+// predicted values drawn over it as a dashed line.
+func Fit(path, xLabel, yLabel string, xs, ys, predicted []float64) error {
+	t := DefaultTheme
+	p := t.newPlot("", xLabel, yLabel)
+	s, err := t.scatter(xys(xs, ys))
+	if err != nil {
+		return err
+	}
+	// Draw the line from left to right so that the dashes are preserved.
+	linePts := xys(xs, predicted)
+	sort.Slice(linePts, func(i, j int) bool { return linePts[i].X < linePts[j].X })
+	l, err := t.line(linePts, t.AccentColor)
+	if err != nil {
+		return err
+	}
+	dashed(l)
+	p.Add(s, l)
+	return t.save(p, path)
+}
+
+// ROC saves a receiver operating characteristic curve from the false and
+// true positive rates, along with the diagonal of a random classifier.
+func ROC(path string, fpr, tpr []float64, auc float64) error {
+	t := DefaultTheme
+	p := t.newPlot(fmt.Sprintf("ROC curve (AUC = %0.2f)", auc), "False positive rate", "True positive rate")
+	l, err := t.line(xys(fpr, tpr), t.Color)
+	if err != nil {
+		return err
+	}
+	diagonal, err := t.line(plotter.XYs{{X: 0, Y: 0}, {X: 1, Y: 1}}, t.AccentColor)
+	if err != nil {
+		return err
+	}
+	dashed(diagonal)
+	p.Add(l, diagonal)
+	p.X.Min, p.X.Max = 0, 1
+	p.Y.Min, p.Y.Max = 0, 1
+	return t.save(p, path)
+}
+
+// Residuals saves a scatter plot of the residuals against the predicted
+// values, with a dashed line at zero.
+func Residuals(path string, predicted, residuals []float64) error {
+	t := DefaultTheme
+	p := t.newPlot("Residuals", "Predicted", "Residual")
+	s, err := t.scatter(xys(predicted, residuals))
+	if err != nil {
+		return err
+	}
+	p.Add(s)
+	// Draw the zero line across the range of the predictions.
+	if len(predicted) > 0 {
+		minX, maxX := predicted[0], predicted[0]
+		for _, x := range predicted {
+			if x < minX {
+				minX = x
+			}
+			if x > maxX {
+				maxX = x
+			}
+		}
+		zero, err := t.line(plotter.XYs{{X: minX, Y: 0}, {X: maxX, Y: 0}}, t.AccentColor)
+		if err != nil {
+			return err
+		}
+		dashed(zero)
+		p.Add(zero)
+	}
+	return t.save(p, path)
+}
+
+// confusionGrid lays out a confusion matrix as a heat map grid, with the
+// first reference class on the top row.
+type confusionGrid [][]float64
+
+func (g confusionGrid) Dims() (c, r int)   { return len(g), len(g) }
+func (g confusionGrid) Z(c, r int) float64 { return g[len(g)-1-r][c] }
+func (g confusionGrid) X(c int) float64    { return float64(c) }
+func (g confusionGrid) Y(r int) float64    { return float64(r) }
+
+// ConfusionMatrix saves a heat map of the confusion matrix counts, where
+// counts[i][j] is the number of rows of classes[i] predicted as
+// classes[j]. Every cell is annotated with its count.
+func ConfusionMatrix(path string, classes []string, counts [][]float64) error {
+	t := DefaultTheme
+	p := t.newPlot("Confusion matrix", "Predicted", "Actual")
+	grid := confusionGrid(counts)
+	p.Add(plotter.NewHeatMap(grid, palette.Heat(12, 1)))
+	// Write the count in the middle of every cell.
+	n := len(classes)
+	cells := plotter.XYLabels{}
+	for i := range counts {
+		for j := range counts[i] {
+			cells.XYs = append(cells.XYs, plotter.XY{X: float64(j), Y: float64(n - 1 - i)})
+			cells.Labels = append(cells.Labels, fmt.Sprintf("%.0f", counts[i][j]))
+		}
+	}
+	labels, err := plotter.NewLabels(cells)
+	if err != nil {
+		return err
+	}
+	for i := range labels.TextStyle {
+		labels.TextStyle[i].Font.Size = t.LabelSize
+		labels.TextStyle[i].XAlign = draw.XCenter
+		labels.TextStyle[i].YAlign = draw.YCenter
+	}
+	p.Add(labels)
+	// Name the classes along both axes.
+	xTicks := make([]plot.Tick, n)
+	yTicks := make([]plot.Tick, n)
+	for i, class := range classes {
+		xTicks[i] = plot.Tick{Value: float64(i), Label: class}
+		yTicks[i] = plot.Tick{Value: float64(n - 1 - i), Label: class}
+	}
+	p.X.Tick.Marker = plot.ConstantTicks(xTicks)
+	p.Y.Tick.Marker = plot.ConstantTicks(yTicks)
+	return t.save(p, path)
+}
+
+// Bars saves a bar chart of the values, one bar named by each label, in
+// order.
+func Bars(path, title, yLabel string, labels []string, values []float64) error {
+	t := DefaultTheme
+	p := t.newPlot(title, "", yLabel)
+	// Narrow the bars so that many of them still fit the width.
+	width := min(vg.Points(20), t.Width/vg.Length(2*len(values)+2))
+	bars, err := plotter.NewBarChart(plotter.Values(values), width)
+	if err != nil {
+		return err
+	}
+	bars.Color = t.Color
+	bars.LineStyle.Width = 0
+	p.Add(bars)
+	p.NominalX(labels...)
+	return t.save(p, path)
+}
@@ -0,0 +1,128 @@
+// Package privacy accounts for the privacy budget of differentially
+// private training and draws the noise of its mechanisms.
+//
+// A randomized training algorithm is (ε, δ)-differentially private when
+// adding or removing any single row changes the probability of any
+// outcome by a factor of at most e^ε, except with probability δ. DP-SGD
+// reaches it by clipping the gradient of every row and adding Gaussian
+// noise to every update; Epsilon tracks the ε spent over the updates with
+// the Rényi differential privacy (RDP) accountant of the subsampled
+// Gaussian mechanism, and NoiseMultiplier finds the noise that spends a
+// given budget. Noisy counts, such as the class counts of naive Bayes,
+// use the Laplace mechanism instead, whose ε is exact.
+package privacy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"golang.org/x/exp/rand"
+)
+
+// maxOrder is the largest RDP order the accountant tries.
+const maxOrder = 256
+
+// Epsilon returns the ε spent by steps updates of the subsampled Gaussian
+// mechanism for the given δ: every update samples each row with
+// probability q and adds Gaussian noise of standard deviation sigma times
+// the clipping norm to the sum of the clipped gradients. A q of 1 stands
+// for updates on every row. It composes the RDP of the updates at the
+// integer orders from 2 to 256, following Mironov, Talwar and Zhang,
+// "Rényi differential privacy of the sampled Gaussian mechanism" (2019),
+// and converts the best order to (ε, δ). Leaving out the fractional
+// orders makes the ε slightly too high for small budgets, which errs on
+// the safe side. The result is +Inf when sigma is not positive.
+func Epsilon(q, sigma float64, steps int, delta float64) float64 {
+	if sigma <= 0 {
+		return math.Inf(1)
+	}
+	if steps <= 0 || q <= 0 {
+		return 0
+	}
+	best := math.Inf(1)
+	for order := 2; order <= maxOrder; order++ {
+		rdp := float64(steps) * rdpOrder(q, sigma, order)
+		if eps := rdp + math.Log(1/delta)/float64(order-1); eps < best {
+			best = eps
+		}
+	}
+	return best
+}
+
+// rdpOrder returns the RDP of a single update of the subsampled Gaussian
+// mechanism at the integer order.
+func rdpOrder(q, sigma float64, order int) float64 {
+	if q >= 1 {
+		return float64(order) / (2 * sigma * sigma)
+	}
+	// log A, where A = sum_k C(order, k) (1-q)^(order-k) q^k
+	// exp((k² - k) / (2σ²)), summed in log space.
+	logA := math.Inf(-1)
+	for k := 0; k <= order; k++ {
+		term := logBinomial(order, k) + float64(k)*math.Log(q) + float64(order-k)*math.Log1p(-q) +
+			float64(k*k-k)/(2*sigma*sigma)
+		logA = logAdd(logA, term)
+	}
+	return logA / float64(order-1)
+}
+
+// logBinomial returns the logarithm of n choose k.
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// logAdd returns log(e^a + e^b).
+func logAdd(a, b float64) float64 {
+	if math.IsInf(a, -1) {
+		return b
+	}
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
+
+// NoiseMultiplier returns the smallest noise multiplier, within 0.1%,
+// with which steps updates sampling rows with probability q spend at most
+// the ε budget for the given δ.
+func NoiseMultiplier(q float64, steps int, epsilon, delta float64) (float64, error) {
+	if epsilon <= 0 {
+		return 0, fmt.Errorf("privacy: ε must be positive, not %g", epsilon)
+	}
+	if delta <= 0 || delta >= 1 {
+		return 0, fmt.Errorf("privacy: δ must lie in (0, 1), not %g", delta)
+	}
+	// Grow the upper bound until it meets the budget, then bisect.
+	lo, hi := 0.0, 1.0
+	for Epsilon(q, hi, steps, delta) > epsilon {
+		lo, hi = hi, 2*hi
+		if hi > 1e6 {
+			return 0, errors.New("privacy: no noise multiplier meets the budget")
+		}
+	}
+	for hi-lo > 1e-3*hi {
+		mid := (lo + hi) / 2
+		if Epsilon(q, mid, steps, delta) > epsilon {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi, nil
+}
+
+// Laplace draws from the Laplace distribution of mean 0 and the given
+// scale. Adding it to a count whose L1 sensitivity is s, the most that
+// one row changes it by, with a scale of s/ε releases the count with
+// ε-differential privacy.
+func Laplace(r *rand.Rand, scale float64) float64 {
+	u := r.Float64() - 0.5
+	if u < 0 {
+		return scale * math.Log1p(2*u)
+	}
+	return -scale * math.Log1p(-2*u)
+}
@@ -0,0 +1,167 @@
+// Package reasons turns the feature contributions of a prediction into
+// reason codes, such as the principal reasons an adverse action notice
+// must give a declined credit applicant. The features that pushed the
+// score furthest in the adverse direction are mapped to configurable
+// codes and reasons, read from a YAML file:
+//
+//	adverse: high
+//	top: 4
+//	codes:
+//	  fico: {code: R01, reason: Credit score too low}
+//	  int.rate: {code: R02, reason: Interest rate too high}
+//	other: {code: R99, reason: Other factors}
+//
+// With adverse: high, high scores, such as a probability of default, are
+// adverse, and the features of the largest positive contributions are the
+// reasons; with adverse: low, those of the most negative ones.
+package reasons
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Directions of the adverse scores.
+const (
+	High = "high"
+	Low  = "low"
+)
+
+// defaultTop is the number of reasons returned when Config.Top is 0, the
+// four reasons of the usual adverse action notice.
+const defaultTop = 4
+
+// Code is a reason code and the reason it stands for.
+type Code struct {
+	Code   string `yaml:"code"`
+	Reason string `yaml:"reason"`
+}
+
+// Config maps features to reason codes.
+type Config struct {
+	// Adverse is the direction of the adverse scores, High or Low.
+	Adverse string `yaml:"adverse"`
+	// Top is the largest number of reasons of a prediction (0 uses 4).
+	Top int `yaml:"top"`
+	// Codes holds the code of every feature, by name.
+	Codes map[string]Code `yaml:"codes"`
+	// Other, when set, is the code of the features Codes does not name,
+	// which are otherwise errors.
+	Other *Code `yaml:"other"`
+}
+
+// Reason is a reason of a prediction.
+type Reason struct {
+	Code
+	// Contribution is the largest adverse contribution of the features
+	// of the code.
+	Contribution float64
+}
+
+// Load reads the configuration of the YAML file at path and checks it.
+// Unknown keys are errors.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.Check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Check reports the first invalid setting.
+func (c *Config) Check() error {
+	if c.Adverse != High && c.Adverse != Low {
+		return fmt.Errorf("reasons: adverse is %q, expected high or low", c.Adverse)
+	}
+	if c.Top < 0 {
+		return fmt.Errorf("reasons: top is %d", c.Top)
+	}
+	for feature, code := range c.Codes {
+		if code.Code == "" {
+			return fmt.Errorf("reasons: feature %q has no code", feature)
+		}
+	}
+	if c.Other != nil && c.Other.Code == "" {
+		return errors.New("reasons: other has no code")
+	}
+	return nil
+}
+
+// Mapper maps the contributions of the features of a model to reasons.
+type Mapper struct {
+	config *Config
+	// codes holds the code of every feature.
+	codes []Code
+}
+
+// Bind returns the mapper of the features of a model, in the order of its
+// contributions. Every feature needs a code, unless Other is set.
+func (c *Config) Bind(features []string) (*Mapper, error) {
+	m := &Mapper{config: c, codes: make([]Code, len(features))}
+	for j, feature := range features {
+		code, ok := c.Codes[feature]
+		if !ok {
+			if c.Other == nil {
+				return nil, fmt.Errorf("reasons: feature %q has no code, and no other code is set", feature)
+			}
+			code = *c.Other
+		}
+		m.codes[j] = code
+	}
+	return m, nil
+}
+
+// Reasons returns the reasons of the features that moved the score in
+// the adverse direction, the largest contribution first, up to Top of
+// them. Features sharing a code give a single reason.
+func (m *Mapper) Reasons(contributions []float64) ([]Reason, error) {
+	if len(contributions) != len(m.codes) {
+		return nil, fmt.Errorf("reasons: %d contributions for %d features", len(contributions), len(m.codes))
+	}
+	sign := 1.0
+	if m.config.Adverse == Low {
+		sign = -1
+	}
+	var reasons []Reason
+	for j, c := range contributions {
+		// Only adverse contributions are reasons.
+		if c*sign <= 0 || math.IsNaN(c) {
+			continue
+		}
+		k := 0
+		for k < len(reasons) && reasons[k].Code.Code != m.codes[j].Code {
+			k++
+		}
+		if k == len(reasons) {
+			reasons = append(reasons, Reason{Code: m.codes[j], Contribution: c})
+		} else if c*sign > reasons[k].Contribution*sign {
+			reasons[k].Contribution = c
+		}
+	}
+	sort.SliceStable(reasons, func(a, b int) bool {
+		return reasons[a].Contribution*sign > reasons[b].Contribution*sign
+	})
+	top := m.config.Top
+	if top == 0 {
+		top = defaultTop
+	}
+	if len(reasons) > top {
+		reasons = reasons[:top]
+	}
+	return reasons, nil
+}
@@ -0,0 +1,283 @@
+// Package resample rebalances the classes of training rows, so that a
+// classifier does not learn to favor the majority class when the classes
+// are imbalanced. Random undersampling drops rows of the larger classes,
+// random oversampling repeats rows of the smaller ones, and SMOTE
+// synthesizes new rows of the smaller classes between each row and its
+// nearest neighbors of the same class.
+//
+// Resample only the training rows, after any split: resampled test or
+// validation rows, or synthetic rows that leak into them, would no longer
+// measure the classes as the model meets them.
+package resample
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// Method is a way of rebalancing the classes.
+type Method int
+
+const (
+	// None leaves the rows as they are.
+	None Method = iota
+	// Under drops random rows of the larger classes.
+	Under
+	// Over repeats random rows of the smaller classes.
+	Over
+	// SMOTE synthesizes rows of the smaller classes.
+	SMOTE
+)
+
+// String returns the name of the method.
+func (m Method) String() string {
+	switch m {
+	case None:
+		return "none"
+	case Under:
+		return "under"
+	case Over:
+		return "over"
+	case SMOTE:
+		return "smote"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// ParseMethod returns the method of the name: none, under, over or smote.
+func ParseMethod(name string) (Method, error) {
+	for _, m := range []Method{None, Under, Over, SMOTE} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("resample: unknown method %q, expected none, under, over or smote", name)
+}
+
+// Config describes a resampling.
+type Config struct {
+	Method Method
+	// Ratio is the size of the smaller classes relative to the largest
+	// after resampling (0 uses 1, balancing the classes). Undersampling
+	// shrinks every class to at most the smallest over Ratio, and
+	// oversampling grows every class to at least the largest times
+	// Ratio.
+	Ratio float64
+	// Neighbors is the number of nearest neighbors SMOTE interpolates
+	// towards (0 uses 5).
+	Neighbors int
+}
+
+func (cfg Config) ratio() float64 {
+	if cfg.Ratio > 0 {
+		return cfg.Ratio
+	}
+	return 1
+}
+
+func (cfg Config) neighbors() int {
+	if cfg.Neighbors > 0 {
+		return cfg.Neighbors
+	}
+	return 5
+}
+
+// Resample returns the rows of x and their labels y rebalanced by the
+// method of cfg, in a random order, so that models fitted row by row,
+// such as by stochastic gradient descent, do not meet the new rows of a
+// class in a run. SMOTE measures distances on the features as they are,
+// so features of different scales should be standardized first.
+func Resample(x *mat64.Dense, y []float64, cfg Config, r *rand.Rand) (*mat64.Dense, []float64, error) {
+	numRows, _ := x.Dims()
+	if numRows != len(y) {
+		return nil, nil, fmt.Errorf("resample: %d rows and %d labels", numRows, len(y))
+	}
+	if cfg.Ratio < 0 || cfg.Ratio > 1 {
+		return nil, nil, fmt.Errorf("resample: ratio %g, want 0 to 1", cfg.Ratio)
+	}
+	classes := byClass(y)
+	var xs *mat64.Dense
+	var ys []float64
+	switch cfg.Method {
+	case None:
+		return x, y, nil
+	case Under:
+		xs, ys = undersample(x, y, classes, cfg.ratio(), r)
+	case Over, SMOTE:
+		var err error
+		if xs, ys, err = oversample(x, y, classes, cfg, r); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, fmt.Errorf("resample: invalid method %v", cfg.Method)
+	}
+	return shuffle(xs, ys, r), ys, nil
+}
+
+// shuffle permutes the rows of x and the labels y in place, and returns x.
+func shuffle(x *mat64.Dense, y []float64, r *rand.Rand) *mat64.Dense {
+	_, numCols := x.Dims()
+	tmp := make([]float64, numCols)
+	r.Shuffle(len(y), func(a, b int) {
+		ra, rb := x.RawRowView(a), x.RawRowView(b)
+		copy(tmp, ra)
+		copy(ra, rb)
+		copy(rb, tmp)
+		y[a], y[b] = y[b], y[a]
+	})
+	return x
+}
+
+// class is a class and the indices of its rows.
+type class struct {
+	label float64
+	rows  []int
+}
+
+// byClass returns the rows of every class, in the order of the labels.
+func byClass(y []float64) []class {
+	index := make(map[float64]int)
+	var classes []class
+	for i, label := range y {
+		k, ok := index[label]
+		if !ok {
+			k = len(classes)
+			index[label] = k
+			classes = append(classes, class{label: label})
+		}
+		classes[k].rows = append(classes[k].rows, i)
+	}
+	sort.Slice(classes, func(a, b int) bool { return classes[a].label < classes[b].label })
+	return classes
+}
+
+// undersample keeps at most the size of the smallest class over ratio
+// random rows of every class, in their order.
+func undersample(x *mat64.Dense, y []float64, classes []class, ratio float64, r *rand.Rand) (*mat64.Dense, []float64) {
+	smallest := len(y)
+	for _, c := range classes {
+		smallest = min(smallest, len(c.rows))
+	}
+	limit := max(int(float64(smallest)/ratio), smallest)
+	keep := make([]bool, len(y))
+	for _, c := range classes {
+		if len(c.rows) <= limit {
+			for _, i := range c.rows {
+				keep[i] = true
+			}
+			continue
+		}
+		for _, k := range r.Perm(len(c.rows))[:limit] {
+			keep[c.rows[k]] = true
+		}
+	}
+	var rows []int
+	for i, ok := range keep {
+		if ok {
+			rows = append(rows, i)
+		}
+	}
+	_, numCols := x.Dims()
+	xs := mat64.NewDense(len(rows), numCols, nil)
+	ys := make([]float64, len(rows))
+	for k, i := range rows {
+		xs.SetRow(k, x.RawRowView(i))
+		ys[k] = y[i]
+	}
+	return xs, ys
+}
+
+// oversample appends rows to every class smaller than the largest times
+// the ratio after the rows of x, repeating random rows of the class or
+// synthesizing them by SMOTE.
+func oversample(x *mat64.Dense, y []float64, classes []class, cfg Config, r *rand.Rand) (*mat64.Dense, []float64, error) {
+	largest := 0
+	for _, c := range classes {
+		largest = max(largest, len(c.rows))
+	}
+	target := int(math.Ceil(float64(largest) * cfg.ratio()))
+	_, numCols := x.Dims()
+	var extra [][]float64
+	ys := append([]float64(nil), y...)
+	for _, c := range classes {
+		need := target - len(c.rows)
+		if need <= 0 {
+			continue
+		}
+		if cfg.Method == Over {
+			for ; need > 0; need-- {
+				extra = append(extra, x.RawRowView(c.rows[r.Intn(len(c.rows))]))
+				ys = append(ys, c.label)
+			}
+			continue
+		}
+		if len(c.rows) < 2 {
+			return nil, nil, fmt.Errorf("resample: SMOTE needs at least 2 rows of class %g", c.label)
+		}
+		neighbors := nearest(x, c.rows, min(cfg.neighbors(), len(c.rows)-1))
+		for ; need > 0; need-- {
+			// Interpolate between a random row of the class and one of
+			// its nearest neighbors.
+			k := r.Intn(len(c.rows))
+			a := x.RawRowView(c.rows[k])
+			b := x.RawRowView(neighbors[k][r.Intn(len(neighbors[k]))])
+			gap := r.Float64()
+			row := make([]float64, numCols)
+			for j := range row {
+				row[j] = a[j] + gap*(b[j]-a[j])
+			}
+			extra = append(extra, row)
+			ys = append(ys, c.label)
+		}
+	}
+	numRows := len(y)
+	xs := mat64.NewDense(numRows+len(extra), numCols, nil)
+	for i := 0; i < numRows; i++ {
+		xs.SetRow(i, x.RawRowView(i))
+	}
+	for k, row := range extra {
+		xs.SetRow(numRows+k, row)
+	}
+	return xs, ys, nil
+}
+
+// nearest returns the k nearest rows among rows of every one of them, by
+// Euclidean distance, ties going to the earlier row.
+func nearest(x *mat64.Dense, rows []int, k int) [][]int {
+	neighbors := make([][]int, len(rows))
+	distances := make([]float64, 0, k+1)
+	for a, i := range rows {
+		xi := x.RawRowView(i)
+		// Keep the k nearest rows seen so far, sorted by distance.
+		distances = distances[:0]
+		var nearestRows []int
+		for b, l := range rows {
+			// The row itself is not its own neighbor.
+			if b == a {
+				continue
+			}
+			xl := x.RawRowView(l)
+			var d float64
+			for j := range xi {
+				d += (xi[j] - xl[j]) * (xi[j] - xl[j])
+			}
+			if len(distances) == k && d >= distances[k-1] {
+				continue
+			}
+			n := sort.Search(len(distances), func(n int) bool { return distances[n] > d })
+			if len(distances) < k {
+				distances = append(distances, 0)
+				nearestRows = append(nearestRows, 0)
+			}
+			copy(distances[n+1:], distances[n:])
+			copy(nearestRows[n+1:], nearestRows[n:])
+			distances[n], nearestRows[n] = d, l
+		}
+		neighbors[a] = nearestRows
+	}
+	return neighbors
+}
@@ -0,0 +1,267 @@
+// Package rules applies declarative business rules to the rows a model
+// has scored, as credit decisions combine the model with policy: score
+// bands map the score to a decision, hard cutoffs on the raw features
+// decide whatever the score, and override lists force the decision of
+// listed rows, such as the accounts of a manual review. Rules are read
+// from a YAML file:
+//
+//	bands:
+//	  - {below: 0.2, decision: approve, code: B1}
+//	  - {below: 0.5, decision: review, code: B2}
+//	  - {decision: decline, code: B3}
+//	cutoffs:
+//	  - {column: fico, op: "<", value: 620, decision: decline, code: C1, reason: FICO below the policy minimum}
+//	overrides:
+//	  - {column: id, values: ["1042", "1077"], decision: approve, code: O1, reason: approved by the credit committee}
+//
+// An override decides over a cutoff, and a cutoff over the score band.
+// Every rule a row matches is logged by its reason code, the deciding one
+// first, so that every decision can be explained.
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rules holds the rules of a policy.
+type Rules struct {
+	// Bands map the score to a decision: a row takes the first band whose
+	// Below exceeds its score. The last band has no Below, and takes the
+	// rows above the others.
+	Bands []Band `yaml:"bands"`
+	// Cutoffs decide on the raw features of rows, the first matching one
+	// deciding.
+	Cutoffs []Cutoff `yaml:"cutoffs"`
+	// Overrides force the decision of the listed rows, the first
+	// matching one deciding.
+	Overrides []Override `yaml:"overrides"`
+	// Default is the decision of rows no rule matches, which is required
+	// without bands.
+	Default string `yaml:"default"`
+}
+
+// Band is a range of scores.
+type Band struct {
+	// Below bounds the scores of the band, or is nil for the last band.
+	Below    *float64 `yaml:"below"`
+	Decision string   `yaml:"decision"`
+	Code     string   `yaml:"code"`
+	Reason   string   `yaml:"reason"`
+}
+
+// Cutoff decides on the rows whose value of a numeric column compares to
+// Value by Op: <, <=, >, >=, == or !=.
+type Cutoff struct {
+	Column   string  `yaml:"column"`
+	Op       string  `yaml:"op"`
+	Value    float64 `yaml:"value"`
+	Decision string  `yaml:"decision"`
+	Code     string  `yaml:"code"`
+	Reason   string  `yaml:"reason"`
+}
+
+// Override decides on the rows whose value of a column is one of Values,
+// compared as text.
+type Override struct {
+	Column   string   `yaml:"column"`
+	Values   []string `yaml:"values"`
+	Decision string   `yaml:"decision"`
+	Code     string   `yaml:"code"`
+	Reason   string   `yaml:"reason"`
+}
+
+// Outcome is the decision on a row.
+type Outcome struct {
+	Decision string
+	// Codes holds the reason codes of every rule the row matches: the
+	// overrides, the cutoffs and the band, in the order of the file, so
+	// that the deciding rule comes first.
+	Codes []string
+}
+
+// Load reads the rules of the YAML file at path and checks them. Unknown
+// keys are errors, so that a misspelled rule is not silently ignored.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Rules
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := r.Check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Check reports the first invalid rule.
+func (r *Rules) Check() error {
+	if len(r.Bands) == 0 && r.Default == "" {
+		return errors.New("rules: no bands and no default decision")
+	}
+	codes := make(map[string]bool)
+	rule := func(kind string, k int, decision, code string) error {
+		if decision == "" || code == "" {
+			return fmt.Errorf("rules: %s %d needs a decision and a code", kind, k+1)
+		}
+		if codes[code] {
+			return fmt.Errorf("rules: code %q is used twice", code)
+		}
+		codes[code] = true
+		return nil
+	}
+	for k, b := range r.Bands {
+		if err := rule("band", k, b.Decision, b.Code); err != nil {
+			return err
+		}
+		last := k == len(r.Bands)-1
+		switch {
+		case last && b.Below != nil:
+			return fmt.Errorf("rules: the last band has a bound, so the scores above %g have no band", *b.Below)
+		case !last && b.Below == nil:
+			return fmt.Errorf("rules: band %d has no bound but is not the last", k+1)
+		case k > 0 && !last && *b.Below <= *r.Bands[k-1].Below:
+			return fmt.Errorf("rules: the bound of band %d does not exceed the bound of band %d", k+1, k)
+		}
+	}
+	for k, c := range r.Cutoffs {
+		if err := rule("cutoff", k, c.Decision, c.Code); err != nil {
+			return err
+		}
+		if c.Column == "" {
+			return fmt.Errorf("rules: cutoff %d has no column", k+1)
+		}
+		if _, err := compare(c.Op, 0, 0); err != nil {
+			return fmt.Errorf("rules: cutoff %d: %v", k+1, err)
+		}
+	}
+	for k, o := range r.Overrides {
+		if err := rule("override", k, o.Decision, o.Code); err != nil {
+			return err
+		}
+		if o.Column == "" || len(o.Values) == 0 {
+			return fmt.Errorf("rules: override %d needs a column and values", k+1)
+		}
+	}
+	return nil
+}
+
+// HasBands reports whether decisions depend on the score.
+func (r *Rules) HasBands() bool {
+	return len(r.Bands) > 0
+}
+
+// Reason returns the reason of the rule of the code, or "" when it has
+// none.
+func (r *Rules) Reason(code string) string {
+	for _, b := range r.Bands {
+		if b.Code == code {
+			return b.Reason
+		}
+	}
+	for _, c := range r.Cutoffs {
+		if c.Code == code {
+			return c.Reason
+		}
+	}
+	for _, o := range r.Overrides {
+		if o.Code == code {
+			return o.Reason
+		}
+	}
+	return ""
+}
+
+// Engine decides on the records of a CSV file.
+type Engine struct {
+	rules *Rules
+	// cutoffColumns and overrideColumns are the columns of the cutoffs
+	// and of the overrides in the header.
+	cutoffColumns, overrideColumns []int
+}
+
+// Bind returns the engine deciding on the records of a CSV file with the
+// header, which must hold the columns of the cutoffs and overrides.
+func (r *Rules) Bind(header []string) (*Engine, error) {
+	e := &Engine{rules: r}
+	for _, c := range r.Cutoffs {
+		j := slices.Index(header, c.Column)
+		if j < 0 {
+			return nil, fmt.Errorf("rules: cutoff %s: no column %q", c.Code, c.Column)
+		}
+		e.cutoffColumns = append(e.cutoffColumns, j)
+	}
+	for _, o := range r.Overrides {
+		j := slices.Index(header, o.Column)
+		if j < 0 {
+			return nil, fmt.Errorf("rules: override %s: no column %q", o.Code, o.Column)
+		}
+		e.overrideColumns = append(e.overrideColumns, j)
+	}
+	return e, nil
+}
+
+// Decide returns the outcome of a record scored by the model.
+func (e *Engine) Decide(fields []string, score float64) (Outcome, error) {
+	var out Outcome
+	match := func(decision, code string) {
+		if out.Codes == nil {
+			out.Decision = decision
+		}
+		out.Codes = append(out.Codes, code)
+	}
+	for k, o := range e.rules.Overrides {
+		if slices.Contains(o.Values, fields[e.overrideColumns[k]]) {
+			match(o.Decision, o.Code)
+		}
+	}
+	for k, c := range e.rules.Cutoffs {
+		v, err := strconv.ParseFloat(fields[e.cutoffColumns[k]], 64)
+		if err != nil {
+			return Outcome{}, fmt.Errorf("rules: cutoff %s: column %q: %v", c.Code, c.Column, err)
+		}
+		if ok, _ := compare(c.Op, v, c.Value); ok {
+			match(c.Decision, c.Code)
+		}
+	}
+	for _, b := range e.rules.Bands {
+		if b.Below == nil || score < *b.Below {
+			match(b.Decision, b.Code)
+			break
+		}
+	}
+	if out.Codes == nil {
+		out.Decision = e.rules.Default
+	}
+	return out, nil
+}
+
+// compare reports whether a op b holds.
+func compare(op string, a, b float64) (bool, error) {
+	switch op {
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	}
+	return false, fmt.Errorf("unknown operator %q, expected <, <=, >, >=, == or !=", op)
+}
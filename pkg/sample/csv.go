@@ -0,0 +1,132 @@
+package sample
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// Config describes a sample of the rows of a CSV file.
+type Config struct {
+	// Size is the number of rows sampled.
+	Size int
+	// Stratify, when set, names the column whose values stratify the
+	// sample.
+	Stratify string
+	// Weight, when set, names the column of the weights of the rows,
+	// which are then sampled with probabilities proportional to them.
+	Weight string
+	// Seed seeds the sample, so that it can be reproduced.
+	Seed int64
+}
+
+// row is a record along with its position in the file.
+type row struct {
+	index  int
+	record []string
+}
+
+// CSV reads the CSV file at path, with a header row, and returns the
+// header and a sample of the records, in the order of the file. Only
+// the sample is kept in memory.
+func CSV(path string, cfg Config) (header []string, records [][]string, err error) {
+	if cfg.Stratify != "" && cfg.Weight != "" {
+		return nil, nil, errors.New("sample: a sample is either stratified or weighted")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	header, err = reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("sample: %s: %v", path, err)
+	}
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		if j := slices.Index(header, name); j >= 0 {
+			return j, nil
+		}
+		return -1, fmt.Errorf("sample: %s has no column %q", path, name)
+	}
+	strataCol, err := column(cfg.Stratify)
+	if err != nil {
+		return nil, nil, err
+	}
+	weightCol, err := column(cfg.Weight)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Offer every record to the sampler of the configuration.
+	r := rand.New(rand.NewSource(cfg.Seed))
+	uniform := NewReservoir[row](cfg.Size, r)
+	stratified := NewStratified[row](cfg.Size, r)
+	weighted := NewWeighted[row](cfg.Size, r)
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("sample: %s: %v", path, err)
+		}
+		switch {
+		case strataCol >= 0:
+			stratified.Add(record[strataCol], row{i, record})
+		case weightCol >= 0:
+			w, err := strconv.ParseFloat(record[weightCol], 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("sample: %s: row %d: weight: %v", path, i+1, err)
+			}
+			weighted.Add(row{i, record}, w)
+		default:
+			uniform.Add(row{i, record})
+		}
+	}
+	var rows []row
+	switch {
+	case strataCol >= 0:
+		rows = stratified.Items()
+	case weightCol >= 0:
+		rows = weighted.Items()
+	default:
+		rows = uniform.Items()
+	}
+	// Restore the order of the file.
+	sort.Slice(rows, func(a, b int) bool { return rows[a].index < rows[b].index })
+	records = make([][]string, len(rows))
+	for i, x := range rows {
+		records[i] = x.record
+	}
+	return header, records, nil
+}
+
+// WriteCSV writes a sample of the rows of the CSV file at in, as returned
+// by CSV, to the CSV file at out. It returns the number of rows sampled.
+func WriteCSV(in, out string, cfg Config) (int, error) {
+	header, records, err := CSV(in, cfg)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, err
+	}
+	w := csv.NewWriter(f)
+	w.Write(header)
+	w.WriteAll(records)
+	if err := w.Error(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return len(records), f.Close()
+}
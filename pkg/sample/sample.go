@@ -0,0 +1,186 @@
+// Package sample draws random samples of streams of rows in one pass and
+// in memory bounded by the sample size, so that representative subsets of
+// files too large to load can be used to prototype. Reservoir draws a
+// uniform sample, Stratified keeps the proportions of the strata, such as
+// the labels, and Weighted samples rows with probabilities proportional to
+// their weights.
+package sample
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Reservoir keeps a uniform random sample of at most k items of a stream:
+// after n items, every one of them is in the sample with probability k/n.
+type Reservoir[T any] struct {
+	k     int
+	seen  int
+	items []T
+	r     *rand.Rand
+}
+
+// NewReservoir returns an empty reservoir of k items drawing from r.
+func NewReservoir[T any](k int, r *rand.Rand) *Reservoir[T] {
+	return &Reservoir[T]{k: k, r: r}
+}
+
+// Add offers an item of the stream to the reservoir.
+func (s *Reservoir[T]) Add(item T) {
+	s.seen++
+	if len(s.items) < s.k {
+		s.items = append(s.items, item)
+		return
+	}
+	// Keep the item with probability k/seen, in place of a random one.
+	if j := s.r.Intn(s.seen); j < s.k {
+		s.items[j] = item
+	}
+}
+
+// Seen returns the number of items offered to the reservoir.
+func (s *Reservoir[T]) Seen() int {
+	return s.seen
+}
+
+// Items returns the sampled items in random order.
+func (s *Reservoir[T]) Items() []T {
+	// The position of an item in the reservoir depends on when it came,
+	// so shuffle them, which makes any prefix a uniform sample too.
+	items := append([]T(nil), s.items...)
+	s.r.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+	return items
+}
+
+// Stratified keeps a random sample of at most k items of a stream in
+// which every stratum has the same share as in the stream, rounded to
+// whole items. Every stratum keeps a reservoir of k items until the end
+// of the stream, when its share is known.
+type Stratified[T any] struct {
+	k      int
+	strata map[string]*Reservoir[T]
+	r      *rand.Rand
+}
+
+// NewStratified returns an empty sample of k items drawing from r.
+func NewStratified[T any](k int, r *rand.Rand) *Stratified[T] {
+	return &Stratified[T]{k: k, strata: make(map[string]*Reservoir[T]), r: r}
+}
+
+// Add offers an item of the given stratum to the sample.
+func (s *Stratified[T]) Add(stratum string, item T) {
+	res, ok := s.strata[stratum]
+	if !ok {
+		res = NewReservoir[T](s.k, s.r)
+		s.strata[stratum] = res
+	}
+	res.Add(item)
+}
+
+// Items returns the sampled items, grouped by stratum in the order of
+// their names.
+func (s *Stratified[T]) Items() []T {
+	names := make([]string, 0, len(s.strata))
+	var total int
+	for name, res := range s.strata {
+		names = append(names, name)
+		total += res.Seen()
+	}
+	sort.Strings(names)
+	if total == 0 {
+		return nil
+	}
+	// Share the k items between the strata by the largest remainder
+	// method, so that the shares add up to k.
+	k := min(s.k, total)
+	counts := make([]int, len(names))
+	remainders := make([]float64, len(names))
+	var allocated int
+	for i, name := range names {
+		quota := float64(k) * float64(s.strata[name].Seen()) / float64(total)
+		counts[i] = int(quota)
+		remainders[i] = quota - float64(counts[i])
+		allocated += counts[i]
+	}
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for _, i := range order[:k-allocated] {
+		counts[i]++
+	}
+	var items []T
+	for i, name := range names {
+		items = append(items, s.strata[name].Items()[:counts[i]]...)
+	}
+	return items
+}
+
+// keyed is an item of a weighted sample along with its key.
+type keyed[T any] struct {
+	key  float64
+	item T
+}
+
+// minHeap orders the items of a weighted sample by increasing key.
+type minHeap[T any] []keyed[T]
+
+func (h minHeap[T]) Len() int           { return len(h) }
+func (h minHeap[T]) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h minHeap[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap[T]) Push(x any)        { *h = append(*h, x.(keyed[T])) }
+func (h *minHeap[T]) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Weighted keeps a random sample without replacement of at most k items
+// of a stream, drawn with probabilities proportional to their weights.
+// It implements the A-Res algorithm of Efraimidis and Spirakis: every item
+// gets the key u^(1/w), for u uniform in (0, 1), and the k largest keys
+// are kept.
+type Weighted[T any] struct {
+	k    int
+	heap minHeap[T]
+	r    *rand.Rand
+}
+
+// NewWeighted returns an empty sample of k items drawing from r.
+func NewWeighted[T any](k int, r *rand.Rand) *Weighted[T] {
+	return &Weighted[T]{k: k, r: r}
+}
+
+// Add offers an item of the stream with the given weight. Items without
+// a positive weight are never sampled.
+func (s *Weighted[T]) Add(item T, weight float64) {
+	if !(weight > 0) || s.k < 1 {
+		return
+	}
+	// Compare the logarithms of the keys, log(u)/w, which do not
+	// underflow for small weights.
+	key := math.Log(1-s.r.Float64()) / weight
+	if len(s.heap) < s.k {
+		heap.Push(&s.heap, keyed[T]{key, item})
+		return
+	}
+	if key > s.heap[0].key {
+		s.heap[0] = keyed[T]{key, item}
+		heap.Fix(&s.heap, 0)
+	}
+}
+
+// Items returns the sampled items, by decreasing key.
+func (s *Weighted[T]) Items() []T {
+	sorted := append(minHeap[T](nil), s.heap...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].key > sorted[b].key })
+	items := make([]T, len(sorted))
+	for i, x := range sorted {
+		items[i] = x.item
+	}
+	return items
+}
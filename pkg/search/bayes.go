@@ -0,0 +1,149 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+)
+
+const (
+	// bayesStartup is the number of points BayesSearch draws at random
+	// before it has enough scores to propose points from.
+	bayesStartup = 10
+	// bayesCandidates is the number of points drawn from the densities of
+	// the good points, of which BayesSearch tries the most promising.
+	bayesCandidates = 24
+	// bayesGamma is the share of the scored points counted as good.
+	bayesGamma = 0.25
+)
+
+// BayesSearch scores trials points of the space on every fold, one point
+// after the other, and returns the results ranked like GridSearch. The
+// first points are drawn at random, the same as the first points of
+// RandomSearch with the same seed. Every later point is proposed by a
+// tree-structured Parzen estimator: the points scored so far are split
+// into the best quarter, the good points, and the others, the bad points;
+// a density is estimated per dimension on each, and of the points drawn
+// from the densities of the good points the one most likely among the
+// good rather than the bad points is scored next. The search so spends
+// its trials near the best points without leaving the rest of the space,
+// and finds good points in fewer trials than RandomSearch on spaces of a
+// few dimensions.
+//
+// The folds of a point are scored in parallel under the budget (see
+// GridSearchBudget), but the points are not, as every point depends on
+// the scores of those before it. The results depend only on the seed and
+// the scores.
+func BayesSearch(ctx context.Context, space *Space, trials int, seed uint64, folds []split.Fold, budget parallel.Budget, cost func(Params) parallel.Cost, score Scorer) ([]Result, error) {
+	if trials < 1 {
+		return nil, errors.New("search: no trials")
+	}
+	if len(folds) == 0 {
+		return nil, errors.New("search: no folds")
+	}
+	candidates := make([]Params, 0, trials)
+	scores := make([][]float64, 0, trials)
+	means := make([]float64, 0, trials)
+	for i := 0; i < trials; i++ {
+		r := rand.New(rand.NewSource(int64(parallel.Seed(seed, i))))
+		var p Params
+		if i < bayesStartup {
+			p = space.Sample(r)
+		} else {
+			p = space.suggest(r, candidates, means)
+		}
+		results, err := GridSearchBudget(ctx, []Params{p}, folds, budget, cost, score)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, p)
+		scores = append(scores, results[0].Scores)
+		means = append(means, results[0].Mean)
+	}
+	return rank(candidates, scores), nil
+}
+
+// suggest splits the scored points into the good and the bad ones by
+// their mean score and returns the most promising of bayesCandidates
+// points drawn from the densities of the good ones.
+func (s *Space) suggest(r *rand.Rand, points []Params, means []float64) Params {
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	// Sort the points best first, leaving those without a score last.
+	sort.SliceStable(order, func(a, b int) bool {
+		if math.IsNaN(means[order[b]]) {
+			return !math.IsNaN(means[order[a]])
+		}
+		return means[order[a]] > means[order[b]]
+	})
+	numGood := max(1, int(math.Ceil(bayesGamma*float64(len(points)))))
+	good := make([]Params, 0, numGood)
+	bad := make([]Params, 0, len(points)-numGood)
+	for k, i := range order {
+		if k < numGood {
+			good = append(good, points[i])
+		} else {
+			bad = append(bad, points[i])
+		}
+	}
+	var best Params
+	bestRatio := math.Inf(-1)
+	for k := 0; k < bayesCandidates; k++ {
+		p, ratio := s.propose(r, good, bad)
+		if best == nil || ratio > bestRatio {
+			best, bestRatio = p, ratio
+		}
+	}
+	return best
+}
+
+// parzen is a Parzen estimator on [0, 1]: an equal mixture of the uniform
+// density and of a normal kernel, truncated to [0, 1], at every center.
+type parzen struct {
+	centers []float64
+	width   float64
+}
+
+// newParzen returns the Parzen estimator of the centers, with kernels
+// narrowing as the centers grow in number.
+func newParzen(centers []float64) parzen {
+	return parzen{centers: centers, width: 0.3 * math.Pow(float64(len(centers)+1), -0.2)}
+}
+
+// sample draws a position from the density.
+func (pz parzen) sample(r *rand.Rand) float64 {
+	k := r.Intn(len(pz.centers) + 1)
+	if k == len(pz.centers) {
+		return r.Float64()
+	}
+	for {
+		t := pz.centers[k] + pz.width*r.NormFloat64()
+		if t >= 0 && t <= 1 {
+			return t
+		}
+	}
+}
+
+// density returns the density at position t.
+func (pz parzen) density(t float64) float64 {
+	d := 1.0
+	for _, c := range pz.centers {
+		// Scale the kernel by its mass inside [0, 1].
+		mass := normalCDF((1-c)/pz.width) - normalCDF(-c/pz.width)
+		z := (t - c) / pz.width
+		d += math.Exp(-z*z/2) / (pz.width * math.Sqrt(2*math.Pi) * mass)
+	}
+	return d / float64(len(pz.centers)+1)
+}
+
+// normalCDF returns the standard normal distribution function at x.
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
@@ -0,0 +1,137 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Cache stores the metrics of evaluated trials on disk, one JSON file per
+// trial, so that interrupted or repeated tuning runs skip the
+// configurations they already evaluated. Files are written to a
+// temporary name and renamed into place, so several processes can share
+// a cache directory.
+type Cache struct {
+	dir string
+}
+
+// cachedTrial is the content of a cache file.
+type cachedTrial struct {
+	Pipeline string             `json:"pipeline"`
+	Params   string             `json:"params"`
+	Data     string             `json:"data"`
+	Metrics  map[string]float64 `json:"metrics"`
+}
+
+// OpenCache returns the cache stored in dir, creating the directory when
+// needed.
+func OpenCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Trial identifies an evaluation: the pipeline being tuned, described by
+// any string that changes whenever its code or fixed settings do, the
+// parameters of the trial and the hash of the data it is evaluated on.
+type Trial struct {
+	Pipeline string
+	Params   Params
+	DataHash string
+}
+
+// Key returns the cache key of the trial.
+func (t Trial) Key() string {
+	h := sha256.New()
+	for _, part := range []string{t.Pipeline, t.Params.Format(), t.DataHash} {
+		io.WriteString(h, part)
+		// Separate the parts so that they cannot run into each other.
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashFiles returns the SHA-256 hash of the contents of the given files,
+// to be used as the data hash of a trial.
+func HashFiles(paths ...string) (string, error) {
+	h := sha256.New()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// path returns the file holding the trial.
+func (c *Cache) path(t Trial) string {
+	return filepath.Join(c.dir, t.Key()+".json")
+}
+
+// Get returns the metrics of the trial, if it was evaluated before.
+func (c *Cache) Get(t Trial) (map[string]float64, bool, error) {
+	data, err := os.ReadFile(c.path(t))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var cached cachedTrial
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false, err
+	}
+	return cached.Metrics, true, nil
+}
+
+// Put stores the metrics of the trial.
+func (c *Cache) Put(t Trial, metrics map[string]float64) error {
+	data, err := json.MarshalIndent(cachedTrial{
+		Pipeline: t.Pipeline,
+		Params:   t.Params.Format(),
+		Data:     t.DataHash,
+		Metrics:  metrics,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, "trial-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(t))
+}
+
+// Evaluate returns the cached metrics of the trial, or runs eval and
+// caches its metrics when the trial was not evaluated before. The
+// returned flag reports whether the metrics came from the cache.
+func (c *Cache) Evaluate(t Trial, eval func(Params) (map[string]float64, error)) (map[string]float64, bool, error) {
+	if metrics, ok, err := c.Get(t); err != nil || ok {
+		return metrics, ok, err
+	}
+	metrics, err := eval(t.Params)
+	if err != nil {
+		return nil, false, err
+	}
+	return metrics, false, c.Put(t, metrics)
+}
@@ -0,0 +1,278 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+)
+
+// Result is the cross-validated score of a candidate of a search.
+type Result struct {
+	// Rank orders the candidates from 1, the best mean score.
+	Rank int
+	// Trial numbers the candidates from 1 in the order they were given,
+	// or proposed by BayesSearch.
+	Trial  int
+	Params Params
+	// Scores holds the score of every fold.
+	Scores []float64
+	// Mean and Std are the mean and the population standard deviation of
+	// Scores.
+	Mean, Std float64
+}
+
+// GridSearch scores every candidate on every fold and returns the
+// results ranked by mean score, best first, with ties kept in the order
+// of the candidates. Scores are higher for better candidates. The pairs
+// of candidates and folds are scored on the given number of workers (see
+// parallel.Workers), so the scorer must be safe for concurrent use; the
+// results do not depend on the number of workers as long as the scorer
+// draws no random numbers from a shared source. Once ctx is cancelled, no
+// more pairs are scored and GridSearch returns the error of ctx.
+func GridSearch(ctx context.Context, candidates []Params, folds []split.Fold, workers int, score Scorer) ([]Result, error) {
+	return GridSearchBudget(ctx, candidates, folds, parallel.Budget{CPUs: workers}, nil, score)
+}
+
+// GridSearchBudget is like GridSearch, but scores at once only the pairs
+// of candidates and folds whose summed cost, estimated per candidate by
+// cost, fits the budget (see parallel.MapBudget). A nil cost counts every
+// pair as one CPU.
+func GridSearchBudget(ctx context.Context, candidates []Params, folds []split.Fold, budget parallel.Budget, cost func(Params) parallel.Cost, score Scorer) ([]Result, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("search: no candidates")
+	}
+	if len(folds) == 0 {
+		return nil, errors.New("search: no folds")
+	}
+	type scored struct {
+		score float64
+		err   error
+	}
+	var taskCost func(task int) parallel.Cost
+	if cost != nil {
+		taskCost = func(task int) parallel.Cost { return cost(candidates[task/len(folds)]) }
+	}
+	pairs := parallel.MapBudget(len(candidates)*len(folds), budget, taskCost, func(task int) scored {
+		if err := ctx.Err(); err != nil {
+			return scored{err: err}
+		}
+		p, fold := candidates[task/len(folds)], folds[task%len(folds)]
+		s, err := score(p, fold.Train, fold.Test)
+		return scored{s, err}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	scores := make([][]float64, len(candidates))
+	for i, p := range candidates {
+		scores[i] = make([]float64, len(folds))
+		for k := range folds {
+			pair := pairs[i*len(folds)+k]
+			if pair.err != nil {
+				return nil, fmt.Errorf("search: %s: fold %d: %w", p.Format(), k+1, pair.err)
+			}
+			scores[i][k] = pair.score
+		}
+	}
+	return rank(candidates, scores), nil
+}
+
+// RandomSearch is like GridSearchBudget on trials points drawn at random
+// from the space by Samples, which suits spaces of many dimensions or of
+// continuous ranges better than a grid of the same size.
+func RandomSearch(ctx context.Context, space *Space, trials int, seed uint64, folds []split.Fold, budget parallel.Budget, cost func(Params) parallel.Cost, score Scorer) ([]Result, error) {
+	if trials < 1 {
+		return nil, errors.New("search: no trials")
+	}
+	return GridSearchBudget(ctx, space.Samples(trials, seed), folds, budget, cost, score)
+}
+
+// PathScorer fits a model with the parameters on the training rows at
+// every value of a path parameter, in increasing order, each fit
+// continuing the previous one rather than starting over (a warm start),
+// and returns the score on the test rows at every value. Higher scores
+// are better.
+type PathScorer func(p Params, values []float64, train, test []int) ([]float64, error)
+
+// GridSearchPath is like GridSearchBudget, but the candidates that differ
+// only in the parameter named path, such as a number of epochs or of
+// trees, share a single fit per fold, which score walks along their
+// values. Every candidate must set the path parameter. The cost of a
+// shared fit is that of its largest value.
+func GridSearchPath(ctx context.Context, candidates []Params, folds []split.Fold, path string, budget parallel.Budget, cost func(Params) parallel.Cost, score PathScorer) ([]Result, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("search: no candidates")
+	}
+	if len(folds) == 0 {
+		return nil, errors.New("search: no folds")
+	}
+	// Group the candidates by their other parameters, in the order of
+	// their first candidate, each by increasing value of the path.
+	type group struct {
+		base    Params
+		members []int
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	for i, p := range candidates {
+		if !p.Has(path) {
+			return nil, fmt.Errorf("search: %s: no %s to warm start along", p.Format(), path)
+		}
+		base := p.clone()
+		delete(base, path)
+		g, ok := index[base.Format()]
+		if !ok {
+			g = &group{base: base}
+			index[base.Format()] = g
+			groups = append(groups, g)
+		}
+		g.members = append(g.members, i)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g.members, func(a, b int) bool {
+			return candidates[g.members[a]].Float(path) < candidates[g.members[b]].Float(path)
+		})
+	}
+	type scored struct {
+		scores []float64
+		err    error
+	}
+	var taskCost func(task int) parallel.Cost
+	if cost != nil {
+		taskCost = func(task int) parallel.Cost {
+			g := groups[task/len(folds)]
+			return cost(candidates[g.members[len(g.members)-1]])
+		}
+	}
+	paths := parallel.MapBudget(len(groups)*len(folds), budget, taskCost, func(task int) scored {
+		if err := ctx.Err(); err != nil {
+			return scored{err: err}
+		}
+		g, fold := groups[task/len(folds)], folds[task%len(folds)]
+		values := make([]float64, len(g.members))
+		for m, i := range g.members {
+			values[m] = candidates[i].Float(path)
+		}
+		s, err := score(g.base, values, fold.Train, fold.Test)
+		if err == nil && len(s) != len(values) {
+			err = fmt.Errorf("%d scores for %d values of %s", len(s), len(values), path)
+		}
+		return scored{s, err}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	scores := make([][]float64, len(candidates))
+	for i := range scores {
+		scores[i] = make([]float64, len(folds))
+	}
+	for n, g := range groups {
+		for k := range folds {
+			walk := paths[n*len(folds)+k]
+			if walk.err != nil {
+				return nil, fmt.Errorf("search: %s: fold %d: %w", g.base.Format(), k+1, walk.err)
+			}
+			for m, i := range g.members {
+				scores[i][k] = walk.scores[m]
+			}
+		}
+	}
+	return rank(candidates, scores), nil
+}
+
+// rank returns the results of the candidates from their score on every
+// fold, ranked by mean score.
+func rank(candidates []Params, scores [][]float64) []Result {
+	results := make([]Result, len(candidates))
+	for i, p := range candidates {
+		results[i] = Result{Trial: i + 1, Params: p, Scores: scores[i]}
+		for _, s := range scores[i] {
+			results[i].Mean += s / float64(len(scores[i]))
+		}
+		for _, s := range results[i].Scores {
+			results[i].Std += (s - results[i].Mean) * (s - results[i].Mean) / float64(len(scores[i]))
+		}
+		results[i].Std = math.Sqrt(results[i].Std)
+	}
+	// Rank the candidates, leaving those without a score last.
+	sort.SliceStable(results, func(a, b int) bool {
+		if math.IsNaN(results[b].Mean) {
+			return !math.IsNaN(results[a].Mean)
+		}
+		return results[a].Mean > results[b].Mean
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results
+}
+
+// ResultTable returns the results as table rows, for
+// artifacts.Run.WriteTable: the rank, the trial, a column per parameter, the mean
+// and standard deviation of the scores named after the metric, and a
+// column per fold.
+func ResultTable(results []Result, metric string) (columns []string, rows [][]any) {
+	// Collect the names of the parameters of every candidate, as the
+	// conditional dimensions set some of them only.
+	seen := make(map[string]bool)
+	var names []string
+	for _, r := range results {
+		for name := range r.Params {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	columns = append([]string{"rank", "trial"}, names...)
+	columns = append(columns, "mean_"+metric, "std_"+metric)
+	var numFolds int
+	if len(results) > 0 {
+		numFolds = len(results[0].Scores)
+	}
+	for k := 1; k <= numFolds; k++ {
+		columns = append(columns, "fold_"+strconv.Itoa(k))
+	}
+	rows = make([][]any, len(results))
+	for i, r := range results {
+		row := []any{r.Rank, r.Trial}
+		for _, name := range names {
+			v, ok := r.Params[name]
+			if !ok {
+				v = ""
+			}
+			row = append(row, v)
+		}
+		row = append(row, r.Mean, r.Std)
+		for _, s := range r.Scores {
+			row = append(row, s)
+		}
+		rows[i] = row
+	}
+	return columns, rows
+}
+
+// WriteResults prints the ranked candidates with their mean scores,
+// the best first, at most limit of them when limit is positive.
+func WriteResults(w io.Writer, results []Result, limit int) error {
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	if _, err := fmt.Fprintf(w, "%-5s %9s %9s  %s\n", "rank", "mean", "std", "params"); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if _, err := fmt.Fprintf(w, "%-5d %9.4f %9.4f  %s\n", r.Rank, r.Mean, r.Std, r.Params.Format()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
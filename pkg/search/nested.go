@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+)
+
+// Scorer fits a model with the parameters on the training rows and
+// returns its score on the test rows. Higher scores are better.
+type Scorer func(p Params, train, test []int) (float64, error)
+
+// OuterFold is the result of one outer fold of a nested cross-validation.
+type OuterFold struct {
+	// Index numbers the fold from 0.
+	Index int
+	// Best is the candidate with the best mean score over the inner folds.
+	Best Params
+	// InnerScore is the mean score of Best over the inner folds. As the
+	// best of several candidates on the same folds, it is optimistic.
+	InnerScore float64
+	// Score is the score of Best on the held-out outer fold, which took no
+	// part in choosing it.
+	Score float64
+}
+
+// NestedCV runs a nested cross-validation of the candidates. The rows,
+// one per element of strata, are dealt into outer folds. For every outer
+// fold, the candidates are scored on inner folds dealt from the remaining
+// rows only, and the best one is scored on the held-out fold. The mean of
+// the outer scores estimates the score of the whole tuning procedure,
+// without the optimism of scoring the chosen candidate on the folds that
+// chose it. Both levels are stratified by strata; give every row the same
+// stratum to not stratify. Once ctx is cancelled, no more candidates are
+// scored and NestedCV returns the error of ctx.
+func NestedCV(ctx context.Context, candidates []Params, strata []int, outer, inner int, seed int64, score Scorer) ([]OuterFold, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("search: no candidates")
+	}
+	outerFolds, err := split.RepeatedStratifiedKFold(strata, outer, 1, seed)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]OuterFold, len(outerFolds))
+	for k, fold := range outerFolds {
+		// Deal the inner folds from the training rows of the outer fold,
+		// each outer fold from its own seed.
+		trainStrata := make([]int, len(fold.Train))
+		for i, idx := range fold.Train {
+			trainStrata[i] = strata[idx]
+		}
+		innerFolds, err := split.RepeatedStratifiedKFold(trainStrata, inner, 1, int64(parallel.Seed(uint64(seed), k)))
+		if err != nil {
+			return nil, fmt.Errorf("search: outer fold %d: %w", k, err)
+		}
+		results[k] = OuterFold{Index: k, InnerScore: math.Inf(-1)}
+		for _, p := range candidates {
+			var sum float64
+			for _, f := range innerFolds {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				s, err := score(p, subset(fold.Train, f.Train), subset(fold.Train, f.Test))
+				if err != nil {
+					return nil, err
+				}
+				sum += s
+			}
+			if mean := sum / float64(len(innerFolds)); mean > results[k].InnerScore {
+				results[k].Best, results[k].InnerScore = p, mean
+			}
+		}
+		if results[k].Best == nil {
+			return nil, fmt.Errorf("search: outer fold %d: no candidate has a score", k)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if results[k].Score, err = score(results[k].Best, fold.Train, fold.Test); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// subset returns the rows at the given positions.
+func subset(rows, positions []int) []int {
+	out := make([]int, len(positions))
+	for i, p := range positions {
+		out[i] = rows[p]
+	}
+	return out
+}
+
+// NestedColumns names the columns of the rows returned by NestedRows.
+var NestedColumns = []string{"fold", "params", "inner_score", "score"}
+
+// NestedRows returns the outer folds as table rows, numbering the folds
+// from 1.
+func NestedRows(folds []OuterFold) [][]any {
+	rows := make([][]any, len(folds))
+	for i, f := range folds {
+		rows[i] = []any{f.Index + 1, f.Best.Format(), f.InnerScore, f.Score}
+	}
+	return rows
+}
+
+// WriteNested prints the chosen parameters and scores of every outer fold.
+func WriteNested(w io.Writer, folds []OuterFold) error {
+	if _, err := fmt.Fprintf(w, "%-6s %11s %9s  %s\n", "fold", "inner score", "score", "params"); err != nil {
+		return err
+	}
+	for _, f := range folds {
+		if _, err := fmt.Fprintf(w, "%-6d %11.2f %9.2f  %s\n", f.Index+1, f.InnerScore, f.Score, f.Best.Format()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NestedScores returns the mean and the population standard deviation of
+// the outer scores, and the mean of the inner scores.
+func NestedScores(folds []OuterFold) (mean, stdev, innerMean float64) {
+	for _, f := range folds {
+		mean += f.Score / float64(len(folds))
+		innerMean += f.InnerScore / float64(len(folds))
+	}
+	for _, f := range folds {
+		stdev += (f.Score - mean) * (f.Score - mean) / float64(len(folds))
+	}
+	return mean, math.Sqrt(stdev), innerMean
+}
@@ -0,0 +1,399 @@
+// Package search declares the search spaces explored by the tuners. A
+// space is a list of dimensions, each naming one feature or
+// hyperparameter, and can be sampled at random or expanded into a grid.
+// Conditional dimensions are only active when an earlier parameter has a
+// given value, so spaces such as "if penalty is l1 then the solver is
+// coordinate descent" are declared once and shared by every tuner:
+//
+//	space := search.New(
+//		search.Choice("features", "fico", "fico,lti"),
+//		search.LogUniform("learning_rate", 1e-3, 1),
+//		search.Choice("penalty", "l1", "l2"),
+//		search.Conditional("penalty", "l1", search.Choice("solver", "coordinate")),
+//		search.Conditional("penalty", "l2", search.Choice("solver", "gradient", "newton")),
+//	)
+//
+// GridSearch scores every point of a grid over a space, RandomSearch
+// points drawn at random, and BayesSearch points proposed one at a time
+// from the scores of the points before them. NestedCV evaluates a tuning
+// procedure over the points of a space without scoring the chosen point
+// on the folds that chose it.
+package search
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+)
+
+// Params holds one point of a search space, keyed by parameter name.
+type Params map[string]any
+
+// Float returns the named parameter as a float64.
+func (p Params) Float(name string) float64 {
+	switch v := p[name].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	panic(fmt.Sprintf("search: parameter %q is %T, not a number", name, p[name]))
+}
+
+// Int returns the named parameter as an int.
+func (p Params) Int(name string) int {
+	switch v := p[name].(type) {
+	case int:
+		return v
+	case float64:
+		return int(math.Round(v))
+	}
+	panic(fmt.Sprintf("search: parameter %q is %T, not a number", name, p[name]))
+}
+
+// String returns the named parameter as a string.
+func (p Params) String(name string) string {
+	return fmt.Sprint(p[name])
+}
+
+// Has reports whether the named parameter is set, which is not the case
+// for the dimensions of an inactive Conditional.
+func (p Params) Has(name string) bool {
+	_, ok := p[name]
+	return ok
+}
+
+// Format returns the parameters as sorted name=value pairs.
+func (p Params) Format() string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%v", name, p[name])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// clone returns a copy of the parameters.
+func (p Params) clone() Params {
+	c := make(Params, len(p))
+	for name, v := range p {
+		c[name] = v
+	}
+	return c
+}
+
+// Dimension is one axis of a search space.
+type Dimension interface {
+	// sample sets the dimension's parameters in p from r.
+	sample(p Params, r *rand.Rand)
+	// grid returns every extension of p with the dimension's grid
+	// values, using n points for continuous ranges.
+	grid(p Params, n int) []Params
+	// propose sets the dimension's parameters in p, drawn from a density
+	// estimated on the good points, and returns the log of the ratio of
+	// the densities of the drawn values among the good and the bad
+	// points (see BayesSearch).
+	propose(p Params, r *rand.Rand, good, bad []Params) float64
+}
+
+// choice is a categorical dimension.
+type choice struct {
+	name   string
+	values []any
+}
+
+// Choice declares a parameter taking one of the given values.
+func Choice(name string, values ...any) Dimension {
+	if len(values) == 0 {
+		panic(fmt.Sprintf("search: choice %q has no values", name))
+	}
+	return choice{name: name, values: values}
+}
+
+func (c choice) sample(p Params, r *rand.Rand) {
+	p[c.name] = c.values[r.Intn(len(c.values))]
+}
+
+func (c choice) grid(p Params, n int) []Params {
+	out := make([]Params, len(c.values))
+	for i, v := range c.values {
+		out[i] = p.clone()
+		out[i][c.name] = v
+	}
+	return out
+}
+
+func (c choice) propose(p Params, r *rand.Rand, good, bad []Params) float64 {
+	l, g := c.weights(good), c.weights(bad)
+	// Draw a value by its weight among the good points.
+	i, u := 0, r.Float64()
+	for ; i < len(l)-1; i++ {
+		u -= l[i]
+		if u < 0 {
+			break
+		}
+	}
+	p[c.name] = c.values[i]
+	return math.Log(l[i]) - math.Log(g[i])
+}
+
+// weights returns the share of every value among the points, counting
+// each value once more so that no value has a zero weight.
+func (c choice) weights(points []Params) []float64 {
+	w := make([]float64, len(c.values))
+	for i := range w {
+		w[i] = 1
+	}
+	total := float64(len(w))
+	for _, p := range points {
+		v, ok := p[c.name]
+		if !ok {
+			continue
+		}
+		for i, x := range c.values {
+			if x == v {
+				w[i]++
+				total++
+				break
+			}
+		}
+	}
+	for i := range w {
+		w[i] /= total
+	}
+	return w
+}
+
+// uniform is a continuous dimension, sampled uniformly either on the
+// values or on their logarithms, or the integers of a range.
+type uniform struct {
+	name     string
+	min, max float64
+	log      bool
+	integer  bool
+}
+
+// Uniform declares a parameter drawn uniformly from [min, max].
+func Uniform(name string, min, max float64) Dimension {
+	if min > max {
+		panic(fmt.Sprintf("search: uniform %q has min %v above max %v", name, min, max))
+	}
+	return uniform{name: name, min: min, max: max}
+}
+
+// LogUniform declares a positive parameter whose logarithm is drawn
+// uniformly, which suits scales such as learning rates and penalties.
+func LogUniform(name string, min, max float64) Dimension {
+	if min <= 0 || min > max {
+		panic(fmt.Sprintf("search: log-uniform %q needs 0 < min <= max, got [%v, %v]", name, min, max))
+	}
+	return uniform{name: name, min: min, max: max, log: true}
+}
+
+// IntUniform declares an integer parameter drawn uniformly from the
+// integers of [min, max], such as a depth or a number of trees.
+func IntUniform(name string, min, max int) Dimension {
+	if min > max {
+		panic(fmt.Sprintf("search: uniform %q has min %v above max %v", name, min, max))
+	}
+	// Widen the range by half on each side so that the bounds are drawn
+	// as often as the integers between them.
+	return uniform{name: name, min: float64(min) - 0.5, max: float64(max) + 0.5, integer: true}
+}
+
+// at maps t in [0, 1] to the range of the dimension.
+func (u uniform) at(t float64) float64 {
+	// Return the bounds exactly, which the log scale would round.
+	switch t {
+	case 0:
+		return u.min
+	case 1:
+		return u.max
+	}
+	if u.log {
+		return math.Exp(math.Log(u.min) + t*(math.Log(u.max)-math.Log(u.min)))
+	}
+	return u.min + t*(u.max-u.min)
+}
+
+// value returns the parameter at t in [0, 1].
+func (u uniform) value(t float64) any {
+	if u.integer {
+		// Keep the rounded bounds inside the range.
+		return int(math.Round(math.Min(math.Max(u.at(t), u.min+0.5), u.max-0.5)))
+	}
+	return u.at(t)
+}
+
+// position maps a value of the dimension back to [0, 1], the inverse of
+// at.
+func (u uniform) position(v float64) float64 {
+	if u.min == u.max {
+		return 0.5
+	}
+	if u.log {
+		return (math.Log(v) - math.Log(u.min)) / (math.Log(u.max) - math.Log(u.min))
+	}
+	return (v - u.min) / (u.max - u.min)
+}
+
+func (u uniform) sample(p Params, r *rand.Rand) {
+	p[u.name] = u.value(r.Float64())
+}
+
+func (u uniform) grid(p Params, n int) []Params {
+	if n < 2 || u.min == u.max {
+		c := p.clone()
+		c[u.name] = u.value(0.5)
+		return []Params{c}
+	}
+	out := make([]Params, 0, n)
+	for i := 0; i < n; i++ {
+		v := u.value(float64(i) / float64(n-1))
+		// Skip the integers already in the grid of a narrow range.
+		if len(out) > 0 && out[len(out)-1][u.name] == v {
+			continue
+		}
+		c := p.clone()
+		c[u.name] = v
+		out = append(out, c)
+	}
+	return out
+}
+
+func (u uniform) propose(p Params, r *rand.Rand, good, bad []Params) float64 {
+	l, g := u.parzen(good), u.parzen(bad)
+	t := l.sample(r)
+	p[u.name] = u.value(t)
+	return math.Log(l.density(t)) - math.Log(g.density(t))
+}
+
+// parzen returns the Parzen estimator of the positions of the values of
+// the points.
+func (u uniform) parzen(points []Params) parzen {
+	var centers []float64
+	for _, p := range points {
+		if p.Has(u.name) {
+			centers = append(centers, u.position(p.Float(u.name)))
+		}
+	}
+	return newParzen(centers)
+}
+
+// conditional activates its dimensions when a parameter has a value.
+type conditional struct {
+	param string
+	value any
+	dims  []Dimension
+}
+
+// Conditional declares dimensions that only exist when the parameter
+// param, declared earlier in the space, equals value.
+func Conditional(param string, value any, dims ...Dimension) Dimension {
+	return conditional{param: param, value: value, dims: dims}
+}
+
+// active reports whether the condition holds for p.
+func (c conditional) active(p Params) bool {
+	v, ok := p[c.param]
+	return ok && v == c.value
+}
+
+func (c conditional) sample(p Params, r *rand.Rand) {
+	if !c.active(p) {
+		return
+	}
+	for _, d := range c.dims {
+		d.sample(p, r)
+	}
+}
+
+func (c conditional) grid(p Params, n int) []Params {
+	if !c.active(p) {
+		return []Params{p}
+	}
+	return expand([]Params{p}, c.dims, n)
+}
+
+func (c conditional) propose(p Params, r *rand.Rand, good, bad []Params) float64 {
+	if !c.active(p) {
+		return 0
+	}
+	var ratio float64
+	for _, d := range c.dims {
+		ratio += d.propose(p, r, good, bad)
+	}
+	return ratio
+}
+
+// expand extends every point with the grid of every dimension in turn.
+func expand(points []Params, dims []Dimension, n int) []Params {
+	for _, d := range dims {
+		var next []Params
+		for _, p := range points {
+			next = append(next, d.grid(p, n)...)
+		}
+		points = next
+	}
+	return points
+}
+
+// Space is an ordered list of dimensions.
+type Space struct {
+	dims []Dimension
+}
+
+// New returns a space over the given dimensions. A Conditional must
+// come after the dimension it depends on.
+func New(dims ...Dimension) *Space {
+	return &Space{dims: dims}
+}
+
+// Sample draws a random point of the space from r.
+func (s *Space) Sample(r *rand.Rand) Params {
+	p := make(Params)
+	for _, d := range s.dims {
+		d.sample(p, r)
+	}
+	return p
+}
+
+// Samples draws n random points of the space. Point i is drawn from its
+// own random stream, derived from seed and i by parallel.Seed, so it does
+// not depend on how many points are drawn, and trials evaluated in
+// parallel (see parallel.Map) give the same results on any number of
+// workers.
+func (s *Space) Samples(n int, seed uint64) []Params {
+	points := make([]Params, n)
+	for i := range points {
+		points[i] = s.Sample(rand.New(rand.NewSource(int64(parallel.Seed(seed, i)))))
+	}
+	return points
+}
+
+// propose draws a point of the space from the densities of the good
+// points, and returns it with the log of the ratio of its densities
+// among the good and the bad points.
+func (s *Space) propose(r *rand.Rand, good, bad []Params) (Params, float64) {
+	p := make(Params)
+	var ratio float64
+	for _, d := range s.dims {
+		ratio += d.propose(p, r, good, bad)
+	}
+	return p, ratio
+}
+
+// Grid returns every point of the grid over the space, with n evenly
+// spaced values for the continuous dimensions (on the log scale for
+// LogUniform) and every value of the choices.
+func (s *Space) Grid(n int) []Params {
+	return expand([]Params{make(Params)}, s.dims, n)
+}
@@ -0,0 +1,272 @@
+// Package segment evaluates models per segment of the rows, such as FICO
+// score bands or loan purposes. A model can score well overall and still
+// fail a segment that holds few of the rows; scoring every segment on its
+// own and comparing it with the overall score shows where the model
+// underperforms.
+//
+// A segment spec names a column, whose distinct values are the segments,
+// or a numeric column followed by the edges of its bands:
+//
+//	purpose        one segment per loan purpose
+//	fico:650,700   fico < 650, 650 <= fico < 700 and fico >= 700
+package segment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+)
+
+// Spec defines the segments of a column.
+type Spec struct {
+	// Column names the column the rows are segmented by.
+	Column string
+	// Edges holds the ascending edges of the bands of a numeric column.
+	// Without edges, every distinct value is a segment.
+	Edges []float64
+}
+
+// Parse parses a spec, the column name followed, for bands, by a colon and
+// the comma separated edges.
+func Parse(spec string) (Spec, error) {
+	column, edges, banded := strings.Cut(spec, ":")
+	s := Spec{Column: strings.TrimSpace(column)}
+	if s.Column == "" {
+		return Spec{}, fmt.Errorf("segment: %q names no column", spec)
+	}
+	if !banded {
+		return s, nil
+	}
+	for _, field := range strings.Split(edges, ",") {
+		edge, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return Spec{}, fmt.Errorf("segment: %q: edge %q is not a number", spec, field)
+		}
+		if n := len(s.Edges); n > 0 && edge <= s.Edges[n-1] {
+			return Spec{}, fmt.Errorf("segment: %q: edges must be ascending", spec)
+		}
+		s.Edges = append(s.Edges, edge)
+	}
+	return s, nil
+}
+
+// String returns the spec as parsed by Parse.
+func (s Spec) String() string {
+	if len(s.Edges) == 0 {
+		return s.Column
+	}
+	edges := make([]string, len(s.Edges))
+	for i, edge := range s.Edges {
+		edges[i] = strconv.FormatFloat(edge, 'g', -1, 64)
+	}
+	return s.Column + ":" + strings.Join(edges, ",")
+}
+
+// Assign returns the segment of every row given the values of the
+// column: the value itself, or for bands the band it falls in, such as
+// "650 <= fico < 700".
+func (s Spec) Assign(values []string) ([]string, error) {
+	segments := make([]string, len(values))
+	for i, value := range values {
+		if len(s.Edges) == 0 {
+			segments[i] = s.Column + " = " + value
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("segment: row %d: column %q: %q is not a number", i+1, s.Column, value)
+		}
+		segments[i] = s.band(sort.Search(len(s.Edges), func(b int) bool { return s.Edges[b] > v }))
+	}
+	return segments, nil
+}
+
+// band returns the name of band b, which lies below edge b and at or
+// above edge b-1.
+func (s Spec) band(b int) string {
+	format := func(edge float64) string { return strconv.FormatFloat(edge, 'g', -1, 64) }
+	switch {
+	case b == 0:
+		return fmt.Sprintf("%s < %s", s.Column, format(s.Edges[0]))
+	case b == len(s.Edges):
+		return fmt.Sprintf("%s >= %s", s.Column, format(s.Edges[b-1]))
+	}
+	return fmt.Sprintf("%s <= %s < %s", format(s.Edges[b-1]), s.Column, format(s.Edges[b]))
+}
+
+// Metric scores predictions against the observed values.
+type Metric struct {
+	Name string
+	// HigherIsBetter is set for scores such as the accuracy, and unset
+	// for errors such as the RMSE.
+	HigherIsBetter bool
+	Score          func(observed, predicted []float64) float64
+}
+
+// FromMetric adapts a metric of the metrics registry, so that custom
+// metrics score segments too. The metric gets no probabilities, so those
+// needing them score NaN.
+func FromMetric(m metrics.Metric) Metric {
+	return Metric{Name: m.Name, HigherIsBetter: m.HigherIsBetter, Score: func(observed, predicted []float64) float64 {
+		return m.Func(observed, predicted, nil)
+	}}
+}
+
+// builtin adapts a built-in metric of the metrics registry.
+func builtin(name string) Metric {
+	m, err := metrics.Lookup(name)
+	if err != nil {
+		panic(err)
+	}
+	return FromMetric(m)
+}
+
+var (
+	// Accuracy is the share of the rows predicted as the observed class.
+	Accuracy = builtin("accuracy")
+	// BalancedAccuracy is the mean recall of the observed classes, which a
+	// model predicting the majority class cannot inflate.
+	BalancedAccuracy = builtin("balanced_accuracy")
+	// MAE is the mean absolute error of a regression.
+	MAE = builtin("mae")
+	// RMSE is the root mean squared error of a regression.
+	RMSE = builtin("rmse")
+)
+
+// Result is the score of a segment.
+type Result struct {
+	Segment string
+	// Rows is the number of rows of the segment.
+	Rows int
+	// Score is the metric on the rows of the segment.
+	Score float64
+	// Gap is how much better the segment scores than every row; it is
+	// negative for segments scoring worse, whichever way the metric runs.
+	Gap float64
+	// Under is set when the segment scores worse than every row by more
+	// than the tolerance and holds enough rows to tell.
+	Under bool
+}
+
+// Report holds the scores of the segments of a spec.
+type Report struct {
+	Spec   Spec
+	Metric string
+	// Overall is the metric on every row.
+	Overall float64
+	// Segments holds the results in the order of the segment names, or of
+	// the bands.
+	Segments []Result
+}
+
+// Config sets when a segment underperforms.
+type Config struct {
+	// Tolerance is the largest gap below the overall score accepted.
+	Tolerance float64
+	// MinRows is the fewest rows of a segment flagged as
+	// underperforming; smaller segments are reported but their scores
+	// are too noisy to flag.
+	MinRows int
+}
+
+// Evaluate scores the predictions of every segment, given the segment of
+// every row as returned by Spec.Assign.
+func Evaluate(spec Spec, segments []string, observed, predicted []float64, m Metric, cfg Config) (*Report, error) {
+	if len(observed) == 0 || len(predicted) != len(observed) || len(segments) != len(observed) {
+		return nil, errors.New("segment: segments, observed and predicted values must be non-empty and of the same length")
+	}
+	r := &Report{Spec: spec, Metric: m.Name, Overall: m.Score(observed, predicted)}
+	rows := make(map[string][]int)
+	for i, segment := range segments {
+		rows[segment] = append(rows[segment], i)
+	}
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(spec.Edges) > 0 {
+		// Order the bands from the lowest.
+		var bands []string
+		for b := 0; b <= len(spec.Edges); b++ {
+			if band := spec.band(b); rows[band] != nil {
+				bands = append(bands, band)
+			}
+		}
+		names = bands
+	}
+	for _, name := range names {
+		idx := rows[name]
+		obs, pred := make([]float64, len(idx)), make([]float64, len(idx))
+		for k, i := range idx {
+			obs[k], pred[k] = observed[i], predicted[i]
+		}
+		res := Result{Segment: name, Rows: len(idx), Score: m.Score(obs, pred)}
+		res.Gap = res.Score - r.Overall
+		if !m.HigherIsBetter {
+			res.Gap = -res.Gap
+		}
+		res.Under = res.Gap < -cfg.Tolerance && res.Rows >= cfg.MinRows
+		r.Segments = append(r.Segments, res)
+	}
+	return r, nil
+}
+
+// Underperforming returns the segments flagged as underperforming.
+func (r *Report) Underperforming() []Result {
+	return slices.DeleteFunc(slices.Clone(r.Segments), func(res Result) bool { return !res.Under })
+}
+
+// Columns are the column names of the rows returned by Rows.
+var Columns = []string{"spec", "segment", "rows", "metric", "score", "gap", "underperforms"}
+
+// Rows returns a row per segment, for artifacts.Run.WriteTable.
+func (r *Report) Rows() [][]any {
+	rows := make([][]any, len(r.Segments))
+	for i, res := range r.Segments {
+		rows[i] = []any{r.Spec.String(), res.Segment, res.Rows, r.Metric, res.Score, res.Gap, res.Under}
+	}
+	return rows
+}
+
+// Write prints the overall score and a table of the segments, marking
+// the underperforming ones.
+func (r *Report) Write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Segments of %s (%s overall = %.4f)\n", r.Spec.Column, r.Metric, r.Overall); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "segment\trows\t%s\tgap\t\n", r.Metric)
+	for _, res := range r.Segments {
+		flag := ""
+		if res.Under {
+			flag = "underperforms"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.4f\t%+.4f\t%s\n", res.Segment, res.Rows, res.Score, res.Gap, flag)
+	}
+	return tw.Flush()
+}
+
+// WriteMarkdown writes the report as a Markdown section, for model cards.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Segments of %s\n\n", r.Spec.Column)
+	fmt.Fprintf(&b, "Overall %s: %.4f.\n\n", r.Metric, r.Overall)
+	fmt.Fprintf(&b, "| segment | rows | %s | gap | |\n|---|---:|---:|---:|---|\n", r.Metric)
+	for _, res := range r.Segments {
+		flag := ""
+		if res.Under {
+			flag = "**underperforms**"
+		}
+		fmt.Fprintf(&b, "| %s | %d | %.4f | %+.4f | %s |\n", res.Segment, res.Rows, res.Score, res.Gap, flag)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
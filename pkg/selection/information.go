@@ -0,0 +1,287 @@
+package selection
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Criterion is the statistic features are ranked by against class labels.
+type Criterion int
+
+const (
+	// MutualInformation ranks the features by how much knowing their bin
+	// tells of the class, in nats. It catches any dependence, not only
+	// monotonic ones.
+	MutualInformation Criterion = iota
+	// ChiSquared ranks the features by the p-value of the chi-squared test
+	// of independence of their bins and the classes, the smallest first.
+	ChiSquared
+)
+
+// String returns the name of the criterion.
+func (c Criterion) String() string {
+	switch c {
+	case MutualInformation:
+		return "mi"
+	case ChiSquared:
+		return "chi2"
+	}
+	return fmt.Sprintf("Criterion(%d)", int(c))
+}
+
+// ParseCriterion returns the criterion of the name, mi or chi2.
+func ParseCriterion(name string) (Criterion, error) {
+	switch name {
+	case "mi":
+		return MutualInformation, nil
+	case "chi2":
+		return ChiSquared, nil
+	}
+	return 0, fmt.Errorf("selection: unknown criterion %q, expected mi or chi2", name)
+}
+
+// DefaultBins is the number of bins of the numeric features when none is
+// given.
+const DefaultBins = 10
+
+// Relevance holds the statistics of a feature against class labels,
+// computed on the contingency table of its bins and the classes.
+type Relevance struct {
+	// Index is the column of the feature.
+	Index int
+	// Bins is the number of bins of the feature: its distinct values when
+	// they are few, quantile bins otherwise.
+	Bins int
+	// MutualInformation is the mutual information of the bins and the
+	// classes, in nats.
+	MutualInformation float64
+	// ChiSquared is the chi-squared statistic of the table, with
+	// DegreesOfFreedom, and PValue the probability of a statistic as large
+	// if the feature were independent of the class.
+	ChiSquared       float64
+	DegreesOfFreedom int
+	PValue           float64
+}
+
+// RankClasses bins every column of x into at most bins quantile bins,
+// DefaultBins if 0, and ranks the columns by the criterion of their
+// relevance to the class labels, the most relevant first. Columns with no
+// more distinct values than bins keep one bin per value, so that binary
+// and categorical codes are counted as they are. Values and labels must
+// not be NaN.
+func RankClasses(x mat64.Matrix, labels []float64, c Criterion, bins int) ([]Relevance, error) {
+	numRows, numFeatures := x.Dims()
+	if numRows != len(labels) {
+		return nil, fmt.Errorf("selection: %d rows and %d labels", numRows, len(labels))
+	}
+	if numRows < 2 {
+		return nil, errors.New("selection: want at least 2 rows")
+	}
+	if c != MutualInformation && c != ChiSquared {
+		return nil, fmt.Errorf("selection: invalid criterion %v", c)
+	}
+	if bins == 0 {
+		bins = DefaultBins
+	}
+	if bins < 2 {
+		return nil, fmt.Errorf("selection: %d bins, want at least 2", bins)
+	}
+	for i, label := range labels {
+		if math.IsNaN(label) {
+			return nil, fmt.Errorf("selection: row %d: the label is NaN", i+1)
+		}
+	}
+	classes, numClasses := codes(labels)
+	column := make([]float64, numRows)
+	ranking := make([]Relevance, numFeatures)
+	for j := 0; j < numFeatures; j++ {
+		mat64.Col(column, j, x)
+		for i, v := range column {
+			if math.IsNaN(v) {
+				return nil, fmt.Errorf("selection: row %d: column %d is NaN", i+1, j)
+			}
+		}
+		binned, numBins := binValues(column, bins)
+		table := make([][]float64, numBins)
+		for b := range table {
+			table[b] = make([]float64, numClasses)
+		}
+		for i, b := range binned {
+			table[b][classes[i]]++
+		}
+		r := Relevance{Index: j, Bins: numBins, MutualInformation: mutualInformation(table)}
+		r.ChiSquared, r.DegreesOfFreedom = chiSquared(table)
+		r.PValue = 1
+		if r.DegreesOfFreedom > 0 {
+			r.PValue = chiSquaredSurvival(r.ChiSquared, r.DegreesOfFreedom)
+		}
+		ranking[j] = r
+	}
+	sort.SliceStable(ranking, func(a, b int) bool {
+		ra, rb := ranking[a], ranking[b]
+		if c == MutualInformation {
+			return ra.MutualInformation > rb.MutualInformation
+		}
+		// Strong features all have p-values of 0, so their statistics
+		// break the tie.
+		if ra.PValue != rb.PValue {
+			return ra.PValue < rb.PValue
+		}
+		return ra.ChiSquared > rb.ChiSquared
+	})
+	return ranking, nil
+}
+
+// codes returns the index of the class of every label, classes numbered
+// in sorted order, and the number of classes.
+func codes(labels []float64) ([]int, int) {
+	sorted := append([]float64(nil), labels...)
+	sort.Float64s(sorted)
+	sorted = distinct(sorted)
+	classes := make([]int, len(labels))
+	for i, label := range labels {
+		classes[i] = sort.SearchFloat64s(sorted, label)
+	}
+	return classes, len(sorted)
+}
+
+// binValues returns the bin of every value and the number of bins. With
+// more than bins distinct values, the bins are bounded by the quantiles
+// of the values, equal values always sharing a bin.
+func binValues(values []float64, bins int) ([]int, int) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	edges := distinct(append([]float64(nil), sorted...))
+	numBins := len(edges)
+	if len(edges) > bins {
+		// Bin k holds the values above k of the edges.
+		quantiles := make([]float64, 0, bins-1)
+		for k := 1; k < bins; k++ {
+			quantiles = append(quantiles, sorted[k*len(sorted)/bins])
+		}
+		edges = distinct(quantiles)
+		numBins = len(edges) + 1
+	}
+	binned := make([]int, len(values))
+	for i, v := range values {
+		binned[i] = sort.SearchFloat64s(edges, v)
+	}
+	return binned, numBins
+}
+
+// distinct removes the repeated values of the sorted values in place.
+func distinct(sorted []float64) []float64 {
+	out := sorted[:0]
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// mutualInformation returns the mutual information, in nats, of the rows
+// and the columns of the contingency table.
+func mutualInformation(table [][]float64) float64 {
+	rowSums, colSums, total := margins(table)
+	var mi float64
+	for b, row := range table {
+		for k, n := range row {
+			if n > 0 {
+				mi += n / total * math.Log(n*total/(rowSums[b]*colSums[k]))
+			}
+		}
+	}
+	return math.Max(mi, 0)
+}
+
+// chiSquared returns the chi-squared statistic of independence of the
+// contingency table and its degrees of freedom, counting the non-empty
+// rows and columns only.
+func chiSquared(table [][]float64) (float64, int) {
+	rowSums, colSums, total := margins(table)
+	var stat float64
+	for b, row := range table {
+		for k, n := range row {
+			if expected := rowSums[b] * colSums[k] / total; expected > 0 {
+				stat += (n - expected) * (n - expected) / expected
+			}
+		}
+	}
+	return stat, (nonZero(rowSums) - 1) * (nonZero(colSums) - 1)
+}
+
+// margins returns the row and column sums of the table and its total.
+func margins(table [][]float64) (rowSums, colSums []float64, total float64) {
+	rowSums = make([]float64, len(table))
+	colSums = make([]float64, len(table[0]))
+	for b, row := range table {
+		for k, n := range row {
+			rowSums[b] += n
+			colSums[k] += n
+			total += n
+		}
+	}
+	return rowSums, colSums, total
+}
+
+func nonZero(values []float64) int {
+	var n int
+	for _, v := range values {
+		if v != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// chiSquaredSurvival returns the probability that a chi-squared variable
+// of df degrees of freedom exceeds stat: the regularized upper incomplete
+// gamma function Q(df/2, stat/2).
+func chiSquaredSurvival(stat float64, df int) float64 {
+	a, x := float64(df)/2, stat/2
+	if x <= 0 {
+		return 1
+	}
+	lnPrefix := a*math.Log(x) - x
+	lgamma, _ := math.Lgamma(a)
+	if x < a+1 {
+		// Sum the series of the lower function P and complement it.
+		term := 1 / a
+		sum := term
+		for n := 1; n < 500 && math.Abs(term) > math.Abs(sum)*1e-15; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+		}
+		return math.Max(0, 1-sum*math.Exp(lnPrefix-lgamma))
+	}
+	// Evaluate the continued fraction of Q with the modified Lentz method.
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for n := 1; n < 500; n++ {
+		an := -float64(n) * (float64(n) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(lnPrefix-lgamma) * h
+}
@@ -0,0 +1,233 @@
+package selection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Weighted is a fitted model that weighs its features, such as a linear
+// or logistic regression by its coefficients.
+type Weighted interface {
+	// Predict returns the prediction of a row of features.
+	Predict(row []float64) (float64, error)
+	// Importance returns the weight of every feature. The weights must be
+	// comparable across features, such as the coefficients of
+	// standardized features.
+	Importance() []float64
+}
+
+// Prober is implemented by the Weighted classifiers that predict the
+// probability of class 1, which metrics such as the AUC need.
+type Prober interface {
+	Probability(row []float64) (float64, error)
+}
+
+// FitFunc fits a model on the rows of x and their targets y.
+type FitFunc func(ctx context.Context, x *mat64.Dense, y []float64) (Weighted, error)
+
+// EliminationConfig configures Eliminate.
+type EliminationConfig struct {
+	// Metric scores the subsets of features on the test rows of the folds.
+	Metric metrics.Metric
+	// MinFeatures is the smallest subset scored, 1 if 0.
+	MinFeatures int
+	// Step is the number of features dropped at once, 1 if 0.
+	Step int
+	// Workers is the number of folds fitted in parallel (see
+	// parallel.Workers).
+	Workers int
+}
+
+// Subset holds the cross-validated score of a subset of the features.
+type Subset struct {
+	// Features holds the columns of the subset, in column order.
+	Features []int
+	// Score is the mean metric of the folds and SE its standard error.
+	Score, SE float64
+	// Dropped holds the columns dropped from the subset to make the next
+	// one, the weakest first, and is empty for the last subset.
+	Dropped []int
+}
+
+// Elimination is the result of a recursive feature elimination.
+type Elimination struct {
+	// Subsets holds the subsets of features from every feature down to
+	// the smallest one.
+	Subsets []Subset
+	// Best is the index of the subset of the best score, by the
+	// orientation of the metric, the smallest one on ties.
+	Best int
+}
+
+// Ranking returns the columns in the order they are eliminated in
+// reverse: the last kept first, then the features dropped last.
+func (e *Elimination) Ranking() []int {
+	last := e.Subsets[len(e.Subsets)-1]
+	ranking := append([]int(nil), last.Features...)
+	for s := len(e.Subsets) - 2; s >= 0; s-- {
+		dropped := e.Subsets[s].Dropped
+		for k := len(dropped) - 1; k >= 0; k-- {
+			ranking = append(ranking, dropped[k])
+		}
+	}
+	return ranking
+}
+
+// Eliminate runs a recursive feature elimination: starting from every
+// column of x, it scores the subset of features by cross-validation on
+// the folds, fits the model on every row and drops the features of the
+// smallest absolute weight, until MinFeatures are left. The elimination
+// order comes from fits on every row, including the test rows of the
+// folds, so the best score is slightly optimistic; score the chosen
+// subset on held out rows.
+func Eliminate(ctx context.Context, x *mat64.Dense, y []float64, folds []split.Fold, fit FitFunc, cfg EliminationConfig) (*Elimination, error) {
+	numRows, numFeatures := x.Dims()
+	if numRows != len(y) {
+		return nil, fmt.Errorf("selection: %d rows and %d targets", numRows, len(y))
+	}
+	if len(folds) == 0 {
+		return nil, errors.New("selection: no folds")
+	}
+	if cfg.Metric.Func == nil {
+		return nil, errors.New("selection: no metric")
+	}
+	minFeatures, step := max(cfg.MinFeatures, 1), max(cfg.Step, 1)
+	if minFeatures > numFeatures {
+		return nil, fmt.Errorf("selection: %d features, fewer than the %d to keep", numFeatures, minFeatures)
+	}
+	features := make([]int, numFeatures)
+	for j := range features {
+		features[j] = j
+	}
+	e := &Elimination{}
+	for {
+		s := Subset{Features: features}
+		scores, err := split.CrossValidate(ctx, folds, cfg.Workers, 0, func(k int, fold split.Fold, _ *rand.Rand) (float64, error) {
+			m, err := fit(ctx, subMatrix(x, fold.Train, features), pick(y, fold.Train))
+			if err != nil {
+				return 0, err
+			}
+			return score(m, subMatrix(x, fold.Test, features), pick(y, fold.Test), cfg.Metric)
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.Score, s.SE = meanSE(scores)
+		if len(features) <= minFeatures {
+			e.Subsets = append(e.Subsets, s)
+			break
+		}
+		// Fit on every row to find the weakest features.
+		m, err := fit(ctx, subMatrix(x, nil, features), y)
+		if err != nil {
+			return nil, err
+		}
+		weights := m.Importance()
+		if len(weights) != len(features) {
+			return nil, fmt.Errorf("selection: %d weights for %d features", len(weights), len(features))
+		}
+		drop := min(step, len(features)-minFeatures)
+		weakest := make([]bool, len(features))
+		for range drop {
+			k := -1
+			for j, w := range weights {
+				if !weakest[j] && (k < 0 || math.Abs(w) < math.Abs(weights[k])) {
+					k = j
+				}
+			}
+			weakest[k] = true
+			s.Dropped = append(s.Dropped, features[k])
+		}
+		var next []int
+		for j, column := range features {
+			if !weakest[j] {
+				next = append(next, column)
+			}
+		}
+		e.Subsets = append(e.Subsets, s)
+		features = next
+	}
+	for s, subset := range e.Subsets {
+		best := e.Subsets[e.Best].Score
+		if subset.Score == best || (subset.Score > best) == cfg.Metric.HigherIsBetter {
+			e.Best = s
+		}
+	}
+	return e, nil
+}
+
+// score returns the metric of the predictions of the model on the rows of
+// x.
+func score(m Weighted, x *mat64.Dense, y []float64, metric metrics.Metric) (float64, error) {
+	numRows, _ := x.Dims()
+	predicted := make([]float64, numRows)
+	var proba []float64
+	prober, ok := m.(Prober)
+	if ok {
+		proba = make([]float64, numRows)
+	}
+	for i := range predicted {
+		row := x.RawRowView(i)
+		var err error
+		if predicted[i], err = m.Predict(row); err != nil {
+			return 0, err
+		}
+		if ok {
+			if proba[i], err = prober.Probability(row); err != nil {
+				return 0, err
+			}
+		}
+	}
+	s := metric.Func(y, predicted, proba)
+	if math.IsNaN(s) {
+		return 0, fmt.Errorf("selection: metric %q scores NaN", metric.Name)
+	}
+	return s, nil
+}
+
+// subMatrix returns the rows of x, every row if nil, with the columns.
+func subMatrix(x *mat64.Dense, rows, columns []int) *mat64.Dense {
+	if rows == nil {
+		n, _ := x.Dims()
+		rows = make([]int, n)
+		for i := range rows {
+			rows[i] = i
+		}
+	}
+	sub := mat64.NewDense(len(rows), len(columns), nil)
+	for i, row := range rows {
+		for k, j := range columns {
+			sub.Set(i, k, x.At(row, j))
+		}
+	}
+	return sub
+}
+
+// pick returns the values at the indices.
+func pick(values []float64, indices []int) []float64 {
+	picked := make([]float64, len(indices))
+	for k, i := range indices {
+		picked[k] = values[i]
+	}
+	return picked
+}
+
+// meanSE returns the mean of the values and its standard error.
+func meanSE(values []float64) (float64, float64) {
+	m := mean(values)
+	if len(values) < 2 {
+		return m, 0
+	}
+	var ss float64
+	for _, v := range values {
+		ss += (v - m) * (v - m)
+	}
+	return m, math.Sqrt(ss / float64(len(values)-1) / float64(len(values)))
+}
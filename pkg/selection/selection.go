@@ -0,0 +1,218 @@
+// Package selection ranks features by their correlation with the target
+// and filters out those unlikely to help a model: near-constant columns,
+// whose variance does not exceed a threshold, and columns only weakly
+// correlated with the target. Pearson's correlation measures how linear
+// the relation of a feature with the target is; Spearman's, the Pearson
+// correlation of their ranks, how monotonic it is, so it is robust to
+// outliers and to skewed features. RankClasses ranks features against
+// class labels instead, by mutual information or a chi-squared test.
+package selection
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Method is the correlation the features are ranked by.
+type Method int
+
+const (
+	// Pearson ranks the features by their linear correlation with the
+	// target.
+	Pearson Method = iota
+	// Spearman ranks the features by their rank correlation with the
+	// target.
+	Spearman
+)
+
+// String returns the name of the method.
+func (m Method) String() string {
+	switch m {
+	case Pearson:
+		return "pearson"
+	case Spearman:
+		return "spearman"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// ParseMethod returns the method of the name, pearson or spearman.
+func ParseMethod(name string) (Method, error) {
+	switch name {
+	case "pearson":
+		return Pearson, nil
+	case "spearman":
+		return Spearman, nil
+	}
+	return 0, fmt.Errorf("selection: unknown correlation %q, expected pearson or spearman", name)
+}
+
+// Config holds the thresholds of the filters.
+type Config struct {
+	// Method is the correlation the features are ranked and filtered by.
+	Method Method
+	// MinVariance drops the features whose variance does not exceed it.
+	// The zero value drops the constant features only.
+	MinVariance float64
+	// MinCorrelation drops the features whose absolute correlation with
+	// the target, by Method, is below it (0 keeps them all).
+	MinCorrelation float64
+}
+
+// Reasons features are dropped for.
+const (
+	NearConstant     = "near-constant"
+	WeaklyCorrelated = "weakly correlated"
+)
+
+// Feature holds the statistics of a feature.
+type Feature struct {
+	// Index is the column of the feature.
+	Index int
+	// Variance is the variance of the feature.
+	Variance float64
+	// Pearson and Spearman are the correlations of the feature with the
+	// target, 0 for constant features.
+	Pearson, Spearman float64
+	// Dropped is the reason the feature is dropped, empty when it is
+	// kept.
+	Dropped string
+}
+
+// Correlation returns the correlation of the feature by the method.
+func (f Feature) Correlation(m Method) float64 {
+	if m == Spearman {
+		return f.Spearman
+	}
+	return f.Pearson
+}
+
+// Report ranks the features.
+type Report struct {
+	// Method is the correlation the features are ranked by.
+	Method Method
+	// Features holds the kept features, the most correlated first, and
+	// then the dropped ones, in the same order.
+	Features []Feature
+}
+
+// Selected returns the columns of the kept features, in rank order.
+func (r *Report) Selected() []int {
+	var columns []int
+	for _, f := range r.Features {
+		if f.Dropped == "" {
+			columns = append(columns, f.Index)
+		}
+	}
+	return columns
+}
+
+// Rank computes the variance of every column of x and its correlations
+// with the target y, drops the features that fail the filters of cfg and
+// ranks the others by their absolute correlation.
+func Rank(x mat64.Matrix, y []float64, cfg Config) (*Report, error) {
+	numRows, numFeatures := x.Dims()
+	if numRows != len(y) {
+		return nil, fmt.Errorf("selection: %d rows and %d targets", numRows, len(y))
+	}
+	if numRows < 2 {
+		return nil, errors.New("selection: want at least 2 rows")
+	}
+	if cfg.Method != Pearson && cfg.Method != Spearman {
+		return nil, fmt.Errorf("selection: invalid method %v", cfg.Method)
+	}
+	r := &Report{Method: cfg.Method}
+	column := make([]float64, numRows)
+	for j := 0; j < numFeatures; j++ {
+		mat64.Col(column, j, x)
+		f := Feature{
+			Index:    j,
+			Variance: variance(column),
+			Pearson:  Correlation(column, y),
+			Spearman: RankCorrelation(column, y),
+		}
+		switch {
+		case f.Variance <= cfg.MinVariance:
+			f.Dropped = NearConstant
+		case math.Abs(f.Correlation(cfg.Method)) < cfg.MinCorrelation:
+			f.Dropped = WeaklyCorrelated
+		}
+		r.Features = append(r.Features, f)
+	}
+	sort.SliceStable(r.Features, func(a, b int) bool {
+		fa, fb := r.Features[a], r.Features[b]
+		if (fa.Dropped == "") != (fb.Dropped == "") {
+			return fa.Dropped == ""
+		}
+		return math.Abs(fa.Correlation(cfg.Method)) > math.Abs(fb.Correlation(cfg.Method))
+	})
+	return r, nil
+}
+
+// Correlation returns the Pearson correlation of x and y, or 0 when
+// either is constant.
+func Correlation(x, y []float64) float64 {
+	mx, my := mean(x), mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// RankCorrelation returns the Spearman correlation of x and y: the
+// Pearson correlation of their ranks, ties taking their average rank.
+func RankCorrelation(x, y []float64) float64 {
+	return Correlation(ranks(x), ranks(y))
+}
+
+// ranks returns the rank of every value, from 1, ties taking the average
+// of their ranks.
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+	r := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		// Ranks start+1 to end average to (start+end+1)/2.
+		for _, i := range order[start:end] {
+			r[i] = float64(start+end+1) / 2
+		}
+		start = end
+	}
+	return r
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// variance returns the population variance of the values.
+func variance(values []float64) float64 {
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,156 @@
+// Package shift detects covariate shift between two samples of rows with
+// a classifier two-sample test. A random forest is trained to tell the
+// rows of a reference sample, such as the training data, from the rows of
+// a current sample, such as the rows served since. When both come from
+// the same distribution no classifier can do better than chance, so the
+// cross-validated AUC of the forest stays near 0.5; an AUC well above it
+// shows that the samples differ. Unlike a per-feature drift score, the
+// test also catches shifts in the joint distribution of the features, and
+// the feature importances of the forest name the features that separate
+// the samples most.
+package shift
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/forest"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Config holds the settings of the test.
+type Config struct {
+	// NumTrees is the number of trees of every forest (0 uses 100).
+	NumTrees int
+	// Folds is the number of cross-validation folds the rows are scored
+	// out of (0 uses 5).
+	Folds int
+	// Params limits the growth of the trees.
+	Params tree.Params
+	// Seed seeds the folds and the forests.
+	Seed uint64
+	// Workers is the number of trees grown in parallel (see
+	// parallel.Workers).
+	Workers int
+}
+
+// Feature is the importance of a feature in telling the samples apart.
+type Feature struct {
+	// Index is the column of the feature.
+	Index int
+	// Importance is the mean impurity decrease of the feature in the
+	// forests, normalized to sum to 1 over the features.
+	Importance float64
+}
+
+// Result is the outcome of the test.
+type Result struct {
+	// AUC is the area under the ROC curve of the out-of-fold
+	// probabilities that the rows belong to the current sample.
+	AUC float64
+	// Reference and Current are the numbers of rows of the samples.
+	Reference, Current int
+	// Features holds every feature, the most important first.
+	Features []Feature
+}
+
+// Shifted reports whether the AUC reaches threshold, such as 0.6.
+func (r *Result) Shifted(threshold float64) bool {
+	return r.AUC >= threshold
+}
+
+// Test trains forests to tell the rows of reference, labeled 0, from the
+// rows of current, labeled 1, and scores every row with the forest of the
+// fold that held it out. Both matrices must have the same columns. It
+// stops with the error of ctx when ctx is cancelled.
+func Test(ctx context.Context, reference, current mat64.Matrix, cfg Config) (*Result, error) {
+	numRef, numFeatures := reference.Dims()
+	numCur, curFeatures := current.Dims()
+	if numFeatures != curFeatures {
+		return nil, fmt.Errorf("shift: reference rows have %d features, current rows %d", numFeatures, curFeatures)
+	}
+	numFolds := cfg.folds()
+	if numFolds < 2 {
+		return nil, fmt.Errorf("shift: %d folds, want at least 2", numFolds)
+	}
+	if numRef < numFolds || numCur < numFolds {
+		return nil, fmt.Errorf("shift: %d reference and %d current rows, want at least %d of each", numRef, numCur, numFolds)
+	}
+	// Stack the samples and label the current rows 1.
+	x := mat64.NewDense(numRef+numCur, numFeatures, nil)
+	x.Stack(reference, current)
+	y := make([]float64, numRef+numCur)
+	strata := make([]int, len(y))
+	for i := numRef; i < len(y); i++ {
+		y[i], strata[i] = 1, 1
+	}
+	// Score every row out of fold, with folds keeping the share of
+	// current rows, and average the importances of the forests.
+	r := rand.New(rand.NewSource(int64(cfg.Seed)))
+	folds := split.FoldSets(split.StratifiedFolds(strata, numFolds, r), numFolds)
+	scores := make([]float64, len(y))
+	importances := make([]float64, numFeatures)
+	for k, fold := range folds {
+		f := forest.New(tree.Classification, cfg.numTrees(), cfg.Params, cfg.Seed+uint64(k))
+		f.Workers = cfg.Workers
+		if err := f.Fit(ctx, subset(x, fold.Train), pick(y, fold.Train), nil); err != nil {
+			return nil, err
+		}
+		for _, i := range fold.Test {
+			scores[i] = f.PredictProba(x.RawRowView(i))[1]
+		}
+		for j, v := range f.Importances {
+			importances[j] += v / float64(numFolds)
+		}
+	}
+	auc, err := metrics.AUC(y, scores)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{AUC: auc, Reference: numRef, Current: numCur}
+	for j, v := range importances {
+		res.Features = append(res.Features, Feature{Index: j, Importance: v})
+	}
+	sort.SliceStable(res.Features, func(a, b int) bool {
+		return res.Features[a].Importance > res.Features[b].Importance
+	})
+	return res, nil
+}
+
+func (cfg Config) numTrees() int {
+	if cfg.NumTrees > 0 {
+		return cfg.NumTrees
+	}
+	return 100
+}
+
+func (cfg Config) folds() int {
+	if cfg.Folds > 0 {
+		return cfg.Folds
+	}
+	return 5
+}
+
+// subset returns the given rows of x.
+func subset(x *mat64.Dense, rows []int) *mat64.Dense {
+	_, numCols := x.Dims()
+	s := mat64.NewDense(len(rows), numCols, nil)
+	for i, row := range rows {
+		s.SetRow(i, x.RawRowView(row))
+	}
+	return s
+}
+
+// pick returns the given values.
+func pick(values []float64, rows []int) []float64 {
+	s := make([]float64, len(rows))
+	for i, row := range rows {
+		s[i] = values[row]
+	}
+	return s
+}
@@ -0,0 +1,75 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrShape is returned when merging sketches of different sizes.
+var ErrShape = errors.New("sketch: sketches differ in size")
+
+// CountMin is a count-min sketch: it estimates the number of occurrences
+// of the keys of a stream in a fixed table of counters, whatever the
+// number of distinct keys. Estimates are never below the true counts,
+// and exceed them by at most epsilon times the total count with
+// probability 1-delta, for the epsilon and delta of NewCountMin.
+type CountMin struct {
+	width, depth int
+	// counts holds depth rows of width counters.
+	counts []uint64
+	total  uint64
+}
+
+// NewCountMin returns an empty sketch with the given error bound epsilon,
+// as a fraction of the total count, and failure probability delta. It
+// keeps e/epsilon × ln(1/delta) counters.
+func NewCountMin(epsilon, delta float64) *CountMin {
+	width := max(int(math.Ceil(math.E/epsilon)), 1)
+	depth := max(int(math.Ceil(math.Log(1/delta))), 1)
+	return &CountMin{width: width, depth: depth, counts: make([]uint64, width*depth)}
+}
+
+// cell returns the index of the counter of the key in row i. The rows
+// derive their hashes from the two halves of one 64-bit hash.
+func (c *CountMin) cell(h uint64, i int) int {
+	h1, h2 := h&0xffffffff, h>>32
+	return i*c.width + int((h1+uint64(i)*h2)%uint64(c.width))
+}
+
+// Add adds count occurrences of the key.
+func (c *CountMin) Add(key string, count uint64) {
+	h := hash(key)
+	for i := 0; i < c.depth; i++ {
+		c.counts[c.cell(h, i)] += count
+	}
+	c.total += count
+}
+
+// Count returns an estimate of the number of occurrences of the key: the
+// smallest of its counters.
+func (c *CountMin) Count(key string) uint64 {
+	h := hash(key)
+	estimate := uint64(math.MaxUint64)
+	for i := 0; i < c.depth; i++ {
+		estimate = min(estimate, c.counts[c.cell(h, i)])
+	}
+	return estimate
+}
+
+// Total returns the number of occurrences added to the sketch.
+func (c *CountMin) Total() uint64 {
+	return c.total
+}
+
+// Merge adds the counts of o, built with the same epsilon and delta, to
+// the sketch.
+func (c *CountMin) Merge(o *CountMin) error {
+	if c.width != o.width || c.depth != o.depth {
+		return ErrShape
+	}
+	for i, n := range o.counts {
+		c.counts[i] += n
+	}
+	c.total += o.total
+	return nil
+}
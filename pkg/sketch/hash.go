@@ -0,0 +1,16 @@
+package sketch
+
+import "hash/fnv"
+
+// hash returns a 64-bit hash of the key. FNV-1a is followed by the
+// SplitMix64 finalizer, which spreads its poorly mixed high bits. The
+// hash is the same in every process, so sketches built by different
+// processes can be merged.
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	z := h.Sum64()
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
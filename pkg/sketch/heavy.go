@@ -0,0 +1,70 @@
+package sketch
+
+import "sort"
+
+// Item is a key along with its estimated number of occurrences.
+type Item struct {
+	Key   string
+	Count uint64
+}
+
+// HeavyHitters tracks the most frequent keys of a stream. A count-min
+// sketch estimates the count of every key, and the k keys with the
+// largest estimates are kept as candidates. Keys frequent enough to stand
+// out from the sketch error are found whatever the number of distinct
+// keys, in memory bounded by k and the sketch.
+type HeavyHitters struct {
+	k      int
+	counts *CountMin
+	top    map[string]uint64
+}
+
+// NewHeavyHitters returns a tracker of the k most frequent keys, whose
+// counts are estimated by a count-min sketch with the given epsilon and
+// delta (see NewCountMin).
+func NewHeavyHitters(k int, epsilon, delta float64) *HeavyHitters {
+	return &HeavyHitters{k: k, counts: NewCountMin(epsilon, delta), top: make(map[string]uint64, k+1)}
+}
+
+// Add adds one occurrence of the key.
+func (h *HeavyHitters) Add(key string) {
+	h.counts.Add(key, 1)
+	estimate := h.counts.Count(key)
+	if _, ok := h.top[key]; ok || len(h.top) < h.k {
+		h.top[key] = estimate
+		return
+	}
+	// Replace the least frequent candidate when the key overtakes it.
+	var least string
+	var found bool
+	for candidate, n := range h.top {
+		if !found || n < h.top[least] || (n == h.top[least] && candidate < least) {
+			least, found = candidate, true
+		}
+	}
+	if estimate > h.top[least] {
+		delete(h.top, least)
+		h.top[key] = estimate
+	}
+}
+
+// Total returns the number of keys added.
+func (h *HeavyHitters) Total() uint64 {
+	return h.counts.Total()
+}
+
+// Top returns the candidates, most frequent first, with their estimated
+// counts. Ties are ordered by key.
+func (h *HeavyHitters) Top() []Item {
+	items := make([]Item, 0, len(h.top))
+	for key, n := range h.top {
+		items = append(items, Item{key, n})
+	}
+	sort.Slice(items, func(a, b int) bool {
+		if items[a].Count != items[b].Count {
+			return items[a].Count > items[b].Count
+		}
+		return items[a].Key < items[b].Key
+	})
+	return items
+}
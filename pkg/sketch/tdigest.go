@@ -0,0 +1,224 @@
+// Package sketch summarizes unbounded streams of values in bounded
+// memory. A TDigest keeps a few dozen weighted centroids, small near
+// the extremes of the distribution and large in its middle, from which it
+// estimates quantiles, the CDF and histograms of the stream. For
+// categorical values, such as IDs with millions of distinct values,
+// HyperLogLog estimates the number of distinct values, CountMin their
+// frequencies and HeavyHitters the most frequent ones. Sketches of
+// several streams or shards can be merged.
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultCompression is the compression of digests that keep about 60
+// centroids, with quantile errors of about 0.1% of the rank.
+const DefaultCompression = 100
+
+// centroid is a group of values summarized by their mean and weight.
+type centroid struct {
+	mean, weight float64
+}
+
+// TDigest is a t-digest of the values of a stream. The zero value is not
+// usable; create digests with NewTDigest.
+type TDigest struct {
+	compression float64
+	// centroids holds the merged centroids, sorted by mean, and buffer
+	// the values added since the last merge.
+	centroids []centroid
+	buffer    []centroid
+	// total is the summed weight of the centroids and of the buffer.
+	total    float64
+	min, max float64
+}
+
+// NewTDigest returns an empty digest. A larger compression keeps more
+// centroids and gives more accurate estimates; it is DefaultCompression
+// when not positive.
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &TDigest{compression: compression, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Add adds a value to the digest. NaN values are ignored.
+func (t *TDigest) Add(x float64) {
+	t.AddWeighted(x, 1)
+}
+
+// AddWeighted adds a value with the given weight to the digest. NaN
+// values and values without a positive weight are ignored.
+func (t *TDigest) AddWeighted(x, weight float64) {
+	if math.IsNaN(x) || !(weight > 0) {
+		return
+	}
+	t.buffer = append(t.buffer, centroid{x, weight})
+	t.total += weight
+	t.min, t.max = math.Min(t.min, x), math.Max(t.max, x)
+	// Merge once the buffer outgrows the centroids, which bounds the
+	// memory of the digest whatever the length of the stream.
+	if len(t.buffer) >= int(5*t.compression) {
+		t.merge()
+	}
+}
+
+// Merge adds the values summarized by o to the digest.
+func (t *TDigest) Merge(o *TDigest) {
+	if o.total == 0 {
+		return
+	}
+	t.buffer = append(t.buffer, o.centroids...)
+	t.buffer = append(t.buffer, o.buffer...)
+	t.total += o.total
+	t.min, t.max = math.Min(t.min, o.min), math.Max(t.max, o.max)
+	t.merge()
+}
+
+// scale maps a quantile to the scale of the k1 function of the t-digest
+// paper, on which every centroid spans at most one unit. Its slope is
+// steepest at the extremes, so centroids are small there.
+func (t *TDigest) scale(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*math.Min(math.Max(q, 0), 1)-1)
+}
+
+// merge merges the buffer into the centroids.
+func (t *TDigest) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(a, b int) bool { return all[a].mean < all[b].mean })
+	// Sweep the centroids in order, merging each into the current one
+	// while the current one spans at most one unit of the scale.
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	var before float64
+	left := t.scale(0)
+	for _, c := range all[1:] {
+		if t.scale((before+cur.weight+c.weight)/t.total)-left <= 1 {
+			cur.weight += c.weight
+			cur.mean += (c.mean - cur.mean) * c.weight / cur.weight
+			continue
+		}
+		merged = append(merged, cur)
+		before += cur.weight
+		left = t.scale(before / t.total)
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = t.buffer[:0]
+}
+
+// Count returns the summed weight of the values added to the digest.
+func (t *TDigest) Count() float64 {
+	return t.total
+}
+
+// Min returns the smallest value added to the digest, or NaN when it is
+// empty.
+func (t *TDigest) Min() float64 {
+	if t.total == 0 {
+		return math.NaN()
+	}
+	return t.min
+}
+
+// Max returns the largest value added to the digest, or NaN when it is
+// empty.
+func (t *TDigest) Max() float64 {
+	if t.total == 0 {
+		return math.NaN()
+	}
+	return t.max
+}
+
+// Centroids returns the number of centroids kept by the digest.
+func (t *TDigest) Centroids() int {
+	t.merge()
+	return len(t.centroids)
+}
+
+// knots returns the ranks and values between which the digest
+// interpolates: the minimum at rank 0, the mean of every centroid at the
+// rank of its middle, and the maximum at the total weight.
+func (t *TDigest) knots() (ranks, values []float64) {
+	t.merge()
+	ranks = append(ranks, 0)
+	values = append(values, t.min)
+	var before float64
+	for _, c := range t.centroids {
+		ranks = append(ranks, before+c.weight/2)
+		values = append(values, c.mean)
+		before += c.weight
+	}
+	return append(ranks, t.total), append(values, t.max)
+}
+
+// Quantile returns an estimate of the q-quantile of the values, for q in
+// [0, 1], interpolating linearly between the centroids. It returns NaN
+// when the digest is empty or q is out of range.
+func (t *TDigest) Quantile(q float64) float64 {
+	if t.total == 0 || !(q >= 0 && q <= 1) {
+		return math.NaN()
+	}
+	ranks, values := t.knots()
+	rank := q * t.total
+	k := sort.SearchFloat64s(ranks, rank)
+	if k == 0 {
+		return values[0]
+	}
+	return interpolate(rank, ranks[k-1], ranks[k], values[k-1], values[k])
+}
+
+// CDF returns an estimate of the fraction of the values at most x. It
+// returns NaN when the digest is empty.
+func (t *TDigest) CDF(x float64) float64 {
+	switch {
+	case t.total == 0:
+		return math.NaN()
+	case x < t.min:
+		return 0
+	case x >= t.max:
+		return 1
+	}
+	ranks, values := t.knots()
+	// Find the last knot at most x, so that ties take the rank of their
+	// largest knot.
+	k := sort.Search(len(values), func(k int) bool { return values[k] > x })
+	return interpolate(x, values[k-1], values[k], ranks[k-1], ranks[k]) / t.total
+}
+
+// interpolate maps x from [x0, x1] to [y0, y1] linearly.
+func interpolate(x, x0, x1, y0, y1 float64) float64 {
+	if x1 == x0 {
+		return y1
+	}
+	return y0 + (x-x0)/(x1-x0)*(y1-y0)
+}
+
+// Histogram returns an estimate of the histogram of the values over
+// numBins bins of equal width between the smallest and the largest value:
+// the numBins+1 bin edges and the weight of the values in every bin. It
+// returns nil slices when the digest is empty.
+func (t *TDigest) Histogram(numBins int) (edges, counts []float64) {
+	if t.total == 0 || numBins < 1 {
+		return nil, nil
+	}
+	edges = make([]float64, numBins+1)
+	for b := range edges {
+		edges[b] = t.min + (t.max-t.min)*float64(b)/float64(numBins)
+	}
+	counts = make([]float64, numBins)
+	below := 0.0
+	for b := range counts {
+		// The last edge is the maximum, whose CDF covers every value.
+		at := t.CDF(edges[b+1]) * t.total
+		counts[b] = at - below
+		below = at
+	}
+	return edges, counts
+}
@@ -0,0 +1,58 @@
+package split
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/parallel"
+)
+
+// CrossValidate calls fit for every fold on a pool of workers (see
+// parallel.Map) and returns the results in fold order. Fold k draws its
+// random numbers from r, seeded from seed and k by parallel.Seed, so the
+// results do not depend on the number of workers or on scheduling, and
+// fit must not use any other shared random source. Since the folds are
+// independent, the wall time shrinks roughly by the number of workers.
+//
+// Once ctx is cancelled, no more folds are started and CrossValidate
+// returns the error of ctx. Otherwise it returns the error of the first
+// fold, in fold order, that failed.
+func CrossValidate[T any](ctx context.Context, folds []Fold, workers int, seed uint64, fit func(k int, fold Fold, r *rand.Rand) (T, error)) ([]T, error) {
+	return CrossValidateBudget(ctx, folds, parallel.Budget{CPUs: workers}, nil, seed, fit)
+}
+
+// CrossValidateBudget is like CrossValidate, but fits at once only the
+// folds whose summed cost, estimated by cost, fits the budget (see
+// parallel.MapBudget), so that folds of large models do not exhaust the
+// memory of the machine together. A nil cost counts every fold as one
+// CPU.
+func CrossValidateBudget[T any](ctx context.Context, folds []Fold, budget parallel.Budget, cost func(fold Fold) parallel.Cost, seed uint64, fit func(k int, fold Fold, r *rand.Rand) (T, error)) ([]T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	var foldCost func(k int) parallel.Cost
+	if cost != nil {
+		foldCost = func(k int) parallel.Cost { return cost(folds[k]) }
+	}
+	results := parallel.MapBudget(len(folds), budget, foldCost, func(k int) result {
+		if err := ctx.Err(); err != nil {
+			return result{err: err}
+		}
+		r := rand.New(rand.NewSource(int64(parallel.Seed(seed, k))))
+		value, err := fit(k, folds[k], r)
+		return result{value, err}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([]T, len(folds))
+	for k, res := range results {
+		if res.err != nil {
+			return nil, fmt.Errorf("fold %d: %w", k+1, res.err)
+		}
+		values[k] = res.value
+	}
+	return values, nil
+}
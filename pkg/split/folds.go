@@ -0,0 +1,90 @@
+package split
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Fold holds the rows of one cross-validation fold.
+type Fold struct {
+	// Repeat numbers the k-fold pass the fold belongs to, and Index the
+	// fold within the pass, both from 0.
+	Repeat, Index int
+	// Train and Test hold the row indices of the training and the test
+	// set, in ascending order.
+	Train, Test []int
+}
+
+// KFolds assigns each of n rows to one of k folds, so that the fold sizes
+// differ by at most one. The rows are shuffled first.
+func KFolds(n, k int, r *rand.Rand) []int {
+	folds := make([]int, n)
+	for rank, idx := range r.Perm(n) {
+		folds[idx] = rank % k
+	}
+	return folds
+}
+
+// FoldSets returns the training and test rows of each of the k folds,
+// given the fold of every row as returned by KFolds or StratifiedFolds.
+func FoldSets(folds []int, k int) []Fold {
+	sets := make([]Fold, k)
+	for i := range sets {
+		sets[i].Index = i
+		for idx, fold := range folds {
+			if fold == i {
+				sets[i].Test = append(sets[i].Test, idx)
+			} else {
+				sets[i].Train = append(sets[i].Train, idx)
+			}
+		}
+	}
+	return sets
+}
+
+// RepeatedKFold returns the folds of repeats k-fold passes over n rows,
+// each shuffling the rows differently. Averaging the scores of several
+// passes gives a more stable estimate than a single pass on small
+// datasets, where the score depends on how the rows fall into the folds.
+func RepeatedKFold(n, k, repeats int, seed int64) ([]Fold, error) {
+	if err := checkFolds(n, k, repeats); err != nil {
+		return nil, err
+	}
+	r := rand.New(rand.NewSource(seed))
+	return repeat(repeats, k, func() []int { return KFolds(n, k, r) }), nil
+}
+
+// RepeatedStratifiedKFold is like RepeatedKFold, with the folds of every
+// pass stratified as by StratifiedFolds.
+func RepeatedStratifiedKFold(strata []int, k, repeats int, seed int64) ([]Fold, error) {
+	if err := checkFolds(len(strata), k, repeats); err != nil {
+		return nil, err
+	}
+	r := rand.New(rand.NewSource(seed))
+	return repeat(repeats, k, func() []int { return StratifiedFolds(strata, k, r) }), nil
+}
+
+// repeat returns the folds of repeats passes, drawing the fold of every
+// row anew for every pass.
+func repeat(repeats, k int, assign func() []int) []Fold {
+	var folds []Fold
+	for p := 0; p < repeats; p++ {
+		sets := FoldSets(assign(), k)
+		for i := range sets {
+			sets[i].Repeat = p
+		}
+		folds = append(folds, sets...)
+	}
+	return folds
+}
+
+// checkFolds checks that n rows can be divided into k folds at least once.
+func checkFolds(n, k, repeats int) error {
+	if k < 2 || k > n {
+		return fmt.Errorf("split: cannot divide %d rows into %d folds", n, k)
+	}
+	if repeats < 1 {
+		return fmt.Errorf("split: %d repeats", repeats)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+// Package split divides datasets into training and test sets, so the
+// examples share one implementation of the split with configurable
+// ratios, shuffling and seeding. It also divides them into plain,
+// stratified and repeated cross-validation folds, and runs the folds in
+// parallel.
+package split
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/go-gota/gota/dataframe"
+)
+
+// Config describes how rows are divided into a training and a test set.
+type Config struct {
+	// TestFraction is the share of the rows put into the test set.
+	TestFraction float64
+	// Shuffle randomizes the assignment of rows to the sets. Without
+	// shuffling the last rows form the test set.
+	Shuffle bool
+	// Seed seeds the shuffling, so a split can be reproduced.
+	Seed int64
+}
+
+// TrainTest splits n rows into the row indices of the training and the
+// test set. The indices of each set are in ascending order.
+func TrainTest(n int, cfg Config) (train, test []int) {
+	rows := make([]int, n)
+	for i := range rows {
+		rows[i] = i
+	}
+	if cfg.Shuffle {
+		r := rand.New(rand.NewSource(cfg.Seed))
+		r.Shuffle(n, func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+	}
+	numTest := int(float64(n) * cfg.TestFraction)
+	train = append([]int(nil), rows[:n-numTest]...)
+	test = append([]int(nil), rows[n-numTest:]...)
+	sort.Ints(train)
+	sort.Ints(test)
+	return train, test
+}
+
+// StratifiedTrainTest splits the rows so that every stratum is represented
+// in the test set in the same proportion as in the whole dataset. strata
+// holds the stratum of every row, such as its class or target bin.
+func StratifiedTrainTest(strata []int, cfg Config) (train, test []int) {
+	keys, groups := groupByStratum(strata)
+	r := rand.New(rand.NewSource(cfg.Seed))
+	// Take the test rows of each stratum in turn. The number of test rows is
+	// rounded on the running total so that the overall fraction is kept.
+	var seen, numTest int
+	for _, key := range keys {
+		rows := groups[key]
+		if cfg.Shuffle {
+			r.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		}
+		seen += len(rows)
+		take := int(math.Round(float64(seen)*cfg.TestFraction)) - numTest
+		numTest += take
+		test = append(test, rows[len(rows)-take:]...)
+		train = append(train, rows[:len(rows)-take]...)
+	}
+	sort.Ints(train)
+	sort.Ints(test)
+	return train, test
+}
+
+// StratifiedFolds assigns each row to one of k folds, dealing the rows of
+// every stratum round-robin over the folds so that each fold has the same
+// distribution of strata. The rows of a stratum are shuffled first.
+func StratifiedFolds(strata []int, k int, r *rand.Rand) []int {
+	keys, groups := groupByStratum(strata)
+	// The starting fold carries over between strata so that the fold
+	// sizes stay balanced.
+	folds := make([]int, len(strata))
+	var next int
+	for _, key := range keys {
+		rows := groups[key]
+		r.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		for _, idx := range rows {
+			folds[idx] = next
+			next = (next + 1) % k
+		}
+	}
+	return folds
+}
+
+// QuantileBins assigns each value to one of numBins quantile bins, so that
+// every bin holds roughly the same number of rows. It is used to stratify
+// on a continuous target.
+func QuantileBins(values []float64, numBins int) []int {
+	// Order the row indices by their value.
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return values[order[a]] < values[order[b]]
+	})
+	// Walk the sorted rows, moving to the next bin every len/numBins rows.
+	bins := make([]int, len(values))
+	for rank, idx := range order {
+		bins[idx] = rank * numBins / len(values)
+	}
+	return bins
+}
+
+// groupByStratum returns the sorted strata and the row indices of each.
+func groupByStratum(strata []int) ([]int, map[int][]int) {
+	groups := make(map[int][]int)
+	var keys []int
+	for idx, stratum := range strata {
+		if _, ok := groups[stratum]; !ok {
+			keys = append(keys, stratum)
+		}
+		groups[stratum] = append(groups[stratum], idx)
+	}
+	sort.Ints(keys)
+	return keys, groups
+}
+
+// SplitCSV streams the CSV file at src, with a header row, into a
+// training file at trainPath and a test file at testPath, both with the
+// header. test holds the ascending indices of the test rows below the
+// header, as returned by TrainTest and StratifiedTrainTest; the other
+// rows are training rows. The records are copied as read, one at a time,
+// so files larger than the memory can be split.
+func SplitCSV(src, trainPath, testPath string, test []int) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	reader := csv.NewReader(bufio.NewReader(in))
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("split: %s: %v", src, err)
+	}
+	// Create both files and write the header to each.
+	var files [2]*os.File
+	var records [2]*csv.Writer
+	for k, path := range []string{trainPath, testPath} {
+		if files[k], err = os.Create(path); err != nil {
+			break
+		}
+		records[k] = csv.NewWriter(files[k])
+		err = records[k].Write(header)
+	}
+	// Copy every record to the file of its set.
+	for i := 0; err == nil; i++ {
+		var record []string
+		record, err = reader.Read()
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			err = fmt.Errorf("split: %s: %v", src, err)
+			break
+		}
+		set := 0
+		if len(test) > 0 && test[0] == i {
+			set, test = 1, test[1:]
+		}
+		err = records[set].Write(record)
+	}
+	for k, f := range files {
+		if f == nil {
+			continue
+		}
+		if err == nil {
+			records[k].Flush()
+			err = records[k].Error()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// WriteCSV writes the given rows of the dataframe to a CSV file at path.
+func WriteCSV(df dataframe.DataFrame, rows []int, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// Create a buffered writer.
+	w := bufio.NewWriter(f)
+	// Write the dataframe out as a CSV.
+	if err := df.Subset(rows).WriteCSV(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,66 @@
+// Package surrogate distills models into shallow decision trees. A
+// surrogate tree is grown on the predictions of a model rather than on
+// the labels, so its few rules summarize how the model predicts. Its
+// fidelity measures how far the summary can be trusted: the share of the
+// rows on which it predicts the same class as the model, or for
+// regressions the R² of its predictions against those of the model.
+package surrogate
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/tree"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Distill grows the unfitted tree t on the rows of x and the predictions
+// the model makes for them, and returns the fidelity of the tree on those
+// rows. Predictions of classification models are class indices. The
+// random source is passed to tree.Tree.Fit.
+func Distill(t *tree.Tree, x mat64.Matrix, predictions []float64, r *rand.Rand) (float64, error) {
+	if err := t.Fit(x, predictions, nil, r); err != nil {
+		return 0, err
+	}
+	return Fidelity(t, x, predictions)
+}
+
+// Fidelity returns the agreement of the tree with the model predictions
+// of the rows of x: the share of the rows given the same class for
+// classification trees, and the R² of the tree against the model for
+// regression trees. The R² of a model predicting a constant is 1 when the
+// tree predicts it exactly, and 0 otherwise.
+func Fidelity(t *tree.Tree, x mat64.Matrix, predictions []float64) (float64, error) {
+	numRows, numFeatures := x.Dims()
+	if numRows == 0 || len(predictions) != numRows {
+		return 0, fmt.Errorf("surrogate: %d predictions for %d rows", len(predictions), numRows)
+	}
+	if numFeatures != t.NumFeatures {
+		return 0, fmt.Errorf("surrogate: rows have %d features, the tree %d", numFeatures, t.NumFeatures)
+	}
+	row := make([]float64, numFeatures)
+	var agree int
+	var mean, sse, sst float64
+	for _, p := range predictions {
+		mean += p / float64(numRows)
+	}
+	for i, p := range predictions {
+		mat64.Row(row, i, x)
+		got := t.Predict(row)
+		if got == p {
+			agree++
+		}
+		sse += (got - p) * (got - p)
+		sst += (p - mean) * (p - mean)
+	}
+	if t.Task == tree.Classification {
+		return float64(agree) / float64(numRows), nil
+	}
+	switch {
+	case sst > 0:
+		return 1 - sse/sst, nil
+	case sse == 0:
+		return 1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,205 @@
+// Package tensorboard writes TensorBoard event files, so the training
+// curves of the iterative trainers can be followed with
+// "tensorboard --logdir runs". Events are encoded by hand in the protocol
+// buffer format TensorBoard reads, and framed as TFRecords.
+package tensorboard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// numBuckets is the number of buckets of the weight histograms.
+const numBuckets = 30
+
+// castagnoli is the CRC-32C table used by the TFRecord checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Writer appends events to an event file.
+type Writer struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+// NewWriter creates an event file in dir, creating the directory when
+// needed, and writes the file version event.
+func NewWriter(dir string) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	name := fmt.Sprintf("events.out.tfevents.%d.%s", time.Now().Unix(), host)
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	tw := &Writer{f: f, w: bufio.NewWriter(f)}
+	// The first event names the version of the file format.
+	var event []byte
+	event = appendDouble(event, 1, wallTime())
+	event = appendBytes(event, 3, []byte("brain.Event:2"))
+	if err := tw.writeRecord(event); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return tw, nil
+}
+
+// Scalar records the value of the tag at the given step.
+func (tw *Writer) Scalar(tag string, step int, value float64) error {
+	var v []byte
+	v = appendBytes(v, 1, []byte(tag))
+	v = appendFloat(v, 2, float32(value))
+	return tw.writeSummary(step, v)
+}
+
+// Histogram records the distribution of the values of the tag at the
+// given step.
+func (tw *Writer) Histogram(tag string, step int, values []float64) error {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	min, max := sorted[0], sorted[len(sorted)-1]
+	var sum, sumSquares float64
+	for _, x := range sorted {
+		sum += x
+		sumSquares += x * x
+	}
+	// Count the values in equal width buckets between min and max, each
+	// bucket being described by its upper limit.
+	limits := make([]float64, numBuckets)
+	counts := make([]float64, numBuckets)
+	width := (max - min) / numBuckets
+	for b := range limits {
+		limits[b] = min + float64(b+1)*width
+	}
+	limits[numBuckets-1] = max
+	for _, x := range sorted {
+		b := numBuckets - 1
+		if width > 0 {
+			b = int((x - min) / width)
+			if b >= numBuckets {
+				b = numBuckets - 1
+			}
+		}
+		counts[b]++
+	}
+	var h []byte
+	h = appendDouble(h, 1, min)
+	h = appendDouble(h, 2, max)
+	h = appendDouble(h, 3, float64(len(sorted)))
+	h = appendDouble(h, 4, sum)
+	h = appendDouble(h, 5, sumSquares)
+	h = appendPackedDoubles(h, 6, limits)
+	h = appendPackedDoubles(h, 7, counts)
+	var v []byte
+	v = appendBytes(v, 1, []byte(tag))
+	v = appendBytes(v, 5, h)
+	return tw.writeSummary(step, v)
+}
+
+// Flush writes the buffered events to the file, so TensorBoard picks
+// them up while training continues.
+func (tw *Writer) Flush() error {
+	return tw.w.Flush()
+}
+
+// Close flushes and closes the event file.
+func (tw *Writer) Close() error {
+	if err := tw.w.Flush(); err != nil {
+		tw.f.Close()
+		return err
+	}
+	return tw.f.Close()
+}
+
+// writeSummary writes an event holding a summary with a single value.
+func (tw *Writer) writeSummary(step int, value []byte) error {
+	var summary []byte
+	summary = appendBytes(summary, 1, value)
+	var event []byte
+	event = appendDouble(event, 1, wallTime())
+	event = appendVarint(event, 2, uint64(step))
+	event = appendBytes(event, 5, summary)
+	return tw.writeRecord(event)
+}
+
+// writeRecord frames the data as a TFRecord: the length, its checksum,
+// the data and the checksum of the data.
+func (tw *Writer) writeRecord(data []byte) error {
+	var header [12]byte
+	binary.LittleEndian.PutUint64(header[:8], uint64(len(data)))
+	binary.LittleEndian.PutUint32(header[8:], maskedCRC(header[:8]))
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], maskedCRC(data))
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		if _, err := tw.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maskedCRC returns the masked CRC-32C checksum used by TFRecords.
+func maskedCRC(data []byte) uint32 {
+	crc := crc32.Checksum(data, castagnoli)
+	return ((crc >> 15) | (crc << 17)) + 0xa282ead8
+}
+
+// wallTime returns the current time in seconds.
+func wallTime() float64 {
+	return float64(time.Now().UnixNano()) / 1e9
+}
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendVarint(b []byte, field int, v uint64) []byte {
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendDouble(b []byte, field int, v float64) []byte {
+	b = appendTag(b, field, wireFixed64)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+func appendFloat(b []byte, field int, v float32) []byte {
+	b = appendTag(b, field, wireFixed32)
+	return binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendPackedDoubles(b []byte, field int, vs []float64) []byte {
+	packed := make([]byte, 0, 8*len(vs))
+	for _, v := range vs {
+		packed = binary.LittleEndian.AppendUint64(packed, math.Float64bits(v))
+	}
+	return appendBytes(b, field, packed)
+}
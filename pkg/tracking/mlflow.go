@@ -0,0 +1,252 @@
+// Package tracking logs runs to an MLflow tracking server through its REST
+// API, so the runs of the examples show up next to experiments tracked
+// from other languages, and streams metrics to pluggable sinks such as a
+// webhook. Tracking is optional: the examples only log when the
+// MLFLOW_TRACKING_URI or METRICS_WEBHOOK_URL environment variables are
+// set, and keep their local artifact directory either way.
+package tracking
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrackingURIEnv is the environment variable holding the address of the
+// tracking server, as used by the MLflow clients.
+const TrackingURIEnv = "MLFLOW_TRACKING_URI"
+
+// maxParamsPerBatch is the number of parameters the server accepts in a
+// single log-batch request.
+const maxParamsPerBatch = 100
+
+// MLflow is a client of an MLflow tracking server.
+type MLflow struct {
+	// URI is the base address of the server, such as http://localhost:5000.
+	URI string
+	// Client sends the requests.
+	Client *http.Client
+}
+
+// NewMLflow returns a client of the tracking server at uri.
+func NewMLflow(uri string) *MLflow {
+	return &MLflow{URI: strings.TrimRight(uri, "/"), Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// FromEnv returns a client of the server named by MLFLOW_TRACKING_URI, or
+// false when the variable is not set.
+func FromEnv() (*MLflow, bool) {
+	uri := os.Getenv(TrackingURIEnv)
+	if uri == "" {
+		return nil, false
+	}
+	return NewMLflow(uri), true
+}
+
+// RunInfo identifies a run on the tracking server.
+type RunInfo struct {
+	RunID       string `json:"run_id"`
+	ArtifactURI string `json:"artifact_uri"`
+}
+
+// call sends a request to an API endpoint and decodes the JSON response
+// into out, when out is not nil.
+func (m *MLflow) call(method, endpoint string, body, out any) error {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, m.URI+endpoint, payload)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return m.do(req, out)
+}
+
+// do sends the request and decodes the JSON response into out.
+func (m *MLflow) do(req *http.Request, out any) error {
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tracking: %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(data))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// ExperimentID returns the id of the named experiment, creating the
+// experiment when it does not exist yet.
+func (m *MLflow) ExperimentID(name string) (string, error) {
+	var found struct {
+		Experiment struct {
+			ExperimentID string `json:"experiment_id"`
+		} `json:"experiment"`
+	}
+	err := m.call(http.MethodGet, "/api/2.0/mlflow/experiments/get-by-name?experiment_name="+url.QueryEscape(name), nil, &found)
+	if err == nil {
+		return found.Experiment.ExperimentID, nil
+	}
+	// The server answers with an error when the experiment is missing,
+	// so try to create it.
+	var created struct {
+		ExperimentID string `json:"experiment_id"`
+	}
+	if err := m.call(http.MethodPost, "/api/2.0/mlflow/experiments/create", map[string]any{"name": name}, &created); err != nil {
+		return "", err
+	}
+	return created.ExperimentID, nil
+}
+
+// CreateRun starts a run named runName in the experiment.
+func (m *MLflow) CreateRun(experimentID, runName string) (RunInfo, error) {
+	var resp struct {
+		Run struct {
+			Info RunInfo `json:"info"`
+		} `json:"run"`
+	}
+	body := map[string]any{
+		"experiment_id": experimentID,
+		"run_name":      runName,
+		"start_time":    time.Now().UnixMilli(),
+	}
+	if err := m.call(http.MethodPost, "/api/2.0/mlflow/runs/create", body, &resp); err != nil {
+		return RunInfo{}, err
+	}
+	return resp.Run.Info, nil
+}
+
+// LogParams logs the parameters of the run, formatting every value as a
+// string as MLflow expects.
+func (m *MLflow) LogParams(runID string, params map[string]any) error {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for start := 0; start < len(keys); start += maxParamsPerBatch {
+		end := start + maxParamsPerBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := make([]map[string]string, 0, end-start)
+		for _, key := range keys[start:end] {
+			batch = append(batch, map[string]string{"key": key, "value": fmt.Sprint(params[key])})
+		}
+		if err := m.call(http.MethodPost, "/api/2.0/mlflow/runs/log-batch", map[string]any{"run_id": runID, "params": batch}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogMetrics logs the metrics of the run at the given step.
+func (m *MLflow) LogMetrics(runID string, metrics map[string]float64, step int) error {
+	now := time.Now().UnixMilli()
+	batch := make([]map[string]any, 0, len(metrics))
+	for key, value := range metrics {
+		batch = append(batch, map[string]any{"key": key, "value": value, "timestamp": now, "step": step})
+	}
+	return m.call(http.MethodPost, "/api/2.0/mlflow/runs/log-batch", map[string]any{"run_id": runID, "metrics": batch}, nil)
+}
+
+// LogArtifact uploads the local file to the run's artifacts under path.
+// Only servers that proxy artifacts (an artifact URI with the
+// mlflow-artifacts scheme) are supported.
+func (m *MLflow) LogArtifact(run RunInfo, localPath, path string) error {
+	root, ok := strings.CutPrefix(run.ArtifactURI, "mlflow-artifacts:")
+	if !ok {
+		return fmt.Errorf("tracking: artifact URI %q is not served by the tracking server", run.ArtifactURI)
+	}
+	// Drop the host of URIs such as mlflow-artifacts://host/path.
+	if rest, ok := strings.CutPrefix(root, "//"); ok {
+		if i := strings.Index(rest, "/"); i >= 0 {
+			root = rest[i:]
+		}
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	endpoint := "/api/2.0/mlflow-artifacts/artifacts/" + strings.Trim(root, "/") + "/" + filepath.ToSlash(path)
+	req, err := http.NewRequest(http.MethodPut, m.URI+endpoint, f)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return m.do(req, nil)
+}
+
+// LogArtifacts uploads every file below dir, keeping their relative paths.
+func (m *MLflow) LogArtifacts(run RunInfo, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return m.LogArtifact(run, path, rel)
+	})
+}
+
+// FinishRun marks the run as finished.
+func (m *MLflow) FinishRun(runID string) error {
+	body := map[string]any{
+		"run_id":   runID,
+		"status":   "FINISHED",
+		"end_time": time.Now().UnixMilli(),
+	}
+	return m.call(http.MethodPost, "/api/2.0/mlflow/runs/update", body, nil)
+}
+
+// LogRun logs a finished local run in one go: it creates a run named
+// runName in the experiment, logs the parameters and metrics, uploads the
+// files of the artifact directory dir and marks the run as finished. It
+// returns the id of the run on the server.
+func (m *MLflow) LogRun(experiment, runName string, params map[string]any, metrics map[string]float64, dir string) (string, error) {
+	experimentID, err := m.ExperimentID(experiment)
+	if err != nil {
+		return "", err
+	}
+	run, err := m.CreateRun(experimentID, runName)
+	if err != nil {
+		return "", err
+	}
+	if err := m.LogParams(run.RunID, params); err != nil {
+		return run.RunID, err
+	}
+	if err := m.LogMetrics(run.RunID, metrics, 0); err != nil {
+		return run.RunID, err
+	}
+	if err := m.LogArtifacts(run, dir); err != nil {
+		return run.RunID, err
+	}
+	return run.RunID, m.FinishRun(run.RunID)
+}
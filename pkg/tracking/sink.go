@@ -0,0 +1,164 @@
+package tracking
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// WebhookURLEnv is the environment variable holding the address the
+	// webhook sink posts to.
+	WebhookURLEnv = "METRICS_WEBHOOK_URL"
+	// WebhookTokenEnv is the environment variable holding an optional
+	// bearer token sent with every webhook request.
+	WebhookTokenEnv = "METRICS_WEBHOOK_TOKEN"
+)
+
+// Sink receives the metrics of a run as they are computed, so they can be
+// streamed to any experiment tracker.
+type Sink interface {
+	// LogStep records the metrics of a training step, such as an epoch.
+	LogStep(step int, metrics map[string]float64) error
+	// LogFinal records the final results of the run.
+	LogFinal(metrics map[string]float64) error
+	// Close releases the resources of the sink.
+	Close() error
+}
+
+// Discard is a sink that drops every metric.
+type Discard struct{}
+
+// LogStep implements Sink.
+func (Discard) LogStep(int, map[string]float64) error { return nil }
+
+// LogFinal implements Sink.
+func (Discard) LogFinal(map[string]float64) error { return nil }
+
+// Close implements Sink.
+func (Discard) Close() error { return nil }
+
+// Multi sends the metrics to every sink in turn, stopping at the first
+// error.
+type Multi []Sink
+
+// LogStep implements Sink.
+func (m Multi) LogStep(step int, metrics map[string]float64) error {
+	for _, s := range m {
+		if err := s.LogStep(step, metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogFinal implements Sink.
+func (m Multi) LogFinal(metrics map[string]float64) error {
+	for _, s := range m {
+		if err := s.LogFinal(metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Sink, closing every sink and returning the first error.
+func (m Multi) Close() error {
+	var first error
+	for _, s := range m {
+		if err := s.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Webhook posts every batch of metrics as a JSON document to a URL:
+//
+//	{"run": "...", "step": 3, "final": false, "timestamp": 1700000000000, "metrics": {"loss/train": 0.5}}
+//
+// Final results are posted with "final": true and no step.
+type Webhook struct {
+	// URL receives the POST requests.
+	URL string
+	// Run names the run in every document.
+	Run string
+	// Header is added to every request, for example to authenticate.
+	Header http.Header
+	// Client sends the requests.
+	Client *http.Client
+}
+
+// NewWebhook returns a webhook sink posting the metrics of the named run
+// to url.
+func NewWebhook(url, run string) *Webhook {
+	return &Webhook{URL: url, Run: run, Header: make(http.Header), Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// webhookPayload is the document posted by the webhook sink.
+type webhookPayload struct {
+	Run       string             `json:"run"`
+	Step      *int               `json:"step,omitempty"`
+	Final     bool               `json:"final"`
+	Timestamp int64              `json:"timestamp"`
+	Metrics   map[string]float64 `json:"metrics"`
+}
+
+// LogStep implements Sink.
+func (w *Webhook) LogStep(step int, metrics map[string]float64) error {
+	return w.post(webhookPayload{Run: w.Run, Step: &step, Metrics: metrics})
+}
+
+// LogFinal implements Sink.
+func (w *Webhook) LogFinal(metrics map[string]float64) error {
+	return w.post(webhookPayload{Run: w.Run, Final: true, Metrics: metrics})
+}
+
+// Close implements Sink.
+func (w *Webhook) Close() error { return nil }
+
+// post sends the payload and checks for a successful status.
+func (w *Webhook) post(payload webhookPayload) error {
+	payload.Timestamp = time.Now().UnixMilli()
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for key, values := range w.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("tracking: webhook %s: %s: %s", w.URL, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// SinkFromEnv returns a webhook sink for the named run when
+// METRICS_WEBHOOK_URL is set, sending METRICS_WEBHOOK_TOKEN as a bearer
+// token when present, and a Discard sink otherwise.
+func SinkFromEnv(run string) Sink {
+	url := os.Getenv(WebhookURLEnv)
+	if url == "" {
+		return Discard{}
+	}
+	w := NewWebhook(url, run)
+	if token := os.Getenv(WebhookTokenEnv); token != "" {
+		w.Header.Set("Authorization", "Bearer "+token)
+	}
+	return w
+}
@@ -0,0 +1,171 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Claims lists the optional invariants a transformer promises. The
+// invariants every transformer must keep are always checked.
+type Claims struct {
+	// Idempotent transformers leave their own output unchanged when they
+	// are fitted on it again, as scalers do.
+	Idempotent bool
+	// ScaleInvariant transformers give the same output when the rows are
+	// multiplied by a positive factor before fitting and transforming.
+	ScaleInvariant bool
+	// ShiftInvariant transformers give the same output when a constant is
+	// added to the rows before fitting and transforming.
+	ShiftInvariant bool
+}
+
+// Violation is returned by Check when a transformer breaks an invariant.
+type Violation struct {
+	// Property names the broken invariant.
+	Property string
+	// Row and Col locate the first value that differs.
+	Row, Col int
+	// Want and Got are the expected and actual values.
+	Want, Got float64
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("transform: %s violated at row %d, column %d: want %g, got %g", v.Property, v.Row, v.Col, v.Want, v.Got)
+}
+
+// Check verifies the invariants of the transformers made by newT on the
+// rows of x, comparing values with the tolerance tol, taken relative to
+// the magnitude of values beyond 1. A fresh
+// transformer is made for every fit. It checks that:
+//
+//   - fitting twice on the same rows gives the same transformation,
+//   - Transform leaves its input unchanged,
+//   - the output keeps the shape of the input,
+//   - InverseTransform undoes Transform, when the transformer is an Inverter,
+//
+// and the invariants listed in claims.
+func Check(newT func() Transformer, x mat64.Matrix, claims Claims, tol float64) error {
+	original := mat64.DenseCopyOf(x)
+	fitTransform := func(x mat64.Matrix) (*mat64.Dense, Transformer, error) {
+		t := newT()
+		if err := t.Fit(x); err != nil {
+			return nil, nil, err
+		}
+		out, err := t.Transform(x)
+		return out, t, err
+	}
+	out, t, err := fitTransform(x)
+	if err != nil {
+		return err
+	}
+	if err := compare("input unchanged", original, x, tol); err != nil {
+		return err
+	}
+	if err := compareShape("shape kept", original, out); err != nil {
+		return err
+	}
+	again, _, err := fitTransform(x)
+	if err != nil {
+		return err
+	}
+	if err := compare("deterministic fit", out, again, tol); err != nil {
+		return err
+	}
+	if inv, ok := t.(Inverter); ok {
+		back, err := inv.InverseTransform(out)
+		if err != nil {
+			return err
+		}
+		if err := compare("inverse round trip", original, back, tol); err != nil {
+			return err
+		}
+	}
+	if claims.Idempotent {
+		twice, _, err := fitTransform(out)
+		if err != nil {
+			return err
+		}
+		if err := compare("idempotence", out, twice, tol); err != nil {
+			return err
+		}
+	}
+	if claims.ScaleInvariant {
+		for _, factor := range []float64{0.01, 3, 1000} {
+			var scaled mat64.Dense
+			scaled.Scale(factor, original)
+			got, _, err := fitTransform(&scaled)
+			if err != nil {
+				return err
+			}
+			if err := compare(fmt.Sprintf("scale invariance (x%g)", factor), out, got, tol); err != nil {
+				return err
+			}
+		}
+	}
+	if claims.ShiftInvariant {
+		for _, shift := range []float64{-50, 7.5} {
+			shifted := columnMap(original, func(_ int, v float64) float64 { return v + shift })
+			got, _, err := fitTransform(shifted)
+			if err != nil {
+				return err
+			}
+			if err := compare(fmt.Sprintf("shift invariance (%+g)", shift), out, got, tol); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckRandom runs Check on numTrials random matrices drawn from r, with
+// between 2 and 50 rows and between 1 and 5 columns of values of varied
+// scales, so that a new transformer is validated beyond a single dataset.
+func CheckRandom(newT func() Transformer, claims Claims, tol float64, numTrials int, r *rand.Rand) error {
+	for trial := 0; trial < numTrials; trial++ {
+		numRows, numCols := 2+r.Intn(49), 1+r.Intn(5)
+		x := mat64.NewDense(numRows, numCols, nil)
+		for j := 0; j < numCols; j++ {
+			// Give every column its own location and spread.
+			loc, spread := r.NormFloat64()*100, math.Pow(10, r.Float64()*4-2)
+			for i := 0; i < numRows; i++ {
+				x.Set(i, j, loc+r.NormFloat64()*spread)
+			}
+		}
+		if err := Check(newT, x, claims, tol); err != nil {
+			return fmt.Errorf("trial %d (%dx%d): %w", trial, numRows, numCols, err)
+		}
+	}
+	return nil
+}
+
+// compareShape returns an error when got and want have different shapes.
+func compareShape(property string, want, got mat64.Matrix) error {
+	wantRows, wantCols := want.Dims()
+	gotRows, gotCols := got.Dims()
+	if wantRows != gotRows || wantCols != gotCols {
+		return fmt.Errorf("transform: %s violated: want %dx%d, got %dx%d", property, wantRows, wantCols, gotRows, gotCols)
+	}
+	return nil
+}
+
+// compare returns a Violation of the property at the first value of got
+// that differs from want by more than tol, relative to the magnitude of
+// want when it exceeds 1.
+func compare(property string, want, got mat64.Matrix, tol float64) error {
+	if err := compareShape(property, want, got); err != nil {
+		return err
+	}
+	numRows, numCols := want.Dims()
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			w, g := want.At(i, j), got.At(i, j)
+			if math.Abs(w-g) > tol*math.Max(1, math.Abs(w)) || math.IsNaN(g) != math.IsNaN(w) {
+				return &Violation{Property: property, Row: i, Col: j, Want: w, Got: g}
+			}
+		}
+	}
+	return nil
+}
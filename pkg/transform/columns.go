@@ -0,0 +1,348 @@
+package transform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Steps a ColumnTransformer routes columns to, besides the steps New
+// returns.
+const (
+	// StepOneHot encodes every category of a column as a 0 or 1 feature,
+	// with OneHot.
+	StepOneHot = "onehot"
+	// StepPassthrough keeps numeric columns as they are.
+	StepPassthrough = "passthrough"
+	// StepDrop leaves columns out.
+	StepDrop = "drop"
+)
+
+// routeSteps are the steps only a ColumnTransformer knows, which cannot be
+// registered either.
+var routeSteps = []string{StepOneHot, StepPassthrough, StepDrop}
+
+// Route sends named columns to a step, and holds what the step learns
+// from them.
+type Route struct {
+	Step    string   `json:"step"`
+	Columns []string `json:"columns"`
+	// OneHot is the fitted encoder of a one-hot step.
+	OneHot *OneHot `json:"onehot,omitempty"`
+	// State is the fitted transformer of the other steps but passthrough
+	// and drop, such as standard or a registered step, encoded as JSON.
+	State json.RawMessage `json:"state,omitempty"`
+}
+
+// ColumnTransformer turns the named text columns of raw rows into a
+// feature matrix, each column through the step of its route: numeric
+// columns can go through any step of New, such as standard, minmax,
+// impute or a registered step, or be passed through, and categorical ones
+// can be one-hot encoded. The features are the outputs of the routes in
+// order, then of the remainder. It is described by a comma separated
+// list of step:column entries, so that it fits in a flag or an experiment
+// file:
+//
+//	standard:fico,standard:int.rate,onehot:purpose,passthrough:intercept
+//
+// The column * routes every column no other entry names, which are
+// otherwise dropped. A fitted ColumnTransformer encodes to JSON, so that
+// it is saved along with the model it feeds.
+type ColumnTransformer struct {
+	Routes []Route `json:"routes"`
+	// Remainder, when set, routes the columns no route names.
+	Remainder *Route `json:"remainder,omitempty"`
+	// Names holds the names of the features of the fitted transformer.
+	// One-hot features are named column=category.
+	Names []string `json:"names,omitempty"`
+}
+
+// ParseColumns parses the description of a column transformer. Entries of
+// the same step form a single route, placed at the first of them.
+func ParseColumns(spec string) (*ColumnTransformer, error) {
+	c := &ColumnTransformer{}
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		step, column, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || column == "" {
+			return nil, fmt.Errorf("transform: %q is not a step:column entry", entry)
+		}
+		if _, ok := lookup(step); !ok && !slices.Contains(builtinSteps, step) && !slices.Contains(routeSteps, step) {
+			return nil, fmt.Errorf("transform: unknown step %q, expected one of %s", step, strings.Join(slices.Concat(builtinSteps, routeSteps, Registered()), ", "))
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("transform: column %q is routed twice", column)
+		}
+		seen[column] = true
+		if column == "*" {
+			c.Remainder = &Route{Step: step}
+			continue
+		}
+		k := slices.IndexFunc(c.Routes, func(r Route) bool { return r.Step == step })
+		if k < 0 {
+			c.Routes = append(c.Routes, Route{Step: step})
+			k = len(c.Routes) - 1
+		}
+		c.Routes[k].Columns = append(c.Routes[k].Columns, column)
+	}
+	return c, nil
+}
+
+// Columns returns the columns the routes name, without the remainder.
+func (c *ColumnTransformer) Columns() []string {
+	var columns []string
+	for _, r := range c.Routes {
+		columns = append(columns, r.Columns...)
+	}
+	return columns
+}
+
+// Inputs returns the columns the fitted transformer reads, including
+// those of the remainder and leaving out the dropped ones.
+func (c *ColumnTransformer) Inputs() []string {
+	var columns []string
+	for _, r := range c.routes() {
+		if r.Step != StepDrop {
+			columns = append(columns, r.Columns...)
+		}
+	}
+	return columns
+}
+
+// routes returns the routes and the remainder, when set.
+func (c *ColumnTransformer) routes() []*Route {
+	routes := make([]*Route, 0, len(c.Routes)+1)
+	for k := range c.Routes {
+		routes = append(routes, &c.Routes[k])
+	}
+	if c.Remainder != nil {
+		routes = append(routes, c.Remainder)
+	}
+	return routes
+}
+
+// Fit learns every step from the rows of a file with the header. The
+// remainder takes the columns of the header that no route names, except
+// those of exclude, such as the target.
+func (c *ColumnTransformer) Fit(header []string, rows [][]string, exclude ...string) error {
+	if len(rows) == 0 {
+		return errors.New("transform: no rows")
+	}
+	if c.Remainder != nil {
+		c.Remainder.Columns = nil
+		routed := c.Columns()
+		for _, name := range header {
+			if !slices.Contains(routed, name) && !slices.Contains(exclude, name) {
+				c.Remainder.Columns = append(c.Remainder.Columns, name)
+			}
+		}
+	}
+	c.Names = nil
+	for _, r := range c.routes() {
+		indices, err := columnIndices(header, r.Columns)
+		if err != nil {
+			return err
+		}
+		r.OneHot, r.State = nil, nil
+		switch r.Step {
+		case StepOneHot:
+			values := make([][]string, len(rows))
+			for i, row := range rows {
+				values[i] = make([]string, len(indices))
+				for k, j := range indices {
+					values[i][k] = row[j]
+				}
+			}
+			r.OneHot = &OneHot{}
+			if err := r.OneHot.Fit(values); err != nil {
+				return err
+			}
+		case StepPassthrough, StepDrop:
+		default:
+			if err := r.fitStep(rows, indices); err != nil {
+				return err
+			}
+		}
+		c.Names = append(c.Names, r.names()...)
+	}
+	if len(c.Names) == 0 {
+		return errors.New("transform: no column is routed to a feature")
+	}
+	return nil
+}
+
+// fitStep fits the transformer of the step on the columns at the indices
+// and keeps its state. Only impute steps take missing values.
+func (r *Route) fitStep(rows [][]string, indices []int) error {
+	t, err := New(r.Step)
+	if err != nil {
+		return err
+	}
+	_, imputes := t.(*Impute)
+	x, err := numericColumns(rows, indices, r.Columns, imputes)
+	if err != nil {
+		return err
+	}
+	if err := t.Fit(x); err != nil {
+		return fmt.Errorf("transform: step %q: %v", r.Step, err)
+	}
+	out, err := t.Transform(x)
+	if err != nil {
+		return fmt.Errorf("transform: step %q: %v", r.Step, err)
+	}
+	if _, numCols := out.Dims(); numCols != len(indices) {
+		return fmt.Errorf("transform: step %q turns %d columns into %d, want the same number", r.Step, len(indices), numCols)
+	}
+	if r.State, err = json.Marshal(t); err != nil {
+		return fmt.Errorf("transform: step %q: %v", r.Step, err)
+	}
+	return nil
+}
+
+// transformer returns the fitted transformer of the step, decoded from
+// its state.
+func (r *Route) transformer() (Transformer, error) {
+	if r.State == nil {
+		return nil, ErrNotFitted
+	}
+	t, err := New(r.Step)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(r.State, t); err != nil {
+		return nil, fmt.Errorf("transform: step %q: %v", r.Step, err)
+	}
+	return t, nil
+}
+
+// names returns the names of the features of the fitted route.
+func (r *Route) names() []string {
+	switch r.Step {
+	case StepDrop:
+		return nil
+	case StepOneHot:
+		return r.OneHot.Names(r.Columns)
+	}
+	return r.Columns
+}
+
+// Encoder returns the function writing the features of a record of a file
+// with the header into dst, which holds a value for every feature. Values
+// of a one-hot column not seen by Fit set none of its features. Missing
+// numeric values are NaN. Dropped columns need not be in the header.
+func (c *ColumnTransformer) Encoder(header []string) (func(fields []string, dst []float64) error, error) {
+	if c.Names == nil {
+		return nil, ErrNotFitted
+	}
+	routes := c.routes()
+	indices := make([][]int, len(routes))
+	// steps holds the transformers of the routes, and buffers a row of
+	// their columns.
+	steps := make([]Transformer, len(routes))
+	buffers := make([]*mat64.Dense, len(routes))
+	for k, r := range routes {
+		if r.Step == StepDrop {
+			continue
+		}
+		var err error
+		if indices[k], err = columnIndices(header, r.Columns); err != nil {
+			return nil, err
+		}
+		switch r.Step {
+		case StepOneHot, StepPassthrough:
+		default:
+			if steps[k], err = r.transformer(); err != nil {
+				return nil, err
+			}
+			buffers[k] = mat64.NewDense(1, len(r.Columns), nil)
+		}
+	}
+	return func(fields []string, dst []float64) error {
+		if len(dst) != len(c.Names) {
+			return ErrColumns
+		}
+		out := 0
+		for k, r := range routes {
+			if r.Step == StepOneHot {
+				values := make([]string, len(indices[k]))
+				for m, j := range indices[k] {
+					values[m] = fields[j]
+				}
+				width := r.OneHot.Width()
+				if err := r.OneHot.Encode(values, dst[out:out+width]); err != nil {
+					return err
+				}
+				out += width
+				continue
+			}
+			row := dst[out : out+len(indices[k])]
+			if steps[k] != nil {
+				row = buffers[k].RawRowView(0)
+			}
+			for m, j := range indices[k] {
+				v, err := ParseFloat(fields[j])
+				if err != nil {
+					return fmt.Errorf("column %q: %v", r.Columns[m], err)
+				}
+				row[m] = v
+			}
+			if t := steps[k]; t != nil {
+				y, err := t.Transform(buffers[k])
+				if err != nil {
+					return fmt.Errorf("step %q: %v", r.Step, err)
+				}
+				copy(dst[out:], y.RawRowView(0))
+			}
+			out += len(indices[k])
+		}
+		return nil
+	}, nil
+}
+
+// Transform returns the feature matrix of the rows of a file with the
+// header.
+func (c *ColumnTransformer) Transform(header []string, rows [][]string) (*mat64.Dense, error) {
+	encode, err := c.Encoder(header)
+	if err != nil {
+		return nil, err
+	}
+	x := mat64.NewDense(len(rows), len(c.Names), nil)
+	for i, row := range rows {
+		if err := encode(row, x.RawRowView(i)); err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+1, err)
+		}
+	}
+	return x, nil
+}
+
+// columnIndices returns the index of every named column in the header.
+func columnIndices(header, columns []string) ([]int, error) {
+	indices := make([]int, len(columns))
+	for k, name := range columns {
+		if indices[k] = slices.Index(header, name); indices[k] < 0 {
+			return nil, fmt.Errorf("transform: no column %q", name)
+		}
+	}
+	return indices, nil
+}
+
+// numericColumns returns the values of the columns at the indices as a
+// matrix. Missing values are NaN, and an error unless missing is set.
+func numericColumns(rows [][]string, indices []int, names []string, missing bool) (*mat64.Dense, error) {
+	x := mat64.NewDense(len(rows), len(indices), nil)
+	for i, row := range rows {
+		for k, j := range indices {
+			v, err := ParseFloat(row[j])
+			if err != nil || (math.IsNaN(v) && !missing) {
+				return nil, fmt.Errorf("transform: row %d: column %q: %q is not a number", i+1, names[k], row[j])
+			}
+			x.Set(i, k, v)
+		}
+	}
+	return x, nil
+}
@@ -0,0 +1,192 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ErrUnknownCategory is returned when Ordinal encodes a category it was
+// not fitted on.
+var ErrUnknownCategory = errors.New("transform: unknown category")
+
+// Categorical encodes text columns, such as the purpose of a loan, as
+// numeric features. It learns the categories of every column, its
+// vocabulary, from the training rows. The fitted vocabularies encode to
+// JSON, so that an encoder is saved along with the model it feeds and
+// new rows are encoded the same way.
+type Categorical interface {
+	// Fit learns the categories of every column from the rows, each
+	// holding a value per column.
+	Fit(rows [][]string) error
+	// Width returns the number of features of the encoded columns.
+	Width() int
+	// Names returns the names of the features, given the names of the
+	// columns.
+	Names(columns []string) []string
+	// Encode writes the features of a row of values of the columns into
+	// dst, which holds Width values.
+	Encode(fields []string, dst []float64) error
+}
+
+// vocabularies returns the sorted distinct values of every column of the
+// rows.
+func vocabularies(rows [][]string) ([][]string, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("transform: no rows")
+	}
+	numCols := len(rows[0])
+	seen := make([]map[string]bool, numCols)
+	for j := range seen {
+		seen[j] = make(map[string]bool)
+	}
+	for i, row := range rows {
+		if len(row) != numCols {
+			return nil, fmt.Errorf("transform: row %d has %d values, expected %d", i+1, len(row), numCols)
+		}
+		for j, v := range row {
+			seen[j][v] = true
+		}
+	}
+	categories := make([][]string, numCols)
+	for j := range categories {
+		for v := range seen[j] {
+			categories[j] = append(categories[j], v)
+		}
+		sort.Strings(categories[j])
+	}
+	return categories, nil
+}
+
+// OneHot encodes every category of a column as its own feature, 1 for the
+// rows of the category and 0 for the others, which suits categories
+// without an order. A category it was not fitted on sets none of the
+// features of its column.
+type OneHot struct {
+	// Categories holds the sorted categories of every column.
+	Categories [][]string `json:"categories"`
+}
+
+// Fit implements Categorical.
+func (o *OneHot) Fit(rows [][]string) error {
+	categories, err := vocabularies(rows)
+	if err != nil {
+		return err
+	}
+	o.Categories = categories
+	return nil
+}
+
+// Width implements Categorical.
+func (o *OneHot) Width() int {
+	var n int
+	for _, categories := range o.Categories {
+		n += len(categories)
+	}
+	return n
+}
+
+// Names implements Categorical. The feature of a category is named
+// column=category.
+func (o *OneHot) Names(columns []string) []string {
+	var names []string
+	for j, categories := range o.Categories {
+		for _, category := range categories {
+			names = append(names, columns[j]+"="+category)
+		}
+	}
+	return names
+}
+
+// Encode implements Categorical.
+func (o *OneHot) Encode(fields []string, dst []float64) error {
+	if o.Categories == nil {
+		return ErrNotFitted
+	}
+	if len(fields) != len(o.Categories) || len(dst) != o.Width() {
+		return ErrColumns
+	}
+	out := 0
+	for j, categories := range o.Categories {
+		for _, category := range categories {
+			dst[out] = 0
+			if fields[j] == category {
+				dst[out] = 1
+			}
+			out++
+		}
+	}
+	return nil
+}
+
+// Ordinal encodes every category of a column as its index in the
+// categories of the column, a single feature per column, which suits
+// ordered categories such as grades, and trees. The categories are
+// sorted, unless Order gives them in the order of the column, such as
+// low, medium, high.
+type Ordinal struct {
+	// Order, when set, holds the categories of some columns in their
+	// order, and nil for the columns whose categories are sorted.
+	Order [][]string `json:"order,omitempty"`
+	// Categories holds the fitted categories of every column, in the
+	// order of their codes.
+	Categories [][]string `json:"categories"`
+}
+
+// Fit implements Categorical. The categories of Order must hold every
+// value of their column.
+func (o *Ordinal) Fit(rows [][]string) error {
+	categories, err := vocabularies(rows)
+	if err != nil {
+		return err
+	}
+	if o.Order != nil {
+		if len(o.Order) != len(categories) {
+			return fmt.Errorf("transform: the order of the categories is given for %d columns, the rows have %d", len(o.Order), len(categories))
+		}
+		for j, order := range o.Order {
+			if order == nil {
+				continue
+			}
+			for _, v := range categories[j] {
+				if !slices.Contains(order, v) {
+					return fmt.Errorf("%w %q in column %d", ErrUnknownCategory, v, j+1)
+				}
+			}
+			categories[j] = slices.Clone(order)
+		}
+	}
+	o.Categories = categories
+	return nil
+}
+
+// Width implements Categorical.
+func (o *Ordinal) Width() int {
+	return len(o.Categories)
+}
+
+// Names implements Categorical. The features keep the names of the
+// columns.
+func (o *Ordinal) Names(columns []string) []string {
+	return slices.Clone(columns)
+}
+
+// Encode implements Categorical. It returns an error wrapping
+// ErrUnknownCategory for a category it was not fitted on.
+func (o *Ordinal) Encode(fields []string, dst []float64) error {
+	if o.Categories == nil {
+		return ErrNotFitted
+	}
+	if len(fields) != len(o.Categories) || len(dst) != len(o.Categories) {
+		return ErrColumns
+	}
+	for j, categories := range o.Categories {
+		code := slices.Index(categories, fields[j])
+		if code < 0 {
+			return fmt.Errorf("%w %q in column %d", ErrUnknownCategory, fields[j], j+1)
+		}
+		dst[j] = float64(code)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Strategy names how Impute fills the missing values of a column.
+type Strategy string
+
+// Strategies of Impute.
+const (
+	// Mean fills a column with the mean of its values.
+	Mean Strategy = "mean"
+	// Median fills a column with the median of its values, which
+	// outliers do not move.
+	Median Strategy = "median"
+	// Mode fills a column with its most frequent value, the smallest of
+	// ties, which suits codes and counts.
+	Mode Strategy = "mode"
+	// Constant fills a column with a given value.
+	Constant Strategy = "constant"
+)
+
+// missing holds the spellings of missing values in CSV files, in lower
+// case.
+var missing = []string{"", "na", "n/a", "nan", "null", "none", "?"}
+
+// ParseFloat parses a field of a CSV file, returning NaN for the missing
+// values of exports: blanks, NA, N/A, NaN, null, none and ?, in any case.
+func ParseFloat(field string) (float64, error) {
+	field = strings.TrimSpace(field)
+	for _, m := range missing {
+		if strings.EqualFold(field, m) {
+			return math.NaN(), nil
+		}
+	}
+	return strconv.ParseFloat(field, 64)
+}
+
+// ParseStrategy parses a strategy written mean, median, mode or
+// constant=<value>, returning the value of the constant.
+func ParseStrategy(s string) (Strategy, float64, error) {
+	name, value, hasValue := strings.Cut(s, "=")
+	switch st := Strategy(name); st {
+	case Mean, Median, Mode:
+		if !hasValue {
+			return st, 0, nil
+		}
+	case Constant:
+		if hasValue {
+			c, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(c) {
+				return "", 0, fmt.Errorf("transform: constant %q is not a number", value)
+			}
+			return st, c, nil
+		}
+		return "", 0, fmt.Errorf("transform: expected constant=<value>, not %q", s)
+	}
+	return "", 0, fmt.Errorf("transform: unknown strategy %q, expected mean, median, mode or constant=<value>", s)
+}
+
+// Impute fills the missing values, NaN, of every column with a value
+// learned from the training rows, by the strategy of the column, so that
+// new rows are filled with the same values. It keeps the number of
+// columns.
+type Impute struct {
+	// Strategies holds the strategy of every column. A single strategy
+	// applies to every column, and none means Mean.
+	Strategies []Strategy `json:"strategies,omitempty"`
+	// Constants holds the values of the columns of the Constant strategy,
+	// aligned with Strategies.
+	Constants []float64 `json:"constants,omitempty"`
+	// Values holds the value every column is filled with.
+	Values []float64 `json:"values"`
+	// Missing holds the number of missing values of every column of the
+	// fitted rows.
+	Missing []int `json:"missing"`
+}
+
+// strategy returns the strategy of column j and its constant.
+func (m *Impute) strategy(j int) (Strategy, float64) {
+	switch len(m.Strategies) {
+	case 0:
+		return Mean, 0
+	case 1:
+		j = 0
+	}
+	var c float64
+	if j < len(m.Constants) {
+		c = m.Constants[j]
+	}
+	return m.Strategies[j], c
+}
+
+// Fit implements Transformer. Columns without any value must use the
+// Constant strategy.
+func (m *Impute) Fit(x mat64.Matrix) error {
+	numRows, numCols := x.Dims()
+	if len(m.Strategies) > 1 && len(m.Strategies) != numCols {
+		return fmt.Errorf("transform: %d strategies for %d columns", len(m.Strategies), numCols)
+	}
+	m.Values = make([]float64, numCols)
+	m.Missing = make([]int, numCols)
+	for j := 0; j < numCols; j++ {
+		var values []float64
+		for i := 0; i < numRows; i++ {
+			if v := x.At(i, j); math.IsNaN(v) {
+				m.Missing[j]++
+			} else {
+				values = append(values, v)
+			}
+		}
+		st, c := m.strategy(j)
+		if len(values) == 0 && st != Constant {
+			return fmt.Errorf("transform: column %d has no values to take the %s of", j+1, st)
+		}
+		switch st {
+		case Mean:
+			for _, v := range values {
+				m.Values[j] += v / float64(len(values))
+			}
+		case Median:
+			sort.Float64s(values)
+			n := len(values)
+			m.Values[j] = (values[(n-1)/2] + values[n/2]) / 2
+		case Mode:
+			sort.Float64s(values)
+			best := 0
+			for start := 0; start < len(values); {
+				end := start
+				for end < len(values) && values[end] == values[start] {
+					end++
+				}
+				if end-start > best {
+					best, m.Values[j] = end-start, values[start]
+				}
+				start = end
+			}
+		case Constant:
+			m.Values[j] = c
+		default:
+			return fmt.Errorf("transform: unknown strategy %q of column %d", st, j+1)
+		}
+	}
+	return nil
+}
+
+// Transform implements Transformer.
+func (m *Impute) Transform(x mat64.Matrix) (*mat64.Dense, error) {
+	if err := checkColumns(x, m.Values); err != nil {
+		return nil, err
+	}
+	return columnMap(x, func(j int, v float64) float64 {
+		if math.IsNaN(v) {
+			return m.Values[j]
+		}
+		return v
+	}), nil
+}
@@ -0,0 +1,145 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// OutlierMethod names how Outliers bounds the values of a column.
+type OutlierMethod string
+
+// Methods of Outliers.
+const (
+	// IQR bounds a column by its quartiles, widened by K times the
+	// interquartile range, 1.5 by default. The quartiles do not move with
+	// the outliers themselves.
+	IQR OutlierMethod = "iqr"
+	// ZScore bounds a column by its mean, give or take K standard
+	// deviations, 3 by default.
+	ZScore OutlierMethod = "zscore"
+)
+
+// ParseOutlierMethod parses a method written iqr or zscore, optionally
+// followed by =<k>, returning the multiplier k, 0 for the default.
+func ParseOutlierMethod(s string) (OutlierMethod, float64, error) {
+	name, value, hasValue := strings.Cut(s, "=")
+	m := OutlierMethod(name)
+	if m != IQR && m != ZScore {
+		return "", 0, fmt.Errorf("transform: unknown outlier method %q, expected iqr or zscore", s)
+	}
+	if !hasValue {
+		return m, 0, nil
+	}
+	k, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(k > 0) || math.IsInf(k, 1) {
+		return "", 0, fmt.Errorf("transform: outlier multiplier %q is not a positive number", value)
+	}
+	return m, k, nil
+}
+
+// Outliers learns bounds of every column from the training rows and finds
+// the rows with a value outside them. It does not change values; Inside
+// gives the rows to keep, so that a few extreme rows do not skew a fit.
+type Outliers struct {
+	// Method bounds every column, IQR if empty.
+	Method OutlierMethod `json:"method,omitempty"`
+	// K widens the bounds. Zero means the default of the method.
+	K float64 `json:"k,omitempty"`
+	// Lower and Upper hold the bounds of every column, which are inside.
+	Lower []float64 `json:"lower"`
+	Upper []float64 `json:"upper"`
+}
+
+// Fit learns the bounds of every column from the rows of x.
+func (o *Outliers) Fit(x mat64.Matrix) error {
+	numRows, numCols := x.Dims()
+	if numRows == 0 {
+		return fmt.Errorf("transform: no rows to bound")
+	}
+	if o.K < 0 || math.IsNaN(o.K) {
+		return fmt.Errorf("transform: outlier multiplier %v is negative", o.K)
+	}
+	o.Lower = make([]float64, numCols)
+	o.Upper = make([]float64, numCols)
+	for j := 0; j < numCols; j++ {
+		col := mat64.Col(nil, j, x)
+		switch o.Method {
+		case IQR, "":
+			k := o.K
+			if k == 0 {
+				k = 1.5
+			}
+			sort.Float64s(col)
+			q1, q3 := quantile(col, 0.25), quantile(col, 0.75)
+			o.Lower[j], o.Upper[j] = q1-k*(q3-q1), q3+k*(q3-q1)
+		case ZScore:
+			k := o.K
+			if k == 0 {
+				k = 3
+			}
+			var mean, variance float64
+			for _, v := range col {
+				mean += v / float64(numRows)
+			}
+			for _, v := range col {
+				variance += (v - mean) * (v - mean) / float64(numRows)
+			}
+			std := math.Sqrt(variance)
+			o.Lower[j], o.Upper[j] = mean-k*std, mean+k*std
+		default:
+			return fmt.Errorf("transform: unknown outlier method %q", o.Method)
+		}
+	}
+	return nil
+}
+
+// quantile returns the q quantile of the sorted values, interpolating
+// linearly between the two closest values.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// Flag reports for every row of x whether it has a value outside the
+// bounds of its column. Missing values, NaN, are not outliers.
+func (o *Outliers) Flag(x mat64.Matrix) ([]bool, error) {
+	if err := checkColumns(x, o.Lower); err != nil {
+		return nil, err
+	}
+	numRows, numCols := x.Dims()
+	flags := make([]bool, numRows)
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			if v := x.At(i, j); v < o.Lower[j] || v > o.Upper[j] {
+				flags[i] = true
+				break
+			}
+		}
+	}
+	return flags, nil
+}
+
+// Inside returns the indices of the rows of x with every value inside the
+// bounds, in order.
+func (o *Outliers) Inside(x mat64.Matrix) ([]int, error) {
+	flags, err := o.Flag(x)
+	if err != nil {
+		return nil, err
+	}
+	var rows []int
+	for i, outside := range flags {
+		if !outside {
+			rows = append(rows, i)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,90 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// builtinSteps are the steps of this package, which cannot be registered.
+var builtinSteps = []string{"standard", "minmax", "impute"}
+
+var registry = struct {
+	sync.RWMutex
+	steps map[string]func() Transformer
+}{steps: make(map[string]func() Transformer)}
+
+// Register adds a step to the registry under a name, typically from an
+// init function, so that New finds it by name like the built-in steps,
+// and a ColumnTransformer routes columns to it. newT returns a new
+// transformer of the step. The transformer must keep the number of
+// columns, and its fitted state must encode to JSON and decode back into
+// a new transformer, so that it can be saved along with a model. Names are unique, and cannot hold the separators of the
+// descriptions of a ColumnTransformer: ',', ':', '=' and '*'.
+func Register(step string, newT func() Transformer) error {
+	if step == "" || newT == nil {
+		return errors.New("transform: a registered step needs a name and a constructor")
+	}
+	if slices.Contains(builtinSteps, step) || slices.Contains(routeSteps, step) {
+		return fmt.Errorf("transform: %q is a built-in step", step)
+	}
+	if strings.ContainsAny(step, ",:=*") {
+		return fmt.Errorf("transform: step name %q holds one of , : = *", step)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.steps[step]; ok {
+		return fmt.Errorf("transform: %q is already registered", step)
+	}
+	registry.steps[step] = newT
+	return nil
+}
+
+// MustRegister is like Register but panics on an error.
+func MustRegister(step string, newT func() Transformer) {
+	if err := Register(step, newT); err != nil {
+		panic(err)
+	}
+}
+
+// lookup returns the constructor of the registered step.
+func lookup(step string) (func() Transformer, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	newT, ok := registry.steps[step]
+	return newT, ok
+}
+
+// New returns a new transformer of the step: standard for Standard,
+// minmax for MinMax, impute for Impute with the Mean strategy, or a
+// registered step.
+func New(step string) (Transformer, error) {
+	switch step {
+	case "standard":
+		return &Standard{}, nil
+	case "minmax":
+		return &MinMax{}, nil
+	case "impute":
+		return &Impute{}, nil
+	}
+	newT, ok := lookup(step)
+	if !ok {
+		return nil, fmt.Errorf("transform: unknown step %q, expected one of %s", step, strings.Join(slices.Concat(builtinSteps, Registered()), ", "))
+	}
+	return newT(), nil
+}
+
+// Registered returns the names of the registered steps, sorted.
+func Registered() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	steps := make([]string, 0, len(registry.steps))
+	for step := range registry.steps {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	return steps
+}
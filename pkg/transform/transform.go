@@ -0,0 +1,159 @@
+// Package transform holds preprocessing steps that are fitted on training
+// rows and then applied to any rows, such as feature scalers, along with
+// a checker of the invariants every step should keep. New returns the
+// steps by name, including those other packages add with Register.
+// ColumnTransformer routes the named columns of raw rows to their own
+// steps.
+package transform
+
+import (
+	"errors"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// ErrNotFitted is returned when a transformer is used before Fit.
+var ErrNotFitted = errors.New("transform: transformer is not fitted")
+
+// ErrColumns is returned when the rows to transform do not have the
+// number of columns the transformer was fitted on.
+var ErrColumns = errors.New("transform: number of columns differs from the fitted rows")
+
+// Transformer learns a transformation from training rows and applies it
+// to rows with the same columns.
+type Transformer interface {
+	// Fit learns the parameters of the transformation from the rows of x.
+	Fit(x mat64.Matrix) error
+	// Transform returns the transformed copy of x, leaving x unchanged.
+	Transform(x mat64.Matrix) (*mat64.Dense, error)
+}
+
+// Inverter is implemented by transformers that can undo their
+// transformation.
+type Inverter interface {
+	// InverseTransform maps transformed rows back to the original scale.
+	InverseTransform(x mat64.Matrix) (*mat64.Dense, error)
+}
+
+// columnMap applies f to every value of x, along with its column index.
+func columnMap(x mat64.Matrix, f func(j int, v float64) float64) *mat64.Dense {
+	numRows, numCols := x.Dims()
+	out := mat64.NewDense(numRows, numCols, nil)
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			out.Set(i, j, f(j, x.At(i, j)))
+		}
+	}
+	return out
+}
+
+// constantTol is the spread, relative to the mean, below which a column
+// is taken to be constant.
+const constantTol = 1e-12
+
+// Standard rescales every column to zero mean and unit variance.
+// Constant columns are only centered.
+type Standard struct {
+	// Mean and Std are the mean and standard deviation of every column of
+	// the fitted rows.
+	Mean, Std []float64
+}
+
+// Fit implements Transformer.
+func (s *Standard) Fit(x mat64.Matrix) error {
+	numRows, numCols := x.Dims()
+	s.Mean = make([]float64, numCols)
+	s.Std = make([]float64, numCols)
+	for j := 0; j < numCols; j++ {
+		col := mat64.Col(nil, j, x)
+		for _, v := range col {
+			s.Mean[j] += v
+		}
+		s.Mean[j] /= float64(numRows)
+		var variance float64
+		for _, v := range col {
+			variance += (v - s.Mean[j]) * (v - s.Mean[j])
+		}
+		s.Std[j] = math.Sqrt(variance / float64(numRows))
+		// Rounding leaves a tiny spread in constant columns.
+		if s.Std[j] <= constantTol*math.Abs(s.Mean[j]) {
+			s.Std[j] = 1
+		}
+	}
+	return nil
+}
+
+// Transform implements Transformer.
+func (s *Standard) Transform(x mat64.Matrix) (*mat64.Dense, error) {
+	if err := checkColumns(x, s.Mean); err != nil {
+		return nil, err
+	}
+	return columnMap(x, func(j int, v float64) float64 { return (v - s.Mean[j]) / s.Std[j] }), nil
+}
+
+// InverseTransform implements Inverter.
+func (s *Standard) InverseTransform(x mat64.Matrix) (*mat64.Dense, error) {
+	if err := checkColumns(x, s.Mean); err != nil {
+		return nil, err
+	}
+	return columnMap(x, func(j int, v float64) float64 { return v*s.Std[j] + s.Mean[j] }), nil
+}
+
+// MinMax rescales every column so that the fitted rows span [0, 1], as
+// the FICO scores of the loan example. Constant columns are mapped to 0.
+type MinMax struct {
+	// Min and Max are the bounds of every column of the fitted rows.
+	Min, Max []float64
+}
+
+// Fit implements Transformer.
+func (m *MinMax) Fit(x mat64.Matrix) error {
+	numRows, numCols := x.Dims()
+	m.Min = make([]float64, numCols)
+	m.Max = make([]float64, numCols)
+	for j := 0; j < numCols; j++ {
+		m.Min[j], m.Max[j] = math.Inf(1), math.Inf(-1)
+		for i := 0; i < numRows; i++ {
+			m.Min[j] = math.Min(m.Min[j], x.At(i, j))
+			m.Max[j] = math.Max(m.Max[j], x.At(i, j))
+		}
+	}
+	return nil
+}
+
+// span returns the width of column j, or 1 for constant columns.
+func (m *MinMax) span(j int) float64 {
+	if m.Max[j] == m.Min[j] {
+		return 1
+	}
+	return m.Max[j] - m.Min[j]
+}
+
+// Transform implements Transformer.
+func (m *MinMax) Transform(x mat64.Matrix) (*mat64.Dense, error) {
+	if err := checkColumns(x, m.Min); err != nil {
+		return nil, err
+	}
+	return columnMap(x, func(j int, v float64) float64 { return (v - m.Min[j]) / m.span(j) }), nil
+}
+
+// InverseTransform implements Inverter.
+func (m *MinMax) InverseTransform(x mat64.Matrix) (*mat64.Dense, error) {
+	if err := checkColumns(x, m.Min); err != nil {
+		return nil, err
+	}
+	return columnMap(x, func(j int, v float64) float64 { return v*m.span(j) + m.Min[j] }), nil
+}
+
+// checkColumns checks that the transformer was fitted on rows with the
+// columns of x, given one of its per-column parameters.
+func checkColumns(x mat64.Matrix, params []float64) error {
+	if params == nil {
+		return ErrNotFitted
+	}
+	if _, numCols := x.Dims(); numCols != len(params) {
+		return ErrColumns
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package tree
+
+import "sort"
+
+// bins holds the histogram bins of every feature: the values separating
+// the bins and the bin of every row.
+type bins struct {
+	thresholds [][]float64
+	rowBins    [][]int32
+}
+
+// newBins bins the columns over the given rows into at most maxBins bins
+// each. The bins hold about the same number of rows, and distinct values
+// are never split between bins.
+func newBins(cols [][]float64, rows []int, maxBins int) *bins {
+	b := &bins{thresholds: make([][]float64, len(cols)), rowBins: make([][]int32, len(cols))}
+	for j, col := range cols {
+		values := make([]float64, len(rows))
+		for k, i := range rows {
+			values[k] = col[i]
+		}
+		sort.Float64s(values)
+		// Cut after the values at the quantiles, or after every
+		// distinct value when there are few of them.
+		var cuts []float64
+		for q := 1; q < maxBins; q++ {
+			v := values[q*len(values)/maxBins]
+			if len(cuts) == 0 || v > cuts[len(cuts)-1] {
+				cuts = append(cuts, v)
+			}
+		}
+		if distinct := countDistinct(values); distinct <= maxBins {
+			cuts = cuts[:0]
+			for k, v := range values {
+				if k == 0 || v > values[k-1] {
+					cuts = append(cuts, v)
+				}
+			}
+		}
+		// Place the thresholds between a cut and the next larger value,
+		// dropping the cut at the largest value.
+		for _, cut := range cuts {
+			k := sort.Search(len(values), func(k int) bool { return values[k] > cut })
+			if k < len(values) {
+				b.thresholds[j] = append(b.thresholds[j], cut+(values[k]-cut)/2)
+			}
+		}
+		// Assign every row to the first bin whose threshold it does not
+		// exceed.
+		b.rowBins[j] = make([]int32, len(col))
+		for _, i := range rows {
+			b.rowBins[j][i] = int32(sort.SearchFloat64s(b.thresholds[j], col[i]))
+		}
+	}
+	return b
+}
+
+// countDistinct returns the number of distinct sorted values.
+func countDistinct(sorted []float64) int {
+	var n int
+	for k, v := range sorted {
+		if k == 0 || v > sorted[k-1] {
+			n++
+		}
+	}
+	return n
+}
+
+// merge adds the statistics o when sign is 1, and removes them when sign
+// is -1.
+func (s *stats) merge(o *stats, sign float64) {
+	s.weight += sign * o.weight
+	s.count += int(sign) * o.count
+	for c, w := range o.classes {
+		s.classes[c] += sign * w
+	}
+	s.sum += sign * o.sum
+	s.sumSq += sign * o.sumSq
+}
+
+// histogramSplit returns the best split of the rows on feature j between
+// the bins. It accumulates the statistics and the range of the values of
+// every bin in one pass over the rows, then sweeps the bins. The threshold
+// lies halfway between the values of the rows on either side, as for exact
+// splits.
+func (g *grower) histogramSplit(rows []int, j int, parent stats) (threshold, decrease float64, ok bool) {
+	minLeaf := max(g.t.Params.MinSamplesLeaf, 1)
+	parentImpurity := parent.weight * parent.impurity()
+	numBins := len(g.bins.thresholds[j]) + 1
+	hist := make([]stats, numBins)
+	low, high := make([]float64, numBins), make([]float64, numBins)
+	for b := range hist {
+		hist[b] = g.newStats()
+	}
+	col, rowBins := g.cols[j], g.bins.rowBins[j]
+	for _, i := range rows {
+		b, v := rowBins[i], col[i]
+		if hist[b].count == 0 || v < low[b] {
+			low[b] = v
+		}
+		if hist[b].count == 0 || v > high[b] {
+			high[b] = v
+		}
+		hist[b].add(g.y[i], g.weight[i], 1)
+	}
+	// Move the bins one at a time from the right side to the left side,
+	// trying a split before every non-empty bin.
+	left, right := g.newStats(), parent.clone()
+	last := -1
+	for b := range hist {
+		if hist[b].count == 0 {
+			continue
+		}
+		if last >= 0 && left.count >= minLeaf && right.count >= minLeaf {
+			d := parentImpurity - left.weight*left.impurity() - right.weight*right.impurity()
+			if !ok || d > decrease {
+				threshold, decrease, ok = high[last]+(low[b]-high[last])/2, d, true
+			}
+		}
+		left.merge(&hist[b], 1)
+		right.merge(&hist[b], -1)
+		last = b
+	}
+	return threshold, decrease, ok
+}
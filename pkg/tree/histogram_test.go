@@ -0,0 +1,67 @@
+package tree
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+	"golang.org/x/exp/rand"
+)
+
+// syntheticRows returns rows of Gaussian features, rounded to the given
+// number of distinct values per feature when levels is positive, with
+// class labels from a noisy rule on the first features.
+func syntheticRows(numRows, numFeatures, levels int) (*mat64.Dense, []float64) {
+	r := rand.New(rand.NewSource(1))
+	x := mat64.NewDense(numRows, numFeatures, nil)
+	y := make([]float64, numRows)
+	for i := range y {
+		row := x.RawRowView(i)
+		for j := range row {
+			row[j] = r.NormFloat64()
+			if levels > 0 {
+				row[j] = float64(int(r.Float64() * float64(levels)))
+			}
+		}
+		if row[0]+row[1]*row[2]+0.5*r.NormFloat64() > 0 {
+			y[i] = 1
+		}
+	}
+	return x, y
+}
+
+func TestHistogramSplitMatchesExactOnFewValues(t *testing.T) {
+	x, y := syntheticRows(2000, 5, 20)
+	var trees [2][]byte
+	for k, maxBins := range []int{0, 32} {
+		tr := New(Classification, Params{MaxDepth: 8, MaxBins: maxBins})
+		if err := tr.Fit(x, y, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		tr.Params.MaxBins = 0
+		var err error
+		if trees[k], err = json.Marshal(tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(trees[0]) != string(trees[1]) {
+		t.Error("the tree grown on 32 bins of 20-valued features differs from the exact one")
+	}
+}
+
+// benchmarkSplit grows a deep tree on many rows of continuous features,
+// where exact split finding sorts the rows of every node.
+func benchmarkSplit(b *testing.B, maxBins int) {
+	x, y := syntheticRows(50000, 10, 0)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr := New(Classification, Params{MaxDepth: 10, MaxBins: maxBins})
+		if err := tr.Fit(x, y, nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExactSplit(b *testing.B) { benchmarkSplit(b, 0) }
+
+func BenchmarkHistogramSplit(b *testing.B) { benchmarkSplit(b, 255) }
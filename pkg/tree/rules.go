@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Leaves returns the number of leaves of the tree.
+func (t *Tree) Leaves() int {
+	var n int
+	for i := range t.Nodes {
+		if t.Nodes[i].Leaf() {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteRules writes the tree as nested rules, one line per branch and
+// leaf, indented by depth. Features are named by features, and the
+// classes of classification trees by classes; either may be nil, which
+// names them by index.
+func (t *Tree) WriteRules(w io.Writer, features, classes []string) error {
+	if len(t.Nodes) == 0 {
+		_, err := fmt.Fprintln(w, "(empty tree)")
+		return err
+	}
+	return t.writeRules(w, 0, 0, features, classes)
+}
+
+func (t *Tree) writeRules(w io.Writer, i, depth int, features, classes []string) error {
+	n := &t.Nodes[i]
+	indent := strings.Repeat("  ", depth)
+	if n.Leaf() {
+		_, err := fmt.Fprintf(w, "%s%s\n", indent, t.describe(n, classes))
+		return err
+	}
+	name := fmt.Sprintf("feature %d", n.Feature)
+	if n.Feature < len(features) {
+		name = features[n.Feature]
+	}
+	if _, err := fmt.Fprintf(w, "%s%s <= %.4g\n", indent, name, n.Threshold); err != nil {
+		return err
+	}
+	if err := t.writeRules(w, n.Left, depth+1, features, classes); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%s%s > %.4g\n", indent, name, n.Threshold); err != nil {
+		return err
+	}
+	return t.writeRules(w, n.Right, depth+1, features, classes)
+}
+
+// describe returns the prediction of a leaf, its class and probability
+// for classification trees or its value for regression trees, and its
+// weight.
+func (t *Tree) describe(n *Node, classes []string) string {
+	if t.Task == Regression {
+		return fmt.Sprintf("value %.4g (weight %g)", n.Value[0], n.Weight)
+	}
+	c := int(Decide(t.Task, n.Value))
+	name := fmt.Sprint(c)
+	if c < len(classes) {
+		name = classes[c]
+	}
+	return fmt.Sprintf("class %s (p = %.2f, weight %g)", name, n.Value[c], n.Weight)
+}
@@ -0,0 +1,435 @@
+// Package tree grows CART decision trees: binary trees whose every split
+// compares one feature with a threshold, chosen to minimize the Gini
+// impurity of classification trees or the variance of regression trees.
+// Rows can be weighted, every split can be drawn from a random subset of
+// the features, as random forests do, and the decrease of impurity
+// brought by every feature is recorded as its importance. On large
+// datasets, splits can be found on histograms of the features rather than
+// on every distinct value. Trees hold only exported fields, so they
+// serialize to JSON.
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Task is what a tree predicts.
+type Task int
+
+const (
+	// Classification trees predict class indices 0, 1, ... and the
+	// probability of every class.
+	Classification Task = iota
+	// Regression trees predict the weighted mean of the target.
+	Regression
+)
+
+// String returns the name of the task.
+func (t Task) String() string {
+	switch t {
+	case Classification:
+		return "classification"
+	case Regression:
+		return "regression"
+	}
+	return fmt.Sprintf("Task(%d)", int(t))
+}
+
+// MarshalText implements encoding.TextMarshaler, so that the task is
+// saved by name.
+func (t Task) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Task) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "classification":
+		*t = Classification
+	case "regression":
+		*t = Regression
+	default:
+		return fmt.Errorf("tree: unknown task %q", text)
+	}
+	return nil
+}
+
+var (
+	// ErrLengths is returned when the number of labels or weights differs
+	// from the number of rows, or there are no rows.
+	ErrLengths = errors.New("tree: rows, labels and weights differ in number")
+	// ErrLabels is returned when the labels of a classification tree are
+	// not class indices.
+	ErrLabels = errors.New("tree: classification labels must be non-negative integers")
+	// ErrWeights is returned when a weight is negative or every weight
+	// is 0.
+	ErrWeights = errors.New("tree: weights must be non-negative with a positive sum")
+)
+
+// Params holds the settings limiting the growth of a tree.
+type Params struct {
+	// MaxDepth is the largest depth of a leaf, or 0 for no limit.
+	MaxDepth int `json:"max_depth"`
+	// MinSamplesSplit is the fewest rows a node needs to be split.
+	// Values below 2 are taken as 2.
+	MinSamplesSplit int `json:"min_samples_split"`
+	// MinSamplesLeaf is the fewest rows of every leaf. Values below 1 are
+	// taken as 1.
+	MinSamplesLeaf int `json:"min_samples_leaf"`
+	// MaxFeatures is the number of features drawn at random as split
+	// candidates at every node, or 0 to consider every feature.
+	MaxFeatures int `json:"max_features"`
+	// MaxBins, when positive, bins every feature into at most MaxBins
+	// quantile bins before growing the tree, and only tries splits
+	// between bins. Split finding then takes a pass over the rows of a
+	// node instead of a sort, which is much faster on large datasets.
+	// Features with at most MaxBins distinct values get the same splits
+	// as without bins. BenchmarkHistogramSplit and BenchmarkExactSplit
+	// compare the two.
+	MaxBins int `json:"max_bins,omitempty"`
+}
+
+// Node is a node of a tree. Leaves have a Feature of -1.
+type Node struct {
+	// Feature and Threshold define the split: rows whose feature is at
+	// most the threshold go to the Left child, the others to the Right
+	// child. Children are indices into the nodes of the tree.
+	Feature   int     `json:"feature"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Left      int     `json:"left,omitempty"`
+	Right     int     `json:"right,omitempty"`
+	// Value holds the weighted class probabilities of the rows of the
+	// node for classification trees, and their weighted mean for
+	// regression trees.
+	Value []float64 `json:"value"`
+	// Weight is the total weight of the rows of the node.
+	Weight float64 `json:"weight"`
+}
+
+// Leaf reports whether the node is a leaf.
+func (n *Node) Leaf() bool {
+	return n.Feature < 0
+}
+
+// Tree is a CART decision tree.
+type Tree struct {
+	Task   Task   `json:"task"`
+	Params Params `json:"params"`
+	// NumFeatures is the number of features of the fitted rows.
+	NumFeatures int `json:"num_features"`
+	// NumClasses is the number of classes of a classification tree. When
+	// it is 0, Fit sets it from the largest label.
+	NumClasses int `json:"num_classes,omitempty"`
+	// Nodes holds the nodes of the fitted tree, the root first.
+	Nodes []Node `json:"nodes"`
+	// Importances holds the share of the total weighted impurity
+	// decrease brought by the splits on every feature.
+	Importances []float64 `json:"importances"`
+}
+
+// New returns an unfitted tree.
+func New(task Task, params Params) *Tree {
+	return &Tree{Task: task, Params: params}
+}
+
+// Fit grows the tree on the rows of x and their labels y. Weights, when
+// not nil, weigh every row; rows of weight 0 are left out. The random
+// source draws the split candidates when Params.MaxFeatures is below the
+// number of features, and may be nil otherwise.
+func (t *Tree) Fit(x mat64.Matrix, y, weights []float64, r *rand.Rand) error {
+	numRows, numFeatures := x.Dims()
+	if numRows == 0 || len(y) != numRows || (weights != nil && len(weights) != numRows) {
+		return ErrLengths
+	}
+	// Gather the rows that take part, with their weights.
+	g := &grower{t: t, y: y, r: r, weight: make([]float64, numRows)}
+	var rows []int
+	var total float64
+	for i := 0; i < numRows; i++ {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		if w < 0 || math.IsNaN(w) {
+			return ErrWeights
+		}
+		if t.Task == Classification {
+			if y[i] < 0 || y[i] != math.Trunc(y[i]) {
+				return ErrLabels
+			}
+			t.NumClasses = max(t.NumClasses, int(y[i])+1)
+		}
+		if w > 0 {
+			rows = append(rows, i)
+			g.weight[i] = w
+			total += w
+		}
+	}
+	if total == 0 {
+		return ErrWeights
+	}
+	// Keep the columns at hand for sorting the rows.
+	g.cols = make([][]float64, numFeatures)
+	for j := range g.cols {
+		g.cols[j] = mat64.Col(nil, j, x)
+	}
+	if t.Params.MaxBins > 0 {
+		g.bins = newBins(g.cols, rows, t.Params.MaxBins)
+	}
+	t.NumFeatures = numFeatures
+	t.Nodes = nil
+	t.Importances = make([]float64, numFeatures)
+	g.grow(rows, 0)
+	// Normalize the importances to shares of the total decrease.
+	var sum float64
+	for _, v := range t.Importances {
+		sum += v
+	}
+	if sum > 0 {
+		for j := range t.Importances {
+			t.Importances[j] /= sum
+		}
+	}
+	return nil
+}
+
+// grower holds the state of Fit.
+type grower struct {
+	t *Tree
+	// cols holds the columns of the rows, y their labels and weight their
+	// weights.
+	cols   [][]float64
+	y      []float64
+	weight []float64
+	r      *rand.Rand
+	// bins holds the histogram bins of the features, when they are
+	// binned.
+	bins *bins
+}
+
+// stats accumulates the weighted statistics of a set of rows.
+type stats struct {
+	weight float64
+	count  int
+	// classes holds the weight of every class, for classification.
+	classes []float64
+	// sum and sumSq hold the weighted sums of the target and of its
+	// square, for regression.
+	sum, sumSq float64
+}
+
+// newStats returns empty statistics for the task of the tree.
+func (g *grower) newStats() stats {
+	if g.t.Task == Classification {
+		return stats{classes: make([]float64, g.t.NumClasses)}
+	}
+	return stats{}
+}
+
+// add adds the row of label y and weight w when sign is 1, and removes it
+// when sign is -1.
+func (s *stats) add(y, w float64, sign float64) {
+	s.weight += sign * w
+	s.count += int(sign)
+	if s.classes != nil {
+		s.classes[int(y)] += sign * w
+		return
+	}
+	s.sum += sign * w * y
+	s.sumSq += sign * w * y * y
+}
+
+// impurity returns the Gini impurity or the variance of the rows.
+func (s *stats) impurity() float64 {
+	if s.weight <= 0 {
+		return 0
+	}
+	if s.classes != nil {
+		gini := 1.0
+		for _, c := range s.classes {
+			p := c / s.weight
+			gini -= p * p
+		}
+		return gini
+	}
+	mean := s.sum / s.weight
+	return math.Max(s.sumSq/s.weight-mean*mean, 0)
+}
+
+// clone returns a copy of the statistics.
+func (s stats) clone() stats {
+	if s.classes != nil {
+		s.classes = append([]float64(nil), s.classes...)
+	}
+	return s
+}
+
+// value returns the prediction of the rows.
+func (s *stats) value() []float64 {
+	if s.classes != nil {
+		probs := make([]float64, len(s.classes))
+		for c, w := range s.classes {
+			probs[c] = w / s.weight
+		}
+		return probs
+	}
+	return []float64{s.sum / s.weight}
+}
+
+// grow adds the node of the rows at the given depth, and its subtree,
+// returning its index.
+func (g *grower) grow(rows []int, depth int) int {
+	s := g.newStats()
+	for _, i := range rows {
+		s.add(g.y[i], g.weight[i], 1)
+	}
+	index := len(g.t.Nodes)
+	g.t.Nodes = append(g.t.Nodes, Node{Feature: -1, Value: s.value(), Weight: s.weight})
+	// Stop at the depth limit, on too few rows, or on pure nodes.
+	p := g.t.Params
+	if (p.MaxDepth > 0 && depth >= p.MaxDepth) || len(rows) < max(p.MinSamplesSplit, 2) || s.impurity() == 0 {
+		return index
+	}
+	feature, threshold, decrease, ok := g.bestSplit(rows, s)
+	if !ok {
+		return index
+	}
+	g.t.Importances[feature] += decrease
+	var left, right []int
+	for _, i := range rows {
+		if g.cols[feature][i] <= threshold {
+			left = append(left, i)
+		} else {
+			right = append(right, i)
+		}
+	}
+	l := g.grow(left, depth+1)
+	r := g.grow(right, depth+1)
+	n := &g.t.Nodes[index]
+	n.Feature, n.Threshold, n.Left, n.Right = feature, threshold, l, r
+	return index
+}
+
+// candidates returns the features to consider for a split.
+func (g *grower) candidates() []int {
+	numFeatures := g.t.NumFeatures
+	k := g.t.Params.MaxFeatures
+	if k <= 0 || k >= numFeatures {
+		features := make([]int, numFeatures)
+		for j := range features {
+			features[j] = j
+		}
+		return features
+	}
+	return g.r.Perm(numFeatures)[:k]
+}
+
+// bestSplit returns the split of the rows with the largest weighted
+// impurity decrease, if any split keeps enough rows on both sides and
+// decreases the impurity.
+func (g *grower) bestSplit(rows []int, parent stats) (feature int, threshold, decrease float64, ok bool) {
+	minDecrease := 1e-12 * parent.weight * parent.impurity()
+	for _, j := range g.candidates() {
+		var t, d float64
+		var found bool
+		if g.bins != nil {
+			t, d, found = g.histogramSplit(rows, j, parent)
+		} else {
+			t, d, found = g.exactSplit(rows, j, parent)
+		}
+		if found && d > decrease+minDecrease {
+			feature, threshold, decrease, ok = j, t, d, true
+		}
+	}
+	return feature, threshold, decrease, ok
+}
+
+// exactSplit returns the best split of the rows on feature j, trying a
+// threshold between every two distinct values.
+func (g *grower) exactSplit(rows []int, j int, parent stats) (threshold, decrease float64, ok bool) {
+	minLeaf := max(g.t.Params.MinSamplesLeaf, 1)
+	parentImpurity := parent.weight * parent.impurity()
+	// Sweep the rows in the order of the feature, moving one row at a
+	// time from the right side to the left side.
+	col := g.cols[j]
+	sorted := append([]int(nil), rows...)
+	sort.SliceStable(sorted, func(a, b int) bool { return col[sorted[a]] < col[sorted[b]] })
+	left, right := g.newStats(), parent.clone()
+	for k := 0; k < len(sorted)-1; k++ {
+		i := sorted[k]
+		left.add(g.y[i], g.weight[i], 1)
+		right.add(g.y[i], g.weight[i], -1)
+		v, next := col[i], col[sorted[k+1]]
+		if v == next || left.count < minLeaf || right.count < minLeaf {
+			continue
+		}
+		d := parentImpurity - left.weight*left.impurity() - right.weight*right.impurity()
+		if !ok || d > decrease {
+			threshold, decrease, ok = v+(next-v)/2, d, true
+		}
+	}
+	return threshold, decrease, ok
+}
+
+// leaf returns the leaf reached by the row.
+func (t *Tree) leaf(row []float64) *Node {
+	n := &t.Nodes[0]
+	for !n.Leaf() {
+		if row[n.Feature] <= n.Threshold {
+			n = &t.Nodes[n.Left]
+		} else {
+			n = &t.Nodes[n.Right]
+		}
+	}
+	return n
+}
+
+// PredictProba returns the class probabilities of the row for
+// classification trees, or the one-value prediction for regression trees.
+func (t *Tree) PredictProba(row []float64) []float64 {
+	return t.leaf(row).Value
+}
+
+// Predict returns the most probable class index of the row, the lowest
+// on ties, or the predicted value for regression trees.
+func (t *Tree) Predict(row []float64) float64 {
+	return Decide(t.Task, t.PredictProba(row))
+}
+
+// Decide turns the output of PredictProba into a prediction: the index
+// of the most probable class, the lowest on ties, for classification and
+// the value itself for regression.
+func Decide(task Task, value []float64) float64 {
+	if task == Regression {
+		return value[0]
+	}
+	best := 0
+	for c, p := range value {
+		if p > value[best] {
+			best = c
+		}
+	}
+	return float64(best)
+}
+
+// Depth returns the depth of the deepest leaf, the root being at depth 0.
+func (t *Tree) Depth() int {
+	var depth func(i int) int
+	depth = func(i int) int {
+		n := &t.Nodes[i]
+		if n.Leaf() {
+			return 0
+		}
+		return 1 + max(depth(n.Left), depth(n.Right))
+	}
+	if len(t.Nodes) == 0 {
+		return 0
+	}
+	return depth(0)
+}
@@ -0,0 +1,122 @@
+// Package workspace locates the inputs and outputs of the example
+// programs: the directory of their datasets, their test fixtures and the
+// directory their runs are written to. The locations are flags, which
+// default to environment variables, so the examples run from any working
+// directory, such as with go run at the root of the repository.
+//
+// Relative paths that do not exist in the working directory are looked up
+// next to the source of the example, so that the default locations, such
+// as ../dataset, are found wherever the example is run from.
+package workspace
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+)
+
+// Environment variables setting the defaults of the flags.
+const (
+	DataDirEnv = "GOML_DATA_DIR"
+	RunsDirEnv = "GOML_RUNS_DIR"
+)
+
+// Workspace holds the locations of an example program.
+type Workspace struct {
+	program string
+	// sourceDir is the directory of the source of the example.
+	sourceDir string
+	dataDir   *string
+	runsDir   *string
+	runName   *string
+}
+
+// Register defines the -data-dir, -runs-dir and -run-name flags of the
+// program on fs. dataDir is the default directory of the datasets, used
+// when GOML_DATA_DIR is not set. Register is meant to be called from a
+// package-level variable of the example, whose source directory it
+// records.
+func Register(fs *flag.FlagSet, program, dataDir string) *Workspace {
+	w := &Workspace{program: program}
+	if _, file, _, ok := runtime.Caller(1); ok {
+		w.sourceDir = filepath.Dir(file)
+	}
+	w.dataDir = fs.String("data-dir", env(DataDirEnv, dataDir), "directory of the datasets (default $"+DataDirEnv+" when set)")
+	w.runsDir = fs.String("runs-dir", env(RunsDirEnv, "runs"), "directory the run directories are written to (default $"+RunsDirEnv+" when set)")
+	w.runName = fs.String("run-name", "", "name of the run directory (default the program name and the time)")
+	return w
+}
+
+// env returns the value of the environment variable, or def when unset.
+func env(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// resolve returns the path, or the path next to the source of the
+// example when it is relative, missing from the working directory and
+// present there.
+func (w *Workspace) resolve(path string) string {
+	if filepath.IsAbs(path) || w.sourceDir == "" {
+		return path
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	alt := filepath.Join(w.sourceDir, path)
+	if _, err := os.Stat(alt); err == nil {
+		return alt
+	}
+	return path
+}
+
+// DataDir returns the directory of the datasets.
+func (w *Workspace) DataDir() string {
+	return w.resolve(*w.dataDir)
+}
+
+// Data returns the path of the named file of the dataset directory.
+func (w *Workspace) Data(name string) string {
+	return filepath.Join(w.DataDir(), name)
+}
+
+// Fixture returns the path of a file shipped with the source of the
+// example, such as testdata/fixture.csv.
+func (w *Workspace) Fixture(path string) string {
+	return w.resolve(path)
+}
+
+// NewRun creates the directory of a new run in the runs directory, named
+// by -run-name or after the program and the current time, and writes its
+// manifest, with the hashes of the files of the dataset directory.
+func (w *Workspace) NewRun() (*artifacts.Run, error) {
+	var run *artifacts.Run
+	var err error
+	if *w.runName != "" {
+		run, err = artifacts.NewNamedRun(*w.runsDir, *w.runName)
+	} else {
+		run, err = artifacts.NewRun(*w.runsDir, w.program)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(w.DataDir())
+	if err != nil {
+		return nil, err
+	}
+	var datasets []string
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			datasets = append(datasets, w.Data(e.Name()))
+		}
+	}
+	if err := run.WriteManifest(w.program, datasets); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
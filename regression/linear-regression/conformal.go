@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/conformal"
+	"github.com/gonum/matrix/mat64"
+	"github.com/sajari/regression"
+)
+
+// Split conformal prediction
+// We hold out part of the training data as a calibration set, fit the model on the
+// rest, and use the absolute residuals on the calibration set to size a prediction
+// interval. For exchangeable data the interval then contains the observed Sales
+// with probability of at least 1 - alpha, whatever the model.
+
+// conformalAlpha is the allowed miscoverage rate of the prediction intervals.
+const conformalAlpha = 0.1
+
+// calibrationFraction is the share of the training rows held out for calibration.
+const calibrationFraction = 0.25
+
+// readTVSales reads the TV feature and the Sales target from a dataset file.
+func readTVSales(path string) ([][]float64, []float64, error) {
+	// Open the dataset file.
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	// Create a CSV reader reading from the opened file.
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 4
+	// Read in all of the CSV records
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	var xs [][]float64
+	var ys []float64
+	for i, record := range records {
+		// Skip the header.
+		if i == 0 {
+			continue
+		}
+		// Parse the TV value.
+		tvVal, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Parse the Sales regression measure, or "y".
+		yVal, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		xs = append(xs, []float64{tvVal})
+		ys = append(ys, yVal)
+	}
+	return xs, ys, nil
+}
+
+// conformalIntervals calibrates split conformal intervals of the
+// regression, prints their width and test coverage and returns them.
+func conformalIntervals() (width, coverage float64, err error) {
+	// Load the training and test data.
+	xs, ys, err := readTVSales(files.Data("training.csv"))
+	if err != nil {
+		return 0, 0, err
+	}
+	testXs, testYs, err := readTVSales(files.Data("test.csv"))
+	if err != nil {
+		return 0, 0, err
+	}
+	// Hold out a random part of the training rows for calibration.
+	r := rand.New(rand.NewSource(splitSeed))
+	perm := r.Perm(len(ys))
+	numCalibration := int(float64(len(ys)) * calibrationFraction)
+	// Fit the regression on the remaining rows.
+	var reg regression.Regression
+	reg.SetObserved("Sales")
+	reg.SetVar(0, "TV")
+	for _, idx := range perm[numCalibration:] {
+		reg.Train(regression.DataPoint(ys[idx], xs[idx]))
+	}
+	if err := reg.Run(); err != nil {
+		return 0, 0, err
+	}
+	// Calibrate the interval width on the held out rows.
+	calXs := mat64.NewDense(numCalibration, 1, nil)
+	var calYs []float64
+	for i, idx := range perm[:numCalibration] {
+		calXs.SetRow(i, xs[idx])
+		calYs = append(calYs, ys[idx])
+	}
+	c, err := conformal.CalibrateRegressor(reg.Predict, calXs, calYs, conformalAlpha)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Measure the empirical coverage of the intervals on the test set.
+	var covered int
+	for i, x := range testXs {
+		lower, upper, err := c.Interval(x)
+		if err != nil {
+			return 0, 0, err
+		}
+		if testYs[i] >= lower && testYs[i] <= upper {
+			covered++
+		}
+	}
+	// Output the interval width and coverage to standard out.
+	coverage = float64(covered) / float64(len(testYs))
+	fmt.Printf("Conformal interval (alpha = %0.2f) = prediction +/- %0.2f\n", conformalAlpha, c.Width)
+	fmt.Printf("Test coverage = %0.2f\n\n", coverage)
+	return c.Width, coverage, nil
+}
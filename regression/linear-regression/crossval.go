@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/sajari/regression"
+)
+
+// k-fold cross-validation
+// A single 80/20 split leaves the test error at the mercy of which 40 rows
+// were held out. As the golearn examples do for the classifiers, we also
+// divide the whole dataset into k folds, fit a model on all folds but one,
+// evaluate it on the remaining fold, and report the mean and the standard
+// deviation of the errors over the folds. The folds are stratified on the
+// Sales quantile bins, like the split.
+
+// numFolds is the number of cross-validation folds.
+var numFolds = flag.Int("folds", 5, "number of cross-validation folds (0 skips the cross-validation)")
+
+// crossValidate fits and evaluates the regression on every fold, prints
+// the errors of every fold and their mean and standard deviation, saves
+// them to the folds table, and returns the means and standard deviations
+// of the MAE and RMSE.
+func crossValidate(run *artifacts.Run) (map[string]float64, error) {
+	if *numFolds < 2 {
+		return nil, fmt.Errorf("-folds is %d: cross-validation needs at least 2 folds", *numFolds)
+	}
+	xs, ys, err := readTVSales(dataset)
+	if err != nil {
+		return nil, err
+	}
+	bins := split.QuantileBins(ys, numTargetBins)
+	folds := split.StratifiedFolds(bins, *numFolds, rand.New(rand.NewSource(splitSeed)))
+	scores := make([]metrics.Regression, *numFolds)
+	for k := range scores {
+		// Fit the regression on the rows of the other folds.
+		var r regression.Regression
+		r.SetObserved("Sales")
+		r.SetVar(0, "TV")
+		for i, fold := range folds {
+			if fold != k {
+				r.Train(regression.DataPoint(ys[i], xs[i]))
+			}
+		}
+		if err := r.Run(); err != nil {
+			return nil, err
+		}
+		// Predict the rows of the fold.
+		var observed, predicted []float64
+		for i, fold := range folds {
+			if fold != k {
+				continue
+			}
+			yPredicted, err := r.Predict(xs[i])
+			if err != nil {
+				return nil, err
+			}
+			observed = append(observed, ys[i])
+			predicted = append(predicted, yPredicted)
+		}
+		var err error
+		scores[k], err = metrics.RegressionScores(observed, predicted, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Output the errors of every fold and their spread.
+	fmt.Printf("%d-fold cross-validation\n", *numFolds)
+	rows := make([][]any, len(scores))
+	maes := make([]float64, len(scores))
+	rmses := make([]float64, len(scores))
+	for k, s := range scores {
+		fmt.Printf("Fold %d: MAE = %0.2f RMSE = %0.2f R^2 = %0.4f\n", k+1, s.MAE, s.RMSE, s.R2)
+		rows[k] = []any{k + 1, s.MAE, s.RMSE, s.R2}
+		maes[k], rmses[k] = s.MAE, s.RMSE
+	}
+	maeMean, maeStdev := meanStdev(maes)
+	rmseMean, rmseStdev := meanStdev(rmses)
+	fmt.Printf("MAE = %0.2f (+/- %0.2f)\nRMSE = %0.2f (+/- %0.2f)\n\n", maeMean, 2*maeStdev, rmseMean, 2*rmseStdev)
+	if err := run.WriteTable("folds", []string{"fold", "mae", "rmse", "r2"}, rows); err != nil {
+		return nil, err
+	}
+	return map[string]float64{
+		"cv_mae_mean":   maeMean,
+		"cv_mae_stdev":  maeStdev,
+		"cv_rmse_mean":  rmseMean,
+		"cv_rmse_stdev": rmseStdev,
+	}, nil
+}
+
+// meanStdev returns the mean and the population standard deviation of
+// the values, as golearn computes them for the classifiers.
+func meanStdev(values []float64) (mean, stdev float64) {
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
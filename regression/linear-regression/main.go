@@ -1,19 +1,23 @@
 package main
 
 import (
-	"bufio"
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"log"
-	"math"
 	"os"
+	"path/filepath"
 	"strconv"
 
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/bachhm.dev/go-machine-learning/pkg/tracking"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
 	"github.com/go-gota/gota/dataframe"
 	"github.com/sajari/regression"
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
 )
 
 // Step 1: Profiling the data
@@ -31,7 +35,9 @@ import (
 // Step 3: Creating our training and test sets
 // To avoid over-fitting and make sure that our model can generalize, we are going to
 // split our dataset into a training set and a test set
-// In this case, we will use an 80/20 split for our training and test data
+// In this case, we will use an 80/20 split for our training and test data.
+// Because the dataset is small, we bin Sales into quantiles and stratify the
+// folds on those bins, so both sets cover the full range of the target.
 
 // Step 4: Training our model
 // Next, we are going to actually train, or fit, our linear regression model.
@@ -45,24 +51,147 @@ import (
 // then calculate one of the evaluation metrics. In this case, we will use the mean
 // absolute error (MAE) to evaluate our model.
 
-const dataset = "../dataset/Advertising.csv"
-const trainingDataSet = "../dataset/training.csv"
-const testDataSet = "../dataset/test.csv"
+// dataset is the advertising data, or the sample of it used by the run.
+var dataset string
+
+// testFraction is the share of the rows held out for testing.
+const testFraction = 0.2
+
+// numTargetBins is the number of Sales quantile bins used to stratify the folds.
+const numTargetBins = 5
+
+// splitSeed seeds the shuffling of rows within each bin.
+const splitSeed = 44111342
+
+// files locates the datasets and the runs of the example.
+var files = workspace.Register(flag.CommandLine, "linear-regression", "../dataset")
 
 func main() {
-	dataProfiling()
-	chooseIndependentVariable()
-	splitData()
-	r := train()
-	test(r)
-	visualizeRegression(r)
+	if err := experiment.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	if err := runExample(); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func dataProfiling() {
+// runExample runs the mode selected by the flags, the whole pipeline by
+// default, returning the first error met.
+func runExample() error {
+	// Compare the model with scikit-learn instead, when requested.
+	if *parityMode {
+		return checkParity()
+	}
+	// Create the artifact directory of this run.
+	run, err := files.NewRun()
+	if err != nil {
+		return err
+	}
+	// Record the memory used by every stage of the run.
+	tracker, err := newMemoryTracker()
+	if err != nil {
+		return err
+	}
+	tracker.Begin("load")
+	if dataset, err = sampleData(run, files.Data("Advertising.csv")); err != nil {
+		return err
+	}
+	if err := dataProfiling(run); err != nil {
+		return err
+	}
+	if err := chooseIndependentVariable(run); err != nil {
+		return err
+	}
+	tracker.Begin("preprocess")
+	if err := splitData(); err != nil {
+		return err
+	}
+	tracker.Begin("train")
+	r, err := trainOrLoad(run)
+	if err != nil {
+		return err
+	}
+	tracker.Begin("evaluate")
+	metrics, err := test(r)
+	if err != nil {
+		return err
+	}
+	if err := visualizeRegression(r, run); err != nil {
+		return err
+	}
+	if metrics["conformal_width"], metrics["conformal_coverage"], err = conformalIntervals(); err != nil {
+		return err
+	}
+	if *numFolds > 0 {
+		cv, err := crossValidate(run)
+		if err != nil {
+			return err
+		}
+		for name, value := range cv {
+			metrics[name] = value
+		}
+	}
+	memoryMetrics, err := writeMemory(run, tracker)
+	if err != nil {
+		return err
+	}
+	for name, value := range memoryMetrics {
+		metrics[name] = value
+	}
+	// Record the metrics and the configuration of the run.
+	if err := run.WriteMetrics(metrics); err != nil {
+		return err
+	}
+	config := map[string]any{
+		"dataset":              dataset,
+		"features":             "TV",
+		"target":               "Sales",
+		"test_fraction":        testFraction,
+		"num_target_bins":      numTargetBins,
+		"split_seed":           splitSeed,
+		"conformal_alpha":      conformalAlpha,
+		"calibration_fraction": calibrationFraction,
+		"num_folds":            *numFolds,
+		"memory_budget":        *memoryBudget,
+		"model":                *modelPath,
+		"sample":               *sampleSize,
+		"sample_by":            *sampleBy,
+		"sample_weight":        *sampleWeight,
+		"selection_method":     *selectionMethod,
+		"min_variance":         *minVariance,
+		"min_correlation":      *minCorrelation,
+	}
+	if err := run.WriteConfig(config); err != nil {
+		return err
+	}
+	if err := experiment.Write(run.Path(experiment.File), flag.CommandLine); err != nil {
+		return err
+	}
+	// Send the results to the webhook sink, when one is configured.
+	sink := tracking.SinkFromEnv(filepath.Base(run.Dir))
+	if err := sink.LogFinal(metrics); err != nil {
+		return err
+	}
+	if err := sink.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Artifacts saved to %s\n", run.Dir)
+	// Log the run to the MLflow tracking server, when one is configured.
+	if mlflow, ok := tracking.FromEnv(); ok {
+		runID, err := mlflow.LogRun("linear-regression", filepath.Base(run.Dir), config, metrics, run.Dir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Logged MLflow run %s\n", runID)
+	}
+	return nil
+}
+
+func dataProfiling(run *artifacts.Run) error {
 	// Open the CSV file.
 	advertFile, err := os.Open(dataset)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer advertFile.Close()
 	// Create a dataframe from the CSV file.
@@ -75,37 +204,19 @@ func dataProfiling() {
 
 	// Create a histogram for each of the columns in the dataset.
 	for _, colName := range advertDF.Names() {
-		// Create a plotter.Values value and fill it with the
-		// values from the respective column of the dataframe.
-		plotVals := make(plotter.Values, advertDF.Nrow())
-		for i, floatVal := range advertDF.Col(colName).Float() {
-			plotVals[i] = floatVal
-		}
-		// Make a plot and set its title.
-		p := plot.New()
-		p.Title.Text = fmt.Sprintf("Histogram of a %s", colName)
-		// Create a histogram of our values drawn
-		// from the standard normal.
-		h, err := plotter.NewHist(plotVals, 16)
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Normalize the histogram.
-		h.Normalize(1)
-		// Add the histogram to the plot.
-		p.Add(h)
-		// Save the plot to a PNG file.
-		if err := p.Save(4*vg.Inch, 4*vg.Inch, colName+"_hist.png"); err != nil {
-			log.Fatal(err)
+		title := fmt.Sprintf("Histogram of a %s", colName)
+		if err := plots.Histogram(run.PlotPath(colName+"_hist.png"), title, advertDF.Col(colName).Float(), 16); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-func chooseIndependentVariable() {
+func chooseIndependentVariable(run *artifacts.Run) error {
 	// Open the advertising dataset file.
 	f, err := os.Open(dataset)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer f.Close()
 	// Create a dataframe from the CSV file.
@@ -114,88 +225,45 @@ func chooseIndependentVariable() {
 	yVals := advertDF.Col("Sales").Float()
 	// Create a scatter plot for each of the features in the dataset.
 	for _, colName := range advertDF.Names() {
-		// pts will hold the values for plotting
-		pts := make(plotter.XYs, advertDF.Nrow())
-		// Fill pts with data.
-		for i, floatVal := range advertDF.Col(colName).Float() {
-			pts[i].X = floatVal
-			pts[i].Y = yVals[i]
-		}
-		// Create the plot.
-		p := plot.New()
-		p.X.Label.Text = colName
-		p.Y.Label.Text = "y"
-		p.Add(plotter.NewGrid())
-		s, err := plotter.NewScatter(pts)
-		if err != nil {
-			log.Fatal(err)
-		}
-		s.GlyphStyle.Radius = vg.Points(3)
-		// Save the plot to a PNG file.
-		p.Add(s)
-		if err := p.Save(4*vg.Inch, 4*vg.Inch, colName+"_scatter.png"); err != nil {
-			log.Fatal(err)
+		if err := plots.Scatter(run.PlotPath(colName+"_scatter.png"), colName, "y", advertDF.Col(colName).Float(), yVals); err != nil {
+			return err
 		}
 	}
+	// Rank the features by their correlation with Sales.
+	return rankFeatures(run, advertDF)
 }
 
-func splitData() {
+func splitData() error {
 	// Open the advertising dataset file.
 	f, err := os.Open(dataset)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer f.Close()
 	// Create a dataframe from the CSV file.
 	// The types of the columns will be inferred.
 	advertDF := dataframe.ReadCSV(f)
-	// Calculate the number of elements in each set.
-	trainingNum := (4 * advertDF.Nrow()) / 5
-	testNum := advertDF.Nrow() / 5
-	if trainingNum+testNum < advertDF.Nrow() {
-		trainingNum++
-	}
-	// Create the subset indices.
-	trainingIdx := make([]int, trainingNum)
-	testIdx := make([]int, testNum)
-	// Enumerate the training indices.
-	for i := 0; i < trainingNum; i++ {
-		trainingIdx[i] = i
-	}
-	// Enumerate the test indices.
-	for i := 0; i < testNum; i++ {
-		testIdx[i] = trainingNum + i
-	}
-	// Create the subset dataframes.
-	trainingDF := advertDF.Subset(trainingIdx)
-	testDF := advertDF.Subset(testIdx)
-	// Create a map that will be used in writing the data
-	// to files.
-	setMap := map[int]dataframe.DataFrame{
-		0: trainingDF,
-		1: testDF,
-	}
-	// Create the respective files.
-	for idx, setName := range []string{trainingDataSet, testDataSet} {
-		// Save the filtered dataset file.
-		f, err := os.Create(setName)
-		if err != nil {
-			log.Fatal(err)
-		}
-		// Create a buffered writer.
-		w := bufio.NewWriter(f)
-		// Write the dataframe out as a CSV.
-		if err := setMap[idx].WriteCSV(w); err != nil {
-			log.Fatal(err)
-		}
+	// Bin the Sales target into quantiles and stratify the split on the
+	// bins, so that both sets receive low, medium and high Sales
+	// observations in the same proportions.
+	bins := split.QuantileBins(advertDF.Col("Sales").Float(), numTargetBins)
+	trainingIdx, testIdx := split.StratifiedTrainTest(bins, split.Config{
+		TestFraction: testFraction,
+		Shuffle:      true,
+		Seed:         splitSeed,
+	})
+	// Save the respective files.
+	if err := split.WriteCSV(advertDF, trainingIdx, files.Data("training.csv")); err != nil {
+		return err
 	}
+	return split.WriteCSV(advertDF, testIdx, files.Data("test.csv"))
 }
 
-func train() regression.Regression {
+func train() (regression.Regression, error) {
 	// Open the training dataset file.
-	f, err := os.Open(trainingDataSet)
+	f, err := os.Open(files.Data("training.csv"))
 	if err != nil {
-		log.Fatal(err)
+		return regression.Regression{}, err
 	}
 	defer f.Close()
 	// Create a new CSV reader reading from the opened file.
@@ -204,7 +272,7 @@ func train() regression.Regression {
 	reader.FieldsPerRecord = 4
 	trainingData, err := reader.ReadAll()
 	if err != nil {
-		log.Fatal(err)
+		return regression.Regression{}, err
 	}
 	// In this case we are going to try and model our Sales (y)
 	// by the TV feature plus an intercept. As such, let's create
@@ -221,28 +289,30 @@ func train() regression.Regression {
 		// Parse the Sales regression measure, or "y".
 		yVal, err := strconv.ParseFloat(record[3], 64)
 		if err != nil {
-			log.Fatal(err)
+			return regression.Regression{}, err
 		}
 		// Parse the TV value.
 		tvVal, err := strconv.ParseFloat(record[0], 64)
 		if err != nil {
-			log.Fatal(err)
+			return regression.Regression{}, err
 		}
 		// Add these points to the regression value.
 		r.Train(regression.DataPoint(yVal, []float64{tvVal}))
 	}
 	// Train/fit the regression model.
-	r.Run()
+	if err := r.Run(); err != nil {
+		return regression.Regression{}, err
+	}
 	// Output the trained model parameters.
 	fmt.Printf("\nRegression Formula:\n%v\n\n", r.Formula)
-	return r
+	return r, nil
 }
 
-func test(r regression.Regression) {
+func test(r predictor) (map[string]float64, error) {
 	// Open the test dataset file.
-	f, err := os.Open(testDataSet)
+	f, err := os.Open(files.Data("test.csv"))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer f.Close()
 	// Create a CSV reader reading from the opened file.
@@ -251,11 +321,11 @@ func test(r regression.Regression) {
 	reader.FieldsPerRecord = 4
 	testData, err := reader.ReadAll()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	// Loop over the test data predicting y and evaluating the prediction
-	// with the mean absolute error.
-	var mAE float64
+	// Loop over the test data predicting y.
+	observed := make([]float64, 0, len(testData))
+	predicted := make([]float64, 0, len(testData))
 	for i, record := range testData {
 		// Skip the header.
 		if i == 0 {
@@ -264,73 +334,57 @@ func test(r regression.Regression) {
 		// Parse the observed Sales, or "y".
 		yObserved, err := strconv.ParseFloat(record[3], 64)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		// Parse the TV value.
 		tvVal, err := strconv.ParseFloat(record[0], 64)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		// Predict y with our trained model.
 		yPredicted, err := r.Predict([]float64{tvVal})
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
-		// Add the to the mean absolute error.
-		mAE += math.Abs(yObserved-yPredicted) / float64(len(testData))
+		observed = append(observed, yObserved)
+		predicted = append(predicted, yPredicted)
+	}
+	// Evaluate the predictions and output the scores to standard out.
+	scores, err := metrics.RegressionScores(observed, predicted, 1)
+	if err != nil {
+		return nil, err
 	}
-	// Output the MAE to standard out.
-	fmt.Printf("MAE = %0.2f\n\n", mAE)
+	metrics.WriteRegression(os.Stdout, scores)
+	fmt.Println()
+	return scores.Map(), nil
 }
 
-func visualizeRegression(r regression.Regression) {
+func visualizeRegression(r predictor, run *artifacts.Run) error {
 	// Output the trained model parameters.
 	// Open the advertising dataset file.
 	f, err := os.Open(dataset)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer f.Close()
 	// Create a dataframe from the CSV file.
 	advertDF := dataframe.ReadCSV(f)
 	// Extract the target column.
 	yVals := advertDF.Col("Sales").Float()
-	// pts will hold the values for plotting.
-	pts := make(plotter.XYs, advertDF.Nrow())
-	// ptsPred will hold the predicted values for plotting.
-	ptsPred := make(plotter.XYs, advertDF.Nrow())
-	// Fill pts with data.
-	for i, floatVal := range advertDF.Col("TV").Float() {
-		pts[i].X = floatVal
-		pts[i].Y = yVals[i]
-		ptsPred[i].X = floatVal
-		ptsPred[i].Y, err = r.Predict([]float64{floatVal})
+	xVals := advertDF.Col("TV").Float()
+	// Predict Sales for every observation and compute the residuals.
+	predicted := make([]float64, len(xVals))
+	residuals := make([]float64, len(xVals))
+	for i, floatVal := range xVals {
+		predicted[i], err = r.Predict([]float64{floatVal})
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
+		residuals[i] = yVals[i] - predicted[i]
 	}
-	// Create the plot.
-	p := plot.New()
-
-	p.X.Label.Text = "TV"
-	p.Y.Label.Text = "Sales"
-	p.Add(plotter.NewGrid())
-	// Add the scatter plot points for the observations.
-	s, err := plotter.NewScatter(pts)
-	if err != nil {
-		log.Fatal(err)
-	}
-	s.GlyphStyle.Radius = vg.Points(3)
-	// Add the line plot points for the predictions.
-	l, err := plotter.NewLine(ptsPred)
-	if err != nil {
-		log.Fatal(err)
-	}
-	l.LineStyle.Width = vg.Points(1)
-	l.LineStyle.Dashes = []vg.Length{vg.Points(5), vg.Points(5)}
-	// Save the plot to a PNG file.
-	p.Add(s, l)
-	if err := p.Save(4*vg.Inch, 4*vg.Inch, "regression_line.png"); err != nil {
-		log.Fatal(err)
+	// Save the observations with the fitted line, and the residuals.
+	if err := plots.Fit(run.PlotPath("regression_line.png"), "TV", "Sales", xVals, yVals, predicted); err != nil {
+		return err
 	}
+	return plots.Residuals(run.PlotPath("residuals.png"), predicted, residuals)
 }
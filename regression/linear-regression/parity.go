@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/parity"
+	"github.com/sajari/regression"
+)
+
+// Parity with scikit-learn
+// The regression of Sales on TV is fitted on every row of the dataset and
+// compared with the reference coefficients and predictions of
+// pkg/parity/reference.py, those of scikit-learn's LinearRegression or,
+// as its source records, the exact least squares solution.
+
+// parityFixture holds the reference outputs the model is compared with.
+const parityFixture = "testdata/parity_linear.json"
+
+// parityMode compares the model with the reference instead of running
+// the example.
+var parityMode = flag.Bool("parity", false, "compare the model with the reference outputs in "+parityFixture+" instead of running the example")
+
+// checkParity fits the model on the reference dataset, prints how it
+// compares with the reference and returns an error when it does not
+// match.
+func checkParity() error {
+	ref, err := parity.Load(files.Fixture(parityFixture))
+	if err != nil {
+		return err
+	}
+	// Read the dataset the reference was fitted on.
+	f, err := os.Open(files.Fixture(ref.Data))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return err
+	}
+	// Fit Sales on TV over every row.
+	var r regression.Regression
+	r.SetObserved("Sales")
+	r.SetVar(0, "TV")
+	var tvs []float64
+	for _, record := range records[1:] {
+		tvVal, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			return err
+		}
+		yVal, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return err
+		}
+		tvs = append(tvs, tvVal)
+		r.Train(regression.DataPoint(yVal, []float64{tvVal}))
+	}
+	if err := r.Run(); err != nil {
+		return err
+	}
+	// Compare the coefficients and the predictions.
+	got := map[string]float64{"intercept": r.Coeff(0), "TV": r.Coeff(1)}
+	predictions := make([]float64, len(tvs))
+	for i, tv := range tvs {
+		if predictions[i], err = r.Predict([]float64{tv}); err != nil {
+			return err
+		}
+	}
+	results := []parity.Result{ref.CompareCoefficients(got), ref.CompareValues(predictions)}
+	return parity.WriteReport(os.Stdout, ref, got, results)
+}
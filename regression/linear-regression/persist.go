@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+)
+
+// modelFile names the saved regression in the run directory.
+const modelFile = "linear_regression.json"
+
+// modelPath is a model saved by a previous run, used instead of training.
+var modelPath = flag.String("model", "", "evaluate the model saved at this path, such as runs/<run>/models/"+modelFile+", instead of training one")
+
+// predictor predicts Sales from the features of a row.
+type predictor interface {
+	Predict(row []float64) (float64, error)
+}
+
+// trainOrLoad trains the regression and saves its coefficients to the
+// model directory of the run, or loads the model saved at -model.
+func trainOrLoad(run *artifacts.Run) (predictor, error) {
+	if *modelPath != "" {
+		var m model.Linear
+		h, err := model.Load(*modelPath, model.KindLinear, &m)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("\nLoaded the model of %s from %s\n\n", h.Created.Format(time.RFC3339), *modelPath)
+		return &m, nil
+	}
+	r, err := train()
+	if err != nil {
+		return nil, err
+	}
+	if err := model.Save(run.ModelPath(modelFile), model.KindLinear, model.FromRegression(&r, 1)); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+// Sampling
+//
+// Prototyping on a huge file is slow. With -sample, the run reads a random
+// sample of the rows of the raw data instead, drawn in one pass over the
+// file and saved to the run directory. The sample is uniform, or keeps
+// the proportions of the values of the -sample-by column, or favors the
+// rows with large values of the -sample-weight column.
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/sample"
+)
+
+// sampleSeed seeds the sample of the raw data.
+const sampleSeed = 44111342
+
+var (
+	// sampleSize is the number of rows sampled from the raw data.
+	sampleSize = flag.Int("sample", 0, "use a random sample of this many rows of the raw data (0 uses every row)")
+	// sampleBy names the column stratifying the sample.
+	sampleBy = flag.String("sample-by", "", "with -sample, keep the proportions of the values of this column, such as the label")
+	// sampleWeight names the column of the weights of the rows.
+	sampleWeight = flag.String("sample-weight", "", "with -sample, sample rows with probabilities proportional to this column")
+)
+
+// sampleData writes a sample of the rows of the CSV file at path to the
+// run directory and returns the path of the sample, or returns path when
+// -sample is not given.
+func sampleData(run *artifacts.Run, path string) (string, error) {
+	if *sampleSize <= 0 {
+		return path, nil
+	}
+	out := run.Path("sample_" + filepath.Base(path))
+	cfg := sample.Config{Size: *sampleSize, Stratify: *sampleBy, Weight: *sampleWeight, Seed: sampleSeed}
+	n, err := sample.WriteCSV(path, out, cfg)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Sampled %d rows of %s to %s\n\n", n, path, out)
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/selection"
+	"github.com/go-gota/gota/dataframe"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Feature selection
+// The scatter plots let us eyeball which feature follows Sales most
+// closely. To back them with numbers, we also compute the Pearson and
+// Spearman correlations of every feature with Sales, drop near-constant
+// features and those too weakly correlated, and rank the rest, so the
+// choice of TV can be checked on any sample of the data.
+
+var (
+	// selectionMethod is the correlation the features are ranked by.
+	selectionMethod = flag.String("selection-method", "pearson", "correlation the features are ranked by: pearson or spearman")
+	// minVariance drops the features whose variance does not exceed it.
+	minVariance = flag.Float64("min-variance", 0, "drop the features whose variance does not exceed this (0 drops constant features)")
+	// minCorrelation drops the features less correlated with Sales.
+	minCorrelation = flag.Float64("min-correlation", 0.1, "drop the features whose absolute correlation with Sales is below this")
+)
+
+// rankFeatures ranks the features of the dataframe by their correlation
+// with Sales, prints the ranking and saves it to the feature_selection
+// table.
+func rankFeatures(run *artifacts.Run, advertDF dataframe.DataFrame) error {
+	method, err := selection.ParseMethod(*selectionMethod)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, name := range advertDF.Names() {
+		if name != "Sales" {
+			names = append(names, name)
+		}
+	}
+	x := mat64.NewDense(advertDF.Nrow(), len(names), nil)
+	for j, name := range names {
+		x.SetCol(j, advertDF.Col(name).Float())
+	}
+	report, err := selection.Rank(x, advertDF.Col("Sales").Float(), selection.Config{
+		Method:         method,
+		MinVariance:    *minVariance,
+		MinCorrelation: *minCorrelation,
+	})
+	if err != nil {
+		return err
+	}
+	// Output the ranking to stdout and save it.
+	fmt.Printf("Features ranked by their %s correlation with Sales\n", method)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "rank\tfeature\tpearson\tspearman\tvariance\tdropped")
+	columns := []string{"rank", "feature", "pearson", "spearman", "variance", "dropped"}
+	rows := make([][]any, len(report.Features))
+	for i, f := range report.Features {
+		rows[i] = []any{i + 1, names[f.Index], f.Pearson, f.Spearman, f.Variance, f.Dropped}
+		fmt.Fprintf(tw, "%d\t%s\t%.4f\t%.4f\t%.4g\t%s\n", i+1, names[f.Index], f.Pearson, f.Spearman, f.Variance, f.Dropped)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if err := run.WriteTable("feature_selection", columns, rows); err != nil {
+		return err
+	}
+	if selected := report.Selected(); len(selected) > 0 {
+		fmt.Printf("Best feature: %s\n\n", names[selected[0]])
+	} else {
+		fmt.Printf("Every feature was dropped\n\n")
+	}
+	return nil
+}
@@ -1,22 +0,0 @@
-module github.com/bachhm.dev/go-machine-learning
-
-go 1.22.3
-
-require gonum.org/v1/plot v0.14.0
-
-require (
-	git.sr.ht/~sbinet/gg v0.5.0 // indirect
-	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b // indirect
-	github.com/campoy/embedmd v1.0.0 // indirect
-	github.com/go-fonts/liberation v0.3.2 // indirect
-	github.com/go-gota/gota v0.12.0 // indirect
-	github.com/go-latex/latex v0.0.0-20231108140139-5c1ce85aa4ea // indirect
-	github.com/go-pdf/fpdf v0.9.0 // indirect
-	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/sajari/regression v1.0.1 // indirect
-	golang.org/x/image v0.14.0 // indirect
-	golang.org/x/net v0.29.0 // indirect
-	golang.org/x/text v0.18.0 // indirect
-	gonum.org/v1/gonum v0.15.1 // indirect
-)
@@ -1,80 +1,142 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"log"
 	"math"
 	"os"
+	"os/signal"
 	"strconv"
 
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/experiment"
+	"github.com/bachhm.dev/go-machine-learning/pkg/metrics"
+	"github.com/bachhm.dev/go-machine-learning/pkg/model"
+	"github.com/bachhm.dev/go-machine-learning/pkg/workspace"
+	"github.com/gonum/matrix/mat64"
 	"github.com/sajari/regression"
 )
 
-const trainingDataSet = "../dataset/training.csv"
-const testDataSet = "../dataset/test.csv"
+// files locates the datasets and the runs of the example.
+var files = workspace.Register(flag.CommandLine, "multiple-linear-regression", "../dataset")
+
+// modelFile names the saved regression in the run directory.
+const modelFile = "multiple_linear_regression.json"
 
 func main() {
-	r := train()
-	test(r)
+	if err := experiment.Parse(flag.CommandLine, os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+	// Stop fitting the regularization path on an interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := runExample(ctx); err != nil {
+		log.Fatal(err)
+	}
 }
 
-func train() regression.Regression {
-	// Open the training dataset file.
-	f, err := os.Open(trainingDataSet)
+// runExample trains and evaluates the regression, returning the first
+// error met.
+func runExample(ctx context.Context) error {
+	// Create the artifact directory of this run.
+	run, err := files.NewRun()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer f.Close()
-	// Create a new CSV reader reading from the opened file.
-	reader := csv.NewReader(f)
-	// Read in all of the CSV records
-	reader.FieldsPerRecord = 4
-	trainingData, err := reader.ReadAll()
+	x, y, err := trainingRows(run)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	// In this case we are going to try and model our Sales
-	// by the TV and Radio features plus an intercept.
-	var r regression.Regression
-	r.SetObserved("Sales")
-	r.SetVar(0, "TV")
-	r.SetVar(1, "Radio")
-	// Loop over the CSV records adding the training data.
-	for i, record := range trainingData {
-		// Skip the header.
-		if i == 0 {
-			continue
-		}
-		// Parse the Sales.
-		yVal, err := strconv.ParseFloat(record[3], 64)
+	r, err := train(x, y)
+	if err != nil {
+		return err
+	}
+	if err := saveModel(&r, run); err != nil {
+		return err
+	}
+	testScores, err := test(r)
+	if err != nil {
+		return err
+	}
+	scores := testScores.Map()
+	if *pathLambdas > 0 {
+		pathScores, err := regularizationPath(ctx, run, x, y)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		// Parse the TV value.
-		tvVal, err := strconv.ParseFloat(record[0], 64)
+		for name, value := range pathScores {
+			scores[name] = value
+		}
+	}
+	// Save the test metrics for external dashboards.
+	if err := run.WriteMetrics(scores); err != nil {
+		return err
+	}
+	if err := experiment.Write(run.Path(experiment.File), flag.CommandLine); err != nil {
+		return err
+	}
+	fmt.Println("Artifacts saved to", run.Dir)
+	return nil
+}
+
+// saveModel saves the coefficients of the regression to the model
+// directory of the run, and returns an error unless the saved model
+// predicts as the trained one.
+func saveModel(r *regression.Regression, run *artifacts.Run) error {
+	path := run.ModelPath(modelFile)
+	if err := model.Save(path, model.KindLinear, model.FromRegression(r, 2)); err != nil {
+		return err
+	}
+	var saved model.Linear
+	if _, err := model.Load(path, model.KindLinear, &saved); err != nil {
+		return err
+	}
+	for _, row := range [][]float64{{0, 0}, {100, 20}, {250, 45}} {
+		want, err := r.Predict(row)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		// Parse the Radio value.
-		radioVal, err := strconv.ParseFloat(record[1], 64)
+		got, err := saved.Predict(row)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		if math.Abs(got-want) > 1e-9*math.Max(1, math.Abs(want)) {
+			return fmt.Errorf("the saved model predicts %v for %v, the trained one %v", got, row, want)
 		}
-		// Add these points to the regression value.
-		r.Train(regression.DataPoint(yVal, []float64{tvVal, radioVal}))
+	}
+	fmt.Println("Model saved to", path)
+	return nil
+}
+
+// train fits Sales on the TV and Radio columns of the training rows.
+func train(x *mat64.Dense, y []float64) (regression.Regression, error) {
+	// In this case we are going to try and model our Sales
+	// by the TV and Radio features plus an intercept.
+	var r regression.Regression
+	r.SetObserved("Sales")
+	r.SetVar(0, "TV")
+	r.SetVar(1, "Radio")
+	// Loop over the rows adding the training data.
+	for i, yVal := range y {
+		r.Train(regression.DataPoint(yVal, []float64{x.At(i, 0), x.At(i, 1)}))
 	}
 	// Train/fit the regression model.
-	r.Run() // Output the trained model parameters.
+	if err := r.Run(); err != nil {
+		return regression.Regression{}, err
+	}
+	// Output the trained model parameters.
 	fmt.Printf("\nRegression Formula:\n%v\n\n", r.Formula)
-	return r
+	return r, nil
 }
 
-func test(r regression.Regression) {
+func test(r regression.Regression) (metrics.Regression, error) {
 	// Open the test dataset file.
-	f, err := os.Open(testDataSet)
+	f, err := os.Open(files.Data("test.csv"))
 	if err != nil {
-		log.Fatal(err)
+		return metrics.Regression{}, err
 	}
 	defer f.Close()
 	// Create a CSV reader reading from the opened file.
@@ -83,11 +145,11 @@ func test(r regression.Regression) {
 	reader.FieldsPerRecord = 4
 	testData, err := reader.ReadAll()
 	if err != nil {
-		log.Fatal(err)
+		return metrics.Regression{}, err
 	}
-	// Loop over the test data predicting y and evaluating the prediction
-	// with the mean absolute error.
-	var mAE float64
+	// Loop over the test data predicting y.
+	observed := make([]float64, 0, len(testData))
+	predicted := make([]float64, 0, len(testData))
 	for i, record := range testData {
 		// Skip the header.​
 		if i == 0 {
@@ -96,23 +158,32 @@ func test(r regression.Regression) {
 		// Parse the Sales.
 		yObserved, err := strconv.ParseFloat(record[3], 64)
 		if err != nil {
-			log.Fatal(err)
+			return metrics.Regression{}, err
 		}
 		// Parse the TV value.
 		tvVal, err := strconv.ParseFloat(record[0], 64)
 		if err != nil {
-			log.Fatal(err)
+			return metrics.Regression{}, err
 		}
 		// Parse the Radio value.
 		radioVal, err := strconv.ParseFloat(record[1], 64)
 		if err != nil {
-			log.Fatal(err)
+			return metrics.Regression{}, err
 		}
 		// Predict y with our trained model.
 		yPredicted, err := r.Predict([]float64{tvVal, radioVal})
-		// Add the to the mean absolute error.
-		mAE += math.Abs(yObserved-yPredicted) / float64(len(testData))
+		if err != nil {
+			return metrics.Regression{}, err
+		}
+		observed = append(observed, yObserved)
+		predicted = append(predicted, yPredicted)
+	}
+	// Evaluate the predictions and output the scores to standard out.
+	scores, err := metrics.RegressionScores(observed, predicted, 2)
+	if err != nil {
+		return metrics.Regression{}, err
 	}
-	// Output the MAE to standard out.
-	fmt.Printf("MAE = %0.2f\n\n", mAE)
+	metrics.WriteRegression(os.Stdout, scores)
+	fmt.Println()
+	return scores, nil
 }
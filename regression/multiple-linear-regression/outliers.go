@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/transform"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Outliers
+// A few markets spend far more than the others, on newspapers mostly, and
+// least squares pulls the fit toward them. We bound every spend column by
+// the training rows, by default at 1.5 interquartile ranges beyond the
+// quartiles, and list the rows outside the bounds. Removing them fits
+// both the regression and the regularization path on the usual markets
+// only. The test rows are all kept: the model still has to predict them.
+
+var (
+	// outlierMethod bounds the spend columns.
+	outlierMethod = flag.String("outliers", "iqr", "bounds of the spend columns of the training rows: iqr, zscore, optionally =<k> to widen them, or none")
+	// outlierAction is what happens to the rows outside the bounds.
+	outlierAction = flag.String("outlier-action", "remove", "what to do with the training rows outside the bounds: flag lists them, remove also drops them from the fits")
+)
+
+// trainingRows returns the spend columns and the Sales of the training
+// rows, without the outliers unless they are only flagged. It saves the
+// rows outside the bounds to the outliers table of the run.
+func trainingRows(run *artifacts.Run) (*mat64.Dense, []float64, error) {
+	x, y, err := readFeatures(files.Data("training.csv"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if *outlierMethod == "none" {
+		return x, y, nil
+	}
+	if *outlierAction != "flag" && *outlierAction != "remove" {
+		return nil, nil, fmt.Errorf("unknown outlier action %q, expected flag or remove", *outlierAction)
+	}
+	method, k, err := transform.ParseOutlierMethod(*outlierMethod)
+	if err != nil {
+		return nil, nil, err
+	}
+	bounds := transform.Outliers{Method: method, K: k}
+	if err := bounds.Fit(x); err != nil {
+		return nil, nil, err
+	}
+	flags, err := bounds.Flag(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Save the rows outside the bounds, numbered as in the file.
+	columns := append([]string{"row"}, pathFeatures...)
+	columns = append(columns, "Sales")
+	var rows [][]any
+	var inside []int
+	for i, outside := range flags {
+		if !outside {
+			inside = append(inside, i)
+			continue
+		}
+		row := []any{i + 1}
+		for j := range pathFeatures {
+			row = append(row, x.At(i, j))
+		}
+		rows = append(rows, append(row, y[i]))
+	}
+	if err := run.WriteTable("outliers", columns, rows); err != nil {
+		return nil, nil, err
+	}
+	for j, name := range pathFeatures {
+		fmt.Printf("%s bounds: [%.2f, %.2f]\n", name, bounds.Lower[j], bounds.Upper[j])
+	}
+	if *outlierAction == "flag" {
+		fmt.Printf("Flagged %d of %d training rows as outliers\n\n", len(rows), len(y))
+		return x, y, nil
+	}
+	fmt.Printf("Dropped %d of %d training rows as outliers\n\n", len(rows), len(y))
+	kept := mat64.NewDense(len(inside), len(pathFeatures), nil)
+	keptY := make([]float64, len(inside))
+	for r, i := range inside {
+		kept.SetRow(r, mat64.Row(nil, i, x))
+		keptY[r] = y[i]
+	}
+	return kept, keptY, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/bachhm.dev/go-machine-learning/pkg/artifacts"
+	"github.com/bachhm.dev/go-machine-learning/pkg/elasticnet"
+	"github.com/bachhm.dev/go-machine-learning/pkg/plots"
+	"github.com/bachhm.dev/go-machine-learning/pkg/split"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Regularization path
+// Instead of choosing TV and Radio by hand, we can let a lasso or elastic
+// net choose among all the advertising features. A strong penalty zeroes
+// every coefficient; as it relaxes, the features enter the model one by
+// one, the most useful first. We fit the whole path of penalties in one
+// pass, each fit warm-started from the previous one, plot how the
+// coefficients move along it, and cross-validate every penalty to pick one.
+
+var (
+	// pathL1Ratio mixes the lasso and ridge penalties of the path.
+	pathL1Ratio = flag.Float64("l1-ratio", 1, "mix of the penalties of the regularization path: 1 is the lasso, 0 ridge regression")
+	// pathLambdas is the number of penalties of the path.
+	pathLambdas = flag.Int("lambdas", 50, "number of penalties of the regularization path (0 skips the path)")
+	// pathFolds is the number of folds the penalties are scored on.
+	pathFolds = flag.Int("path-folds", 5, "number of cross-validation folds scoring the penalties of the path")
+)
+
+// pathFeatures are the features the path chooses among.
+var pathFeatures = []string{"TV", "Radio", "Newspaper"}
+
+// pathSeed seeds the folds of the path.
+const pathSeed = 7
+
+// regularizationPath fits the regularization path on the training rows x
+// and y, saves the coefficients and the cross-validated error of every
+// penalty to the regularization_path table and plots them, and returns
+// the penalty of the smallest error and its number of features.
+func regularizationPath(ctx context.Context, run *artifacts.Run, x *mat64.Dense, y []float64) (map[string]float64, error) {
+	cfg := elasticnet.Config{L1Ratio: *pathL1Ratio, NumLambdas: *pathLambdas}
+	path, err := elasticnet.Fit(ctx, x, y, cfg)
+	if err != nil {
+		return nil, err
+	}
+	folds, err := split.RepeatedKFold(len(y), *pathFolds, 1, pathSeed)
+	if err != nil {
+		return nil, err
+	}
+	validation, err := elasticnet.CrossValidate(ctx, x, y, path, folds, 0, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Save the trajectories with the error of every penalty.
+	columns := append([]string{"lambda", "cv_mse", "cv_mse_se", "nonzero"}, pathFeatures...)
+	rows := make([][]any, len(path.Lambdas))
+	logLambdas := make([]float64, len(path.Lambdas))
+	trajectories := make([][]float64, len(pathFeatures))
+	for s, lambda := range path.Lambdas {
+		rows[s] = []any{lambda, validation.MSE[s], validation.SE[s], path.NonZero(s)}
+		for j := range pathFeatures {
+			rows[s] = append(rows[s], path.Coefficients[s][j])
+			trajectories[j] = append(trajectories[j], path.Coefficients[s][j])
+		}
+		logLambdas[s] = math.Log10(lambda)
+	}
+	if err := run.WriteTable("regularization_path", columns, rows); err != nil {
+		return nil, err
+	}
+	if err := plots.Lines(run.PlotPath("regularization_path.png"), "Regularization path", "log10(lambda)", "Standardized coefficient", logLambdas, pathFeatures, trajectories); err != nil {
+		return nil, err
+	}
+	if err := plots.Line(run.PlotPath("regularization_cv.png"), "Cross-validated error", "log10(lambda)", "MSE", logLambdas, validation.MSE); err != nil {
+		return nil, err
+	}
+	// Output the penalties the cross-validation picks.
+	for _, pick := range []struct {
+		name  string
+		index int
+	}{{"smallest error", validation.Best}, {"within one standard error", validation.OneSE}} {
+		m := path.Model(pick.index, "Sales", pathFeatures)
+		fmt.Printf("Penalty of the %s: lambda %.4g, CV MSE %.4f, %d features\n", pick.name, path.Lambdas[pick.index], validation.MSE[pick.index], path.NonZero(pick.index))
+		fmt.Printf("  Sales = %.4f", m.Intercept)
+		for j, name := range m.Features {
+			fmt.Printf(" + %.4f*%s", m.Coefficients[j], name)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	return map[string]float64{
+		"path_best_lambda":   path.Lambdas[validation.Best],
+		"path_best_cv_mse":   validation.MSE[validation.Best],
+		"path_best_features": float64(path.NonZero(validation.Best)),
+		"path_1se_lambda":    path.Lambdas[validation.OneSE],
+		"path_1se_features":  float64(path.NonZero(validation.OneSE)),
+	}, nil
+}
+
+// readFeatures returns the path features and the Sales of the rows of the
+// CSV file.
+func readFeatures(path string) (*mat64.Dense, []float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) < 2 {
+		return nil, nil, fmt.Errorf("%s has no rows", path)
+	}
+	// Find the columns of the features and of Sales in the header.
+	index := make(map[string]int)
+	for j, name := range records[0] {
+		index[name] = j
+	}
+	for _, name := range append(pathFeatures, "Sales") {
+		if _, ok := index[name]; !ok {
+			return nil, nil, fmt.Errorf("%s has no %s column", path, name)
+		}
+	}
+	x := mat64.NewDense(len(records)-1, len(pathFeatures), nil)
+	y := make([]float64, len(records)-1)
+	for i, record := range records[1:] {
+		for j, name := range pathFeatures {
+			v, err := strconv.ParseFloat(record[index[name]], 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: row %d: %v", path, i+1, err)
+			}
+			x.Set(i, j, v)
+		}
+		v, err := strconv.ParseFloat(record[index["Sales"]], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: row %d: %v", path, i+1, err)
+		}
+		y[i] = v
+	}
+	return x, y, nil
+}